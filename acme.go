@@ -0,0 +1,266 @@
+package main
+
+// This file obtains and renews the server's certificate from Let's Encrypt
+// or another ACME CA when the acme section is set, in place of cert_file
+// and key_file.  The CA checks that we control the domain either over TLS
+// on port 443 (tls-alpn-01, the default), which must reach http_port, or
+// over plain HTTP on port 80 (http-01), answered by the redirect listener
+// of redirect_port (see redirect.go).  Certificates are kept in cache_dir
+// and renewed 30 days before they expire.  If the CA cannot be reached,
+// the last certificate obtained is served even once expired, rather than
+// none.  The certificate is checked twice a day, and one that fails to be
+// obtained or is within acmeExpiryWarning of expiring, renewal having
+// failed for a fortnight, is reported as a fault.
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "errors"
+    "fmt"
+    "net"
+    "strings"
+    "sync"
+    "time"
+
+    "golang.org/x/crypto/acme"
+    "golang.org/x/crypto/acme/autocert"
+)
+
+// ACME challenge types.
+const (
+    ACMEChallengeTLSALPN = "tls-alpn-01"
+    ACMEChallengeHTTP    = "http-01"
+)
+
+// defaultACMECacheDir is the cache directory, beside config.json, used
+// when cache_dir is empty.
+const defaultACMECacheDir = "acme-cache"
+
+// acmeCheckInterval is how often the certificate is checked, and
+// acmeExpiryWarning how long before expiry a certificate not yet renewed
+// is reported.
+const (
+    acmeCheckInterval = 12 * time.Hour
+    acmeExpiryWarning = 14 * 24 * time.Hour
+)
+
+// acmeCertificates serves the certificates of an autocert.Manager,
+// remembering the last one obtained for each domain to fall back to.
+type acmeCertificates struct {
+    manager *autocert.Manager
+    domains []string
+    mu      sync.Mutex
+    last    map[string]*tls.Certificate
+    // fault describes the current certificate problem, if any.
+    fault   string
+}
+
+// validateACMEConfig checks the acme section of config.json.  The http-01
+// challenge needs the redirect listener on port redirect.
+func validateACMEConfig(ac *ACMEConfig, redirect int) error {
+    if ac == nil {
+        return nil
+    }
+    if len(ac.Domains) == 0 {
+        return errors.New("acme needs at least one domain")
+    }
+    for _, d := range ac.Domains {
+        if d == "" || strings.ContainsAny(d, "/:* ") || net.ParseIP(d) != nil {
+            return fmt.Errorf("acme domain %q must be a host name", d)
+        }
+    }
+    switch ac.Challenge {
+    case "", ACMEChallengeTLSALPN, ACMEChallengeHTTP:
+    default:
+        return fmt.Errorf("acme challenge must be %s or %s, not %q", ACMEChallengeTLSALPN, ACMEChallengeHTTP, ac.Challenge)
+    }
+    if ac.Challenge == ACMEChallengeHTTP && redirect == 0 {
+        return errors.New("acme http-01 challenge needs redirect_port, usually 80")
+    }
+    return nil
+}
+
+// startACME sets tlsConfig to serve certificates obtained through ac and
+// starts the check of the certificate.
+func (s *Server) startACME(ac ACMEConfig, tlsConfig *tls.Config) {
+    cacheDir := ac.CacheDir
+    if cacheDir == "" {
+        cacheDir = defaultACMECacheDir
+    }
+    m := &autocert.Manager{
+        Prompt:     autocert.AcceptTOS,
+        Cache:      autocert.DirCache(s.cfgMgr.resolve(cacheDir)),
+        HostPolicy: autocert.HostWhitelist(ac.Domains...),
+        Email:      ac.Email,
+    }
+    if ac.DirectoryURL != "" {
+        m.Client = &acme.Client{DirectoryURL: ac.DirectoryURL}
+    }
+    certs := &acmeCertificates{manager: m, domains: ac.Domains, last: make(map[string]*tls.Certificate)}
+    s.acme = certs
+    tlsConfig.GetCertificate = certs.getCertificate
+    // http-01 challenges are answered by the redirect listener.
+    if ac.Challenge != ACMEChallengeHTTP {
+        // The CA's validation connections, which offer only acme.ALPNProto,
+        // carry no client certificate, so they are answered with a
+        // configuration that asks for none.
+        challengeConfig := &tls.Config{
+            MinVersion:     tls.VersionTLS12,
+            GetCertificate: m.GetCertificate,
+            NextProtos:     []string{acme.ALPNProto},
+        }
+        tlsConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+            if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acme.ALPNProto {
+                return challengeConfig, nil
+            }
+            return nil, nil
+        }
+    }
+    s.logger.Log("acme: certificates for %s from %s, %s challenge", strings.Join(ac.Domains, ", "), acmeDirectory(ac), acmeChallenge(ac))
+    s.background(func() {
+        ticker := time.NewTicker(acmeCheckInterval)
+        defer ticker.Stop()
+        for {
+            s.checkCertificate()
+            select {
+            case <-ticker.C:
+            case <-s.stop:
+                return
+            }
+        }
+    })
+}
+
+// acmeDirectory returns the CA ac obtains certificates from.
+func acmeDirectory(ac ACMEConfig) string {
+    if ac.DirectoryURL != "" {
+        return ac.DirectoryURL
+    }
+    return autocert.DefaultACMEDirectory
+}
+
+// acmeChallenge returns the challenge type ac uses.
+func acmeChallenge(ac ACMEConfig) string {
+    if ac.Challenge != "" {
+        return ac.Challenge
+    }
+    return ACMEChallengeTLSALPN
+}
+
+// getCertificate returns the certificate for hello from the manager, or
+// the last one obtained for the domain if the manager has none to give.
+// Connections without a server name, such as those to an IP address, get
+// the certificate of the first domain.
+func (a *acmeCertificates) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+    if hello.ServerName == "" {
+        h := *hello
+        h.ServerName = a.domains[0]
+        hello = &h
+    }
+    name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+    cert, err := a.manager.GetCertificate(hello)
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if err == nil {
+        a.last[name] = cert
+        return cert, nil
+    }
+    if last := a.last[name]; last != nil {
+        return last, nil
+    }
+    for _, d := range a.domains {
+        if strings.EqualFold(d, name) {
+            if cached := a.cached(d); cached != nil {
+                a.last[name] = cached
+                return cached, nil
+            }
+        }
+    }
+    return nil, err
+}
+
+// cached returns the certificate for name in the cache, expired or not,
+// or nil.
+func (a *acmeCertificates) cached(name string) *tls.Certificate {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    data, err := a.manager.Cache.Get(ctx, name)
+    if err != nil {
+        return nil
+    }
+    cert, err := tls.X509KeyPair(data, data)
+    if err != nil {
+        return nil
+    }
+    if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+        return nil
+    }
+    return &cert
+}
+
+// checkCertificate obtains the certificate of each domain, renewing it if
+// due, and reports a fault if one cannot be had or is close to expiry.
+// Faults are alerted on every check until resolved.
+func (s *Server) checkCertificate() {
+    a := s.acme
+    var problems []string
+    for _, domain := range a.domains {
+        hello := &tls.ClientHelloInfo{
+            ServerName:   domain,
+            CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+        }
+        cert, err := a.manager.GetCertificate(hello)
+        if err != nil {
+            a.mu.Lock()
+            cert = a.last[domain]
+            a.mu.Unlock()
+            if cert == nil {
+                cert = a.cached(domain)
+            }
+            if cert == nil {
+                problems = append(problems, fmt.Sprintf("unable to obtain a certificate for %s: %v", domain, err))
+                continue
+            }
+        }
+        if cert.Leaf == nil {
+            continue
+        }
+        if left := time.Until(cert.Leaf.NotAfter); left < acmeExpiryWarning {
+            when := "expires"
+            if left < 0 {
+                when = "expired"
+            }
+            problem := fmt.Sprintf("certificate for %s %s %s and has not been renewed", domain, when, localTime(cert.Leaf.NotAfter).Format("2006-01-02 15:04"))
+            if err != nil {
+                problem += fmt.Sprintf(": %v", err)
+            }
+            problems = append(problems, problem)
+        }
+    }
+    fault := strings.Join(problems, "; ")
+    a.mu.Lock()
+    was := a.fault
+    a.fault = fault
+    a.mu.Unlock()
+    switch {
+    case fault != "":
+        s.logger.Warning("fault: %s", fault)
+        s.sendAlerts(s.newAlertEvent(EventFault, Zone{Name: "certificate"}, ""))
+    case was != "":
+        s.logger.Log("acme: certificate problem resolved")
+    }
+}
+
+// faults returns the current certificate problem, if any.
+func (a *acmeCertificates) faults() []string {
+    if a == nil {
+        return nil
+    }
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if a.fault == "" {
+        return nil
+    }
+    return []string{a.fault}
+}
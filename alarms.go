@@ -0,0 +1,278 @@
+package main
+
+// This file records alarms so that they can be acknowledged.  Every zone
+// that raises an alarm gets an alarm event with its own ID, which stays
+// unacknowledged until someone deals with it through POST
+// /api/events/{id}/ack.  Acknowledging clears the zone's triggered state,
+// and cancels its escalation, without disarming.  A zone acknowledged
+// during the alarm while still open is not triggered again until it has
+// closed, after which opening it raises a new alarm event.  Alarm events
+// are kept in alarms.json so that unacknowledged alarms survive a restart.
+
+import (
+    "encoding/json"
+    "errors"
+    "io"
+    "io/ioutil"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// alarmsPath is the file alarm events are persisted to.
+const alarmsPath = "alarms.json"
+
+// maxAckedAlarms bounds the acknowledged alarm events that are kept.
+// Unacknowledged ones are never dropped.
+const maxAckedAlarms = 500
+
+// AlarmEvent is an alarm raised by a zone, and its acknowledgement.  When
+// the entry delay expires the alarm is raised by the entry/exit zone that
+// started it; events saved by older versions have ZoneID zero for this.
+type AlarmEvent struct {
+    ID      int        `json:"id"`
+    ZoneID  int        `json:"zone_id,omitempty"`
+    Zone    string     `json:"zone,omitempty"`
+    Mode    string     `json:"mode"`
+    Reason  string     `json:"reason"`
+    Time    time.Time  `json:"time"`
+    AckedBy string     `json:"acked_by,omitempty"`
+    AckedAt *time.Time `json:"acked_at,omitempty"`
+    Note    string     `json:"note,omitempty"`
+}
+
+// AlarmLog holds the alarm events.  Every change is written to its file.
+type AlarmLog struct {
+    mu     sync.Mutex
+    nextID int
+    events []AlarmEvent
+    path   string
+    logger *EventLogger
+}
+
+// NewAlarmLog constructs an alarm log and restores the alarm events saved
+// at path.
+func NewAlarmLog(path string, logger *EventLogger) *AlarmLog {
+    al := &AlarmLog{path: path, logger: logger}
+    al.load()
+    return al
+}
+
+// load restores the alarm events saved by a previous run.
+func (al *AlarmLog) load() {
+    data, err := ioutil.ReadFile(al.path)
+    if err != nil {
+        if !os.IsNotExist(err) {
+            al.logger.Log("unable to read %s: %v", al.path, err)
+        }
+        return
+    }
+    if err := json.Unmarshal(data, &al.events); err != nil {
+        al.logger.Log("ignoring invalid %s: %v", al.path, err)
+        al.events = nil
+        return
+    }
+    for _, e := range al.events {
+        if e.ID > al.nextID {
+            al.nextID = e.ID
+        }
+    }
+}
+
+// saveLocked writes the alarm events to disk, dropping the oldest
+// acknowledged ones beyond maxAckedAlarms.  The caller must hold al.mu.
+func (al *AlarmLog) saveLocked() {
+    acked := 0
+    for _, e := range al.events {
+        if e.AckedAt != nil {
+            acked++
+        }
+    }
+    kept := al.events[:0]
+    for _, e := range al.events {
+        if e.AckedAt != nil && acked > maxAckedAlarms {
+            acked--
+            continue
+        }
+        kept = append(kept, e)
+    }
+    al.events = kept
+    data, err := json.MarshalIndent(al.events, "", "  ")
+    if err == nil {
+        tmpPath := al.path + ".tmp"
+        if err = ioutil.WriteFile(tmpPath, data, 0600); err == nil {
+            err = os.Rename(tmpPath, al.path)
+        }
+    }
+    if err != nil {
+        al.logger.Log("unable to save %s: %v", al.path, err)
+    }
+}
+
+// Raise records a new alarm event for zone and returns it.
+func (al *AlarmLog) Raise(zone Zone, mode, reason string) AlarmEvent {
+    al.mu.Lock()
+    defer al.mu.Unlock()
+    al.nextID++
+    e := AlarmEvent{ID: al.nextID, ZoneID: zone.ID, Zone: zone.Name, Mode: mode, Reason: reason, Time: time.Now()}
+    al.events = append(al.events, e)
+    al.saveLocked()
+    return e
+}
+
+// Ack acknowledges the alarm event id on behalf of username.
+func (al *AlarmLog) Ack(id int, username, note string) (AlarmEvent, error) {
+    al.mu.Lock()
+    defer al.mu.Unlock()
+    for i, e := range al.events {
+        if e.ID != id {
+            continue
+        }
+        if e.AckedAt != nil {
+            return e, errors.New("already acknowledged")
+        }
+        now := time.Now()
+        al.events[i].AckedBy, al.events[i].AckedAt, al.events[i].Note = username, &now, note
+        al.saveLocked()
+        return al.events[i], nil
+    }
+    return AlarmEvent{}, errors.New("not found")
+}
+
+// Unacked returns the unacknowledged alarm events, oldest first.
+func (al *AlarmLog) Unacked() []AlarmEvent {
+    al.mu.Lock()
+    defer al.mu.Unlock()
+    out := []AlarmEvent{}
+    for _, e := range al.events {
+        if e.AckedAt == nil {
+            out = append(out, e)
+        }
+    }
+    return out
+}
+
+// raiseAlarm records an alarm event for zone and logs its ID.
+func (s *Server) raiseAlarm(zone Zone, mode, reason string) {
+    e := s.alarms.Raise(zone, mode, reason)
+    if zone.ID != 0 {
+        s.logger.Log("alarm event %d raised by zone id=%d (%s)", e.ID, zone.ID, zone.Name)
+    } else {
+        s.logger.Log("alarm event %d raised: %s", e.ID, reason)
+    }
+}
+
+// ackAlarm acknowledges alarm event id for username, clearing the zone's
+// triggered state and cancelling its escalation.  The system stays armed,
+// or in alarm.
+func (s *Server) ackAlarm(id int, username, note string) (AlarmEvent, error) {
+    e, err := s.alarms.Ack(id, username, note)
+    if err != nil {
+        return e, err
+    }
+    if note != "" {
+        s.logger.Log("alarm event %d acknowledged by %s: %s", id, username, note)
+    } else {
+        s.logger.Log("alarm event %d acknowledged by %s", id, username)
+    }
+    if e.ZoneID != 0 {
+        s.triggerMu.Lock()
+        delete(s.triggered, e.ZoneID)
+        delete(s.alarmed, e.ZoneID)
+        if s.alarm {
+            if s.acked == nil {
+                s.acked = make(map[int]bool)
+            }
+            s.acked[e.ZoneID] = true
+        }
+        s.triggerMu.Unlock()
+        s.cancelEscalation(e.ZoneID, username, "acknowledged")
+    }
+    s.publishLive("acknowledge", Zone{ID: e.ZoneID, Name: e.Zone}, username)
+    return e, nil
+}
+
+// awaitingRestore reports whether zone was acknowledged during the alarm
+// and is still open, and so is not to be triggered again.  Once the zone
+// reads closed it is watched as usual.
+func (s *Server) awaitingRestore(zone Zone) bool {
+    s.triggerMu.Lock()
+    acked := s.acked[zone.ID]
+    s.triggerMu.Unlock()
+    if !acked {
+        return false
+    }
+    if s.sense(zone) {
+        s.logger.Debug("zone id=%d acknowledged, waiting for it to restore", zone.ID)
+        return true
+    }
+    s.triggerMu.Lock()
+    delete(s.acked, zone.ID)
+    s.triggerMu.Unlock()
+    s.logger.Log("zone id=%d (%s) restored after acknowledgement", zone.ID, zone.Name)
+    return false
+}
+
+// handleEventByID handles GET /api/events/unacked, listing the
+// unacknowledged alarm events oldest first, and POST
+// /api/events/{id}/ack with an optional {"note":"..."}, acknowledging one.
+// Operators and above.
+func (s *Server) handleEventByID(w http.ResponseWriter, r *http.Request, user User) {
+    rest := strings.TrimPrefix(r.URL.Path, "/api/events/")
+    if rest == "unacked" {
+        if r.Method != http.MethodGet {
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        events := s.alarms.Unacked()
+        for i := range events {
+            events[i].Time = localTime(events[i].Time)
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(events)
+        return
+    }
+    idStr, action, _ := strings.Cut(rest, "/")
+    id, err := strconv.Atoi(idStr)
+    if err != nil || action != "ack" {
+        http.NotFound(w, r)
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleOperator) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    var req struct {
+        Note string `json:"note"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+        http.Error(w, "invalid JSON", http.StatusBadRequest)
+        return
+    }
+    e, err := s.ackAlarm(id, user.Username, strings.TrimSpace(req.Note))
+    if err != nil {
+        switch err.Error() {
+        case "not found":
+            http.Error(w, "not found", http.StatusNotFound)
+        case "already acknowledged":
+            http.Error(w, err.Error(), http.StatusConflict)
+        default:
+            http.Error(w, "internal error", http.StatusInternalServerError)
+        }
+        return
+    }
+    e.Time = localTime(e.Time)
+    if e.AckedAt != nil {
+        t := localTime(*e.AckedAt)
+        e.AckedAt = &t
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(e)
+}
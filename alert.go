@@ -3,61 +3,433 @@ package main
 // This file defines pluggable alert handlers for when a sensor is triggered.
 
 import (
+    "bytes"
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/base64"
+    "errors"
     "fmt"
+    "mime"
+    "mime/multipart"
+    "net"
     "net/smtp"
+    "net/textproto"
+    "os"
+    "strconv"
+    "strings"
+    "time"
 )
 
+// Event types carried by AlertEvent.
+const (
+    EventTrigger = "trigger" // a monitored zone was triggered
+    EventAlarm   = "alarm"   // the system entered alarm state
+    EventTest    = "test"    // a zone was triggered manually in TestSoft mode
+    EventArm     = "arm"     // the system was armed
+    EventDisarm  = "disarm"  // the system was disarmed
+    EventFault   = "fault"   // a sensor or subsystem fault was detected
+    // Security events, see security.go.
+    EventLoginFailed     = "login_failed"     // one or more logins failed
+    EventAdminChange     = "admin_change"     // users, zones or arm modes were changed
+    EventSessionLimit    = "session_limit"    // a login exceeded the per-user session limit
+    EventSessionMismatch = "session_mismatch" // a session cookie was used by another client
+)
+
+// alertEventTypes lists the event types an alert may subscribe to.
+var alertEventTypes = []string{EventTrigger, EventAlarm, EventTest, EventArm, EventDisarm, EventFault, EventLoginFailed, EventAdminChange, EventSessionLimit, EventSessionMismatch}
+
+// alertEventClasses maps names that subscribe an alert to a group of event
+// types at once.
+var alertEventClasses = map[string][]string{
+    "security": {EventLoginFailed, EventAdminChange, EventSessionLimit, EventSessionMismatch},
+}
+
+// defaultAlertEvents is the subscription of an alert with no Events filter:
+// the zone events that were delivered before filters existed, plus faults
+// so that a failing alert is always reported somewhere.
+var defaultAlertEvents = []string{EventTrigger, EventAlarm, EventTest, EventFault}
+
+// alertEvents returns the effective event subscription of ac, with event
+// classes expanded.  Voice calls are too intrusive for anything but alarms,
+// so that is their default.  Alerts with a digest schedule also receive
+// digests.
+func alertEvents(ac AlertConfig) []string {
+    var events []string
+    if ac.Digest != nil {
+        events = append(events, EventDigest)
+    }
+    if len(ac.Events) == 0 {
+        if strings.EqualFold(ac.Type, "voice") {
+            return append(events, EventAlarm)
+        }
+        return append(events, defaultAlertEvents...)
+    }
+    for _, e := range ac.Events {
+        if class, ok := alertEventClasses[e]; ok {
+            events = append(events, class...)
+        } else {
+            events = append(events, e)
+        }
+    }
+    return events
+}
+
+// validateAlertEvents rejects event names that no event type or class
+// matches.
+func validateAlertEvents(events []string) error {
+    for _, e := range events {
+        _, known := alertEventClasses[e]
+        for _, t := range alertEventTypes {
+            if e == t {
+                known = true
+                break
+            }
+        }
+        if !known {
+            return fmt.Errorf("unknown event %q (expected security or one of %s)", e, strings.Join(alertEventTypes, ", "))
+        }
+    }
+    return nil
+}
+
+// Event severities used by handlers that distinguish urgency, e.g. through
+// message priority or colour.
+const (
+    SeverityCritical = "critical"
+    SeverityWarning  = "warning"
+    SeverityInfo     = "info"
+)
+
+// eventSeverity maps an event type to its severity.  Alarms and triggers are
+// critical, faults, failed logins and session limits and mismatches are
+// warnings, and everything else is informational.
+func eventSeverity(eventType string) string {
+    switch eventType {
+    case EventAlarm, EventTrigger:
+        return SeverityCritical
+    case EventFault, EventLoginFailed, EventSessionLimit, EventSessionMismatch:
+        return SeverityWarning
+    default:
+        return SeverityInfo
+    }
+}
+
+// defaultAlertTimeout bounds how long a single delivery by an alert handler
+// may take, including connecting, so that a dead or blackholed endpoint
+// cannot hang alert dispatch.  Config.AlertTimeout and AlertConfig.Timeout
+// override it.
+const defaultAlertTimeout = 15 * time.Second
+
+// errAlertTimeout is wrapped by the error of a delivery that ran out of time,
+// so that timeouts can be told apart from endpoints rejecting an alert.
+var errAlertTimeout = errors.New("alert timed out")
+
+// alertTimeout returns the delivery timeout configured for ac.
+func alertTimeout(ac AlertConfig) time.Duration {
+    if ac.Timeout > 0 {
+        return time.Duration(ac.Timeout) * time.Second
+    }
+    return defaultAlertTimeout
+}
+
+// timeoutError wraps err in errAlertTimeout if it reports a timeout.  Other
+// errors, and nil, are returned unchanged.
+func timeoutError(err error) error {
+    if err == nil || errors.Is(err, errAlertTimeout) {
+        return err
+    }
+    var ne net.Error
+    if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()) {
+        return fmt.Errorf("%w: %v", errAlertTimeout, err)
+    }
+    return err
+}
+
+// AlertEvent describes an occurrence that alert handlers should report.
+// Zone is the zone involved, Mode is the arm mode at the time of the event
+// and User is the user that caused it, if any.  Snapshot is the zone's
+// camera image, if one was captured.
+type AlertEvent struct {
+    Type     string
+    Zone     Zone
+    Mode     string
+    User     string
+    Time     time.Time
+    Snapshot *Snapshot
+}
+
 // AlertHandler represents a mechanism that can send an alert when a zone is
 // triggered.  Implementations may deliver notifications via email, SMS or
-// other channels.  The Send method receives the zone that was triggered and
-// a logger to record any diagnostics.  If an error is returned, the caller
+// other channels.  The Send method receives the event to report and a
+// logger to record any diagnostics.  If an error is returned, the caller
 // should log it but continue operation.
 type AlertHandler interface {
     Name() string
-    Send(zone Zone, logger *EventLogger) error
+    Send(event AlertEvent, logger *EventLogger) error
 }
 
 // LogAlert logs a simple message to the event logger when a zone triggers.
 // This is the default alert handler if no other alerts are configured.
-type LogAlert struct{}
+type LogAlert struct {
+    templates *alertTemplates
+}
 
 // Name returns the type name of the alert handler.
 func (LogAlert) Name() string { return "log" }
 
 // Send writes an alert to the event log.
-func (LogAlert) Send(zone Zone, logger *EventLogger) error {
-    logger.Log("alert: zone %d (%s) triggered", zone.ID, zone.Name)
+func (l LogAlert) Send(event AlertEvent, logger *EventLogger) error {
+    msg := fmt.Sprintf("zone %d (%s) triggered", event.Zone.ID, event.Zone.Name)
+    logger.Log("alert: %s", l.templates.Body(event, msg, logger))
     return nil
 }
 
+// SMTP TLS modes for EmailAlert.  "starttls" upgrades a plaintext
+// connection and refuses to continue if the server does not offer STARTTLS;
+// "tls" uses implicit TLS from the first byte (typically port 465); "none"
+// sends without encryption and should only be used with a local relay.
+const (
+    SMTPTLSStartTLS = "starttls"
+    SMTPTLSImplicit = "tls"
+    SMTPTLSNone     = "none"
+)
+
 // EmailAlert sends an email via an SMTP server when a zone triggers.  All
 // configuration values are supplied via the corresponding AlertConfig in
-// config.json.  The subject defaults to "Minder alert" if empty.
+// config.json.  The subject defaults to "Minder alert" if empty.  TLSMode
+// defaults to "tls" on port 465 and "starttls" otherwise.  CAFile optionally
+// names a PEM bundle used instead of the system roots to verify the server,
+// for self-hosted mail servers with a private CA.  Every recipient in To,
+// Cc and Bcc is addressed in a single SMTP transaction; Bcc recipients do
+// not appear in the headers.
 type EmailAlert struct {
     SMTPServer string
     SMTPPort   int
     Username   string
     Password   string
     From       string
-    To         string
+    To         []string
+    Cc         []string
+    Bcc        []string
     Subject    string
+    TLSMode    string
+    CAFile     string
+    // Timeout bounds the whole SMTP conversation, from dialling to QUIT.
+    // If zero, defaultAlertTimeout is used.
+    Timeout    time.Duration
+    // Users, if set, adds the users' email addresses to To.
+    Users      userDirectory
+    templates  *alertTemplates
 }
 
 // Name returns the type name of the alert handler.
 func (EmailAlert) Name() string { return "email" }
 
-// Send dispatches an email.  It composes a minimal plaintext message with a
-// subject and body describing the triggered zone.  Errors from smtp.SendMail
-// are returned directly so the caller can log them.
-func (e EmailAlert) Send(zone Zone, logger *EventLogger) error {
+// Send dispatches an email.  It composes a plaintext message with a
+// subject and body describing the triggered zone.  Connection, TLS and SMTP
+// errors are returned directly so the caller can log them.
+func (e EmailAlert) Send(event AlertEvent, logger *EventLogger) error {
     subject := e.Subject
     if subject == "" {
         subject = "Minder alert"
     }
-    body := fmt.Sprintf("Zone %s (ID %d) has been triggered", zone.Name, zone.ID)
-    // Compose headers and body.  RFC 5322 requires CRLF line endings.
-    msg := fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s\r\n", e.To, subject, body)
-    addr := fmt.Sprintf("%s:%d", e.SMTPServer, e.SMTPPort)
-    auth := smtp.PlainAuth("", e.Username, e.Password, e.SMTPServer)
-    return smtp.SendMail(addr, auth, e.From, []string{e.To}, []byte(msg))
-}
\ No newline at end of file
+    body := fmt.Sprintf("Zone %s (ID %d) has been triggered", event.Zone.Name, event.Zone.ID)
+    if event.Type == EventDigest {
+        // The summary of the day is carried as the zone name.
+        subject, body = "Minder daily digest", event.Zone.Name
+    }
+    subject = e.templates.Subject(event, subject, logger)
+    body = e.templates.Body(event, body, logger)
+    e.To = appendUnique(e.To, e.Users.emails(event)...)
+    msg, err := e.compose(subject, body, event.Time, event.Snapshot)
+    if err != nil {
+        return err
+    }
+    var rcpts []string
+    rcpts = append(rcpts, e.To...)
+    rcpts = append(rcpts, e.Cc...)
+    rcpts = append(rcpts, e.Bcc...)
+    if len(rcpts) == 0 {
+        return errors.New("email alert has no recipients")
+    }
+    return e.sendMail(rcpts, msg)
+}
+
+// compose builds an RFC 5322 message with From, To, Cc, Date, Message-ID
+// and Subject headers.  Non-ASCII subjects are encoded per RFC 2047.  When
+// a snapshot is given the message is multipart/mixed with the image as an
+// attachment.
+func (e EmailAlert) compose(subject, body string, date time.Time, snapshot *Snapshot) ([]byte, error) {
+    id, err := randomString(18)
+    if err != nil {
+        return nil, err
+    }
+    domain := "minder.local"
+    if at := strings.LastIndex(e.From, "@"); at >= 0 && at < len(e.From)-1 {
+        domain = strings.TrimSuffix(e.From[at+1:], ">")
+    }
+    if date.IsZero() {
+        date = time.Now()
+    }
+    var b strings.Builder
+    // RFC 5322 requires CRLF line endings.
+    header := func(name, value string) {
+        b.WriteString(name + ": " + value + "\r\n")
+    }
+    header("From", e.From)
+    if len(e.To) > 0 {
+        header("To", strings.Join(e.To, ", "))
+    }
+    if len(e.Cc) > 0 {
+        header("Cc", strings.Join(e.Cc, ", "))
+    }
+    header("Date", date.Format(time.RFC1123Z))
+    header("Message-ID", fmt.Sprintf("<%s@%s>", id, domain))
+    header("Subject", mime.QEncoding.Encode("utf-8", subject))
+    header("MIME-Version", "1.0")
+    text := strings.ReplaceAll(body, "\n", "\r\n") + "\r\n"
+    if snapshot == nil {
+        header("Content-Type", "text/plain; charset=UTF-8")
+        header("Content-Transfer-Encoding", "8bit")
+        b.WriteString("\r\n")
+        b.WriteString(text)
+        return []byte(b.String()), nil
+    }
+    var parts bytes.Buffer
+    mw := multipart.NewWriter(&parts)
+    header("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+    b.WriteString("\r\n")
+    pw, err := mw.CreatePart(textproto.MIMEHeader{
+        "Content-Type":              {"text/plain; charset=UTF-8"},
+        "Content-Transfer-Encoding": {"8bit"},
+    })
+    if err != nil {
+        return nil, err
+    }
+    pw.Write([]byte(text))
+    pw, err = mw.CreatePart(textproto.MIMEHeader{
+        "Content-Type":              {snapshot.ContentType},
+        "Content-Transfer-Encoding": {"base64"},
+        "Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", snapshot.Filename())},
+    })
+    if err != nil {
+        return nil, err
+    }
+    // Base64 bodies are wrapped at 76 characters per RFC 2045.
+    enc := base64.StdEncoding.EncodeToString(snapshot.Data)
+    for len(enc) > 76 {
+        pw.Write([]byte(enc[:76] + "\r\n"))
+        enc = enc[76:]
+    }
+    pw.Write([]byte(enc + "\r\n"))
+    if err := mw.Close(); err != nil {
+        return nil, err
+    }
+    b.Write(parts.Bytes())
+    return []byte(b.String()), nil
+}
+
+// tlsMode returns the effective TLS mode.
+func (e EmailAlert) tlsMode() string {
+    mode := strings.ToLower(e.TLSMode)
+    if mode == "" {
+        if e.SMTPPort == 465 {
+            return SMTPTLSImplicit
+        }
+        return SMTPTLSStartTLS
+    }
+    return mode
+}
+
+// tlsConfig builds the TLS configuration used to verify the mail server.
+func (e EmailAlert) tlsConfig() (*tls.Config, error) {
+    cfg := &tls.Config{
+        ServerName: e.SMTPServer,
+        MinVersion: tls.VersionTLS12,
+    }
+    if e.CAFile != "" {
+        pem, err := os.ReadFile(e.CAFile)
+        if err != nil {
+            return nil, fmt.Errorf("reading CA file: %w", err)
+        }
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(pem) {
+            return nil, fmt.Errorf("no certificates found in %s", e.CAFile)
+        }
+        cfg.RootCAs = pool
+    }
+    return cfg, nil
+}
+
+// sendMail drives an SMTP conversation by hand so that the TLS mode, the
+// certificate verification and the deadline are under our control, which
+// smtp.SendMail does not allow.
+func (e EmailAlert) sendMail(to []string, msg []byte) error {
+    mode := e.tlsMode()
+    tlsCfg, err := e.tlsConfig()
+    if err != nil {
+        return err
+    }
+    addr := net.JoinHostPort(e.SMTPServer, strconv.Itoa(e.SMTPPort))
+    timeout := e.Timeout
+    if timeout <= 0 {
+        timeout = defaultAlertTimeout
+    }
+    dialer := &net.Dialer{Timeout: timeout}
+    var conn net.Conn
+    switch mode {
+    case SMTPTLSImplicit:
+        conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsCfg)
+    case SMTPTLSStartTLS, SMTPTLSNone:
+        conn, err = dialer.Dial("tcp", addr)
+    default:
+        return fmt.Errorf("unknown SMTP TLS mode %q", e.TLSMode)
+    }
+    if err != nil {
+        return err
+    }
+    // A single deadline covers every read and write of the conversation.
+    if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+        conn.Close()
+        return err
+    }
+    c, err := smtp.NewClient(conn, e.SMTPServer)
+    if err != nil {
+        conn.Close()
+        return err
+    }
+    defer c.Close()
+    if mode == SMTPTLSStartTLS {
+        if ok, _ := c.Extension("STARTTLS"); !ok {
+            return fmt.Errorf("SMTP server %s does not support STARTTLS", e.SMTPServer)
+        }
+        if err := c.StartTLS(tlsCfg); err != nil {
+            return err
+        }
+    }
+    if e.Username != "" {
+        if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, e.SMTPServer)); err != nil {
+            return err
+        }
+    }
+    if err := c.Mail(e.From); err != nil {
+        return err
+    }
+    for _, rcpt := range to {
+        if err := c.Rcpt(rcpt); err != nil {
+            return err
+        }
+    }
+    wc, err := c.Data()
+    if err != nil {
+        return err
+    }
+    if _, err := wc.Write(msg); err != nil {
+        return err
+    }
+    if err := wc.Close(); err != nil {
+        return err
+    }
+    return c.Quit()
+}
@@ -0,0 +1,487 @@
+package main
+
+// This file implements the HTTP endpoints for managing and exercising alert
+// handlers.
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// redactedSecret replaces credentials in alert configurations returned by
+// the API.  When it is sent back unchanged in a PUT the stored value is
+// kept.
+const redactedSecret = "********"
+
+// alertTestResult reports the outcome of sending a test alert through one
+// handler.
+type alertTestResult struct {
+    Index int    `json:"index"`
+    ID    int    `json:"id"`
+    Name  string `json:"name"`
+    OK    bool   `json:"ok"`
+    Error string `json:"error,omitempty"`
+}
+
+// handleAlerts handles GET and POST on /api/alerts.  Admins only, as alert
+// configurations carry credentials.
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request, user User) {
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    switch r.Method {
+    case http.MethodGet:
+        cfg := s.cfgMgr.Get()
+        alerts := make([]AlertConfig, len(cfg.Alerts))
+        for i, ac := range cfg.Alerts {
+            alerts[i] = redactAlertConfig(ac)
+            alerts[i].Events = alertEvents(ac)
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(alerts)
+    case http.MethodPost:
+        if s.configReadOnly(w) {
+            return
+        }
+        var ac AlertConfig
+        if err := json.NewDecoder(r.Body).Decode(&ac); err != nil {
+            http.Error(w, "invalid JSON", http.StatusBadRequest)
+            return
+        }
+        if err := validateAlertConfig(ac, s.cfgMgr.Get()); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        err := s.cfgMgr.Update(func(c *Config) error {
+            maxID := 0
+            for _, existing := range c.Alerts {
+                if existing.ID > maxID {
+                    maxID = existing.ID
+                }
+            }
+            ac.ID = maxID + 1
+            c.Alerts = append(c.Alerts, ac)
+            return nil
+        })
+        if err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        s.logger.Log("create alert %s (id=%d) by %s", ac.Type, ac.ID, user.Username)
+        s.audit(r, user.Username, "alert.create", fmt.Sprintf("alert %d", ac.ID), nil, redactAlertConfig(ac))
+        w.WriteHeader(http.StatusCreated)
+        _ = json.NewEncoder(w).Encode(redactAlertConfig(ac))
+    default:
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+// handleAlertByID handles PUT and DELETE on /api/alerts/{id} and POST on
+// /api/alerts/{id-or-name}/test.  Admins only.
+func (s *Server) handleAlertByID(w http.ResponseWriter, r *http.Request, user User) {
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+    if len(parts) == 4 && parts[3] == "test" {
+        s.handleAlertTest(w, r, user, parts[2])
+        return
+    }
+    if len(parts) != 3 {
+        http.NotFound(w, r)
+        return
+    }
+    id, err := strconv.Atoi(parts[2])
+    if err != nil {
+        http.Error(w, "invalid id", http.StatusBadRequest)
+        return
+    }
+    switch r.Method {
+    case http.MethodPut:
+        if s.configReadOnly(w) {
+            return
+        }
+        var ac AlertConfig
+        if err := json.NewDecoder(r.Body).Decode(&ac); err != nil {
+            http.Error(w, "invalid JSON", http.StatusBadRequest)
+            return
+        }
+        cfg := s.cfgMgr.Get()
+        found := false
+        var before AlertConfig
+        for _, existing := range cfg.Alerts {
+            if existing.ID == id {
+                before = existing
+                ac = restoreAlertSecrets(ac, existing)
+                found = true
+                break
+            }
+        }
+        if !found {
+            http.Error(w, "not found", http.StatusNotFound)
+            return
+        }
+        ac.ID = id
+        if err := validateAlertConfig(ac, cfg); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        var updated []AlertConfig
+        for _, existing := range cfg.Alerts {
+            if existing.ID == id {
+                existing = ac
+            }
+            updated = append(updated, existing)
+        }
+        if err := validateEscalation(cfg.Escalation, updated); err != nil {
+            http.Error(w, err.Error(), http.StatusConflict)
+            return
+        }
+        err = s.cfgMgr.Update(func(c *Config) error {
+            for i, existing := range c.Alerts {
+                if existing.ID == id {
+                    c.Alerts[i] = ac
+                    return nil
+                }
+            }
+            return errors.New("not found")
+        })
+        if err != nil {
+            if err.Error() == "not found" {
+                http.Error(w, "not found", http.StatusNotFound)
+            } else {
+                http.Error(w, "internal error", http.StatusInternalServerError)
+            }
+            return
+        }
+        s.logger.Log("update alert %s (id=%d) by %s", ac.Type, id, user.Username)
+        s.audit(r, user.Username, "alert.update", fmt.Sprintf("alert %d", id), redactAlertConfig(before), redactAlertConfig(ac))
+        w.WriteHeader(http.StatusNoContent)
+    case http.MethodDelete:
+        if s.configReadOnly(w) {
+            return
+        }
+        cfg := s.cfgMgr.Get()
+        var remaining []AlertConfig
+        for _, existing := range cfg.Alerts {
+            if existing.ID != id {
+                remaining = append(remaining, existing)
+            }
+        }
+        if err := validateEscalation(cfg.Escalation, remaining); err != nil {
+            http.Error(w, err.Error(), http.StatusConflict)
+            return
+        }
+        var removed AlertConfig
+        err = s.cfgMgr.Update(func(c *Config) error {
+            for i, existing := range c.Alerts {
+                if existing.ID == id {
+                    removed = existing
+                    c.Alerts = append(c.Alerts[:i], c.Alerts[i+1:]...)
+                    return nil
+                }
+            }
+            return errors.New("not found")
+        })
+        if err != nil {
+            if err.Error() == "not found" {
+                http.Error(w, "not found", http.StatusNotFound)
+            } else {
+                http.Error(w, "internal error", http.StatusInternalServerError)
+            }
+            return
+        }
+        s.logger.Log("delete alert %s (id=%d) by %s", removed.Type, id, user.Username)
+        s.audit(r, user.Username, "alert.delete", fmt.Sprintf("alert %d", id), redactAlertConfig(removed), nil)
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+// handleAlertTest sends a test alert through the single handler identified
+// by key.
+func (s *Server) handleAlertTest(w http.ResponseWriter, r *http.Request, user User, key string) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    handlers, ids, names := s.alertQueue.current()
+    idx, err := findAlertHandler(ids, names, key)
+    if err != nil {
+        if err.Error() == "not found" {
+            http.Error(w, "not found", http.StatusNotFound)
+        } else {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+        }
+        return
+    }
+    res := s.testAlertHandler(idx, ids[idx], names[idx], handlers[idx], user.Username)
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(res)
+}
+
+// handleAlertTestAll sends a test alert through every configured handler
+// and returns the individual results.  Admins only.
+func (s *Server) handleAlertTestAll(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    handlers, ids, names := s.alertQueue.current()
+    results := make([]alertTestResult, len(handlers))
+    for i, h := range handlers {
+        results[i] = s.testAlertHandler(i, ids[i], names[i], h, user.Username)
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(results)
+}
+
+// alertName returns the name an alert is addressed by: its Name if set,
+// otherwise its type, in lower case.
+func alertName(ac AlertConfig) string {
+    if ac.Name != "" {
+        return strings.ToLower(ac.Name)
+    }
+    return strings.ToLower(ac.Type)
+}
+
+// findAlertHandler resolves a path segment to a handler index.  The segment
+// is either an alert ID or an alert name such as "email"; a name must match
+// exactly one handler.
+func findAlertHandler(ids []int, names []string, key string) (int, error) {
+    if id, err := strconv.Atoi(key); err == nil {
+        for i := range ids {
+            if ids[i] == id {
+                return i, nil
+            }
+        }
+        return 0, errors.New("not found")
+    }
+    found := -1
+    for i, name := range names {
+        if strings.EqualFold(name, key) {
+            if found >= 0 {
+                return 0, errors.New("several alerts are named " + key + ", use the id")
+            }
+            found = i
+        }
+    }
+    if found < 0 {
+        return 0, errors.New("not found")
+    }
+    return found, nil
+}
+
+// testAlertHandler sends a synthetic test event through a single handler,
+// bypassing the retry queue, and logs the outcome with a "test" marker so
+// it cannot be mistaken for a real alarm.
+func (s *Server) testAlertHandler(idx, id int, name string, h AlertHandler, username string) alertTestResult {
+    event := AlertEvent{
+        Type: EventTest,
+        Zone: Zone{ID: 0, Name: "Test alert", Type: ZoneTypeContact, Enabled: true},
+        Mode: s.currentMode,
+        User: username,
+        Time: time.Now(),
+    }
+    res := alertTestResult{Index: idx, ID: id, Name: name, OK: true}
+    err := s.alertQueue.sendWithTimeout(h, event)
+    s.alertQueue.recordResult(id, err)
+    if err != nil {
+        res.OK = false
+        res.Error = redactAlertSecrets(err.Error(), s.cfgMgr.Get().Alerts)
+        s.logger.Log("test alert %d (%s) by %s failed: %s", id, name, username, res.Error)
+    } else {
+        s.logger.Log("test alert %d (%s) by %s sent", id, name, username)
+    }
+    return res
+}
+
+// validateAlertConfig checks that ac has a known type, the fields that type
+// requires (see AlertConfig) and valid templates.
+func validateAlertConfig(ac AlertConfig, cfg Config) error {
+    missing := func(field string) error {
+        return errors.New(ac.Type + " alert requires " + field)
+    }
+    switch strings.ToLower(ac.Type) {
+    case "log":
+    case "email":
+        if ac.SMTPServer == "" || ac.SMTPPort == 0 {
+            return missing("smtp_server and smtp_port")
+        }
+        if ac.From == "" || (len(ac.To) == 0 && !ac.NotifyUsers) {
+            return missing("from and to (or notify_users)")
+        }
+    case "webhook", "slack":
+        if ac.URL == "" {
+            return missing("url")
+        }
+    case "sms", "voice":
+        if ac.AccountSID == "" || ac.AuthToken == "" {
+            return missing("account_sid and auth_token")
+        }
+        if ac.From == "" || (len(ac.ToNumbers) == 0 && !ac.NotifyUsers) {
+            return missing("from and to_numbers (or notify_users)")
+        }
+    case "ntfy":
+        if ac.Topic == "" {
+            return missing("topic")
+        }
+    case "gotify":
+        if ac.URL == "" || ac.Token == "" {
+            return missing("url and token")
+        }
+    case "discord":
+        if ac.URL == "" && len(ac.URLs) == 0 {
+            return missing("url or urls")
+        }
+    case "matrix":
+        if ac.URL == "" || ac.Token == "" || ac.RoomID == "" {
+            return missing("url, token and room_id")
+        }
+    case "pushbullet":
+        if ac.Token == "" {
+            return missing("token")
+        }
+        if ac.Device != "" && ac.Channel != "" {
+            return errors.New("pushbullet alert accepts device or channel, not both")
+        }
+    case "signal":
+        if ac.Address == "" || ac.From == "" {
+            return missing("address and from")
+        }
+        if len(ac.ToNumbers) == 0 && ac.GroupID == "" {
+            return missing("to_numbers or group_id")
+        }
+    case "mqtt":
+        if ac.URL == "" && (cfg.MQTT == nil || cfg.MQTT.Broker == "") {
+            return missing("url unless the mqtt section is configured")
+        }
+    case "":
+        return errors.New("missing type")
+    default:
+        return errors.New("unknown alert type " + ac.Type)
+    }
+    return validateAlertOptions(ac)
+}
+
+// validateAlertOptions checks the settings common to every alert type:
+// event filter, heartbeat, digest, timeout and templates.
+func validateAlertOptions(ac AlertConfig) error {
+    if err := validateAlertEvents(ac.Events); err != nil {
+        return err
+    }
+    if err := validateHeartbeat(ac.Heartbeat); err != nil {
+        return err
+    }
+    if err := validateDigest(ac.Digest); err != nil {
+        return err
+    }
+    if ac.Timeout < 0 {
+        return fmt.Errorf("timeout must not be negative")
+    }
+    _, err := newAlertTemplates(ac)
+    return err
+}
+
+// redactAlertConfig returns a copy of ac with its credentials replaced by
+// redactedSecret: passwords, tokens and secrets, webhook header values,
+// and the URLs of Slack and Discord, which embed their credential.
+func redactAlertConfig(ac AlertConfig) AlertConfig {
+    for _, field := range []*string{&ac.Password, &ac.AuthToken, &ac.Token, &ac.Secret} {
+        if *field != "" {
+            *field = redactedSecret
+        }
+    }
+    if alertURLsSecret(ac) {
+        if ac.URL != "" {
+            ac.URL = redactedSecret
+        }
+        if len(ac.URLs) > 0 {
+            urls := make([]string, len(ac.URLs))
+            for i := range urls {
+                urls[i] = redactedSecret
+            }
+            ac.URLs = urls
+        }
+    }
+    if len(ac.Headers) > 0 {
+        headers := make(map[string]string, len(ac.Headers))
+        for name := range ac.Headers {
+            headers[name] = redactedSecret
+        }
+        ac.Headers = headers
+    }
+    return ac
+}
+
+// alertURLsSecret reports whether the URLs of ac are credentials, as the
+// webhook URLs of Slack and Discord are.
+func alertURLsSecret(ac AlertConfig) bool {
+    t := strings.ToLower(ac.Type)
+    return t == "slack" || t == "discord"
+}
+
+// restoreAlertSecrets copies credentials from existing into ac wherever ac
+// still holds the redacted placeholder.
+func restoreAlertSecrets(ac, existing AlertConfig) AlertConfig {
+    if ac.Password == redactedSecret {
+        ac.Password = existing.Password
+    }
+    if ac.AuthToken == redactedSecret {
+        ac.AuthToken = existing.AuthToken
+    }
+    if ac.Token == redactedSecret {
+        ac.Token = existing.Token
+    }
+    if ac.Secret == redactedSecret {
+        ac.Secret = existing.Secret
+    }
+    if ac.URL == redactedSecret {
+        ac.URL = existing.URL
+    }
+    for i, u := range ac.URLs {
+        if u == redactedSecret && i < len(existing.URLs) {
+            ac.URLs[i] = existing.URLs[i]
+        }
+    }
+    for name, value := range ac.Headers {
+        if value == redactedSecret {
+            ac.Headers[name] = existing.Headers[name]
+        }
+    }
+    return ac
+}
+
+// redactAlertSecrets removes any password, token or secret from the alert
+// configurations that appears in msg.  Handler errors often quote the
+// request URL, which for Slack and Discord is itself a credential.
+func redactAlertSecrets(msg string, alerts []AlertConfig) string {
+    for _, ac := range alerts {
+        secrets := []string{ac.Password, ac.AuthToken, ac.Token, ac.Secret}
+        if alertURLsSecret(ac) {
+            secrets = append(secrets, ac.URL)
+            secrets = append(secrets, ac.URLs...)
+        }
+        for _, value := range ac.Headers {
+            secrets = append(secrets, value)
+        }
+        for _, secret := range secrets {
+            if len(secret) >= 4 {
+                msg = strings.ReplaceAll(msg, secret, "[redacted]")
+            }
+        }
+    }
+    return msg
+}
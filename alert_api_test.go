@@ -0,0 +1,36 @@
+package main
+
+// Tests of the redaction of alert credentials returned by the API, and
+// their restoration when sent back.
+
+import (
+    "encoding/json"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestRedactAlertConfig(t *testing.T) {
+    alerts := []AlertConfig{
+        {ID: 1, Type: "slack", URL: "https://hooks.slack.com/services/T0/B0/slack-cred"},
+        {ID: 2, Type: "discord", URLs: []string{"https://discord.com/api/webhooks/1/discord-cred-1", "https://discord.com/api/webhooks/2/discord-cred-2"}},
+        {ID: 3, Type: "webhook", URL: "https://example.org/hook", Secret: "hmac-cred", Headers: map[string]string{"Authorization": "Bearer header-cred"}},
+    }
+    for _, ac := range alerts {
+        before, _ := json.Marshal(ac)
+        redacted := redactAlertConfig(ac)
+        data, _ := json.Marshal(redacted)
+        if strings.Contains(string(data), "cred") {
+            t.Errorf("alert %d: secret in %s", ac.ID, data)
+        }
+        if after, _ := json.Marshal(ac); string(after) != string(before) {
+            t.Errorf("alert %d changed by redaction: %s", ac.ID, after)
+        }
+        if got := restoreAlertSecrets(redacted, ac); !reflect.DeepEqual(got, ac) {
+            t.Errorf("alert %d restored as %+v", ac.ID, got)
+        }
+    }
+    if got := redactAlertConfig(alerts[2]).URL; got != alerts[2].URL {
+        t.Errorf("plain webhook URL redacted to %q", got)
+    }
+}
@@ -0,0 +1,199 @@
+package main
+
+// This file implements an alert handler posting embeds to Discord webhooks.
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Discord allows 30 requests per minute per webhook.  Requests beyond that
+// are delayed until the window allows them rather than dropped.
+const (
+    discordRateLimit  = 30
+    discordRateWindow = time.Minute
+    // discordMaxWait caps the time a post spends waiting for its webhook,
+    // whether for the window or after a 429 response, so that the send
+    // stays well within alertSendTimeout.  A post that would wait longer
+    // fails at once, and the alert queue retries the event later.
+    discordMaxWait = 10 * time.Second
+)
+
+// errDiscordRateLimited is returned for a webhook that cannot be posted to
+// within discordMaxWait.
+var errDiscordRateLimited = errors.New("discord rate limited")
+
+// Embed colours: red for alarms, amber for faults and grey for
+// informational events.
+var discordColours = map[string]int{
+    SeverityCritical: 0xd00000,
+    SeverityWarning:  0xf2c744,
+    SeverityInfo:     0x9e9e9e,
+}
+
+// DiscordAlert posts an embed describing each event to one or more Discord
+// webhooks.  Each webhook is rate limited independently.
+type DiscordAlert struct {
+    URLs   []string
+    client *http.Client
+    tmpl   *alertTemplates
+
+    mu     sync.Mutex
+    limits map[string]*discordLimiter // per webhook URL
+}
+
+// discordLimiter holds the recent and booked send times of one webhook.
+type discordLimiter struct {
+    mu   sync.Mutex
+    sent []time.Time
+}
+
+// NewDiscordAlert constructs a DiscordAlert from its configuration.  The
+// single URL field and the URLs list are combined.
+func NewDiscordAlert(ac AlertConfig, tmpl *alertTemplates) *DiscordAlert {
+    var urls []string
+    if ac.URL != "" {
+        urls = append(urls, ac.URL)
+    }
+    urls = append(urls, ac.URLs...)
+    return &DiscordAlert{
+        URLs:   urls,
+        client: &http.Client{Timeout: alertTimeout(ac)},
+        tmpl:   tmpl,
+        limits: make(map[string]*discordLimiter),
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*DiscordAlert) Name() string { return "discord" }
+
+// Send posts the event to every webhook.  Failures for individual webhooks
+// are combined into the returned error.
+func (d *DiscordAlert) Send(event AlertEvent, logger *EventLogger) error {
+    title := "Minder " + event.Type
+    if event.Zone.Name != "" {
+        title += ": " + event.Zone.Name
+    }
+    embed := map[string]any{
+        "title":     d.tmpl.Subject(event, title, logger),
+        "color":     discordColours[eventSeverity(event.Type)],
+        "timestamp": event.Time.Format(time.RFC3339),
+        "fields": []map[string]any{
+            {"name": "Zone", "value": orDash(event.Zone.Name), "inline": true},
+            {"name": "Type", "value": orDash(string(event.Zone.Type)), "inline": true},
+            {"name": "Mode", "value": orDash(event.Mode), "inline": true},
+        },
+    }
+    if text := d.tmpl.Body(event, "", logger); text != "" {
+        embed["description"] = text
+    }
+    body, err := json.Marshal(map[string]any{"embeds": []any{embed}})
+    if err != nil {
+        return err
+    }
+    var errs []error
+    for _, u := range d.URLs {
+        if err := d.post(u, body); err != nil {
+            errs = append(errs, err)
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// post waits for the webhook's rate limit window and sends body.  A 429
+// response is retried once after the advised delay.  No more than
+// discordMaxWait is spent waiting in all.
+func (d *DiscordAlert) post(url string, body []byte) error {
+    l := d.limiter(url)
+    var waited time.Duration
+    for attempt := 0; attempt < 2; attempt++ {
+        delay, err := l.reserve(time.Now(), discordMaxWait-waited)
+        if err != nil {
+            return err
+        }
+        time.Sleep(delay)
+        waited += delay
+        resp, err := d.client.Post(url, "application/json", bytes.NewReader(body))
+        if err != nil {
+            return fmt.Errorf("discord: %w", err)
+        }
+        detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        resp.Body.Close()
+        if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+            var rl struct {
+                RetryAfter float64 `json:"retry_after"`
+            }
+            _ = json.Unmarshal(detail, &rl)
+            delay := time.Duration(rl.RetryAfter * float64(time.Second))
+            if delay <= 0 {
+                delay = time.Second
+            }
+            if waited+delay > discordMaxWait {
+                return errDiscordRateLimited
+            }
+            time.Sleep(delay)
+            waited += delay
+            continue
+        }
+        if resp.StatusCode < 200 || resp.StatusCode > 299 {
+            return fmt.Errorf("discord returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+        }
+        return nil
+    }
+    return errDiscordRateLimited
+}
+
+// limiter returns the rate limiter of url.
+func (d *DiscordAlert) limiter(url string) *discordLimiter {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    l := d.limits[url]
+    if l == nil {
+        l = &discordLimiter{}
+        d.limits[url] = l
+    }
+    return l
+}
+
+// reserve books the earliest send at or after now that keeps the webhook
+// within discordRateLimit requests per discordRateWindow, and returns how
+// long to wait for it.  Booking rather than sleeping under the lock keeps
+// bursts ordered without holding up other senders.  A send that would
+// have to wait longer than max is not booked and errDiscordRateLimited is
+// returned.
+func (l *discordLimiter) reserve(now time.Time, max time.Duration) (time.Duration, error) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    recent := l.sent[:0]
+    for _, t := range l.sent {
+        if now.Sub(t) < discordRateWindow {
+            recent = append(recent, t)
+        }
+    }
+    l.sent = recent
+    at := now
+    if n := len(recent); n >= discordRateLimit {
+        at = recent[n-discordRateLimit].Add(discordRateWindow)
+    }
+    if at.Sub(now) > max {
+        return 0, errDiscordRateLimited
+    }
+    l.sent = append(l.sent, at)
+    return at.Sub(now), nil
+}
+
+// orDash returns s, or "-" if s is empty.  Discord rejects embed fields with
+// empty values.
+func orDash(s string) string {
+    if s == "" {
+        return "-"
+    }
+    return s
+}
@@ -0,0 +1,132 @@
+package main
+
+// This file moves alert delivery off the caller's goroutine so that a slow
+// handler cannot stall sensor polling.
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Dispatcher sizing.  alertQueueSize bounds the number of events waiting
+// for a worker; when it is full the oldest event is dropped.
+const (
+    alertWorkers     = 4
+    alertQueueSize   = 64
+    alertSendTimeout = 45 * time.Second
+)
+
+// alertJob is an event waiting for a dispatcher worker.  When only is
+// non-nil the event goes to the named handlers alone; see dispatch.
+type alertJob struct {
+    event AlertEvent
+    only  map[string]bool
+}
+
+// startWorkers creates the job channel and the worker pool that drains it.
+// Once the queue is stopped the workers deliver the jobs still waiting and
+// return.
+func (q *alertQueue) startWorkers() {
+    q.jobs = make(chan alertJob, alertQueueSize)
+    for i := 0; i < alertWorkers; i++ {
+        q.workers.Add(1)
+        q.loops.Add(1)
+        go func() {
+            defer q.loops.Done()
+            defer q.workers.Done()
+            for {
+                select {
+                case job := <-q.jobs:
+                    q.dispatch(job)
+                case <-q.stop:
+                    for {
+                        select {
+                        case job := <-q.jobs:
+                            q.dispatch(job)
+                        default:
+                            return
+                        }
+                    }
+                }
+            }
+        }()
+    }
+}
+
+// Stop ends the retry loop and lets the workers finish the jobs already
+// queued; loops is done once they have and the delivery status is saved.
+// Retries still pending are abandoned.
+func (q *alertQueue) Stop() {
+    q.stopOnce.Do(func() { close(q.stop) })
+}
+
+// enqueue hands event to the worker pool without blocking, subject to the
+// rate limit (see alert_ratelimit.go).
+func (q *alertQueue) enqueue(event AlertEvent) {
+    if !q.limit(event) {
+        return
+    }
+    q.enqueueJob(alertJob{event: event})
+}
+
+// enqueueTo hands event to the worker pool for delivery to the handlers
+// with the given names only.  Such targeted deliveries, escalation tiers
+// and fault reports, are not rate limited.
+func (q *alertQueue) enqueueTo(event AlertEvent, names []string) {
+    only := make(map[string]bool, len(names))
+    for _, name := range names {
+        only[strings.ToLower(name)] = true
+    }
+    q.enqueueJob(alertJob{event: event, only: only})
+}
+
+// enqueueJob queues job without blocking.  If the queue is full the oldest
+// waiting job is discarded to make room, on the basis that the newest event
+// best reflects the current state of the system.
+func (q *alertQueue) enqueueJob(job alertJob) {
+    for {
+        select {
+        case q.jobs <- job:
+            return
+        default:
+        }
+        select {
+        case old := <-q.jobs:
+            q.logger.Log("alert queue full, dropping %s alert for zone %s", old.event.Type, old.event.Zone.Name)
+        default:
+        }
+    }
+}
+
+// alertTimeouter is implemented by handlers whose deliveries legitimately
+// take longer than alertSendTimeout, such as voice calls.
+type alertTimeouter interface {
+    Timeout() time.Duration
+}
+
+// sendWithTimeout calls h.Send but gives up after alertSendTimeout, or the
+// handler's own timeout, so that a hung handler cannot hold a worker
+// indefinitely.  The abandoned send is left to finish in the background.
+// Timeouts, whether reported by the handler or by this guard, wrap
+// errAlertTimeout.
+func (q *alertQueue) sendWithTimeout(h AlertHandler, event AlertEvent) error {
+    timeout := alertSendTimeout
+    if t, ok := h.(alertTimeouter); ok && t.Timeout() > timeout {
+        timeout = t.Timeout()
+    }
+    // Alert messages show the time in the household's time zone.
+    event.Time = localTime(event.Time)
+    done := make(chan error, 1)
+    go func() {
+        done <- h.Send(event, q.logger)
+    }()
+    timer := time.NewTimer(timeout)
+    defer timer.Stop()
+    select {
+    case err := <-done:
+        return timeoutError(err)
+    case <-timer.C:
+        return fmt.Errorf("%w: no result after %s", errAlertTimeout, timeout)
+    }
+}
@@ -0,0 +1,158 @@
+package main
+
+// Tests of the alert dispatcher: a slow or hung handler must not hold up
+// the caller, which in the server is the sensor polling loop.
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+)
+
+// slowHandler is an alert handler taking delay over each delivery, or
+// blocking until release is closed if delay is negative.
+type slowHandler struct {
+    delay   time.Duration
+    release chan struct{}
+    mu      sync.Mutex
+    sent    int
+}
+
+func (h *slowHandler) Name() string { return "slow" }
+
+func (h *slowHandler) Send(event AlertEvent, logger *EventLogger) error {
+    if h.delay < 0 {
+        <-h.release
+    } else {
+        time.Sleep(h.delay)
+    }
+    h.mu.Lock()
+    h.sent++
+    h.mu.Unlock()
+    return nil
+}
+
+// inTempDir runs the rest of the test in a temporary directory, so that
+// files such as alert_status.json do not land in the source tree.
+func inTempDir(t *testing.T) string {
+    dir := t.TempDir()
+    wd, err := os.Getwd()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := os.Chdir(dir); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { os.Chdir(wd) })
+    return dir
+}
+
+func TestEnqueueLatencyWithSlowHandler(t *testing.T) {
+    tests := []struct {
+        name  string
+        delay time.Duration
+    }{
+        {"fast", 0},
+        {"slow", 100 * time.Millisecond},
+        {"hung", -1},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := inTempDir(t)
+            logger := NewEventLogger(filepath.Join(dir, "events.log"))
+            h := &slowHandler{delay: tt.delay, release: make(chan struct{})}
+            q := newAlertQueue([]AlertHandler{h}, []int{1}, Config{}, logger)
+            defer func() {
+                close(h.release)
+                q.Stop()
+                q.loops.Wait()
+            }()
+            // Twice the queue size, so that the drop-oldest path is taken
+            // whenever the handler cannot keep up.
+            var worst time.Duration
+            for i := 0; i < 2*alertQueueSize; i++ {
+                start := time.Now()
+                q.enqueue(AlertEvent{Type: EventAlarm, Zone: Zone{ID: i, Name: fmt.Sprintf("Zone %d", i)}, Time: start})
+                if d := time.Since(start); d > worst {
+                    worst = d
+                }
+            }
+            if worst > 50*time.Millisecond {
+                t.Errorf("enqueue took up to %s with a %s handler", worst, tt.name)
+            }
+        })
+    }
+}
+
+func TestEnqueueDropsOldest(t *testing.T) {
+    dir := inTempDir(t)
+    q := &alertQueue{
+        jobs:   make(chan alertJob, 2),
+        logger: NewEventLogger(filepath.Join(dir, "events.log")),
+    }
+    for i := 1; i <= 3; i++ {
+        q.enqueueJob(alertJob{event: AlertEvent{Type: EventAlarm, Zone: Zone{ID: i}}})
+    }
+    var got []int
+    for len(q.jobs) > 0 {
+        got = append(got, (<-q.jobs).event.Zone.ID)
+    }
+    if len(got) != 2 || got[0] != 2 || got[1] != 3 {
+        t.Errorf("queued zones %v, want [2 3]", got)
+    }
+}
+
+func TestDispatchDelivers(t *testing.T) {
+    dir := inTempDir(t)
+    logger := NewEventLogger(filepath.Join(dir, "events.log"))
+    h := &slowHandler{release: make(chan struct{})}
+    q := newAlertQueue([]AlertHandler{h}, []int{1}, Config{}, logger)
+    for i := 0; i < 5; i++ {
+        q.enqueue(AlertEvent{Type: EventAlarm, Zone: Zone{ID: i}, Time: time.Now()})
+    }
+    // Stop lets the workers finish the jobs already queued.
+    q.Stop()
+    q.loops.Wait()
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if h.sent != 5 {
+        t.Errorf("%d alerts delivered, want 5", h.sent)
+    }
+}
+
+func TestSetHandlersKeepsRetries(t *testing.T) {
+    dir := inTempDir(t)
+    q := &alertQueue{logger: NewEventLogger(filepath.Join(dir, "events.log"))}
+    q.loadStatus()
+    a, b := &slowHandler{}, &slowHandler{}
+    q.setHandlers([]AlertHandler{a, b}, []int{1, 2}, Config{})
+    q.pending = []*queuedAlert{
+        {handler: 0, event: AlertEvent{Type: EventAlarm, Zone: Zone{ID: 10}}},
+        {handler: 1, event: AlertEvent{Type: EventAlarm, Zone: Zone{ID: 20}}},
+    }
+    tests := []struct {
+        name string
+        ids  []int
+        want map[int]int // zone ID to alert ID
+    }{
+        {"reordered", []int{2, 1}, map[int]int{10: 1, 20: 2}},
+        {"removed", []int{2}, map[int]int{20: 2}},
+    }
+    for _, tt := range tests {
+        handlers := make([]AlertHandler, len(tt.ids))
+        for i := range handlers {
+            handlers[i] = &slowHandler{}
+        }
+        q.setHandlers(handlers, tt.ids, Config{})
+        got := make(map[int]int)
+        for _, qa := range q.pending {
+            got[qa.event.Zone.ID] = q.ids[qa.handler]
+        }
+        if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+            t.Errorf("%s: retries %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,79 @@
+package main
+
+// This file implements an alert handler pushing messages to a Gotify server.
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+)
+
+// gotifyPriorities maps event severity to Gotify message priority.  Gotify
+// clients typically treat priority 8 and above as high importance.
+var gotifyPriorities = map[string]int{
+    SeverityCritical: 8,
+    SeverityWarning:  5,
+    SeverityInfo:     2,
+}
+
+// GotifyAlert posts events to the /message endpoint of a Gotify server
+// using an application token.
+type GotifyAlert struct {
+    Server string
+    Token  string
+    client *http.Client
+    tmpl   *alertTemplates
+}
+
+// gotifyMessage is the JSON document accepted by Gotify's /message endpoint.
+type gotifyMessage struct {
+    Title    string `json:"title"`
+    Message  string `json:"message"`
+    Priority int    `json:"priority"`
+}
+
+// NewGotifyAlert constructs a GotifyAlert from its configuration.
+func NewGotifyAlert(ac AlertConfig, tmpl *alertTemplates) *GotifyAlert {
+    return &GotifyAlert{
+        Server: strings.TrimRight(ac.URL, "/"),
+        Token:  ac.Token,
+        client: &http.Client{Timeout: alertTimeout(ac)},
+        tmpl:   tmpl,
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*GotifyAlert) Name() string { return "gotify" }
+
+// Send posts the event to Gotify.  Connection errors and non-200 responses
+// are returned so the caller can log them.
+func (g *GotifyAlert) Send(event AlertEvent, logger *EventLogger) error {
+    msg := gotifyMessage{
+        Title:    g.tmpl.Subject(event, "Minder alarm: "+event.Zone.Name, logger),
+        Message:  g.tmpl.Body(event, fmt.Sprintf("Zone %s (ID %d) %s event while %s at %s", event.Zone.Name, event.Zone.ID, event.Type, event.Mode, event.Time.Format("15:04:05")), logger),
+        Priority: gotifyPriorities[eventSeverity(event.Type)],
+    }
+    body, err := json.Marshal(msg)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequest(http.MethodPost, g.Server+"/message", bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("X-Gotify-Key", g.Token)
+    resp, err := g.client.Do(req)
+    if err != nil {
+        return fmt.Errorf("gotify %s: %w", g.Server, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("gotify returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+    }
+    return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+// Tests of the Gotify alert handler against a fake server.
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestGotifySend(t *testing.T) {
+    tests := []struct {
+        name     string
+        event    string
+        status   int
+        priority int
+        wantErr  string
+    }{
+        {"alarm", EventAlarm, http.StatusOK, 8, ""},
+        {"fault", EventFault, http.StatusOK, 5, ""},
+        {"test", EventTest, http.StatusOK, 2, ""},
+        {"refused", EventAlarm, http.StatusUnauthorized, 8, "401"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var got gotifyMessage
+            var key, path string
+            srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                key, path = r.Header.Get("X-Gotify-Key"), r.URL.Path
+                if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+                    t.Errorf("decoding body: %v", err)
+                }
+                w.WriteHeader(tt.status)
+            }))
+            defer srv.Close()
+            g := NewGotifyAlert(AlertConfig{URL: srv.URL + "/", Token: "app-token"}, nil)
+            event := AlertEvent{Type: tt.event, Zone: Zone{ID: 3, Name: "Hall"}, Mode: "Away", Time: time.Now()}
+            err := g.Send(event, nil)
+            switch {
+            case tt.wantErr == "" && err != nil:
+                t.Fatalf("Send: %v", err)
+            case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+                t.Fatalf("Send error %v, want one containing %q", err, tt.wantErr)
+            }
+            if path != "/message" {
+                t.Errorf("path %q, want /message", path)
+            }
+            if key != "app-token" {
+                t.Errorf("X-Gotify-Key %q, want app-token", key)
+            }
+            if got.Title != "Minder alarm: Hall" {
+                t.Errorf("title %q", got.Title)
+            }
+            if got.Priority != tt.priority {
+                t.Errorf("priority %d, want %d", got.Priority, tt.priority)
+            }
+            if !strings.Contains(got.Message, "Hall (ID 3)") {
+                t.Errorf("message %q does not name the zone", got.Message)
+            }
+        })
+    }
+}
+
+func TestGotifyConnectionError(t *testing.T) {
+    srv := httptest.NewServer(http.NotFoundHandler())
+    srv.Close()
+    g := NewGotifyAlert(AlertConfig{URL: srv.URL, Token: "x"}, nil)
+    if err := g.Send(AlertEvent{Type: EventAlarm, Time: time.Now()}, nil); err == nil {
+        t.Fatal("Send to a closed server succeeded")
+    }
+}
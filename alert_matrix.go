@@ -0,0 +1,136 @@
+package main
+
+// This file implements an alert handler posting messages to a Matrix room
+// through the client-server API.
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "html"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync/atomic"
+    "time"
+)
+
+// matrixMaxRetryAfter caps how long a rate limited message waits before
+// its single retry.
+const matrixMaxRetryAfter = 30 * time.Second
+
+// matrixTxnSeq distinguishes transaction IDs created within the same
+// nanosecond.
+var matrixTxnSeq int64
+
+// MatrixAlert sends each event as a message to a Matrix room, as the user
+// owning the access token.  The user must already have joined the room.
+type MatrixAlert struct {
+    Homeserver string
+    Token      string
+    RoomID     string
+    client     *http.Client
+    tmpl       *alertTemplates
+}
+
+// matrixMessage is the content of an m.room.message event.
+type matrixMessage struct {
+    MsgType       string `json:"msgtype"`
+    Body          string `json:"body"`
+    Format        string `json:"format,omitempty"`
+    FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+// matrixError is the standard error response of the client-server API.
+type matrixError struct {
+    ErrCode      string `json:"errcode"`
+    Error        string `json:"error"`
+    RetryAfterMS int64  `json:"retry_after_ms"`
+}
+
+// NewMatrixAlert constructs a MatrixAlert from its configuration.
+func NewMatrixAlert(ac AlertConfig, tmpl *alertTemplates) *MatrixAlert {
+    return &MatrixAlert{
+        Homeserver: strings.TrimRight(ac.URL, "/"),
+        Token:      ac.Token,
+        RoomID:     ac.RoomID,
+        client:     &http.Client{Timeout: alertTimeout(ac)},
+        tmpl:       tmpl,
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*MatrixAlert) Name() string { return "matrix" }
+
+// Send posts the event to the room.  Informational events are sent as
+// m.notice, which clients and bots treat as low priority.  If the
+// homeserver responds with M_LIMIT_EXCEEDED the message is retried once
+// after the advised interval, under the same transaction ID so that it
+// cannot be posted twice.
+func (m *MatrixAlert) Send(event AlertEvent, logger *EventLogger) error {
+    subject := m.tmpl.Subject(event, fmt.Sprintf("Minder %s: %s", event.Type, event.Zone.Name), logger)
+    text := m.tmpl.Body(event, fmt.Sprintf("Zone %s (ID %d) %s event while %s at %s", event.Zone.Name, event.Zone.ID, event.Type, event.Mode, event.Time.Format("15:04:05")), logger)
+    msg := matrixMessage{
+        MsgType:       "m.text",
+        Body:          subject + "\n" + text,
+        Format:        "org.matrix.custom.html",
+        FormattedBody: "<strong>" + html.EscapeString(subject) + "</strong><br>" + html.EscapeString(text),
+    }
+    if eventSeverity(event.Type) == SeverityInfo {
+        msg.MsgType = "m.notice"
+    }
+    body, err := json.Marshal(msg)
+    if err != nil {
+        return err
+    }
+    txn := fmt.Sprintf("minder-%d-%d", time.Now().UnixNano(), atomic.AddInt64(&matrixTxnSeq, 1))
+    retryAfter, err := m.put(txn, body)
+    if err == nil || retryAfter == 0 {
+        return err
+    }
+    time.Sleep(retryAfter)
+    _, err = m.put(txn, body)
+    return err
+}
+
+// put sends one m.room.message event.  When the homeserver rate limits the
+// request it returns a non-zero retry interval together with the error.
+func (m *MatrixAlert) put(txn string, body []byte) (time.Duration, error) {
+    endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", m.Homeserver, url.PathEscape(m.RoomID), url.PathEscape(txn))
+    req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(body))
+    if err != nil {
+        return 0, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Authorization", "Bearer "+m.Token)
+    resp, err := m.client.Do(req)
+    if err != nil {
+        return 0, fmt.Errorf("matrix %s: %w", m.Homeserver, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusOK {
+        return 0, nil
+    }
+    detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+    var merr matrixError
+    _ = json.Unmarshal(detail, &merr)
+    switch {
+    case merr.ErrCode == "M_LIMIT_EXCEEDED" || resp.StatusCode == http.StatusTooManyRequests:
+        wait := time.Duration(merr.RetryAfterMS) * time.Millisecond
+        if wait <= 0 {
+            wait = time.Second
+        }
+        if wait > matrixMaxRetryAfter {
+            wait = matrixMaxRetryAfter
+        }
+        return wait, fmt.Errorf("matrix rate limited: %s", resp.Status)
+    case merr.ErrCode == "M_UNKNOWN_TOKEN" || merr.ErrCode == "M_MISSING_TOKEN" || resp.StatusCode == http.StatusUnauthorized:
+        return 0, fmt.Errorf("matrix rejected the access token (%s): check the token of this alert", merr.ErrCode)
+    case merr.ErrCode == "M_FORBIDDEN":
+        return 0, fmt.Errorf("matrix refused to post to room %s, has the user joined it? %s", m.RoomID, merr.Error)
+    case merr.ErrCode != "":
+        return 0, fmt.Errorf("matrix returned %s: %s %s", resp.Status, merr.ErrCode, merr.Error)
+    }
+    return 0, fmt.Errorf("matrix returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+}
@@ -0,0 +1,119 @@
+package main
+
+// This file implements an alert handler publishing events to MQTT.
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// mqttDefaultEventTopic is the topic prefix used when none is configured.
+const mqttDefaultEventTopic = "minder/events"
+
+// MQTTAlert publishes a JSON description of each event to
+// <prefix>/<event type>.  QoS and retain flags can be chosen per event type;
+// the "*" key supplies a default for types not listed.  Without explicit
+// configuration alarms and triggers are published at QoS 1 and everything
+// else at QoS 0, none retained.
+type MQTTAlert struct {
+    client *MQTTClient
+    own    bool // client is a dedicated connection, closed by Close
+    prefix string
+    qos    map[string]int
+    retain map[string]bool
+    tmpl   *alertTemplates
+}
+
+// mqttEventPayload is the JSON document published for each event.
+type mqttEventPayload struct {
+    Type      string      `json:"type"`
+    Zone      webhookZone `json:"zone"`
+    Mode      string      `json:"mode"`
+    User      string      `json:"user,omitempty"`
+    Timestamp string      `json:"timestamp"`
+    Message   string      `json:"message"`
+}
+
+// NewMQTTAlert constructs an MQTTAlert.  If the server already has a shared
+// MQTT connection it is reused; otherwise a dedicated connection is opened
+// to the broker given in the alert's URL.
+func NewMQTTAlert(ac AlertConfig, tmpl *alertTemplates, shared *MQTTClient, logger *EventLogger) *MQTTAlert {
+    client, own := shared, false
+    if client == nil {
+        own = true
+        client = NewMQTTClient(MQTTConfig{
+            Broker:   ac.URL,
+            Username: ac.Username,
+            Password: ac.Password,
+        }, logger)
+    }
+    prefix := strings.TrimRight(ac.Topic, "/")
+    if prefix == "" {
+        prefix = mqttDefaultEventTopic
+    }
+    return &MQTTAlert{
+        client: client,
+        own:    own,
+        prefix: prefix,
+        qos:    ac.QoS,
+        retain: ac.Retain,
+        tmpl:   tmpl,
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*MQTTAlert) Name() string { return "mqtt" }
+
+// Send publishes the event.  While the broker is unreachable the event is
+// buffered by the shared client and delivered after reconnection.
+func (m *MQTTAlert) Send(event AlertEvent, logger *EventLogger) error {
+    payload, err := json.Marshal(mqttEventPayload{
+        Type: event.Type,
+        Zone: webhookZone{
+            ID:   event.Zone.ID,
+            Name: event.Zone.Name,
+            Type: event.Zone.Type,
+        },
+        Mode:      event.Mode,
+        User:      event.User,
+        Timestamp: event.Time.Format(time.RFC3339),
+        Message:   m.tmpl.Body(event, fmt.Sprintf("Zone %s (ID %d) %s", event.Zone.Name, event.Zone.ID, event.Type), logger),
+    })
+    if err != nil {
+        return err
+    }
+    qos, retain := m.options(event.Type)
+    return m.client.Publish(m.prefix+"/"+event.Type, qos, retain, payload)
+}
+
+// Close disconnects the handler's dedicated connection, if it has one.  The
+// shared connection is left to the server.
+func (m *MQTTAlert) Close() {
+    if m.own {
+        m.client.Close()
+    }
+}
+
+// options returns the QoS and retain flag for an event type.
+func (m *MQTTAlert) options(eventType string) (byte, bool) {
+    qos := 0
+    if eventSeverity(eventType) == SeverityCritical {
+        qos = 1
+    }
+    if q, ok := m.qos["*"]; ok {
+        qos = q
+    }
+    if q, ok := m.qos[eventType]; ok {
+        qos = q
+    }
+    if qos < 0 || qos > 2 {
+        qos = 1
+    }
+    retain := m.retain["*"]
+    if r, ok := m.retain[eventType]; ok {
+        retain = r
+    }
+    return byte(qos), retain
+}
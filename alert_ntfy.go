@@ -0,0 +1,132 @@
+package main
+
+// This file implements an alert handler publishing to an ntfy server.
+
+import (
+    "bytes"
+    "crypto/tls"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+)
+
+// ntfyDefaultServer is used when no server URL is configured.
+const ntfyDefaultServer = "https://ntfy.sh"
+
+// ntfy message priorities.  See https://docs.ntfy.sh/publish/#message-priority
+const (
+    ntfyPriorityDefault = 3
+    ntfyPriorityHigh    = 4
+)
+
+// NtfyAlert publishes events to an ntfy topic using the JSON publishing
+// API.  Alarm and trigger events are sent at high priority with a siren
+// emoji tag; other events are sent at default priority.
+type NtfyAlert struct {
+    Server   string
+    Topic    string
+    Token    string
+    Priority int
+    Tags     []string
+    client   *http.Client
+    tmpl     *alertTemplates
+}
+
+// ntfyMessage is the JSON document accepted by an ntfy server's root URL.
+type ntfyMessage struct {
+    Topic    string   `json:"topic"`
+    Title    string   `json:"title"`
+    Message  string   `json:"message"`
+    Priority int      `json:"priority"`
+    Tags     []string `json:"tags,omitempty"`
+}
+
+// NewNtfyAlert constructs an NtfyAlert from its configuration.  When
+// InsecureSkipVerify is set the HTTP client accepts self-signed server
+// certificates.
+func NewNtfyAlert(ac AlertConfig, tmpl *alertTemplates) *NtfyAlert {
+    server := strings.TrimRight(ac.URL, "/")
+    if server == "" {
+        server = ntfyDefaultServer
+    }
+    priority := ac.Priority
+    if priority < 1 || priority > 5 {
+        priority = ntfyPriorityHigh
+    }
+    client := &http.Client{Timeout: alertTimeout(ac)}
+    if ac.InsecureSkipVerify {
+        client.Transport = &http.Transport{
+            TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+        }
+    }
+    return &NtfyAlert{
+        Server:   server,
+        Topic:    ac.Topic,
+        Token:    ac.Token,
+        Priority: priority,
+        Tags:     ac.Tags,
+        client:   client,
+        tmpl:     tmpl,
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*NtfyAlert) Name() string { return "ntfy" }
+
+// Send publishes the event.  Transport errors and non-2xx responses are
+// returned so the caller can log them.
+func (n *NtfyAlert) Send(event AlertEvent, logger *EventLogger) error {
+    msg := ntfyMessage{
+        Topic:    n.Topic,
+        Priority: ntfyPriorityDefault,
+    }
+    switch event.Type {
+    case EventAlarm, EventTrigger:
+        msg.Title = "Minder alarm: " + event.Zone.Name
+        msg.Message = fmt.Sprintf("Zone %s (ID %d) triggered while armed %s", event.Zone.Name, event.Zone.ID, event.Mode)
+        msg.Priority = n.Priority
+        msg.Tags = []string{"rotating_light"}
+    case EventTest:
+        msg.Title = "Minder test: " + event.Zone.Name
+        msg.Message = fmt.Sprintf("Test trigger of zone %s (ID %d)", event.Zone.Name, event.Zone.ID)
+        msg.Tags = []string{"test_tube"}
+    case EventArm:
+        msg.Title = "Minder armed"
+        msg.Message = fmt.Sprintf("System armed %s by %s", event.Mode, event.User)
+        msg.Tags = []string{"lock"}
+    case EventDisarm:
+        msg.Title = "Minder disarmed"
+        msg.Message = fmt.Sprintf("System disarmed by %s", event.User)
+        msg.Tags = []string{"unlock"}
+    default:
+        msg.Title = "Minder " + event.Type
+        msg.Message = fmt.Sprintf("%s: zone %s", event.Type, event.Zone.Name)
+    }
+    msg.Title = n.tmpl.Subject(event, msg.Title, logger)
+    msg.Message = n.tmpl.Body(event, msg.Message, logger)
+    msg.Tags = append(msg.Tags, n.Tags...)
+    body, err := json.Marshal(msg)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequest(http.MethodPost, n.Server, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    if n.Token != "" {
+        req.Header.Set("Authorization", "Bearer "+n.Token)
+    }
+    resp, err := n.client.Do(req)
+    if err != nil {
+        return fmt.Errorf("ntfy %s: %w", n.Server, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("ntfy returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+    }
+    return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+// This file implements an alert handler sending Pushbullet pushes.
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+)
+
+// pushbulletAPI is the Pushbullet endpoint pushes are created at.
+const pushbulletAPI = "https://api.pushbullet.com/v2/pushes"
+
+// PushbulletAlert creates a push for each event, to every device of the
+// account owning Token unless Device or Channel narrows the target.  When
+// StatusURL is set the push is a link to the Minder status page rather
+// than a plain note.
+type PushbulletAlert struct {
+    Token     string
+    Device    string
+    Channel   string
+    StatusURL string
+    client    *http.Client
+    tmpl      *alertTemplates
+}
+
+// pushbulletPush is the JSON document accepted by the pushes endpoint.
+type pushbulletPush struct {
+    Type       string `json:"type"`
+    Title      string `json:"title"`
+    Body       string `json:"body"`
+    URL        string `json:"url,omitempty"`
+    DeviceIden string `json:"device_iden,omitempty"`
+    ChannelTag string `json:"channel_tag,omitempty"`
+}
+
+// NewPushbulletAlert constructs a PushbulletAlert from its configuration.
+// baseURL is Config.BaseURL, the address the Minder UI is reached at.
+func NewPushbulletAlert(ac AlertConfig, tmpl *alertTemplates, baseURL string) *PushbulletAlert {
+    p := &PushbulletAlert{
+        Token:   ac.Token,
+        Device:  ac.Device,
+        Channel: ac.Channel,
+        client:  &http.Client{Timeout: alertTimeout(ac)},
+        tmpl:    tmpl,
+    }
+    if baseURL != "" {
+        p.StatusURL = strings.TrimRight(baseURL, "/") + "/"
+    }
+    return p
+}
+
+// Name returns the type name of the alert handler.
+func (*PushbulletAlert) Name() string { return "pushbullet" }
+
+// Send creates the push.  Error responses, including the 401 returned for
+// a revoked token, are returned verbatim so that the delivery status
+// shows why the handler is failing.
+func (p *PushbulletAlert) Send(event AlertEvent, logger *EventLogger) error {
+    push := pushbulletPush{
+        Type:       "note",
+        Title:      p.tmpl.Subject(event, event.Zone.Name, logger),
+        Body:       p.tmpl.Body(event, fmt.Sprintf("Minder %s event in zone %s (ID %d) while %s at %s", event.Type, event.Zone.Name, event.Zone.ID, event.Mode, event.Time.Format("15:04:05")), logger),
+        DeviceIden: p.Device,
+        ChannelTag: p.Channel,
+    }
+    if p.StatusURL != "" {
+        push.Type = "link"
+        push.URL = p.StatusURL
+    }
+    body, err := json.Marshal(push)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequest(http.MethodPost, pushbulletAPI, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Access-Token", p.Token)
+    resp, err := p.client.Do(req)
+    if err != nil {
+        return fmt.Errorf("pushbullet: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("pushbullet returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+    }
+    return nil
+}
@@ -0,0 +1,418 @@
+package main
+
+// This file implements the retry queue for alerts that could not be
+// delivered on the first attempt.
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Retry defaults used when Config.AlertRetry is absent or leaves a field
+// zero.
+const (
+    defaultAlertMaxAttempts = 10
+    defaultAlertMaxAge      = time.Hour
+    alertRetryBaseDelay     = 5 * time.Second
+    alertRetryMaxDelay      = 5 * time.Minute
+)
+
+// queuedAlert is an event waiting to be redelivered to a single handler.
+type queuedAlert struct {
+    handler  int // index into the handler slice
+    event    AlertEvent
+    attempts int
+    next     time.Time
+}
+
+// alertHandlerStatus records the delivery history of one handler for
+// GET /api/alerts/status.  The counters cover the calendar day Day and are
+// reset when the first attempt of a new day is recorded.
+type alertHandlerStatus struct {
+    Index         int              `json:"index"`
+    ID            int              `json:"id"`
+    Name          string           `json:"name"`
+    Queued        int              `json:"queued"`
+    LastAttemptAt *time.Time       `json:"last_attempt_at,omitempty"`
+    LastSuccessAt *time.Time       `json:"last_success_at,omitempty"`
+    LastErrorAt   *time.Time       `json:"last_error_at,omitempty"`
+    LastError     string           `json:"last_error,omitempty"`
+    Day           string           `json:"day,omitempty"`
+    SentToday     int              `json:"sent_today"`
+    FailedToday   int              `json:"failed_today"`
+    Heartbeat     *heartbeatStatus `json:"heartbeat,omitempty"`
+}
+
+// failing reports whether the most recent delivery attempt failed.
+func (st alertHandlerStatus) failing() bool {
+    return st.LastErrorAt != nil && (st.LastSuccessAt == nil || st.LastSuccessAt.Before(*st.LastErrorAt))
+}
+
+// alertQueue delivers events to the alert handlers and holds failed
+// deliveries for retry with exponential backoff.  The queue lives in
+// memory: it survives subsequent triggers but not a restart (the delivery
+// status is snapshotted to disk, see alert_status.go).  Entries are
+// dropped, with a log entry, after maxAttempts deliveries or once the event
+// is older than maxAge, so the queue cannot grow without bound.
+type alertQueue struct {
+    mu          sync.Mutex
+    handlers    []AlertHandler
+    ids         []int    // alert configuration ID of each handler
+    names       []string // alert configuration name of each handler
+    escalated   map[string]bool // names reached only through escalation
+    events      []map[string]bool // event subscription of each handler
+    status      []alertHandlerStatus
+    saved       map[int]alertHandlerStatus // status by alert ID, see alert_status.go
+    dirty       bool // status changed since the last snapshot
+    pending     []*queuedAlert
+    gen         int // incremented whenever handlers is replaced
+    maxAttempts int
+    maxAge      time.Duration
+    logger      *EventLogger
+    jobs        chan alertJob // see alert_dispatch.go
+    stop        chan struct{} // closed by Stop
+    stopOnce    sync.Once
+    workers     sync.WaitGroup // the dispatcher workers
+    loops       sync.WaitGroup // the workers, retry and snapshot loops
+    limiter     *alertLimiter // nil if not limited, see alert_ratelimit.go
+}
+
+// newAlertQueue creates a queue for handlers and starts its worker pool and
+// retry loop.
+func newAlertQueue(handlers []AlertHandler, ids []int, cfg Config, logger *EventLogger) *alertQueue {
+    q := &alertQueue{
+        maxAttempts: defaultAlertMaxAttempts,
+        maxAge:      defaultAlertMaxAge,
+        logger:      logger,
+        limiter:     newAlertLimiter(cfg.AlertRateLimit),
+    }
+    if rc := cfg.AlertRetry; rc != nil {
+        if rc.MaxAttempts > 0 {
+            q.maxAttempts = rc.MaxAttempts
+        }
+        if rc.MaxAge > 0 {
+            q.maxAge = time.Duration(rc.MaxAge) * time.Second
+        }
+    }
+    q.loadStatus()
+    q.setHandlers(handlers, ids, cfg)
+    q.stop = make(chan struct{})
+    q.startWorkers()
+    q.loops.Add(2)
+    go q.retryLoop()
+    go q.snapshotLoop()
+    return q
+}
+
+// setHandlers replaces the handler set; ids gives the alert configuration
+// ID of each handler, which is looked up in cfg for its name and escalation
+// tier.  Pending retries and delivery status are carried over by alert ID;
+// retries for alerts that no longer exist, or no longer take the event, are
+// discarded.
+func (q *alertQueue) setHandlers(handlers []AlertHandler, ids []int, cfg Config) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    oldIDs := q.ids
+    q.handlers = handlers
+    q.ids = ids
+    q.names = make([]string, len(handlers))
+    q.events = make([]map[string]bool, len(handlers))
+    for i, id := range ids {
+        ac := AlertConfig{Type: "log"}
+        for _, c := range cfg.Alerts {
+            if c.ID == id {
+                ac = c
+            }
+        }
+        q.names[i] = alertName(ac)
+        q.events[i] = make(map[string]bool)
+        for _, e := range alertEvents(ac) {
+            q.events[i][e] = true
+        }
+    }
+    q.escalated = make(map[string]bool)
+    for _, tier := range cfg.Escalation {
+        for _, name := range tier.Alerts {
+            q.escalated[strings.ToLower(name)] = true
+        }
+    }
+    q.gen++
+    pending := q.pending
+    q.pending = nil
+    for _, qa := range pending {
+        q.requeue(qa, oldIDs[qa.handler])
+    }
+    if dropped := len(pending) - len(q.pending); dropped > 0 {
+        q.logger.Log("alert handlers changed, discarding %d queued alerts", dropped)
+    }
+    for _, st := range q.status {
+        q.saved[st.ID] = st
+    }
+    q.status = make([]alertHandlerStatus, len(handlers))
+    for i := range handlers {
+        st := q.saved[ids[i]]
+        st.Index, st.ID, st.Name, st.Heartbeat = i, ids[i], q.names[i], nil
+        q.status[i] = st
+    }
+}
+
+// requeue adds qa to the pending retries for the handler with alert ID id,
+// reporting false if there is none that takes the event.  q.mu must be
+// held.
+func (q *alertQueue) requeue(qa *queuedAlert, id int) bool {
+    for i := range q.ids {
+        if q.ids[i] == id && q.events[i][qa.event.Type] {
+            qa.handler = i
+            q.pending = append(q.pending, qa)
+            return true
+        }
+    }
+    return false
+}
+
+// current returns the handlers events are delivered to and their alert
+// configuration IDs and names.
+func (q *alertQueue) current() ([]AlertHandler, []int, []string) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    return q.handlers, q.ids, q.names
+}
+
+// dispatch delivers a job to its handlers: the handlers named in job.only
+// or, if that is nil, every handler except those reserved for escalation
+// when the event is a trigger or alarm.  Handlers not subscribed to the
+// event type are skipped in either case.  Failed deliveries are logged and
+// queued for retry; they do not stop delivery to the remaining handlers.
+// It is called by the dispatcher workers; use enqueue to send an event.
+func (q *alertQueue) dispatch(job alertJob) {
+    // Fetching the snapshot here, rather than when the event is queued,
+    // keeps camera latency off the polling goroutine.
+    event := attachSnapshot(job.event, q.logger)
+    q.mu.Lock()
+    handlers, ids, names, escalated, subscribed, gen := q.handlers, q.ids, q.names, q.escalated, q.events, q.gen
+    q.mu.Unlock()
+    for i, h := range handlers {
+        if !subscribed[i][event.Type] {
+            continue
+        }
+        if job.only != nil {
+            if !job.only[names[i]] {
+                continue
+            }
+        } else if escalated[names[i]] && (event.Type == EventTrigger || event.Type == EventAlarm) {
+            continue
+        }
+        err := q.sendWithTimeout(h, event)
+        if err != nil {
+            q.logger.Log("alert handler %s error: %v", h.Name(), err)
+        }
+        q.mu.Lock()
+        var qa *queuedAlert
+        if err != nil {
+            qa = &queuedAlert{
+                handler:  i,
+                event:    event,
+                attempts: 1,
+                next:     time.Now().Add(alertRetryBaseDelay),
+            }
+        }
+        switch {
+        case q.gen == gen:
+            q.record(i, err)
+            if qa != nil {
+                q.pending = append(q.pending, qa)
+            }
+        case qa != nil:
+            q.requeue(qa, ids[i])
+        }
+        q.mu.Unlock()
+    }
+}
+
+// record updates the status of handler i after a delivery attempt.  q.mu
+// must be held.
+func (q *alertQueue) record(i int, err error) {
+    now := time.Now()
+    st := &q.status[i]
+    if day := now.Format("2006-01-02"); st.Day != day {
+        st.Day, st.SentToday, st.FailedToday = day, 0, 0
+    }
+    st.LastAttemptAt = &now
+    if err != nil {
+        st.LastError = err.Error()
+        st.LastErrorAt = &now
+        st.FailedToday++
+    } else {
+        st.LastSuccessAt = &now
+        st.SentToday++
+    }
+    q.dirty = true
+}
+
+// recordResult records the outcome of a delivery made outside the
+// dispatcher, such as a test alert or heartbeat, against the handler with
+// alert ID id.
+func (q *alertQueue) recordResult(id int, err error) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    for i := range q.status {
+        if q.status[i].ID == id {
+            q.record(i, err)
+            return
+        }
+    }
+}
+
+// degraded reports whether the most recent delivery attempt of any handler
+// failed.
+func (q *alertQueue) degraded() bool {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    for _, st := range q.status {
+        if st.failing() {
+            return true
+        }
+    }
+    return false
+}
+
+// retryLoop redelivers queued alerts as they become due, until the queue
+// is stopped.
+func (q *alertQueue) retryLoop() {
+    defer q.loops.Done()
+    ticker := time.NewTicker(time.Second)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ticker.C:
+            q.retryDue()
+        case <-q.stop:
+            return
+        }
+    }
+}
+
+// retryDue makes one delivery attempt for every queued alert whose backoff
+// has expired and drops alerts that are too old or out of attempts.
+func (q *alertQueue) retryDue() {
+    now := time.Now()
+    q.mu.Lock()
+    var due []*queuedAlert
+    kept := q.pending[:0]
+    for _, qa := range q.pending {
+        switch {
+        case now.Sub(qa.event.Time) > q.maxAge:
+            q.logger.Log("alert handler %s: dropping %s alert for zone %s after %d attempts, older than %s",
+                q.handlers[qa.handler].Name(), qa.event.Type, qa.event.Zone.Name, qa.attempts, q.maxAge)
+        case now.Before(qa.next):
+            kept = append(kept, qa)
+        default:
+            due = append(due, qa)
+        }
+    }
+    q.pending = kept
+    handlers, ids, gen := q.handlers, q.ids, q.gen
+    q.mu.Unlock()
+
+    for _, qa := range due {
+        h := handlers[qa.handler]
+        err := q.sendWithTimeout(h, qa.event)
+        qa.attempts++
+        q.mu.Lock()
+        // If the handlers were replaced during the send, skip bookkeeping
+        // and carry a failed alert over to the handler's successor.
+        if q.gen != gen {
+            if err != nil && qa.attempts < q.maxAttempts {
+                qa.next = time.Now().Add(alertRetryDelay(qa.attempts))
+                q.requeue(qa, ids[qa.handler])
+            }
+            q.mu.Unlock()
+            continue
+        }
+        q.record(qa.handler, err)
+        switch {
+        case err == nil:
+            q.logger.Log("alert handler %s delivered %s alert for zone %s after %d attempts",
+                h.Name(), qa.event.Type, qa.event.Zone.Name, qa.attempts)
+        case qa.attempts >= q.maxAttempts:
+            q.logger.Log("alert handler %s: giving up on %s alert for zone %s after %d attempts: %v",
+                h.Name(), qa.event.Type, qa.event.Zone.Name, qa.attempts, err)
+        default:
+            qa.next = time.Now().Add(alertRetryDelay(qa.attempts))
+            q.pending = append(q.pending, qa)
+        }
+        q.mu.Unlock()
+    }
+}
+
+// alertRetryDelay returns the backoff before the next attempt after the
+// given number of failed attempts: 5s, 10s, 20s, ... capped at 5 minutes.
+func alertRetryDelay(attempts int) time.Duration {
+    d := alertRetryBaseDelay
+    for i := 1; i < attempts && d < alertRetryMaxDelay; i++ {
+        d *= 2
+    }
+    if d > alertRetryMaxDelay {
+        d = alertRetryMaxDelay
+    }
+    return d
+}
+
+// workingNames returns the names of the handlers, other than handler
+// except, whose most recent delivery did not fail.
+func (q *alertQueue) workingNames(except int) []string {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    var names []string
+    for i, st := range q.status {
+        if i == except {
+            continue
+        }
+        if st.failing() {
+            continue
+        }
+        names = append(names, q.names[i])
+    }
+    return names
+}
+
+// snapshot returns the per-handler status and the total queue depth.
+func (q *alertQueue) snapshot() ([]alertHandlerStatus, int) {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    out := make([]alertHandlerStatus, len(q.status))
+    copy(out, q.status)
+    for _, qa := range q.pending {
+        out[qa.handler].Queued++
+    }
+    return out, len(q.pending)
+}
+
+// handleAlertStatus reports the retry queue depth and the delivery and
+// heartbeat history of each alert handler.  Admins only, as error text may
+// reveal details of the alert configuration.
+func (s *Server) handleAlertStatus(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    handlers, depth := s.alertQueue.snapshot()
+    alerts := s.cfgMgr.Get().Alerts
+    for i := range handlers {
+        handlers[i].LastError = redactAlertSecrets(handlers[i].LastError, alerts)
+        handlers[i].Heartbeat = s.heartbeat(handlers[i].ID)
+    }
+    resp := struct {
+        QueueDepth int                  `json:"queue_depth"`
+        Handlers   []alertHandlerStatus `json:"handlers"`
+    }{depth, handlers}
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,210 @@
+package main
+
+// This file implements the global alert rate limit, which stops a burst of
+// events (typically a wiring fault triggering many zones at once) from
+// flooding every handler.
+
+import (
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Rate limit defaults used when Config.AlertRateLimit leaves a field zero.
+// Without the section events are not limited.
+const (
+    defaultAlertRatePerMinute = 10
+    defaultAlertRateBurst     = 10
+    // alertSummaryZones is the number of zones named in a summary before
+    // the rest are elided.
+    alertSummaryZones = 5
+)
+
+// alertLimiter is a token bucket over the events handed to the dispatcher.
+// Events arriving while the bucket is empty are held back and coalesced
+// into a single summary event, queued once a token is available again.
+type alertLimiter struct {
+    mu     sync.Mutex
+    rate   float64 // tokens added per second
+    burst  float64
+    tokens float64
+    last   time.Time
+    held   []AlertEvent // events coalesced into the next summary
+    timer  *time.Timer  // fires when the summary can be sent
+}
+
+// newAlertLimiter creates a full bucket configured by rc, or returns nil
+// for no limit if rc is nil.
+func newAlertLimiter(rc *AlertRateLimitConfig) *alertLimiter {
+    if rc == nil {
+        return nil
+    }
+    perMinute, burst := defaultAlertRatePerMinute, defaultAlertRateBurst
+    if rc.PerMinute > 0 {
+        perMinute = rc.PerMinute
+    }
+    if rc.Burst > 0 {
+        burst = rc.Burst
+    }
+    return &alertLimiter{
+        rate:   float64(perMinute) / 60,
+        burst:  float64(burst),
+        tokens: float64(burst),
+        last:   time.Now(),
+    }
+}
+
+// rateLimitExempt reports whether events of eventType bypass the limit.
+// Alarms are the events a household most needs to hear about, so they are
+// never held back.
+func rateLimitExempt(eventType string) bool {
+    return eventType == EventAlarm
+}
+
+// refill adds the tokens accrued since the last call.  l.mu must be held.
+func (l *alertLimiter) refill(now time.Time) {
+    l.tokens += now.Sub(l.last).Seconds() * l.rate
+    if l.tokens > l.burst {
+        l.tokens = l.burst
+    }
+    l.last = now
+}
+
+// allow takes a token for event and reports whether it may be sent now.
+// If not, the event is held for the summary and flush is scheduled to run
+// once a token is available.  While a summary is pending every further
+// event is held so that it is not overtaken by later ones.
+func (l *alertLimiter) allow(event AlertEvent, flush func()) bool {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.refill(time.Now())
+    if len(l.held) == 0 && l.tokens >= 1 {
+        l.tokens--
+        return true
+    }
+    l.held = append(l.held, event)
+    if l.timer == nil {
+        wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+        l.timer = time.AfterFunc(wait, flush)
+    }
+    return false
+}
+
+// summary takes a token and returns the held events, or nil if none are
+// held.  If the bucket has not yet refilled, flush is rescheduled.
+func (l *alertLimiter) summary(flush func()) []AlertEvent {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.timer = nil
+    if len(l.held) == 0 {
+        return nil
+    }
+    l.refill(time.Now())
+    if l.tokens < 1 {
+        wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+        l.timer = time.AfterFunc(wait, flush)
+        return nil
+    }
+    l.tokens--
+    held := l.held
+    l.held = nil
+    return held
+}
+
+// drain stops the pending summary, if any, and returns the held events.
+func (l *alertLimiter) drain() []AlertEvent {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.timer != nil {
+        l.timer.Stop()
+        l.timer = nil
+    }
+    held := l.held
+    l.held = nil
+    return held
+}
+
+// summaryEvent describes held events as a single event.  Its zone name
+// carries the description, e.g. "7 further zones triggered: kitchen,
+// landing, …", so that every handler reports it without changes.
+func summaryEvent(held []AlertEvent) AlertEvent {
+    allTriggers := true
+    seen := make(map[string]bool)
+    var zones []string
+    for _, e := range held {
+        if e.Type != EventTrigger {
+            allTriggers = false
+        }
+        if !seen[e.Zone.Name] {
+            seen[e.Zone.Name] = true
+            zones = append(zones, e.Zone.Name)
+        }
+    }
+    list := zones
+    if len(list) > alertSummaryZones {
+        list = append(list[:alertSummaryZones:alertSummaryZones], "…")
+    }
+    // The summary takes the type of the last held event, or trigger if any
+    // trigger was held, so that it reaches the handlers that would have
+    // reported the most urgent of them.
+    last := held[len(held)-1]
+    event := AlertEvent{Type: last.Type, Mode: last.Mode, User: last.User, Time: last.Time}
+    for _, e := range held {
+        if e.Type == EventTrigger {
+            event.Type = EventTrigger
+        }
+    }
+    if allTriggers {
+        event.Zone.Name = fmt.Sprintf("%d further zones triggered: %s", len(zones), strings.Join(list, ", "))
+    } else {
+        event.Zone.Name = fmt.Sprintf("%d further events held back by the rate limit: %s", len(held), strings.Join(list, ", "))
+    }
+    return event
+}
+
+// limit applies the rate limit to event and reports whether it may be
+// queued now.  Held events are logged so that the event log stays
+// complete.
+func (q *alertQueue) limit(event AlertEvent) bool {
+    if rateLimitExempt(event.Type) {
+        return true
+    }
+    q.mu.Lock()
+    l := q.limiter
+    q.mu.Unlock()
+    if l == nil || l.allow(event, func() { q.flushSummary(l) }) {
+        return true
+    }
+    q.logger.Log("alert rate limit reached, holding back %s alert for zone %s", event.Type, event.Zone.Name)
+    return false
+}
+
+// flushSummary queues the summary of the events held by l once the bucket
+// has refilled.
+func (q *alertQueue) flushSummary(l *alertLimiter) {
+    q.sendSummary(l.summary(func() { q.flushSummary(l) }))
+}
+
+// sendSummary queues the summary of held, if there are any.
+func (q *alertQueue) sendSummary(held []AlertEvent) {
+    if held == nil {
+        return
+    }
+    event := summaryEvent(held)
+    q.logger.Log("sending summary of %d rate limited alerts", len(held))
+    q.enqueueJob(alertJob{event: event})
+}
+
+// setRateLimit replaces the rate limit with one configured by rc, nil for
+// none.  Events held by the old limit are summarised straight away rather
+// than lost.
+func (q *alertQueue) setRateLimit(rc *AlertRateLimitConfig) {
+    q.mu.Lock()
+    old := q.limiter
+    q.limiter = newAlertLimiter(rc)
+    q.mu.Unlock()
+    if old != nil {
+        q.sendSummary(old.drain())
+    }
+}
@@ -0,0 +1,56 @@
+package main
+
+// Tests of the global alert rate limit.
+
+import (
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestRateLimit(t *testing.T) {
+    tests := []struct {
+        name   string
+        rc     *AlertRateLimitConfig
+        events int
+        queued int
+    }{
+        {"off by default", nil, 20, 20},
+        {"defaults", &AlertRateLimitConfig{}, 20, defaultAlertRateBurst},
+        {"burst", &AlertRateLimitConfig{PerMinute: 1, Burst: 3}, 5, 3},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := inTempDir(t)
+            q := &alertQueue{
+                jobs:    make(chan alertJob, alertQueueSize),
+                logger:  NewEventLogger(filepath.Join(dir, "events.log")),
+                limiter: newAlertLimiter(tt.rc),
+            }
+            for i := 0; i < tt.events; i++ {
+                q.enqueue(AlertEvent{Type: EventTrigger, Zone: Zone{ID: i, Name: "Zone"}})
+            }
+            // Alarms are never held back.
+            q.enqueue(AlertEvent{Type: EventAlarm})
+            if got := len(q.jobs); got != tt.queued+1 {
+                t.Errorf("%d events queued, want %d", got, tt.queued+1)
+            }
+            // Turning the limit off sends what it held as a summary.
+            q.setRateLimit(nil)
+            held := tt.events - tt.queued
+            if held == 0 {
+                return
+            }
+            if got := len(q.jobs); got != tt.queued+2 {
+                t.Fatalf("%d events queued after removing the limit, want %d", got, tt.queued+2)
+            }
+            var last alertJob
+            for len(q.jobs) > 0 {
+                last = <-q.jobs
+            }
+            if !strings.Contains(last.event.Zone.Name, "further zones triggered") {
+                t.Errorf("summary %q", last.event.Zone.Name)
+            }
+        })
+    }
+}
@@ -0,0 +1,183 @@
+package main
+
+// This file implements an alert handler sending Signal messages through a
+// local signal-cli daemon running in JSON-RPC mode.
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "strings"
+    "sync/atomic"
+    "time"
+)
+
+// SignalAlert sends a text message describing each event via signal-cli.
+// The daemon may be reached over HTTP (signal-cli daemon --http), a unix
+// socket (--socket) or TCP (--tcp).  When the only recipient is the sender
+// itself the message is sent as a "note to self".
+type SignalAlert struct {
+    Address    string
+    Account    string
+    Recipients []string
+    GroupID    string
+    timeout    time.Duration
+    client     *http.Client
+    tmpl       *alertTemplates
+    nextID     int64
+}
+
+// signalRequest is a JSON-RPC 2.0 request to signal-cli.
+type signalRequest struct {
+    JSONRPC string         `json:"jsonrpc"`
+    Method  string         `json:"method"`
+    Params  map[string]any `json:"params"`
+    ID      int64          `json:"id"`
+}
+
+// signalResponse is a JSON-RPC 2.0 response from signal-cli.  Only the
+// fields needed to detect delivery failures are decoded.
+type signalResponse struct {
+    ID     *int64 `json:"id"`
+    Result *struct {
+        Results []struct {
+            RecipientAddress struct {
+                Number string `json:"number"`
+            } `json:"recipientAddress"`
+            Type string `json:"type"`
+        } `json:"results"`
+    } `json:"result"`
+    Error *struct {
+        Code    int    `json:"code"`
+        Message string `json:"message"`
+    } `json:"error"`
+}
+
+// NewSignalAlert constructs a SignalAlert from its configuration.
+func NewSignalAlert(ac AlertConfig, tmpl *alertTemplates) *SignalAlert {
+    return &SignalAlert{
+        Address:    ac.Address,
+        Account:    ac.From,
+        Recipients: ac.ToNumbers,
+        GroupID:    ac.GroupID,
+        timeout:    alertTimeout(ac),
+        client:     &http.Client{Timeout: alertTimeout(ac)},
+        tmpl:       tmpl,
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*SignalAlert) Name() string { return "signal" }
+
+// Send delivers the event as a Signal message.  An unreachable daemon, a
+// JSON-RPC error or a per-recipient failure such as an unregistered number
+// is returned as an error.
+func (sg *SignalAlert) Send(event AlertEvent, logger *EventLogger) error {
+    text := fmt.Sprintf("Minder %s: zone %s (ID %d) while %s at %s",
+        event.Type, event.Zone.Name, event.Zone.ID, event.Mode, event.Time.Format("15:04:05 02 Jan"))
+    text = sg.tmpl.Body(event, text, logger)
+    params := map[string]any{
+        "account": sg.Account,
+        "message": text,
+    }
+    switch {
+    case sg.GroupID != "":
+        params["groupId"] = sg.GroupID
+    case len(sg.Recipients) == 1 && sg.Recipients[0] == sg.Account:
+        params["noteToSelf"] = true
+    default:
+        params["recipient"] = sg.Recipients
+    }
+    req := signalRequest{
+        JSONRPC: "2.0",
+        Method:  "send",
+        Params:  params,
+        ID:      atomic.AddInt64(&sg.nextID, 1),
+    }
+    body, err := json.Marshal(req)
+    if err != nil {
+        return err
+    }
+    var resp *signalResponse
+    if strings.HasPrefix(sg.Address, "http://") || strings.HasPrefix(sg.Address, "https://") {
+        resp, err = sg.callHTTP(body)
+    } else {
+        resp, err = sg.callSocket(body, req.ID)
+    }
+    if err != nil {
+        return err
+    }
+    if resp.Error != nil {
+        return fmt.Errorf("signal-cli error %d: %s", resp.Error.Code, resp.Error.Message)
+    }
+    if resp.Result != nil {
+        var failures []string
+        for _, r := range resp.Result.Results {
+            if r.Type != "SUCCESS" {
+                failures = append(failures, fmt.Sprintf("%s: %s", r.RecipientAddress.Number, r.Type))
+            }
+        }
+        if len(failures) > 0 {
+            return fmt.Errorf("signal delivery failed for %s", strings.Join(failures, ", "))
+        }
+    }
+    return nil
+}
+
+// callHTTP posts a JSON-RPC request to the daemon's HTTP endpoint.
+func (sg *SignalAlert) callHTTP(body []byte) (*signalResponse, error) {
+    url := strings.TrimRight(sg.Address, "/") + "/api/v1/rpc"
+    httpResp, err := sg.client.Post(url, "application/json", bytes.NewReader(body))
+    if err != nil {
+        return nil, fmt.Errorf("signal-cli daemon unreachable at %s: %w", sg.Address, err)
+    }
+    defer httpResp.Body.Close()
+    data, err := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+    if err != nil {
+        return nil, err
+    }
+    var resp signalResponse
+    if err := json.Unmarshal(data, &resp); err != nil {
+        return nil, fmt.Errorf("signal-cli returned %s: %s", httpResp.Status, strings.TrimSpace(string(data)))
+    }
+    return &resp, nil
+}
+
+// callSocket sends a newline delimited JSON-RPC request over a unix or TCP
+// socket and waits for the response with the matching ID.  Notifications
+// the daemon interleaves (such as received messages) are skipped.
+func (sg *SignalAlert) callSocket(body []byte, id int64) (*signalResponse, error) {
+    network, addr := "tcp", sg.Address
+    if strings.HasPrefix(addr, "unix:") {
+        network, addr = "unix", strings.TrimPrefix(addr, "unix:")
+    }
+    conn, err := net.DialTimeout(network, addr, sg.timeout)
+    if err != nil {
+        return nil, fmt.Errorf("signal-cli daemon unreachable at %s: %w", sg.Address, err)
+    }
+    defer conn.Close()
+    _ = conn.SetDeadline(time.Now().Add(sg.timeout))
+    if _, err := conn.Write(append(body, '\n')); err != nil {
+        return nil, err
+    }
+    scanner := bufio.NewScanner(conn)
+    scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        var resp signalResponse
+        if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+            continue
+        }
+        if resp.ID != nil && *resp.ID == id {
+            return &resp, nil
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("signal-cli: %w", err)
+    }
+    return nil, errors.New("signal-cli closed the connection without responding")
+}
@@ -0,0 +1,127 @@
+package main
+
+// This file implements an alert handler posting to a Slack incoming webhook.
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// slackMaxRetryAfter caps how long Send will wait when Slack rate limits a
+// request, so a large Retry-After cannot stall alert dispatch.
+const slackMaxRetryAfter = 30 * time.Second
+
+// slackColours maps event severity to the attachment colour bar: red for
+// alarms, yellow for faults and grey for informational events.
+var slackColours = map[string]string{
+    SeverityCritical: "#d00000",
+    SeverityWarning:  "#f2c744",
+    SeverityInfo:     "#9e9e9e",
+}
+
+// SlackAlert posts a Block Kit message to a Slack incoming webhook.  The
+// blocks are wrapped in an attachment so the message carries a colour bar
+// indicating severity.
+type SlackAlert struct {
+    URL     string
+    Channel string
+    client  *http.Client
+    tmpl    *alertTemplates
+}
+
+// NewSlackAlert constructs a SlackAlert from its configuration.
+func NewSlackAlert(ac AlertConfig, tmpl *alertTemplates) *SlackAlert {
+    return &SlackAlert{
+        URL:     ac.URL,
+        Channel: ac.Channel,
+        client:  &http.Client{Timeout: alertTimeout(ac)},
+        tmpl:    tmpl,
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*SlackAlert) Name() string { return "slack" }
+
+// Send posts the event to Slack.  If Slack responds with HTTP 429 the
+// request is retried once after the advertised Retry-After interval.
+func (sl *SlackAlert) Send(event AlertEvent, logger *EventLogger) error {
+    body, err := json.Marshal(sl.message(event, logger))
+    if err != nil {
+        return err
+    }
+    retryAfter, err := sl.post(body)
+    if err == nil || retryAfter == 0 {
+        return err
+    }
+    time.Sleep(retryAfter)
+    _, err = sl.post(body)
+    return err
+}
+
+// message builds the webhook payload for event.
+func (sl *SlackAlert) message(event AlertEvent, logger *EventLogger) map[string]any {
+    severity := eventSeverity(event.Type)
+    headline := fmt.Sprintf("Minder %s: %s", event.Type, event.Zone.Name)
+    if event.Zone.Name == "" {
+        headline = "Minder " + event.Type
+    }
+    headline = sl.tmpl.Subject(event, headline, logger)
+    fields := []map[string]string{
+        {"type": "mrkdwn", "text": "*Zone:*\n" + event.Zone.Name},
+        {"type": "mrkdwn", "text": "*Mode:*\n" + event.Mode},
+        {"type": "mrkdwn", "text": "*Time:*\n" + event.Time.Format(time.RFC1123)},
+        {"type": "mrkdwn", "text": "*Severity:*\n" + severity},
+    }
+    blocks := []map[string]any{
+        {"type": "header", "text": map[string]string{"type": "plain_text", "text": headline}},
+        {"type": "section", "fields": fields},
+    }
+    if text := sl.tmpl.Body(event, "", logger); text != "" {
+        blocks = append(blocks, map[string]any{
+            "type": "section",
+            "text": map[string]string{"type": "mrkdwn", "text": text},
+        })
+    }
+    msg := map[string]any{
+        // Text is shown in notifications and by clients without Block Kit.
+        "text": headline,
+        "attachments": []map[string]any{
+            {"color": slackColours[severity], "blocks": blocks},
+        },
+    }
+    if sl.Channel != "" {
+        msg["channel"] = sl.Channel
+    }
+    return msg
+}
+
+// post sends body to the webhook.  When Slack rate limits the request it
+// returns a non-zero retry interval together with the error.
+func (sl *SlackAlert) post(body []byte) (time.Duration, error) {
+    resp, err := sl.client.Post(sl.URL, "application/json", bytes.NewReader(body))
+    if err != nil {
+        return 0, fmt.Errorf("slack: %w", err)
+    }
+    defer resp.Body.Close()
+    detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+    if resp.StatusCode == http.StatusTooManyRequests {
+        wait := time.Second
+        if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+            wait = time.Duration(secs) * time.Second
+        }
+        if wait > slackMaxRetryAfter {
+            wait = slackMaxRetryAfter
+        }
+        return wait, fmt.Errorf("slack rate limited: %s", resp.Status)
+    }
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return 0, fmt.Errorf("slack returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+    }
+    return 0, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+// This file implements an SMS alert handler using the Twilio Messages API.
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+)
+
+// twilioAPIBase is the root of the Twilio REST API.
+const twilioAPIBase = "https://api.twilio.com/2010-04-01"
+
+// SMS segment limits.  A message using only the GSM 03.38 alphabet fits 160
+// characters in one segment; any other character forces UCS-2 encoding with
+// a limit of 70 characters.
+const (
+    smsGSMSegment  = 160
+    smsUCS2Segment = 70
+)
+
+// gsm7Chars lists the characters of the GSM 03.38 basic character set.
+// Characters from the extension table are deliberately omitted because they
+// occupy two septets and would complicate the length calculation.
+const gsm7Chars = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+    "¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// SMSAlert sends a short text message to each configured number via Twilio.
+type SMSAlert struct {
+    AccountSID string
+    AuthToken  string
+    From       string
+    To         []string
+    Truncate   bool
+    // Users, if set, adds the users' phone numbers to To.
+    Users      userDirectory
+    client     *http.Client
+    tmpl       *alertTemplates
+}
+
+// NewSMSAlert constructs an SMSAlert from its configuration.
+func NewSMSAlert(ac AlertConfig, tmpl *alertTemplates) *SMSAlert {
+    return &SMSAlert{
+        AccountSID: ac.AccountSID,
+        AuthToken:  ac.AuthToken,
+        From:       ac.From,
+        To:         ac.ToNumbers,
+        Truncate:   ac.TruncateSMS,
+        client:     &http.Client{Timeout: alertTimeout(ac)},
+        tmpl:       tmpl,
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*SMSAlert) Name() string { return "sms" }
+
+// Send delivers the message to every recipient.  A failure for one
+// recipient does not prevent delivery to the others; all failures are
+// combined into the returned error.
+func (a *SMSAlert) Send(event AlertEvent, logger *EventLogger) error {
+    msg := fmt.Sprintf("Minder: %s triggered at %s", event.Zone.Name, smsTime(event.Time))
+    if event.Mode != "" {
+        msg += fmt.Sprintf(" (%s)", event.Mode)
+    }
+    if event.Type == EventDigest {
+        msg = "Minder: " + event.Zone.Name
+    }
+    msg = a.tmpl.Body(event, msg, logger)
+    if a.Truncate {
+        msg = truncateSMS(msg)
+    }
+    to := appendUnique(a.To, a.Users.phones(event)...)
+    if len(to) == 0 {
+        return errors.New("sms alert has no recipients")
+    }
+    var errs []error
+    for _, to := range to {
+        if err := a.sendOne(to, msg); err != nil {
+            errs = append(errs, fmt.Errorf("%s: %w", to, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// sendOne posts a single message to the Twilio Messages API.
+func (a *SMSAlert) sendOne(to, body string) error {
+    form := url.Values{}
+    form.Set("To", to)
+    form.Set("From", a.From)
+    form.Set("Body", body)
+    endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", twilioAPIBase, url.PathEscape(a.AccountSID))
+    req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.SetBasicAuth(a.AccountSID, a.AuthToken)
+    resp, err := a.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        // Twilio returns a JSON error document; include a bounded excerpt.
+        detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("twilio returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+    }
+    return nil
+}
+
+// truncateSMS shortens msg so that it fits in a single SMS segment.  The
+// limit depends on whether the text can be encoded in GSM-7.  Truncation
+// works on runes so multi-byte characters are never split, and an ellipsis
+// marks that the message was shortened.
+func truncateSMS(msg string) string {
+    limit := smsGSMSegment
+    ellipsis := "..."
+    for _, r := range msg {
+        if !strings.ContainsRune(gsm7Chars, r) {
+            limit = smsUCS2Segment
+            ellipsis = "…"
+            break
+        }
+    }
+    runes := []rune(msg)
+    if len(runes) <= limit {
+        return msg
+    }
+    keep := limit - len([]rune(ellipsis))
+    return strings.TrimRight(string(runes[:keep]), " ") + ellipsis
+}
+
+// smsTime formats t for inclusion in a text message.
+func smsTime(t time.Time) string {
+    return t.Format("15:04 02 Jan")
+}
@@ -0,0 +1,87 @@
+package main
+
+// This file persists alert delivery status so that the history shown by
+// GET /api/alerts/status survives a restart.
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "os"
+    "time"
+)
+
+// alertStatusPath is the file the delivery status is snapshotted to.
+const alertStatusPath = "alert_status.json"
+
+// alertStatusInterval is how often changed delivery status is written out.
+const alertStatusInterval = 5 * time.Minute
+
+// loadStatus restores the delivery status saved by a previous run.  A
+// missing or unreadable file simply starts the history afresh.
+func (q *alertQueue) loadStatus() {
+    q.saved = make(map[int]alertHandlerStatus)
+    data, err := ioutil.ReadFile(alertStatusPath)
+    if err != nil {
+        if !os.IsNotExist(err) {
+            q.logger.Log("unable to read %s: %v", alertStatusPath, err)
+        }
+        return
+    }
+    var saved []alertHandlerStatus
+    if err := json.Unmarshal(data, &saved); err != nil {
+        q.logger.Log("ignoring invalid %s: %v", alertStatusPath, err)
+        return
+    }
+    for _, st := range saved {
+        st.Queued = 0
+        q.saved[st.ID] = st
+    }
+}
+
+// saveStatus writes the delivery status to disk if it has changed.
+func (q *alertQueue) saveStatus() error {
+    q.mu.Lock()
+    if !q.dirty {
+        q.mu.Unlock()
+        return nil
+    }
+    status := make([]alertHandlerStatus, len(q.status))
+    copy(status, q.status)
+    q.dirty = false
+    q.mu.Unlock()
+    data, err := json.MarshalIndent(status, "", "  ")
+    if err != nil {
+        return err
+    }
+    tmpPath := alertStatusPath + ".tmp"
+    if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+        return err
+    }
+    return os.Rename(tmpPath, alertStatusPath)
+}
+
+// snapshotLoop periodically saves the delivery status, and once more when
+// the queue is stopped.
+func (q *alertQueue) snapshotLoop() {
+    defer q.loops.Done()
+    ticker := time.NewTicker(alertStatusInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ticker.C:
+        case <-q.stop:
+            // Wait for the last deliveries to be recorded.
+            q.workers.Wait()
+            q.writeStatus()
+            return
+        }
+        q.writeStatus()
+    }
+}
+
+// writeStatus saves the delivery status, logging any error.
+func (q *alertQueue) writeStatus() {
+    if err := q.saveStatus(); err != nil {
+        q.logger.Log("unable to save %s: %v", alertStatusPath, err)
+    }
+}
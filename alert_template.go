@@ -0,0 +1,124 @@
+package main
+
+// This file implements optional user supplied templates for alert wording.
+
+import (
+    "fmt"
+    "io"
+    "strings"
+    "text/template"
+    "time"
+)
+
+// alertTemplateData is the value templates are executed against.  It
+// exposes {{.Event}}, {{.Zone}} (e.g. {{.Zone.Name}}), {{.Mode}}, {{.User}}
+// and {{.Time}}.
+type alertTemplateData struct {
+    Event string
+    Zone  Zone
+    Mode  string
+    User  string
+    Time  time.Time
+}
+
+// alertTemplates holds the parsed SubjectTemplate and BodyTemplate of an
+// alert configuration.  Either may be nil, in which case the handler's
+// default wording is used.  A nil *alertTemplates is valid and always
+// yields the defaults.
+type alertTemplates struct {
+    subject *template.Template
+    body    *template.Template
+}
+
+// newAlertTemplates parses and validates the templates of ac.  Validation
+// executes each template against a sample event so that references to
+// unknown fields are reported now rather than when a real alarm fires.
+func newAlertTemplates(ac AlertConfig) (*alertTemplates, error) {
+    if ac.SubjectTemplate == "" && ac.BodyTemplate == "" {
+        return nil, nil
+    }
+    t := &alertTemplates{}
+    var err error
+    if t.subject, err = parseAlertTemplate("subject_template", ac.SubjectTemplate); err != nil {
+        return nil, err
+    }
+    if t.body, err = parseAlertTemplate("body_template", ac.BodyTemplate); err != nil {
+        return nil, err
+    }
+    return t, nil
+}
+
+// parseAlertTemplate parses and test-executes a single template.  An empty
+// source yields a nil template.
+func parseAlertTemplate(name, src string) (*template.Template, error) {
+    if src == "" {
+        return nil, nil
+    }
+    tmpl, err := template.New(name).Parse(src)
+    if err != nil {
+        return nil, fmt.Errorf("invalid %s: %w", name, err)
+    }
+    sample := alertTemplateData{
+        Event: EventTrigger,
+        Zone:  Zone{ID: 1, Name: "Sample", Type: ZoneTypeContact, Pin: 1, Enabled: true},
+        Mode:  "Away",
+        User:  "admin",
+        Time:  time.Now(),
+    }
+    if err := tmpl.Execute(io.Discard, sample); err != nil {
+        return nil, fmt.Errorf("invalid %s: %w", name, err)
+    }
+    return tmpl, nil
+}
+
+// validateAlertTemplates checks the templates of every alert configuration
+// and reports the first problem found.
+func validateAlertTemplates(alerts []AlertConfig) error {
+    for i, ac := range alerts {
+        if _, err := newAlertTemplates(ac); err != nil {
+            return fmt.Errorf("alerts[%d] (%s): %w", i, ac.Type, err)
+        }
+    }
+    return nil
+}
+
+// Subject renders the subject template for event, or returns fallback if
+// there is no template or rendering fails.
+func (t *alertTemplates) Subject(event AlertEvent, fallback string, logger *EventLogger) string {
+    if t == nil {
+        return fallback
+    }
+    return renderAlertTemplate(t.subject, event, fallback, logger)
+}
+
+// Body renders the body template for event, or returns fallback if there
+// is no template or rendering fails.
+func (t *alertTemplates) Body(event AlertEvent, fallback string, logger *EventLogger) string {
+    if t == nil {
+        return fallback
+    }
+    return renderAlertTemplate(t.body, event, fallback, logger)
+}
+
+// renderAlertTemplate executes tmpl.  A rendering error is logged and the
+// fallback text used so that a broken template never suppresses an alert.
+func renderAlertTemplate(tmpl *template.Template, event AlertEvent, fallback string, logger *EventLogger) string {
+    if tmpl == nil {
+        return fallback
+    }
+    var b strings.Builder
+    data := alertTemplateData{
+        Event: event.Type,
+        Zone:  event.Zone,
+        Mode:  event.Mode,
+        User:  event.User,
+        Time:  event.Time,
+    }
+    if err := tmpl.Execute(&b, data); err != nil {
+        if logger != nil {
+            logger.Log("alert template %s error: %v", tmpl.Name(), err)
+        }
+        return fallback
+    }
+    return b.String()
+}
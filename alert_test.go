@@ -0,0 +1,298 @@
+package main
+
+// Tests of the email alert handler: the to/cc/bcc lists, the headers of
+// composed messages and delivery to a fake SMTP server.  Also the delivery
+// timeout of the email and HTTP based handlers, and the Discord rate
+// limit.
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "mime"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "net/mail"
+    "reflect"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestAddressListJSON(t *testing.T) {
+    tests := []struct {
+        in   string
+        want AddressList
+        out  string
+    }{
+        {`"a@example.org"`, AddressList{"a@example.org"}, `"a@example.org"`},
+        {`["a@example.org"]`, AddressList{"a@example.org"}, `"a@example.org"`},
+        {`["a@example.org","b@example.org"]`, AddressList{"a@example.org", "b@example.org"}, `["a@example.org","b@example.org"]`},
+        {`""`, nil, `null`},
+    }
+    for _, tt := range tests {
+        var got AddressList
+        if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+            t.Errorf("Unmarshal(%s): %v", tt.in, err)
+            continue
+        }
+        if !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("Unmarshal(%s) = %q, want %q", tt.in, got, tt.want)
+        }
+        out, err := json.Marshal(got)
+        if err != nil || string(out) != tt.out {
+            t.Errorf("Marshal(%q) = %s, %v, want %s", got, out, err, tt.out)
+        }
+    }
+    var ac AlertConfig
+    if err := json.Unmarshal([]byte(`{"type":"email","to":"a@example.org","cc":["b@example.org","c@example.org"]}`), &ac); err != nil {
+        t.Fatal(err)
+    }
+    if len(ac.To) != 1 || len(ac.Cc) != 2 {
+        t.Errorf("to %q, cc %q", ac.To, ac.Cc)
+    }
+}
+
+func TestEmailCompose(t *testing.T) {
+    e := EmailAlert{
+        From: "Minder <alarm@example.org>",
+        To:   []string{"a@example.org", "b@example.org"},
+        Cc:   []string{"c@example.org"},
+        Bcc:  []string{"secret@example.org"},
+    }
+    date := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+    msg, err := e.compose("Alarm: Hallway ⚠", "Zone Hallway\ntriggered", date, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if strings.Contains(strings.ReplaceAll(string(msg), "\r\n", ""), "\n") {
+        t.Error("message has bare LF line endings")
+    }
+    m, err := mail.ReadMessage(strings.NewReader(string(msg)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    tests := []struct {
+        header string
+        want   string
+    }{
+        {"From", "Minder <alarm@example.org>"},
+        {"To", "a@example.org, b@example.org"},
+        {"Cc", "c@example.org"},
+        {"Bcc", ""},
+        {"MIME-Version", "1.0"},
+    }
+    for _, tt := range tests {
+        if got := m.Header.Get(tt.header); got != tt.want {
+            t.Errorf("%s: %q, want %q", tt.header, got, tt.want)
+        }
+    }
+    if got, err := m.Header.Date(); err != nil || !got.Equal(date) {
+        t.Errorf("Date: %v, %v, want %v", got, err, date)
+    }
+    if id := m.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.org>") {
+        t.Errorf("Message-ID %q", id)
+    }
+    if subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); err != nil || subject != "Alarm: Hallway ⚠" {
+        t.Errorf("Subject decodes to %q, %v", subject, err)
+    }
+}
+
+// fakeSMTP is an SMTP server accepting every message, recording the
+// envelope of each transaction.  If silent it accepts connections but
+// never answers.
+type fakeSMTP struct {
+    ln     net.Listener
+    silent bool
+    mu     sync.Mutex
+    txns   [][]string
+    data   []string
+}
+
+func newFakeSMTP(t *testing.T, silent bool) *fakeSMTP {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    f := &fakeSMTP{ln: ln, silent: silent}
+    t.Cleanup(func() { ln.Close() })
+    go f.serve()
+    return f
+}
+
+func (f *fakeSMTP) port() int {
+    return f.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve() {
+    for {
+        conn, err := f.ln.Accept()
+        if err != nil {
+            return
+        }
+        if f.silent {
+            // Hold the connection open without a greeting.
+            go func() {
+                time.Sleep(time.Minute)
+                conn.Close()
+            }()
+            continue
+        }
+        go f.session(conn)
+    }
+}
+
+func (f *fakeSMTP) session(conn net.Conn) {
+    defer conn.Close()
+    r := bufio.NewReader(conn)
+    reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+    reply("220 fake ESMTP")
+    var rcpts []string
+    for {
+        line, err := r.ReadString('\n')
+        if err != nil {
+            return
+        }
+        cmd := strings.ToUpper(strings.TrimSpace(line))
+        switch {
+        case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+            reply("250 fake")
+        case strings.HasPrefix(cmd, "MAIL FROM:"):
+            rcpts = nil
+            reply("250 ok")
+        case strings.HasPrefix(cmd, "RCPT TO:"):
+            rcpts = append(rcpts, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+            reply("250 ok")
+        case cmd == "DATA":
+            reply("354 go ahead")
+            var body strings.Builder
+            for {
+                l, err := r.ReadString('\n')
+                if err != nil {
+                    return
+                }
+                if l == ".\r\n" {
+                    break
+                }
+                body.WriteString(l)
+            }
+            f.mu.Lock()
+            f.txns = append(f.txns, rcpts)
+            f.data = append(f.data, body.String())
+            f.mu.Unlock()
+            reply("250 queued")
+        case cmd == "QUIT":
+            reply("221 bye")
+            return
+        default:
+            reply("250 ok")
+        }
+    }
+}
+
+func TestEmailSendOneTransaction(t *testing.T) {
+    f := newFakeSMTP(t, false)
+    e := EmailAlert{
+        SMTPServer: "127.0.0.1",
+        SMTPPort:   f.port(),
+        From:       "alarm@example.org",
+        To:         []string{"a@example.org", "b@example.org"},
+        Cc:         []string{"c@example.org"},
+        Bcc:        []string{"d@example.org"},
+        TLSMode:    SMTPTLSNone,
+        Timeout:    5 * time.Second,
+    }
+    if err := e.Send(AlertEvent{Type: EventAlarm, Zone: Zone{ID: 1, Name: "Hall"}, Time: time.Now()}, nil); err != nil {
+        t.Fatal(err)
+    }
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if len(f.txns) != 1 {
+        t.Fatalf("%d transactions, want 1", len(f.txns))
+    }
+    want := []string{"a@example.org", "b@example.org", "c@example.org", "d@example.org"}
+    if !reflect.DeepEqual(f.txns[0], want) {
+        t.Errorf("recipients %q, want %q", f.txns[0], want)
+    }
+    if strings.Contains(f.data[0], "d@example.org") {
+        t.Error("Bcc recipient appears in the message")
+    }
+    if !strings.Contains(f.data[0], "Hall (ID 1)") {
+        t.Errorf("body does not name the zone:\n%s", f.data[0])
+    }
+}
+
+func TestSendTimeout(t *testing.T) {
+    // The silent server accepts connections but never answers, like a
+    // host behind a firewall that drops replies.
+    // The dispatcher classifies errors with timeoutError, see
+    // sendWithTimeout.
+    f := newFakeSMTP(t, true)
+    url := fmt.Sprintf("http://127.0.0.1:%d/", f.port())
+    ac := AlertConfig{URL: url, Token: "x", Topic: "alarm", Timeout: 1}
+    tests := []struct {
+        name    string
+        handler AlertHandler
+    }{
+        {"email", &EmailAlert{SMTPServer: "127.0.0.1", SMTPPort: f.port(), From: "alarm@example.org", To: []string{"a@example.org"}, TLSMode: SMTPTLSNone, Timeout: time.Second}},
+        {"slack", NewSlackAlert(ac, nil)},
+        {"discord", NewDiscordAlert(ac, nil)},
+        {"gotify", NewGotifyAlert(ac, nil)},
+        {"ntfy", NewNtfyAlert(ac, nil)},
+        {"webhook", NewWebhookAlert(ac, nil)},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            start := time.Now()
+            err := tt.handler.Send(AlertEvent{Type: EventAlarm, Zone: Zone{ID: 1, Name: "Hall"}, Time: start}, nil)
+            if d := time.Since(start); d > 5*time.Second {
+                t.Errorf("Send returned after %s with a 1s timeout", d)
+            }
+            if !errors.Is(timeoutError(err), errAlertTimeout) {
+                t.Errorf("Send error %v, want a timeout", err)
+            }
+        })
+    }
+}
+
+func TestDiscordRateLimit(t *testing.T) {
+    var mu sync.Mutex
+    posts := map[string]int{}
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        posts[r.URL.Path]++
+        mu.Unlock()
+        w.WriteHeader(http.StatusNoContent)
+    }))
+    defer srv.Close()
+    busy, quiet := srv.URL+"/busy", srv.URL+"/quiet"
+    d := NewDiscordAlert(AlertConfig{URLs: []string{busy, quiet}}, nil)
+    // The busy webhook has used its window up in the last few seconds, so
+    // its next post is a minute away; the quiet one is unaffected.
+    now := time.Now()
+    l := d.limiter(busy)
+    for i := 0; i < discordRateLimit; i++ {
+        l.sent = append(l.sent, now.Add(-5*time.Second))
+    }
+    start := time.Now()
+    err := d.Send(AlertEvent{Type: EventAlarm, Zone: Zone{ID: 1, Name: "Hall"}, Time: start}, nil)
+    if took := time.Since(start); took > discordMaxWait {
+        t.Errorf("Send took %s", took)
+    }
+    if !errors.Is(err, errDiscordRateLimited) {
+        t.Errorf("Send error %v, want errDiscordRateLimited", err)
+    }
+    mu.Lock()
+    if posts["/busy"] != 0 || posts["/quiet"] != 1 {
+        t.Errorf("posts %v, want only the quiet webhook's", posts)
+    }
+    mu.Unlock()
+    // A slot freeing up within discordMaxWait is waited for.
+    l.sent[0] = now.Add(-discordRateWindow + 50*time.Millisecond)
+    if delay, err := l.reserve(now, discordMaxWait); err != nil || delay != 50*time.Millisecond {
+        t.Errorf("reserve: %s, %v, want a wait of 50ms", delay, err)
+    }
+}
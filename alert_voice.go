@@ -0,0 +1,167 @@
+package main
+
+// This file implements a voice call alert handler using the Twilio Calls
+// API.
+
+import (
+    "encoding/json"
+    "encoding/xml"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+)
+
+// Voice call timing.  Each number rings for voiceRingSeconds; the call
+// status is polled every voicePollInterval until Twilio reports an outcome.
+const (
+    voiceRingSeconds  = 30
+    voicePollInterval = 3 * time.Second
+    // voiceCallTimeout bounds the wait for the outcome of a single call.
+    voiceCallTimeout = voiceRingSeconds*time.Second + 30*time.Second
+)
+
+// VoiceAlert calls each configured number in turn and reads out a message,
+// stopping at the first call that is answered.  It is meant for alarms
+// only and works well as the last tier of an escalation.
+type VoiceAlert struct {
+    AccountSID string
+    AuthToken  string
+    From       string
+    To         []string
+    // Users, if set, adds the users' phone numbers after To.
+    Users      userDirectory
+    client     *http.Client
+    tmpl       *alertTemplates
+}
+
+// NewVoiceAlert constructs a VoiceAlert from its configuration.
+func NewVoiceAlert(ac AlertConfig, tmpl *alertTemplates) *VoiceAlert {
+    return &VoiceAlert{
+        AccountSID: ac.AccountSID,
+        AuthToken:  ac.AuthToken,
+        From:       ac.From,
+        To:         ac.ToNumbers,
+        client:     &http.Client{Timeout: alertTimeout(ac)},
+        tmpl:       tmpl,
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*VoiceAlert) Name() string { return "voice" }
+
+// Timeout allows for every number to ring out in turn, which takes far
+// longer than other handlers need.
+func (v *VoiceAlert) Timeout() time.Duration {
+    return time.Duration(len(v.numbers(AlertEvent{Type: EventAlarm, Time: time.Now()}))) * voiceCallTimeout
+}
+
+// numbers returns the numbers to call about event, in order.
+func (v *VoiceAlert) numbers(event AlertEvent) []string {
+    return appendUnique(v.To, v.Users.phones(event)...)
+}
+
+// Send calls the numbers in order until one answers.  If nobody answers
+// the outcome of every call is returned as an error.
+func (v *VoiceAlert) Send(event AlertEvent, logger *EventLogger) error {
+    text := fmt.Sprintf("Minder alarm. Zone %s has been triggered.", event.Zone.Name)
+    if event.Mode != "" {
+        text += fmt.Sprintf(" The system was armed in %s mode.", event.Mode)
+    }
+    if event.Type == EventDigest {
+        text = "Minder. " + event.Zone.Name
+    }
+    text = v.tmpl.Body(event, text, logger)
+    var say strings.Builder
+    if err := xml.EscapeText(&say, []byte(text)); err != nil {
+        return err
+    }
+    // The message is repeated so that it is not missed while the phone is
+    // being picked up.
+    twiml := "<Response><Say>" + say.String() + "</Say><Pause length=\"1\"/><Say>" + say.String() + "</Say></Response>"
+    var errs []error
+    for _, to := range v.numbers(event) {
+        answered, err := v.call(to, twiml)
+        if answered {
+            logger.Log("voice alert answered by %s", to)
+            return nil
+        }
+        errs = append(errs, fmt.Errorf("%s: %w", to, err))
+    }
+    if len(errs) == 0 {
+        return errors.New("voice alert has no numbers to call")
+    }
+    return errors.Join(errs...)
+}
+
+// call places one call and polls its status until it is answered or ends
+// unanswered.  The returned error describes why an unanswered call failed.
+func (v *VoiceAlert) call(to, twiml string) (bool, error) {
+    form := url.Values{}
+    form.Set("To", to)
+    form.Set("From", v.From)
+    form.Set("Twiml", twiml)
+    form.Set("Timeout", fmt.Sprint(voiceRingSeconds))
+    endpoint := fmt.Sprintf("%s/Accounts/%s/Calls.json", twilioAPIBase, url.PathEscape(v.AccountSID))
+    call, err := v.do(http.MethodPost, endpoint, form)
+    if err != nil {
+        return false, err
+    }
+    status := fmt.Sprintf("%s/Accounts/%s/Calls/%s.json", twilioAPIBase, url.PathEscape(v.AccountSID), url.PathEscape(call.SID))
+    deadline := time.Now().Add(voiceCallTimeout)
+    for {
+        switch call.Status {
+        case "in-progress", "completed":
+            return true, nil
+        case "busy", "no-answer", "failed", "canceled":
+            return false, fmt.Errorf("call %s", call.Status)
+        }
+        if time.Now().After(deadline) {
+            return false, fmt.Errorf("call still %s after %s", call.Status, voiceCallTimeout)
+        }
+        time.Sleep(voicePollInterval)
+        if call, err = v.do(http.MethodGet, status, nil); err != nil {
+            return false, err
+        }
+    }
+}
+
+// twilioCall is the part of a Twilio call resource used by VoiceAlert.
+type twilioCall struct {
+    SID    string `json:"sid"`
+    Status string `json:"status"`
+}
+
+// do performs an authenticated request against the Twilio API and decodes
+// the call resource in the response.
+func (v *VoiceAlert) do(method, endpoint string, form url.Values) (twilioCall, error) {
+    var call twilioCall
+    var body io.Reader
+    if form != nil {
+        body = strings.NewReader(form.Encode())
+    }
+    req, err := http.NewRequest(method, endpoint, body)
+    if err != nil {
+        return call, err
+    }
+    if form != nil {
+        req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    }
+    req.SetBasicAuth(v.AccountSID, v.AuthToken)
+    resp, err := v.client.Do(req)
+    if err != nil {
+        return call, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return call, fmt.Errorf("twilio returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&call); err != nil {
+        return call, fmt.Errorf("decoding twilio response: %w", err)
+    }
+    return call, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+// This file implements a generic webhook alert handler.
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// WebhookAlert sends a JSON description of each event to an HTTP endpoint.
+// When Secret is set the body is signed with HMAC-SHA256 and the hex digest
+// is sent in the X-Minder-Signature header as "sha256=<digest>" so that the
+// receiver can verify the request came from Minder.
+type WebhookAlert struct {
+    URL     string
+    Method  string
+    Headers map[string]string
+    Secret  string
+    client  *http.Client
+    tmpl    *alertTemplates
+}
+
+// webhookPayload is the JSON document posted to the webhook endpoint.
+type webhookPayload struct {
+    Event     string      `json:"event"`
+    Zone      webhookZone `json:"zone"`
+    Mode      string      `json:"mode"`
+    User      string      `json:"user,omitempty"`
+    Timestamp string      `json:"timestamp"`
+    Message   string      `json:"message"`
+    // Snapshot carries the zone's camera image, base64 encoded, when one
+    // was captured.
+    Snapshot *webhookSnapshot `json:"snapshot,omitempty"`
+}
+
+type webhookSnapshot struct {
+    ContentType string `json:"content_type"`
+    Data        string `json:"data"`
+}
+
+type webhookZone struct {
+    ID   int      `json:"id"`
+    Name string   `json:"name"`
+    Type ZoneType `json:"type"`
+}
+
+// NewWebhookAlert constructs a WebhookAlert from its configuration.  The
+// method defaults to POST.
+func NewWebhookAlert(ac AlertConfig, tmpl *alertTemplates) *WebhookAlert {
+    method := strings.ToUpper(ac.Method)
+    if method == "" {
+        method = http.MethodPost
+    }
+    return &WebhookAlert{
+        URL:     ac.URL,
+        Method:  method,
+        Headers: ac.Headers,
+        Secret:  ac.Secret,
+        client:  &http.Client{Timeout: alertTimeout(ac)},
+        tmpl:    tmpl,
+    }
+}
+
+// Name returns the type name of the alert handler.
+func (*WebhookAlert) Name() string { return "webhook" }
+
+// Send posts the event to the configured URL.  Transport errors, timeouts
+// and non-2xx responses are returned so the caller can log them.
+func (wh *WebhookAlert) Send(event AlertEvent, logger *EventLogger) error {
+    payload := webhookPayload{
+        Event: event.Type,
+        Zone: webhookZone{
+            ID:   event.Zone.ID,
+            Name: event.Zone.Name,
+            Type: event.Zone.Type,
+        },
+        Mode:      event.Mode,
+        User:      event.User,
+        Timestamp: event.Time.Format(time.RFC3339),
+        Message:   wh.tmpl.Body(event, fmt.Sprintf("Zone %s (ID %d) %s", event.Zone.Name, event.Zone.ID, event.Type), logger),
+    }
+    if sn := event.Snapshot; sn != nil {
+        payload.Snapshot = &webhookSnapshot{
+            ContentType: sn.ContentType,
+            Data:        base64.StdEncoding.EncodeToString(sn.Data),
+        }
+    }
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequest(wh.Method, wh.URL, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    for k, v := range wh.Headers {
+        req.Header.Set(k, v)
+    }
+    if wh.Secret != "" {
+        req.Header.Set("X-Minder-Signature", "sha256="+signWebhook(wh.Secret, body))
+    }
+    resp, err := wh.client.Do(req)
+    if err != nil {
+        return fmt.Errorf("webhook %s: %w", wh.URL, err)
+    }
+    defer resp.Body.Close()
+    // Drain a little of the body so the connection can be reused.
+    _, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return fmt.Errorf("webhook %s returned %s", wh.URL, resp.Status)
+    }
+    return nil
+}
+
+// signWebhook returns the hex encoded HMAC-SHA256 of body keyed by secret.
+func signWebhook(secret string, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write(body)
+    return hex.EncodeToString(mac.Sum(nil))
+}
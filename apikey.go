@@ -0,0 +1,221 @@
+package main
+
+// This file implements static API keys for simple automation clients such
+// as a cron job or an ESP32, presented as "X-API-Key".  Unlike API tokens
+// they belong to no user; an admin defines each key with a role and an
+// optional endpoint allowlist.
+
+import (
+    "crypto/subtle"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// apiKeyPrefix marks Minder API keys so that they are recognisable in
+// scripts and secret scanners.
+const apiKeyPrefix = "mnk_"
+
+// apiKeyUserPrefix starts the name of the synthetic user of an API key.
+const apiKeyUserPrefix = "apikey:"
+
+// apiKeySessionOnly lists the endpoints that manage credentials and need a
+// logged in session rather than an API key.
+var apiKeySessionOnly = []string{"/api/password", "/api/2fa", "/api/tokens", "/api/api_keys", "/api/webauthn/", "/api/sessions", "/api/devices", "/api/csrf"}
+
+// validateAPIKeys checks the api_keys section of config.json.
+func validateAPIKeys(keys []APIKey) error {
+    names := make(map[string]bool)
+    for _, k := range keys {
+        if k.Name == "" || k.Hash == "" {
+            return errors.New("api_keys need a name and a hash")
+        }
+        if names[k.Name] {
+            return fmt.Errorf("duplicate api_keys name %q", k.Name)
+        }
+        names[k.Name] = true
+        if _, ok := roleRanks[k.Role]; !ok {
+            return fmt.Errorf("api key %s: role must be viewer, operator or admin", k.Name)
+        }
+        for _, e := range k.Endpoints {
+            if !strings.Contains(e, "/") {
+                return fmt.Errorf("api key %s: endpoint %q must be a path such as /api/status", k.Name, e)
+            }
+        }
+    }
+    return nil
+}
+
+// apiKeyAllows reports whether a key restricted to endpoints may make
+// request r.  An empty list allows every endpoint.
+func apiKeyAllows(endpoints []string, r *http.Request) bool {
+    if len(endpoints) == 0 {
+        return true
+    }
+    for _, e := range endpoints {
+        method, path := "", e
+        if i := strings.IndexByte(e, ' '); i >= 0 {
+            method, path = e[:i], strings.TrimSpace(e[i+1:])
+        }
+        if method != "" && !strings.EqualFold(method, r.Method) {
+            continue
+        }
+        if strings.HasSuffix(path, "*") {
+            if strings.HasPrefix(r.URL.Path, strings.TrimSuffix(path, "*")) {
+                return true
+            }
+        } else if r.URL.Path == path {
+            return true
+        }
+    }
+    return false
+}
+
+// authenticateAPIKey resolves an X-API-Key header to the synthetic user of
+// its key and checks that the key may make r.  Every use is logged with
+// the key's name; its last use is recorded at most once a minute.
+func (s *Server) authenticateAPIKey(key string, r *http.Request) (User, error) {
+    hash := hashAPIToken(key)
+    var found APIKey
+    for _, k := range s.cfgMgr.Get().APIKeys {
+        if subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hash)) == 1 {
+            found = k
+        }
+    }
+    if found.Name == "" {
+        return User{}, errors.New("invalid API key")
+    }
+    for _, p := range apiKeySessionOnly {
+        if r.URL.Path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(r.URL.Path, p)) {
+            return User{}, errors.New("this endpoint needs a logged in session")
+        }
+    }
+    if !apiKeyAllows(found.Endpoints, r) {
+        s.logger.Log("API key %s refused for %s %s from %s", found.Name, r.Method, r.URL.Path, s.clientIP(r))
+        return User{}, errors.New("API key does not permit this request")
+    }
+    s.logger.Log("API key %s used for %s %s from %s", found.Name, r.Method, r.URL.Path, s.clientIP(r))
+    s.recordAPIKeyUse(found.ID)
+    return User{Username: apiKeyUserPrefix + found.Name, Role: found.Role, Admin: found.Role == RoleAdmin, APIKey: found.Name}, nil
+}
+
+// apiKeyView is an API key as listed by the API, without its hash.
+type apiKeyView struct {
+    ID         string     `json:"id"`
+    Name       string     `json:"name"`
+    Role       string     `json:"role"`
+    Endpoints  []string   `json:"endpoints,omitempty"`
+    CreatedAt  time.Time  `json:"created_at"`
+    LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+    Key        string     `json:"key,omitempty"` // only when created
+}
+
+// handleAPIKeys handles GET and POST on /api/api_keys, listing and
+// creating API keys.  Expected JSON for POST:
+// {"name":"...","role":"operator","endpoints":["POST /api/disarm"]}.  The
+// key itself is returned only in the response to the POST.  Admins only.
+func (s *Server) handleAPIKeys(w http.ResponseWriter, r *http.Request, user User) {
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    switch r.Method {
+    case http.MethodGet:
+        keys := []apiKeyView{}
+        for _, k := range s.cfgMgr.Get().APIKeys {
+            keys = append(keys, apiKeyView{ID: k.ID, Name: k.Name, Role: k.Role, Endpoints: k.Endpoints, CreatedAt: k.CreatedAt, LastUsedAt: s.apiKeyLastUsed(k.ID, k.LastUsedAt)})
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(keys)
+    case http.MethodPost:
+        if s.configReadOnly(w) {
+            return
+        }
+        var req struct {
+            Name      string   `json:"name"`
+            Role      string   `json:"role"`
+            Endpoints []string `json:"endpoints"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "invalid JSON", http.StatusBadRequest)
+            return
+        }
+        secret, err := randomString(32)
+        if err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        id, err := randomString(6)
+        if err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        key := APIKey{ID: id, Name: req.Name, Hash: hashAPIToken(apiKeyPrefix + secret), Role: req.Role, Endpoints: req.Endpoints, CreatedAt: time.Now()}
+        if err := validateAPIKeys(append(s.cfgMgr.Get().APIKeys, key)); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        err = s.cfgMgr.Update(func(c *Config) error {
+            if err := validateAPIKeys(append(c.APIKeys[:len(c.APIKeys):len(c.APIKeys)], key)); err != nil {
+                return err
+            }
+            c.APIKeys = append(c.APIKeys, key)
+            return nil
+        })
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        view := apiKeyView{ID: key.ID, Name: key.Name, Role: key.Role, Endpoints: key.Endpoints, CreatedAt: key.CreatedAt}
+        s.logger.Log("create API key %s (%s) by %s", key.ID, key.Name, user.Username)
+        s.audit(r, user.Username, "api_key.create", key.Name, nil, view)
+        view.Key = apiKeyPrefix + secret
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusCreated)
+        _ = json.NewEncoder(w).Encode(view)
+    default:
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+// handleAPIKeyByID deletes an API key with DELETE /api/api_keys/{id}.
+// Admins only.
+func (s *Server) handleAPIKeyByID(w http.ResponseWriter, r *http.Request, user User) {
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    if r.Method != http.MethodDelete {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if s.configReadOnly(w) {
+        return
+    }
+    id := strings.TrimPrefix(r.URL.Path, "/api/api_keys/")
+    var removed APIKey
+    err := s.cfgMgr.Update(func(c *Config) error {
+        for i, k := range c.APIKeys {
+            if k.ID == id {
+                removed = k
+                c.APIKeys = append(c.APIKeys[:i:i], c.APIKeys[i+1:]...)
+                return nil
+            }
+        }
+        return errors.New("not found")
+    })
+    if err != nil {
+        if err.Error() == "not found" {
+            http.Error(w, "not found", http.StatusNotFound)
+        } else {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+        }
+        return
+    }
+    s.logger.Log("delete API key %s (%s) by %s", removed.ID, removed.Name, user.Username)
+    s.audit(r, user.Username, "api_key.delete", removed.Name, apiKeyView{ID: removed.ID, Name: removed.Name, Role: removed.Role, Endpoints: removed.Endpoints, CreatedAt: removed.CreatedAt}, nil)
+    w.WriteHeader(http.StatusNoContent)
+}
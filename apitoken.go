@@ -0,0 +1,220 @@
+package main
+
+// This file implements long-lived per-user API tokens for automation
+// clients such as Home Assistant, presented as "Authorization: Bearer".
+
+import (
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// Token scopes.  An empty scope grants everything the owning user may do.
+const (
+    TokenScopeRead = "read" // GET requests only
+    TokenScopeArm  = "arm"  // status plus arming and disarming
+)
+
+// apiTokenPrefix marks Minder tokens so that they are recognisable in
+// configuration files and secret scanners.
+const apiTokenPrefix = "mnd_"
+
+// hashAPIToken returns the stored form of a token.  Tokens are long and
+// random, so a fast hash suffices and keeps per-request checks cheap.
+func hashAPIToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+// validTokenScope reports whether scope is a known token scope.
+func validTokenScope(scope string) bool {
+    return scope == "" || scope == TokenScopeRead || scope == TokenScopeArm
+}
+
+// tokenAllows reports whether a token with scope may make request r.
+func tokenAllows(scope string, r *http.Request) bool {
+    switch scope {
+    case "":
+        return true
+    case TokenScopeRead:
+        return r.Method == http.MethodGet || r.Method == http.MethodHead
+    case TokenScopeArm:
+        switch r.URL.Path {
+        case "/api/status":
+            return r.Method == http.MethodGet
+        case "/api/arm", "/api/disarm", "/api/acknowledge":
+            return true
+        }
+    }
+    return false
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or
+// "" if there is none.
+func bearerToken(r *http.Request) string {
+    auth := r.Header.Get("Authorization")
+    if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+        return ""
+    }
+    return strings.TrimSpace(auth[7:])
+}
+
+// authenticateToken resolves a bearer token to its owner and checks that
+// its scope permits r.  The last use of the token is recorded.
+func (s *Server) authenticateToken(token string, r *http.Request) (User, error) {
+    hash := hashAPIToken(token)
+    var owner User
+    var found APIToken
+    for _, u := range s.cfgMgr.Get().Users {
+        if u.Disabled {
+            continue
+        }
+        for _, t := range u.Tokens {
+            if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) == 1 {
+                owner, found = u, t
+            }
+        }
+    }
+    if owner.Username == "" {
+        return User{}, errors.New("invalid token")
+    }
+    if !tokenAllows(found.Scope, r) {
+        return User{}, errors.New("token scope does not permit this request")
+    }
+    s.recordTokenUse(found.ID)
+    return owner, nil
+}
+
+// apiTokenView is a token as listed by the API, without its hash.
+type apiTokenView struct {
+    ID         string     `json:"id"`
+    Name       string     `json:"name"`
+    Scope      string     `json:"scope,omitempty"`
+    CreatedAt  time.Time  `json:"created_at"`
+    LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+    Token      string     `json:"token,omitempty"` // only when created
+}
+
+// handleTokens handles GET and POST on /api/tokens, listing and creating
+// the tokens of the logged in user.  Expected JSON for POST:
+// {"name":"...","scope":"read"}.  The token itself is returned only in the
+// response to the POST.  Tokens cannot be managed with a token.
+func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request, user User) {
+    if bearerToken(r) != "" {
+        http.Error(w, "tokens must be managed from a logged in session", http.StatusForbidden)
+        return
+    }
+    switch r.Method {
+    case http.MethodGet:
+        tokens := []apiTokenView{}
+        for _, t := range user.Tokens {
+            tokens = append(tokens, apiTokenView{ID: t.ID, Name: t.Name, Scope: t.Scope, CreatedAt: t.CreatedAt, LastUsedAt: s.tokenLastUsed(t.ID, t.LastUsedAt)})
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(tokens)
+    case http.MethodPost:
+        if s.configReadOnly(w) {
+            return
+        }
+        var req struct {
+            Name  string `json:"name"`
+            Scope string `json:"scope"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "invalid JSON", http.StatusBadRequest)
+            return
+        }
+        if req.Name == "" {
+            http.Error(w, "missing name", http.StatusBadRequest)
+            return
+        }
+        if !validTokenScope(req.Scope) {
+            http.Error(w, "scope must be read or arm, or omitted for full access", http.StatusBadRequest)
+            return
+        }
+        secret, err := randomString(32)
+        if err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        id, err := randomString(6)
+        if err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        token := APIToken{ID: id, Name: req.Name, Hash: hashAPIToken(apiTokenPrefix + secret), Scope: req.Scope, CreatedAt: time.Now()}
+        err = s.cfgMgr.Update(func(c *Config) error {
+            for i, u := range c.Users {
+                if u.Username == user.Username {
+                    c.Users[i].Tokens = append(c.Users[i].Tokens, token)
+                    return nil
+                }
+            }
+            return errors.New("not found")
+        })
+        if err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        s.logger.Log("create API token %s (%s) by %s", token.ID, token.Name, user.Username)
+        s.audit(r, user.Username, "token.create", token.ID, nil, apiTokenView{ID: token.ID, Name: token.Name, Scope: token.Scope, CreatedAt: token.CreatedAt})
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusCreated)
+        _ = json.NewEncoder(w).Encode(apiTokenView{
+            ID:        token.ID,
+            Name:      token.Name,
+            Scope:     token.Scope,
+            CreatedAt: token.CreatedAt,
+            Token:     apiTokenPrefix + secret,
+        })
+    default:
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+// handleTokenByID revokes one of the logged in user's tokens with DELETE
+// /api/tokens/{id}.
+func (s *Server) handleTokenByID(w http.ResponseWriter, r *http.Request, user User) {
+    if bearerToken(r) != "" {
+        http.Error(w, "tokens must be managed from a logged in session", http.StatusForbidden)
+        return
+    }
+    if r.Method != http.MethodDelete {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if s.configReadOnly(w) {
+        return
+    }
+    id := strings.TrimPrefix(r.URL.Path, "/api/tokens/")
+    err := s.cfgMgr.Update(func(c *Config) error {
+        for i, u := range c.Users {
+            if u.Username != user.Username {
+                continue
+            }
+            for j, t := range u.Tokens {
+                if t.ID == id {
+                    c.Users[i].Tokens = append(u.Tokens[:j:j], u.Tokens[j+1:]...)
+                    return nil
+                }
+            }
+        }
+        return errors.New("not found")
+    })
+    if err != nil {
+        if err.Error() == "not found" {
+            http.Error(w, "not found", http.StatusNotFound)
+        } else {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+        }
+        return
+    }
+    s.logger.Log("revoke API token %s by %s", id, user.Username)
+    s.audit(r, user.Username, "token.revoke", id, nil, nil)
+    w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,211 @@
+package main
+
+// This file keeps the audit trail of administrative actions: changes to
+// users, zones, arm modes and alerts, password changes and resets, API
+// tokens and API keys, passkeys, revoked sessions and devices, and users
+// created or given a new role by single sign-on, proxy authentication or
+// the directory.  Entries go to audit.log, one JSON object per line, apart
+// from the sensor events of the event log.  They are written by the code
+// making each change, straight after it succeeds.
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "os"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// auditPath is the file the audit trail is appended to.
+const auditPath = "audit.log"
+
+// AuditEntry records one administrative action.  Before and After
+// summarise the changed object, without password hashes or secrets; either
+// is omitted when the object did not exist.  Changes describes what an
+// update changed, e.g. `name "Garage" -> "Shed"`.
+type AuditEntry struct {
+    Time    time.Time       `json:"time"`
+    Actor   string          `json:"actor"`
+    Action  string          `json:"action"`
+    Target  string          `json:"target,omitempty"`
+    Before  json.RawMessage `json:"before,omitempty"`
+    After   json.RawMessage `json:"after,omitempty"`
+    Changes string          `json:"changes,omitempty"`
+    IP      string          `json:"ip,omitempty"`
+}
+
+// auditTrail serialises writes to auditPath.
+type auditTrail struct {
+    mu sync.Mutex
+}
+
+// userAudit is the summary of a user recorded in audit entries.
+type userAudit struct {
+    Role               string   `json:"role"`
+    Disabled           bool     `json:"disabled,omitempty"`
+    MustChangePassword bool     `json:"must_change_password,omitempty"`
+    HasPIN             bool     `json:"has_pin,omitempty"`
+    Email              string   `json:"email,omitempty"`
+    Phone              string   `json:"phone,omitempty"`
+    NotificationsOff   bool     `json:"notifications_off,omitempty"`
+    AllowedModes       []string `json:"allowed_modes,omitempty"`
+    SessionLimitExempt bool     `json:"session_limit_exempt,omitempty"`
+    Tokens             int      `json:"api_tokens,omitempty"`
+    TwoFactor          bool     `json:"two_factor,omitempty"`
+}
+
+// auditUser summarises u for an audit entry.
+func auditUser(u User) userAudit {
+    return userAudit{
+        Role:               u.Role,
+        Disabled:           u.Disabled,
+        MustChangePassword: u.MustChangePassword,
+        HasPIN:             u.PINHash != "",
+        Email:              u.Email,
+        Phone:              u.Phone,
+        NotificationsOff:   u.NotificationsOff,
+        AllowedModes:       u.AllowedModes,
+        SessionLimitExempt: u.SessionLimitExempt,
+        Tokens:             len(u.Tokens),
+        TwoFactor:          u.TOTPSecret != "",
+    }
+}
+
+// audit appends an entry for action on target, made by actor with request
+// r, to the audit trail.  before and after are marshalled as given; pass
+// nil for an object that did not exist.  What an update changed is also
+// written to the event log.  Failures are written to the event log.
+func (s *Server) audit(r *http.Request, actor, action, target string, before, after any) {
+    entry := AuditEntry{Time: time.Now(), Actor: actor, Action: action, Target: target, IP: s.clientIP(r)}
+    if before != nil {
+        entry.Before, _ = json.Marshal(before)
+    }
+    if after != nil {
+        entry.After, _ = json.Marshal(after)
+    }
+    if before != nil && after != nil {
+        if changes := diffJSON(before, after); len(changes) > 0 {
+            entry.Changes = summariseChanges(changes)
+            what := action
+            if target != "" {
+                what += " " + target
+            }
+            s.logger.Log("%s by %s: %s", what, actor, entry.Changes)
+        }
+    }
+    line, err := json.Marshal(entry)
+    if err != nil {
+        s.logger.Log("unable to write audit entry %s %s: %v", action, target, err)
+        return
+    }
+    s.auditTrail.mu.Lock()
+    defer s.auditTrail.mu.Unlock()
+    f, err := os.OpenFile(auditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+    if err == nil {
+        _, err = f.Write(append(line, '\n'))
+        if cerr := f.Close(); err == nil {
+            err = cerr
+        }
+    }
+    if err != nil {
+        s.logger.Log("unable to write audit entry %s %s: %v", action, target, err)
+    }
+}
+
+// auditProvisioned records user as created, or their role as changed, by
+// an external source of identities (oidc, proxy_auth or ldap) on a login.
+// before is the user as they were, with an empty Username if they did not
+// exist.
+func (s *Server) auditProvisioned(r *http.Request, source string, before, user User) {
+    switch {
+    case before.Username == "":
+        s.audit(r, source, "user.create", user.Username, nil, auditUser(user))
+        s.adminChange(source, "user %s created with role %s", user.Username, user.Role)
+    case before.Role != user.Role:
+        s.audit(r, source, "user.role", user.Username, auditUser(before), auditUser(user))
+        s.adminChange(source, "user %s role changed from %s to %s", user.Username, before.Role, user.Role)
+    }
+}
+
+// parseAuditTime parses a since or until parameter, either RFC 3339 or a
+// date.  A date given as until covers the whole day.
+func parseAuditTime(v string, until bool) (time.Time, error) {
+    if t, err := time.Parse(time.RFC3339, v); err == nil {
+        return t, nil
+    }
+    t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("invalid time %q (expected RFC 3339 or YYYY-MM-DD)", v)
+    }
+    if until {
+        t = t.AddDate(0, 0, 1)
+    }
+    return t, nil
+}
+
+// handleAudit returns audit entries, newest first, with GET
+// /api/audit?actor=...&action=...&since=...&until=...&limit=N.  All
+// filters are optional; limit defaults to 100.  Admins only.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    q := r.URL.Query()
+    var since, until time.Time
+    var err error
+    if v := q.Get("since"); v != "" {
+        if since, err = parseAuditTime(v, false); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+    }
+    if v := q.Get("until"); v != "" {
+        if until, err = parseAuditTime(v, true); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+    }
+    limit := 100
+    if n, err := strconv.Atoi(q.Get("limit")); err == nil && n > 0 {
+        limit = n
+    }
+    entries := []AuditEntry{}
+    f, err := os.Open(auditPath)
+    if err != nil && !os.IsNotExist(err) {
+        http.Error(w, "internal error", http.StatusInternalServerError)
+        return
+    }
+    if err == nil {
+        defer f.Close()
+        scanner := bufio.NewScanner(f)
+        scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+        for scanner.Scan() {
+            var e AuditEntry
+            if json.Unmarshal(scanner.Bytes(), &e) != nil {
+                continue
+            }
+            if (q.Get("actor") != "" && e.Actor != q.Get("actor")) || (q.Get("action") != "" && e.Action != q.Get("action")) {
+                continue
+            }
+            if (!since.IsZero() && e.Time.Before(since)) || (!until.IsZero() && !e.Time.Before(until)) {
+                continue
+            }
+            entries = append(entries, e)
+        }
+    }
+    // The file is in time order; return the newest entries first.
+    result := []AuditEntry{}
+    for i := len(entries) - 1; i >= 0 && len(result) < limit; i-- {
+        result = append(result, entries[i])
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(result)
+}
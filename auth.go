@@ -3,59 +3,189 @@ package main
 import (
     "crypto/rand"
     "encoding/base64"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
     "sync"
     "time"
+    "unicode"
+    "unicode/utf8"
 
     "golang.org/x/crypto/bcrypt"
 )
 
-// hashPassword takes a plaintext password and returns a bcrypt hash.  If hashing
-// fails the program panics because it is a programmer error.
-func hashPassword(password string) string {
-    hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// hashPassword takes a plaintext password and returns a hash made with the
+// configured algorithm (see passwordhash.go).  bcrypt refuses passwords
+// longer than bcryptMaxLength bytes, so callers hash before taking any
+// lock and answer the error as a bad request.
+func hashPassword(password string) (string, error) {
+    hp := currentHashParams()
+    if hp.Algorithm == HashArgon2id {
+        return hashArgon2id(password, hp)
+    }
+    hash, err := bcrypt.GenerateFromPassword([]byte(password), hp.Cost)
     if err != nil {
-        panic(err)
+        return "", err
     }
-    return string(hash)
+    return string(hash), nil
 }
 
-// checkPasswordHash verifies a plaintext password against a stored bcrypt hash.
-// It returns nil if the password matches, or an error otherwise.
+// checkPasswordHash verifies a plaintext password against a stored hash of
+// any supported algorithm, whatever the current configuration.  It returns
+// nil if the password matches, or an error otherwise.
 func checkPasswordHash(password, hash string) error {
+    if strings.HasPrefix(hash, "$argon2id$") {
+        return checkArgon2id(password, hash)
+    }
     return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-// Session represents an authenticated session.  It stores the username
-// and expiry time.  Sessions are kept in memory; they are not persisted.
+// Session represents an authenticated session.  It stores the username,
+// when the session started and was last used, when it expires, and the
+// client it was started from.  ID is a short public identifier used to
+// list and revoke the session; it is unrelated to the secret cookie value.
+// Sessions are kept in memory and, unless persistence is disabled, saved to
+// sessions.json (see sessions.go).
 type Session struct {
-    Username string
-    Expires  time.Time
+    ID        string    `json:"id"`
+    Username  string    `json:"username"`
+    Created   time.Time `json:"created"`
+    LastSeen  time.Time `json:"last_seen"`
+    Expires   time.Time `json:"expires"`
+    IP        string    `json:"ip,omitempty"`
+    UserAgent string    `json:"user_agent,omitempty"`
+    // Device is the ID of the remembered device whose refresh token
+    // started the session, if any (see devices.go).
+    Device    string    `json:"device,omitempty"`
+    // CSRF is the token state-changing requests made with the session must
+    // send in the X-CSRF-Token header (see csrf.go).
+    CSRF      string    `json:"csrf"`
+}
+
+// expiry returns when s expires if it was last used at lastSeen, given the
+// absolute lifetime ttl and the idle timeout idle (zero for none).
+func (s Session) expiry(lastSeen time.Time, ttl, idle time.Duration) time.Time {
+    exp := s.Created.Add(ttl)
+    if idle > 0 && lastSeen.Add(idle).Before(exp) {
+        exp = lastSeen.Add(idle)
+    }
+    return exp
 }
 
 // SessionManager manages active sessions.  It generates random session IDs
-// and cleans up expired sessions periodically.
+// and cleans up expired sessions periodically.  Sessions are keyed by the
+// hash of their ID so that the saved file cannot be used to hijack them.
 type SessionManager struct {
     mu       sync.RWMutex
     sessions map[string]Session
+    // byUser indexes the keys of sessions by username, so that the
+    // sessions of one user can be counted without a full scan.
+    byUser   map[string]map[string]bool
+    // path is the file sessions are persisted to, or "" if they are not.
+    path     string
+    logger   *EventLogger
+    // dirty is set when sessions have been extended since they were last
+    // saved.  Extensions alone are written by Purge rather than on every
+    // request.
+    dirty    bool
+}
+
+// NewSessionManager constructs a session store.  If path is not empty the
+// unexpired sessions saved there are restored and every change is written
+// back.
+func NewSessionManager(path string, logger *EventLogger) *SessionManager {
+    sm := &SessionManager{sessions: make(map[string]Session), byUser: make(map[string]map[string]bool), path: path, logger: logger}
+    if path != "" {
+        sm.load()
+    }
+    return sm
+}
+
+// addLocked stores s under key.  The caller must hold sm.mu.
+func (sm *SessionManager) addLocked(key string, s Session) {
+    sm.sessions[key] = s
+    if sm.byUser[s.Username] == nil {
+        sm.byUser[s.Username] = make(map[string]bool)
+    }
+    sm.byUser[s.Username][key] = true
+}
+
+// removeLocked deletes the session stored under key.  The caller must hold
+// sm.mu.
+func (sm *SessionManager) removeLocked(key string) {
+    s, ok := sm.sessions[key]
+    if !ok {
+        return
+    }
+    delete(sm.sessions, key)
+    delete(sm.byUser[s.Username], key)
+    if len(sm.byUser[s.Username]) == 0 {
+        delete(sm.byUser, s.Username)
+    }
 }
 
-// NewSessionManager constructs an empty session store.
-func NewSessionManager() *SessionManager {
-    return &SessionManager{sessions: make(map[string]Session)}
+// sessionLimit caps the number of live sessions a user may have.  Max is
+// zero for no limit.  When a new session would exceed it, the oldest
+// sessions are ended if Evict is set; otherwise the new one is refused with
+// errTooManySessions.
+type sessionLimit struct {
+    Max   int
+    Evict bool
 }
 
-// Create starts a new session for the given username.  The session expires after
-// the provided duration.
-func (sm *SessionManager) Create(username string, ttl time.Duration) (string, Session, error) {
+// errTooManySessions is returned by Create when the user already has the
+// maximum number of sessions and eviction is not enabled.
+var errTooManySessions = errors.New("too many sessions")
+
+// Create starts a new session with the Username, IP, UserAgent and Device
+// of tmpl.  The session expires ttl after it starts, or earlier if it is
+// unused for idle (zero for no idle timeout).  The sessions evicted to stay
+// within limit are returned.
+func (sm *SessionManager) Create(tmpl Session, ttl, idle time.Duration, limit sessionLimit) (string, Session, []Session, error) {
+    username := tmpl.Username
     sm.mu.Lock()
     defer sm.mu.Unlock()
+    var evicted []Session
+    if limit.Max > 0 {
+        now := time.Now()
+        var live []string
+        for key := range sm.byUser[username] {
+            if now.Before(sm.sessions[key].Expires) {
+                live = append(live, key)
+            } else {
+                sm.removeLocked(key)
+            }
+        }
+        if len(live) >= limit.Max {
+            if !limit.Evict {
+                return "", Session{}, nil, errTooManySessions
+            }
+            sort.Slice(live, func(i, j int) bool { return sm.sessions[live[i]].Created.Before(sm.sessions[live[j]].Created) })
+            for _, key := range live[:len(live)-limit.Max+1] {
+                evicted = append(evicted, sm.sessions[key])
+                sm.removeLocked(key)
+            }
+        }
+    }
     id, err := randomString(32)
     if err != nil {
-        return "", Session{}, err
+        return "", Session{}, nil, err
     }
-    s := Session{Username: username, Expires: time.Now().Add(ttl)}
-    sm.sessions[id] = s
-    return id, s, nil
+    publicID, err := randomString(6)
+    if err != nil {
+        return "", Session{}, nil, err
+    }
+    csrf, err := randomString(32)
+    if err != nil {
+        return "", Session{}, nil, err
+    }
+    now := time.Now()
+    s := Session{ID: publicID, Username: username, Created: now, LastSeen: now, IP: tmpl.IP, UserAgent: tmpl.UserAgent, Device: tmpl.Device, CSRF: csrf}
+    s.Expires = s.expiry(now, ttl, idle)
+    sm.addLocked(hashSessionID(id), s)
+    sm.saveLocked()
+    return id, s, evicted, nil
 }
 
 // Get retrieves a session by ID.  If the session has expired or does not exist
@@ -63,34 +193,267 @@ func (sm *SessionManager) Create(username string, ttl time.Duration) (string, Se
 func (sm *SessionManager) Get(id string) (Session, bool) {
     sm.mu.RLock()
     defer sm.mu.RUnlock()
-    s, ok := sm.sessions[id]
+    s, ok := sm.sessions[hashSessionID(id)]
     if !ok || time.Now().After(s.Expires) {
         return Session{}, false
     }
     return s, true
 }
 
+// Touch checks the session with the given ID against the current lifetime
+// ttl and idle timeout idle and, if it is still valid, records its use and
+// extends it up to the end of its lifetime.  Checking against the current
+// limits rather than those the session started with lets changes to them
+// apply to existing sessions.
+func (sm *SessionManager) Touch(id string, ttl, idle time.Duration) (Session, bool) {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+    key := hashSessionID(id)
+    s, ok := sm.sessions[key]
+    now := time.Now()
+    if !ok || !now.Before(s.expiry(s.LastSeen, ttl, idle)) {
+        return Session{}, false
+    }
+    s.LastSeen = now
+    s.Expires = s.expiry(now, ttl, idle)
+    sm.sessions[key] = s
+    sm.dirty = true
+    return s, true
+}
+
 // Delete removes a session.  It returns true if the session existed.
 func (sm *SessionManager) Delete(id string) bool {
     sm.mu.Lock()
     defer sm.mu.Unlock()
-    if _, ok := sm.sessions[id]; ok {
-        delete(sm.sessions, id)
+    key := hashSessionID(id)
+    if _, ok := sm.sessions[key]; ok {
+        sm.removeLocked(key)
+        sm.saveLocked()
         return true
     }
     return false
 }
 
+// List returns the unexpired sessions of username, or of every user if
+// username is empty, oldest first.
+func (sm *SessionManager) List(username string) []Session {
+    sm.mu.RLock()
+    defer sm.mu.RUnlock()
+    now := time.Now()
+    list := []Session{}
+    for _, s := range sm.sessions {
+        if now.Before(s.Expires) && (username == "" || s.Username == username) {
+            list = append(list, s)
+        }
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+    return list
+}
+
+// Revoke removes the session with public ID id, provided it belongs to
+// username or username is empty.  It returns the removed session and
+// whether there was one.
+func (sm *SessionManager) Revoke(id, username string) (Session, bool) {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+    for key, s := range sm.sessions {
+        if s.ID == id && (username == "" || s.Username == username) {
+            sm.removeLocked(key)
+            sm.saveLocked()
+            return s, true
+        }
+    }
+    return Session{}, false
+}
+
+// DeleteDevice removes the sessions started from the remembered device
+// with ID device, and returns the number removed.
+func (sm *SessionManager) DeleteDevice(device string) int {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+    n := 0
+    for key, s := range sm.sessions {
+        if s.Device == device {
+            sm.removeLocked(key)
+            n++
+        }
+    }
+    if n > 0 {
+        sm.saveLocked()
+    }
+    return n
+}
+
+// DeleteUser removes every session of username except the session with ID
+// except, and returns the number removed.
+func (sm *SessionManager) DeleteUser(username, except string) int {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+    keep := ""
+    if except != "" {
+        keep = hashSessionID(except)
+    }
+    n := 0
+    for key := range sm.byUser[username] {
+        if key != keep {
+            sm.removeLocked(key)
+            n++
+        }
+    }
+    if n > 0 {
+        sm.saveLocked()
+    }
+    return n
+}
+
+// Password policy defaults used when Config.PasswordPolicy is absent or
+// leaves a length zero.
+const (
+    defaultPasswordMinLength = 8
+    defaultPINMinLength      = 4
+)
+
+// bcryptMaxLength is the longest password, in bytes, bcrypt can hash.
+const bcryptMaxLength = 72
+
+// bannedPasswords are refused whatever the policy, compared without regard
+// to case.
+var bannedPasswords = []string{
+    "admin", "administrator", "password", "password1", "passw0rd", "minder",
+    "letmein", "welcome", "qwerty", "qwertyuiop", "iloveyou", "changeme",
+    "12345678", "123456789", "1234567890", "11111111", "00000000", "abc12345",
+}
+
+// bannedPINs are common PINs refused in addition to repeated digits and
+// runs such as 1234 or 9876.
+var bannedPINs = []string{
+    "1212", "1122", "1010", "2580", "0852", "1004", "2000", "2001", "6969", "1313",
+}
+
+// validatePassword checks a new password for username against policy,
+// which may be nil for the defaults.  The error names the rule that
+// failed.  Existing passwords are not checked, so a stricter policy never
+// locks anyone out.
+func validatePassword(password, username string, policy *PasswordPolicy) error {
+    var p PasswordPolicy
+    if policy != nil {
+        p = *policy
+    }
+    if p.MinLength <= 0 {
+        p.MinLength = defaultPasswordMinLength
+    }
+    if utf8.RuneCountInString(password) < p.MinLength {
+        return fmt.Errorf("password must be at least %d characters", p.MinLength)
+    }
+    if currentHashParams().Algorithm == HashBcrypt && len(password) > bcryptMaxLength {
+        return fmt.Errorf("password must be at most %d bytes", bcryptMaxLength)
+    }
+    for _, banned := range bannedPasswords {
+        if strings.EqualFold(password, banned) {
+            return errors.New("password is too common")
+        }
+    }
+    if username != "" && strings.EqualFold(password, username) {
+        return errors.New("password must not be the username")
+    }
+    var upper, lower, digit, symbol bool
+    for _, r := range password {
+        switch {
+        case unicode.IsUpper(r):
+            upper = true
+        case unicode.IsLower(r):
+            lower = true
+        case unicode.IsDigit(r):
+            digit = true
+        default:
+            symbol = true
+        }
+    }
+    switch {
+    case p.RequireUpper && !upper:
+        return errors.New("password must contain an upper-case letter")
+    case p.RequireLower && !lower:
+        return errors.New("password must contain a lower-case letter")
+    case p.RequireDigit && !digit:
+        return errors.New("password must contain a digit")
+    case p.RequireSymbol && !symbol:
+        return errors.New("password must contain a symbol")
+    }
+    return nil
+}
+
+// validatePIN checks a new disarm PIN against the PIN length of policy,
+// which may be nil for the default.  PINs consist of digits only.
+func validatePIN(pin string, policy *PasswordPolicy) error {
+    minLength := defaultPINMinLength
+    if policy != nil && policy.PINMinLength > 0 {
+        minLength = policy.PINMinLength
+    }
+    if len(pin) < minLength {
+        return fmt.Errorf("PIN must be at least %d digits", minLength)
+    }
+    if currentHashParams().Algorithm == HashBcrypt && len(pin) > bcryptMaxLength {
+        return fmt.Errorf("PIN must be at most %d digits", bcryptMaxLength)
+    }
+    for _, r := range pin {
+        if r < '0' || r > '9' {
+            return errors.New("PIN must contain only digits")
+        }
+    }
+    if strings.Count(pin, pin[:1]) == len(pin) {
+        return errors.New("PIN must not repeat a single digit")
+    }
+    if pinRun(pin, 1) || pinRun(pin, -1) {
+        return errors.New("PIN must not be a run of consecutive digits")
+    }
+    for _, banned := range bannedPINs {
+        if pin == banned {
+            return errors.New("PIN is too common")
+        }
+    }
+    return nil
+}
+
+// pinRun reports whether each digit of pin follows the previous one by
+// step, wrapping from 9 to 0, as in 1234 or 7890.
+func pinRun(pin string, step int) bool {
+    for i := 1; i < len(pin); i++ {
+        if (int(pin[i-1]-'0')+step+10)%10 != int(pin[i]-'0') {
+            return false
+        }
+    }
+    return true
+}
+
+// Count returns the number of unexpired sessions.
+func (sm *SessionManager) Count() int {
+    sm.mu.RLock()
+    defer sm.mu.RUnlock()
+    now := time.Now()
+    n := 0
+    for _, s := range sm.sessions {
+        if now.Before(s.Expires) {
+            n++
+        }
+    }
+    return n
+}
+
 // Purge removes all expired sessions.
 func (sm *SessionManager) Purge() {
     sm.mu.Lock()
     defer sm.mu.Unlock()
     now := time.Now()
-    for id, s := range sm.sessions {
+    n := 0
+    for key, s := range sm.sessions {
         if now.After(s.Expires) {
-            delete(sm.sessions, id)
+            sm.removeLocked(key)
+            n++
         }
     }
+    if n > 0 || sm.dirty {
+        sm.saveLocked()
+    }
 }
 
 // randomString returns a URL‑safe base64 string of length n bytes (before encoding).
@@ -0,0 +1,122 @@
+package main
+
+// This file implements the small subset of CBOR (RFC 8949) needed to read
+// WebAuthn attestation objects and COSE public keys.
+
+import (
+    "encoding/binary"
+    "errors"
+    "fmt"
+)
+
+// cborMaxDepth bounds the nesting of decoded items so that hostile input
+// cannot exhaust the stack.
+const cborMaxDepth = 16
+
+// decodeCBOR decodes the first CBOR item in data and returns it together
+// with the bytes that follow it.  Integers decode as int64, byte strings as
+// []byte, text strings as string, arrays as []any and maps as map[any]any.
+// Indefinite lengths, tags and floating point values are not supported.
+func decodeCBOR(data []byte) (any, []byte, error) {
+    return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (any, []byte, error) {
+    if depth > cborMaxDepth {
+        return nil, nil, errors.New("cbor: nesting too deep")
+    }
+    if len(data) == 0 {
+        return nil, nil, errors.New("cbor: unexpected end of data")
+    }
+    major, info := data[0]>>5, data[0]&0x1f
+    data = data[1:]
+    if major == 7 {
+        switch info {
+        case 20:
+            return false, data, nil
+        case 21:
+            return true, data, nil
+        case 22, 23:
+            return nil, data, nil
+        }
+        return nil, nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+    }
+    var arg uint64
+    switch {
+    case info < 24:
+        arg = uint64(info)
+    case info <= 27:
+        n := 1 << (info - 24)
+        if len(data) < n {
+            return nil, nil, errors.New("cbor: unexpected end of data")
+        }
+        switch n {
+        case 1:
+            arg = uint64(data[0])
+        case 2:
+            arg = uint64(binary.BigEndian.Uint16(data))
+        case 4:
+            arg = uint64(binary.BigEndian.Uint32(data))
+        case 8:
+            arg = binary.BigEndian.Uint64(data)
+        }
+        data = data[n:]
+    default:
+        return nil, nil, errors.New("cbor: indefinite lengths are not supported")
+    }
+    switch major {
+    case 0, 1:
+        if arg > 1<<62 {
+            return nil, nil, errors.New("cbor: integer out of range")
+        }
+        if major == 1 {
+            return -1 - int64(arg), data, nil
+        }
+        return int64(arg), data, nil
+    case 2, 3:
+        if arg > uint64(len(data)) {
+            return nil, nil, errors.New("cbor: unexpected end of data")
+        }
+        if major == 3 {
+            return string(data[:arg]), data[arg:], nil
+        }
+        return data[:arg:arg], data[arg:], nil
+    case 4:
+        if arg > uint64(len(data)) {
+            return nil, nil, errors.New("cbor: unexpected end of data")
+        }
+        items := make([]any, 0, arg)
+        for i := uint64(0); i < arg; i++ {
+            var item any
+            var err error
+            if item, data, err = decodeCBORItem(data, depth+1); err != nil {
+                return nil, nil, err
+            }
+            items = append(items, item)
+        }
+        return items, data, nil
+    case 5:
+        if arg > uint64(len(data)) {
+            return nil, nil, errors.New("cbor: unexpected end of data")
+        }
+        m := make(map[any]any, arg)
+        for i := uint64(0); i < arg; i++ {
+            var key, value any
+            var err error
+            if key, data, err = decodeCBORItem(data, depth+1); err != nil {
+                return nil, nil, err
+            }
+            switch key.(type) {
+            case int64, string:
+            default:
+                return nil, nil, errors.New("cbor: unsupported map key")
+            }
+            if value, data, err = decodeCBORItem(data, depth+1); err != nil {
+                return nil, nil, err
+            }
+            m[key] = value
+        }
+        return m, data, nil
+    }
+    return nil, nil, fmt.Errorf("cbor: unsupported major type %d", major)
+}
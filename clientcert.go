@@ -0,0 +1,226 @@
+package main
+
+// This file authenticates requests by TLS client certificate, for devices
+// such as a wall tablet that should not store a password.  Certificates
+// are verified against a configured CA during the handshake, refused if
+// revoked by a CRL signed by that CA or by the denylist, and mapped from
+// their common name or a subject alternative name to a Minder user.  The
+// browser presents a certificate by itself, so like proxy authentication
+// certificate logins are subject to the same-origin check of csrf.go.
+
+import (
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/pem"
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Client certificate modes.
+const (
+    ClientCertRequest = "request"
+    ClientCertRequire = "require"
+)
+
+// validateClientCerts checks the client_certs section of config.json.
+func validateClientCerts(cc *ClientCertConfig) error {
+    if cc == nil || cc.Mode == "" {
+        return nil
+    }
+    if cc.Mode != ClientCertRequest && cc.Mode != ClientCertRequire {
+        return errors.New(`client_certs mode must be "request" or "require"`)
+    }
+    if cc.CAFile == "" {
+        return errors.New("client_certs needs a ca_file")
+    }
+    for name, username := range cc.Users {
+        if name == "" || username == "" {
+            return errors.New("client_certs users map names to user names")
+        }
+    }
+    return nil
+}
+
+// clientCertTLS adds client certificate verification to cfg as configured
+// by cc.
+func (s *Server) clientCertTLS(cfg *tls.Config, cc *ClientCertConfig) error {
+    if cc == nil || cc.Mode == "" {
+        return nil
+    }
+    data, err := os.ReadFile(cc.CAFile)
+    if err != nil {
+        return fmt.Errorf("reading client CA file: %w", err)
+    }
+    pool := x509.NewCertPool()
+    var cas []*x509.Certificate
+    for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+        if block.Type != "CERTIFICATE" {
+            continue
+        }
+        ca, err := x509.ParseCertificate(block.Bytes)
+        if err != nil {
+            return fmt.Errorf("parsing client CA file: %w", err)
+        }
+        pool.AddCert(ca)
+        cas = append(cas, ca)
+    }
+    if len(cas) == 0 {
+        return fmt.Errorf("no certificates found in %s", cc.CAFile)
+    }
+    s.clientCertMode, s.clientCAs = cc.Mode, cas
+    cfg.ClientCAs = pool
+    cfg.ClientAuth = tls.VerifyClientCertIfGiven
+    if cc.Mode == ClientCertRequire {
+        cfg.ClientAuth = tls.RequireAndVerifyClientCert
+    }
+    // Revocation is checked against the current configuration, so that a
+    // lost device can be shut out without a restart.
+    cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+        if len(cs.PeerCertificates) == 0 {
+            return nil
+        }
+        if reason := s.certRevoked(cs.PeerCertificates[0]); reason != "" {
+            s.logger.Log("security: refused client certificate %s: %s", cs.PeerCertificates[0].Subject, reason)
+            return errors.New("client certificate revoked")
+        }
+        return nil
+    }
+    return nil
+}
+
+// certSerial formats a certificate serial number as colon separated hex,
+// the form openssl prints.
+func certSerial(cert *x509.Certificate) string {
+    hex := fmt.Sprintf("%X", cert.SerialNumber)
+    if len(hex)%2 == 1 {
+        hex = "0" + hex
+    }
+    var parts []string
+    for i := 0; i < len(hex); i += 2 {
+        parts = append(parts, hex[i:i+2])
+    }
+    return strings.Join(parts, ":")
+}
+
+// crlCache holds the serial numbers revoked by the configured CRL file,
+// reread when the file changes.
+type crlCache struct {
+    mu      sync.Mutex
+    path    string
+    modTime time.Time
+    revoked map[string]bool
+}
+
+// revokedSerials returns the serials revoked by the CRL at path, which must
+// be signed by one of cas.
+func (c *crlCache) revokedSerials(path string, cas []*x509.Certificate) (map[string]bool, error) {
+    info, err := os.Stat(path)
+    if err != nil {
+        return nil, err
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.path == path && c.modTime.Equal(info.ModTime()) {
+        return c.revoked, nil
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    if block, _ := pem.Decode(data); block != nil {
+        data = block.Bytes
+    }
+    crl, err := x509.ParseRevocationList(data)
+    if err != nil {
+        return nil, err
+    }
+    signed := false
+    for _, ca := range cas {
+        if crl.CheckSignatureFrom(ca) == nil {
+            signed = true
+            break
+        }
+    }
+    if !signed {
+        return nil, errors.New("CRL is not signed by the client CA")
+    }
+    revoked := make(map[string]bool)
+    for _, e := range crl.RevokedCertificateEntries {
+        revoked[certSerial(&x509.Certificate{SerialNumber: e.SerialNumber})] = true
+    }
+    c.path, c.modTime, c.revoked = path, info.ModTime(), revoked
+    return revoked, nil
+}
+
+// certRevoked returns why cert may no longer be used, or "" if it may.  A
+// CRL that cannot be read refuses every certificate rather than none.
+func (s *Server) certRevoked(cert *x509.Certificate) string {
+    cc := s.cfgMgr.Get().ClientCerts
+    if cc == nil {
+        return ""
+    }
+    serial := certSerial(cert)
+    for _, r := range cc.Revoked {
+        if strings.EqualFold(strings.ReplaceAll(r, ":", ""), strings.ReplaceAll(serial, ":", "")) {
+            return "serial " + serial + " is in revoked"
+        }
+    }
+    if cc.CRLFile != "" {
+        revoked, err := s.crl.revokedSerials(cc.CRLFile, s.clientCAs)
+        if err != nil {
+            return "unable to read CRL: " + err.Error()
+        }
+        if revoked[serial] {
+            return "serial " + serial + " is revoked by the CRL"
+        }
+    }
+    return ""
+}
+
+// certUsername returns the user the verified client certificate of r maps
+// to, matching its common name first and then its DNS, email and URI
+// subject alternative names.  It returns "" if r has no verified
+// certificate or none of its names is mapped.
+func certUsername(cc *ClientCertConfig, r *http.Request) (string, *x509.Certificate) {
+    if cc == nil || cc.Mode == "" || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+        return "", nil
+    }
+    cert := r.TLS.VerifiedChains[0][0]
+    names := []string{cert.Subject.CommonName}
+    names = append(names, cert.DNSNames...)
+    names = append(names, cert.EmailAddresses...)
+    for _, u := range cert.URIs {
+        names = append(names, u.String())
+    }
+    for _, n := range names {
+        if username := cc.Users[n]; n != "" && username != "" {
+            return username, cert
+        }
+    }
+    return "", cert
+}
+
+// certUser returns the user of r's verified client certificate, and the
+// certificate.  The certificate is nil if r has none.
+func (s *Server) certUser(r *http.Request) (User, *x509.Certificate, error) {
+    username, cert := certUsername(s.cfgMgr.Get().ClientCerts, r)
+    if cert == nil {
+        return User{}, nil, nil
+    }
+    if reason := s.certRevoked(cert); reason != "" {
+        return User{}, cert, errors.New(reason)
+    }
+    if username == "" {
+        return User{}, cert, errors.New("no user mapped")
+    }
+    user, _ := s.cfgMgr.FindUser(username)
+    if user.Username == "" || user.Disabled {
+        return User{}, cert, fmt.Errorf("user %s unknown or disabled", username)
+    }
+    return user, cert, nil
+}
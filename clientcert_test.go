@@ -0,0 +1,147 @@
+package main
+
+// Tests of client certificate logins: revocation by a CRL, which must be
+// signed by the client CA, and the requests certificates may make.
+
+import (
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/pem"
+    "math/big"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// testCA returns a CA certificate with its key, for signing client
+// certificates and CRLs.
+func testCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    tmpl := &x509.Certificate{
+        SerialNumber:          big.NewInt(1),
+        Subject:               pkix.Name{CommonName: name},
+        SubjectKeyId:          []byte(name),
+        NotBefore:             time.Now().Add(-time.Hour),
+        NotAfter:              time.Now().Add(time.Hour),
+        KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+        BasicConstraintsValid: true,
+        IsCA:                  true,
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ca, err := x509.ParseCertificate(der)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return ca, key
+}
+
+// writeCRL writes a CRL revoking serials, signed by ca, to path.
+func writeCRL(t *testing.T, path string, ca *x509.Certificate, key *ecdsa.PrivateKey, serials ...int64) {
+    var entries []x509.RevocationListEntry
+    for _, n := range serials {
+        entries = append(entries, x509.RevocationListEntry{SerialNumber: big.NewInt(n), RevocationTime: time.Now()})
+    }
+    der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+        Number:                    big.NewInt(time.Now().UnixNano()),
+        ThisUpdate:                time.Now().Add(-time.Minute),
+        NextUpdate:                time.Now().Add(time.Hour),
+        RevokedCertificateEntries: entries,
+    }, ca, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0o600); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestCRLSignature(t *testing.T) {
+    dir := inTempDir(t)
+    ca, caKey := testCA(t, "clients")
+    other, otherKey := testCA(t, "other")
+    cfg := validTestConfig()
+    cfg.ClientCerts = &ClientCertConfig{Mode: ClientCertRequest, CAFile: "ca.crt", CRLFile: filepath.Join(dir, "clients.crl")}
+    s, _ := newTestServer(t, cfg)
+    s.clientCertMode, s.clientCAs = ClientCertRequest, []*x509.Certificate{ca}
+    revoked := &x509.Certificate{SerialNumber: big.NewInt(7)}
+    valid := &x509.Certificate{SerialNumber: big.NewInt(8)}
+
+    writeCRL(t, cfg.ClientCerts.CRLFile, ca, caKey, 7)
+    if s.certRevoked(revoked) == "" {
+        t.Error("certificate revoked by the CRL accepted")
+    }
+    if reason := s.certRevoked(valid); reason != "" {
+        t.Errorf("valid certificate refused: %s", reason)
+    }
+    // A CRL signed by another key, which would revoke nothing, refuses
+    // every certificate.
+    writeCRL(t, cfg.ClientCerts.CRLFile, other, otherKey)
+    future := time.Now().Add(time.Minute)
+    if err := os.Chtimes(cfg.ClientCerts.CRLFile, future, future); err != nil {
+        t.Fatal(err)
+    }
+    for _, cert := range []*x509.Certificate{revoked, valid} {
+        if s.certRevoked(cert) == "" {
+            t.Errorf("serial %s accepted with a CRL of another CA", cert.SerialNumber)
+        }
+    }
+}
+
+func TestClientCertSameOrigin(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.ClientCerts = &ClientCertConfig{Mode: ClientCertRequire, CAFile: "ca.crt", Users: map[string]string{"tablet": "hallway"}}
+    cfg.Users = []User{{Username: "hallway", Role: RoleOperator}}
+    s, _ := newTestServer(t, cfg)
+    s.clientCertMode = ClientCertRequire
+    request := func(method, origin string) int {
+        r := httptest.NewRequest(method, "https://alarm.example.org/api/status", nil)
+        r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{
+            SerialNumber: big.NewInt(9),
+            Subject:      pkix.Name{CommonName: "tablet"},
+        }}}}
+        if origin != "" {
+            r.Header.Set("Origin", origin)
+        }
+        w := httptest.NewRecorder()
+        s.withAuth(func(w http.ResponseWriter, r *http.Request, user User) {})(w, r)
+        return w.Code
+    }
+    tests := []struct {
+        method, origin string
+        want           int
+    }{
+        {"POST", "https://alarm.example.org", http.StatusOK},
+        {"POST", "https://evil.example", http.StatusForbidden},
+        {"GET", "https://evil.example", http.StatusOK},
+    }
+    for _, tt := range tests {
+        if code := request(tt.method, tt.origin); code != tt.want {
+            t.Errorf("%s from %s: status %d, want %d", tt.method, tt.origin, code, tt.want)
+        }
+    }
+    // With client_certs removed by a reload, which applies only at the
+    // next restart, certificates no longer log in and the mode the server
+    // started with is still used.
+    if err := s.cfgMgr.Update(func(c *Config) error {
+        c.ClientCerts = nil
+        return nil
+    }); err != nil {
+        t.Fatal(err)
+    }
+    if code := request("GET", ""); code != http.StatusUnauthorized {
+        t.Errorf("after reload: status %d, want 401", code)
+    }
+}
@@ -0,0 +1,165 @@
+package main
+
+// This file saves transfers over the Pi's WiFi.  Responses of 1 KiB or
+// more in a text format, such as the JSON of /api/logs and the web UI's
+// bundle, are gzipped for clients that accept it; the web UI may also ship
+// files compressed at build time (see static.go).  API responses are never
+// cached, being live state, while the hashed assets of the web UI are
+// cached for good.  The live streams /api/events and /api/ws are passed
+// through untouched.
+
+import (
+    "compress/gzip"
+    "mime"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// gzipMinSize is the least response size worth compressing.
+const gzipMinSize = 1024
+
+// withCompression gzips the responses of next for clients that accept it
+// and marks API responses not to be stored.
+func withCompression(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/") {
+            w.Header().Set("Cache-Control", "no-store")
+        }
+        if r.URL.Path == "/api/events" || r.URL.Path == "/api/ws" {
+            next.ServeHTTP(w, r)
+            return
+        }
+        w.Header().Add("Vary", "Accept-Encoding")
+        if r.Method == http.MethodHead || !acceptsGzip(r) {
+            next.ServeHTTP(w, r)
+            return
+        }
+        gw := &gzipResponseWriter{ResponseWriter: w}
+        defer gw.close()
+        next.ServeHTTP(gw, r)
+    })
+}
+
+// acceptsGzip reports whether the Accept-Encoding header of r allows gzip.
+func acceptsGzip(r *http.Request) bool {
+    for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+        coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        if strings.TrimSpace(coding) != "gzip" {
+            continue
+        }
+        if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            v, err := strconv.ParseFloat(q, 64)
+            return err == nil && v > 0
+        }
+        return true
+    }
+    return false
+}
+
+// compressible reports whether content of type ctype is worth gzipping.
+func compressible(ctype string) bool {
+    media, _, err := mime.ParseMediaType(ctype)
+    if err != nil {
+        return false
+    }
+    switch {
+    case strings.HasPrefix(media, "text/"):
+        return true
+    case media == "application/json", media == "application/x-ndjson",
+        media == "application/javascript", media == "image/svg+xml":
+        return true
+    }
+    return false
+}
+
+// gzipResponseWriter holds back the start of a response until it knows
+// whether the response is large enough to compress, then writes it plain
+// or through a gzip.Writer.
+type gzipResponseWriter struct {
+    http.ResponseWriter
+    status  int
+    buf     []byte
+    started bool
+    gz      *gzip.Writer
+}
+
+// WriteHeader records the status, which is sent with the first output.
+func (g *gzipResponseWriter) WriteHeader(status int) {
+    if !g.started && g.status == 0 {
+        g.status = status
+    }
+}
+
+// Write buffers p until gzipMinSize bytes have been written, then starts
+// the response.
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+    if g.gz != nil {
+        return g.gz.Write(p)
+    }
+    if g.started {
+        return g.ResponseWriter.Write(p)
+    }
+    g.buf = append(g.buf, p...)
+    if len(g.buf) >= gzipMinSize {
+        if err := g.start(); err != nil {
+            return 0, err
+        }
+    }
+    return len(p), nil
+}
+
+// Flush sends what has been written so far, as streaming handlers such as
+// the log export expect.
+func (g *gzipResponseWriter) Flush() {
+    if !g.started {
+        _ = g.start()
+    }
+    if g.gz != nil {
+        _ = g.gz.Flush()
+    }
+    if f, ok := g.ResponseWriter.(http.Flusher); ok {
+        f.Flush()
+    }
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+    return g.ResponseWriter
+}
+
+// start sends the status and headers, compressing the body if it is large
+// enough, of a compressible type and not already encoded.
+func (g *gzipResponseWriter) start() error {
+    g.started = true
+    if g.status == 0 {
+        g.status = http.StatusOK
+    }
+    h := g.Header()
+    if h.Get("Content-Type") == "" && len(g.buf) > 0 {
+        h.Set("Content-Type", http.DetectContentType(g.buf))
+    }
+    if len(g.buf) >= gzipMinSize && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+        h.Del("Content-Length")
+        h.Set("Content-Encoding", "gzip")
+        g.gz = gzip.NewWriter(g.ResponseWriter)
+    }
+    g.ResponseWriter.WriteHeader(g.status)
+    buf := g.buf
+    g.buf = nil
+    if len(buf) == 0 {
+        return nil
+    }
+    _, err := g.Write(buf)
+    return err
+}
+
+// close finishes the response.
+func (g *gzipResponseWriter) close() {
+    if !g.started {
+        _ = g.start()
+    }
+    if g.gz != nil {
+        _ = g.gz.Close()
+    }
+}
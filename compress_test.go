@@ -0,0 +1,89 @@
+package main
+
+// Tests of response compression: what the web UI's bundle and API
+// responses take over the wire, with and without gzip.
+
+import (
+    "bytes"
+    "compress/gzip"
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/fs"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// fetch returns the response of h to GET path, accepting gzip if gzipped.
+func fetch(h http.Handler, path string, gzipped bool) *httptest.ResponseRecorder {
+    r := httptest.NewRequest("GET", path, nil)
+    if gzipped {
+        r.Header.Set("Accept-Encoding", "gzip")
+    }
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, r)
+    return w
+}
+
+func TestGzipTransferSizes(t *testing.T) {
+    dist := embeddedDist(t)
+    assets, _ := fs.Glob(dist, "assets/*")
+    entries := make([]string, 500)
+    for i := range entries {
+        entries[i] = fmt.Sprintf("2026-10-15T09:%02d:%02dZ #%d - trigger zone %d by sensor", i/60, i%60, 1000+i, i%7+1)
+    }
+    logs, _ := json.Marshal(map[string]any{"entries": entries})
+    mux := http.NewServeMux()
+    mux.HandleFunc("/api/logs", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        w.Write(logs)
+    })
+    mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        io.WriteString(w, `{"mode": "Disarmed"}`)
+    })
+    mux.HandleFunc("/", spaHandler(dist))
+    h := withCompression(mux)
+    for _, path := range append([]string{"/api/logs"}, prefix("/", assets)...) {
+        plain := fetch(h, path, false)
+        gzipped := fetch(h, path, true)
+        if plain.Code != http.StatusOK || gzipped.Code != http.StatusOK {
+            t.Fatalf("%s: status %d and %d", path, plain.Code, gzipped.Code)
+        }
+        if gzipped.Header().Get("Content-Encoding") != "gzip" {
+            t.Errorf("%s: not gzipped", path)
+            continue
+        }
+        before, after := plain.Body.Len(), gzipped.Body.Len()
+        zr, err := gzip.NewReader(gzipped.Body)
+        if err != nil {
+            t.Fatal(err)
+        }
+        body, err := io.ReadAll(zr)
+        if err != nil || !bytes.Equal(body, plain.Body.Bytes()) {
+            t.Errorf("%s: gzipped body differs: %v", path, err)
+        }
+        t.Logf("%s: %d bytes, %d gzipped (%d%%)", path, before, after, 100*after/before)
+        if after >= before {
+            t.Errorf("%s: gzipped to %d bytes from %d", path, after, before)
+        }
+        if !strings.Contains(gzipped.Header().Get("Vary"), "Accept-Encoding") {
+            t.Errorf("%s: Vary %q", path, gzipped.Header().Get("Vary"))
+        }
+    }
+    // Responses below gzipMinSize are sent as they are.
+    if w := fetch(h, "/api/status", true); w.Header().Get("Content-Encoding") != "" || w.Header().Get("Cache-Control") != "no-store" {
+        t.Errorf("/api/status: Content-Encoding %q, Cache-Control %q", w.Header().Get("Content-Encoding"), w.Header().Get("Cache-Control"))
+    }
+}
+
+// prefix returns names each prefixed with p.
+func prefix(p string, names []string) []string {
+    out := make([]string, len(names))
+    for i, name := range names {
+        out[i] = p + name
+    }
+    return out
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+    "bytes"
     "encoding/json"
     "errors"
     "fmt"
     "io/ioutil"
+    "log"
     "os"
+    "path/filepath"
+    "runtime"
     "sync"
+    "sync/atomic"
+    "time"
 )
 
 // configPath is the default filename for persisted configuration.
 const configPath = "config.json"
 
+// configEnv is the environment variable naming the configuration file when
+// the -config flag is not given.
+const configEnv = "MINDER_CONFIG"
+
 // ConfigManager wraps the loaded configuration and a mutex for concurrent access.
 // When modifying configuration through the HTTP API, always call Save() to
 // persist changes.
@@ -19,11 +29,31 @@ type ConfigManager struct {
     mu     sync.RWMutex
     cfg    Config
     loaded bool
+    // path is the configuration file; configPath if empty.
+    path   string
+    // secretKey encrypts the secrets in the file, see configsecrets.go.
+    // It is nil if no key is set.
+    secretKey []byte
+    // directory tracks the reachability of the LDAP directory.
+    directory directoryHealth
+    // fallback describes why the configuration was loaded from the
+    // backup rather than the file, or is empty if it was not.  It is
+    // cleared once the file is written or read again.  It is set while
+    // Save holds only the read lock, so is atomic.
+    fallback atomic.Value
+    // subs follow changes to the configuration, see configwatch.go.
+    subsMu sync.Mutex
+    subs   []*configSubscriber
+    // saveMu makes saves one at a time, so that config.json and the
+    // users file are written from the same configuration.
+    saveMu sync.Mutex
 }
 
-// Load reads configuration from disk.  If the file does not exist, a default
-// configuration is created with a single admin user (password: "admin", which
-// you should change immediately) and persisted to disk.
+// Load reads configuration from disk.  If the file is missing or invalid
+// and the backup kept by Save is valid, the backup is loaded instead and
+// the fault reported by Fallback.  If neither exists, a default
+// configuration is created with a single admin user, whose random password
+// is printed and written to initialPasswordFile, and persisted to disk.
 func (cm *ConfigManager) Load() error {
     cm.mu.Lock()
     // If the config is already loaded in memory, release the lock and return.
@@ -31,10 +61,45 @@ func (cm *ConfigManager) Load() error {
         cm.mu.Unlock()
         return nil
     }
+    key, err := loadSecretKey()
+    if err != nil {
+        cm.mu.Unlock()
+        return err
+    }
+    cm.secretKey = key
     // Attempt to read config.json
-    data, err := ioutil.ReadFile(configPath)
+    data, err := ioutil.ReadFile(cm.Path())
+    var cfg Config
+    var fallback string
+    if err == nil {
+        cfg, fallback, err = cm.parseFile(data)
+    }
+    if err != nil {
+        // Fall back to the backup.  A backup that cannot be used stops
+        // the server rather than letting a missing file be replaced by
+        // the default configuration.
+        if backup, berr := ioutil.ReadFile(cm.backupPath()); berr == nil {
+            bcfg, _, berr := cm.parseFile(backup)
+            if berr != nil {
+                cm.mu.Unlock()
+                return fmt.Errorf("unable to load %s (%v) or its backup %s: %w", cm.Path(), err, cm.backupPath(), berr)
+            }
+            fallback = fmt.Sprintf("%s could not be loaded (%v), running on the backup %s", cm.Path(), err, cm.backupPath())
+            cfg, err = bcfg, nil
+        }
+    }
     if err != nil {
         if os.IsNotExist(err) {
+            password, err := cm.newInitialPassword("admin")
+            if err != nil {
+                cm.mu.Unlock()
+                return err
+            }
+            hash, err := hashPassword(password)
+            if err != nil {
+                cm.mu.Unlock()
+                return err
+            }
             // Create a default configuration
             defaultCfg := Config{
                 HTTPPort: 8443,
@@ -46,13 +111,23 @@ func (cm *ConfigManager) Load() error {
                     {Name: "Home", ActiveZones: []int{}},
                 },
                 Users: []User{
-                    {Username: "admin", PasswordHash: hashPassword("admin"), Admin: true},
+                    {Username: "admin", PasswordHash: hash, Role: RoleAdmin, Admin: true, MustChangePassword: true},
                 },
                 LogFile: "events.log",
-                Alerts: []AlertConfig{{Type: "log"}},
+                Alerts: []AlertConfig{{ID: 1, Type: "log"}},
                 ExitDelay: 30,
                 EntryDelay: 30,
             }
+            // The environment overrides the defaults as it would the
+            // file, and the file is written with the defaults.
+            if err := applyEnvOverrides(&defaultCfg); err != nil {
+                cm.mu.Unlock()
+                return err
+            }
+            if err := fatalProblems(defaultCfg.Validate()); err != nil {
+                cm.mu.Unlock()
+                return fmt.Errorf("invalid configuration from the environment: %w", err)
+            }
             cm.cfg = defaultCfg
             cm.loaded = true
             // Release the write lock before saving to avoid deadlock: Save acquires
@@ -60,35 +135,317 @@ func (cm *ConfigManager) Load() error {
             cm.mu.Unlock()
             return cm.Save()
         }
-        // Some other error reading config.json
         cm.mu.Unlock()
-        return fmt.Errorf("unable to read config: %w", err)
-    }
-    // Unmarshal existing config
-    if err := json.Unmarshal(data, &cm.cfg); err != nil {
-        cm.mu.Unlock()
-        return fmt.Errorf("invalid config.json: %w", err)
+        if _, ok := err.(*os.PathError); ok {
+            // Some other error reading config.json
+            return fmt.Errorf("unable to read config: %w", err)
+        }
+        return err
     }
+    flagged := flagDefaultPasswords(cfg.Users)
+    cm.cfg = cfg
     cm.loaded = true
+    cm.fallback.Store(fallback)
     cm.mu.Unlock()
-    return nil
+    for _, name := range flagged {
+        log.Printf("User %s still has the default password and must change it at the next login", name)
+    }
+    if len(flagged) > 0 && fallback == "" {
+        if err := cm.Save(); err != nil {
+            return err
+        }
+    }
+    return cm.migrateUsers()
+}
+
+// flagDefaultPasswords sets MustChangePassword on admins still using the
+// password admin, as installs that predate the random initial password
+// were left with.  It returns the usernames flagged.  Only admins are
+// checked, as each check costs a bcrypt comparison at startup.
+func flagDefaultPasswords(users []User) []string {
+    var flagged []string
+    for i, u := range users {
+        if u.hasRole(RoleAdmin) && !u.MustChangePassword && u.PasswordHash != "" && checkPasswordHash("admin", u.PasswordHash) == nil {
+            users[i].MustChangePassword = true
+            flagged = append(flagged, u.Username)
+        }
+    }
+    return flagged
+}
+
+// Reload reads the configuration file again and, if it is valid, replaces
+// the configuration with it, returning the one replaced.  If it is not,
+// the configuration is kept and the errors returned.
+func (cm *ConfigManager) Reload() (Config, error) {
+    data, err := ioutil.ReadFile(cm.Path())
+    if err != nil {
+        return Config{}, fmt.Errorf("unable to read config: %w", err)
+    }
+    cfg, fallback, err := cm.parseFile(data)
+    if err != nil {
+        return Config{}, err
+    }
+    cm.mu.Lock()
+    old := cm.cfg
+    cm.cfg = cfg
+    cm.fallback.Store(fallback)
+    cm.mu.Unlock()
+    cm.notify(old, cfg)
+    // The configuration is in use whether or not the users can be moved.
+    if err := cm.migrateUsers(); err != nil {
+        log.Printf("%v", err)
+    }
+    return old, nil
+}
+
+// Replace replaces the configuration with cfg, which must have been
+// checked with parse, and saves it.  The file it replaces is first copied
+// to a backup named after the time, whose path is returned with the
+// configuration replaced.  So is the users file, if there is one.  If
+// check is not nil, it is called with the configuration to be replaced
+// while the lock is held, and an error from it refuses the change.
+func (cm *ConfigManager) Replace(cfg Config, check func(current Config) error) (Config, string, error) {
+    cm.mu.Lock()
+    if check != nil {
+        if err := check(cm.cfg); err != nil {
+            cm.mu.Unlock()
+            return Config{}, "", err
+        }
+    }
+    var backup string
+    stamp := time.Now().Format("20060102-150405")
+    for _, path := range []string{cm.Path(), cm.usersPath(cm.cfg)} {
+        if path == "" {
+            continue
+        }
+        if data, err := ioutil.ReadFile(path); err == nil {
+            copied := fmt.Sprintf("%s.%s.bak", path, stamp)
+            if err := ioutil.WriteFile(copied, data, 0600); err != nil {
+                cm.mu.Unlock()
+                return Config{}, "", err
+            }
+            if backup == "" {
+                backup = copied
+            }
+        }
+    }
+    old := cm.cfg
+    cm.cfg = cfg
+    cm.mu.Unlock()
+    if err := cm.Save(); err != nil {
+        cm.mu.Lock()
+        cm.cfg = old
+        cm.mu.Unlock()
+        return Config{}, "", err
+    }
+    cm.notify(old, cfg)
+    return old, backup, nil
+}
+
+// parse decodes and validates a configuration file, migrating user roles
+// and numbering alerts.  Its users are those in data, whatever users_file
+// says.
+func (cm *ConfigManager) parse(data []byte) (Config, error) {
+    var cfg Config
+    if err := json.Unmarshal(data, &cfg); err != nil {
+        return Config{}, fmt.Errorf("invalid config.json: %w", err)
+    }
+    return cm.check(cfg)
+}
+
+// parseFile is parse for the configuration file, reading the users from
+// users_file if it is set.  It returns why the users file's backup was
+// used, if it was.  The file may be in YAML, see configyaml.go.
+func (cm *ConfigManager) parseFile(data []byte) (Config, string, error) {
+    data, err := decodeFile(cm.Path(), data)
+    if err != nil {
+        return Config{}, "", fmt.Errorf("invalid %s: %w", cm.Path(), err)
+    }
+    var cfg Config
+    if err := json.Unmarshal(data, &cfg); err != nil {
+        return Config{}, "", fmt.Errorf("invalid %s: %w", cm.Path(), err)
+    }
+    fallback, err := cm.readUsers(&cfg)
+    if err != nil {
+        return Config{}, "", err
+    }
+    cfg, err = cm.check(cfg)
+    return cfg, fallback, err
+}
+
+// check prepares and validates a decoded configuration for parse.
+func (cm *ConfigManager) check(cfg Config) (Config, error) {
+    cfg, err := decryptSecrets(cfg, cm.secretKey)
+    if err != nil {
+        return Config{}, fmt.Errorf("invalid %s: %w", cm.Path(), err)
+    }
+    if err := applyEnvOverrides(&cfg); err != nil {
+        return Config{}, err
+    }
+    if err := normaliseUserRoles(cfg.Users); err != nil {
+        return Config{}, fmt.Errorf("invalid config.json: %w", err)
+    }
+    assignAlertIDs(cfg.Alerts)
+    if err := fatalProblems(cfg.Validate()); err != nil {
+        return Config{}, fmt.Errorf("invalid %s: %w", cm.Path(), err)
+    }
+    return cfg, nil
+}
+
+// assignAlertIDs numbers alert configurations that have no ID, continuing
+// from the highest ID already in use.
+func assignAlertIDs(alerts []AlertConfig) {
+    maxID := 0
+    for _, ac := range alerts {
+        if ac.ID > maxID {
+            maxID = ac.ID
+        }
+    }
+    for i := range alerts {
+        if alerts[i].ID == 0 {
+            maxID++
+            alerts[i].ID = maxID
+        }
+    }
 }
 
 // Save writes the configuration to disk.  Call this after any changes to
-// configuration via the API.
+// configuration via the API.  The file is replaced atomically and synced,
+// so a power cut leaves either the old or the new file, and the old file,
+// if valid JSON, is kept as the backup Load falls back to.  With
+// users_file the users are written to that file first, in the same way.
+// With read_only_config nothing is written and changes are kept in memory
+// only.
 func (cm *ConfigManager) Save() error {
+    cm.saveMu.Lock()
+    defer cm.saveMu.Unlock()
     cm.mu.RLock()
     defer cm.mu.RUnlock()
-    
-    bytes, err := json.MarshalIndent(cm.cfg, "", "  ")
+    if cm.cfg.ReadOnlyConfig {
+        return nil
+    }
+
+    cfg := cm.cfg.withFileValues()
+    if cm.secretKey != nil {
+        var err error
+        if cfg, err = encryptSecrets(cfg, cm.secretKey); err != nil {
+            return err
+        }
+    }
+    if path := cm.usersPath(cfg); path != "" {
+        users, err := json.MarshalIndent(usersDocument{Users: cfg.Users}, "", "  ")
+        if err != nil {
+            return err
+        }
+        if err := saveFile(path, path+".bak", users); err != nil {
+            return err
+        }
+        cfg.Users = nil
+    }
+    data, err := json.MarshalIndent(cfg, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := saveFile(cm.Path(), cm.backupPath(), data); err != nil {
+        return err
+    }
+    cm.fallback.Store("")
+    return nil
+}
+
+// Close waits for a save in progress to finish and blocks any later one,
+// so that the program can exit without leaving a file half written.
+func (cm *ConfigManager) Close() {
+    cm.saveMu.Lock()
+}
+
+// saveFile replaces the file at path with data, JSON written in the
+// format of the file, durably, keeping the file replaced at backup.
+func saveFile(path, backup string, data []byte) error {
+    // A file that cannot be read, such as one left empty by a crash
+    // before saves were synced, must not replace a good backup.
+    previous, err := ioutil.ReadFile(path)
+    if err == nil {
+        if decoded, err := decodeFile(path, previous); err == nil && json.Valid(decoded) && len(bytes.TrimSpace(previous)) > 0 {
+            if err := writeFileSync(backup, previous); err != nil {
+                return err
+            }
+        }
+    }
+    data, err = encodeFile(path, data, previous)
     if err != nil {
         return err
     }
-    tmpPath := configPath + ".tmp"
-    if err := ioutil.WriteFile(tmpPath, bytes, 0600); err != nil {
+    return writeFileSync(path, data)
+}
+
+// writeFileSync replaces the file at path with data durably: it writes a
+// temporary file, syncs it, renames it over path and syncs the directory.
+func writeFileSync(path string, data []byte) error {
+    tmpPath := path + ".tmp"
+    f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+    if err != nil {
+        return err
+    }
+    if _, err := f.Write(data); err != nil {
+        f.Close()
+        return err
+    }
+    if err := f.Sync(); err != nil {
+        f.Close()
+        return err
+    }
+    if err := f.Close(); err != nil {
+        return err
+    }
+    if err := os.Rename(tmpPath, path); err != nil {
+        return err
+    }
+    return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs a directory, so that a rename in it survives a power cut.
+// Windows cannot sync directories, and does not need to.
+func syncDir(dir string) error {
+    if runtime.GOOS == "windows" {
+        return nil
+    }
+    d, err := os.Open(dir)
+    if err != nil {
         return err
     }
-    return os.Rename(tmpPath, configPath)
+    defer d.Close()
+    return d.Sync()
+}
+
+// backupPath returns the backup of the configuration file kept by Save.
+func (cm *ConfigManager) backupPath() string {
+    return cm.Path() + ".bak"
+}
+
+// Fallback returns why the configuration was loaded from the backup, or
+// "" if it was not.
+func (cm *ConfigManager) Fallback() string {
+    reason, _ := cm.fallback.Load().(string)
+    return reason
+}
+
+// Path returns the configuration file.
+func (cm *ConfigManager) Path() string {
+    if cm.path == "" {
+        return configPath
+    }
+    return cm.path
+}
+
+// resolve returns a path from the configuration, such as log_file,
+// relative to the directory of the configuration file rather than the
+// working directory.
+func (cm *ConfigManager) resolve(path string) string {
+    if path == "" || filepath.IsAbs(path) {
+        return path
+    }
+    return filepath.Join(filepath.Dir(cm.Path()), path)
 }
 
 // Get returns a copy of the current configuration.  Callers must treat the
@@ -99,21 +456,69 @@ func (cm *ConfigManager) Get() Config {
     return cm.cfg
 }
 
+// errLastAdmin is returned by Update when a change would leave no enabled
+// admin, which would lock everyone out of user management.
+var errLastAdmin = errors.New("the last enabled admin cannot be deleted, disabled or demoted")
+
 // Update applies a user supplied function to modify the configuration.  It
 // holds the write lock, calls the supplied function with a pointer to the
 // internal config, and then persists the change.  The updater must not
-// capture the pointer beyond the scope of the function.
+// capture the pointer beyond the scope of the function.  A change that
+// removes the last enabled admin, by deletion, disabling or a change of
+// role, is undone and errLastAdmin returned.  So is a change that leaves
+// the configuration with errors (see Config.Validate), returning them, and
+// one whose function returns an error.  Subscribers are told of a change once it is made, even if saving it
+// fails.
 func (cm *ConfigManager) Update(fn func(*Config) error) error {
+    // Save acquires a read lock on the same mutex, so the change is made
+    // and the lock released before saving.
+    if err := cm.update(fn); err != nil {
+        return err
+    }
+    return cm.Save()
+}
+
+// update makes the change of Update while holding the write lock, which
+// is released even if fn panics.
+func (cm *ConfigManager) update(fn func(*Config) error) error {
     cm.mu.Lock()
-    // Apply the update while holding the write lock.
+    defer cm.mu.Unlock()
+    admins := enabledAdmins(cm.cfg.Users)
+    // fn may change the slices of the configuration in place, so a deep
+    // copy is kept to undo a change that is refused.
+    saved, err := json.Marshal(cm.cfg)
+    if err != nil {
+        return err
+    }
+    undo := func() {
+        var restored Config
+        if err := json.Unmarshal(saved, &restored); err == nil {
+            restored.fileValues = cm.cfg.fileValues
+            cm.cfg = restored
+        }
+    }
+    // Apply the update while holding the write lock.  What fn changed
+    // before failing is undone.
     if err := fn(&cm.cfg); err != nil {
-        cm.mu.Unlock()
+        undo()
         return err
     }
-    // Release the lock before saving to avoid deadlock: Save acquires a read
-    // lock on the same mutex.
-    cm.mu.Unlock()
-    return cm.Save()
+    if admins > 0 && enabledAdmins(cm.cfg.Users) == 0 {
+        undo()
+        return errLastAdmin
+    }
+    if err := fatalProblems(cm.cfg.Validate()); err != nil {
+        undo()
+        return err
+    }
+    var old Config
+    if cm.subscribed() {
+        if err := json.Unmarshal(saved, &old); err == nil {
+            old.fileValues = cm.cfg.fileValues
+            cm.notify(old, cm.cfg)
+        }
+    }
+    return nil
 }
 
 // FindUser returns a user and its index by username.  If not found, index
@@ -130,12 +535,26 @@ func (cm *ConfigManager) FindUser(username string) (User, int) {
 }
 
 // Authenticate checks whether the provided username and password are valid.  It
-// returns the user object if authentication succeeds.
+// returns the user object if authentication succeeds.  Local accounts are
+// checked against their password hash; when an LDAP directory is
+// configured, other usernames and directory users are checked against it
+// (see ldap.go).
 func (cm *ConfigManager) Authenticate(username, password string) (User, error) {
     user, _ := cm.FindUser(username)
-    if user.Username == "" {
+    if user.Disabled {
         return User{}, errors.New("invalid credentials")
     }
+    if user.PasswordHash == "" {
+        lc := cm.Get().LDAP
+        if lc == nil || lc.URL == "" || (user.Username != "" && !user.Directory) {
+            return User{}, errors.New("invalid credentials")
+        }
+        user, err := cm.authenticateLDAP(*lc, username, password)
+        if err != nil {
+            return User{}, errors.New("invalid credentials")
+        }
+        return user, nil
+    }
     if err := checkPasswordHash(password, user.PasswordHash); err != nil {
         return User{}, errors.New("invalid credentials")
     }
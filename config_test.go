@@ -0,0 +1,218 @@
+package main
+
+// Tests of loading, checking and updating the configuration.
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestLoadFlagsDefaultPassword(t *testing.T) {
+    dir := inTempDir(t)
+    path := filepath.Join(dir, "config.json")
+    cfg := validTestConfig()
+    cfg.Users = []User{
+        {Username: "admin", PasswordHash: testHash("admin"), Role: RoleAdmin},
+        {Username: "root", PasswordHash: testHash("admin"), Role: RoleAdmin},
+        {Username: "alice", PasswordHash: testHash("s3cret-pass"), Role: RoleAdmin},
+        {Username: "bob", PasswordHash: testHash("admin"), Role: RoleOperator},
+    }
+    if err := (&ConfigManager{path: path, cfg: cfg, loaded: true}).Save(); err != nil {
+        t.Fatal(err)
+    }
+    cm := &ConfigManager{path: path}
+    if err := cm.Load(); err != nil {
+        t.Fatal(err)
+    }
+    want := map[string]bool{"admin": true, "root": true}
+    for _, u := range cm.Get().Users {
+        if u.MustChangePassword != want[u.Username] {
+            t.Errorf("%s: must_change_password %v, want %v", u.Username, u.MustChangePassword, want[u.Username])
+        }
+    }
+    // The flag is written back to config.json.
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var saved Config
+    if err := json.Unmarshal(data, &saved); err != nil {
+        t.Fatal(err)
+    }
+    for _, u := range saved.Users {
+        if u.MustChangePassword != want[u.Username] {
+            t.Errorf("saved %s: must_change_password %v, want %v", u.Username, u.MustChangePassword, want[u.Username])
+        }
+    }
+}
+
+func TestUpdateLastAdminUndoesEverything(t *testing.T) {
+    dir := inTempDir(t)
+    cfg := validTestConfig()
+    cfg.Zones = []Zone{{ID: 1, Name: "Hall", Pin: 4}}
+    cfg.Users = []User{{Username: "alice", Role: RoleAdmin, Admin: true, AllowedModes: []string{"Away"}}}
+    cm := &ConfigManager{path: filepath.Join(dir, "config.json"), cfg: cfg, loaded: true}
+    err := cm.Update(func(c *Config) error {
+        c.Zones[0].Name = "Shed"
+        c.Users[0].AllowedModes[0] = "Home"
+        c.Users[0].Role, c.Users[0].Admin = RoleOperator, false
+        return nil
+    })
+    if err != errLastAdmin {
+        t.Fatalf("demoting the last admin: %v, want errLastAdmin", err)
+    }
+    got := cm.Get()
+    if got.Zones[0].Name != "Hall" {
+        t.Errorf("zone renamed to %q alongside the refused change", got.Zones[0].Name)
+    }
+    if u := got.Users[0]; u.Role != RoleAdmin || u.AllowedModes[0] != "Away" {
+        t.Errorf("user left as role %q, allowed modes %v", u.Role, u.AllowedModes)
+    }
+}
+
+func TestIncompleteAlertIsError(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Alerts = []AlertConfig{
+        {ID: 1, Type: "log"},
+        {ID: 2, Type: "email", From: "minder@example.org", To: []string{"alice@example.org"}},
+    }
+    err := fatalProblems(cfg.Validate())
+    ce, ok := err.(configErrors)
+    if !ok || len(ce) != 1 || ce[0].Path != "alerts[1]" {
+        t.Fatalf("email alert without smtp_server: %v", err)
+    }
+}
+
+func TestWebhookSecretsEncrypted(t *testing.T) {
+    key := make([]byte, 32)
+    cfg := validTestConfig()
+    cfg.Alerts = []AlertConfig{
+        {ID: 1, Type: "slack", URL: "https://hooks.slack.com/services/T0/B0/slack-cred"},
+        {ID: 2, Type: "discord", URLs: []string{"https://discord.com/api/webhooks/1/discord-cred"}},
+        {ID: 3, Type: "webhook", URL: "https://example.org/hook", Headers: map[string]string{"Authorization": "Bearer header-cred"}},
+    }
+    encrypted, err := encryptSecrets(cfg, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    data, _ := json.Marshal(encrypted.Alerts)
+    if strings.Contains(string(data), "cred") {
+        t.Errorf("secret in the clear: %s", data)
+    }
+    if encrypted.Alerts[2].URL != cfg.Alerts[2].URL {
+        t.Errorf("plain webhook URL encrypted")
+    }
+    if cfg.Alerts[2].Headers["Authorization"] != "Bearer header-cred" {
+        t.Errorf("headers of the configuration encrypted in place")
+    }
+    decrypted, err := decryptSecrets(encrypted, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(decrypted.Alerts, cfg.Alerts) {
+        t.Errorf("decrypted as %+v", decrypted.Alerts)
+    }
+}
+
+func TestFirstRunEnvOverrides(t *testing.T) {
+    dir := inTempDir(t)
+    path := filepath.Join(dir, "config.json")
+    t.Setenv("MINDER_HTTP_PORT", "9443")
+    cm := &ConfigManager{path: path}
+    if err := cm.Load(); err != nil {
+        t.Fatal(err)
+    }
+    if port := cm.Get().HTTPPort; port != 9443 {
+        t.Errorf("http_port %d, want 9443 from the environment", port)
+    }
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var saved Config
+    if err := json.Unmarshal(data, &saved); err != nil {
+        t.Fatal(err)
+    }
+    if saved.HTTPPort != 8443 {
+        t.Errorf("saved http_port %d, want the default 8443", saved.HTTPPort)
+    }
+}
+
+func TestFirstRunPassword(t *testing.T) {
+    dir := inTempDir(t)
+    path := filepath.Join(dir, "config.json")
+    cm := &ConfigManager{path: path}
+    if err := cm.Load(); err != nil {
+        t.Fatal(err)
+    }
+    users := cm.Get().Users
+    if len(users) != 1 || !users[0].MustChangePassword {
+        t.Fatalf("users %+v, want one admin who must change the password", users)
+    }
+    for _, password := range []string{"admin", ""} {
+        if checkPasswordHash(password, users[0].PasswordHash) == nil {
+            t.Errorf("generated admin password is %q", password)
+        }
+    }
+    initial, err := ioutil.ReadFile(filepath.Join(dir, initialPasswordFile))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if checkPasswordHash(strings.TrimSpace(string(initial)), users[0].PasswordHash) != nil {
+        t.Error("password in the initial password file does not match")
+    }
+    // Neither the file nor the configuration holds the password, or the
+    // old default, in any form other than the hash.
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var doc map[string]any
+    if err := json.Unmarshal(data, &doc); err != nil {
+        t.Fatal(err)
+    }
+    var walk func(path string, v any)
+    walk = func(path string, v any) {
+        switch v := v.(type) {
+        case map[string]any:
+            for k, e := range v {
+                walk(path+"."+k, e)
+            }
+        case []any:
+            for i, e := range v {
+                walk(fmt.Sprintf("%s[%d]", path, i), e)
+            }
+        case string:
+            if v == strings.TrimSpace(string(initial)) || (v == "admin" && !strings.HasSuffix(path, ".username") && !strings.HasSuffix(path, ".role")) {
+                t.Errorf("%s is %q", path, v)
+            }
+        }
+    }
+    walk("", doc)
+}
+
+func TestYAMLUsersFileBackup(t *testing.T) {
+    dir := inTempDir(t)
+    path := filepath.Join(dir, "users.yaml")
+    if err := ioutil.WriteFile(path, []byte("users: [\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    backup := "# kept by hand\nusers:\n  - username: alice\n    role: admin\n"
+    if err := ioutil.WriteFile(path+".bak", []byte(backup), 0600); err != nil {
+        t.Fatal(err)
+    }
+    cm := &ConfigManager{path: filepath.Join(dir, "config.json")}
+    cfg := Config{UsersFile: "users.yaml"}
+    fallback, err := cm.readUsers(&cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if fallback == "" || len(cfg.Users) != 1 || cfg.Users[0].Username != "alice" {
+        t.Errorf("users %+v from the YAML backup, fallback %q", cfg.Users, fallback)
+    }
+}
@@ -0,0 +1,260 @@
+package main
+
+// This file checks a configuration as a whole, after it is loaded and after
+// every change through the API.  Problems are reported with the location
+// in config.json they concern, such as "zones[2].id".  Errors stop the
+// server from starting, with every one of them listed, and changes that
+// would introduce one are refused.  Warnings, such as an arm mode naming a
+// deleted zone, are logged at startup and listed by GET
+// /api/health/config.
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+// Severities of configuration problems.
+const (
+    ProblemError   = "error"
+    ProblemWarning = "warning"
+)
+
+// ConfigProblem is a problem found by Config.Validate.  Path locates it in
+// config.json.
+type ConfigProblem struct {
+    Path     string `json:"path"`
+    Message  string `json:"message"`
+    Severity string `json:"severity"`
+}
+
+// String returns the problem as "path: message".
+func (p ConfigProblem) String() string {
+    if p.Path == "" || strings.HasPrefix(p.Message, p.Path) {
+        return p.Message
+    }
+    return p.Path + ": " + p.Message
+}
+
+// configErrors is the error for a configuration with errors.
+type configErrors []ConfigProblem
+
+func (ce configErrors) Error() string {
+    lines := make([]string, len(ce))
+    for i, p := range ce {
+        lines[i] = "  " + p.String()
+    }
+    return fmt.Sprintf("%d errors:\n%s", len(ce), strings.Join(lines, "\n"))
+}
+
+// fatalProblems returns the errors among problems as a configErrors, or
+// nil if there are none.
+func fatalProblems(problems []ConfigProblem) error {
+    var errs configErrors
+    for _, p := range problems {
+        if p.Severity == ProblemError {
+            errs = append(errs, p)
+        }
+    }
+    if len(errs) == 0 {
+        return nil
+    }
+    return errs
+}
+
+// Validate checks cfg and returns every problem found, errors and
+// warnings.  Alert IDs must have been assigned.
+func (cfg Config) Validate() []ConfigProblem {
+    var problems []ConfigProblem
+    fail := func(path, format string, args ...any) {
+        problems = append(problems, ConfigProblem{Path: path, Message: fmt.Sprintf(format, args...), Severity: ProblemError})
+    }
+    warn := func(path, format string, args ...any) {
+        problems = append(problems, ConfigProblem{Path: path, Message: fmt.Sprintf(format, args...), Severity: ProblemWarning})
+    }
+
+    if cfg.HTTPPort < 1 || cfg.HTTPPort > 65535 {
+        fail("http_port", "must be between 1 and 65535, not %d", cfg.HTTPPort)
+    }
+    if cfg.RedirectPort > 65535 {
+        fail("redirect_port", "must be at most 65535, not %d", cfg.RedirectPort)
+    }
+    if cfg.CertFile == "" && cfg.ACME == nil && !plainHTTP(cfg) {
+        fail("cert_file", "required")
+    }
+    if cfg.KeyFile == "" && cfg.ACME == nil && !plainHTTP(cfg) {
+        fail("key_file", "required")
+    }
+
+    zoneIDs := make(map[int]int)
+    pins := make(map[int]int)
+    for i, z := range cfg.Zones {
+        path := fmt.Sprintf("zones[%d]", i)
+        if z.ID <= 0 {
+            fail(path+".id", "must be positive, not %d", z.ID)
+        } else if j, dup := zoneIDs[z.ID]; dup {
+            fail(path+".id", "duplicate zone id %d (also zones[%d])", z.ID, j)
+        } else {
+            zoneIDs[z.ID] = i
+        }
+        if z.Name == "" {
+            warn(path+".name", "zone %d has no name", z.ID)
+        }
+        if z.Type != ZoneTypeContact && z.Type != ZoneTypePIR {
+            warn(path+".type", "unknown zone type %q (expected contact or pir)", z.Type)
+        }
+        switch strings.ToUpper(z.Mode) {
+        case "", "NO", "NC", "EOL":
+        default:
+            warn(path+".mode", "unknown mode %q, read as NO (expected NO, NC or EOL)", z.Mode)
+        }
+        if z.Enabled {
+            if j, dup := pins[z.Pin]; dup {
+                warn(path+".pin", "pin %d is also used by zones[%d]", z.Pin, j)
+            } else {
+                pins[z.Pin] = i
+            }
+        }
+    }
+
+    modes := make(map[string]int)
+    for i, am := range cfg.ArmModes {
+        path := fmt.Sprintf("arm_modes[%d]", i)
+        name := strings.ToLower(am.Name)
+        if am.Name == "" {
+            fail(path+".name", "required")
+        } else if j, dup := modes[name]; dup {
+            fail(path+".name", "duplicate arm mode %q (also arm_modes[%d])", am.Name, j)
+        } else {
+            modes[name] = i
+        }
+        for j, id := range am.ActiveZones {
+            if _, ok := zoneIDs[id]; !ok {
+                warn(fmt.Sprintf("%s.active_zones[%d]", path, j), "zone %d does not exist", id)
+            }
+        }
+    }
+
+    usernames := make(map[string]int)
+    subjects := make(map[string]int)
+    for i, u := range cfg.Users {
+        path := fmt.Sprintf("users[%d]", i)
+        if u.Username == "" {
+            fail(path+".username", "required")
+        } else if j, dup := usernames[u.Username]; dup {
+            fail(path+".username", "duplicate user %q (also users[%d])", u.Username, j)
+        } else {
+            usernames[u.Username] = i
+        }
+        if u.OIDCSubject != "" {
+            key := strings.TrimSuffix(u.OIDCIssuer, "/") + " " + u.OIDCSubject
+            if j, dup := subjects[key]; dup {
+                fail(path+".oidc_subject", "already bound to users[%d]", j)
+            } else {
+                subjects[key] = i
+            }
+        }
+        if err := validateAllowedModes(u.AllowedModes, cfg.ArmModes); err != nil {
+            warn(path+".allowed_modes", "%v", err)
+        }
+    }
+    if enabledAdmins(cfg.Users) == 0 {
+        warn("users", "no enabled admin, so users and settings cannot be managed")
+    }
+
+    if cfg.ExitDelay < 0 {
+        fail("exit_delay", "must not be negative")
+    }
+    if cfg.EntryDelay < 0 {
+        fail("entry_delay", "must not be negative")
+    }
+    if cfg.RetentionDays < 0 {
+        fail("retention_days", "must not be negative")
+    }
+    if cfg.AlertTimeout < 0 {
+        fail("alert_timeout", "must not be negative")
+    }
+    if hl := cfg.HTTPLimits; hl != nil {
+        for _, f := range []struct {
+            key   string
+            value int
+        }{
+            {"read_header_timeout", hl.ReadHeaderTimeout},
+            {"read_timeout", hl.ReadTimeout},
+            {"write_timeout", hl.WriteTimeout},
+            {"idle_timeout", hl.IdleTimeout},
+            {"max_header_bytes", hl.MaxHeaderBytes},
+            {"max_connections", hl.MaxConnections},
+        } {
+            if f.value < 0 {
+                fail("http_limits."+f.key, "must not be negative")
+            }
+        }
+    }
+
+    alertIDs := make(map[int]int)
+    for i, ac := range cfg.Alerts {
+        path := fmt.Sprintf("alerts[%d]", i)
+        if j, dup := alertIDs[ac.ID]; dup {
+            fail(path+".id", "duplicate alert id %d (also alerts[%d])", ac.ID, j)
+        } else {
+            alertIDs[ac.ID] = i
+        }
+        if err := validateAlertConfig(ac, cfg); err != nil {
+            fail(path, "%v", err)
+        }
+    }
+
+    for _, c := range []struct {
+        path string
+        err  error
+    }{
+        {"ldap", validateLDAPConfig(cfg.LDAP)},
+        {"oidc", validateOIDCConfig(cfg.OIDC)},
+        {"client_certs", validateClientCerts(cfg.ClientCerts)},
+        {"proxy_auth", validateProxyAuth(cfg.ProxyAuth)},
+        {"cors", validateCORSConfig(cfg.CORS)},
+        {"listen_mode", validateListenMode(cfg)},
+        {"listen", validateListen(cfg)},
+        {"unix_socket", validateUnixSocket(cfg.UnixSocket)},
+        {"acme", validateACMEConfig(cfg.ACME, redirectPort(cfg))},
+        {"trusted_proxies", validateTrustedProxies(cfg.TrustedProxies)},
+        {"session_ttl", validateSessionLimits(cfg)},
+        {"session_binding", validateSessionBinding(cfg.SessionBinding)},
+        {"api_keys", validateAPIKeys(cfg.APIKeys)},
+        {"hash_params", validateHashParams(cfg.HashParams)},
+        {"escalation", validateEscalation(cfg.Escalation, cfg.Alerts)},
+        {"log_rotation", validateLogRetention(cfg)},
+        {"log_level", validateLogLevel(cfg.LogLevel)},
+        {"log_sinks", validateLogSinks(cfg)},
+        {"timezone", validateTimezone(cfg.Timezone)},
+        {"base_url", validateBaseURL(cfg.BaseURL)},
+    } {
+        if c.err != nil {
+            fail(c.path, "%v", c.err)
+        }
+    }
+    return problems
+}
+
+// handleConfigHealth returns the problems with the running configuration
+// with GET /api/health/config, as {"ok": true, "problems": [...],
+// "overrides": [...]}, where ok is false if there are errors and overrides
+// lists the settings overridden by the environment.  Admins only.
+func (s *Server) handleConfigHealth(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    problems := s.cfgMgr.Get().Validate()
+    if problems == nil {
+        problems = []ConfigProblem{}
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(map[string]any{"ok": fatalProblems(problems) == nil, "problems": problems, "overrides": s.cfgMgr.Overrides()})
+}
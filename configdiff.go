@@ -0,0 +1,212 @@
+package main
+
+// This file describes what a change to the configuration changed, such as
+// `name "Garage" -> "Shed", pin 3 -> 17` for a zone, for the event log
+// and the audit trail.  Objects are compared as their JSON, field by
+// field; list entries are matched by their id, name or username, so that
+// deleting one zone is not reported as every later zone changing.  The
+// objects compared are the redacted summaries given to audit, so secrets
+// never appear.  Long lists of changes are summarised by counts.
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// maxListedChanges is the number of changes listed in full; more are
+// summarised by counts.
+const maxListedChanges = 8
+
+// maxChangeValue is the length beyond which values are shortened.
+const maxChangeValue = 40
+
+// configChange is one difference found by diffJSON.  Path is empty for a
+// change of the whole value.
+type configChange struct {
+    Path string
+    Old  any
+    New  any
+    // Added and Removed mark list entries that exist on one side only.
+    Added   bool
+    Removed bool
+}
+
+// String returns the change as e.g. `pin 3 -> 17`.
+func (c configChange) String() string {
+    var s string
+    switch {
+    case c.Added:
+        s = "added"
+    case c.Removed:
+        s = "removed"
+    default:
+        s = changeValue(c.Old) + " -> " + changeValue(c.New)
+    }
+    if c.Path == "" {
+        return s
+    }
+    return c.Path + " " + s
+}
+
+// changeValue formats a value of a change compactly.
+func changeValue(v any) string {
+    if v == nil {
+        return "none"
+    }
+    data, _ := json.Marshal(v)
+    s := string(data)
+    if len(s) > maxChangeValue {
+        s = s[:maxChangeValue-3] + "..."
+    }
+    return s
+}
+
+// diffJSON returns the differences between two values, each marshalled to
+// JSON and compared structurally.
+func diffJSON(before, after any) []configChange {
+    var b, a any
+    for _, c := range []struct {
+        v   any
+        out *any
+    }{{before, &b}, {after, &a}} {
+        data, err := json.Marshal(c.v)
+        if err == nil {
+            err = json.Unmarshal(data, c.out)
+        }
+        if err != nil {
+            return nil
+        }
+    }
+    var changes []configChange
+    diffValue("", b, a, &changes)
+    return changes
+}
+
+// diffValue appends the differences between b and a, found at path, to
+// changes.
+func diffValue(path string, b, a any, changes *[]configChange) {
+    switch bv := b.(type) {
+    case map[string]any:
+        if av, ok := a.(map[string]any); ok {
+            keys := make([]string, 0, len(bv)+len(av))
+            for k := range bv {
+                keys = append(keys, k)
+            }
+            for k := range av {
+                if _, ok := bv[k]; !ok {
+                    keys = append(keys, k)
+                }
+            }
+            sort.Strings(keys)
+            for _, k := range keys {
+                diffValue(joinPath(path, k), bv[k], av[k], changes)
+            }
+            return
+        }
+    case []any:
+        if av, ok := a.([]any); ok {
+            if key := entryKey(bv, av); key != "" {
+                diffEntriesByKey(path, key, bv, av, changes)
+                return
+            }
+        }
+    }
+    bj, _ := json.Marshal(b)
+    aj, _ := json.Marshal(a)
+    if string(bj) != string(aj) {
+        *changes = append(*changes, configChange{Path: path, Old: b, New: a})
+    }
+}
+
+// joinPath appends the field key to path.
+func joinPath(path, key string) string {
+    if path == "" {
+        return key
+    }
+    return path + "." + key
+}
+
+// entryKey returns the field, id, name or username, by which the entries
+// of two lists of objects can be matched, or "" if they cannot.
+func entryKey(lists ...[]any) string {
+    for _, key := range []string{"id", "name", "username"} {
+        ok := true
+        for _, list := range lists {
+            for _, e := range list {
+                m, isMap := e.(map[string]any)
+                if !isMap {
+                    return ""
+                }
+                if _, has := m[key]; !has {
+                    ok = false
+                }
+            }
+        }
+        if ok {
+            return key
+        }
+    }
+    return ""
+}
+
+// diffEntriesByKey appends the differences between two lists of objects
+// matched by key to changes.
+func diffEntriesByKey(path, key string, b, a []any, changes *[]configChange) {
+    label := func(e any) string {
+        return fmt.Sprintf("%s[%s=%v]", path, key, e.(map[string]any)[key])
+    }
+    index := func(list []any) map[string]any {
+        m := make(map[string]any)
+        for _, e := range list {
+            m[label(e)] = e
+        }
+        return m
+    }
+    bm, am := index(b), index(a)
+    for _, e := range b {
+        l := label(e)
+        if ae, ok := am[l]; ok {
+            diffValue(l, e, ae, changes)
+        } else {
+            *changes = append(*changes, configChange{Path: l, Removed: true})
+        }
+    }
+    for _, e := range a {
+        if l := label(e); bm[l] == nil {
+            *changes = append(*changes, configChange{Path: l, Added: true})
+        }
+    }
+}
+
+// summariseChanges describes changes in full if there are few, otherwise
+// by their number in each top level field, e.g. "42 changes: users 12,
+// zones 30".
+func summariseChanges(changes []configChange) string {
+    if len(changes) <= maxListedChanges {
+        parts := make([]string, len(changes))
+        for i, c := range changes {
+            parts[i] = c.String()
+        }
+        return strings.Join(parts, ", ")
+    }
+    counts := make(map[string]int)
+    for _, c := range changes {
+        top := c.Path
+        if i := strings.IndexAny(top, ".["); i >= 0 {
+            top = top[:i]
+        }
+        counts[top]++
+    }
+    fields := make([]string, 0, len(counts))
+    for f := range counts {
+        fields = append(fields, f)
+    }
+    sort.Strings(fields)
+    parts := make([]string, len(fields))
+    for i, f := range fields {
+        parts[i] = fmt.Sprintf("%s %d", f, counts[f])
+    }
+    return fmt.Sprintf("%d changes: %s", len(changes), strings.Join(parts, ", "))
+}
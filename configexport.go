@@ -0,0 +1,130 @@
+package main
+
+// This file exports the configuration as a backup document, so that a
+// failed SD card does not mean setting up every zone and arm mode again.
+// The document wraps the configuration with its format version and a
+// checksum.  Secrets are redacted unless asked for, which leaves a backup
+// that can be kept anywhere but whose secrets must be entered again after
+// a restore.
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// configVersion is the version of the config.json format, raised whenever
+// a change to it needs existing configurations migrating.
+const configVersion = 1
+
+// configExport is the document returned by GET /api/config/export.
+// Checksum is "sha256:" and the hex SHA-256 of Config in compact JSON.
+type configExport struct {
+    ConfigVersion   int             `json:"config_version"`
+    ExportedAt      time.Time       `json:"exported_at"`
+    SecretsIncluded bool            `json:"secrets_included"`
+    Checksum        string          `json:"checksum"`
+    Config          json.RawMessage `json:"config"`
+}
+
+// configChecksum returns the checksum of a configuration in compact JSON.
+func configChecksum(data []byte) string {
+    sum := sha256.Sum256(data)
+    return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// redactConfig returns a copy of cfg with every secret replaced by
+// redactedSecret: password and PIN hashes, API token and key hashes, alert
+// and camera credentials and the LDAP, OIDC and MQTT passwords.  cfg is not
+// changed.
+func redactConfig(cfg Config) Config {
+    redact := func(s *string) {
+        if *s != "" {
+            *s = redactedSecret
+        }
+    }
+    cfg.Users = append([]User(nil), cfg.Users...)
+    for i := range cfg.Users {
+        u := &cfg.Users[i]
+        redact(&u.PasswordHash)
+        redact(&u.PINHash)
+        redact(&u.TOTPSecret)
+        u.RecoveryCodes = append([]string(nil), u.RecoveryCodes...)
+        for j := range u.RecoveryCodes {
+            redact(&u.RecoveryCodes[j])
+        }
+        u.Tokens = append([]APIToken(nil), u.Tokens...)
+        for j := range u.Tokens {
+            redact(&u.Tokens[j].Hash)
+        }
+    }
+    cfg.APIKeys = append([]APIKey(nil), cfg.APIKeys...)
+    for i := range cfg.APIKeys {
+        redact(&cfg.APIKeys[i].Hash)
+    }
+    cfg.Zones = append([]Zone(nil), cfg.Zones...)
+    for i := range cfg.Zones {
+        cfg.Zones[i] = redactZone(cfg.Zones[i])
+    }
+    cfg.Alerts = append([]AlertConfig(nil), cfg.Alerts...)
+    for i := range cfg.Alerts {
+        cfg.Alerts[i] = redactAlertConfig(cfg.Alerts[i])
+    }
+    if cfg.LDAP != nil {
+        ldap := *cfg.LDAP
+        redact(&ldap.BindPassword)
+        cfg.LDAP = &ldap
+    }
+    if cfg.OIDC != nil {
+        oidc := *cfg.OIDC
+        redact(&oidc.ClientSecret)
+        cfg.OIDC = &oidc
+    }
+    if cfg.MQTT != nil {
+        mqtt := *cfg.MQTT
+        redact(&mqtt.Password)
+        cfg.MQTT = &mqtt
+    }
+    return cfg
+}
+
+// handleConfigExport returns the running configuration as a download with
+// GET /api/config/export, secrets redacted unless include_secrets=true is
+// given.  Settings overridden by the environment are exported as they are
+// in config.json.  Exports are audited as config.export.  Admins only.
+func (s *Server) handleConfigExport(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    secrets := r.URL.Query().Get("include_secrets") == "true"
+    cfg := s.cfgMgr.Get().withFileValues()
+    if !secrets {
+        cfg = redactConfig(cfg)
+    }
+    data, err := json.Marshal(cfg)
+    if err != nil {
+        http.Error(w, "internal error", http.StatusInternalServerError)
+        return
+    }
+    now := localTime(time.Now())
+    doc := configExport{ConfigVersion: configVersion, ExportedAt: now, SecretsIncluded: secrets, Checksum: configChecksum(data), Config: data}
+    target := "redacted"
+    if secrets {
+        target = "with secrets"
+    }
+    s.logger.Log("configuration exported %s by %s", target, user.Username)
+    s.audit(r, user.Username, "config.export", target, nil, nil)
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="minder-config-%s.json"`, now.Format("20060102-150405")))
+    enc := json.NewEncoder(w)
+    enc.SetIndent("", "  ")
+    _ = enc.Encode(doc)
+}
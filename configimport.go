@@ -0,0 +1,334 @@
+package main
+
+// This file restores a configuration exported by GET /api/config/export.
+// The document goes through the same migration and validation as
+// config.json at startup, and replaces the running configuration, and the
+// file, only if it passes.  The file replaced is kept as a backup.
+// Secrets redacted by the export are taken from the running configuration
+// where it has the same user, zone, alert or section; the rest are cleared
+// and reported, and must be set again.
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sort"
+)
+
+// configChangeSet lists the entries of one kind added, removed and changed
+// by an import.
+type configChangeSet struct {
+    Added   []string `json:"added"`
+    Removed []string `json:"removed"`
+    Changed []string `json:"changed"`
+}
+
+// configImportResult reports what an import changes, or would change with
+// dry_run.  Unrestored lists the redacted secrets that could not be taken
+// from the running configuration.
+type configImportResult struct {
+    DryRun     bool            `json:"dry_run"`
+    Sections   []string        `json:"sections"`
+    Zones      configChangeSet `json:"zones"`
+    Users      configChangeSet `json:"users"`
+    ArmModes   configChangeSet `json:"arm_modes"`
+    Unrestored []string        `json:"unrestored_secrets"`
+    Warnings   []ConfigProblem `json:"warnings"`
+    Backup     string          `json:"backup,omitempty"`
+}
+
+// diffEntries compares two sets of entries by key, each encoded as JSON.
+func diffEntries(before, after map[string]any) configChangeSet {
+    cs := configChangeSet{Added: []string{}, Removed: []string{}, Changed: []string{}}
+    for key, b := range before {
+        a, ok := after[key]
+        if !ok {
+            cs.Removed = append(cs.Removed, key)
+            continue
+        }
+        bj, _ := json.Marshal(b)
+        aj, _ := json.Marshal(a)
+        if !bytes.Equal(bj, aj) {
+            cs.Changed = append(cs.Changed, key)
+        }
+    }
+    for key := range after {
+        if _, ok := before[key]; !ok {
+            cs.Added = append(cs.Added, key)
+        }
+    }
+    sort.Strings(cs.Added)
+    sort.Strings(cs.Removed)
+    sort.Strings(cs.Changed)
+    return cs
+}
+
+// diffConfigs summarises the changes from before to after.
+func diffConfigs(before, after Config) configImportResult {
+    zones := func(cfg Config) map[string]any {
+        m := make(map[string]any)
+        for _, z := range cfg.Zones {
+            m[fmt.Sprintf("%d (%s)", z.ID, z.Name)] = z
+        }
+        return m
+    }
+    users := func(cfg Config) map[string]any {
+        m := make(map[string]any)
+        for _, u := range cfg.Users {
+            m[u.Username] = u
+        }
+        return m
+    }
+    modes := func(cfg Config) map[string]any {
+        m := make(map[string]any)
+        for _, am := range cfg.ArmModes {
+            m[am.Name] = am
+        }
+        return m
+    }
+    sections := configSections(before, after)
+    if sections == nil {
+        sections = []string{}
+    }
+    return configImportResult{
+        Sections:   sections,
+        Zones:      diffEntries(zones(before), zones(after)),
+        Users:      diffEntries(users(before), users(after)),
+        ArmModes:   diffEntries(modes(before), modes(after)),
+        Unrestored: []string{},
+        Warnings:   []ConfigProblem{},
+    }
+}
+
+// restoreSecrets replaces the redacted secrets of cfg with those of the
+// same entries in current, clearing any it cannot, and returns where those
+// are.
+func restoreSecrets(cfg *Config, current Config) []string {
+    var unrestored []string
+    restore := func(path string, field *string, value string) {
+        if *field != redactedSecret {
+            return
+        }
+        *field = value
+        if value == "" {
+            unrestored = append(unrestored, path)
+        }
+    }
+    for i := range cfg.Users {
+        u := &cfg.Users[i]
+        old, _ := findUser(current.Users, u.Username)
+        restore(fmt.Sprintf("users[%d].password_hash", i), &u.PasswordHash, old.PasswordHash)
+        restore(fmt.Sprintf("users[%d].pin_hash", i), &u.PINHash, old.PINHash)
+        restore(fmt.Sprintf("users[%d].totp_secret", i), &u.TOTPSecret, old.TOTPSecret)
+        for j := range u.RecoveryCodes {
+            hash := ""
+            if j < len(old.RecoveryCodes) {
+                hash = old.RecoveryCodes[j]
+            }
+            restore(fmt.Sprintf("users[%d].recovery_codes[%d]", i, j), &u.RecoveryCodes[j], hash)
+        }
+        for j := range u.Tokens {
+            hash := ""
+            for _, t := range old.Tokens {
+                if t.ID == u.Tokens[j].ID {
+                    hash = t.Hash
+                }
+            }
+            restore(fmt.Sprintf("users[%d].api_tokens[%d].hash", i, j), &u.Tokens[j].Hash, hash)
+        }
+    }
+    for i := range cfg.APIKeys {
+        hash := ""
+        for _, k := range current.APIKeys {
+            if k.ID == cfg.APIKeys[i].ID {
+                hash = k.Hash
+            }
+        }
+        restore(fmt.Sprintf("api_keys[%d].hash", i), &cfg.APIKeys[i].Hash, hash)
+    }
+    for i := range cfg.Zones {
+        password := ""
+        for _, z := range current.Zones {
+            if z.ID == cfg.Zones[i].ID {
+                password = z.SnapshotPassword
+            }
+        }
+        restore(fmt.Sprintf("zones[%d].snapshot_password", i), &cfg.Zones[i].SnapshotPassword, password)
+    }
+    for i := range cfg.Alerts {
+        ac := &cfg.Alerts[i]
+        var existing AlertConfig
+        for _, c := range current.Alerts {
+            if c.ID == ac.ID {
+                existing = c
+            }
+        }
+        restore(fmt.Sprintf("alerts[%d].password", i), &ac.Password, existing.Password)
+        restore(fmt.Sprintf("alerts[%d].auth_token", i), &ac.AuthToken, existing.AuthToken)
+        restore(fmt.Sprintf("alerts[%d].token", i), &ac.Token, existing.Token)
+        restore(fmt.Sprintf("alerts[%d].secret", i), &ac.Secret, existing.Secret)
+        restore(fmt.Sprintf("alerts[%d].url", i), &ac.URL, existing.URL)
+        for j := range ac.URLs {
+            url := ""
+            if j < len(existing.URLs) {
+                url = existing.URLs[j]
+            }
+            restore(fmt.Sprintf("alerts[%d].urls[%d]", i, j), &ac.URLs[j], url)
+        }
+        for name, value := range ac.Headers {
+            restore(fmt.Sprintf("alerts[%d].headers.%s", i, name), &value, existing.Headers[name])
+            ac.Headers[name] = value
+        }
+    }
+    if cfg.LDAP != nil {
+        value := ""
+        if current.LDAP != nil {
+            value = current.LDAP.BindPassword
+        }
+        restore("ldap.bind_password", &cfg.LDAP.BindPassword, value)
+    }
+    if cfg.OIDC != nil {
+        value := ""
+        if current.OIDC != nil {
+            value = current.OIDC.ClientSecret
+        }
+        restore("oidc.client_secret", &cfg.OIDC.ClientSecret, value)
+    }
+    if cfg.MQTT != nil {
+        value := ""
+        if current.MQTT != nil {
+            value = current.MQTT.Password
+        }
+        restore("mqtt.password", &cfg.MQTT.Password, value)
+    }
+    return unrestored
+}
+
+// errImportLockout refuses an import that would lock its admin, and so
+// possibly every admin, out.
+var errImportLockout = errors.New("the import would remove, disable or demote your own account")
+
+// importLockout returns errForbidden if the admin importing cfg has been
+// disabled or demoted in current since the request was authenticated, and
+// errImportLockout if cfg would remove, disable or demote them.  As the
+// importer stays an enabled admin, an import never leaves none.
+func importLockout(current, cfg Config, username string) error {
+    if u, ok := findUser(current.Users, username); ok && (u.Disabled || !u.hasRole(RoleAdmin)) {
+        return errForbidden
+    }
+    if u, ok := findUser(cfg.Users, username); !ok || u.Disabled || !u.hasRole(RoleAdmin) {
+        return errImportLockout
+    }
+    return nil
+}
+
+// findUser returns the user named username and whether there is one.
+func findUser(users []User, username string) (User, bool) {
+    for _, u := range users {
+        if u.Username == username {
+            return u, true
+        }
+    }
+    return User{}, false
+}
+
+// importAllowed answers 403 or 409 for an error from importLockout and
+// returns false, or returns true if err is nil.
+func importAllowed(w http.ResponseWriter, err error) bool {
+    switch err {
+    case nil:
+        return true
+    case errForbidden:
+        http.Error(w, "forbidden", http.StatusForbidden)
+    default:
+        http.Error(w, err.Error(), http.StatusConflict)
+    }
+    return false
+}
+
+// handleConfigImport restores a configuration with POST
+// /api/config/import, whose body is a document from GET
+// /api/config/export.  With ?dry_run=true it only reports what would
+// change.  A document whose checksum does not match, from a newer version,
+// with errors, or that would remove, disable or demote the requesting
+// admin or leave no enabled admin is refused.  Imports are audited as config.import.  Admins only.
+func (s *Server) handleConfigImport(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if s.configReadOnly(w) {
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    var doc configExport
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 10<<20)).Decode(&doc); err != nil || len(doc.Config) == 0 {
+        http.Error(w, "invalid JSON", http.StatusBadRequest)
+        return
+    }
+    if doc.ConfigVersion > configVersion {
+        http.Error(w, fmt.Sprintf("config_version %d is newer than this server supports (%d)", doc.ConfigVersion, configVersion), http.StatusBadRequest)
+        return
+    }
+    var compact bytes.Buffer
+    if err := json.Compact(&compact, doc.Config); err != nil || configChecksum(compact.Bytes()) != doc.Checksum {
+        http.Error(w, "checksum mismatch", http.StatusBadRequest)
+        return
+    }
+    var cfg Config
+    if err := json.Unmarshal(doc.Config, &cfg); err != nil {
+        http.Error(w, "invalid config: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    current := s.cfgMgr.Get()
+    unrestored := restoreSecrets(&cfg, current)
+    // read_only_config can only be set in the file or the environment.
+    cfg.ReadOnlyConfig = current.withFileValues().ReadOnlyConfig
+    data, err := json.Marshal(cfg)
+    if err == nil {
+        cfg, err = s.cfgMgr.parse(data)
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    if !importAllowed(w, importLockout(current, cfg, user.Username)) {
+        return
+    }
+    result := diffConfigs(current, cfg)
+    result.DryRun = r.URL.Query().Get("dry_run") == "true"
+    if unrestored != nil {
+        result.Unrestored = unrestored
+    }
+    for _, p := range cfg.Validate() {
+        result.Warnings = append(result.Warnings, p)
+    }
+    if !result.DryRun {
+        // The configuration may have changed since it was read, so the
+        // check is repeated as it is replaced.
+        old, backup, err := s.cfgMgr.Replace(cfg, func(current Config) error {
+            return importLockout(current, cfg, user.Username)
+        })
+        if err == errForbidden || err == errImportLockout {
+            importAllowed(w, err)
+            return
+        }
+        if err != nil {
+            s.logger.Log("configuration import by %s failed: %v", user.Username, err)
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        result.Backup = backup
+        changes := summariseChanges(diffJSON(redactConfig(old), redactConfig(cfg)))
+        s.logger.Log("configuration imported by %s, previous configuration saved to %s: %s", user.Username, backup, changes)
+        s.audit(r, user.Username, "config.import", s.cfgMgr.Path(), nil, map[string]any{"sections": result.Sections, "zones": result.Zones, "users": result.Users, "arm_modes": result.ArmModes, "changes": changes})
+        s.applyConfig(old, "imported")
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(result)
+}
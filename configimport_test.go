@@ -0,0 +1,88 @@
+package main
+
+// Tests of importing a configuration: the refusal of imports that would
+// lock the importing admin, or every admin, out.
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// importDocument returns cfg as an export document.
+func importDocument(t *testing.T, cfg Config) []byte {
+    data, err := json.Marshal(cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    data, err = json.Marshal(configExport{ConfigVersion: configVersion, Checksum: configChecksum(data), Config: data})
+    if err != nil {
+        t.Fatal(err)
+    }
+    return data
+}
+
+func TestImportLockout(t *testing.T) {
+    users := func(alice, bob User) []User {
+        return []User{alice, bob}
+    }
+    alice := User{Username: "alice", PasswordHash: testHash("s3cret-pass"), Role: RoleAdmin}
+    bob := User{Username: "bob", PasswordHash: testHash("s3cret-pass"), Role: RoleAdmin}
+    demoted, disabled := alice, alice
+    demoted.Role = RoleOperator
+    disabled.Disabled = true
+    tests := []struct {
+        name  string
+        users []User
+        code  int
+    }{
+        {"unchanged", users(alice, bob), http.StatusOK},
+        {"importer removed", []User{bob}, http.StatusConflict},
+        {"importer demoted", users(demoted, bob), http.StatusConflict},
+        {"importer disabled", users(disabled, bob), http.StatusConflict},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := validTestConfig()
+            cfg.Users = users(alice, bob)
+            s, _ := newTestServer(t, cfg)
+            imported := validTestConfig()
+            imported.Users = tt.users
+            w := httptest.NewRecorder()
+            r := httptest.NewRequest("POST", "/api/config/import", bytes.NewReader(importDocument(t, imported)))
+            s.handleConfigImport(w, r, User{Username: "alice", Role: RoleAdmin, Admin: true})
+            if w.Code != tt.code {
+                t.Fatalf("status %d, want %d: %s", w.Code, tt.code, w.Body)
+            }
+            if u, _ := s.cfgMgr.FindUser("alice"); tt.code != http.StatusOK && (u.Role != RoleAdmin || u.Disabled) {
+                t.Errorf("alice left as %+v", u)
+            }
+        })
+    }
+}
+
+func TestImportByDemotedAdmin(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{{Username: "alice", Role: RoleAdmin}, {Username: "bob", Role: RoleAdmin}}
+    s, _ := newTestServer(t, cfg)
+    imported := s.cfgMgr.Get()
+    // bob demotes alice after their import request was authenticated, and
+    // before it replaces the configuration.
+    if err := s.cfgMgr.Update(func(c *Config) error {
+        c.Users[0].Role, c.Users[0].Admin = RoleViewer, false
+        return nil
+    }); err != nil {
+        t.Fatal(err)
+    }
+    _, _, err := s.cfgMgr.Replace(imported, func(current Config) error {
+        return importLockout(current, imported, "alice")
+    })
+    if err != errForbidden {
+        t.Fatalf("Replace: %v, want errForbidden", err)
+    }
+    if u, _ := s.cfgMgr.FindUser("alice"); u.Role != RoleViewer {
+        t.Errorf("import by a demoted admin restored alice as %s", u.Role)
+    }
+}
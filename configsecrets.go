@@ -0,0 +1,222 @@
+package main
+
+// This file encrypts the secrets kept in config.json, such as alert
+// passwords and tokens, so that the file and its backups do not hold them
+// in the clear.  Each secret is stored as "enc:" followed by base64 of a
+// random data key sealed with the master key and the secret sealed with
+// the data key, both with AES-256-GCM.  The master key comes from
+// MINDER_SECRET_KEY, or from the file named by MINDER_SECRET_KEY_FILE,
+// which should be kept out of backups of config.json.  Secrets are
+// decrypted when the file is read and encrypted whenever it is written;
+// plaintext secrets in an existing file are accepted, and encrypted the
+// next time it is saved.  Without a key the file is written as before.
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "sort"
+    "strings"
+)
+
+// Environment variables supplying the master key for secrets.
+const (
+    secretKeyEnv     = "MINDER_SECRET_KEY"
+    secretKeyFileEnv = "MINDER_SECRET_KEY_FILE"
+)
+
+// encryptedPrefix marks an encrypted secret.
+const encryptedPrefix = "enc:"
+
+// sealedKeySize is the size of a data key sealed with the master key:
+// nonce, key and tag.
+const sealedKeySize = 12 + 32 + 16
+
+// loadSecretKey reads the master key, base64 encoded, from secretKeyEnv
+// or the file named by secretKeyFileEnv.  It returns nil if neither is
+// set.
+func loadSecretKey() ([]byte, error) {
+    source, encoded := secretKeyEnv, os.Getenv(secretKeyEnv)
+    if encoded == "" {
+        path := os.Getenv(secretKeyFileEnv)
+        if path == "" {
+            return nil, nil
+        }
+        data, err := ioutil.ReadFile(path)
+        if err != nil {
+            return nil, fmt.Errorf("unable to read %s: %w", secretKeyFileEnv, err)
+        }
+        source, encoded = path, string(data)
+    }
+    key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+    if err != nil || len(key) != 32 {
+        return nil, fmt.Errorf("the secret key in %s must be 32 bytes, base64 encoded (e.g. from `head -c 32 /dev/urandom | base64`)", source)
+    }
+    return key, nil
+}
+
+// seal encrypts plaintext with key, returning the nonce and ciphertext.
+func seal(key, plaintext []byte) ([]byte, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    gcm, err := cipher.NewGCM(block)
+    if err != nil {
+        return nil, err
+    }
+    nonce := make([]byte, gcm.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return nil, err
+    }
+    return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// unseal decrypts the output of seal.
+func unseal(key, sealed []byte) ([]byte, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    gcm, err := cipher.NewGCM(block)
+    if err != nil {
+        return nil, err
+    }
+    if len(sealed) < gcm.NonceSize() {
+        return nil, errors.New("too short")
+    }
+    return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// encryptSecret encrypts a secret with a new data key, sealed with key.
+func encryptSecret(key []byte, secret string) (string, error) {
+    dataKey := make([]byte, 32)
+    if _, err := rand.Read(dataKey); err != nil {
+        return "", err
+    }
+    sealedKey, err := seal(key, dataKey)
+    if err != nil {
+        return "", err
+    }
+    sealed, err := seal(dataKey, []byte(secret))
+    if err != nil {
+        return "", err
+    }
+    return encryptedPrefix + base64.StdEncoding.EncodeToString(append(sealedKey, sealed...)), nil
+}
+
+// decryptSecret decrypts a secret encrypted by encryptSecret.
+func decryptSecret(key []byte, value string) (string, error) {
+    data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+    if err != nil || len(data) < sealedKeySize {
+        return "", errors.New("malformed encrypted secret")
+    }
+    dataKey, err := unseal(key, data[:sealedKeySize])
+    if err != nil {
+        return "", errors.New("unable to decrypt secret: wrong key?")
+    }
+    secret, err := unseal(dataKey, data[sealedKeySize:])
+    if err != nil {
+        return "", errors.New("unable to decrypt secret: corrupted")
+    }
+    return string(secret), nil
+}
+
+// mapSecrets returns a copy of cfg with fn applied to every secret: alert
+// credentials, including webhook header values and the URLs of Slack and
+// Discord, camera passwords, two-factor secrets and the LDAP, OIDC and
+// MQTT passwords.  Password and token hashes are not secrets in this sense and are left as
+// they are.  cfg is not changed.
+func (cfg Config) mapSecrets(fn func(path, value string) (string, error)) (Config, error) {
+    var err error
+    apply := func(path string, field *string) {
+        if err != nil || *field == "" {
+            return
+        }
+        *field, err = fn(path, *field)
+    }
+    cfg.Zones = append([]Zone(nil), cfg.Zones...)
+    for i := range cfg.Zones {
+        apply(fmt.Sprintf("zones[%d].snapshot_password", i), &cfg.Zones[i].SnapshotPassword)
+    }
+    cfg.Users = append([]User(nil), cfg.Users...)
+    for i := range cfg.Users {
+        apply(fmt.Sprintf("users[%d].totp_secret", i), &cfg.Users[i].TOTPSecret)
+    }
+    cfg.Alerts = append([]AlertConfig(nil), cfg.Alerts...)
+    for i := range cfg.Alerts {
+        ac := &cfg.Alerts[i]
+        apply(fmt.Sprintf("alerts[%d].password", i), &ac.Password)
+        apply(fmt.Sprintf("alerts[%d].auth_token", i), &ac.AuthToken)
+        apply(fmt.Sprintf("alerts[%d].token", i), &ac.Token)
+        apply(fmt.Sprintf("alerts[%d].secret", i), &ac.Secret)
+        if alertURLsSecret(*ac) {
+            apply(fmt.Sprintf("alerts[%d].url", i), &ac.URL)
+            ac.URLs = append([]string(nil), ac.URLs...)
+            for j := range ac.URLs {
+                apply(fmt.Sprintf("alerts[%d].urls[%d]", i, j), &ac.URLs[j])
+            }
+        }
+        if len(ac.Headers) > 0 {
+            names := make([]string, 0, len(ac.Headers))
+            for name := range ac.Headers {
+                names = append(names, name)
+            }
+            sort.Strings(names)
+            headers := make(map[string]string, len(ac.Headers))
+            for _, name := range names {
+                value := ac.Headers[name]
+                apply(fmt.Sprintf("alerts[%d].headers.%s", i, name), &value)
+                headers[name] = value
+            }
+            ac.Headers = headers
+        }
+    }
+    if cfg.LDAP != nil {
+        ldap := *cfg.LDAP
+        apply("ldap.bind_password", &ldap.BindPassword)
+        cfg.LDAP = &ldap
+    }
+    if cfg.OIDC != nil {
+        oidc := *cfg.OIDC
+        apply("oidc.client_secret", &oidc.ClientSecret)
+        cfg.OIDC = &oidc
+    }
+    if cfg.MQTT != nil {
+        mqtt := *cfg.MQTT
+        apply("mqtt.password", &mqtt.Password)
+        cfg.MQTT = &mqtt
+    }
+    return cfg, err
+}
+
+// decryptSecrets returns cfg with its encrypted secrets decrypted with
+// key.  Plaintext secrets are kept.  It fails if there are encrypted
+// secrets and no key.
+func decryptSecrets(cfg Config, key []byte) (Config, error) {
+    return cfg.mapSecrets(func(path, value string) (string, error) {
+        if !strings.HasPrefix(value, encryptedPrefix) {
+            return value, nil
+        }
+        if key == nil {
+            return "", fmt.Errorf("%s is encrypted but no secret key is set: set %s or %s", path, secretKeyEnv, secretKeyFileEnv)
+        }
+        secret, err := decryptSecret(key, value)
+        if err != nil {
+            return "", fmt.Errorf("%s: %w", path, err)
+        }
+        return secret, nil
+    })
+}
+
+// encryptSecrets returns cfg with its secrets encrypted with key.
+func encryptSecrets(cfg Config, key []byte) (Config, error) {
+    return cfg.mapSecrets(func(path, value string) (string, error) {
+        return encryptSecret(key, value)
+    })
+}
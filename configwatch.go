@@ -0,0 +1,138 @@
+package main
+
+// This file lets subsystems follow changes to the configuration instead
+// of reading it afresh on every use or keeping what they built at
+// startup.  A subscriber is called with the configuration before and after
+// every change made by Update, Replace or Reload.  Each subscriber runs in
+// its own goroutine, so a slow one delays neither Save nor the others;
+// changes that arrive while it is busy are merged into one call, from the
+// oldest configuration not yet seen to the newest.
+
+import (
+    "reflect"
+    "strings"
+    "sync"
+    "time"
+)
+
+// configSubscriber is a function following changes to the configuration,
+// with the change waiting for it.
+type configSubscriber struct {
+    fn      func(old, cfg Config)
+    mu      sync.Mutex
+    pending bool
+    old     Config
+    cfg     Config
+    wake    chan struct{}
+}
+
+// Subscribe calls fn with the old and new configuration after every
+// change, until the program exits.  Calls are made one at a time in a
+// goroutine of their own.  fn may be called when nothing fn cares about
+// has changed, so should compare what it uses.
+func (cm *ConfigManager) Subscribe(fn func(old, cfg Config)) {
+    sub := &configSubscriber{fn: fn, wake: make(chan struct{}, 1)}
+    cm.subsMu.Lock()
+    cm.subs = append(cm.subs, sub)
+    cm.subsMu.Unlock()
+    go sub.run()
+}
+
+// subscribed reports whether anything follows changes to the
+// configuration.
+func (cm *ConfigManager) subscribed() bool {
+    cm.subsMu.Lock()
+    defer cm.subsMu.Unlock()
+    return len(cm.subs) > 0
+}
+
+// notify passes a change from old to cfg to every subscriber without
+// waiting for them.
+func (cm *ConfigManager) notify(old, cfg Config) {
+    cm.subsMu.Lock()
+    subs := cm.subs
+    cm.subsMu.Unlock()
+    for _, sub := range subs {
+        sub.mu.Lock()
+        if !sub.pending {
+            sub.pending = true
+            sub.old = old
+        }
+        sub.cfg = cfg
+        sub.mu.Unlock()
+        select {
+        case sub.wake <- struct{}{}:
+        default:
+        }
+    }
+}
+
+// run calls the subscriber with each change as it arrives.
+func (sub *configSubscriber) run() {
+    for range sub.wake {
+        sub.mu.Lock()
+        if !sub.pending {
+            sub.mu.Unlock()
+            continue
+        }
+        old, cfg := sub.old, sub.cfg
+        sub.pending = false
+        sub.old, sub.cfg = Config{}, Config{}
+        sub.mu.Unlock()
+        sub.fn(old, cfg)
+    }
+}
+
+// zoneIndex holds the zones polled in each arm mode, worked out from the
+// configuration when it changes rather than on every poll.
+type zoneIndex struct {
+    // all lists every enabled zone, for the wiring and walk tests.
+    all []Zone
+    // modes lists the enabled active zones of each arm mode, by the
+    // mode's name in lower case.
+    modes map[string][]Zone
+}
+
+// newZoneIndex indexes the zones of cfg.
+func newZoneIndex(cfg Config) *zoneIndex {
+    idx := &zoneIndex{modes: make(map[string][]Zone)}
+    byID := make(map[int]Zone)
+    for _, z := range cfg.Zones {
+        if z.Enabled {
+            idx.all = append(idx.all, z)
+            byID[z.ID] = z
+        }
+    }
+    for _, am := range cfg.ArmModes {
+        name := strings.ToLower(am.Name)
+        if _, dup := idx.modes[name]; dup {
+            continue
+        }
+        var zones []Zone
+        for _, id := range am.ActiveZones {
+            if z, ok := byID[id]; ok {
+                zones = append(zones, z)
+            }
+        }
+        idx.modes[name] = zones
+    }
+    return idx
+}
+
+// watchConfig subscribes the server's subsystems to configuration
+// changes: the poll loop's zones, and the alert handlers and their
+// heartbeat schedules.
+func (s *Server) watchConfig() {
+    s.cfgMgr.Subscribe(func(old, cfg Config) {
+        if !reflect.DeepEqual(old.Zones, cfg.Zones) || !reflect.DeepEqual(old.ArmModes, cfg.ArmModes) {
+            s.zones.Store(newZoneIndex(cfg))
+        }
+    })
+    s.cfgMgr.Subscribe(func(old, cfg Config) {
+        if reflect.DeepEqual(old.Alerts, cfg.Alerts) {
+            return
+        }
+        s.reloadAlerts(cfg)
+        s.runHeartbeats(time.Now())
+    })
+}
@@ -0,0 +1,139 @@
+package main
+
+// This file lets config.json be written in YAML instead, for comments
+// explaining why a zone is NC or a pin was chosen.  The format follows the
+// file's extension: .yaml and .yml are YAML and anything else JSON, so
+// moving to YAML is a matter of renaming the file (JSON is valid YAML).
+// YAML is converted to JSON when read and the JSON struct tags apply as
+// before.  When a YAML file is saved its comments are carried over to the
+// new file, matching mapping keys by name and list entries by their id,
+// name or username, so comments stay with the setting, zone or user they
+// describe.
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "path/filepath"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// isYAML reports whether the file at path is in YAML.
+func isYAML(path string) bool {
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        return true
+    }
+    return false
+}
+
+// decodeFile returns the content of the file at path as JSON.
+func decodeFile(path string, data []byte) ([]byte, error) {
+    if !isYAML(path) {
+        return data, nil
+    }
+    var v any
+    if err := yaml.Unmarshal(data, &v); err != nil {
+        return nil, err
+    }
+    return json.Marshal(v)
+}
+
+// encodeFile returns JSON data in the format of the file at path.  For
+// YAML the comments of previous, the file replaced, are kept.
+func encodeFile(path string, data, previous []byte) ([]byte, error) {
+    if !isYAML(path) {
+        return data, nil
+    }
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil {
+        return nil, err
+    }
+    plainStyle(&doc)
+    var old yaml.Node
+    if yaml.Unmarshal(previous, &old) == nil {
+        copyComments(&old, &doc)
+    }
+    var buf bytes.Buffer
+    enc := yaml.NewEncoder(&buf)
+    enc.SetIndent(2)
+    if err := enc.Encode(&doc); err != nil {
+        return nil, fmt.Errorf("unable to encode %s: %w", path, err)
+    }
+    if err := enc.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// plainStyle gives n and the nodes below it the default block style, in
+// place of the flow style and quotes of the JSON they were read from.
+func plainStyle(n *yaml.Node) {
+    n.Style = 0
+    for _, c := range n.Content {
+        plainStyle(c)
+    }
+}
+
+// copyComments copies the comments of from to the matching nodes of to.
+func copyComments(from, to *yaml.Node) {
+    to.HeadComment = from.HeadComment
+    to.LineComment = from.LineComment
+    to.FootComment = from.FootComment
+    switch {
+    case from.Kind == yaml.DocumentNode && to.Kind == yaml.DocumentNode:
+        if len(from.Content) > 0 && len(to.Content) > 0 {
+            copyComments(from.Content[0], to.Content[0])
+        }
+    case from.Kind == yaml.MappingNode && to.Kind == yaml.MappingNode:
+        for i := 0; i+1 < len(to.Content); i += 2 {
+            if j := mappingKey(from, to.Content[i].Value); j >= 0 {
+                copyComments(from.Content[j], to.Content[i])
+                copyComments(from.Content[j+1], to.Content[i+1])
+            }
+        }
+    case from.Kind == yaml.SequenceNode && to.Kind == yaml.SequenceNode:
+        for i, item := range to.Content {
+            if old := sequenceItem(from, item, i); old != nil {
+                copyComments(old, item)
+            }
+        }
+    }
+}
+
+// mappingKey returns the index of the key named key in the mapping node
+// m, or -1.
+func mappingKey(m *yaml.Node, key string) int {
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        if m.Content[i].Value == key {
+            return i
+        }
+    }
+    return -1
+}
+
+// sequenceItem returns the entry of the sequence node seq matching item,
+// the i'th entry of the new sequence: the mapping with the same id, name
+// or username if it has one, otherwise the i'th entry.
+func sequenceItem(seq, item *yaml.Node, i int) *yaml.Node {
+    if item.Kind == yaml.MappingNode {
+        for _, key := range []string{"id", "name", "username"} {
+            k := mappingKey(item, key)
+            if k < 0 {
+                continue
+            }
+            for _, old := range seq.Content {
+                if j := mappingKey(old, key); j >= 0 && old.Content[j+1].Value == item.Content[k+1].Value {
+                    return old
+                }
+            }
+            return nil
+        }
+    }
+    if i < len(seq.Content) {
+        return seq.Content[i]
+    }
+    return nil
+}
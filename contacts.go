@@ -0,0 +1,94 @@
+package main
+
+// This file lets alerts notify users directly.  An alert with NotifyUsers
+// set sends, in addition to its own recipients, to the email address or
+// phone number of every enabled user who has not turned notifications off
+// and whose preferences ask for the event.
+// The set is read when each alert is sent, so adding a family member needs
+// no change to the alert configurations.
+
+import (
+    "errors"
+    "net/mail"
+    "strings"
+)
+
+// userDirectory returns the current users.  Handlers keep one rather than a
+// copy of the users so that changes apply to the next alert.
+type userDirectory func() []User
+
+// currentUsers returns the configured users; it is the userDirectory given
+// to alert handlers.
+func (s *Server) currentUsers() []User {
+    return s.cfgMgr.Get().Users
+}
+
+// notifiable reports whether u should receive event from alerts sent to
+// users.
+func (u User) notifiable(event AlertEvent) bool {
+    return !u.Disabled && !u.NotificationsOff && u.preferences().wants(event)
+}
+
+// emails returns the addresses of the users to notify of event by email.
+func (d userDirectory) emails(event AlertEvent) []string {
+    var out []string
+    if d == nil {
+        return out
+    }
+    for _, u := range d() {
+        if u.notifiable(event) && u.Email != "" {
+            out = append(out, u.Email)
+        }
+    }
+    return out
+}
+
+// phones returns the numbers of the users to notify of event by SMS or
+// voice call.
+func (d userDirectory) phones(event AlertEvent) []string {
+    var out []string
+    if d == nil {
+        return out
+    }
+    for _, u := range d() {
+        if u.notifiable(event) && u.Phone != "" {
+            out = append(out, u.Phone)
+        }
+    }
+    return out
+}
+
+// appendUnique appends the entries of extra not already in list, ignoring
+// case, so a user who is also listed in an alert is contacted once.
+func appendUnique(list []string, extra ...string) []string {
+    out := append([]string(nil), list...)
+    for _, e := range extra {
+        dup := false
+        for _, have := range out {
+            if strings.EqualFold(have, e) {
+                dup = true
+                break
+            }
+        }
+        if !dup {
+            out = append(out, e)
+        }
+    }
+    return out
+}
+
+// validateContact checks a user's email address and phone number, either
+// of which may be empty.
+func validateContact(email, phone string) error {
+    if email != "" {
+        if a, err := mail.ParseAddress(email); err != nil || a.Address != email {
+            return errors.New("email must be a plain address such as alice@example.com")
+        }
+    }
+    if phone != "" {
+        if len(phone) < 8 || len(phone) > 16 || phone[0] != '+' || strings.Trim(phone[1:], "0123456789") != "" {
+            return errors.New("phone must be in E.164 format, e.g. +447700900123")
+        }
+    }
+    return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+// This file holds the alarm commands that several interfaces offer: arm,
+// disarm, acknowledge and bypass, as sent to the REST API and over the
+// WebSocket.  Each checks that the user's role allows it, so that the
+// interfaces cannot drift apart, and leaves parsing the request and
+// reporting the outcome to its caller.
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+var (
+    // errForbidden is returned by commands the user's role does not allow.
+    errForbidden = errors.New("forbidden")
+    // errZoneNotFound is returned by bypassZone for an unknown zone.
+    errZoneNotFound = errors.New("not found")
+)
+
+// armAs arms mode on behalf of user, an operator or admin allowed to arm
+// it.
+func (s *Server) armAs(user User, mode string) error {
+    if !user.hasRole(RoleOperator) {
+        return errForbidden
+    }
+    if !user.mayArm(mode) {
+        s.logger.Log("arm %s refused for %s", strings.TrimSpace(mode), user.Username)
+        return errForbidden
+    }
+    return s.arm(strings.TrimSpace(mode), user.Username)
+}
+
+// disarmAs disarms on behalf of user, an operator or admin.  With a PIN
+// the disarm is made by its owner on user's keypad, see pinDisarm.
+func (s *Server) disarmAs(r *http.Request, user User, username, pin string) error {
+    if !user.hasRole(RoleOperator) {
+        return errForbidden
+    }
+    if pin == "" {
+        s.disarm(user.Username)
+        return nil
+    }
+    return s.pinDisarm(r, user, username, pin)
+}
+
+// acknowledgeAs acknowledges the alarm on behalf of user, an operator or
+// admin.
+func (s *Server) acknowledgeAs(user User) error {
+    if !user.hasRole(RoleOperator) {
+        return errForbidden
+    }
+    s.acknowledge(user.Username)
+    return nil
+}
+
+// bypassZone disables zone id on behalf of user, an operator or admin, if
+// bypass is set, or enables it again, and returns the zone.  Bypassing is
+// a runtime operation allowed with read_only_config.
+func (s *Server) bypassZone(r *http.Request, user User, id int, bypass bool) (Zone, error) {
+    if !user.hasRole(RoleOperator) {
+        return Zone{}, errForbidden
+    }
+    var before, updated Zone
+    err := s.cfgMgr.Update(func(c *Config) error {
+        for i, z := range c.Zones {
+            if z.ID == id {
+                before = z
+                c.Zones[i].Enabled = !bypass
+                updated = c.Zones[i]
+                return nil
+            }
+        }
+        return errZoneNotFound
+    })
+    if err != nil {
+        return Zone{}, err
+    }
+    // A disabled zone is no longer monitored, so drop any stale trigger.
+    if bypass {
+        s.triggerMu.Lock()
+        delete(s.triggered, id)
+        s.triggerMu.Unlock()
+    }
+    if before.Enabled == updated.Enabled {
+        return updated, nil
+    }
+    change := fmt.Sprintf("enabled %t -> %t", before.Enabled, updated.Enabled)
+    s.logger.Log("patch zone id=%d by %s: %s", id, user.Username, change)
+    s.audit(r, user.Username, "zone.update", fmt.Sprintf("zone %d", id), redactZone(before), redactZone(updated))
+    s.adminChange(user.Username, "zone id=%d changed: %s", id, change)
+    return updated, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+// This file lets web pages on other origins, such as a home dashboard,
+// call the API from the browser.  It is off unless the cors section lists
+// allowed_origins.  Preflight OPTIONS requests to /api from a listed
+// origin are answered here, before authentication, and other API
+// responses to it carry the Access-Control headers; requests from any
+// other origin are served as before, without them, so the browser keeps
+// their responses from the page.  "*" allows every origin, but only
+// without allow_credentials, so that cookies are never offered to any
+// site that asks.  The settings are read on every request.
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+)
+
+// Defaults of the cors section.
+var (
+    defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+    defaultCORSHeaders = []string{"Authorization", "Content-Type", "X-API-Key", csrfHeader}
+)
+
+// defaultCORSMaxAge is how long, in seconds, browsers may cache a
+// preflight response when max_age is zero.
+const defaultCORSMaxAge = 600
+
+// withCORS adds the Access-Control headers of the cors section to API
+// responses for allowed origins and answers their preflight requests.
+func (s *Server) withCORS(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        cc := s.cfgMgr.Get().CORS
+        origin := r.Header.Get("Origin")
+        if cc == nil || len(cc.AllowedOrigins) == 0 || origin == "" || !(r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/")) {
+            next.ServeHTTP(w, r)
+            return
+        }
+        h := w.Header()
+        h.Add("Vary", "Origin")
+        allowed, ok := corsOrigin(cc, origin)
+        if !ok {
+            next.ServeHTTP(w, r)
+            return
+        }
+        h.Set("Access-Control-Allow-Origin", allowed)
+        if cc.AllowCredentials {
+            h.Set("Access-Control-Allow-Credentials", "true")
+        }
+        if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+            h.Set("Access-Control-Expose-Headers", csrfHeader)
+            next.ServeHTTP(w, r)
+            return
+        }
+        methods, headers := cc.AllowedMethods, cc.AllowedHeaders
+        if len(methods) == 0 {
+            methods = defaultCORSMethods
+        }
+        if len(headers) == 0 {
+            headers = defaultCORSHeaders
+        }
+        maxAge := cc.MaxAge
+        if maxAge == 0 {
+            maxAge = defaultCORSMaxAge
+        }
+        h.Add("Vary", "Access-Control-Request-Method")
+        h.Add("Vary", "Access-Control-Request-Headers")
+        h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+        h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+        h.Set("Access-Control-Max-Age", strconv.Itoa(maxAge))
+        w.WriteHeader(http.StatusNoContent)
+    })
+}
+
+// corsOrigin returns the value of Access-Control-Allow-Origin for a
+// request from origin, and whether cc allows it at all.
+func corsOrigin(cc *CORSConfig, origin string) (string, bool) {
+    for _, o := range cc.AllowedOrigins {
+        switch {
+        case o == "*" && !cc.AllowCredentials:
+            return "*", true
+        case strings.EqualFold(strings.TrimSuffix(o, "/"), origin):
+            return origin, true
+        }
+    }
+    return "", false
+}
+
+// validateCORSConfig checks the cors section of config.json.
+func validateCORSConfig(cc *CORSConfig) error {
+    if cc == nil {
+        return nil
+    }
+    for _, o := range cc.AllowedOrigins {
+        if o == "*" {
+            if cc.AllowCredentials {
+                return errors.New(`cors allowed origin "*" cannot be used with allow_credentials; list the origins`)
+            }
+            continue
+        }
+        u, err := url.Parse(strings.TrimSuffix(o, "/"))
+        if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.User != nil {
+            return fmt.Errorf("cors allowed origin %q must be a scheme and host, e.g. https://dash.example.org:8080", o)
+        }
+    }
+    for _, m := range cc.AllowedMethods {
+        if m == "" || m != strings.ToUpper(m) || strings.ContainsAny(m, " ,") {
+            return fmt.Errorf("cors allowed method %q must be an upper case method name, e.g. GET", m)
+        }
+    }
+    for _, h := range cc.AllowedHeaders {
+        if h == "" || h == "*" || strings.ContainsAny(h, " ,:") {
+            return fmt.Errorf("cors allowed header %q must be a header name", h)
+        }
+    }
+    if cc.MaxAge < 0 {
+        return errors.New("cors max_age must not be negative")
+    }
+    return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+// This file protects cookie-authenticated requests against cross-site
+// request forgery with per-session synchronizer tokens.  Every session gets
+// a random token when it starts; it is returned in the X-CSRF-Token header
+// of the login response and of every authenticated response, and by GET
+// /api/csrf.  Requests other than GET, HEAD and OPTIONS must send it back in
+// the same header.  Bearer token requests carry no cookie and are exempt.
+//
+// Requests authenticated by a reverse proxy's header or a client
+// certificate have no session to hold a token, yet the browser supplies
+// their credentials by itself just as it does a cookie.  Those other than
+// GET, HEAD and OPTIONS must instead come from a page of Minder's own
+// origin, as told by Sec-Fetch-Site, Origin or Referer; a request without
+// any of them is not from a browser page and is let through.
+
+import (
+    "crypto/subtle"
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "strings"
+)
+
+// csrfHeader carries the CSRF token in both directions.
+const csrfHeader = "X-CSRF-Token"
+
+// validCSRF reports whether r, made with the session sess, may proceed:
+// either it is a safe method or it carries the session's CSRF token.
+func validCSRF(r *http.Request, sess Session) bool {
+    switch r.Method {
+    case http.MethodGet, http.MethodHead, http.MethodOptions:
+        return true
+    }
+    token := r.Header.Get(csrfHeader)
+    return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(sess.CSRF)) == 1
+}
+
+// sameOrigin reports whether r, authenticated by credentials the browser
+// sends by itself, may proceed: either it is a safe method or it does not
+// come from a page of another origin.
+func (s *Server) sameOrigin(r *http.Request) bool {
+    switch r.Method {
+    case http.MethodGet, http.MethodHead, http.MethodOptions:
+        return true
+    }
+    switch r.Header.Get("Sec-Fetch-Site") {
+    case "same-origin", "none":
+        return true
+    case "":
+    default:
+        return false
+    }
+    from := r.Header.Get("Origin")
+    if from == "" {
+        from = r.Header.Get("Referer")
+    }
+    if from == "" {
+        return true
+    }
+    u, err := url.Parse(from)
+    if err != nil || u.Host == "" {
+        return false
+    }
+    for _, host := range s.ownHosts(r) {
+        if strings.EqualFold(u.Host, host) {
+            return true
+        }
+    }
+    return false
+}
+
+// ownHosts returns the hosts Minder is reached at by r: the Host header,
+// the X-Forwarded-Host a trusted proxy set, and the host of base_url.
+func (s *Server) ownHosts(r *http.Request) []string {
+    cfg := s.cfgMgr.Get()
+    hosts := []string{r.Host}
+    proxied := fromTrustedProxy(r, cfg.TrustedProxies) ||
+        (cfg.ProxyAuth != nil && trustedProxy(remoteHost(r), cfg.ProxyAuth.Proxies))
+    if fwd := r.Header.Values("X-Forwarded-Host"); proxied && len(fwd) > 0 {
+        // The last entry is the one added by the proxy in front of us.
+        all := strings.Split(strings.Join(fwd, ","), ",")
+        hosts = append(hosts, strings.TrimSpace(all[len(all)-1]))
+    }
+    if base, err := url.Parse(cfg.BaseURL); err == nil && base.Host != "" {
+        hosts = append(hosts, base.Host)
+    }
+    return hosts
+}
+
+// handleCSRF returns the CSRF token of the caller's session with GET
+// /api/csrf, for clients that did not keep it from the login response.
+func (s *Server) handleCSRF(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    sess, _, ok := s.requestSession(r)
+    if !ok {
+        http.Error(w, "CSRF tokens belong to cookie sessions", http.StatusBadRequest)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(map[string]string{"token": sess.CSRF})
+}
@@ -0,0 +1,48 @@
+package main
+
+// Tests of the cross-site request forgery checks on requests whose
+// credentials the browser sends by itself.
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestProxyAuthSameOrigin(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.BaseURL = "https://alarm.example.org"
+    cfg.ProxyAuth = &ProxyAuthConfig{Enabled: true, Proxies: []string{"192.0.2.1"}}
+    cfg.Users = []User{{Username: "alice", Role: RoleOperator}}
+    s, _ := newTestServer(t, cfg)
+    tests := []struct {
+        name    string
+        method  string
+        headers map[string]string
+        want    int
+    }{
+        {"safe method from another site", "GET", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, http.StatusOK},
+        {"same origin", "POST", map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "https://alarm.example.org"}, http.StatusOK},
+        {"cross-site", "POST", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+        {"same site, other origin", "POST", map[string]string{"Sec-Fetch-Site": "same-site"}, http.StatusForbidden},
+        {"base_url origin", "POST", map[string]string{"Origin": "https://alarm.example.org"}, http.StatusOK},
+        {"forwarded host", "DELETE", map[string]string{"Origin": "https://minder.lan", "X-Forwarded-Host": "minder.lan"}, http.StatusOK},
+        {"other origin", "POST", map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+        {"null origin", "POST", map[string]string{"Origin": "null"}, http.StatusForbidden},
+        {"other referer", "PUT", map[string]string{"Referer": "https://evil.example/page"}, http.StatusForbidden},
+        {"no browser headers", "POST", nil, http.StatusOK},
+    }
+    for _, tt := range tests {
+        r := httptest.NewRequest(tt.method, "/api/status", nil)
+        r.RemoteAddr = "192.0.2.1:4000"
+        r.Header.Set("Remote-User", "alice")
+        for k, v := range tt.headers {
+            r.Header.Set(k, v)
+        }
+        w := httptest.NewRecorder()
+        s.withAuth(func(w http.ResponseWriter, r *http.Request, user User) {})(w, r)
+        if w.Code != tt.want {
+            t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+        }
+    }
+}
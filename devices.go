@@ -0,0 +1,411 @@
+package main
+
+// This file implements remembered devices.  A password login with
+// "remember": true also registers the device and sets a long-lived refresh
+// token cookie, which POST /api/session/refresh exchanges for a new
+// short-lived session.  The token rotates at every use.  The hashes of used
+// tokens are kept, and presenting one again means the token was copied, so
+// the device and its sessions are revoked.
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// devicesPath is the file remembered devices are persisted to.
+const devicesPath = "devices.json"
+
+// defaultRememberTTL is how long an unused device stays remembered if
+// Config.RememberTTL is not set.
+const defaultRememberTTL = 90 * 24 * time.Hour
+
+// maxUsedRefreshTokens bounds the used token hashes kept per device for
+// detecting reuse.
+const maxUsedRefreshTokens = 32
+
+// The refresh token cookie is only sent to the refresh endpoint.
+const (
+    refreshCookie     = "remember"
+    refreshCookiePath = "/api/session/refresh"
+)
+
+// Errors returned by DeviceManager.Refresh.
+var (
+    errRefreshInvalid = errors.New("invalid or expired refresh token")
+    errRefreshReused  = errors.New("refresh token reused")
+)
+
+// Device is a remembered device.  Only the hash of its current refresh
+// token is stored, along with the hashes of the tokens it has used.
+type Device struct {
+    ID         string    `json:"id"`
+    Username   string    `json:"username"`
+    // Name is the user agent the device was registered with.
+    Name       string    `json:"name,omitempty"`
+    // IP is the client address of the latest login or refresh.
+    IP         string    `json:"ip,omitempty"`
+    Created    time.Time `json:"created"`
+    LastUsed   time.Time `json:"last_used"`
+    Expires    time.Time `json:"expires"`
+    TokenHash  string    `json:"token_hash"`
+    UsedHashes []string  `json:"used_hashes,omitempty"`
+}
+
+// DeviceManager holds the remembered devices, keyed by ID.  Unless
+// persistence is disabled every change is written to devices.json.
+type DeviceManager struct {
+    mu      sync.Mutex
+    devices map[string]Device
+    path    string
+    logger  *EventLogger
+}
+
+// NewDeviceManager constructs a device store.  If path is not empty the
+// unexpired devices saved there are restored and every change is written
+// back.
+func NewDeviceManager(path string, logger *EventLogger) *DeviceManager {
+    dm := &DeviceManager{devices: make(map[string]Device), path: path, logger: logger}
+    if path != "" {
+        dm.load()
+    }
+    return dm
+}
+
+// rememberTTL returns how long an unused device stays remembered under cfg.
+func rememberTTL(cfg Config) time.Duration {
+    if cfg.RememberTTL > 0 {
+        return time.Duration(cfg.RememberTTL) * time.Second
+    }
+    return defaultRememberTTL
+}
+
+// load restores the devices saved by a previous run, dropping expired ones.
+func (dm *DeviceManager) load() {
+    data, err := ioutil.ReadFile(dm.path)
+    if err != nil {
+        if !os.IsNotExist(err) {
+            dm.logger.Log("unable to read %s: %v", dm.path, err)
+        }
+        return
+    }
+    var saved map[string]Device
+    if err := json.Unmarshal(data, &saved); err != nil {
+        dm.logger.Log("ignoring invalid %s: %v", dm.path, err)
+        return
+    }
+    now := time.Now()
+    for id, d := range saved {
+        if now.Before(d.Expires) && d.Username != "" && d.TokenHash != "" {
+            dm.devices[id] = d
+        }
+    }
+}
+
+// saveLocked writes the devices to disk if persistence is enabled.  The
+// caller must hold dm.mu.
+func (dm *DeviceManager) saveLocked() {
+    if dm.path == "" {
+        return
+    }
+    data, err := json.MarshalIndent(dm.devices, "", "  ")
+    if err == nil {
+        tmpPath := dm.path + ".tmp"
+        if err = ioutil.WriteFile(tmpPath, data, 0600); err == nil {
+            err = os.Rename(tmpPath, dm.path)
+        }
+    }
+    if err != nil {
+        dm.logger.Log("unable to save %s: %v", dm.path, err)
+    }
+}
+
+// Register remembers a device of username logging in from ip with
+// userAgent and returns it with its first refresh token.
+func (dm *DeviceManager) Register(username, ip, userAgent string, ttl time.Duration) (Device, string, error) {
+    id, err := randomString(6)
+    if err != nil {
+        return Device{}, "", err
+    }
+    token, err := randomString(32)
+    if err != nil {
+        return Device{}, "", err
+    }
+    now := time.Now()
+    d := Device{ID: id, Username: username, Name: userAgent, IP: ip, Created: now, LastUsed: now, Expires: now.Add(ttl), TokenHash: hashSessionID(token)}
+    dm.mu.Lock()
+    defer dm.mu.Unlock()
+    dm.devices[id] = d
+    dm.saveLocked()
+    return d, token, nil
+}
+
+// Refresh exchanges the refresh token for a new one, extending the device
+// by ttl.  A token that was already used revokes its device: the device is
+// returned with errRefreshReused.  Unknown or expired tokens give
+// errRefreshInvalid.
+func (dm *DeviceManager) Refresh(token, ip string, ttl time.Duration) (Device, string, error) {
+    hash := hashSessionID(token)
+    dm.mu.Lock()
+    defer dm.mu.Unlock()
+    now := time.Now()
+    for id, d := range dm.devices {
+        for _, used := range d.UsedHashes {
+            if used == hash {
+                delete(dm.devices, id)
+                dm.saveLocked()
+                return d, "", errRefreshReused
+            }
+        }
+        if d.TokenHash != hash {
+            continue
+        }
+        if !now.Before(d.Expires) {
+            delete(dm.devices, id)
+            dm.saveLocked()
+            return Device{}, "", errRefreshInvalid
+        }
+        next, err := randomString(32)
+        if err != nil {
+            return Device{}, "", err
+        }
+        d.UsedHashes = append(d.UsedHashes, d.TokenHash)
+        if n := len(d.UsedHashes); n > maxUsedRefreshTokens {
+            d.UsedHashes = append([]string(nil), d.UsedHashes[n-maxUsedRefreshTokens:]...)
+        }
+        d.TokenHash = hashSessionID(next)
+        d.IP = ip
+        d.LastUsed = now
+        d.Expires = now.Add(ttl)
+        dm.devices[id] = d
+        dm.saveLocked()
+        return d, next, nil
+    }
+    return Device{}, "", errRefreshInvalid
+}
+
+// List returns the unexpired devices of username, or of every user if
+// username is empty, oldest first.
+func (dm *DeviceManager) List(username string) []Device {
+    dm.mu.Lock()
+    defer dm.mu.Unlock()
+    now := time.Now()
+    list := []Device{}
+    for _, d := range dm.devices {
+        if now.Before(d.Expires) && (username == "" || d.Username == username) {
+            list = append(list, d)
+        }
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+    return list
+}
+
+// Revoke forgets the device with ID id, provided it belongs to username or
+// username is empty.
+func (dm *DeviceManager) Revoke(id, username string) (Device, bool) {
+    dm.mu.Lock()
+    defer dm.mu.Unlock()
+    d, ok := dm.devices[id]
+    if !ok || (username != "" && d.Username != username) {
+        return Device{}, false
+    }
+    delete(dm.devices, id)
+    dm.saveLocked()
+    return d, true
+}
+
+// DeleteUser forgets every device of username except the one with ID
+// except, and returns the number forgotten.
+func (dm *DeviceManager) DeleteUser(username, except string) int {
+    dm.mu.Lock()
+    defer dm.mu.Unlock()
+    n := 0
+    for id, d := range dm.devices {
+        if d.Username == username && id != except {
+            delete(dm.devices, id)
+            n++
+        }
+    }
+    if n > 0 {
+        dm.saveLocked()
+    }
+    return n
+}
+
+// Purge forgets expired devices.
+func (dm *DeviceManager) Purge() {
+    dm.mu.Lock()
+    defer dm.mu.Unlock()
+    now := time.Now()
+    n := 0
+    for id, d := range dm.devices {
+        if !now.Before(d.Expires) {
+            delete(dm.devices, id)
+            n++
+        }
+    }
+    if n > 0 {
+        dm.saveLocked()
+    }
+}
+
+// setRefreshCookie sets the refresh token cookie, expiring with the device.
+// secure marks it for HTTPS only.
+func setRefreshCookie(w http.ResponseWriter, token string, expires time.Time, secure bool) {
+    http.SetCookie(w, &http.Cookie{
+        Name:     refreshCookie,
+        Value:    token,
+        Path:     refreshCookiePath,
+        HttpOnly: true,
+        Secure:   secure,
+        SameSite: http.SameSiteStrictMode,
+        Expires:  expires,
+    })
+}
+
+// clearRefreshCookie removes the refresh token cookie.
+func clearRefreshCookie(w http.ResponseWriter, secure bool) {
+    http.SetCookie(w, &http.Cookie{
+        Name:     refreshCookie,
+        Value:    "",
+        Path:     refreshCookiePath,
+        HttpOnly: true,
+        Secure:   secure,
+        Expires:  time.Unix(0, 0),
+    })
+}
+
+// startRememberedSession is startSession for a login that asked to be
+// remembered: it also registers the device and sets its refresh token
+// cookie.
+func (s *Server) startRememberedSession(w http.ResponseWriter, r *http.Request, username string) error {
+    d, token, err := s.devices.Register(username, s.clientIP(r), r.UserAgent(), rememberTTL(s.cfgMgr.Get()))
+    if err != nil {
+        return err
+    }
+    if err := s.startDeviceSession(w, r, username, d.ID); err != nil {
+        s.devices.Revoke(d.ID, "")
+        return err
+    }
+    setRefreshCookie(w, token, d.Expires, s.secureRequest(r))
+    s.logger.Log("device %s of %s remembered", d.ID, username)
+    return nil
+}
+
+// handleSessionRefresh starts a new session from the refresh token cookie
+// of a remembered device with POST /api/session/refresh, replacing the
+// device's earlier sessions and rotating the token.  The response is that
+// of a login.
+func (s *Server) handleSessionRefresh(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    cookie, err := r.Cookie(refreshCookie)
+    if err != nil {
+        http.Error(w, "no remembered device", http.StatusUnauthorized)
+        return
+    }
+    d, token, err := s.devices.Refresh(cookie.Value, s.clientIP(r), rememberTTL(s.cfgMgr.Get()))
+    if err == errRefreshReused {
+        n := s.sessions.DeleteDevice(d.ID)
+        desc := fmt.Sprintf("used refresh token of device %s of %s presented again from %s: device forgotten and %d sessions ended", d.ID, d.Username, s.clientIP(r), n)
+        s.logger.Log("security: %s", desc)
+        s.sendAlerts(s.newAlertEvent(EventSessionMismatch, Zone{Name: desc}, d.Username))
+    }
+    if err != nil {
+        clearRefreshCookie(w, s.secureRequest(r))
+        http.Error(w, errRefreshInvalid.Error(), http.StatusUnauthorized)
+        return
+    }
+    user, _ := s.cfgMgr.FindUser(d.Username)
+    if user.Username == "" || user.Disabled {
+        s.devices.Revoke(d.ID, "")
+        clearRefreshCookie(w, s.secureRequest(r))
+        http.Error(w, "unknown user", http.StatusUnauthorized)
+        return
+    }
+    setRefreshCookie(w, token, d.Expires, s.secureRequest(r))
+    s.sessions.DeleteDevice(d.ID)
+    if err := s.startDeviceSession(w, r, d.Username, d.ID); err != nil {
+        sessionError(w, err)
+        return
+    }
+    s.recordLogin(r, d.Username, LoginViaDevice)
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(map[string]any{"status": "ok", "must_change_password": user.MustChangePassword})
+}
+
+// deviceView is a remembered device as listed by the API.  Current marks
+// the device the request's session came from.
+type deviceView struct {
+    ID       string    `json:"id"`
+    Username string    `json:"username"`
+    Name     string    `json:"name,omitempty"`
+    IP       string    `json:"ip,omitempty"`
+    Created  time.Time `json:"created"`
+    LastUsed time.Time `json:"last_used"`
+    Expires  time.Time `json:"expires"`
+    Current  bool      `json:"current,omitempty"`
+}
+
+// handleDevices lists remembered devices with GET /api/devices: the user's
+// own, or every user's for an admin.
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    owner := user.Username
+    if user.hasRole(RoleAdmin) {
+        owner = ""
+    }
+    current, _, _ := s.requestSession(r)
+    views := []deviceView{}
+    for _, d := range s.devices.List(owner) {
+        views = append(views, deviceView{
+            ID:       d.ID,
+            Username: d.Username,
+            Name:     d.Name,
+            IP:       d.IP,
+            Created:  d.Created,
+            LastUsed: d.LastUsed,
+            Expires:  d.Expires,
+            Current:  current.Device != "" && d.ID == current.Device,
+        })
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(views)
+}
+
+// handleDeviceByID forgets a remembered device and ends its sessions with
+// DELETE /api/devices/{id}.  Users may forget only their own devices;
+// admins may forget anyone's.
+func (s *Server) handleDeviceByID(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodDelete {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    id := strings.TrimPrefix(r.URL.Path, "/api/devices/")
+    owner := user.Username
+    if user.hasRole(RoleAdmin) {
+        owner = ""
+    }
+    d, ok := s.devices.Revoke(id, owner)
+    if !ok {
+        http.Error(w, "not found", http.StatusNotFound)
+        return
+    }
+    n := s.sessions.DeleteDevice(d.ID)
+    s.logger.Log("forget device %s of %s by %s (%d sessions ended)", d.ID, d.Username, user.Username, n)
+    s.audit(r, user.Username, "device.revoke", d.Username, map[string]any{"id": d.ID, "sessions": n}, nil)
+    s.adminChange(user.Username, "device %s of %s forgotten", d.ID, d.Username)
+    w.WriteHeader(http.StatusNoContent)
+}
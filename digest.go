@@ -0,0 +1,204 @@
+package main
+
+// This file sends a daily digest: one message through an alert, at a time
+// of day of its choosing, summarising the previous day.  The digest is
+// built from the event log, so a restart during the day loses nothing, and
+// sending it is logged, so a restart after it was sent does not send it
+// again, while one that spanned the digest time sends it late.
+
+import (
+    "fmt"
+    "math"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// EventDigest is the type of digest events.  They are delivered only to
+// the alerts with a digest schedule.
+const EventDigest = "digest"
+
+// digestMaxTimes is the number of arm and disarm times a digest lists
+// before summarising the rest.
+const digestMaxTimes = 5
+
+// digests remembers, by alert name, the last day a digest was sent.
+type digests struct {
+    mu   sync.Mutex
+    sent map[string]string
+}
+
+// validateDigest checks that a digest schedule gives a time of day.
+func validateDigest(d *DigestConfig) error {
+    if d == nil {
+        return nil
+    }
+    if _, err := time.Parse("15:04", d.At); err != nil {
+        return fmt.Errorf("digest at must be HH:MM")
+    }
+    return nil
+}
+
+// digestLoop checks once a minute for digests that are due.
+func (s *Server) digestLoop() {
+    ticker := time.NewTicker(time.Minute)
+    defer ticker.Stop()
+    for {
+        s.runDigests(localTime(time.Now()))
+        select {
+        case <-ticker.C:
+        case <-s.stop:
+            return
+        }
+    }
+}
+
+// runDigests sends the digest of the day before now through every alert
+// whose digest time has passed today and that has not had it yet.
+func (s *Server) runDigests(now time.Time) {
+    cfg := s.cfgMgr.Get()
+    today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+    day := today.AddDate(0, 0, -1).Format("2006-01-02")
+    var due []string
+    for _, ac := range cfg.Alerts {
+        if ac.Digest == nil {
+            continue
+        }
+        // The time of day is taken on the wall clock, which may not be
+        // that long after midnight on the days the clocks change.
+        at, err := time.Parse("15:04", ac.Digest.At)
+        if err != nil || now.Before(time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())) {
+            continue
+        }
+        if name := alertName(ac); !s.digestSent(s.cfgMgr.resolve(cfg.LogFile), name, day) {
+            due = append(due, name)
+        }
+    }
+    if len(due) == 0 {
+        return
+    }
+    s.logger.Flush()
+    summary, err := s.digestSummary(s.cfgMgr.resolve(cfg.LogFile), today.AddDate(0, 0, -1), today)
+    if err != nil {
+        s.logger.Log("digest for %s failed: %v", day, err)
+        return
+    }
+    event := AlertEvent{Type: EventDigest, Zone: Zone{Name: summary}, Mode: s.currentMode, Time: now}
+    for _, name := range due {
+        s.alertQueue.enqueueTo(event, []string{name})
+        s.logger.Log("digest for %s sent to %s", day, name)
+        s.digests.mu.Lock()
+        s.digests.sent[name] = day
+        s.digests.mu.Unlock()
+    }
+}
+
+// digestSent reports whether the digest of day has been sent to the alert
+// name, looking in the event log the first time after a restart.
+func (s *Server) digestSent(logFile, name, day string) bool {
+    s.digests.mu.Lock()
+    defer s.digests.mu.Unlock()
+    if s.digests.sent == nil {
+        s.digests.sent = make(map[string]string)
+    }
+    if last, ok := s.digests.sent[name]; ok {
+        return last >= day
+    }
+    msg := fmt.Sprintf("digest for %s sent to %s", day, name)
+    filter := logFilter{Type: EventDigest, Query: strings.ToLower(msg)}
+    page, err := readLogs(logFile, filter, func(line string) bool { return strings.HasSuffix(line, msg) }, 1, "")
+    if err == nil && len(page.Lines) > 0 {
+        s.digests.sent[name] = day
+        return true
+    }
+    s.digests.sent[name] = ""
+    return false
+}
+
+// digestSummary describes the events logged from from until to, e.g.
+// "Digest for Tue 14 Oct: armed 23:02, disarmed 06:45, 3 triggers (Hall
+// 2, Garage 1), 0 alarms, all sensors healthy".
+func (s *Server) digestSummary(logFile string, from, to time.Time) (string, error) {
+    filter := logFilter{From: from, To: to.Add(-time.Second)}
+    page, err := readLogs(logFile, filter, func(string) bool { return true }, math.MaxInt32, "")
+    if err != nil && !os.IsNotExist(err) {
+        return "", err
+    }
+    var armed, disarmed []string
+    triggers := make(map[string]int)
+    total, alarms, faults := 0, 0, 0
+    // The page is newest first.
+    for i := len(page.Lines) - 1; i >= 0; i-- {
+        e, ok := parseLogLine(page.Lines[i])
+        if !ok {
+            continue
+        }
+        at := localTime(e.Time).Format("15:04")
+        switch logEventType(e.Message) {
+        case EventArm:
+            armed = append(armed, at)
+        case EventDisarm:
+            disarmed = append(disarmed, at)
+        case EventTrigger:
+            row := exportRow(page.Lines[i], s.cfgMgr.Get().Zones)
+            zone := row.ZoneName
+            if zone == "" {
+                zone = "zone " + row.Zone
+            }
+            triggers[zone]++
+            total++
+        case EventAlarm:
+            if strings.HasPrefix(e.Message, "alarm triggered") {
+                alarms++
+            }
+        case EventFault:
+            faults++
+        }
+    }
+    parts := []string{}
+    if len(armed) > 0 {
+        parts = append(parts, "armed "+digestTimes(armed))
+    }
+    if len(disarmed) > 0 {
+        parts = append(parts, "disarmed "+digestTimes(disarmed))
+    }
+    if total > 0 {
+        zones := make([]string, 0, len(triggers))
+        for zone := range triggers {
+            zones = append(zones, zone)
+        }
+        sort.Slice(zones, func(i, j int) bool {
+            if triggers[zones[i]] != triggers[zones[j]] {
+                return triggers[zones[i]] > triggers[zones[j]]
+            }
+            return zones[i] < zones[j]
+        })
+        for i, zone := range zones {
+            zones[i] = fmt.Sprintf("%s %d", zone, triggers[zone])
+        }
+        parts = append(parts, fmt.Sprintf("%d triggers (%s)", total, strings.Join(zones, ", ")))
+    } else {
+        parts = append(parts, "0 triggers")
+    }
+    parts = append(parts, fmt.Sprintf("%d alarms", alarms))
+    current := append(s.heartbeatFaults(), s.cfgMgr.directory.faults()...)
+    switch {
+    case faults == 0 && len(current) == 0:
+        parts = append(parts, "all sensors healthy")
+    case len(current) == 0:
+        parts = append(parts, fmt.Sprintf("%d faults, now cleared", faults))
+    default:
+        parts = append(parts, fmt.Sprintf("%d faults, outstanding: %s", faults, strings.Join(current, "; ")))
+    }
+    return fmt.Sprintf("Digest for %s: %s", from.Format("Mon 2 Jan"), strings.Join(parts, ", ")), nil
+}
+
+// digestTimes lists times, eliding all but the first and last few.
+func digestTimes(times []string) string {
+    if len(times) <= digestMaxTimes {
+        return strings.Join(times, ", ")
+    }
+    return fmt.Sprintf("%s … %s (%d times)", strings.Join(times[:digestMaxTimes-1], ", "), times[len(times)-1], len(times))
+}
@@ -0,0 +1,78 @@
+package main
+
+// Tests of the daily digest: when it is due, including on the day the
+// clocks go forward, and how it is worded.
+
+import (
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestDigestDueSpringForward(t *testing.T) {
+    withTimezone(t, "Europe/London")
+    london, _ := time.LoadLocation("Europe/London")
+    tests := []struct {
+        now  time.Time
+        sent bool
+    }{
+        {time.Date(2026, 3, 29, 6, 59, 0, 0, london), false},
+        // Only six hours have passed since midnight at 07:00 BST.
+        {time.Date(2026, 3, 29, 7, 0, 0, 0, london), true},
+        {time.Date(2026, 3, 29, 7, 30, 0, 0, london), true},
+    }
+    for _, tt := range tests {
+        cfg := validTestConfig()
+        cfg.LogFile = "events.log"
+        cfg.Alerts = []AlertConfig{{ID: 1, Type: "log", Name: "daily", Digest: &DigestConfig{At: "07:00"}}}
+        s, _ := newTestServer(t, cfg)
+        s.runDigests(tt.now)
+        s.digests.mu.Lock()
+        sent := s.digests.sent["daily"] == "2026-03-28"
+        s.digests.mu.Unlock()
+        if sent != tt.sent {
+            t.Errorf("at %s: sent %v, want %v", tt.now.Format("15:04 MST"), sent, tt.sent)
+        }
+    }
+}
+
+// smsRecorder answers Twilio requests, keeping the message bodies.
+type smsRecorder struct {
+    bodies []string
+}
+
+func (rec *smsRecorder) RoundTrip(r *http.Request) (*http.Response, error) {
+    data, _ := io.ReadAll(r.Body)
+    form, _ := url.ParseQuery(string(data))
+    rec.bodies = append(rec.bodies, form.Get("Body"))
+    return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader("{}")), Header: http.Header{}}, nil
+}
+
+func TestDigestWording(t *testing.T) {
+    summary := "Digest for Sat 28 Mar: armed 23:02, disarmed 06:45, 0 alarms, all sensors healthy"
+    event := AlertEvent{Type: EventDigest, Zone: Zone{Name: summary}, Mode: "Disarmed", Time: time.Now()}
+
+    f := newFakeSMTP(t, false)
+    e := EmailAlert{SMTPServer: "127.0.0.1", SMTPPort: f.port(), From: "alarm@example.org", To: []string{"a@example.org"}, TLSMode: SMTPTLSNone, Timeout: 5 * time.Second}
+    if err := e.Send(event, nil); err != nil {
+        t.Fatal(err)
+    }
+    f.mu.Lock()
+    data := f.data[0]
+    f.mu.Unlock()
+    if !strings.Contains(data, "Subject: Minder daily digest") || !strings.Contains(data, summary) || strings.Contains(data, "triggered") {
+        t.Errorf("email:\n%s", data)
+    }
+
+    rec := &smsRecorder{}
+    sms := &SMSAlert{AccountSID: "AC1", From: "+15550100", To: []string{"+15550101"}, client: &http.Client{Transport: rec}}
+    if err := sms.Send(event, nil); err != nil {
+        t.Fatal(err)
+    }
+    if len(rec.bodies) != 1 || rec.bodies[0] != "Minder: "+summary {
+        t.Errorf("SMS %q", rec.bodies)
+    }
+}
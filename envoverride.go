@@ -0,0 +1,110 @@
+package main
+
+// This file lets environment variables override scalar settings of
+// config.json, for containers and declarative deployments that set them
+// from outside while zones and users stay in the file.  Each setting is
+// named by its JSON name in upper case after MINDER_, such as
+// MINDER_HTTP_PORT.  Overrides are applied whenever the file is read, so
+// they survive reloads and imports, and are never written back: Save
+// keeps the file's own values for them.
+
+import (
+    "fmt"
+    "os"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// overridableSettings are the settings environment variables may override.
+var overridableSettings = []string{
+    "http_port", "cert_file", "key_file", "base_url", "log_file",
+    "log_level", "timezone", "retention_days", "exit_delay", "entry_delay",
+    "read_only_config",
+}
+
+// overrideEnv returns the environment variable overriding key.
+func overrideEnv(key string) string {
+    return "MINDER_" + strings.ToUpper(key)
+}
+
+// configField returns the field of cfg with the JSON name key.
+func configField(cfg *Config, key string) reflect.Value {
+    v := reflect.ValueOf(cfg).Elem()
+    t := v.Type()
+    for i := 0; i < t.NumField(); i++ {
+        name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+        if name == key {
+            return v.Field(i)
+        }
+    }
+    return reflect.Value{}
+}
+
+// applyEnvOverrides sets the settings of cfg given in the environment,
+// remembering their values from the file in cfg.fileValues.
+func applyEnvOverrides(cfg *Config) error {
+    cfg.fileValues = nil
+    for _, key := range overridableSettings {
+        env := overrideEnv(key)
+        value, ok := os.LookupEnv(env)
+        if !ok {
+            continue
+        }
+        field := configField(cfg, key)
+        original := field.Interface()
+        switch field.Kind() {
+        case reflect.Int:
+            n, err := strconv.Atoi(strings.TrimSpace(value))
+            if err != nil {
+                return fmt.Errorf("%s must be a whole number, not %q", env, value)
+            }
+            field.SetInt(int64(n))
+        case reflect.String:
+            field.SetString(value)
+        case reflect.Bool:
+            b, err := strconv.ParseBool(strings.TrimSpace(value))
+            if err != nil {
+                return fmt.Errorf("%s must be true or false, not %q", env, value)
+            }
+            field.SetBool(b)
+        }
+        if cfg.fileValues == nil {
+            cfg.fileValues = make(map[string]any)
+        }
+        cfg.fileValues[key] = original
+    }
+    return nil
+}
+
+// withFileValues returns cfg with its overridden settings set back to
+// their values in the file, for saving.
+func (cfg Config) withFileValues() Config {
+    for key, value := range cfg.fileValues {
+        configField(&cfg, key).Set(reflect.ValueOf(value))
+    }
+    return cfg
+}
+
+// Overridden reports whether the setting key is overridden by an
+// environment variable, which it returns.
+func (cm *ConfigManager) Overridden(key string) (string, bool) {
+    cm.mu.RLock()
+    defer cm.mu.RUnlock()
+    _, ok := cm.cfg.fileValues[key]
+    return overrideEnv(key), ok
+}
+
+// Overrides returns the settings overridden by environment variables,
+// sorted.
+func (cm *ConfigManager) Overrides() []string {
+    cm.mu.RLock()
+    defer cm.mu.RUnlock()
+    keys := []string{}
+    for key := range cm.cfg.fileValues {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
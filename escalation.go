@@ -0,0 +1,187 @@
+package main
+
+// This file implements alert escalation: notifying further alert handlers
+// in tiers while an alarm remains unacknowledged.
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+)
+
+// escalation tracks the progress of one alarm through the escalation
+// tiers.  Each alarmed zone has its own escalation so that overlapping
+// alarms do not reset or cancel each other's timers.
+type escalation struct {
+    event  AlertEvent
+    tiers  []EscalationTier // snapshot taken when the alarm fired
+    next   int              // index of the next tier to notify
+    nextAt time.Time
+    timer  *time.Timer
+}
+
+// escalationStatus describes an active escalation in /api/status.
+type escalationStatus struct {
+    ZoneID int        `json:"zone_id"`
+    Zone   string     `json:"zone"`
+    Tier   int        `json:"tier"` // number of tiers notified so far
+    Tiers  int        `json:"tiers"`
+    NextAt *time.Time `json:"next_at,omitempty"`
+}
+
+// validateEscalation checks that every escalation tier names at least one
+// alert and that each name matches a configured alert.
+func validateEscalation(tiers []EscalationTier, alerts []AlertConfig) error {
+    for i, tier := range tiers {
+        if tier.Delay < 0 {
+            return fmt.Errorf("escalation tier %d: negative delay", i+1)
+        }
+        if len(tier.Alerts) == 0 {
+            return fmt.Errorf("escalation tier %d: no alerts", i+1)
+        }
+        for _, name := range tier.Alerts {
+            found := false
+            for _, ac := range alerts {
+                if alertName(ac) == strings.ToLower(name) {
+                    found = true
+                    break
+                }
+            }
+            if !found {
+                return fmt.Errorf("escalation tier %d: no alert named %q", i+1, name)
+            }
+        }
+    }
+    return nil
+}
+
+// startEscalation begins escalating an alarm event.  It does nothing if no
+// escalation is configured or the zone is already escalating.
+func (s *Server) startEscalation(event AlertEvent) {
+    tiers := s.cfgMgr.Get().Escalation
+    if len(tiers) == 0 {
+        return
+    }
+    s.escMu.Lock()
+    defer s.escMu.Unlock()
+    if _, active := s.escalations[event.Zone.ID]; active {
+        return
+    }
+    e := &escalation{event: event, tiers: tiers}
+    s.escalations[event.Zone.ID] = e
+    s.scheduleEscalation(e)
+}
+
+// scheduleEscalation arms the timer for the next tier of e.  s.escMu must
+// be held.
+func (s *Server) scheduleEscalation(e *escalation) {
+    delay := time.Duration(e.tiers[e.next].Delay) * time.Second
+    e.nextAt = time.Now().Add(delay)
+    e.timer = time.AfterFunc(delay, func() {
+        s.fireEscalation(e)
+    })
+}
+
+// fireEscalation notifies the alerts of the next tier of e and schedules
+// the tier after it.  If e was cancelled in the meantime nothing is sent.
+func (s *Server) fireEscalation(e *escalation) {
+    s.escMu.Lock()
+    defer s.escMu.Unlock()
+    if s.escalations[e.event.Zone.ID] != e {
+        return
+    }
+    tier := e.tiers[e.next]
+    s.logger.Log("escalation tier %d for zone %s: notifying %s", e.next+1, e.event.Zone.Name, strings.Join(tier.Alerts, ", "))
+    s.alertQueue.enqueueTo(e.event, tier.Alerts)
+    e.next++
+    if e.next < len(e.tiers) {
+        s.scheduleEscalation(e)
+        return
+    }
+    e.timer = nil
+    e.nextAt = time.Time{}
+}
+
+// cancelEscalations stops every active escalation.  reason is recorded in
+// the event log together with the actor.
+func (s *Server) cancelEscalations(actor, reason string) {
+    s.escMu.Lock()
+    defer s.escMu.Unlock()
+    for id, e := range s.escalations {
+        if e.timer != nil {
+            e.timer.Stop()
+            s.logger.Log("escalation for zone %s cancelled at tier %d/%d by %s (%s)", e.event.Zone.Name, e.next, len(e.tiers), actor, reason)
+        }
+        delete(s.escalations, id)
+    }
+}
+
+// cancelEscalation stops the escalation of the alarm of zone id, if any.
+func (s *Server) cancelEscalation(id int, actor, reason string) {
+    s.escMu.Lock()
+    defer s.escMu.Unlock()
+    e, ok := s.escalations[id]
+    if !ok {
+        return
+    }
+    if e.timer != nil {
+        e.timer.Stop()
+        s.logger.Log("escalation for zone %s cancelled at tier %d/%d by %s (%s)", e.event.Zone.Name, e.next, len(e.tiers), actor, reason)
+    }
+    delete(s.escalations, id)
+}
+
+// escalationSnapshot returns the state of all escalations, ordered by zone
+// ID, for the status response.
+func (s *Server) escalationSnapshot() []escalationStatus {
+    s.escMu.Lock()
+    defer s.escMu.Unlock()
+    out := []escalationStatus{}
+    for _, e := range s.escalations {
+        st := escalationStatus{
+            ZoneID: e.event.Zone.ID,
+            Zone:   e.event.Zone.Name,
+            Tier:   e.next,
+            Tiers:  len(e.tiers),
+        }
+        if !e.nextAt.IsZero() {
+            t := localTime(e.nextAt)
+            st.NextAt = &t
+        }
+        out = append(out, st)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].ZoneID < out[j].ZoneID })
+    return out
+}
+
+// acknowledge acknowledges the current alarm on behalf of username: every
+// unacknowledged alarm event, and any pending escalation tiers.  The
+// system is not disarmed.
+func (s *Server) acknowledge(username string) {
+    s.logger.Log("alarm acknowledged by %s", username)
+    for _, e := range s.alarms.Unacked() {
+        _, _ = s.ackAlarm(e.ID, username, "")
+    }
+    s.cancelEscalations(username, "acknowledged")
+    s.publishLive("acknowledge", Zone{}, username)
+}
+
+// handleAcknowledge acknowledges the current alarm, cancelling any pending
+// escalation tiers without disarming the system.
+func (s *Server) handleAcknowledge(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleOperator) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    if err := s.acknowledgeAs(user); err != nil {
+        http.Error(w, err.Error(), http.StatusForbidden)
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,88 @@
+package main
+
+// This file numbers events, so that a client can ask for what happened
+// since the last event it saw.  Every event logged, and every live update,
+// takes the next ID of one sequence, written into the log line as
+// "<time> #<id> - <message>".  IDs only ever increase: the sequence carries
+// on across rotation and restarts.  Rather than write the counter to disk
+// at every event, a block of IDs is reserved in <log file>.seq at a time,
+// and a restart continues after the reserved block; the IDs left unused
+// are skipped.  The reservation is synced to disk before any ID of the
+// block is used, so that it survives a power cut.
+
+import (
+    "fmt"
+    "io/ioutil"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// eventIDBlock is how many IDs are reserved at a time.
+const eventIDBlock = 1000
+
+// seqPath returns the file the ID reservation is kept in.
+func (el *EventLogger) seqPath() string {
+    return el.filePath + ".seq"
+}
+
+// loadSeq continues the sequence after the reserved block, or after the
+// newest ID logged should the reservation be lost or behind.  Just after
+// rotation the current log holds no IDs, so the rotated logs are read,
+// newest first, until one does.
+func (el *EventLogger) loadSeq() {
+    if data, err := ioutil.ReadFile(el.seqPath()); err == nil {
+        el.lastID, _ = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+    }
+    for _, path := range append([]string{el.filePath}, rotatedLogs(el.filePath)...) {
+        if id := newestLogID(path); id > 0 {
+            if id > el.lastID {
+                el.lastID = id
+            }
+            break
+        }
+    }
+    el.reservedID = el.lastID
+}
+
+// newestLogID returns the ID of the newest entry with one in the log at
+// path, or 0 if there is none.
+func newestLogID(path string) uint64 {
+    r, size, done, err := openLog(path)
+    if err != nil {
+        return 0
+    }
+    defer done()
+    rr := newReverseLineReader(r, size)
+    for {
+        line, _, err := rr.next()
+        if err != nil {
+            return 0
+        }
+        if e, ok := parseLogLine(string(line)); ok && e.ID > 0 {
+            return e.ID
+        }
+    }
+}
+
+// nextIDLocked returns the next event ID, reserving another block first
+// if needed.  el.mu must be held.
+func (el *EventLogger) nextIDLocked() uint64 {
+    el.lastID++
+    if el.lastID > el.reservedID {
+        reserved := el.lastID + eventIDBlock - 1
+        if err := writeFileSync(el.seqPath(), []byte(strconv.FormatUint(reserved, 10)+"\n")); err != nil {
+            fmt.Fprintf(os.Stderr, "log sequence error: %v\n", err)
+        }
+        el.reservedID = reserved
+    }
+    return el.lastID
+}
+
+// NextID returns the next event ID for an event that is not logged, such
+// as a live update.
+func (el *EventLogger) NextID() uint64 {
+    el.mu.Lock()
+    defer el.mu.Unlock()
+    return el.nextIDLocked()
+}
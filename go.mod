@@ -3,8 +3,22 @@ module minder
 go 1.20
 
 require (
+	github.com/eclipse/paho.mqtt.golang v1.4.3
+	github.com/go-ldap/ldap/v3 v3.4.6
+	github.com/gorilla/websocket v1.5.0
 	golang.org/x/crypto v0.16.0
+	golang.org/x/net v0.10.0
+	gopkg.in/yaml.v3 v3.0.1
 	// Periph modules: host at v3.8.5 and conn at v3.7.2 are the latest tagged versions as of 2025.
 	periph.io/x/conn/v3 v3.7.2
 	periph.io/x/host/v3 v3.8.5
 )
+
+require (
+	github.com/Azure/go-ntlmssp v0.0.0-20221128193559-754e69321358 // indirect
+	github.com/go-asn1-ber/asn1-ber v1.5.5 // indirect
+	github.com/google/uuid v1.3.1 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
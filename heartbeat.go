@@ -0,0 +1,212 @@
+package main
+
+// This file implements scheduled heartbeat notifications that prove each
+// alert handler can still deliver before a real alarm depends on it.
+
+import (
+    "fmt"
+    "sync"
+    "time"
+)
+
+// defaultHeartbeatFailures is the number of consecutive heartbeat failures
+// that raise a fault when HeartbeatConfig.FailureThreshold is zero.
+const defaultHeartbeatFailures = 3
+
+// heartbeatStatus is the heartbeat state of one alert, reported by
+// GET /api/alerts/status.
+type heartbeatStatus struct {
+    LastAt    *time.Time `json:"last_at,omitempty"`
+    LastOK    bool       `json:"last_ok"`
+    LastError string     `json:"last_error,omitempty"`
+    NextAt    time.Time  `json:"next_at"`
+    Failures  int        `json:"consecutive_failures"`
+    Fault     bool       `json:"fault"`
+    schedule  HeartbeatConfig
+}
+
+// heartbeats tracks the heartbeat state of every alert with a schedule,
+// keyed by alert ID.
+type heartbeats struct {
+    mu    sync.Mutex
+    state map[int]*heartbeatStatus
+}
+
+// validateHeartbeat checks that hb sets exactly one of At and EveryHours.
+func validateHeartbeat(hb *HeartbeatConfig) error {
+    if hb == nil {
+        return nil
+    }
+    if (hb.At == "") == (hb.EveryHours == 0) {
+        return fmt.Errorf("heartbeat needs exactly one of at and every_hours")
+    }
+    if hb.EveryHours < 0 || hb.FailureThreshold < 0 {
+        return fmt.Errorf("heartbeat values must not be negative")
+    }
+    if hb.At != "" {
+        if _, err := time.Parse("15:04", hb.At); err != nil {
+            return fmt.Errorf("heartbeat at must be HH:MM")
+        }
+    }
+    return nil
+}
+
+// nextHeartbeat returns the first scheduled heartbeat after now.
+func nextHeartbeat(hb HeartbeatConfig, now time.Time) time.Time {
+    if hb.EveryHours > 0 {
+        return now.Add(time.Duration(hb.EveryHours) * time.Hour)
+    }
+    at, _ := time.Parse("15:04", hb.At)
+    next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+    if !next.After(now) {
+        next = next.AddDate(0, 0, 1)
+    }
+    return next
+}
+
+// heartbeatLoop checks once a minute for heartbeats that are due, until
+// the server stops.
+func (s *Server) heartbeatLoop() {
+    ticker := time.NewTicker(time.Minute)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ticker.C:
+            s.runHeartbeats(time.Now())
+        case <-s.stop:
+            return
+        }
+    }
+}
+
+// runHeartbeats sends a heartbeat through every handler whose schedule has
+// come round.  Schedules are picked up from the configuration on each run,
+// so alerts added or changed through the API need no restart.
+func (s *Server) runHeartbeats(now time.Time) {
+    cfg := s.cfgMgr.Get()
+    handlers, ids, names := s.alertQueue.current()
+    s.heartbeats.mu.Lock()
+    active := make(map[int]bool)
+    var due []int
+    for i, id := range ids {
+        var hb *HeartbeatConfig
+        for _, ac := range cfg.Alerts {
+            if ac.ID == id {
+                hb = ac.Heartbeat
+            }
+        }
+        if hb == nil {
+            continue
+        }
+        active[id] = true
+        st := s.heartbeats.state[id]
+        if st == nil || st.schedule != *hb {
+            st = &heartbeatStatus{schedule: *hb, NextAt: nextHeartbeat(*hb, now), LastOK: true}
+            s.heartbeats.state[id] = st
+        }
+        if !now.Before(st.NextAt) {
+            st.NextAt = nextHeartbeat(*hb, now)
+            due = append(due, i)
+        }
+    }
+    for id := range s.heartbeats.state {
+        if !active[id] {
+            delete(s.heartbeats.state, id)
+        }
+    }
+    s.heartbeats.mu.Unlock()
+
+    for _, i := range due {
+        event := AlertEvent{
+            Type: EventTest,
+            Zone: Zone{ID: 0, Name: "Heartbeat", Type: ZoneTypeContact, Enabled: true},
+            Mode: s.currentMode,
+            User: "heartbeat",
+            Time: now,
+        }
+        err := s.alertQueue.sendWithTimeout(handlers[i], event)
+        s.alertQueue.recordResult(ids[i], err)
+        s.recordHeartbeat(i, ids[i], names[i], err)
+    }
+}
+
+// recordHeartbeat stores the result of a heartbeat.  When the consecutive
+// failures of an alert reach its threshold a fault is raised and reported
+// through the handlers that are still delivering; the next success clears
+// it.
+func (s *Server) recordHeartbeat(idx, id int, name string, err error) {
+    s.heartbeats.mu.Lock()
+    st := s.heartbeats.state[id]
+    if st == nil {
+        s.heartbeats.mu.Unlock()
+        return
+    }
+    now := time.Now()
+    st.LastAt = &now
+    raise := false
+    if err == nil {
+        st.LastOK = true
+        st.LastError = ""
+        st.Failures = 0
+        if st.Fault {
+            st.Fault = false
+            s.logger.Log("heartbeat for alert %s recovered", name)
+        } else {
+            s.logger.Log("heartbeat for alert %s sent", name)
+        }
+    } else {
+        st.LastOK = false
+        st.LastError = redactAlertSecrets(err.Error(), s.cfgMgr.Get().Alerts)
+        st.Failures++
+        s.logger.Log("heartbeat for alert %s failed (%d in a row): %s", name, st.Failures, st.LastError)
+        threshold := st.schedule.FailureThreshold
+        if threshold <= 0 {
+            threshold = defaultHeartbeatFailures
+        }
+        if st.Failures >= threshold && !st.Fault {
+            st.Fault = true
+            raise = true
+        }
+    }
+    failures, lastErr := st.Failures, st.LastError
+    s.heartbeats.mu.Unlock()
+    if !raise {
+        return
+    }
+    s.logger.Warning("fault: alert %s failed %d heartbeats: %s", name, failures, lastErr)
+    others := s.alertQueue.workingNames(idx)
+    if len(others) == 0 {
+        return
+    }
+    event := s.newAlertEvent(EventFault, Zone{Name: "alert " + name}, "")
+    event.User = "heartbeat"
+    s.alertQueue.enqueueTo(event, others)
+}
+
+// heartbeatFaults returns a description of each alert whose heartbeat is
+// in fault, for /api/status.
+func (s *Server) heartbeatFaults() []string {
+    _, ids, names := s.alertQueue.current()
+    s.heartbeats.mu.Lock()
+    defer s.heartbeats.mu.Unlock()
+    faults := []string{}
+    for i, id := range ids {
+        if st := s.heartbeats.state[id]; st != nil && st.Fault {
+            faults = append(faults, fmt.Sprintf("alert %s failed %d heartbeats: %s", names[i], st.Failures, st.LastError))
+        }
+    }
+    return faults
+}
+
+// heartbeat returns a copy of the heartbeat state of alert id, or nil if
+// it has no schedule.
+func (s *Server) heartbeat(id int) *heartbeatStatus {
+    s.heartbeats.mu.Lock()
+    defer s.heartbeats.mu.Unlock()
+    st := s.heartbeats.state[id]
+    if st == nil {
+        return nil
+    }
+    cp := *st
+    return &cp
+}
@@ -0,0 +1,257 @@
+package main
+
+// This file integrates Minder with Home Assistant using MQTT discovery.
+
+import (
+    "crypto/subtle"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+)
+
+// haPublishInterval is how often the alarm and zone states are compared
+// against the last published values.  Only changes are published.
+const haPublishInterval = time.Second
+
+// homeAssistant publishes discovery documents and state for Home
+// Assistant's MQTT integration and handles commands from its alarm panel.
+// Minder appears as one alarm_control_panel plus a binary_sensor per zone.
+type homeAssistant struct {
+    s   *Server
+    mc  *MQTTClient
+    cfg HomeAssistantConfig
+
+    mu         sync.Mutex
+    panelSent  bool
+    lastState  string
+    lastZones  map[int]string
+    discovered map[int]string // zone ID -> name last announced
+}
+
+// startHomeAssistant begins publishing to Home Assistant.  It subscribes to
+// the command topic and starts a background loop publishing state changes.
+func (s *Server) startHomeAssistant(cfg HomeAssistantConfig) {
+    if cfg.DiscoveryPrefix == "" {
+        cfg.DiscoveryPrefix = "homeassistant"
+    }
+    if cfg.AwayMode == "" {
+        cfg.AwayMode = "Away"
+    }
+    if cfg.HomeMode == "" {
+        cfg.HomeMode = "Home"
+    }
+    if cfg.NightMode == "" {
+        cfg.NightMode = "Night"
+    }
+    ha := &homeAssistant{s: s, mc: s.mqtt, cfg: cfg}
+    ha.reset()
+    s.mqtt.Subscribe(ha.commandTopic(), ha.handleCommand)
+    // After every (re)connection republish everything as retained state
+    // may have been lost by the broker.
+    s.mqtt.OnConnect(func() {
+        ha.reset()
+        ha.publish()
+    })
+    s.background(func() {
+        ticker := time.NewTicker(haPublishInterval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ticker.C:
+                ha.publish()
+            case <-s.stop:
+                return
+            }
+        }
+    })
+}
+
+func (ha *homeAssistant) stateTopic() string   { return ha.mc.BaseTopic() + "/alarm/state" }
+func (ha *homeAssistant) commandTopic() string { return ha.mc.BaseTopic() + "/alarm/set" }
+func (ha *homeAssistant) zoneTopic(id int) string {
+    return fmt.Sprintf("%s/zone/%d/state", ha.mc.BaseTopic(), id)
+}
+
+// reset forgets what has been published so the next publish sends the
+// complete state again.
+func (ha *homeAssistant) reset() {
+    ha.mu.Lock()
+    defer ha.mu.Unlock()
+    ha.panelSent = false
+    ha.lastState = ""
+    ha.lastZones = make(map[int]string)
+    if ha.discovered == nil {
+        ha.discovered = make(map[int]string)
+    }
+    // Zone names are never empty, so blanking them forces every zone to be
+    // announced again while remembering which IDs may need withdrawing.
+    for id := range ha.discovered {
+        ha.discovered[id] = ""
+    }
+}
+
+// publish sends discovery documents and states that have changed since the
+// previous call.
+func (ha *homeAssistant) publish() {
+    ha.mu.Lock()
+    defer ha.mu.Unlock()
+    cfg := ha.s.cfgMgr.Get()
+    device := map[string]any{
+        "identifiers":  []string{"minder"},
+        "name":         "Minder",
+        "manufacturer": "Minder",
+    }
+    if !ha.panelSent {
+        doc := map[string]any{
+            "name":               "Minder",
+            "unique_id":          "minder_alarm",
+            "state_topic":        ha.stateTopic(),
+            "command_topic":      ha.commandTopic(),
+            "availability_topic": ha.mc.StatusTopic(),
+            "command_template":   `{"action":"{{ action }}","code":"{{ code }}"}`,
+            "supported_features": []string{"arm_home", "arm_away", "arm_night"},
+            "code_arm_required":    ha.cfg.Code != "",
+            "code_disarm_required": ha.cfg.Code != "",
+            "device":             device,
+        }
+        if ha.cfg.Code != "" {
+            doc["code"] = "REMOTE_CODE"
+        }
+        ha.panelSent = ha.publishJSON(ha.cfg.DiscoveryPrefix+"/alarm_control_panel/minder/config", doc)
+    }
+    // Announce new or renamed zones and withdraw deleted ones.
+    present := make(map[int]bool)
+    for _, z := range cfg.Zones {
+        present[z.ID] = true
+        if name, ok := ha.discovered[z.ID]; ok && name == z.Name {
+            continue
+        }
+        deviceClass := "motion"
+        if z.Type == ZoneTypeContact {
+            deviceClass = "door"
+        }
+        doc := map[string]any{
+            "name":               z.Name,
+            "unique_id":          fmt.Sprintf("minder_zone_%d", z.ID),
+            "state_topic":        ha.zoneTopic(z.ID),
+            "availability_topic": ha.mc.StatusTopic(),
+            "device_class":       deviceClass,
+            "payload_on":         "ON",
+            "payload_off":        "OFF",
+            "device":             device,
+        }
+        if ha.publishJSON(ha.zoneConfigTopic(z.ID), doc) {
+            ha.discovered[z.ID] = z.Name
+        }
+    }
+    for id := range ha.discovered {
+        if !present[id] {
+            // An empty retained config removes the entity from Home Assistant.
+            if ha.mc.Publish(ha.zoneConfigTopic(id), 1, true, nil) == nil {
+                delete(ha.discovered, id)
+                delete(ha.lastZones, id)
+            }
+        }
+    }
+    if state := ha.s.homeAssistantState(ha.cfg); state != ha.lastState {
+        if ha.mc.Publish(ha.stateTopic(), 1, true, []byte(state)) == nil {
+            ha.lastState = state
+        }
+    }
+    ha.s.triggerMu.Lock()
+    triggered := make(map[int]bool, len(ha.s.triggered))
+    for id, active := range ha.s.triggered {
+        triggered[id] = active
+    }
+    ha.s.triggerMu.Unlock()
+    for _, z := range cfg.Zones {
+        state := "OFF"
+        if triggered[z.ID] || (z.Enabled && zoneTriggered(z)) {
+            state = "ON"
+        }
+        if ha.lastZones[z.ID] != state {
+            if ha.mc.Publish(ha.zoneTopic(z.ID), 1, true, []byte(state)) == nil {
+                ha.lastZones[z.ID] = state
+            }
+        }
+    }
+}
+
+func (ha *homeAssistant) zoneConfigTopic(id int) string {
+    return fmt.Sprintf("%s/binary_sensor/minder_zone_%d/config", ha.cfg.DiscoveryPrefix, id)
+}
+
+// publishJSON publishes a retained discovery document and reports success.
+func (ha *homeAssistant) publishJSON(topic string, doc any) bool {
+    payload, err := json.Marshal(doc)
+    if err != nil {
+        return false
+    }
+    if err := ha.mc.Publish(topic, 1, true, payload); err != nil {
+        ha.s.logger.Log("home assistant publish to %s failed: %v", topic, err)
+        return false
+    }
+    return true
+}
+
+// homeAssistantState maps Minder's current mode to a Home Assistant alarm
+// panel state.  Test modes are reported as disarmed because they never
+// raise a real alarm.
+func (s *Server) homeAssistantState(cfg HomeAssistantConfig) string {
+    switch {
+    case s.alarm:
+        return "triggered"
+    case s.entryTimer != nil:
+        return "pending"
+    case s.currentMode == "ExitDelay":
+        return "arming"
+    case s.currentMode == "Disarmed" || s.testMode != 0:
+        return "disarmed"
+    case strings.EqualFold(s.currentMode, cfg.AwayMode):
+        return "armed_away"
+    case strings.EqualFold(s.currentMode, cfg.HomeMode):
+        return "armed_home"
+    case strings.EqualFold(s.currentMode, cfg.NightMode):
+        return "armed_night"
+    default:
+        return "armed_custom_bypass"
+    }
+}
+
+// handleCommand processes a command from the Home Assistant alarm panel.
+// The payload is the JSON produced by the command_template in the
+// discovery document; a bare action string is also accepted.  All
+// transitions are logged with the actor "mqtt".
+func (ha *homeAssistant) handleCommand(topic string, payload []byte) {
+    var cmd struct {
+        Action string `json:"action"`
+        Code   string `json:"code"`
+    }
+    if err := json.Unmarshal(payload, &cmd); err != nil {
+        cmd.Action = strings.TrimSpace(string(payload))
+    }
+    if ha.cfg.Code != "" && subtle.ConstantTimeCompare([]byte(cmd.Code), []byte(ha.cfg.Code)) != 1 {
+        ha.s.logger.Log("mqtt command %s rejected: invalid code", cmd.Action)
+        return
+    }
+    var err error
+    switch strings.ToUpper(cmd.Action) {
+    case "DISARM":
+        ha.s.disarm("mqtt")
+    case "ARM_AWAY":
+        err = ha.s.arm(ha.cfg.AwayMode, "mqtt")
+    case "ARM_HOME":
+        err = ha.s.arm(ha.cfg.HomeMode, "mqtt")
+    case "ARM_NIGHT":
+        err = ha.s.arm(ha.cfg.NightMode, "mqtt")
+    default:
+        ha.s.logger.Log("mqtt command %q not supported", cmd.Action)
+        return
+    }
+    if err != nil {
+        ha.s.logger.Log("mqtt command %s failed: %v", cmd.Action, err)
+    }
+    go ha.publish()
+}
@@ -0,0 +1,79 @@
+package main
+
+// This file bounds what a client can take of the HTTPS server, which may
+// be reachable from the internet on a device as small as a Pi Zero.
+// Timeouts close connections that stall in the TLS handshake, send their
+// request slowly (slowloris) or stop reading the response, and a limit on
+// open connections stops a scan from exhausting memory and descriptors.
+// The live streams of /api/events and /api/ws are exempt from the read and
+// write timeouts, having keep-alives of their own.
+
+import (
+    "crypto/tls"
+    "net"
+    "net/http"
+    "time"
+
+    "golang.org/x/net/netutil"
+)
+
+// Defaults of HTTPLimitsConfig.
+const (
+    defaultReadHeaderTimeout = 10 * time.Second
+    defaultReadTimeout       = 30 * time.Second
+    defaultWriteTimeout      = 60 * time.Second
+    defaultHTTPIdleTimeout   = 120 * time.Second
+    defaultMaxHeaderBytes    = 64 << 10
+    defaultMaxConnections    = 64
+)
+
+// newHTTPServer returns the HTTPS server for handler on addr, with the
+// timeouts and header limit of limits, which may be nil.
+func newHTTPServer(addr string, handler http.Handler, tlsConfig *tls.Config, limits *HTTPLimitsConfig) *http.Server {
+    var hl HTTPLimitsConfig
+    if limits != nil {
+        hl = *limits
+    }
+    seconds := func(n int, def time.Duration) time.Duration {
+        if n > 0 {
+            return time.Duration(n) * time.Second
+        }
+        return def
+    }
+    srv := &http.Server{
+        Addr:              addr,
+        Handler:           handler,
+        TLSConfig:         tlsConfig,
+        ReadHeaderTimeout: seconds(hl.ReadHeaderTimeout, defaultReadHeaderTimeout),
+        ReadTimeout:       seconds(hl.ReadTimeout, defaultReadTimeout),
+        WriteTimeout:      seconds(hl.WriteTimeout, defaultWriteTimeout),
+        IdleTimeout:       seconds(hl.IdleTimeout, defaultHTTPIdleTimeout),
+        MaxHeaderBytes:    defaultMaxHeaderBytes,
+    }
+    if hl.MaxHeaderBytes > 0 {
+        srv.MaxHeaderBytes = hl.MaxHeaderBytes
+    }
+    return srv
+}
+
+// listenLimited listens on addr, accepting at most the MaxConnections of
+// limits, which may be nil, at once.
+func listenLimited(addr string, limits *HTTPLimitsConfig) (net.Listener, error) {
+    ln, err := net.Listen("tcp", addr)
+    if err != nil {
+        return nil, err
+    }
+    max := defaultMaxConnections
+    if limits != nil && limits.MaxConnections > 0 {
+        max = limits.MaxConnections
+    }
+    return netutil.LimitListener(ln, max), nil
+}
+
+// clearDeadlines lifts the read and write timeouts from the connection of
+// a live stream, which stays open for as long as the client keeps it.
+func clearDeadlines(w http.ResponseWriter) {
+    rc := http.NewResponseController(w)
+    _ = rc.SetReadDeadline(time.Time{})
+    _ = rc.SetWriteDeadline(time.Time{})
+}
@@ -0,0 +1,94 @@
+package main
+
+// Tests of the HTTP server's limits: that slow clients are disconnected
+// within the configured timeouts, and that connections are capped.
+
+import (
+    "bufio"
+    "io"
+    "net"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+// startLimitedServer serves handler over plain HTTP with limits, returning
+// its address.
+func startLimitedServer(t *testing.T, handler http.Handler, limits *HTTPLimitsConfig) string {
+    ln, err := listenLimited("127.0.0.1:0", limits)
+    if err != nil {
+        t.Fatal(err)
+    }
+    srv := newHTTPServer("", handler, nil, limits)
+    go srv.Serve(ln)
+    t.Cleanup(func() { srv.Close() })
+    return ln.Addr().String()
+}
+
+func TestSlowClientDisconnected(t *testing.T) {
+    handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        io.Copy(io.Discard, r.Body)
+    })
+    tests := []struct {
+        name   string
+        limits HTTPLimitsConfig
+        sent   string        // before the client stalls
+        within time.Duration // of connecting
+    }{
+        {"slow headers", HTTPLimitsConfig{ReadHeaderTimeout: 1, ReadTimeout: 30}, "GET / HTTP/1.1\r\nHost: minder\r\n", time.Second},
+        {"slow body", HTTPLimitsConfig{ReadHeaderTimeout: 1, ReadTimeout: 2}, "POST / HTTP/1.1\r\nHost: minder\r\nContent-Length: 100\r\n\r\npartial", 2 * time.Second},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            addr := startLimitedServer(t, handler, &tt.limits)
+            conn, err := net.Dial("tcp", addr)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer conn.Close()
+            start := time.Now()
+            if _, err := io.WriteString(conn, tt.sent); err != nil {
+                t.Fatal(err)
+            }
+            conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+            io.Copy(io.Discard, conn)
+            if d := time.Since(start); d > tt.within+time.Second {
+                t.Errorf("disconnected after %s, want within %s", d.Round(time.Millisecond), tt.within)
+            }
+        })
+    }
+}
+
+func TestConnectionLimit(t *testing.T) {
+    handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        io.WriteString(w, "ok")
+    })
+    addr := startLimitedServer(t, handler, &HTTPLimitsConfig{MaxConnections: 1})
+    held, err := net.Dial("tcp", addr)
+    if err != nil {
+        t.Fatal(err)
+    }
+    io.WriteString(held, "GET / HTTP/1.1\r\nHost: minder\r\n\r\n")
+    if _, err := http.ReadResponse(bufio.NewReader(held), nil); err != nil {
+        t.Fatal(err)
+    }
+    // The kept-alive connection uses the only slot: another is not served
+    // until it is closed.
+    other, err := net.Dial("tcp", addr)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer other.Close()
+    io.WriteString(other, "GET / HTTP/1.1\r\nHost: minder\r\nConnection: close\r\n\r\n")
+    other.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+    if _, err := other.Read(make([]byte, 1)); err == nil {
+        t.Fatal("second connection served beyond max_connections")
+    }
+    held.Close()
+    other.SetReadDeadline(time.Now().Add(5 * time.Second))
+    body, err := io.ReadAll(other)
+    if err != nil || !strings.HasSuffix(string(body), "ok") {
+        t.Errorf("second connection after the first closed: %q, %v", body, err)
+    }
+}
@@ -0,0 +1,50 @@
+package main
+
+// This file gives the admin account created on first run a random
+// password instead of a well known one.  The password is printed once at
+// startup, so it reaches the journal of a service, and written to a file
+// beside config.json that only the owner can read, for installs where
+// the output is not seen.  The account must change it at first login,
+// and the file is removed once it has.
+
+import (
+    "fmt"
+    "io/ioutil"
+    "log"
+    "os"
+)
+
+// initialPasswordFile is the file beside config.json holding the password
+// of the admin account created on first run.
+const initialPasswordFile = "minder-initial-password"
+
+// initialPasswordPath returns the file holding the initial admin
+// password.
+func (cm *ConfigManager) initialPasswordPath() string {
+    return cm.resolve(initialPasswordFile)
+}
+
+// newInitialPassword generates the password of the admin account created
+// on first run, records it in the initial password file and prints it.
+func (cm *ConfigManager) newInitialPassword(username string) (string, error) {
+    password, err := randomString(12)
+    if err != nil {
+        return "", err
+    }
+    path := cm.initialPasswordPath()
+    if err := ioutil.WriteFile(path, []byte(password+"\n"), 0600); err != nil {
+        return "", fmt.Errorf("unable to write the initial password: %w", err)
+    }
+    log.Printf("Created %s with the user %q, whose initial password is %s (also in %s); it must be changed at first login", cm.Path(), username, password, path)
+    return password, nil
+}
+
+// removeInitialPassword deletes the initial password file, once the
+// password in it has been changed.
+func (cm *ConfigManager) removeInitialPassword() error {
+    err := os.Remove(cm.initialPasswordPath())
+    if os.IsNotExist(err) {
+        return nil
+    }
+    return err
+}
@@ -0,0 +1,163 @@
+package main
+
+// This file records when and from where each user last authenticated,
+// and when each API token and API key was last used.  These are gathered
+// in memory and written to config.json at most once per
+// lastLoginFlushInterval so that busy API clients do not cause a config
+// write on every request.
+
+import (
+    "net/http"
+    "sync"
+    "time"
+)
+
+// lastLoginFlushInterval is how long recorded logins wait before being
+// written to config.json.
+const lastLoginFlushInterval = time.Minute
+
+// How a user last authenticated, stored in User.LastLoginVia.
+const (
+    LoginViaPassword = "password"
+    LoginViaPasskey  = "passkey"
+    LoginViaToken    = "token"
+    LoginViaPIN      = "pin"
+    LoginViaOIDC     = "oidc"
+    LoginViaDevice   = "device"
+    LoginViaProxy    = "proxy"
+    LoginViaCert     = "certificate"
+)
+
+// loginRecord is a successful authentication awaiting its write.
+type loginRecord struct {
+    at  time.Time
+    ip  string
+    via string
+}
+
+// lastLogins holds the logins not yet written, keyed by username, and the
+// last uses of tokens and API keys, keyed by ID.  timer is set while a
+// flush is scheduled.
+type lastLogins struct {
+    mu      sync.Mutex
+    pending map[string]loginRecord
+    tokens  map[string]time.Time
+    keys    map[string]time.Time
+    timer   *time.Timer
+}
+
+// recordLogin notes a successful authentication of username by r and
+// schedules it to be written.
+func (s *Server) recordLogin(r *http.Request, username, via string) {
+    rec := loginRecord{at: time.Now(), ip: s.clientIP(r), via: via}
+    s.lastLogins.mu.Lock()
+    defer s.lastLogins.mu.Unlock()
+    if s.lastLogins.pending == nil {
+        s.lastLogins.pending = make(map[string]loginRecord)
+    }
+    s.lastLogins.pending[username] = rec
+    s.scheduleLastLogins()
+}
+
+// recordTokenUse notes a use of the API token with the given ID.
+func (s *Server) recordTokenUse(id string) {
+    s.lastLogins.mu.Lock()
+    defer s.lastLogins.mu.Unlock()
+    if s.lastLogins.tokens == nil {
+        s.lastLogins.tokens = make(map[string]time.Time)
+    }
+    s.lastLogins.tokens[id] = time.Now()
+    s.scheduleLastLogins()
+}
+
+// recordAPIKeyUse notes a use of the API key with the given ID.
+func (s *Server) recordAPIKeyUse(id string) {
+    s.lastLogins.mu.Lock()
+    defer s.lastLogins.mu.Unlock()
+    if s.lastLogins.keys == nil {
+        s.lastLogins.keys = make(map[string]time.Time)
+    }
+    s.lastLogins.keys[id] = time.Now()
+    s.scheduleLastLogins()
+}
+
+// scheduleLastLogins starts the flush timer if not already running.  The
+// caller must hold s.lastLogins.mu.
+func (s *Server) scheduleLastLogins() {
+    if s.lastLogins.timer == nil {
+        s.lastLogins.timer = time.AfterFunc(lastLoginFlushInterval, s.flushLastLogins)
+    }
+}
+
+// flushLastLogins writes the pending logins and uses to config.json.
+func (s *Server) flushLastLogins() {
+    s.lastLogins.mu.Lock()
+    pending, tokens, keys := s.lastLogins.pending, s.lastLogins.tokens, s.lastLogins.keys
+    s.lastLogins.pending, s.lastLogins.tokens, s.lastLogins.keys = nil, nil, nil
+    if s.lastLogins.timer != nil {
+        s.lastLogins.timer.Stop()
+        s.lastLogins.timer = nil
+    }
+    s.lastLogins.mu.Unlock()
+    if len(pending) == 0 && len(tokens) == 0 && len(keys) == 0 {
+        return
+    }
+    err := s.cfgMgr.Update(func(c *Config) error {
+        for i, u := range c.Users {
+            if rec, ok := pending[u.Username]; ok {
+                at := rec.at
+                c.Users[i].LastLogin = &at
+                c.Users[i].LastLoginIP = rec.ip
+                c.Users[i].LastLoginVia = rec.via
+            }
+            for j, t := range u.Tokens {
+                if at, ok := tokens[t.ID]; ok {
+                    c.Users[i].Tokens[j].LastUsedAt = &at
+                }
+            }
+        }
+        for i, k := range c.APIKeys {
+            if at, ok := keys[k.ID]; ok {
+                c.APIKeys[i].LastUsedAt = &at
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        s.logger.Log("unable to record last logins: %v", err)
+    }
+}
+
+// withPendingLogin returns u with any login not yet written applied.
+func (s *Server) withPendingLogin(u User) User {
+    s.lastLogins.mu.Lock()
+    defer s.lastLogins.mu.Unlock()
+    if rec, ok := s.lastLogins.pending[u.Username]; ok {
+        at := rec.at
+        u.LastLogin = &at
+        u.LastLoginIP = rec.ip
+        u.LastLoginVia = rec.via
+    }
+    return u
+}
+
+// tokenLastUsed returns the last use of a token, including one not yet
+// written, given the time stored.
+func (s *Server) tokenLastUsed(id string, stored *time.Time) *time.Time {
+    s.lastLogins.mu.Lock()
+    defer s.lastLogins.mu.Unlock()
+    if at, ok := s.lastLogins.tokens[id]; ok {
+        return &at
+    }
+    return stored
+}
+
+// apiKeyLastUsed is tokenLastUsed for API keys.
+func (s *Server) apiKeyLastUsed(id string, stored *time.Time) *time.Time {
+    s.lastLogins.mu.Lock()
+    defer s.lastLogins.mu.Unlock()
+    if at, ok := s.lastLogins.keys[id]; ok {
+        return &at
+    }
+    return stored
+}
@@ -0,0 +1,30 @@
+package main
+
+// Tests of the batched writes of last logins and token and key uses.
+
+import "testing"
+
+func TestTokenUseBuffered(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{{Username: "alice", Role: RoleAdmin, Tokens: []APIToken{{ID: "t1", Name: "ha", Hash: "h1"}}}}
+    cfg.APIKeys = []APIKey{{ID: "k1", Name: "cron", Role: RoleViewer, Hash: "h2"}}
+    s, _ := newTestServer(t, cfg)
+    s.recordTokenUse("t1")
+    s.recordAPIKeyUse("k1")
+    // Nothing is written until the flush.
+    c := s.cfgMgr.Get()
+    if c.Users[0].Tokens[0].LastUsedAt != nil || c.APIKeys[0].LastUsedAt != nil {
+        t.Fatal("use written before the flush")
+    }
+    if s.tokenLastUsed("t1", nil) == nil || s.apiKeyLastUsed("k1", nil) == nil {
+        t.Error("pending use not reported")
+    }
+    s.flushLastLogins()
+    c = s.cfgMgr.Get()
+    if c.Users[0].Tokens[0].LastUsedAt == nil {
+        t.Error("token use not written")
+    }
+    if c.APIKeys[0].LastUsedAt == nil {
+        t.Error("API key use not written")
+    }
+}
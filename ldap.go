@@ -0,0 +1,270 @@
+package main
+
+// This file authenticates users against an LDAP directory.  A directory
+// user who logs in for the first time gets a shadow User record without a
+// password hash, whose role follows their group membership at every login.
+// Local accounts (those with a password hash) never consult the directory,
+// so they keep working when it is unreachable.
+
+import (
+    "crypto/tls"
+    "crypto/x509"
+    "errors"
+    "fmt"
+    "net"
+    "net/url"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/go-ldap/ldap/v3"
+)
+
+// defaultLDAPTimeout bounds connecting to the directory and each request.
+const defaultLDAPTimeout = 5 * time.Second
+
+// errLDAPUnavailable wraps failures to reach or query the directory, as
+// opposed to the directory rejecting the credentials.
+var errLDAPUnavailable = errors.New("LDAP directory unavailable")
+
+// directoryHealth records whether the directory was reachable at the last
+// login that needed it.  report is called when that changes.
+type directoryHealth struct {
+    mu      sync.Mutex
+    down    bool
+    lastErr string
+    report  func(down bool, err error)
+}
+
+// record notes the outcome of a directory login; err is nil if the
+// directory answered.
+func (d *directoryHealth) record(err error) {
+    d.mu.Lock()
+    changed := d.down != (err != nil)
+    d.down = err != nil
+    d.lastErr = ""
+    if err != nil {
+        d.lastErr = err.Error()
+    }
+    report := d.report
+    d.mu.Unlock()
+    if changed && report != nil {
+        report(err != nil, err)
+    }
+}
+
+// faults describes the directory outage, if any, for /api/status.
+func (d *directoryHealth) faults() []string {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    if !d.down {
+        return nil
+    }
+    return []string{"LDAP directory unreachable: " + d.lastErr}
+}
+
+// reportDirectory logs the LDAP directory becoming unreachable, or
+// reachable again, and raises a fault event for an outage.
+func (s *Server) reportDirectory(down bool, err error) {
+    if !down {
+        s.logger.Log("LDAP directory reachable again")
+        return
+    }
+    s.logger.Warning("fault: LDAP directory unreachable: %v", err)
+    s.sendAlerts(s.newAlertEvent(EventFault, Zone{Name: "LDAP directory"}, ""))
+}
+
+// validateLDAPConfig checks the ldap section of config.json.
+func validateLDAPConfig(lc *LDAPConfig) error {
+    if lc == nil || lc.URL == "" {
+        return nil
+    }
+    u, err := url.Parse(lc.URL)
+    if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+        return errors.New("ldap url must be ldap://host[:port] or ldaps://host[:port]")
+    }
+    if lc.StartTLS && u.Scheme == "ldaps" {
+        return errors.New("ldap start_tls cannot be used with ldaps://")
+    }
+    if lc.BindTemplate == "" && lc.SearchBase == "" {
+        return errors.New("ldap needs either bind_template or search_base")
+    }
+    if lc.BindTemplate != "" && !strings.Contains(lc.BindTemplate, "{username}") {
+        return errors.New("ldap bind_template must contain {username}")
+    }
+    for group, role := range lc.GroupRoles {
+        if _, ok := roleRanks[role]; !ok {
+            return fmt.Errorf("ldap group %s: unknown role %q", group, role)
+        }
+    }
+    if _, ok := roleRanks[lc.DefaultRole]; lc.DefaultRole != "" && !ok {
+        return fmt.Errorf("ldap default_role: unknown role %q", lc.DefaultRole)
+    }
+    return nil
+}
+
+// ldapTLSConfig builds the TLS configuration used to verify the directory.
+func ldapTLSConfig(lc LDAPConfig, host string) (*tls.Config, error) {
+    cfg := &tls.Config{
+        ServerName:         host,
+        MinVersion:         tls.VersionTLS12,
+        InsecureSkipVerify: lc.InsecureSkipVerify,
+    }
+    if lc.CAFile != "" {
+        pem, err := os.ReadFile(lc.CAFile)
+        if err != nil {
+            return nil, fmt.Errorf("reading CA file: %w", err)
+        }
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(pem) {
+            return nil, fmt.Errorf("no certificates found in %s", lc.CAFile)
+        }
+        cfg.RootCAs = pool
+    }
+    return cfg, nil
+}
+
+// dialLDAP connects to the directory, upgrading to TLS if configured.
+func dialLDAP(lc LDAPConfig) (*ldap.Conn, error) {
+    u, err := url.Parse(lc.URL)
+    if err != nil {
+        return nil, err
+    }
+    timeout := defaultLDAPTimeout
+    if lc.Timeout > 0 {
+        timeout = time.Duration(lc.Timeout) * time.Second
+    }
+    tlsCfg, err := ldapTLSConfig(lc, u.Hostname())
+    if err != nil {
+        return nil, err
+    }
+    conn, err := ldap.DialURL(lc.URL, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}), ldap.DialWithTLSConfig(tlsCfg))
+    if err != nil {
+        return nil, err
+    }
+    conn.SetTimeout(timeout)
+    if lc.StartTLS {
+        if err := conn.StartTLS(tlsCfg); err != nil {
+            conn.Close()
+            return nil, fmt.Errorf("StartTLS: %w", err)
+        }
+    }
+    return conn, nil
+}
+
+// ldapAuthenticate verifies username and password against the directory
+// and returns the role their groups grant.  Errors wrapping
+// errLDAPUnavailable mean the directory could not be asked; any other
+// error means it refused the login.
+func ldapAuthenticate(lc LDAPConfig, username, password string) (string, error) {
+    // An empty password would be an unauthenticated bind, which many
+    // servers accept.
+    if password == "" || username == "" {
+        return "", errors.New("missing credentials")
+    }
+    conn, err := dialLDAP(lc)
+    if err != nil {
+        return "", fmt.Errorf("%w: %v", errLDAPUnavailable, err)
+    }
+    defer conn.Close()
+    var userDN string
+    var groups []string
+    if lc.BindTemplate != "" {
+        userDN = strings.ReplaceAll(lc.BindTemplate, "{username}", ldap.EscapeDN(username))
+    } else {
+        if lc.BindDN != "" {
+            if err := conn.Bind(lc.BindDN, lc.BindPassword); err != nil {
+                return "", fmt.Errorf("%w: service account bind: %v", errLDAPUnavailable, err)
+            }
+        }
+        filter := lc.UserFilter
+        if filter == "" {
+            filter = "(uid={username})"
+        }
+        res, err := conn.Search(ldap.NewSearchRequest(lc.SearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+            strings.ReplaceAll(filter, "{username}", ldap.EscapeFilter(username)), []string{"memberOf"}, nil))
+        if err != nil {
+            return "", fmt.Errorf("%w: user search: %v", errLDAPUnavailable, err)
+        }
+        if len(res.Entries) != 1 {
+            return "", errors.New("user not found in directory")
+        }
+        userDN = res.Entries[0].DN
+    }
+    if err := conn.Bind(userDN, password); err != nil {
+        if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+            return "", errors.New("invalid credentials")
+        }
+        return "", fmt.Errorf("%w: bind: %v", errLDAPUnavailable, err)
+    }
+    // Group membership is read as the user, from memberOf on their entry or
+    // by searching group_base for groups listing them as a member.
+    if lc.GroupBase != "" {
+        res, err := conn.Search(ldap.NewSearchRequest(lc.GroupBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+            fmt.Sprintf("(|(member=%s)(uniqueMember=%s)(memberUid=%s))", ldap.EscapeFilter(userDN), ldap.EscapeFilter(userDN), ldap.EscapeFilter(username)),
+            []string{"dn"}, nil))
+        if err != nil {
+            return "", fmt.Errorf("%w: group search: %v", errLDAPUnavailable, err)
+        }
+        for _, e := range res.Entries {
+            groups = append(groups, e.DN)
+        }
+    } else {
+        res, err := conn.Search(ldap.NewSearchRequest(userDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false,
+            "(objectClass=*)", []string{"memberOf"}, nil))
+        if err != nil {
+            return "", fmt.Errorf("%w: reading groups: %v", errLDAPUnavailable, err)
+        }
+        if len(res.Entries) == 1 {
+            groups = res.Entries[0].GetAttributeValues("memberOf")
+        }
+    }
+    role := lc.DefaultRole
+    for _, g := range groups {
+        for mapped, r := range lc.GroupRoles {
+            if strings.EqualFold(g, mapped) && roleRanks[r] > roleRanks[role] {
+                role = r
+            }
+        }
+    }
+    if role == "" {
+        return "", errors.New("not a member of any group mapped to a role")
+    }
+    return role, nil
+}
+
+// authenticateLDAP logs username in through the directory, creating or
+// updating their shadow user with the role granted by their groups.
+func (cm *ConfigManager) authenticateLDAP(lc LDAPConfig, username, password string) (User, error) {
+    role, err := ldapAuthenticate(lc, username, password)
+    if errors.Is(err, errLDAPUnavailable) {
+        cm.directory.record(err)
+        return User{}, err
+    }
+    cm.directory.record(nil)
+    if err != nil {
+        return User{}, err
+    }
+    var user User
+    err = cm.Update(func(c *Config) error {
+        for i, u := range c.Users {
+            if u.Username == username {
+                if u.Disabled {
+                    return errors.New("account disabled")
+                }
+                c.Users[i].Role = role
+                c.Users[i].Admin = role == RoleAdmin
+                user = c.Users[i]
+                return nil
+            }
+        }
+        user = User{Username: username, Role: role, Admin: role == RoleAdmin, Directory: true}
+        c.Users = append(c.Users, user)
+        return nil
+    })
+    if err != nil {
+        return User{}, err
+    }
+    return user, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+// This file lets the server listen on chosen addresses only, such as the
+// LAN and Tailscale interfaces but not a guest network, rather than every
+// interface.  listen lists host:port entries, each served by an
+// http.Server of its own sharing the handler and TLS configuration.  All
+// are opened before any is served, so that an address that cannot be had
+// stops startup with an error naming it.  The redirect listener follows
+// the same hosts.  The addresses bound are logged and returned in the
+// listening field of /api/status.
+
+import (
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "strconv"
+)
+
+// listenAddrs returns the addresses the server listens on for cfg: listen
+// if set, otherwise http_port on every interface, or on 127.0.0.1 in
+// listen mode "http" without insecure_bind.
+func listenAddrs(cfg Config) []string {
+    if len(cfg.Listen) > 0 {
+        return cfg.Listen
+    }
+    if plainHTTP(cfg) && !cfg.InsecureBind {
+        return []string{fmt.Sprintf("127.0.0.1:%d", cfg.HTTPPort)}
+    }
+    return []string{fmt.Sprintf(":%d", cfg.HTTPPort)}
+}
+
+// serverPort returns the port the server is reached at for redirects:
+// that of the first TCP listen entry, or http_port.
+func serverPort(cfg Config) int {
+    for _, addr := range cfg.Listen {
+        if _, unix := unixSocketPath(addr); unix {
+            continue
+        }
+        if _, port, err := net.SplitHostPort(addr); err == nil {
+            if n, err := strconv.Atoi(port); err == nil {
+                return n
+            }
+        }
+    }
+    return cfg.HTTPPort
+}
+
+// listenHosts returns the hosts of cfg's TCP listen entries without
+// duplicates, or "" alone for every interface if listen is empty.
+func listenHosts(cfg Config) []string {
+    if len(cfg.Listen) == 0 {
+        return []string{""}
+    }
+    var hosts []string
+    seen := make(map[string]bool)
+    for _, addr := range cfg.Listen {
+        if _, unix := unixSocketPath(addr); unix {
+            continue
+        }
+        host, _, _ := net.SplitHostPort(addr)
+        if !seen[host] {
+            seen[host] = true
+            hosts = append(hosts, host)
+        }
+    }
+    return hosts
+}
+
+// validateListen checks the listen entries of cfg.  Each must be an IP
+// address, or empty for every interface, and a port, or a socket path.
+// In listen mode "http" addresses must be loopback ones unless
+// insecure_bind is set, and sockets rule out client_certs mode require.
+func validateListen(cfg Config) error {
+    seen := make(map[string]bool)
+    for _, addr := range cfg.Listen {
+        if seen[addr] {
+            return fmt.Errorf("listen address %q is given twice", addr)
+        }
+        seen[addr] = true
+        if path, unix := unixSocketPath(addr); unix {
+            if err := validateUnixListen(addr, path); err != nil {
+                return err
+            }
+            // Sockets are served without TLS, so no client certificate
+            // could be asked for on them.
+            if cfg.ClientCerts != nil && cfg.ClientCerts.Mode == ClientCertRequire {
+                return fmt.Errorf("listen address %q is a unix socket, served without TLS, so client_certs mode require cannot be enforced on it", addr)
+            }
+            continue
+        }
+        host, port, err := net.SplitHostPort(addr)
+        if err != nil {
+            return fmt.Errorf("listen address %q must be host:port, e.g. 192.168.1.10:8443 or [::1]:8443", addr)
+        }
+        if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+            return fmt.Errorf("listen address %q needs a port between 1 and 65535", addr)
+        }
+        ip := net.ParseIP(host)
+        if host != "" && ip == nil {
+            return fmt.Errorf("listen address %q must name an IP address, not a host name", addr)
+        }
+        if plainHTTP(cfg) && !cfg.InsecureBind && (ip == nil || !ip.IsLoopback()) {
+            return fmt.Errorf("listen address %q is not a loopback address; listen_mode http needs insecure_bind to listen on it", addr)
+        }
+    }
+    return nil
+}
+
+// listenAll opens the addresses of cfg, closing those already open and
+// returning an error naming the address if one cannot be.
+func listenAll(cfg Config) ([]net.Listener, error) {
+    var lns []net.Listener
+    for _, addr := range listenAddrs(cfg) {
+        var ln net.Listener
+        var err error
+        if path, unix := unixSocketPath(addr); unix {
+            ln, err = listenUnix(path, cfg.UnixSocket, cfg.HTTPLimits)
+        } else {
+            ln, err = listenLimited(addr, cfg.HTTPLimits)
+        }
+        if err != nil {
+            for _, l := range lns {
+                l.Close()
+            }
+            return nil, fmt.Errorf("unable to listen on %s: %w", addr, err)
+        }
+        lns = append(lns, ln)
+    }
+    return lns, nil
+}
+
+// serve listens on every address of cfg and serves handler on them until
+// the server is shut down or one of them fails.  tlsConfig is nil to
+// serve plain HTTP; otherwise certFile and keyFile are loaded, or the
+// certificate comes from tlsConfig if they are empty.  Unix domain
+// sockets always serve plain HTTP.
+func (s *Server) serve(cfg Config, handler http.Handler, tlsConfig *tls.Config, certFile, keyFile string) error {
+    lns, err := listenAll(cfg)
+    if err != nil {
+        return err
+    }
+    var servers []*http.Server
+    var bound []string
+    for _, ln := range lns {
+        addr := ln.Addr().String()
+        if ln.Addr().Network() == "unix" {
+            addr = unixPrefix + addr
+        }
+        srv := newHTTPServer(addr, handler, tlsConfig, cfg.HTTPLimits)
+        if !s.addHTTPServer(srv) {
+            for _, l := range lns {
+                l.Close()
+            }
+            return nil
+        }
+        servers = append(servers, srv)
+        bound = append(bound, addr)
+        switch {
+        case ln.Addr().Network() == "unix":
+            s.logger.Print(LogInfo, "Listening for plain HTTP on %s", addr)
+        case tlsConfig == nil:
+            s.logger.Print(LogInfo, "Listening on http://%s", addr)
+        default:
+            s.logger.Print(LogInfo, "Listening on https://%s", addr)
+        }
+    }
+    s.httpMu.Lock()
+    s.listening = bound
+    s.httpMu.Unlock()
+    errs := make(chan error, len(servers))
+    for i, srv := range servers {
+        go func(srv *http.Server, ln net.Listener) {
+            var err error
+            if tlsConfig == nil || ln.Addr().Network() == "unix" {
+                err = srv.Serve(ln)
+            } else {
+                err = srv.ServeTLS(ln, certFile, keyFile)
+            }
+            if err != nil && !errors.Is(err, http.ErrServerClosed) {
+                err = fmt.Errorf("listener on %s failed: %w", ln.Addr(), err)
+            }
+            errs <- err
+        }(srv, lns[i])
+    }
+    for range servers {
+        if err := <-errs; err != nil && !errors.Is(err, http.ErrServerClosed) {
+            return err
+        }
+    }
+    return nil
+}
+
+// listeningAddrs returns the addresses the server is bound to.
+func (s *Server) listeningAddrs() []string {
+    s.httpMu.Lock()
+    defer s.httpMu.Unlock()
+    return append([]string{}, s.listening...)
+}
@@ -0,0 +1,30 @@
+package main
+
+// Tests of the checks of listen entries.
+
+import "testing"
+
+func TestValidateListen(t *testing.T) {
+    require := &ClientCertConfig{Mode: ClientCertRequire, CAFile: "ca.pem"}
+    request := &ClientCertConfig{Mode: ClientCertRequest, CAFile: "ca.pem"}
+    tests := []struct {
+        name string
+        cfg  Config
+        ok   bool
+    }{
+        {"addresses", Config{Listen: []string{"192.168.1.10:8443", "[::1]:8443", ":9443"}}, true},
+        {"host name", Config{Listen: []string{"alarm.lan:8443"}}, false},
+        {"no port", Config{Listen: []string{"192.168.1.10"}}, false},
+        {"twice", Config{Listen: []string{":8443", ":8443"}}, false},
+        {"plain HTTP off loopback", Config{ListenMode: ListenModeHTTP, Listen: []string{"192.168.1.10:8080"}}, false},
+        {"socket", Config{Listen: []string{"unix:/run/minder/minder.sock"}}, true},
+        {"socket with client certificates requested", Config{Listen: []string{"unix:/run/minder/minder.sock"}, ClientCerts: request}, true},
+        {"socket with client certificates required", Config{Listen: []string{"127.0.0.1:8443", "unix:/run/minder/minder.sock"}, ClientCerts: require}, false},
+        {"TCP with client certificates required", Config{Listen: []string{"127.0.0.1:8443"}, ClientCerts: require}, true},
+    }
+    for _, tt := range tests {
+        if err := validateListen(tt.cfg); (err == nil) != tt.ok {
+            t.Errorf("%s: %v", tt.name, err)
+        }
+    }
+}
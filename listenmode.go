@@ -0,0 +1,99 @@
+package main
+
+// This file lets the server speak plain HTTP behind a reverse proxy, such
+// as Caddy on the same machine, that terminates TLS itself.  With
+// listen_mode "http" no certificate is loaded or generated, no redirect
+// listener is started and the API and UI are served over plain HTTP on
+// http_port of 127.0.0.1, or on the loopback addresses of listen, only;
+// insecure_bind allows any address instead, which sends passwords and
+// session cookies in the clear to anyone on the network who asks
+// directly.  Cookies are marked Secure when the request reached the proxy
+// over HTTPS, as told by the forwarded_proto_header (default
+// X-Forwarded-Proto) of a trusted proxy.
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+// Listen modes.
+const (
+    ListenModeTLS  = "tls"
+    ListenModeHTTP = "http"
+)
+
+// defaultForwardedProtoHeader is the header naming the scheme a request
+// reached the reverse proxy with, when forwarded_proto_header is empty.
+const defaultForwardedProtoHeader = "X-Forwarded-Proto"
+
+// listenMode returns the listen mode of cfg.
+func listenMode(cfg Config) string {
+    if cfg.ListenMode != "" {
+        return cfg.ListenMode
+    }
+    return ListenModeTLS
+}
+
+// plainHTTP reports whether cfg serves plain HTTP.
+func plainHTTP(cfg Config) bool {
+    return listenMode(cfg) == ListenModeHTTP
+}
+
+// validateListenMode checks listen_mode and the settings that cannot go
+// with it.
+func validateListenMode(cfg Config) error {
+    switch cfg.ListenMode {
+    case "", ListenModeTLS:
+        if cfg.InsecureBind {
+            return errors.New("insecure_bind applies only to listen_mode http")
+        }
+        return nil
+    case ListenModeHTTP:
+    default:
+        return fmt.Errorf("listen_mode must be %s or %s, not %q", ListenModeTLS, ListenModeHTTP, cfg.ListenMode)
+    }
+    if cfg.ACME != nil {
+        return errors.New("acme needs listen_mode tls")
+    }
+    if cfg.ClientCerts != nil && cfg.ClientCerts.Mode != "" {
+        return errors.New("client_certs needs listen_mode tls")
+    }
+    return nil
+}
+
+// secureRequest reports whether r reached us over HTTPS, directly or
+// through a trusted proxy, so that cookies set in reply may be marked
+// Secure.
+func (s *Server) secureRequest(r *http.Request) bool {
+    if r.TLS != nil {
+        return true
+    }
+    cfg := s.cfgMgr.Get()
+    if !fromTrustedProxy(r, cfg.TrustedProxies) {
+        return false
+    }
+    header := cfg.ForwardedProtoHeader
+    if header == "" {
+        header = defaultForwardedProtoHeader
+    }
+    // A chain of proxies may append to the header; the last entry is the
+    // one added by the proxy in front of us.
+    protos := strings.Split(strings.Join(r.Header.Values(header), ","), ",")
+    return strings.EqualFold(strings.TrimSpace(protos[len(protos)-1]), "https")
+}
+
+// serveHTTP serves handler over plain HTTP until the server is shut down.
+func (s *Server) serveHTTP(cfg Config, handler http.Handler) error {
+    if cfg.InsecureBind {
+        where := "every interface"
+        if len(cfg.Listen) > 0 {
+            where = strings.Join(cfg.Listen, ", ")
+        }
+        s.logger.Warning("INSECURE: serving plain HTTP on %s (insecure_bind); passwords and session cookies cross the network unencrypted unless every client goes through a TLS proxy", where)
+    } else {
+        s.logger.Warning("serving plain HTTP on loopback only (listen_mode http); TLS must be terminated by a reverse proxy on this machine")
+    }
+    return s.serve(cfg, handler, nil, "", "")
+}
@@ -0,0 +1,124 @@
+package main
+
+// This file slows down password and PIN guessing.  Once a client address
+// has failed lockoutThreshold times, its further attempts are refused with
+// 429 for lockoutBase, doubling with each further failure up to
+// lockoutMax, without the credentials being checked.  A success clears
+// the count.  Password logins and keypad PINs are counted separately, so
+// that mistyped PINs on a keypad do not lock its address out of the web
+// UI, and a four digit PIN cannot be found by trying every one.
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+const (
+    // lockoutThreshold is the number of failures allowed before an
+    // address is locked out.
+    lockoutThreshold = 5
+    lockoutBase      = 30 * time.Second
+    lockoutMax       = 15 * time.Minute
+    // lockoutForget is how long after its last failure an address's
+    // count is dropped.
+    lockoutForget = time.Hour
+)
+
+// What a lockout applies to.
+const (
+    lockoutLogin = "login"
+    lockoutPIN   = "pin"
+)
+
+// lockoutEntry counts the failures of one address.
+type lockoutEntry struct {
+    failures int
+    last     time.Time
+    until    time.Time
+}
+
+// lockouts holds the failure counts keyed by kind and client address.
+type lockouts struct {
+    mu      sync.Mutex
+    entries map[string]*lockoutEntry
+}
+
+// remaining returns how long key is still locked out for, or zero.
+func (l *lockouts) remaining(key string, now time.Time) time.Duration {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if e := l.entries[key]; e != nil && now.Before(e.until) {
+        return e.until.Sub(now)
+    }
+    return 0
+}
+
+// fail counts a failure of key, locking it out once over the threshold.
+func (l *lockouts) fail(key string, now time.Time) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    for k, e := range l.entries {
+        if now.Sub(e.last) > lockoutForget {
+            delete(l.entries, k)
+        }
+    }
+    if l.entries == nil {
+        l.entries = make(map[string]*lockoutEntry)
+    }
+    e := l.entries[key]
+    if e == nil {
+        e = &lockoutEntry{}
+        l.entries[key] = e
+    }
+    e.failures++
+    e.last = now
+    if over := e.failures - lockoutThreshold; over >= 0 {
+        d := lockoutBase
+        for i := 0; i < over && d < lockoutMax; i++ {
+            d *= 2
+        }
+        if d > lockoutMax {
+            d = lockoutMax
+        }
+        e.until = now.Add(d)
+    }
+}
+
+// clear forgets the failures of key.
+func (l *lockouts) clear(key string) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    delete(l.entries, key)
+}
+
+// lockoutKey returns the key of kind for the client of r.
+func (s *Server) lockoutKey(kind string, r *http.Request) string {
+    return kind + " " + s.clientIP(r)
+}
+
+// lockedOut answers 429 and returns true if the client of r is locked out
+// of kind.
+func (s *Server) lockedOut(w http.ResponseWriter, r *http.Request, kind string) bool {
+    d := s.lockouts.remaining(s.lockoutKey(kind, r), time.Now())
+    if d <= 0 {
+        return false
+    }
+    secs := int((d + time.Second - 1) / time.Second)
+    w.Header().Set("Retry-After", strconv.Itoa(secs))
+    http.Error(w, fmt.Sprintf("too many failed attempts, try again in %d seconds", secs), http.StatusTooManyRequests)
+    return true
+}
+
+// countFailure counts a failure of kind by the client of r, logging a
+// security event when it locks the client out.
+func (s *Server) countFailure(r *http.Request, kind string) {
+    key := s.lockoutKey(kind, r)
+    now := time.Now()
+    s.lockouts.fail(key, now)
+    if d := s.lockouts.remaining(key, now); d > 0 {
+        s.logger.Log("security: %s attempts from %s locked out for %s", kind, s.clientIP(r), d)
+    }
+}
@@ -0,0 +1,114 @@
+package main
+
+// Tests of the lockout of addresses guessing passwords or PINs.
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestLockoutBackoff(t *testing.T) {
+    var l lockouts
+    now := time.Now()
+    for i := 1; i < lockoutThreshold; i++ {
+        l.fail("k", now)
+    }
+    if d := l.remaining("k", now); d != 0 {
+        t.Fatalf("locked out for %s below the threshold", d)
+    }
+    want := lockoutBase
+    for i := 0; i < 8; i++ {
+        l.fail("k", now)
+        if d := l.remaining("k", now); d != want {
+            t.Errorf("failure %d: locked out for %s, want %s", lockoutThreshold+i, d, want)
+        }
+        if want *= 2; want > lockoutMax {
+            want = lockoutMax
+        }
+    }
+    if d := l.remaining("other", now); d != 0 {
+        t.Errorf("another key locked out for %s", d)
+    }
+    l.clear("k")
+    if d := l.remaining("k", now); d != 0 {
+        t.Errorf("locked out for %s after clear", d)
+    }
+}
+
+func pinTestServer(t *testing.T) *Server {
+    cfg := validTestConfig()
+    cfg.Users = []User{
+        {Username: "keypad", PasswordHash: testHash("keypad-pass"), Role: RoleOperator},
+        {Username: "alice", Role: RoleOperator, PINHash: testHash("4826")},
+        {Username: "bob", Role: RoleOperator, PINHash: testHash("4826")},
+        {Username: "carol", Role: RoleViewer, PINHash: testHash("7351")},
+    }
+    s, _ := newTestServer(t, cfg)
+    return s
+}
+
+func TestPINDisarm(t *testing.T) {
+    s := pinTestServer(t)
+    keypad := User{Username: "keypad", Role: RoleOperator}
+    tests := []struct {
+        username, pin string
+        err           error
+    }{
+        {"alice", "4826", nil},
+        {"", "4826", nil}, // shared by alice and bob
+        {"carol", "7351", errInvalidPIN},
+        {"alice", "7351", errInvalidPIN},
+    }
+    for _, tt := range tests {
+        r := httptest.NewRequest("POST", "/api/disarm", nil)
+        if err := s.pinDisarm(r, keypad, tt.username, tt.pin); err != tt.err {
+            t.Errorf("%q %s: %v, want %v", tt.username, tt.pin, err, tt.err)
+        }
+    }
+}
+
+func TestPINLockout(t *testing.T) {
+    s := pinTestServer(t)
+    keypad := User{Username: "keypad", Role: RoleOperator}
+    disarm := func(pin string) int {
+        w := httptest.NewRecorder()
+        s.handleDisarm(w, httptest.NewRequest("POST", "/api/disarm", strings.NewReader(`{"pin": "`+pin+`"}`)), keypad)
+        return w.Code
+    }
+    for i := 0; i < lockoutThreshold; i++ {
+        if code := disarm("0000"); code != http.StatusForbidden {
+            t.Fatalf("wrong PIN %d: status %d", i+1, code)
+        }
+    }
+    // Locked out, the right PIN is refused without being checked.
+    if code := disarm("4826"); code != http.StatusTooManyRequests {
+        t.Errorf("right PIN while locked out: status %d", code)
+    }
+    // Password logins from the address are counted separately.
+    w := httptest.NewRecorder()
+    s.handleLogin(w, httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"username": "keypad", "password": "wrong"}`)))
+    if w.Code != http.StatusUnauthorized {
+        t.Errorf("login after PIN lockout: status %d", w.Code)
+    }
+}
+
+func TestLoginLockout(t *testing.T) {
+    s := pinTestServer(t)
+    login := func(password string) *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        s.handleLogin(w, httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"username": "keypad", "password": "`+password+`"}`)))
+        return w
+    }
+    for i := 0; i < lockoutThreshold; i++ {
+        if w := login("wrong"); w.Code != http.StatusUnauthorized {
+            t.Fatalf("wrong password %d: status %d", i+1, w.Code)
+        }
+    }
+    w := login("keypad-pass")
+    if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+        t.Errorf("right password while locked out: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+    }
+}
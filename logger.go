@@ -1,40 +1,359 @@
 package main
 
 import (
+    "bufio"
+    "compress/gzip"
     "fmt"
+    "io"
     "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
     "sync"
+    "sync/atomic"
     "time"
 )
 
+// Log rotation defaults, see LogRotationConfig.
+const (
+    defaultLogMaxSizeMB = 10
+    defaultLogKeep      = 10
+)
+
+// logFlushInterval is how long an event may wait in the buffer before it
+// is written to the log file.  Triggers and alarms are written at once.
+const logFlushInterval = time.Second
+
 // EventLogger writes timestamped events to a file.  It is safe for concurrent use.
 type EventLogger struct {
     filePath string
     mu       sync.Mutex
+    rotation *LogRotationConfig // nil if the log is not rotated
+    // file is the log file, held open between events, and buf buffers
+    // writes to it until the next Flush.  Both are nil until the first
+    // event and after an error.
+    file       *os.File
+    buf        *bufio.Writer
+    flushTimer *time.Timer
+    // tidying serialises compressing and pruning rotated logs, which runs
+    // in the background so that logging is not held up.
+    tidying  sync.Mutex
+    // recent holds the latest entries, see Recent.
+    recent   recentLog
+    // level is the rank of the least severe level printed, see SetLevel.
+    level    atomic.Int32
+    // lastID is the latest event ID and reservedID the last one reserved,
+    // see eventid.go.
+    lastID     uint64
+    reservedID uint64
+    // sinks are where records are sent besides the event log, see
+    // logsink.go.  sinksMu is held for writing while they are replaced.
+    sinks    atomic.Pointer[[]*logSinkEntry]
+    sinksMu  sync.RWMutex
 }
 
 // NewEventLogger creates a logger writing to filePath.  If the directory does not
-// exist it will be created.  File rotation by date can be added later.
+// exist it will be created.  The file is not rotated until SetRotation is
+// called.  The latest entries of
+// an existing log are loaded for Recent, and event IDs continue from
+// those already used.  The log level is info until SetLevel is called, and
+// records are printed to standard error until SetSinks is called.  Events
+// are buffered for up to logFlushInterval, so Flush should be called
+// before reading the file and before exiting.
 func NewEventLogger(filePath string) *EventLogger {
-    return &EventLogger{filePath: filePath}
+    el := &EventLogger{filePath: filePath}
+    el.SetLevel(LogInfo)
+    el.SetSinks(nil)
+    el.loadSeq()
+    el.recent.fill(filePath)
+    return el
+}
+
+// Recent returns up to limit of the latest entries for which keep is true,
+// newest first, without reading the log file.
+func (el *EventLogger) Recent(limit int, keep func(string) bool) []string {
+    return el.recent.latest(limit, keep)
+}
+
+// SetRotation changes when the log is rotated and how many rotated logs
+// are kept.  If rc is nil the log is not rotated, and grows until it is
+// rotated by other means such as logrotate.
+func (el *EventLogger) SetRotation(rc *LogRotationConfig) {
+    el.mu.Lock()
+    defer el.mu.Unlock()
+    el.rotation = nil
+    if rc != nil {
+        copied := *rc
+        el.rotation = &copied
+    }
 }
 
-// Log writes a single event with timestamp.  Errors are ignored but printed
-// to standard error.
+// Log writes a single event with timestamp and event ID.  Errors are
+// ignored but printed to standard error.
 func (el *EventLogger) Log(format string, args ...any) {
+    el.logEvent(LogInfo, fmt.Sprintf(format, args...))
+}
+
+// logEvent writes an event of level to the event log and then passes it to
+// the sinks.
+func (el *EventLogger) logEvent(level, msg string) {
+    el.mu.Lock()
+    now := time.Now()
+    id := el.nextIDLocked()
+    line := fmt.Sprintf("%s #%d - %s\n", now.UTC().Format(time.RFC3339), id, msg)
+    el.recent.add(strings.TrimSuffix(line, "\n"))
+    pending := len(line)
+    if el.buf != nil {
+        pending += el.buf.Buffered()
+    }
+    if rotateLog(el.filePath, el.rotation, now, pending, el.closeLocked) {
+        go el.tidy(el.rotation.keep())
+    }
+    urgent := level == LogAlarm || logEventType(msg) == EventTrigger
+    if err := el.writeLocked(line, urgent); err != nil {
+        fmt.Fprintf(os.Stderr, "log error: %v\n", err)
+    }
+    el.mu.Unlock()
+    el.emit(logRecord{Time: now, ID: id, Level: level, Message: msg, Event: true})
+}
+
+// writeLocked buffers line for the log file, opening it if need be, and
+// reopens the file to try again should that fail.  If urgent, the buffer
+// is written out and synced to disk at once, so that a power cut does not
+// lose the event; otherwise it is written within logFlushInterval.  el.mu
+// must be held.
+func (el *EventLogger) writeLocked(line string, urgent bool) error {
+    err := el.bufferLocked(line)
+    if err != nil {
+        el.closeLocked()
+        if err = el.bufferLocked(line); err != nil {
+            el.closeLocked()
+            return err
+        }
+    }
+    if !urgent {
+        if el.flushTimer == nil {
+            el.flushTimer = time.AfterFunc(logFlushInterval, el.Flush)
+        }
+        return nil
+    }
+    if err := el.buf.Flush(); err != nil {
+        el.closeLocked()
+        return err
+    }
+    if err := el.file.Sync(); err != nil {
+        el.closeLocked()
+        return err
+    }
+    return nil
+}
+
+// bufferLocked adds line to the buffer, opening the log file if it is not
+// open.  el.mu must be held.
+func (el *EventLogger) bufferLocked(line string) error {
+    if el.file == nil {
+        f, err := os.OpenFile(el.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+        if err != nil {
+            return err
+        }
+        el.file, el.buf = f, bufio.NewWriter(f)
+    }
+    _, err := el.buf.WriteString(line)
+    return err
+}
+
+// Flush writes buffered events to the log file.
+func (el *EventLogger) Flush() {
     el.mu.Lock()
     defer el.mu.Unlock()
-    msg := fmt.Sprintf(format, args...)
-    ts := time.Now().Format(time.RFC3339)
-    line := fmt.Sprintf("%s - %s\n", ts, msg)
-    // Open file in append mode, create if not exists
-    f, err := os.OpenFile(el.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+    if el.flushTimer != nil {
+        el.flushTimer.Stop()
+        el.flushTimer = nil
+    }
+    if el.buf != nil {
+        if err := el.buf.Flush(); err != nil {
+            fmt.Fprintf(os.Stderr, "log error: %v\n", err)
+            el.closeLocked()
+        }
+    }
+}
+
+// closeLocked writes out the buffer and closes the log file, so that the
+// next event reopens it.  el.mu must be held.
+func (el *EventLogger) closeLocked() {
+    if el.file == nil {
+        return
+    }
+    err := el.buf.Flush()
+    if cerr := el.file.Close(); err == nil {
+        err = cerr
+    }
     if err != nil {
         fmt.Fprintf(os.Stderr, "log error: %v\n", err)
-        return
     }
-    defer f.Close()
-    if _, err := f.WriteString(line); err != nil {
-        fmt.Fprintf(os.Stderr, "log write error: %v\n", err)
+    el.file, el.buf = nil, nil
+}
+
+// appendLog appends line to the log at path, creating it if need be.
+func appendLog(path, line string) error {
+    f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+    if err != nil {
+        return err
+    }
+    _, err = f.WriteString(line)
+    if cerr := f.Close(); err == nil {
+        err = cerr
+    }
+    return err
+}
+
+// keep returns how many rotated logs are kept.
+func (rc LogRotationConfig) keep() int {
+    if rc.Keep <= 0 {
+        return defaultLogKeep
+    }
+    return rc.Keep
+}
+
+// rotateLog renames the log at path to events-YYYYMMDD.log, after the day
+// of its last entry, if adding n bytes would take it past the size limit
+// or, with daily rotation, if it was last written on an earlier day.  It
+// reports whether the log was rotated.  If release is not nil, it is
+// called before the rename to close the log.  A nil rc never rotates.
+func rotateLog(path string, rc *LogRotationConfig, now time.Time, n int, release func()) bool {
+    if rc == nil {
+        return false
+    }
+    info, err := os.Stat(path)
+    if err != nil || info.Size() == 0 {
+        return false
+    }
+    maxSize := int64(rc.MaxSizeMB)
+    if maxSize <= 0 {
+        maxSize = defaultLogMaxSizeMB
     }
-}
\ No newline at end of file
+    last := info.ModTime()
+    y1, m1, d1 := last.Date()
+    y2, m2, d2 := now.Date()
+    newDay := y1 != y2 || m1 != m2 || d1 != d2
+    if info.Size()+int64(n) <= maxSize<<20 && !(rc.Daily && newDay) {
+        return false
+    }
+    ext := filepath.Ext(path)
+    base := strings.TrimSuffix(path, ext) + "-" + last.Format("20060102")
+    name := base + ext
+    for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+        name = base + "-" + strconv.Itoa(i) + ext
+    }
+    if release != nil {
+        release()
+    }
+    if err := os.Rename(path, name); err != nil {
+        fmt.Fprintf(os.Stderr, "log rotation error: %v\n", err)
+        return false
+    }
+    return true
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+    _, err := os.Stat(path)
+    return err == nil
+}
+
+// tidy tidies the rotated event logs, see tidyLogs.
+func (el *EventLogger) tidy(keep int) {
+    el.tidying.Lock()
+    defer el.tidying.Unlock()
+    tidyLogs(el.filePath, keep)
+}
+
+// tidyLogs compresses every rotated log of the log at path but the newest
+// and deletes all but the newest keep.
+func tidyLogs(path string, keep int) {
+    for i, name := range rotatedLogs(path) {
+        switch {
+        case i >= keep:
+            if err := os.Remove(name); err != nil {
+                fmt.Fprintf(os.Stderr, "log rotation error: %v\n", err)
+            }
+        case i > 0 && !strings.HasSuffix(name, ".gz"):
+            if err := gzipFile(name); err != nil {
+                fmt.Fprintf(os.Stderr, "log rotation error: %v\n", err)
+            }
+        }
+    }
+}
+
+// gzipFile replaces path with a gzip compressed path.gz.
+func gzipFile(path string) error {
+    in, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer in.Close()
+    tmp := path + ".gz.tmp"
+    out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+    if err != nil {
+        return err
+    }
+    zw := gzip.NewWriter(out)
+    _, err = io.Copy(zw, in)
+    if cerr := zw.Close(); err == nil {
+        err = cerr
+    }
+    if cerr := out.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Rename(tmp, path+".gz")
+    }
+    if err != nil {
+        os.Remove(tmp)
+        return err
+    }
+    return os.Remove(path)
+}
+
+// rotatedLog is a rotated log file and the day and sequence number in its
+// name.
+type rotatedLog struct {
+    path string
+    day  string
+    seq  int
+}
+
+// rotatedLogs returns the rotated logs of the log at path, newest first.
+func rotatedLogs(path string) []string {
+    ext := filepath.Ext(path)
+    prefix := strings.TrimSuffix(path, ext) + "-"
+    matches, _ := filepath.Glob(prefix + "*")
+    var logs []rotatedLog
+    for _, m := range matches {
+        name := strings.TrimSuffix(strings.TrimSuffix(m, ".gz"), ext)
+        day, seq, _ := strings.Cut(strings.TrimPrefix(name, prefix), "-")
+        if _, err := time.Parse("20060102", day); err != nil || !strings.HasSuffix(strings.TrimSuffix(m, ".gz"), ext) {
+            continue
+        }
+        n := 0
+        if seq != "" {
+            var err error
+            if n, err = strconv.Atoi(seq); err != nil {
+                continue
+            }
+        }
+        logs = append(logs, rotatedLog{path: m, day: day, seq: n})
+    }
+    sort.Slice(logs, func(i, j int) bool {
+        if logs[i].day != logs[j].day {
+            return logs[i].day > logs[j].day
+        }
+        return logs[i].seq > logs[j].seq
+    })
+    out := make([]string, len(logs))
+    for i, l := range logs {
+        out[i] = l.path
+    }
+    return out
+}
@@ -0,0 +1,83 @@
+package main
+
+// Tests of the event log's rotation and event IDs, and benchmarks of
+// writing it.
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestLogRotationOptIn(t *testing.T) {
+    dir := inTempDir(t)
+    path := filepath.Join(dir, "events.log")
+    if err := os.WriteFile(path, []byte(strings.Repeat("x", 2<<20)), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    tomorrow := time.Now().AddDate(0, 0, 1)
+    if rotateLog(path, nil, tomorrow, 100, nil) {
+        t.Fatal("log rotated without log_rotation")
+    }
+    if n := len(rotatedLogs(path)); n != 0 {
+        t.Fatalf("%d rotated logs", n)
+    }
+    if !rotateLog(path, &LogRotationConfig{MaxSizeMB: 1}, time.Now(), 100, nil) {
+        t.Fatal("log over max_size_mb not rotated")
+    }
+    if n := len(rotatedLogs(path)); n != 1 {
+        t.Errorf("%d rotated logs, want 1", n)
+    }
+}
+
+func TestEventIDsAfterRotation(t *testing.T) {
+    dir := inTempDir(t)
+    path := filepath.Join(dir, "events.log")
+    // The log has just been rotated and the reservation is behind, as
+    // after a power cut that lost its last update.
+    line := func(id int) string {
+        return fmt.Sprintf("%s #%d - arm Away by alice\n", time.Now().UTC().Format(time.RFC3339), id)
+    }
+    if err := os.WriteFile(filepath.Join(dir, "events-20260301.log"), []byte(line(1499)+line(1500)), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(path+".seq", []byte("999\n"), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    el := NewEventLogger(path)
+    if id := el.NextID(); id != 1501 {
+        t.Errorf("next ID %d, want 1501", id)
+    }
+    data, err := os.ReadFile(path + ".seq")
+    if err != nil || strings.TrimSpace(string(data)) != fmt.Sprint(1501+eventIDBlock-1) {
+        t.Errorf("reservation %q, %v", data, err)
+    }
+}
+
+// BenchmarkEventLog measures logging events through the buffered log.
+func BenchmarkEventLog(b *testing.B) {
+    el := NewEventLogger(filepath.Join(b.TempDir(), "events.log"))
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        el.Log("arm Away by alice")
+    }
+    el.Flush()
+    b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "events/s")
+}
+
+// BenchmarkAppendLog is what the event log did before holding the file
+// open, opening and closing it for every event, for comparison.
+func BenchmarkAppendLog(b *testing.B) {
+    path := filepath.Join(b.TempDir(), "events.log")
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        line := fmt.Sprintf("%s #%d - arm Away by alice\n", time.Now().UTC().Format(time.RFC3339), i)
+        if err := appendLog(path, line); err != nil {
+            b.Fatal(err)
+        }
+    }
+    b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "events/s")
+}
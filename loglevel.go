@@ -0,0 +1,127 @@
+package main
+
+// This file adds levels to logging.  The event log is the alarm history, so
+// every entry of level info and above is always written to it; the level
+// chosen with log_level decides what is also printed to standard error, or
+// to the sinks of log_sinks that do not set their own level.  Debug output,
+// such as every pin read while polling, never enters the event log, and is
+// only printed at the debug level.
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// Log levels, from the most to the least verbose.
+const (
+    LogDebug   = "debug"
+    LogInfo    = "info"
+    LogWarning = "warning"
+    LogAlarm   = "alarm"
+)
+
+// logLevelRanks orders the log levels.
+var logLevelRanks = map[string]int32{LogDebug: 0, LogInfo: 1, LogWarning: 2, LogAlarm: 3}
+
+// validateLogLevel checks a log_level setting.  "" means info.
+func validateLogLevel(level string) error {
+    if _, ok := logLevelRanks[level]; level != "" && !ok {
+        return fmt.Errorf("unknown log_level %q (expected debug, info, warning or alarm)", level)
+    }
+    return nil
+}
+
+// SetLevel sets the least severe level printed to standard error.  level
+// must be valid; "" means info.
+func (el *EventLogger) SetLevel(level string) {
+    if level == "" {
+        level = LogInfo
+    }
+    el.level.Store(logLevelRanks[level])
+}
+
+// Level returns the current log level.
+func (el *EventLogger) Level() string {
+    rank := el.level.Load()
+    for level, r := range logLevelRanks {
+        if r == rank {
+            return level
+        }
+    }
+    return LogInfo
+}
+
+// enabled reports whether records of level are printed.
+func (el *EventLogger) enabled(level string) bool {
+    return logLevelRanks[level] >= el.level.Load()
+}
+
+// Print passes a record of level to the sinks, which by default print it
+// to standard error if the log level allows it.  It does not write to the
+// event log.
+func (el *EventLogger) Print(level, format string, args ...any) {
+    el.emit(logRecord{Time: time.Now(), Level: level, Message: fmt.Sprintf(format, args...)})
+}
+
+// Debug prints a diagnostic record to standard error at the debug level.
+// Debug records never enter the event log.
+func (el *EventLogger) Debug(format string, args ...any) {
+    el.Print(LogDebug, format, args...)
+}
+
+// Warning writes an event to the event log and prints it as a warning.
+func (el *EventLogger) Warning(format string, args ...any) {
+    el.logEvent(LogWarning, fmt.Sprintf(format, args...))
+}
+
+// Alarm writes an event to the event log and prints it as an alarm.
+func (el *EventLogger) Alarm(format string, args ...any) {
+    el.logEvent(LogAlarm, fmt.Sprintf(format, args...))
+}
+
+// handleLogLevel handles GET and PUT on /api/settings/log_level.  PUT
+// {"level":"debug"} changes the log level at once, until the next restart
+// resets it to log_level in config.json.  While MINDER_LOG_LEVEL
+// overrides log_level the setting is read only: GET reports "read_only"
+// and PUT is refused.  Admins only.
+func (s *Server) handleLogLevel(w http.ResponseWriter, r *http.Request, user User) {
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    switch r.Method {
+    case http.MethodGet:
+    case http.MethodPut:
+        if env, ok := s.cfgMgr.Overridden("log_level"); ok {
+            http.Error(w, "log_level is set by "+env, http.StatusConflict)
+            return
+        }
+        var req struct {
+            Level string `json:"level"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "invalid JSON", http.StatusBadRequest)
+            return
+        }
+        req.Level = strings.ToLower(strings.TrimSpace(req.Level))
+        if err := validateLogLevel(req.Level); err != nil || req.Level == "" {
+            http.Error(w, "level must be debug, info, warning or alarm", http.StatusBadRequest)
+            return
+        }
+        before := s.logger.Level()
+        s.logger.SetLevel(req.Level)
+        if before != req.Level {
+            s.logger.Log("log level %s -> %s by %s", before, req.Level, user.Username)
+            s.audit(r, user.Username, "settings.log_level", "", before, req.Level)
+        }
+    default:
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _, readOnly := s.cfgMgr.Overridden("log_level")
+    _ = json.NewEncoder(w).Encode(map[string]any{"level": s.logger.Level(), "read_only": readOnly})
+}
@@ -0,0 +1,472 @@
+package main
+
+// This file reads and filters the event log for /api/logs.  The log is read
+// backwards from its end, a block at a time, so that showing the latest
+// entries of a large log costs no more than reading those entries.  Log
+// lines are plain text, "<RFC 3339 time> - <message>", so an entry's type,
+// zone and user are read from the wording of its message.  Lines that do
+// not have that shape, such as those of very old logs, only match the
+// substring filter.
+//
+// Pages are continued from a cursor naming the log, the offset of the
+// oldest line returned and a checksum of that line.  Rotation renames the
+// current log and may compress it, so the line is looked for at that
+// offset in every log, and a cursor whose line is in none of them, because
+// it was pruned or the log rewritten, is refused.
+
+import (
+    "bytes"
+    "compress/gzip"
+    "errors"
+    "fmt"
+    "hash/fnv"
+    "io"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// logEntry is a log line split into its time, event ID and message.  ID is
+// zero for lines logged before events were numbered.
+type logEntry struct {
+    Time    time.Time
+    ID      uint64
+    Message string
+}
+
+// parseLogLine splits line into a logEntry.  It reports false if the line
+// does not start with a timestamp.
+func parseLogLine(line string) (logEntry, bool) {
+    ts, msg, ok := strings.Cut(line, " - ")
+    if !ok {
+        return logEntry{}, false
+    }
+    var id uint64
+    if i := strings.Index(ts, " #"); i >= 0 {
+        n, err := strconv.ParseUint(ts[i+len(" #"):], 10, 64)
+        if err != nil {
+            return logEntry{}, false
+        }
+        ts, id = ts[:i], n
+    }
+    t, err := time.Parse(time.RFC3339, ts)
+    if err != nil {
+        return logEntry{}, false
+    }
+    return logEntry{Time: t, ID: id, Message: msg}, true
+}
+
+// logEventType returns the type of a log message: its first word, as in
+// "arm", "disarm", "trigger", "login" or "fault".
+func logEventType(msg string) string {
+    word, _, _ := strings.Cut(msg, " ")
+    return strings.ToLower(strings.TrimSuffix(word, ":"))
+}
+
+// logFilter selects log lines for /api/logs.  Empty fields match anything.
+type logFilter struct {
+    Type  string
+    Zone  string
+    User  string
+    From  time.Time
+    To    time.Time
+    Query string
+}
+
+// parseLogFilter reads the type, zone, user, from, to and q parameters.
+func parseLogFilter(q url.Values) (logFilter, error) {
+    f := logFilter{Type: strings.ToLower(q.Get("type")), Zone: q.Get("zone"), User: q.Get("user"), Query: strings.ToLower(q.Get("q"))}
+    for _, p := range []struct {
+        name string
+        t    *time.Time
+    }{{"from", &f.From}, {"to", &f.To}} {
+        if v := q.Get(p.name); v != "" {
+            t, err := time.Parse(time.RFC3339, v)
+            if err != nil {
+                return logFilter{}, fmt.Errorf("invalid %s %q (expected RFC 3339)", p.name, v)
+            }
+            *p.t = t
+        }
+    }
+    return f, nil
+}
+
+// structured reports whether f filters on anything but the substring.
+func (f logFilter) structured() bool {
+    return f.Type != "" || f.Zone != "" || f.User != "" || !f.From.IsZero() || !f.To.IsZero()
+}
+
+// mentionsZone reports whether msg concerns zone, given as an ID or a name.
+// IDs match "id=N" and names match "zone <name>" or "(<name>)", as the
+// server words its zone messages.
+func mentionsZone(msg, zone string) bool {
+    if _, err := strconv.Atoi(zone); err == nil {
+        for rest := msg; ; {
+            i := strings.Index(rest, "id="+zone)
+            if i < 0 {
+                return false
+            }
+            rest = rest[i+len("id="+zone):]
+            if rest == "" || rest[0] < '0' || rest[0] > '9' {
+                return true
+            }
+        }
+    }
+    lower, zone := strings.ToLower(msg), strings.ToLower(zone)
+    return strings.Contains(lower, "zone "+zone) || strings.Contains(lower, "("+zone+")")
+}
+
+// match reports whether line passes f.
+func (f logFilter) match(line string) bool {
+    if f.Query != "" && !strings.Contains(strings.ToLower(line), f.Query) {
+        return false
+    }
+    if !f.structured() {
+        return true
+    }
+    e, ok := parseLogLine(line)
+    if !ok {
+        return false
+    }
+    if f.Type != "" && logEventType(e.Message) != f.Type {
+        return false
+    }
+    if f.Zone != "" && !mentionsZone(e.Message, f.Zone) {
+        return false
+    }
+    if f.User != "" && !mentionsUser(e.Message, f.User) {
+        return false
+    }
+    if (!f.From.IsZero() && e.Time.Before(f.From)) || (!f.To.IsZero() && e.Time.After(f.To)) {
+        return false
+    }
+    return true
+}
+
+// logBlockSize is how much of the log reverseLineReader reads at a time.
+const logBlockSize = 64 * 1024
+
+// reverseLineReader returns the lines of a file from the last to the
+// first, reading it backwards in blocks.
+type reverseLineReader struct {
+    r   io.ReaderAt
+    pos int64  // offset of buf in the file
+    buf []byte // the unreturned bytes read so far
+    eof bool
+}
+
+// newReverseLineReader reads the lines of r that end before offset end.
+func newReverseLineReader(r io.ReaderAt, end int64) *reverseLineReader {
+    return &reverseLineReader{r: r, pos: end}
+}
+
+// next returns the previous line, without its newline, and the offset at
+// which it starts.  The line is only valid until the next call.  At the
+// start of the file it returns io.EOF.
+func (rr *reverseLineReader) next() ([]byte, int64, error) {
+    for {
+        if i := bytes.LastIndexByte(rr.buf, '\n'); i >= 0 {
+            line := rr.buf[i+1:]
+            rr.buf = rr.buf[:i]
+            return line, rr.pos + int64(i) + 1, nil
+        }
+        if rr.pos == 0 {
+            if rr.eof {
+                return nil, 0, io.EOF
+            }
+            rr.eof = true
+            return rr.buf, 0, nil
+        }
+        n := int64(logBlockSize)
+        if n > rr.pos {
+            n = rr.pos
+        }
+        block := make([]byte, n, n+int64(len(rr.buf)))
+        if _, err := rr.r.ReadAt(block, rr.pos-n); err != nil && err != io.EOF {
+            return nil, 0, err
+        }
+        rr.buf = append(block, rr.buf...)
+        rr.pos -= n
+    }
+}
+
+// errInvalidCursor is returned by readLogs for a before it cannot find.
+var errInvalidCursor = errors.New("invalid before")
+
+// lineSum returns the checksum of a log line used in cursors.
+func lineSum(line []byte) string {
+    h := fnv.New32a()
+    h.Write(line)
+    return strconv.FormatUint(uint64(h.Sum32()), 16)
+}
+
+// logCursor returns the cursor continuing before line, which starts at
+// offset in the log at path, or in the current log if current.
+func logCursor(path string, current bool, offset int64, line []byte) string {
+    c := strconv.FormatInt(offset, 10) + "-" + lineSum(line)
+    if !current {
+        c = filepath.Base(path) + ":" + c
+    }
+    return c
+}
+
+// lineAt returns the line starting at offset in r, of size bytes, without
+// its newline.
+func lineAt(r io.ReaderAt, size, offset int64) ([]byte, error) {
+    var line []byte
+    block := make([]byte, 4096)
+    for pos := offset; pos < size; {
+        n, err := r.ReadAt(block, pos)
+        if i := bytes.IndexByte(block[:n], '\n'); i >= 0 {
+            return append(line, block[:i]...), nil
+        }
+        line = append(line, block[:n]...)
+        pos += int64(n)
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+    }
+    return line, nil
+}
+
+// findCursor returns the index in files of the log holding the line of
+// cursor before, and its offset there.  The log the cursor names is tried
+// first, then the others, as rotation may have renamed it since.
+func findCursor(files []string, before string) (int, int64, error) {
+    name, rest := "", before
+    if i := strings.LastIndexByte(before, ':'); i >= 0 {
+        name, rest = before[:i], before[i+1:]
+    }
+    offset, sum, ok := strings.Cut(rest, "-")
+    n, err := strconv.ParseInt(offset, 10, 64)
+    if !ok || err != nil || n < 0 || sum == "" {
+        return 0, 0, errInvalidCursor
+    }
+    order := make([]int, 0, len(files))
+    for i, f := range files {
+        if (i == 0 && name == "") || (i > 0 && filepath.Base(f) == name) {
+            order = append([]int{i}, order...)
+        } else {
+            order = append(order, i)
+        }
+    }
+    for _, i := range order {
+        r, size, done, err := openLog(files[i])
+        if os.IsNotExist(err) {
+            continue
+        }
+        if err != nil {
+            return 0, 0, err
+        }
+        if n >= size {
+            done()
+            continue
+        }
+        line, err := lineAt(r, size, n)
+        done()
+        if err != nil {
+            return 0, 0, err
+        }
+        if lineSum(line) == sum {
+            return i, n, nil
+        }
+    }
+    return 0, 0, errInvalidCursor
+}
+
+// logPage is a page of log entries, newest first.  Next is the cursor for
+// the following page, or "" if there is none.  Total counts the matching
+// entries up to the cursor the page was read from, and is only set if
+// Counted.
+type logPage struct {
+    Lines   []string
+    Total   int
+    Counted bool
+    Next    string
+    oldest  string // cursor of the last line in Lines
+    more    bool   // whether there are matches beyond Lines
+}
+
+// openLog opens a current or rotated log for reading backwards.  Gzipped
+// logs cannot be read backwards, so they are decompressed into memory;
+// rotation bounds their size.
+func openLog(path string) (io.ReaderAt, int64, func(), error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, 0, nil, err
+    }
+    if !strings.HasSuffix(path, ".gz") {
+        info, err := f.Stat()
+        if err != nil {
+            f.Close()
+            return nil, 0, nil, err
+        }
+        return f, info.Size(), func() { f.Close() }, nil
+    }
+    defer f.Close()
+    zr, err := gzip.NewReader(f)
+    if err != nil {
+        return nil, 0, nil, err
+    }
+    data, err := io.ReadAll(zr)
+    if err != nil {
+        return nil, 0, nil, err
+    }
+    return bytes.NewReader(data), int64(len(data)), func() {}, nil
+}
+
+// readLogs reads a page of up to limit entries matching filter and keep
+// from the log at path and then its rotated logs, newest first.  before is
+// the Next of an earlier page: "<offset>-<line checksum>" in the current
+// log, or "<rotated log>:<offset>-<line checksum>".  Rotated logs older
+// than the filter's from time are not read.
+func readLogs(path string, filter logFilter, keep func(string) bool, limit int, before string) (logPage, error) {
+    page := logPage{Lines: []string{}, Counted: filter.Query != "" || filter.structured()}
+    files := append([]string{path}, rotatedLogs(path)...)
+    start, end := 0, int64(-1)
+    if before != "" {
+        var err error
+        if start, end, err = findCursor(files, before); err != nil {
+            return logPage{}, err
+        }
+    }
+    found := false
+    for i := start; i < len(files); i++ {
+        if i > 0 && !filter.From.IsZero() {
+            // A log rotated on a day holds no entries after that day.
+            day, _ := time.ParseInLocation("20060102", logDay(files[i], path), time.Local)
+            if !day.IsZero() && day.AddDate(0, 0, 1).Before(filter.From) {
+                break
+            }
+        }
+        r, size, done, err := openLog(files[i])
+        if os.IsNotExist(err) {
+            continue
+        }
+        if err != nil {
+            return logPage{}, err
+        }
+        found = true
+        if i != start || end < 0 {
+            end = size
+        }
+        err = page.read(newReverseLineReader(r, end), files[i], i == 0, filter, keep, limit)
+        done()
+        if err != nil {
+            return logPage{}, err
+        }
+        if page.more && !page.Counted {
+            break
+        }
+    }
+    if !found {
+        return logPage{}, os.ErrNotExist
+    }
+    if page.more {
+        page.Next = page.oldest
+    }
+    return page, nil
+}
+
+// readLogsSince reads the entries matching filter and keep whose event ID
+// is above since, oldest first: up to limit of them, and whether there are
+// more.  Reading stops at the first entry that is not newer, so this only
+// costs as much as the entries returned.
+func readLogsSince(path string, filter logFilter, keep func(string) bool, limit int, since uint64) ([]string, bool, error) {
+    // Newest first, keeping only the oldest limit entries seen.
+    var newer []string
+    more, found := false, false
+    for _, name := range append([]string{path}, rotatedLogs(path)...) {
+        r, size, done, err := openLog(name)
+        if os.IsNotExist(err) {
+            continue
+        }
+        if err != nil {
+            return nil, false, err
+        }
+        found = true
+        rr := newReverseLineReader(r, size)
+        for {
+            b, _, err := rr.next()
+            if err == io.EOF {
+                break
+            }
+            if err != nil {
+                done()
+                return nil, false, err
+            }
+            line := string(b)
+            e, ok := parseLogLine(line)
+            if ok && e.ID <= since {
+                // Older than since, or logged before events were
+                // numbered.
+                done()
+                return reverseLines(newer), more, nil
+            }
+            if !ok || !filter.match(line) || !keep(line) {
+                continue
+            }
+            if len(newer) == limit {
+                newer, more = newer[1:], true
+            }
+            newer = append(newer, line)
+        }
+        done()
+    }
+    if !found {
+        return nil, false, os.ErrNotExist
+    }
+    return reverseLines(newer), more, nil
+}
+
+// reverseLines reverses lines in place and returns them, or an empty
+// slice if there are none.
+func reverseLines(lines []string) []string {
+    for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+        lines[i], lines[j] = lines[j], lines[i]
+    }
+    if lines == nil {
+        return []string{}
+    }
+    return lines
+}
+
+// read adds the entries of one log to the page.
+func (p *logPage) read(rr *reverseLineReader, path string, current bool, filter logFilter, keep func(string) bool, limit int) error {
+    for {
+        b, offset, err := rr.next()
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+        line := string(b)
+        if line == "" || !filter.match(line) || !keep(line) {
+            continue
+        }
+        if len(p.Lines) == limit {
+            p.more = true
+            if !p.Counted {
+                return nil
+            }
+        } else {
+            p.Lines = append(p.Lines, line)
+            p.oldest = logCursor(path, current, offset, b)
+        }
+        p.Total++
+    }
+}
+
+// logDay returns the YYYYMMDD day in the name of a rotated log of path.
+func logDay(rotated, path string) string {
+    prefix := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + "-"
+    day, _, _ := strings.Cut(strings.TrimPrefix(filepath.Base(rotated), prefix), "-")
+    return strings.TrimSuffix(strings.TrimSuffix(day, ".gz"), filepath.Ext(path))
+}
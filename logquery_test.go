@@ -0,0 +1,141 @@
+package main
+
+// Tests of reading the event log a page at a time, across rotation.
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// writeTestLog appends the numbered entries from..to to the log at path.
+func writeTestLog(t *testing.T, path string, from, to int) {
+    f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+    start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+    for i := from; i <= to; i++ {
+        fmt.Fprintf(f, "%s #%d - entry %d\n", start.Add(time.Duration(i)*time.Second).Format(time.RFC3339), i, i)
+    }
+}
+
+// pageEntries returns the entry numbers of a page.
+func pageEntries(page logPage) []string {
+    var out []string
+    for _, l := range page.Lines {
+        e, _ := parseLogLine(l)
+        out = append(out, strings.TrimPrefix(e.Message, "entry "))
+    }
+    return out
+}
+
+func TestLogCursorAcrossRotation(t *testing.T) {
+    dir := inTempDir(t)
+    path := filepath.Join(dir, "events.log")
+    keep := func(string) bool { return true }
+    writeTestLog(t, path, 1, 10)
+    page, err := readLogs(path, logFilter{}, keep, 3, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := strings.Join(pageEntries(page), " "); got != "10 9 8" {
+        t.Fatalf("first page %q", got)
+    }
+    // The log is rotated, and a new one started, before the next page.
+    if err := os.Rename(path, filepath.Join(dir, "events-20260301.log")); err != nil {
+        t.Fatal(err)
+    }
+    writeTestLog(t, path, 11, 40)
+    page, err = readLogs(path, logFilter{}, keep, 3, page.Next)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := strings.Join(pageEntries(page), " "); got != "7 6 5" {
+        t.Errorf("page after rotation %q, want 7 6 5", got)
+    }
+    // The rotated log is compressed, and read on from the same place.
+    if err := gzipFile(filepath.Join(dir, "events-20260301.log")); err != nil {
+        t.Fatal(err)
+    }
+    page, err = readLogs(path, logFilter{}, keep, 3, page.Next)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := strings.Join(pageEntries(page), " "); got != "4 3 2" {
+        t.Errorf("page after compression %q, want 4 3 2", got)
+    }
+}
+
+func TestLogCursorStale(t *testing.T) {
+    dir := inTempDir(t)
+    path := filepath.Join(dir, "events.log")
+    keep := func(string) bool { return true }
+    writeTestLog(t, path, 1, 10)
+    page, err := readLogs(path, logFilter{}, keep, 3, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    // The log is replaced by one whose lines merely sit at the same
+    // offsets.
+    if err := os.Remove(path); err != nil {
+        t.Fatal(err)
+    }
+    writeTestLog(t, path, 101, 110)
+    for _, before := range []string{page.Next, "12", "12-zz", "events-20260301.log:0-0", "-1-0"} {
+        if _, err := readLogs(path, logFilter{}, keep, 3, before); err != errInvalidCursor {
+            t.Errorf("before %q: %v, want %v", before, err, errInvalidCursor)
+        }
+    }
+}
+
+// benchmarkLog writes a synthetic log of lines entries for the benchmarks.
+func benchmarkLog(b *testing.B, lines int) string {
+    path := filepath.Join(b.TempDir(), "events.log")
+    f, err := os.Create(path)
+    if err != nil {
+        b.Fatal(err)
+    }
+    start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+    for i := 1; i <= lines; i++ {
+        fmt.Fprintf(f, "%s #%d - trigger zone id=%d (Hallway PIR)\n", start.Add(time.Duration(i)*200*time.Millisecond).Format(time.RFC3339), i, i%16)
+    }
+    if err := f.Close(); err != nil {
+        b.Fatal(err)
+    }
+    return path
+}
+
+func BenchmarkReadLogsTail(b *testing.B) {
+    path := benchmarkLog(b, 1000000)
+    keep := func(string) bool { return true }
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if _, err := readLogs(path, logFilter{}, keep, 200, ""); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+// BenchmarkReadWholeLog is what handleLogs did before reading backwards,
+// for comparison.
+func BenchmarkReadWholeLog(b *testing.B) {
+    path := benchmarkLog(b, 1000000)
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        data, err := os.ReadFile(path)
+        if err != nil {
+            b.Fatal(err)
+        }
+        lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+        if len(lines) > 200 {
+            lines = lines[len(lines)-200:]
+        }
+    }
+}
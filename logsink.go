@@ -0,0 +1,303 @@
+package main
+
+// This file sends log records to destinations besides the event log.  The
+// event log at log_file is the alarm history and always records every
+// event; log_sinks adds further files, syslog and standard error, each
+// with its own filter.  Each sink is written by a goroutine of its own
+// from a bounded queue, so that a slow or unreachable sink neither holds
+// up the alarm logic nor the other sinks: when its queue is full, records
+// are dropped and counted.  A sink that fails is reported once on standard
+// error and retried with the next record.  Without log_sinks, records are
+// printed to standard error at log_level, as they always were.
+
+import (
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+const (
+    // logSinkQueue is how many records a sink may fall behind by before
+    // records for it are dropped.
+    logSinkQueue = 256
+    // logSinkTimeout bounds connecting to a network sink and each write
+    // to it.
+    logSinkTimeout = 5 * time.Second
+)
+
+// Log sink types, see LogSinkConfig.
+const (
+    SinkFile   = "file"
+    SinkSyslog = "syslog"
+    SinkStderr = "stderr"
+)
+
+// logRecord is a record passed to the sinks: an event, which is also in the
+// event log and has its ID, or a diagnostic printed with Print or Debug.
+type logRecord struct {
+    Time    time.Time
+    ID      uint64
+    Level   string
+    Message string
+    Event   bool
+}
+
+// logSink is a destination for log records.  Its methods are only called
+// from the sink's goroutine.
+type logSink interface {
+    write(rec logRecord) error
+    // close releases the sink's file or connection.
+    close() error
+}
+
+// logSinkEntry is a configured sink with its queue.  The sink's goroutine
+// closes done once the queue has been closed and drained.
+type logSinkEntry struct {
+    cfg     LogSinkConfig
+    sink    logSink
+    queue   chan logRecord
+    done    chan struct{}
+    failing atomic.Bool
+    dropped atomic.Int64
+}
+
+// run writes the records queued for the sink until the queue is closed,
+// and then closes the sink.
+func (e *logSinkEntry) run() {
+    defer close(e.done)
+    for rec := range e.queue {
+        if n := e.dropped.Swap(0); n > 0 {
+            fmt.Fprintf(os.Stderr, "log sink %s fell behind, %d records dropped\n", e.cfg.name(), n)
+        }
+        err := e.sink.write(rec)
+        switch {
+        case err != nil && !e.failing.Swap(true):
+            fmt.Fprintf(os.Stderr, "log sink %s error: %v\n", e.cfg.name(), err)
+        case err == nil && e.failing.Swap(false):
+            fmt.Fprintf(os.Stderr, "log sink %s recovered\n", e.cfg.name())
+        }
+    }
+    if err := e.sink.close(); err != nil {
+        fmt.Fprintf(os.Stderr, "log sink %s error: %v\n", e.cfg.name(), err)
+    }
+}
+
+// validateLogSinks checks the log_sinks setting.
+func validateLogSinks(cfg Config) error {
+    for i, sc := range cfg.LogSinks {
+        if sc.Level != "" {
+            if _, ok := logLevelRanks[sc.Level]; !ok {
+                return fmt.Errorf("log_sinks[%d]: unknown level %q (expected debug, info, warning or alarm)", i, sc.Level)
+            }
+        }
+        switch sc.Type {
+        case SinkFile:
+            if sc.Path == "" {
+                return fmt.Errorf("log_sinks[%d]: file sink requires a path", i)
+            }
+            if filepath.Clean(sc.Path) == filepath.Clean(cfg.LogFile) {
+                return fmt.Errorf("log_sinks[%d]: %s is the event log, which is always written", i, sc.Path)
+            }
+        case SinkSyslog:
+            if !syslogSupported {
+                return fmt.Errorf("log_sinks[%d]: syslog is not supported on this platform", i)
+            }
+            switch sc.Network {
+            case "", "udp", "tcp", "unix", "unixgram":
+            default:
+                return fmt.Errorf("log_sinks[%d]: unknown network %q (expected udp, tcp, unix or unixgram)", i, sc.Network)
+            }
+            if sc.Network != "" && sc.Address == "" {
+                return fmt.Errorf("log_sinks[%d]: network requires an address", i)
+            }
+        case SinkStderr:
+        default:
+            return fmt.Errorf("log_sinks[%d]: unknown type %q (expected file, syslog or stderr)", i, sc.Type)
+        }
+        for _, e := range sc.Events {
+            if e == "" || strings.ContainsAny(e, " \t") {
+                return fmt.Errorf("log_sinks[%d]: invalid event type %q", i, e)
+            }
+        }
+    }
+    return nil
+}
+
+// SetSinks replaces the sinks records are sent to.  If sinks is empty,
+// records are printed to standard error at the log level.  File sinks are
+// rotated like the event log, so SetRotation should be called first.
+// The sinks replaced write out the records already queued for them and
+// are then closed.  sinks must be valid.
+func (el *EventLogger) SetSinks(sinks []LogSinkConfig) {
+    if len(sinks) == 0 {
+        sinks = []LogSinkConfig{{Type: SinkStderr}}
+    }
+    el.mu.Lock()
+    rotation := el.rotation
+    el.mu.Unlock()
+    entries := make([]*logSinkEntry, 0, len(sinks))
+    for _, sc := range sinks {
+        e := &logSinkEntry{cfg: sc, queue: make(chan logRecord, logSinkQueue), done: make(chan struct{})}
+        switch sc.Type {
+        case SinkFile:
+            e.sink = &fileSink{path: sc.Path, rotation: rotation}
+        case SinkSyslog:
+            e.sink = newSyslogSink(sc)
+        default:
+            e.sink = stderrSink{}
+        }
+        entries = append(entries, e)
+        go e.run()
+    }
+    el.sinksMu.Lock()
+    old := el.sinks.Swap(&entries)
+    el.sinksMu.Unlock()
+    if old != nil {
+        for _, e := range *old {
+            close(e.queue)
+        }
+    }
+}
+
+// CloseSinks writes out the records queued for the sinks, waiting until
+// deadline at most, and closes them.  Records emitted afterwards are
+// dropped.
+func (el *EventLogger) CloseSinks(deadline time.Time) {
+    el.sinksMu.Lock()
+    old := el.sinks.Swap(&[]*logSinkEntry{})
+    el.sinksMu.Unlock()
+    if old == nil {
+        return
+    }
+    for _, e := range *old {
+        close(e.queue)
+    }
+    timer := time.NewTimer(time.Until(deadline))
+    defer timer.Stop()
+    for _, e := range *old {
+        select {
+        case <-e.done:
+        case <-timer.C:
+            fmt.Fprintf(os.Stderr, "log sink %s still writing at shutdown\n", e.cfg.name())
+            return
+        }
+    }
+}
+
+// emit queues rec for every sink that accepts it, without waiting for
+// any of them.  sinksMu is held for reading so that SetSinks does not
+// close a queue being sent on.
+func (el *EventLogger) emit(rec logRecord) {
+    el.sinksMu.RLock()
+    defer el.sinksMu.RUnlock()
+    sinks := el.sinks.Load()
+    if sinks == nil {
+        return
+    }
+    for _, e := range *sinks {
+        if !el.accepts(e.cfg, rec) {
+            continue
+        }
+        select {
+        case e.queue <- rec:
+        default:
+            e.dropped.Add(1)
+        }
+    }
+}
+
+// accepts reports whether a sink configured by sc takes rec.  Records
+// below the sink's level are dropped; the level defaults to info for files
+// and to the log level otherwise.  Files take only events.  Syslog and
+// standard error take diagnostics, and of the events those listed in
+// Events or, if none are, only warnings and alarms.  Events, if given,
+// always limits the events taken.
+func (el *EventLogger) accepts(sc LogSinkConfig, rec logRecord) bool {
+    switch {
+    case sc.Level != "":
+        if logLevelRanks[rec.Level] < logLevelRanks[sc.Level] {
+            return false
+        }
+    case sc.Type == SinkFile:
+        if logLevelRanks[rec.Level] < logLevelRanks[LogInfo] {
+            return false
+        }
+    default:
+        if !el.enabled(rec.Level) {
+            return false
+        }
+    }
+    if !rec.Event {
+        return sc.Type != SinkFile
+    }
+    if len(sc.Events) == 0 {
+        return sc.Type == SinkFile || rec.Level != LogInfo
+    }
+    eventType := logEventType(rec.Message)
+    for _, e := range sc.Events {
+        if strings.EqualFold(e, eventType) {
+            return true
+        }
+    }
+    return false
+}
+
+// name describes the sink in error messages.
+func (sc LogSinkConfig) name() string {
+    switch {
+    case sc.Path != "":
+        return sc.Type + " " + sc.Path
+    case sc.Address != "":
+        return sc.Type + " " + sc.Address
+    }
+    return sc.Type
+}
+
+// stderrSink prints records to standard error as the log package does.
+type stderrSink struct{}
+
+func (stderrSink) write(rec logRecord) error {
+    if rec.Level == LogDebug {
+        log.Print("DEBUG: " + rec.Message)
+    } else {
+        log.Print(rec.Message)
+    }
+    return nil
+}
+
+func (stderrSink) close() error {
+    return nil
+}
+
+// fileSink appends events to a file in the format of the event log,
+// rotating it with the same settings.
+type fileSink struct {
+    path     string
+    rotation *LogRotationConfig
+    tidying  sync.Mutex
+}
+
+func (fs *fileSink) write(rec logRecord) error {
+    line := fmt.Sprintf("%s #%d - %s\n", rec.Time.UTC().Format(time.RFC3339), rec.ID, rec.Message)
+    if rotateLog(fs.path, fs.rotation, rec.Time, len(line), nil) {
+        go func() {
+            fs.tidying.Lock()
+            defer fs.tidying.Unlock()
+            tidyLogs(fs.path, fs.rotation.keep())
+        }()
+    }
+    return appendLog(fs.path, line)
+}
+
+// close waits for any tidying of rotated files to finish.  The file itself
+// is opened for each write.
+func (fs *fileSink) close() error {
+    fs.tidying.Lock()
+    defer fs.tidying.Unlock()
+    return nil
+}
@@ -0,0 +1,27 @@
+//go:build windows || plan9
+// +build windows plan9
+
+// This file stands in for the syslog sink on the platforms without syslog,
+// where validateLogSinks rejects syslog sinks.
+
+package main
+
+import "errors"
+
+// syslogSupported reports whether syslog sinks can be configured.
+const syslogSupported = false
+
+// syslogSink is never used on these platforms.
+type syslogSink struct{}
+
+func newSyslogSink(cfg LogSinkConfig) logSink {
+    return syslogSink{}
+}
+
+func (syslogSink) write(rec logRecord) error {
+    return errors.New("syslog is not supported on this platform")
+}
+
+func (syslogSink) close() error {
+    return nil
+}
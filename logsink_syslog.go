@@ -0,0 +1,112 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+// This file provides the syslog sink on the platforms that have syslog.
+// Messages are formatted as log/syslog does, but the connection is made
+// here so that dialling and each write have a deadline: a remote syslog
+// server that stops answering fails the sink rather than blocking it.
+
+package main
+
+import (
+    "fmt"
+    "net"
+    "os"
+    "strings"
+    "time"
+)
+
+// syslogSupported reports whether syslog sinks can be configured.
+const syslogSupported = true
+
+// Syslog priorities, see RFC 5424.
+const (
+    syslogDaemon  = 3 << 3
+    syslogAlert   = 1
+    syslogWarning = 4
+    syslogInfo    = 6
+    syslogDebug   = 7
+)
+
+// syslogSink sends records to syslog, connecting on first use so that a
+// syslog daemon that is not yet running does not stop the server, and
+// again after a failed write.  It is only used by its sink's goroutine.
+type syslogSink struct {
+    cfg   LogSinkConfig
+    conn  net.Conn
+    local bool
+}
+
+func newSyslogSink(cfg LogSinkConfig) logSink {
+    return &syslogSink{cfg: cfg}
+}
+
+// dial connects to the configured syslog, or the local one.
+func (ss *syslogSink) dial() error {
+    if ss.cfg.Network != "" {
+        conn, err := net.DialTimeout(ss.cfg.Network, ss.cfg.Address, logSinkTimeout)
+        if err != nil {
+            return err
+        }
+        ss.conn, ss.local = conn, false
+        return nil
+    }
+    for _, network := range []string{"unixgram", "unix"} {
+        for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+            if conn, err := net.DialTimeout(network, path, logSinkTimeout); err == nil {
+                ss.conn, ss.local = conn, true
+                return nil
+            }
+        }
+    }
+    return fmt.Errorf("no local syslog found")
+}
+
+func (ss *syslogSink) write(rec logRecord) error {
+    if ss.conn == nil {
+        if err := ss.dial(); err != nil {
+            return err
+        }
+    }
+    msg := rec.Message
+    if rec.Event {
+        msg = fmt.Sprintf("#%d - %s", rec.ID, rec.Message)
+    }
+    priority := syslogDaemon | syslogInfo
+    switch rec.Level {
+    case LogDebug:
+        priority = syslogDaemon | syslogDebug
+    case LogWarning:
+        priority = syslogDaemon | syslogWarning
+    case LogAlarm:
+        priority = syslogDaemon | syslogAlert
+    }
+    tag := ss.cfg.Tag
+    if tag == "" {
+        tag = "minder"
+    }
+    msg = strings.TrimSuffix(msg, "\n")
+    var line string
+    if ss.local {
+        line = fmt.Sprintf("<%d>%s %s[%d]: %s\n", priority, rec.Time.Format(time.Stamp), tag, os.Getpid(), msg)
+    } else {
+        host, _ := os.Hostname()
+        line = fmt.Sprintf("<%d>%s %s %s[%d]: %s\n", priority, rec.Time.Format(time.RFC3339), host, tag, os.Getpid(), msg)
+    }
+    _ = ss.conn.SetWriteDeadline(time.Now().Add(logSinkTimeout))
+    if _, err := ss.conn.Write([]byte(line)); err != nil {
+        ss.conn.Close()
+        ss.conn = nil
+        return err
+    }
+    return nil
+}
+
+func (ss *syslogSink) close() error {
+    if ss.conn == nil {
+        return nil
+    }
+    err := ss.conn.Close()
+    ss.conn = nil
+    return err
+}
@@ -0,0 +1,92 @@
+package main
+
+// Tests of the log sinks: that a slow sink does not hold up logging, and
+// that sinks replaced by SetSinks are closed.
+
+import (
+    "bufio"
+    "io"
+    "net"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// blockedSink is a sink whose writes wait until release is closed.
+type blockedSink struct {
+    release chan struct{}
+    closed  chan struct{}
+}
+
+func (bs *blockedSink) write(rec logRecord) error {
+    <-bs.release
+    return nil
+}
+
+func (bs *blockedSink) close() error {
+    close(bs.closed)
+    return nil
+}
+
+func TestSlowSinkDoesNotBlock(t *testing.T) {
+    dir := inTempDir(t)
+    el := NewEventLogger(filepath.Join(dir, "events.log"))
+    bs := &blockedSink{release: make(chan struct{}), closed: make(chan struct{})}
+    e := &logSinkEntry{cfg: LogSinkConfig{Type: SinkFile, Path: "slow.log"}, sink: bs, queue: make(chan logRecord, logSinkQueue), done: make(chan struct{})}
+    go e.run()
+    el.sinks.Store(&[]*logSinkEntry{e})
+    done := make(chan struct{})
+    go func() {
+        for i := 0; i < 2*logSinkQueue; i++ {
+            el.Log("trigger zone %d", i)
+        }
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("logging held up by a sink that does not return")
+    }
+    if e.dropped.Load() == 0 {
+        t.Error("no records dropped for the full queue")
+    }
+    close(bs.release)
+    el.CloseSinks(time.Now().Add(5 * time.Second))
+    select {
+    case <-bs.closed:
+    default:
+        t.Error("sink not closed by CloseSinks")
+    }
+}
+
+func TestReplacedSyslogSinkClosed(t *testing.T) {
+    if !syslogSupported {
+        t.Skip("no syslog on this platform")
+    }
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer ln.Close()
+    dir := inTempDir(t)
+    el := NewEventLogger(filepath.Join(dir, "events.log"))
+    el.SetSinks([]LogSinkConfig{{Type: SinkSyslog, Network: "tcp", Address: ln.Addr().String(), Events: []string{"trigger"}}})
+    el.Log("trigger zone 1")
+    ln.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+    conn, err := ln.Accept()
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+    conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    br := bufio.NewReader(conn)
+    line, err := br.ReadString('\n')
+    if err != nil || !strings.HasPrefix(line, "<30>") || !strings.HasSuffix(line, " - trigger zone 1\n") {
+        t.Fatalf("read %q, %v", line, err)
+    }
+    el.SetSinks(nil)
+    if _, err := br.ReadString('\n'); err != io.EOF {
+        t.Errorf("connection of the replaced sink still open: %v", err)
+    }
+}
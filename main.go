@@ -1,20 +1,57 @@
 package main
 
 import (
+    "flag"
     "log"
+    "os"
+    "os/signal"
+    "syscall"
 )
 
 // Entry point for the Minder alarm system
 func main() {
-    var cfgMgr ConfigManager
+    path := flag.String("config", "", "configuration file (default $"+configEnv+" or "+configPath+")")
+    regenerateCert := flag.Bool("regenerate-cert", false, "replace cert_file and key_file with a new self-signed certificate and exit")
+    flag.Parse()
+    cfgMgr := ConfigManager{path: *path}
+    if cfgMgr.path == "" {
+        cfgMgr.path = os.Getenv(configEnv)
+    }
     if err := cfgMgr.Load(); err != nil {
         log.Fatalf("failed to load configuration: %v", err)
     }
+    if *regenerateCert {
+        cfg := cfgMgr.Get()
+        fingerprint, err := cfgMgr.generateCertificate(cfg)
+        if err != nil {
+            log.Fatalf("unable to generate a certificate: %v", err)
+        }
+        log.Printf("Generated a self-signed certificate for %s in %s, SHA-256 fingerprint %s", certHostname(cfg), cfgMgr.resolve(cfg.CertFile), fingerprint)
+        return
+    }
     server, err := NewServer(&cfgMgr)
     if err != nil {
         log.Fatalf("initialisation error: %v", err)
     }
-    if err := server.Start(); err != nil {
-        log.Fatalf("server exited: %v", err)
+    // Reload the configuration on SIGHUP, and shut down in order when
+    // stopped by SIGTERM or SIGINT.
+    sig := make(chan os.Signal, 1)
+    signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+    exited := make(chan error, 1)
+    go func() {
+        exited <- server.Start()
+    }()
+    for {
+        select {
+        case err := <-exited:
+            log.Fatalf("server exited: %v", err)
+        case received := <-sig:
+            if received == syscall.SIGHUP {
+                server.Reload()
+                continue
+            }
+            server.Shutdown(shutdownTimeout)
+            return
+        }
     }
 }
\ No newline at end of file
@@ -1,5 +1,12 @@
 package main
 
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+)
+
 // ZoneType enumerates the types of sensors supported by the system.
 // For now we support "contact" (magnetic door/window sensor) and "pir" (passive infrared motion detector).
 type ZoneType string
@@ -25,6 +32,20 @@ type Zone struct {
     // circuit completes an exit delay during arming.  This field is
     // optional and defaults to false.
     EntryExit bool   `json:"entry_exit,omitempty"`
+    // Silent marks a silent alarm zone.  Triggers are logged and alert
+    // handlers are notified as usual, but the system is not put into the
+    // local alarm state.  Defaults to false.
+    Silent    bool   `json:"silent,omitempty"`
+    // LastWalkTest records when this zone was last verified during a
+    // completed walk test.  It is maintained by the server and preserved
+    // across zone updates.
+    LastWalkTest *time.Time `json:"last_walk_test,omitempty"`
+    // SnapshotURL optionally points at a camera endpoint returning a still
+    // image, which is attached to trigger and alarm notifications for this
+    // zone.  SnapshotUsername and SnapshotPassword enable basic auth.
+    SnapshotURL      string `json:"snapshot_url,omitempty"`
+    SnapshotUsername string `json:"snapshot_username,omitempty"`
+    SnapshotPassword string `json:"snapshot_password,omitempty"`
 }
 
 // ArmMode associates a name with a list of zone IDs that should be monitored when this mode is active.
@@ -34,13 +55,212 @@ type ArmMode struct {
     ActiveZones []int  `json:"active_zones"`
 }
 
+// User roles, from least to most privileged.  Viewers see the status and
+// the log entries of their own actions; operators may also arm, disarm and
+// acknowledge; admins may do everything, including managing zones, users,
+// arm modes and alerts.
+const (
+    RoleViewer   = "viewer"
+    RoleOperator = "operator"
+    RoleAdmin    = "admin"
+)
+
+// roleRanks orders the roles for hasRole.
+var roleRanks = map[string]int{RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
 // User represents an account that can log in to the web UI.
-// Passwords are stored as bcrypt hashes.  The Admin flag indicates
-// whether the user may manage zones and other user accounts.
+// Passwords are stored as bcrypt hashes.  Role determines what the user may
+// do.  The Admin flag predates roles: it is kept equal to Role == "admin"
+// and a config without roles is migrated from it when loaded.
 type User struct {
     Username     string `json:"username"`
     PasswordHash string `json:"password_hash"`
+    Role         string `json:"role,omitempty"`
     Admin        bool   `json:"admin"`
+    // MustChangePassword restricts the user to changing their password
+    // (POST /api/password).  It is set on the default admin account and
+    // when an admin resets another user's password.
+    MustChangePassword bool `json:"must_change_password,omitempty"`
+    // Email and Phone (E.164) are where alerts with NotifyUsers reach the
+    // user, unless NotificationsOff is set.
+    Email            string `json:"email,omitempty"`
+    Phone            string `json:"phone,omitempty"`
+    NotificationsOff bool   `json:"notifications_off,omitempty"`
+    // Directory marks a shadow user created by an LDAP login.  They have no
+    // password hash and their role follows their directory groups.
+    Directory        bool   `json:"ldap,omitempty"`
+    // SSO marks a user created by an OIDC single sign-on login.  They have
+    // no password hash and their role follows the provider's claims.
+    SSO              bool   `json:"oidc,omitempty"`
+    // OIDCSubject and OIDCIssuer bind the user to the provider's account
+    // with that "sub" claim.  Single sign-on logs in only as a user bound
+    // this way: users it creates are bound when created, and an admin
+    // links an existing user by setting OIDCSubject (the issuer defaults
+    // to the configured one).
+    OIDCSubject      string `json:"oidc_subject,omitempty"`
+    OIDCIssuer       string `json:"oidc_issuer,omitempty"`
+    // Preferences are the user's own notification and UI settings.  If
+    // nil, the defaults apply.
+    Preferences      *Preferences `json:"preferences,omitempty"`
+    // Proxy marks a user created by reverse proxy header authentication.
+    // They have no password hash.
+    Proxy            bool   `json:"proxy_auth,omitempty"`
+    // PINHash is the hash of the user's keypad disarm PIN, if they have
+    // one.
+    PINHash      string `json:"pin_hash,omitempty"`
+    // Disabled suspends the account without deleting it: the user cannot
+    // log in and their API tokens are revoked.
+    Disabled     bool `json:"disabled,omitempty"`
+    // AllowedModes limits the arm modes the user may select, matched
+    // without regard to case.  If empty, every configured mode is allowed.
+    // The test modes always require an admin.
+    AllowedModes []string `json:"allowed_modes,omitempty"`
+    // SessionLimitExempt exempts the user from Config.MaxSessionsPerUser,
+    // for shared or service accounts such as a wall tablet.
+    SessionLimitExempt bool `json:"session_limit_exempt,omitempty"`
+    // TOTPSecret is the base32 secret of the user's authenticator app when
+    // two-factor login is on, and RecoveryCodes the hashes of their unused
+    // one-time recovery codes; see totp.go.
+    TOTPSecret    string   `json:"totp_secret,omitempty"`
+    RecoveryCodes []string `json:"recovery_codes,omitempty"`
+    // WebAuthn lists the passkeys registered for passwordless login.
+    WebAuthn     []WebAuthnCredential `json:"webauthn,omitempty"`
+    // Tokens lists the user's API tokens.
+    Tokens       []APIToken `json:"api_tokens,omitempty"`
+    // APIKey is the name of the API key a synthetic user was made for; it
+    // is empty for real users and never stored.
+    APIKey       string `json:"-"`
+    // LastLogin and LastLoginIP record the user's most recent successful
+    // authentication and LastLoginVia how it was made ("password",
+    // "passkey", "token", "pin", "oidc" or "device").  They are written at
+    // most once a minute.
+    LastLogin    *time.Time `json:"last_login,omitempty"`
+    LastLoginIP  string     `json:"last_login_ip,omitempty"`
+    LastLoginVia string     `json:"last_login_via,omitempty"`
+}
+
+// hasRole reports whether u has role or a more privileged one.
+func (u User) hasRole(role string) bool {
+    return roleRanks[u.Role] >= roleRanks[role]
+}
+
+// isTestMode reports whether mode names one of the test modes, TestSoft or
+// TestWiring.
+func isTestMode(mode string) bool {
+    switch strings.ToLower(strings.TrimSpace(mode)) {
+    case "testsoft", "test soft", "testwiring", "test wiring":
+        return true
+    }
+    return false
+}
+
+// mayArm reports whether u may switch the system into mode.
+func (u User) mayArm(mode string) bool {
+    if isTestMode(mode) {
+        return u.hasRole(RoleAdmin)
+    }
+    if !u.hasRole(RoleOperator) {
+        return false
+    }
+    if len(u.AllowedModes) == 0 {
+        return true
+    }
+    for _, m := range u.AllowedModes {
+        if strings.EqualFold(m, strings.TrimSpace(mode)) {
+            return true
+        }
+    }
+    return false
+}
+
+// validateAllowedModes checks that every entry of allowed names a
+// configured arm mode.
+func validateAllowedModes(allowed []string, modes []ArmMode) error {
+    for _, name := range allowed {
+        found := false
+        for _, am := range modes {
+            if strings.EqualFold(am.Name, name) {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return fmt.Errorf("unknown arm mode %q in allowed_modes", name)
+        }
+    }
+    return nil
+}
+
+// enabledAdmins returns the number of users who are admins and not
+// disabled.
+func enabledAdmins(users []User) int {
+    n := 0
+    for _, u := range users {
+        if u.Role == RoleAdmin && !u.Disabled {
+            n++
+        }
+    }
+    return n
+}
+
+// normaliseUserRoles migrates users without a role from the Admin flag and
+// keeps Admin in step with Role.  It rejects unknown roles.
+func normaliseUserRoles(users []User) error {
+    for i := range users {
+        u := &users[i]
+        if u.Role == "" {
+            u.Role = RoleOperator
+            if u.Admin {
+                u.Role = RoleAdmin
+            }
+        }
+        if _, ok := roleRanks[u.Role]; !ok {
+            return fmt.Errorf("user %s: unknown role %q", u.Username, u.Role)
+        }
+        u.Admin = u.Role == RoleAdmin
+    }
+    return nil
+}
+
+// APIToken is a long-lived bearer token acting as its owner.  Only the
+// SHA-256 Hash of the token is stored.  Scope optionally restricts it to
+// "read" (GET requests) or "arm" (status, arm and disarm).
+type APIToken struct {
+    ID         string     `json:"id"`
+    Name       string     `json:"name"`
+    Hash       string     `json:"hash"`
+    Scope      string     `json:"scope,omitempty"`
+    CreatedAt  time.Time  `json:"created_at"`
+    LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// APIKey is a static key for simple automation clients, presented in the
+// X-API-Key header.  It is not tied to a user: requests made with it act as
+// a synthetic user with Role, named "apikey:" followed by Name.  Only the
+// SHA-256 Hash of the key is stored.  Endpoints optionally restricts the
+// key to the listed paths, each optionally preceded by a method and a space
+// (e.g. "POST /api/disarm"); a trailing "*" matches any path with that
+// prefix.
+type APIKey struct {
+    ID         string     `json:"id"`
+    Name       string     `json:"name"`
+    Hash       string     `json:"hash"`
+    Role       string     `json:"role"`
+    Endpoints  []string   `json:"endpoints,omitempty"`
+    CreatedAt  time.Time  `json:"created_at"`
+    LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// WebAuthnCredential is a passkey registered by a user.  PublicKey is the
+// COSE encoded key from the authenticator and SignCount the last signature
+// counter it reported, used to detect cloned authenticators.
+type WebAuthnCredential struct {
+    ID         []byte     `json:"id"`
+    PublicKey  []byte     `json:"public_key"`
+    SignCount  uint32     `json:"sign_count"`
+    Name       string     `json:"name,omitempty"`
+    CreatedAt  time.Time  `json:"created_at"`
+    LastUsedAt *time.Time `json:"last_used_at,omitempty"`
 }
 
 // Config is the top‑level structure serialized to config.json.  It contains
@@ -48,12 +268,56 @@ type User struct {
 // can be added (e.g. alert settings) without breaking backward compatibility.
 type Config struct {
     HTTPPort int     `json:"http_port"` // port to listen on (default 8443)
+    // Listen lists the host:port addresses to listen on in place of
+    // HTTPPort on every interface, e.g. ["192.168.1.10:8443",
+    // "100.64.0.5:8443"], or "unix:/path" for a Unix domain socket.  See
+    // listen.go.
+    Listen   []string `json:"listen,omitempty"`
+    // UnixSocket sets the permissions of the sockets in Listen.  If nil,
+    // defaults apply.  See unixsocket.go.
+    UnixSocket *UnixSocketConfig `json:"unix_socket,omitempty"`
     CertFile string  `json:"cert_file"` // path to PEM encoded certificate
     KeyFile  string  `json:"key_file"`  // path to PEM encoded key
+    // CertHostname is the host name of the self-signed certificate
+    // generated when CertFile and KeyFile do not exist.  If empty, the
+    // machine's host name is used.  See selfsigned.go.
+    CertHostname string `json:"cert_hostname,omitempty"`
+    // ACME obtains the certificate from Let's Encrypt or another ACME CA
+    // in place of CertFile and KeyFile.  See ACMEConfig.
+    ACME *ACMEConfig `json:"acme,omitempty"`
     Zones    []Zone  `json:"zones"`
     ArmModes []ArmMode `json:"arm_modes"`
-    Users    []User  `json:"users"`
+    Users    []User  `json:"users,omitempty"`
+    // UsersFile keeps Users in a file of their own rather than in
+    // config.json, relative to config.json.  See usersfile.go.
+    UsersFile string `json:"users_file,omitempty"`
+    // ReadOnlyConfig stops the API from changing the configuration, for
+    // one managed by other tools.  See readonly.go.
+    ReadOnlyConfig bool `json:"read_only_config,omitempty"`
     LogFile  string  `json:"log_file,omitempty"` // path to event log file
+    // LogRotation controls when the event log is rotated.  If nil, it is
+    // not rotated.
+    LogRotation *LogRotationConfig `json:"log_rotation,omitempty"`
+    // RetentionDays deletes rotated event logs once all their entries are
+    // older than this many days.  If zero, they are kept until rotation's
+    // keep limit removes them.
+    RetentionDays int `json:"retention_days,omitempty"`
+    // LogLevel is the least severe level of log records printed to
+    // standard error: debug, info (the default), warning or alarm.  It
+    // does not change what is written to the event log.
+    LogLevel string `json:"log_level,omitempty"`
+    // LogSinks are further destinations for log records, each with its
+    // own filter, see LogSinkConfig.  If empty, records are printed to
+    // standard error at LogLevel.  The event log is written regardless.
+    LogSinks []LogSinkConfig `json:"log_sinks,omitempty"`
+    // Timezone is the IANA time zone, e.g. "Europe/London", that times are
+    // shown in by the API, alerts and the UI.  The event log itself is
+    // kept in UTC.  If empty, the machine's zone is used.
+    Timezone string `json:"timezone,omitempty"`
+    // BaseURL is the address the Minder UI is reached at, e.g.
+    // "https://minder.example.org".  Alerts that can carry a link point it
+    // at the status page.
+    BaseURL  string  `json:"base_url,omitempty"`
     // Alerts define how the system should notify when a zone is triggered.
     // If empty, a default log alert will be used.  Each alert configuration
     // may define an email transport or other mechanism.  See AlertConfig for
@@ -70,19 +334,540 @@ type Config struct {
     // the system awaits disarm before raising an alarm.  If zero,
     // a default of 30 seconds will be used.
     EntryDelay int `json:"entry_delay,omitempty"`
+    // MQTT configures an optional shared connection to an MQTT broker used
+    // by MQTT alert handlers and integrations.
+    MQTT *MQTTConfig `json:"mqtt,omitempty"`
+    // AlertRetry controls how failed alert deliveries are retried.  If
+    // nil, defaults are used.
+    AlertRetry *AlertRetryConfig `json:"alert_retry,omitempty"`
+    // LDAP optionally authenticates users against a directory.  See
+    // LDAPConfig.
+    LDAP *LDAPConfig `json:"ldap,omitempty"`
+    // OIDC optionally enables single sign-on through an OpenID Connect
+    // provider.  See OIDCConfig.
+    OIDC *OIDCConfig `json:"oidc,omitempty"`
+    // ListenMode is "tls" (the default) to serve HTTPS, or "http" to serve
+    // plain HTTP on 127.0.0.1 behind a reverse proxy that terminates TLS.
+    // See listenmode.go.
+    ListenMode string `json:"listen_mode,omitempty"`
+    // InsecureBind makes listen mode "http" listen on every interface
+    // rather than 127.0.0.1 only.
+    InsecureBind bool `json:"insecure_bind,omitempty"`
+    // ForwardedProtoHeader is the header in which a trusted proxy passes
+    // the scheme of the original request in listen mode "http".  If
+    // empty, X-Forwarded-Proto is used.
+    ForwardedProtoHeader string `json:"forwarded_proto_header,omitempty"`
+    // RedirectPort is the port of the plain HTTP listener redirecting to
+    // HTTPS, usually 80.  If zero there is none.  See redirect.go.
+    RedirectPort int `json:"redirect_port,omitempty"`
+    // HTTPLimits bounds the time and number of connections the HTTPS
+    // server gives clients.  If nil, defaults apply.  See HTTPLimitsConfig.
+    HTTPLimits *HTTPLimitsConfig `json:"http_limits,omitempty"`
+    // ClientCerts optionally authenticates TLS client certificates.  See
+    // ClientCertConfig.
+    ClientCerts *ClientCertConfig `json:"client_certs,omitempty"`
+    // ProxyAuth optionally trusts a user name header set by an
+    // authenticating reverse proxy.  See ProxyAuthConfig.
+    ProxyAuth *ProxyAuthConfig `json:"proxy_auth,omitempty"`
+    // CORS optionally lets pages on other origins call the API.  If nil,
+    // only the web UI's own origin may.  See CORSConfig.
+    CORS *CORSConfig `json:"cors,omitempty"`
+    // SessionTTL is the absolute lifetime of a login session in seconds,
+    // however often it is used.  If zero, sessions last 24 hours.
+    SessionTTL int `json:"session_ttl,omitempty"`
+    // IdleTimeout ends a session that has not been used for this many
+    // seconds.  Each request extends the session, up to SessionTTL.  If
+    // zero, sessions do not time out while idle.
+    IdleTimeout int `json:"idle_timeout,omitempty"`
+    // MaxSessionsPerUser limits how many sessions each user may have at
+    // once.  If zero, there is no limit.  Users with SessionLimitExempt are
+    // not limited.
+    MaxSessionsPerUser int `json:"max_sessions_per_user,omitempty"`
+    // SessionLimitPolicy decides what happens to a login beyond
+    // MaxSessionsPerUser: "evict" (the default) ends the user's oldest
+    // sessions to make room, "reject" refuses the login.
+    SessionLimitPolicy string `json:"session_limit_policy,omitempty"`
+    // RememberTTL is how many seconds a remembered device stays logged in
+    // without being used.  Each refresh extends it.  If zero, devices are
+    // remembered for 90 days.
+    RememberTTL int `json:"remember_ttl,omitempty"`
+    // SessionBinding optionally rejects session cookies presented by a
+    // client other than the one that logged in.  See SessionBinding.
+    SessionBinding *SessionBinding `json:"session_binding,omitempty"`
+    // APIKeys are static keys for automation clients.  See APIKey.
+    APIKeys []APIKey `json:"api_keys,omitempty"`
+    // PersistSessions controls whether sessions are saved to sessions.json
+    // so that users stay logged in across restarts.  If nil, they are.
+    PersistSessions *bool `json:"persist_sessions,omitempty"`
+    // TrustedProxies lists the addresses (IPs or CIDR ranges) of reverse
+    // proxies whose X-Forwarded-For header is believed when recording the
+    // client address of a request.  If empty, the header is ignored.
+    TrustedProxies []string `json:"trusted_proxies,omitempty"`
+    // HashParams selects how passwords are hashed.  If nil, bcrypt at its
+    // default cost is used.
+    HashParams *HashParams `json:"hash_params,omitempty"`
+    // PasswordPolicy sets the rules new passwords and PINs must meet.  If
+    // nil, defaults are used.
+    PasswordPolicy *PasswordPolicy `json:"password_policy,omitempty"`
+    // AlertTimeout is the number of seconds a single alert delivery may
+    // take before it is abandoned as timed out.  If zero, a default of 15
+    // seconds is used.  AlertConfig.Timeout overrides it per alert.
+    AlertTimeout int `json:"alert_timeout,omitempty"`
+    // AlertRateLimit caps how many events are passed to the alert handlers.
+    // If nil, events are not limited.
+    AlertRateLimit *AlertRateLimitConfig `json:"alert_rate_limit,omitempty"`
+    // Escalation lists tiers of alerts that are notified one after another
+    // while an alarm is neither disarmed nor acknowledged.  Alerts named in
+    // a tier receive trigger and alarm events only through escalation.
+    Escalation []EscalationTier `json:"escalation,omitempty"`
+    // fileValues holds, by JSON name, the values in config.json of the
+    // settings overridden by environment variables, which is what Save
+    // writes for them.  See envoverride.go.
+    fileValues map[string]any
+}
+
+// EscalationTier names the alerts (by AlertConfig name or type) notified
+// Delay seconds after the previous tier, or after the alarm for the first
+// tier.
+type EscalationTier struct {
+    Delay  int      `json:"delay"`
+    Alerts []string `json:"alerts"`
+}
+
+// AlertRetryConfig bounds the alert retry queue.  Failed deliveries are
+// retried with exponential backoff until MaxAttempts deliveries have been
+// made (default 10) or the event is older than MaxAge seconds (default
+// 3600), whichever comes first.
+type AlertRetryConfig struct {
+    MaxAttempts int `json:"max_attempts,omitempty"`
+    MaxAge      int `json:"max_age,omitempty"`
+}
+
+// LDAPConfig configures directory logins.  URL is "ldap://host[:port]" or
+// "ldaps://host[:port]"; StartTLS upgrades a plain connection, verified
+// against CAFile if set.  Users are bound either directly through
+// BindTemplate, e.g. "uid={username},ou=people,dc=example,dc=org", or
+// found below SearchBase with UserFilter (default "(uid={username})"),
+// searching as BindDN/BindPassword if given.  GroupRoles maps group DNs to
+// roles; groups are read from the user's memberOf attribute, or searched
+// for below GroupBase.  Users in no mapped group get DefaultRole, or are
+// refused if it is empty.  Timeout is in seconds (default 5).
+type LDAPConfig struct {
+    URL                string            `json:"url"`
+    StartTLS           bool              `json:"start_tls,omitempty"`
+    CAFile             string            `json:"ca_file,omitempty"`
+    InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"`
+    BindTemplate       string            `json:"bind_template,omitempty"`
+    BindDN             string            `json:"bind_dn,omitempty"`
+    BindPassword       string            `json:"bind_password,omitempty"`
+    SearchBase         string            `json:"search_base,omitempty"`
+    UserFilter         string            `json:"user_filter,omitempty"`
+    GroupBase          string            `json:"group_base,omitempty"`
+    GroupRoles         map[string]string `json:"group_roles,omitempty"`
+    DefaultRole        string            `json:"default_role,omitempty"`
+    Timeout            int               `json:"timeout,omitempty"`
+}
+
+// OIDCConfig configures single sign-on.  Issuer is the provider's issuer
+// URL, from which its endpoints are discovered.  RedirectURL must be the
+// absolute URL of /api/oidc/callback as registered with the provider.
+// Scopes default to "openid profile email".  The user is identified by
+// UsernameClaim (default "preferred_username") and ClaimRoles maps values
+// of RoleClaim (default "groups") to roles, DefaultRole applying when
+// none match.  Unknown users are created only with AutoProvision.
+// PasswordLogin keeps password logins "enabled" (the default), limits
+// them to "admins" as a break-glass path or "disabled" them.
+type OIDCConfig struct {
+    Issuer        string            `json:"issuer"`
+    ClientID      string            `json:"client_id"`
+    ClientSecret  string            `json:"client_secret,omitempty"`
+    RedirectURL   string            `json:"redirect_url"`
+    Scopes        []string          `json:"scopes,omitempty"`
+    UsernameClaim string            `json:"username_claim,omitempty"`
+    RoleClaim     string            `json:"role_claim,omitempty"`
+    ClaimRoles    map[string]string `json:"claim_roles,omitempty"`
+    DefaultRole   string            `json:"default_role,omitempty"`
+    AutoProvision bool              `json:"auto_provision,omitempty"`
+    PasswordLogin string            `json:"password_login,omitempty"`
+}
+
+// LogRotationConfig turns on rotation of the event log.  The log is renamed
+// to events-YYYYMMDD.log, after the day of its last entry, once it would
+// grow past MaxSizeMB (default 10) or, with Daily, at the first entry of a
+// new day.  Rotated logs other than the newest are gzipped, and only the
+// newest Keep (default 10) are kept.
+type LogRotationConfig struct {
+    MaxSizeMB int  `json:"max_size_mb,omitempty"`
+    Daily     bool `json:"daily,omitempty"`
+    Keep      int  `json:"keep,omitempty"`
+}
+
+// LogSinkConfig is a destination for log records besides the event log.
+// Type is "file", appending events to Path in the format of the event log
+// and rotating it like log_rotation; "syslog", to the local syslog or, with
+// Network ("udp", "tcp", "unix" or "unixgram") and Address, a remote one,
+// under Tag (default "minder"); or "stderr".  Level is the least severe
+// level taken, by default info for files and log_level otherwise.  Events
+// limits the events taken by their type, the first word of the message,
+// e.g. "trigger" or "fault"; without it files take every event and syslog
+// and stderr only warnings and alarms, besides diagnostics.
+type LogSinkConfig struct {
+    Type    string   `json:"type"`
+    Path    string   `json:"path,omitempty"`
+    Network string   `json:"network,omitempty"`
+    Address string   `json:"address,omitempty"`
+    Tag     string   `json:"tag,omitempty"`
+    Level   string   `json:"level,omitempty"`
+    Events  []string `json:"events,omitempty"`
+}
+
+// Preferences are a user's settings, changed through /api/profile.
+// NotifyEvents limits the events alerts sent to users notify them of, by
+// the same names as AlertConfig.Events; if empty, they get every event.
+// During QuietHours only critical events reach them.  LandingPage is the
+// web UI page to open on and TemperatureUnit "celsius" or "fahrenheit".
+type Preferences struct {
+    NotifyEvents    []string    `json:"notify_events,omitempty"`
+    QuietHours      *QuietHours `json:"quiet_hours,omitempty"`
+    LandingPage     string      `json:"landing_page,omitempty"`
+    TemperatureUnit string      `json:"temperature_unit,omitempty"`
+}
+
+// QuietHours is a daily period, from Start to End as local "HH:MM" times,
+// which may span midnight.
+type QuietHours struct {
+    Start string `json:"start"`
+    End   string `json:"end"`
+}
+
+// ACMEConfig obtains and renews certificates for Domains automatically.
+// Email is given to the CA for expiry notices.  Certificates and the
+// account key are kept in CacheDir (default "acme-cache" beside
+// config.json).  Challenge is "tls-alpn-01" (default), answered on
+// http_port, which the CA must reach as port 443, or "http-01", answered
+// by the listener on Config.RedirectPort.  DirectoryURL selects another CA,
+// e.g. Let's Encrypt's staging environment; the default is Let's Encrypt.
+type ACMEConfig struct {
+    Domains      []string `json:"domains"`
+    Email        string   `json:"email,omitempty"`
+    CacheDir     string   `json:"cache_dir,omitempty"`
+    Challenge    string   `json:"challenge,omitempty"`
+    DirectoryURL string   `json:"directory_url,omitempty"`
+}
+
+// CORSConfig lets pages on the origins in AllowedOrigins, e.g.
+// "https://dash.example.org", call the API from the browser; "*" allows
+// any origin unless AllowCredentials is set.  AllowedMethods defaults to
+// GET, HEAD and POST, and AllowedHeaders to Authorization, Content-Type,
+// X-API-Key and X-CSRF-Token.  AllowCredentials lets the pages send
+// cookies.  MaxAge is how many seconds browsers may cache a preflight
+// response (default 600).  See cors.go.
+type CORSConfig struct {
+    AllowedOrigins   []string `json:"allowed_origins,omitempty"`
+    AllowedMethods   []string `json:"allowed_methods,omitempty"`
+    AllowedHeaders   []string `json:"allowed_headers,omitempty"`
+    AllowCredentials bool     `json:"allow_credentials,omitempty"`
+    MaxAge           int      `json:"max_age,omitempty"`
+}
+
+// UnixSocketConfig sets the permissions of Unix domain sockets named in
+// listen.  Mode is octal, e.g. "0660" (the default); Group, if set, owns
+// the socket, so that a proxy running as a member may connect.
+type UnixSocketConfig struct {
+    Mode  string `json:"mode,omitempty"`
+    Group string `json:"group,omitempty"`
+}
+
+// HTTPLimitsConfig protects the HTTPS server from slow and numerous
+// clients.  Timeouts are in seconds: ReadHeaderTimeout (default 10) for
+// the TLS handshake and request headers, ReadTimeout (default 30) for the
+// whole request, WriteTimeout (default 60) for the response and
+// IdleTimeout (default 120) between requests on a kept-alive connection.
+// MaxHeaderBytes defaults to 64 KiB.  MaxConnections (default 64) caps the
+// connections served at once; more wait to be accepted.  Zero fields take
+// their default.
+type HTTPLimitsConfig struct {
+    ReadHeaderTimeout int `json:"read_header_timeout,omitempty"`
+    ReadTimeout       int `json:"read_timeout,omitempty"`
+    WriteTimeout      int `json:"write_timeout,omitempty"`
+    IdleTimeout       int `json:"idle_timeout,omitempty"`
+    MaxHeaderBytes    int `json:"max_header_bytes,omitempty"`
+    MaxConnections    int `json:"max_connections,omitempty"`
+}
+
+// ClientCertConfig configures authentication by TLS client certificate.
+// Mode "request" asks clients for a certificate and "require" refuses
+// connections without one; either way certificates must be issued by the
+// CA in CAFile.  Users maps a certificate's common name, or one of its DNS,
+// email or URI subject alternative names, to a user name.  Certificates
+// whose serial number is in Revoked (hex, colons optional) or revoked by
+// the CRL in CRLFile are refused.
+type ClientCertConfig struct {
+    Mode    string            `json:"mode,omitempty"`
+    CAFile  string            `json:"ca_file,omitempty"`
+    Users   map[string]string `json:"users,omitempty"`
+    CRLFile string            `json:"crl_file,omitempty"`
+    Revoked []string          `json:"revoked,omitempty"`
+}
+
+// ProxyAuthConfig configures authentication by a reverse proxy such as
+// Authelia.  When Enabled, requests whose peer address matches Proxies (IPs
+// or CIDR ranges) are authenticated as the user named in Header (default
+// "Remote-User") without a session cookie.  The header is ignored on
+// requests from any other address, so without Proxies it is never
+// believed.  Unknown users are created with DefaultRole only with
+// AutoProvision.
+type ProxyAuthConfig struct {
+    Enabled       bool     `json:"enabled"`
+    Header        string   `json:"header,omitempty"`
+    Proxies       []string `json:"proxies,omitempty"`
+    AutoProvision bool     `json:"auto_provision,omitempty"`
+    DefaultRole   string   `json:"default_role,omitempty"`
+}
+
+// HashParams configures password hashing.  Algorithm is "bcrypt" (the
+// default) with Cost, or "argon2id" with Memory in KiB (default 65536),
+// Time iterations (default 3) and Parallelism threads (default 2).
+// Existing hashes of any algorithm keep working and are re-hashed with
+// these settings at the user's next login.
+type HashParams struct {
+    Algorithm   string `json:"algorithm,omitempty"`
+    Cost        int    `json:"cost,omitempty"`
+    Memory      int    `json:"memory,omitempty"`
+    Time        int    `json:"time,omitempty"`
+    Parallelism int    `json:"parallelism,omitempty"`
+}
+
+// PasswordPolicy is checked whenever a password or disarm PIN is set.
+// MinLength defaults to 8 and PINMinLength to 4; the Require flags demand
+// at least one character of each class.  Common passwords such as "admin"
+// are always refused.
+type PasswordPolicy struct {
+    MinLength     int  `json:"min_length,omitempty"`
+    RequireUpper  bool `json:"require_upper,omitempty"`
+    RequireLower  bool `json:"require_lower,omitempty"`
+    RequireDigit  bool `json:"require_digit,omitempty"`
+    RequireSymbol bool `json:"require_symbol,omitempty"`
+    PINMinLength  int  `json:"pin_min_length,omitempty"`
+}
+
+// SessionBinding ties each session to the client that logged in, so that a
+// leaked session cookie does not work from elsewhere.  With IP set, the
+// client address must stay within the same network as at login: the first
+// IPv4Prefix bits (default 32, the exact address; 24 allows a /24) or
+// IPv6Prefix bits (default 128; 64 allows the usual home or mobile
+// network).  With UserAgent set the User-Agent header must not change,
+// which is the gentler option for phones switching between Wi-Fi and
+// mobile data.  A request that does not match ends the session.
+type SessionBinding struct {
+    IP         bool `json:"ip,omitempty"`
+    IPv4Prefix int  `json:"ipv4_prefix,omitempty"`
+    IPv6Prefix int  `json:"ipv6_prefix,omitempty"`
+    UserAgent  bool `json:"user_agent,omitempty"`
+}
+
+// AlertRateLimitConfig configures the token bucket limiting alert events:
+// PerMinute events a minute on average (default 10) with bursts of up to
+// Burst events (default 10).  Excess events are summarised in a single
+// notification once the bucket refills.  Alarms are never limited.
+type AlertRateLimitConfig struct {
+    PerMinute int `json:"per_minute,omitempty"`
+    Burst     int `json:"burst,omitempty"`
 }
 
 // AlertConfig specifies the configuration for a single alerting mechanism.  The
-// Type field selects the handler: currently "log" writes to the event log and
-// "email" sends an email via SMTP.  When Type is "email", the SMTP fields
-// must be provided.
+// Type field selects the handler: currently "log" writes to the event log,
+// "email" sends an email via SMTP, "webhook" posts a signed JSON payload to
+// an HTTP endpoint, "sms" sends text messages through Twilio, "voice" places
+// Twilio phone calls, "ntfy" publishes to an ntfy topic, "gotify" pushes to
+// a Gotify server, "slack" posts to a Slack incoming webhook, "discord"
+// posts to one or more Discord webhooks, "signal" sends through a local
+// signal-cli daemon, "matrix" posts to a Matrix room, "pushbullet" sends
+// Pushbullet pushes and "mqtt" publishes to an MQTT broker.  When Type is
+// "email", the SMTP fields must be provided; when Type is "webhook" or
+// "slack", URL must be provided; when Type is "sms" or "voice", the Twilio
+// fields, From and ToNumbers must be provided; when Type is "ntfy", Topic
+// must be provided; when Type is "gotify", URL and Token must be provided;
+// when Type is "discord", URL or URLs must be provided; when Type is
+// "signal", Address, From and either ToNumbers or GroupID must be provided;
+// when Type is "matrix", URL, Token and RoomID must be provided; when Type
+// is "pushbullet", Token must be provided;
+// when Type is "mqtt", URL (the broker) must be provided unless Config.MQTT
+// configures a shared connection.
 type AlertConfig struct {
-    Type       string `json:"type"`        // "log" or "email"
+    // ID identifies the alert in the /api/alerts endpoints.  Alerts
+    // written without an ID are numbered when the configuration is loaded.
+    ID         int    `json:"id"`
+    // Name optionally labels the alert so that escalation tiers and the
+    // API can refer to it.  It defaults to the type.
+    Name       string `json:"name,omitempty"`
+    Type       string `json:"type"`        // "log", "email", "webhook", "sms", "ntfy", "gotify", "slack", "discord", "signal", "matrix", "pushbullet", "mqtt" or "voice"
     SMTPServer string `json:"smtp_server,omitempty"`
     SMTPPort   int    `json:"smtp_port,omitempty"`
     Username   string `json:"username,omitempty"`
     Password   string `json:"password,omitempty"`
     From       string `json:"from,omitempty"`
-    To         string `json:"to,omitempty"`
+    To         AddressList `json:"to,omitempty"`
+    Cc         AddressList `json:"cc,omitempty"`
+    Bcc        AddressList `json:"bcc,omitempty"`
     Subject    string `json:"subject,omitempty"`
-}
\ No newline at end of file
+    // TLSMode selects how email is encrypted: "starttls", "tls" (implicit,
+    // port 465) or "none".  Defaults to "tls" on port 465 and "starttls"
+    // otherwise.  CAFile optionally pins the CA used to verify the server.
+    TLSMode    string `json:"tls_mode,omitempty"`
+    CAFile     string `json:"ca_file,omitempty"`
+    // Webhook settings.  Method defaults to POST.  Headers are added to
+    // every request.  When Secret is set, the request carries an
+    // X-Minder-Signature header with an HMAC-SHA256 of the body.
+    URL        string            `json:"url,omitempty"`
+    Method     string            `json:"method,omitempty"`
+    Headers    map[string]string `json:"headers,omitempty"`
+    Secret     string            `json:"secret,omitempty"`
+    // Twilio SMS and voice settings.  From is the sending number and
+    // ToNumbers the destinations, both in E.164 format; voice alerts call
+    // them in order until one answers.  When TruncateSMS is set the
+    // message is shortened to fit a single SMS segment.
+    AccountSID  string   `json:"account_sid,omitempty"`
+    AuthToken   string   `json:"auth_token,omitempty"`
+    ToNumbers   []string `json:"to_numbers,omitempty"`
+    TruncateSMS bool     `json:"truncate_sms,omitempty"`
+    // ntfy settings.  URL is the server (default https://ntfy.sh) and Token
+    // an optional access token.  Gotify uses URL for the server and Token
+    // for the application token.  Priority (1-5) applies to alarm and
+    // trigger events and defaults to 4 (high); Tags are added to every
+    // message.  InsecureSkipVerify disables TLS certificate verification
+    // for self-hosted servers with self-signed certificates.
+    Topic              string   `json:"topic,omitempty"`
+    Token              string   `json:"token,omitempty"`
+    Priority           int      `json:"priority,omitempty"`
+    Tags               []string `json:"tags,omitempty"`
+    InsecureSkipVerify bool     `json:"insecure_skip_verify,omitempty"`
+    // Channel optionally overrides the default channel of a Slack
+    // incoming webhook.  For Pushbullet it is the tag of a channel to push
+    // to instead of the account's devices.
+    Channel string `json:"channel,omitempty"`
+    // Device optionally limits Pushbullet pushes to the device with this
+    // iden.
+    Device string `json:"device,omitempty"`
+    // URLs lists additional webhook URLs for handlers that can post to
+    // several endpoints (currently "discord").
+    URLs []string `json:"urls,omitempty"`
+    // Signal settings.  Address locates the signal-cli JSON-RPC daemon:
+    // "http://host:port" for --http mode, "unix:/path" for --socket mode or
+    // "host:port" for --tcp mode.  From is the registered sender number;
+    // messages go to ToNumbers or, if set, the group GroupID.
+    Address string `json:"address,omitempty"`
+    GroupID string `json:"group_id,omitempty"`
+    // Matrix settings.  URL is the homeserver, Token the access token of
+    // the sending user and RoomID the room (e.g. "!abc:example.org") it
+    // has joined.
+    RoomID string `json:"room_id,omitempty"`
+    // MQTT settings.  Topic is the prefix events are published under
+    // (default "minder/events"); QoS and Retain are keyed by event type
+    // with "*" as the fallback.
+    QoS    map[string]int  `json:"qos,omitempty"`
+    Retain map[string]bool `json:"retain,omitempty"`
+    // Events restricts the event types delivered to this alert, e.g.
+    // ["alarm", "fault"].  If empty, trigger, alarm, test and fault events
+    // are delivered.
+    Events []string `json:"events,omitempty"`
+    // Heartbeat optionally schedules a regular test notification through
+    // this alert.  See HeartbeatConfig.
+    Heartbeat *HeartbeatConfig `json:"heartbeat,omitempty"`
+    // Digest optionally sends a daily summary of the previous day through
+    // this alert.  See DigestConfig.
+    Digest *DigestConfig `json:"digest,omitempty"`
+    // SubjectTemplate and BodyTemplate optionally replace the default
+    // wording of any handler using Go text/template syntax, e.g.
+    // "{{.Event}} in {{.Zone.Name}} while {{.Mode}}".  Handlers without a
+    // subject line ignore SubjectTemplate.
+    SubjectTemplate string `json:"subject_template,omitempty"`
+    BodyTemplate    string `json:"body_template,omitempty"`
+    // Timeout overrides Config.AlertTimeout for this alert, in seconds.
+    Timeout int `json:"timeout,omitempty"`
+    // NotifyUsers additionally sends email, SMS and voice alerts to every
+    // enabled user with an email address or phone number who has not
+    // turned notifications off.
+    NotifyUsers bool `json:"notify_users,omitempty"`
+}
+
+// HeartbeatConfig schedules heartbeat notifications for an alert, either
+// daily At a time of day ("HH:MM", local time) or every EveryHours hours.
+// After FailureThreshold consecutive failures (default 3) a fault is raised
+// and reported through the other alerts.
+type HeartbeatConfig struct {
+    At               string `json:"at,omitempty"`
+    EveryHours       int    `json:"every_hours,omitempty"`
+    FailureThreshold int    `json:"failure_threshold,omitempty"`
+}
+
+// DigestConfig schedules the daily digest of an alert At a time of day
+// ("HH:MM", in Config.Timezone).
+type DigestConfig struct {
+    At string `json:"at"`
+}
+
+// MQTTConfig configures the server's shared MQTT broker connection.
+// Broker is a URL such as "tcp://192.168.1.10:1883" or "ssl://host:8883".
+// ClientID and BaseTopic both default to "minder".  BufferSize bounds the
+// number of messages held while the broker is unreachable (default 100).
+type MQTTConfig struct {
+    Broker     string `json:"broker"`
+    ClientID   string `json:"client_id,omitempty"`
+    Username   string `json:"username,omitempty"`
+    Password   string `json:"password,omitempty"`
+    BufferSize int    `json:"buffer_size,omitempty"`
+    BaseTopic  string `json:"base_topic,omitempty"`
+    // HomeAssistant enables Home Assistant MQTT discovery.  See
+    // HomeAssistantConfig.
+    HomeAssistant *HomeAssistantConfig `json:"home_assistant,omitempty"`
+}
+
+// HomeAssistantConfig controls the Home Assistant integration.  When
+// Enabled, Minder publishes discovery documents for an alarm_control_panel
+// and one binary_sensor per zone, publishes state changes and accepts
+// commands.  DiscoveryPrefix defaults to "homeassistant".  When Code is set,
+// arm and disarm commands must carry the same code.  AwayMode, HomeMode and
+// NightMode name the Minder arm modes used for the corresponding Home
+// Assistant commands (defaults "Away", "Home" and "Night").
+type HomeAssistantConfig struct {
+    Enabled         bool   `json:"enabled"`
+    DiscoveryPrefix string `json:"discovery_prefix,omitempty"`
+    Code            string `json:"code,omitempty"`
+    AwayMode        string `json:"away_mode,omitempty"`
+    HomeMode        string `json:"home_mode,omitempty"`
+    NightMode       string `json:"night_mode,omitempty"`
+}
+// AddressList is a list of email addresses.  In JSON it may be written as a
+// single string or an array of strings so that configurations written
+// before multiple recipients were supported remain valid.  A list with one
+// address is written back as a plain string.
+type AddressList []string
+
+// UnmarshalJSON accepts either a string or an array of strings.
+func (al *AddressList) UnmarshalJSON(data []byte) error {
+    var single string
+    if err := json.Unmarshal(data, &single); err == nil {
+        if single == "" {
+            *al = nil
+        } else {
+            *al = AddressList{single}
+        }
+        return nil
+    }
+    var list []string
+    if err := json.Unmarshal(data, &list); err != nil {
+        return err
+    }
+    *al = list
+    return nil
+}
+
+// MarshalJSON writes a single address as a string and anything else as an
+// array.
+func (al AddressList) MarshalJSON() ([]byte, error) {
+    if len(al) == 1 {
+        return json.Marshal(al[0])
+    }
+    return json.Marshal([]string(al))
+}
@@ -0,0 +1,237 @@
+package main
+
+// This file manages the server's shared MQTT broker connection.
+
+import (
+    "fmt"
+    "sync"
+    "time"
+
+    mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Defaults for the MQTT connection.
+const (
+    mqttDefaultClientID   = "minder"
+    mqttDefaultBaseTopic  = "minder"
+    mqttDefaultBufferSize = 100
+    mqttPublishTimeout    = 10 * time.Second
+    // The client reconnects with exponential backoff starting at
+    // mqttRetryInterval and capped at mqttMaxReconnectWait.
+    mqttRetryInterval    = 2 * time.Second
+    mqttMaxReconnectWait = 2 * time.Minute
+)
+
+// mqttMessage is a publication held while the broker is unreachable.
+type mqttMessage struct {
+    topic   string
+    qos     byte
+    retain  bool
+    payload []byte
+}
+
+// MQTTClient wraps a paho MQTT client.  It reconnects automatically with
+// backoff and holds a bounded number of messages published while the
+// connection is down, delivering them once the broker is reachable again.
+// A single MQTTClient is shared by every part of the server that talks to
+// the broker.  It is safe for concurrent use.
+//
+// The client maintains an availability topic, <base>/status, which reads
+// "online" while connected and is set to "offline" by the broker's last
+// will when the connection drops.
+type MQTTClient struct {
+    client    mqtt.Client
+    logger    *EventLogger
+    broker    string
+    baseTopic string
+
+    mu        sync.Mutex
+    buffer    []mqttMessage
+    limit     int
+    subs      map[string]func(topic string, payload []byte)
+    onConnect []func()
+}
+
+// NewMQTTClient creates a client for the given broker settings and starts
+// connecting in the background.  Connection failures are logged and retried
+// rather than returned so that an unavailable broker never prevents the
+// server from starting.
+func NewMQTTClient(cfg MQTTConfig, logger *EventLogger) *MQTTClient {
+    mc := &MQTTClient{
+        logger:    logger,
+        broker:    cfg.Broker,
+        baseTopic: cfg.BaseTopic,
+        limit:     cfg.BufferSize,
+        subs:      make(map[string]func(string, []byte)),
+    }
+    if mc.limit <= 0 {
+        mc.limit = mqttDefaultBufferSize
+    }
+    if mc.baseTopic == "" {
+        mc.baseTopic = mqttDefaultBaseTopic
+    }
+    clientID := cfg.ClientID
+    if clientID == "" {
+        clientID = mqttDefaultClientID
+    }
+    opts := mqtt.NewClientOptions().
+        AddBroker(cfg.Broker).
+        SetClientID(clientID).
+        SetUsername(cfg.Username).
+        SetPassword(cfg.Password).
+        SetAutoReconnect(true).
+        SetConnectRetry(true).
+        SetConnectRetryInterval(mqttRetryInterval).
+        SetMaxReconnectInterval(mqttMaxReconnectWait).
+        SetWill(mc.StatusTopic(), "offline", 1, true).
+        SetOnConnectHandler(func(mqtt.Client) {
+            logger.Log("mqtt connected to %s", cfg.Broker)
+            mc.connected()
+        }).
+        SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+            logger.Log("mqtt connection to %s lost: %v", cfg.Broker, err)
+        })
+    mc.client = mqtt.NewClient(opts)
+    // With ConnectRetry enabled the token completes only once connected;
+    // retries happen in the background.
+    mc.client.Connect()
+    return mc
+}
+
+// StatusTopic returns the availability topic for this connection.
+func (mc *MQTTClient) StatusTopic() string {
+    return mc.baseTopic + "/status"
+}
+
+// BaseTopic returns the prefix under which Minder publishes its own topics.
+func (mc *MQTTClient) BaseTopic() string {
+    return mc.baseTopic
+}
+
+// Subscribe registers handler for messages on topic.  Subscriptions are
+// renewed automatically every time the connection is re-established.
+func (mc *MQTTClient) Subscribe(topic string, handler func(topic string, payload []byte)) {
+    mc.mu.Lock()
+    mc.subs[topic] = handler
+    mc.mu.Unlock()
+    if mc.client.IsConnectionOpen() {
+        mc.subscribe(topic, handler)
+    }
+}
+
+// OnConnect registers fn to be called, in its own goroutine, every time the
+// connection is (re)established.  It is used to republish retained state.
+// If the client is already connected fn is also called immediately.
+func (mc *MQTTClient) OnConnect(fn func()) {
+    mc.mu.Lock()
+    mc.onConnect = append(mc.onConnect, fn)
+    mc.mu.Unlock()
+    if mc.client.IsConnectionOpen() {
+        go fn()
+    }
+}
+
+// subscribe issues a single subscription request to the broker.
+func (mc *MQTTClient) subscribe(topic string, handler func(string, []byte)) {
+    token := mc.client.Subscribe(topic, 1, func(_ mqtt.Client, m mqtt.Message) {
+        handler(m.Topic(), m.Payload())
+    })
+    if token.WaitTimeout(mqttPublishTimeout) && token.Error() != nil {
+        mc.logger.Log("mqtt subscribe to %s failed: %v", topic, token.Error())
+    }
+}
+
+// connected runs after every successful connection: it marks the client
+// online, renews subscriptions, notifies OnConnect callbacks and delivers
+// any buffered messages.
+func (mc *MQTTClient) connected() {
+    mc.mu.Lock()
+    subs := make(map[string]func(string, []byte), len(mc.subs))
+    for t, h := range mc.subs {
+        subs[t] = h
+    }
+    callbacks := append([]func(){}, mc.onConnect...)
+    mc.mu.Unlock()
+    // Paho invokes this handler on its own goroutine, but waiting on tokens
+    // from within it can deadlock, so the work is done asynchronously.
+    go func() {
+        if err := mc.publish(mqttMessage{topic: mc.StatusTopic(), qos: 1, retain: true, payload: []byte("online")}); err != nil {
+            mc.logger.Log("mqtt status publish failed: %v", err)
+        }
+        for t, h := range subs {
+            mc.subscribe(t, h)
+        }
+        for _, fn := range callbacks {
+            fn()
+        }
+        mc.flush()
+    }()
+}
+
+// Publish sends payload to topic.  If the broker is not connected the
+// message is buffered; when the buffer is full the oldest message is
+// dropped and the loss is logged.
+func (mc *MQTTClient) Publish(topic string, qos byte, retain bool, payload []byte) error {
+    msg := mqttMessage{topic: topic, qos: qos, retain: retain, payload: payload}
+    if !mc.client.IsConnectionOpen() {
+        mc.enqueue(msg)
+        return nil
+    }
+    if err := mc.publish(msg); err != nil {
+        mc.enqueue(msg)
+        return err
+    }
+    return nil
+}
+
+// publish sends a single message and waits for it to complete.
+func (mc *MQTTClient) publish(msg mqttMessage) error {
+    token := mc.client.Publish(msg.topic, msg.qos, msg.retain, msg.payload)
+    if !token.WaitTimeout(mqttPublishTimeout) {
+        return fmt.Errorf("mqtt publish to %s timed out", msg.topic)
+    }
+    return token.Error()
+}
+
+// enqueue appends msg to the offline buffer, dropping the oldest entry if
+// the buffer is full.
+func (mc *MQTTClient) enqueue(msg mqttMessage) {
+    mc.mu.Lock()
+    defer mc.mu.Unlock()
+    if len(mc.buffer) >= mc.limit {
+        dropped := mc.buffer[0]
+        mc.buffer = mc.buffer[1:]
+        mc.logger.Log("mqtt buffer full, dropped message for %s", dropped.topic)
+    }
+    mc.buffer = append(mc.buffer, msg)
+}
+
+// flush publishes buffered messages in order.  It is called whenever the
+// connection is (re)established.  Messages that fail are put back.
+func (mc *MQTTClient) flush() {
+    mc.mu.Lock()
+    pending := mc.buffer
+    mc.buffer = nil
+    mc.mu.Unlock()
+    if len(pending) == 0 {
+        return
+    }
+    sent := 0
+    for _, msg := range pending {
+        if err := mc.publish(msg); err != nil {
+            mc.enqueue(msg)
+            continue
+        }
+        sent++
+    }
+    mc.logger.Log("mqtt delivered %d buffered message(s)", sent)
+}
+
+// Close marks the client offline and disconnects from the broker, allowing
+// a short time for in-flight messages to complete.
+func (mc *MQTTClient) Close() {
+    if mc.client.IsConnectionOpen() {
+        _ = mc.publish(mqttMessage{topic: mc.StatusTopic(), qos: 1, retain: true, payload: []byte("offline")})
+    }
+    mc.client.Disconnect(250)
+}
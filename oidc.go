@@ -0,0 +1,669 @@
+package main
+
+// This file implements OpenID Connect single sign-on with the
+// authorisation code flow and PKCE.  The provider is found through its
+// discovery document and ID tokens are verified against its published
+// keys.  A successful login starts the same session as a password login.
+
+import (
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/ed25519"
+    "crypto/elliptic"
+    "crypto/rsa"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "math/big"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+// oidcLoginTimeout is how long a user has to complete a login at the
+// provider.
+const oidcLoginTimeout = 10 * time.Minute
+
+// oidcHTTPTimeout bounds each request to the provider.
+const oidcHTTPTimeout = 10 * time.Second
+
+// oidcClockSkew is the leeway allowed when checking token times.
+const oidcClockSkew = time.Minute
+
+// Password login modes for OIDCConfig.PasswordLogin.
+const (
+    PasswordLoginEnabled  = "enabled"
+    PasswordLoginAdmins   = "admins"
+    PasswordLoginDisabled = "disabled"
+)
+
+// oidcPending is a login started at /api/oidc/login awaiting its callback.
+type oidcPending struct {
+    verifier string
+    nonce    string
+    expires  time.Time
+}
+
+// oidcProvider holds the outstanding logins, keyed by state, and the
+// provider metadata and keys, fetched when first needed.
+type oidcProvider struct {
+    mu        sync.Mutex
+    pending   map[string]oidcPending
+    issuer    string
+    discovery *oidcDiscovery
+    keys      map[string]jsonWebKey
+}
+
+// oidcDiscovery is the part of the provider's discovery document we use.
+type oidcDiscovery struct {
+    Issuer                string `json:"issuer"`
+    AuthorizationEndpoint string `json:"authorization_endpoint"`
+    TokenEndpoint         string `json:"token_endpoint"`
+    JWKSURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey is a public key from the provider's JWKS (RFC 7517).
+type jsonWebKey struct {
+    Kid string `json:"kid"`
+    Kty string `json:"kty"`
+    Alg string `json:"alg"`
+    Use string `json:"use"`
+    N   string `json:"n"`
+    E   string `json:"e"`
+    Crv string `json:"crv"`
+    X   string `json:"x"`
+    Y   string `json:"y"`
+}
+
+// oidcEnabled reports whether single sign-on is configured.
+func oidcEnabled(oc *OIDCConfig) bool {
+    return oc != nil && oc.Issuer != ""
+}
+
+// validateOIDCConfig checks the oidc section of config.json.
+func validateOIDCConfig(oc *OIDCConfig) error {
+    if !oidcEnabled(oc) {
+        return nil
+    }
+    if u, err := url.Parse(oc.Issuer); err != nil || u.Scheme != "https" || u.Host == "" {
+        return errors.New("oidc issuer must be an https URL")
+    }
+    if oc.ClientID == "" || oc.RedirectURL == "" {
+        return errors.New("oidc needs client_id and redirect_url")
+    }
+    if u, err := url.Parse(oc.RedirectURL); err != nil || u.Host == "" || !strings.HasSuffix(u.Path, "/api/oidc/callback") {
+        return errors.New("oidc redirect_url must be the absolute URL of /api/oidc/callback")
+    }
+    for value, role := range oc.ClaimRoles {
+        if _, ok := roleRanks[role]; !ok {
+            return fmt.Errorf("oidc claim value %s: unknown role %q", value, role)
+        }
+    }
+    if _, ok := roleRanks[oc.DefaultRole]; oc.DefaultRole != "" && !ok {
+        return fmt.Errorf("oidc default_role: unknown role %q", oc.DefaultRole)
+    }
+    switch oc.PasswordLogin {
+    case "", PasswordLoginEnabled, PasswordLoginAdmins, PasswordLoginDisabled:
+    default:
+        return errors.New("oidc password_login must be enabled, admins or disabled")
+    }
+    return nil
+}
+
+// passwordLoginAllowed reports whether user may log in with a password
+// under the configured single sign-on policy.
+func passwordLoginAllowed(oc *OIDCConfig, user User) bool {
+    if !oidcEnabled(oc) {
+        return true
+    }
+    switch oc.PasswordLogin {
+    case PasswordLoginDisabled:
+        return false
+    case PasswordLoginAdmins:
+        return user.hasRole(RoleAdmin)
+    }
+    return true
+}
+
+// oidcGetJSON fetches url and decodes the JSON response into v.
+func oidcGetJSON(client *http.Client, url string, v any) error {
+    resp, err := client.Get(url)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("%s returned %s", url, resp.Status)
+    }
+    return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// metadata returns the discovery document of issuer, fetching it if it has
+// not been fetched yet or the issuer has changed.
+func (p *oidcProvider) metadata(issuer string) (*oidcDiscovery, error) {
+    p.mu.Lock()
+    if p.discovery != nil && p.issuer == issuer {
+        d := p.discovery
+        p.mu.Unlock()
+        return d, nil
+    }
+    p.mu.Unlock()
+    client := &http.Client{Timeout: oidcHTTPTimeout}
+    var d oidcDiscovery
+    if err := oidcGetJSON(client, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &d); err != nil {
+        return nil, fmt.Errorf("discovery: %w", err)
+    }
+    if strings.TrimSuffix(d.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+        return nil, fmt.Errorf("discovery document is for issuer %q", d.Issuer)
+    }
+    if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+        return nil, errors.New("discovery document lacks endpoints")
+    }
+    p.mu.Lock()
+    p.issuer, p.discovery, p.keys = issuer, &d, nil
+    p.mu.Unlock()
+    return &d, nil
+}
+
+// key returns the provider key with ID kid, refetching the key set when
+// the key is unknown so that key rotation is picked up.
+func (p *oidcProvider) key(d *oidcDiscovery, kid string) (jsonWebKey, error) {
+    p.mu.Lock()
+    k, ok := p.keys[kid]
+    p.mu.Unlock()
+    if ok {
+        return k, nil
+    }
+    var set struct {
+        Keys []jsonWebKey `json:"keys"`
+    }
+    if err := oidcGetJSON(&http.Client{Timeout: oidcHTTPTimeout}, d.JWKSURI, &set); err != nil {
+        return jsonWebKey{}, fmt.Errorf("fetching keys: %w", err)
+    }
+    keys := make(map[string]jsonWebKey)
+    for _, k := range set.Keys {
+        if k.Use == "" || k.Use == "sig" {
+            keys[k.Kid] = k
+        }
+    }
+    p.mu.Lock()
+    p.keys = keys
+    p.mu.Unlock()
+    if k, ok := keys[kid]; ok {
+        return k, nil
+    }
+    return jsonWebKey{}, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// begin records a new login and returns its state, nonce and PKCE
+// verifier.  Expired logins are discarded.
+func (p *oidcProvider) begin() (string, oidcPending, error) {
+    state, err := randomString(24)
+    if err != nil {
+        return "", oidcPending{}, err
+    }
+    nonce, err := randomString(24)
+    if err != nil {
+        return "", oidcPending{}, err
+    }
+    verifier, err := randomString(48)
+    if err != nil {
+        return "", oidcPending{}, err
+    }
+    pending := oidcPending{verifier: verifier, nonce: nonce, expires: time.Now().Add(oidcLoginTimeout)}
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if p.pending == nil {
+        p.pending = make(map[string]oidcPending)
+    }
+    now := time.Now()
+    for s, l := range p.pending {
+        if now.After(l.expires) {
+            delete(p.pending, s)
+        }
+    }
+    p.pending[state] = pending
+    return state, pending, nil
+}
+
+// take removes and returns the login with the given state.  Each state can
+// be used once.
+func (p *oidcProvider) take(state string) (oidcPending, bool) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    l, ok := p.pending[state]
+    delete(p.pending, state)
+    if !ok || time.Now().After(l.expires) {
+        return oidcPending{}, false
+    }
+    return l, true
+}
+
+// pkceChallenge returns the S256 code challenge of verifier (RFC 7636).
+func pkceChallenge(verifier string) string {
+    sum := sha256.Sum256([]byte(verifier))
+    return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// exchangeCode redeems an authorisation code for the ID token.
+func exchangeCode(oc OIDCConfig, d *oidcDiscovery, code, verifier string) (string, error) {
+    form := url.Values{}
+    form.Set("grant_type", "authorization_code")
+    form.Set("code", code)
+    form.Set("redirect_uri", oc.RedirectURL)
+    form.Set("code_verifier", verifier)
+    form.Set("client_id", oc.ClientID)
+    req, err := http.NewRequest(http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    if oc.ClientSecret != "" {
+        req.SetBasicAuth(url.QueryEscape(oc.ClientID), url.QueryEscape(oc.ClientSecret))
+    }
+    resp, err := (&http.Client{Timeout: oidcHTTPTimeout}).Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("token endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+    }
+    var tok struct {
+        IDToken string `json:"id_token"`
+    }
+    if err := json.Unmarshal(body, &tok); err != nil || tok.IDToken == "" {
+        return "", errors.New("token response has no id_token")
+    }
+    return tok.IDToken, nil
+}
+
+// verifyJWS checks the signature of a compact JWS with key and returns its
+// decoded payload.
+func verifyJWS(token string, keyFor func(kid string) (jsonWebKey, error)) ([]byte, error) {
+    parts := strings.Split(token, ".")
+    if len(parts) != 3 {
+        return nil, errors.New("malformed ID token")
+    }
+    rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+    if err != nil {
+        return nil, errors.New("malformed ID token header")
+    }
+    var header struct {
+        Alg string `json:"alg"`
+        Kid string `json:"kid"`
+    }
+    if err := json.Unmarshal(rawHeader, &header); err != nil {
+        return nil, errors.New("malformed ID token header")
+    }
+    sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil {
+        return nil, errors.New("malformed ID token signature")
+    }
+    key, err := keyFor(header.Kid)
+    if err != nil {
+        return nil, err
+    }
+    if key.Alg != "" && key.Alg != header.Alg {
+        return nil, fmt.Errorf("key %q is not for %s", header.Kid, header.Alg)
+    }
+    signed := []byte(parts[0] + "." + parts[1])
+    digest := sha256.Sum256(signed)
+    switch header.Alg {
+    case "RS256":
+        n, err1 := base64.RawURLEncoding.DecodeString(key.N)
+        e, err2 := base64.RawURLEncoding.DecodeString(key.E)
+        if key.Kty != "RSA" || err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+            return nil, errors.New("invalid RSA key")
+        }
+        pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+        if pub.N.BitLen() < 2048 {
+            return nil, errors.New("RSA key too short")
+        }
+        if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+            return nil, errors.New("ID token signature does not verify")
+        }
+    case "ES256":
+        x, err1 := base64.RawURLEncoding.DecodeString(key.X)
+        y, err2 := base64.RawURLEncoding.DecodeString(key.Y)
+        if key.Kty != "EC" || key.Crv != "P-256" || err1 != nil || err2 != nil || len(sig) != 64 {
+            return nil, errors.New("invalid EC key or signature")
+        }
+        pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+        if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+            return nil, errors.New("invalid EC key")
+        }
+        if !ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+            return nil, errors.New("ID token signature does not verify")
+        }
+    case "EdDSA":
+        x, err := base64.RawURLEncoding.DecodeString(key.X)
+        if key.Kty != "OKP" || key.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+            return nil, errors.New("invalid Ed25519 key")
+        }
+        if !ed25519.Verify(ed25519.PublicKey(x), signed, sig) {
+            return nil, errors.New("ID token signature does not verify")
+        }
+    default:
+        return nil, fmt.Errorf("unsupported ID token algorithm %q", header.Alg)
+    }
+    payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+    if err != nil {
+        return nil, errors.New("malformed ID token payload")
+    }
+    return payload, nil
+}
+
+// checkIDTokenClaims validates the standard claims of an ID token (OIDC
+// Core 3.1.3.7) and returns all of its claims.
+func checkIDTokenClaims(payload []byte, issuer, clientID, nonce string, now time.Time) (map[string]any, error) {
+    var claims map[string]any
+    if err := json.Unmarshal(payload, &claims); err != nil {
+        return nil, errors.New("malformed ID token claims")
+    }
+    if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(issuer, "/") {
+        return nil, fmt.Errorf("ID token issued by %q", iss)
+    }
+    audOK := false
+    switch aud := claims["aud"].(type) {
+    case string:
+        audOK = aud == clientID
+    case []any:
+        for _, a := range aud {
+            if a == clientID {
+                audOK = true
+            }
+        }
+        if azp, ok := claims["azp"].(string); len(aud) > 1 && (!ok || azp != clientID) {
+            audOK = false
+        }
+    }
+    if !audOK {
+        return nil, errors.New("ID token is not for this client")
+    }
+    exp, ok := claims["exp"].(float64)
+    if !ok || now.After(time.Unix(int64(exp), 0).Add(oidcClockSkew)) {
+        return nil, errors.New("ID token has expired")
+    }
+    if iat, ok := claims["iat"].(float64); ok && time.Unix(int64(iat), 0).After(now.Add(oidcClockSkew)) {
+        return nil, errors.New("ID token issued in the future")
+    }
+    got, _ := claims["nonce"].(string)
+    if subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+        return nil, errors.New("ID token nonce does not match")
+    }
+    return claims, nil
+}
+
+// claimValues returns a claim as a list of strings, accepting a single
+// string or an array.
+func claimValues(claims map[string]any, name string) []string {
+    switch v := claims[name].(type) {
+    case string:
+        return []string{v}
+    case []any:
+        var out []string
+        for _, e := range v {
+            if s, ok := e.(string); ok {
+                out = append(out, s)
+            }
+        }
+        return out
+    }
+    return nil
+}
+
+// oidcRole returns the highest role granted by the role claim, or
+// DefaultRole.
+func oidcRole(oc OIDCConfig, claims map[string]any) string {
+    claim := oc.RoleClaim
+    if claim == "" {
+        claim = "groups"
+    }
+    role := oc.DefaultRole
+    for _, v := range claimValues(claims, claim) {
+        if r, ok := oc.ClaimRoles[v]; ok && roleRanks[r] > roleRanks[role] {
+            role = r
+        }
+    }
+    return role
+}
+
+// handleOIDCLogin redirects the browser to the provider with GET
+// /api/oidc/login.
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    oc := s.cfgMgr.Get().OIDC
+    if !oidcEnabled(oc) {
+        http.NotFound(w, r)
+        return
+    }
+    d, err := s.oidc.metadata(oc.Issuer)
+    if err != nil {
+        s.logger.Log("single sign-on unavailable: %v", err)
+        http.Error(w, "single sign-on provider unavailable", http.StatusBadGateway)
+        return
+    }
+    state, pending, err := s.oidc.begin()
+    if err != nil {
+        http.Error(w, "internal error", http.StatusInternalServerError)
+        return
+    }
+    scopes := oc.Scopes
+    if len(scopes) == 0 {
+        scopes = []string{"openid", "profile", "email"}
+    }
+    q := url.Values{}
+    q.Set("response_type", "code")
+    q.Set("client_id", oc.ClientID)
+    q.Set("redirect_uri", oc.RedirectURL)
+    q.Set("scope", strings.Join(scopes, " "))
+    q.Set("state", state)
+    q.Set("nonce", pending.nonce)
+    q.Set("code_challenge", pkceChallenge(pending.verifier))
+    q.Set("code_challenge_method", "S256")
+    // The state is also kept in a cookie so that the callback can only be
+    // completed by the browser that started the login.
+    http.SetCookie(w, &http.Cookie{
+        Name:     "oidc_state",
+        Value:    state,
+        Path:     "/api/oidc/",
+        HttpOnly: true,
+        Secure:   s.secureRequest(r),
+        SameSite: http.SameSiteLaxMode,
+        MaxAge:   int(oidcLoginTimeout.Seconds()),
+    })
+    sep := "?"
+    if strings.Contains(d.AuthorizationEndpoint, "?") {
+        sep = "&"
+    }
+    http.Redirect(w, r, d.AuthorizationEndpoint+sep+q.Encode(), http.StatusFound)
+}
+
+// handleOIDCCallback completes a login at GET /api/oidc/callback: it checks
+// the state, redeems the code, verifies the ID token and starts a session
+// for the matching user, creating one if auto_provision is set.
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    oc := s.cfgMgr.Get().OIDC
+    if !oidcEnabled(oc) {
+        http.NotFound(w, r)
+        return
+    }
+    q := r.URL.Query()
+    cookie, err := r.Cookie("oidc_state")
+    if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(q.Get("state"))) != 1 {
+        http.Error(w, "login was started in another browser, please start again", http.StatusBadRequest)
+        return
+    }
+    http.SetCookie(w, &http.Cookie{Name: "oidc_state", Path: "/api/oidc/", MaxAge: -1, Secure: s.secureRequest(r), HttpOnly: true})
+    pending, ok := s.oidc.take(q.Get("state"))
+    if !ok {
+        http.Error(w, "unknown or expired login, please start again", http.StatusBadRequest)
+        return
+    }
+    if e := q.Get("error"); e != "" {
+        s.logger.Log("single sign-on refused by provider: %s %s", e, q.Get("error_description"))
+        http.Error(w, "single sign-on was refused", http.StatusUnauthorized)
+        return
+    }
+    id, err := s.completeOIDCLogin(*oc, q.Get("code"), pending)
+    if err != nil {
+        s.logger.Log("single sign-on failed: %v", err)
+        s.recordFailedLogin(r, id.username)
+        http.Error(w, "single sign-on failed", http.StatusUnauthorized)
+        return
+    }
+    before, user, err := s.oidcUser(*oc, id)
+    if err != nil {
+        s.logger.Log("single sign-on as %s (subject %s) refused: %v", id.username, id.subject, err)
+        s.recordFailedLogin(r, id.username)
+        http.Error(w, "single sign-on failed", http.StatusUnauthorized)
+        return
+    }
+    s.auditProvisioned(r, "oidc", before, user)
+    if err := s.startSession(w, r, user.Username); err != nil {
+        sessionError(w, err)
+        return
+    }
+    s.recordLogin(r, user.Username, LoginViaOIDC)
+    s.logger.Log("login %s (single sign-on)", user.Username)
+    http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// oidcIdentity is who a verified ID token says logged in.
+type oidcIdentity struct {
+    issuer   string
+    subject  string
+    username string
+    role     string
+}
+
+// completeOIDCLogin redeems code and verifies the ID token, returning the
+// identity it asserts.  The username is returned with the error once the
+// token has been verified.
+func (s *Server) completeOIDCLogin(oc OIDCConfig, code string, pending oidcPending) (oidcIdentity, error) {
+    if code == "" {
+        return oidcIdentity{}, errors.New("no authorisation code")
+    }
+    d, err := s.oidc.metadata(oc.Issuer)
+    if err != nil {
+        return oidcIdentity{}, err
+    }
+    idToken, err := exchangeCode(oc, d, code, pending.verifier)
+    if err != nil {
+        return oidcIdentity{}, err
+    }
+    payload, err := verifyJWS(idToken, func(kid string) (jsonWebKey, error) { return s.oidc.key(d, kid) })
+    if err != nil {
+        return oidcIdentity{}, err
+    }
+    claims, err := checkIDTokenClaims(payload, d.Issuer, oc.ClientID, pending.nonce, time.Now())
+    if err != nil {
+        return oidcIdentity{}, err
+    }
+    return oidcIdentityOf(oc, d.Issuer, claims)
+}
+
+// oidcIdentityOf returns the identity asserted by the verified claims of
+// an ID token from issuer.
+func oidcIdentityOf(oc OIDCConfig, issuer string, claims map[string]any) (oidcIdentity, error) {
+    claim := oc.UsernameClaim
+    if claim == "" {
+        claim = "preferred_username"
+    }
+    id := oidcIdentity{issuer: issuer, role: oidcRole(oc, claims)}
+    id.subject, _ = claims["sub"].(string)
+    id.username, _ = claims[claim].(string)
+    if id.subject == "" {
+        return id, errors.New("ID token has no sub claim")
+    }
+    if id.username == "" {
+        return id, fmt.Errorf("ID token has no %s claim", claim)
+    }
+    return id, nil
+}
+
+// oidcBound reports whether u is bound to the provider account id.  A
+// binding without an issuer is to the configured issuer.
+func oidcBound(oc OIDCConfig, u User, id oidcIdentity) bool {
+    issuer := u.OIDCIssuer
+    if issuer == "" {
+        issuer = oc.Issuer
+    }
+    return u.OIDCSubject == id.subject && strings.TrimSuffix(issuer, "/") == strings.TrimSuffix(id.issuer, "/")
+}
+
+// oidcUser returns the user a single sign-on login maps to: the one bound
+// to the provider account by its subject and issuer, whatever it is
+// called now.  An existing user of the same name is never taken over
+// implicitly, as the provider may let people choose their own usernames;
+// an admin must bind it by setting its oidc_subject.  The exception is a
+// user created by single sign-on before bindings were recorded, which is
+// bound at its next login.  Users created by single sign-on have their
+// role refreshed from the claims; other users keep theirs.  Unknown
+// accounts are given a new user if auto_provision is set and the claims
+// grant a role.  The user is returned as it was before the login too, a
+// zero User if created.
+func (s *Server) oidcUser(oc OIDCConfig, id oidcIdentity) (User, User, error) {
+    var before, user User
+    err := s.cfgMgr.Update(func(c *Config) error {
+        i := -1
+        for j, u := range c.Users {
+            if u.OIDCSubject != "" && oidcBound(oc, u, id) {
+                i = j
+                break
+            }
+        }
+        if i < 0 {
+            for j, u := range c.Users {
+                if u.Username != id.username {
+                    continue
+                }
+                if u.OIDCSubject != "" || !u.SSO {
+                    return fmt.Errorf("user %s exists but is not bound to this account", u.Username)
+                }
+                c.Users[j].OIDCSubject, c.Users[j].OIDCIssuer = id.subject, id.issuer
+                s.logger.Log("user %s bound to single sign-on subject %s", u.Username, id.subject)
+                i = j
+                break
+            }
+        }
+        if i >= 0 {
+            before = c.Users[i]
+            if c.Users[i].Disabled {
+                return errors.New("account disabled")
+            }
+            if c.Users[i].SSO && id.role != "" {
+                c.Users[i].Role = id.role
+                c.Users[i].Admin = id.role == RoleAdmin
+            }
+            user = c.Users[i]
+            return nil
+        }
+        if !oc.AutoProvision {
+            return errors.New("no such user and auto_provision is off")
+        }
+        if id.role == "" {
+            return errors.New("claims grant no role")
+        }
+        user = User{Username: id.username, Role: id.role, Admin: id.role == RoleAdmin, SSO: true, OIDCSubject: id.subject, OIDCIssuer: id.issuer}
+        c.Users = append(c.Users, user)
+        s.logger.Log("create user %s with role %s by single sign-on", id.username, id.role)
+        return nil
+    })
+    return before, user, err
+}
@@ -0,0 +1,144 @@
+package main
+
+// Tests of single sign-on: the state and nonce checks that tie a callback
+// to the login that started it, and the binding of provider accounts to
+// users.
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+const testIssuer = "https://auth.example.org/"
+
+func oidcTestConfig() Config {
+    cfg := validTestConfig()
+    cfg.OIDC = &OIDCConfig{
+        Issuer:        testIssuer,
+        ClientID:      "minder",
+        RedirectURL:   "https://alarm.example.org/api/oidc/callback",
+        AutoProvision: true,
+        DefaultRole:   RoleViewer,
+    }
+    cfg.Users = []User{
+        {Username: "admin", PasswordHash: testHash("s3cret-pass"), Role: RoleAdmin},
+        {Username: "alice", PasswordHash: testHash("s3cret-pass"), Role: RoleOperator},
+        {Username: "bob", Role: RoleOperator, OIDCSubject: "sub-bob"},
+        {Username: "carol", Role: RoleViewer, SSO: true},
+    }
+    return cfg
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+    s, _ := newTestServer(t, oidcTestConfig())
+    state, _, err := s.oidc.begin()
+    if err != nil {
+        t.Fatal(err)
+    }
+    expired, _, _ := s.oidc.begin()
+    s.oidc.mu.Lock()
+    p := s.oidc.pending[expired]
+    p.expires = time.Now().Add(-time.Second)
+    s.oidc.pending[expired] = p
+    s.oidc.mu.Unlock()
+    callback := func(query, cookie string) int {
+        r := httptest.NewRequest("GET", "/api/oidc/callback?"+query, nil)
+        if cookie != "" {
+            r.AddCookie(&http.Cookie{Name: "oidc_state", Value: cookie})
+        }
+        w := httptest.NewRecorder()
+        s.handleOIDCCallback(w, r)
+        return w.Code
+    }
+    tests := []struct {
+        name          string
+        query, cookie string
+    }{
+        {"no cookie", "state=" + state + "&error=access_denied", ""},
+        {"cookie of another login", "state=" + state + "&error=access_denied", "other"},
+        {"unknown state", "state=forged&error=access_denied", "forged"},
+        {"expired", "state=" + expired + "&error=access_denied", expired},
+    }
+    for _, tt := range tests {
+        if code := callback(tt.query, tt.cookie); code != http.StatusBadRequest {
+            t.Errorf("%s: status %d, want 400", tt.name, code)
+        }
+    }
+    // The state is still pending after the refused callbacks, and is used
+    // up by the first that gets through.
+    if code := callback("state="+state+"&error=access_denied", state); code != http.StatusUnauthorized {
+        t.Errorf("matching state: status %d, want 401 from the provider's error", code)
+    }
+    if code := callback("state="+state+"&error=access_denied", state); code != http.StatusBadRequest {
+        t.Errorf("replayed state: status %d, want 400", code)
+    }
+}
+
+func TestIDTokenNonce(t *testing.T) {
+    now := time.Now()
+    payload := []byte(`{"iss": "` + testIssuer + `", "aud": "minder", "sub": "s", "exp": ` +
+        fmt.Sprint(now.Add(time.Minute).Unix()) + `, "nonce": "n-1"}`)
+    if _, err := checkIDTokenClaims(payload, testIssuer, "minder", "n-1", now); err != nil {
+        t.Errorf("matching nonce: %v", err)
+    }
+    for _, nonce := range []string{"n-2", ""} {
+        if _, err := checkIDTokenClaims(payload, testIssuer, "minder", nonce, now); err == nil {
+            t.Errorf("nonce %q of another login accepted", nonce)
+        }
+    }
+    noNonce := []byte(`{"iss": "` + testIssuer + `", "aud": "minder", "sub": "s", "exp": ` + fmt.Sprint(now.Add(time.Minute).Unix()) + `}`)
+    if _, err := checkIDTokenClaims(noNonce, testIssuer, "minder", "n-1", now); err == nil {
+        t.Error("token without a nonce accepted")
+    }
+}
+
+func TestOIDCUserBinding(t *testing.T) {
+    tests := []struct {
+        name     string
+        id       oidcIdentity
+        want     string // username logged in as, "" if refused
+        created  bool   // a user is created
+    }{
+        {"local user of the same name", oidcIdentity{issuer: testIssuer, subject: "sub-x", username: "alice"}, "", false},
+        {"bound user", oidcIdentity{issuer: testIssuer, subject: "sub-bob", username: "robert"}, "bob", false},
+        {"bound user's name, other subject", oidcIdentity{issuer: testIssuer, subject: "sub-y", username: "bob"}, "", false},
+        {"bound subject, other issuer", oidcIdentity{issuer: "https://evil.example.org/", subject: "sub-bob", username: "eve", role: RoleViewer}, "eve", true},
+        {"user created by single sign-on", oidcIdentity{issuer: testIssuer, subject: "sub-carol", username: "carol"}, "carol", false},
+        {"new account", oidcIdentity{issuer: testIssuer, subject: "sub-dave", username: "dave", role: RoleOperator}, "dave", true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := oidcTestConfig()
+            s, _ := newTestServer(t, cfg)
+            before, user, err := s.oidcUser(*cfg.OIDC, tt.id)
+            if tt.want == "" {
+                if err == nil {
+                    t.Fatalf("logged in as %s", user.Username)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if user.Username != tt.want || (before.Username == "") != tt.created {
+                t.Errorf("logged in as %q, before %q", user.Username, before.Username)
+            }
+            if !oidcBound(*cfg.OIDC, user, tt.id) {
+                t.Errorf("%s not bound to subject %s", user.Username, tt.id.subject)
+            }
+        })
+    }
+    // Once bound, carol is found by subject even if renamed at the
+    // provider.
+    cfg := oidcTestConfig()
+    s, _ := newTestServer(t, cfg)
+    if _, _, err := s.oidcUser(*cfg.OIDC, oidcIdentity{issuer: testIssuer, subject: "sub-carol", username: "carol"}); err != nil {
+        t.Fatal(err)
+    }
+    if _, user, err := s.oidcUser(*cfg.OIDC, oidcIdentity{issuer: testIssuer, subject: "sub-carol", username: "caz"}); err != nil || user.Username != "carol" {
+        t.Errorf("renamed account: user %q, %v", user.Username, err)
+    }
+}
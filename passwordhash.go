@@ -0,0 +1,176 @@
+package main
+
+// This file implements the configurable password hashing algorithms.
+// bcrypt hashes are stored in their usual "$2a$" form and argon2id hashes
+// in the PHC string format, "$argon2id$v=19$m=...,t=...,p=...$salt$hash",
+// so the algorithm and parameters of every stored hash are self-describing.
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "strings"
+    "sync/atomic"
+    "time"
+
+    "golang.org/x/crypto/argon2"
+    "golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms selectable in HashParams.
+const (
+    HashBcrypt   = "bcrypt"
+    HashArgon2id = "argon2id"
+)
+
+// argon2id defaults, the second recommended option of RFC 9106 with a
+// smaller memory cost suited to a Raspberry Pi.
+const (
+    defaultArgon2Memory      = 64 * 1024 // KiB
+    defaultArgon2Time        = 3
+    defaultArgon2Parallelism = 2
+    argon2SaltLen            = 16
+    argon2KeyLen             = 32
+)
+
+// passwordHashParams holds the parameters new hashes are made with.  It is
+// set from the configuration by setHashParams; until then bcrypt at its
+// default cost is used.
+var passwordHashParams atomic.Pointer[HashParams]
+
+// withHashDefaults returns p with unset fields given their defaults.
+func withHashDefaults(p *HashParams) HashParams {
+    var hp HashParams
+    if p != nil {
+        hp = *p
+    }
+    if hp.Algorithm == "" {
+        hp.Algorithm = HashBcrypt
+    }
+    if hp.Cost == 0 {
+        hp.Cost = bcrypt.DefaultCost
+    }
+    if hp.Memory == 0 {
+        hp.Memory = defaultArgon2Memory
+    }
+    if hp.Time == 0 {
+        hp.Time = defaultArgon2Time
+    }
+    if hp.Parallelism == 0 {
+        hp.Parallelism = defaultArgon2Parallelism
+    }
+    return hp
+}
+
+// validateHashParams checks the hash settings in config.json.
+func validateHashParams(p *HashParams) error {
+    hp := withHashDefaults(p)
+    switch hp.Algorithm {
+    case HashBcrypt:
+        if hp.Cost < bcrypt.MinCost || hp.Cost > bcrypt.MaxCost {
+            return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+        }
+    case HashArgon2id:
+        if hp.Memory < 8*hp.Parallelism || hp.Time < 1 || hp.Parallelism < 1 || hp.Parallelism > 255 {
+            return errors.New("argon2id needs memory of at least 8 KiB per thread, time of at least 1 and 1 to 255 threads")
+        }
+    default:
+        return fmt.Errorf("unknown password hash algorithm %q (expected bcrypt or argon2id)", hp.Algorithm)
+    }
+    return nil
+}
+
+// setHashParams selects the parameters used for new password hashes.
+func setHashParams(p *HashParams) {
+    hp := withHashDefaults(p)
+    passwordHashParams.Store(&hp)
+}
+
+// currentHashParams returns the parameters new hashes are made with.
+func currentHashParams() HashParams {
+    if hp := passwordHashParams.Load(); hp != nil {
+        return *hp
+    }
+    return withHashDefaults(nil)
+}
+
+// benchmarkHash reports how long hashing one password takes with the
+// current parameters, so that slow settings are noticed at startup.
+func benchmarkHash() (HashParams, time.Duration) {
+    start := time.Now()
+    _, _ = hashPassword("benchmark-password")
+    return currentHashParams(), time.Since(start)
+}
+
+// hashArgon2id hashes password with the given argon2id parameters.
+func hashArgon2id(password string, hp HashParams) (string, error) {
+    salt := make([]byte, argon2SaltLen)
+    if _, err := rand.Read(salt); err != nil {
+        return "", err
+    }
+    key := argon2.IDKey([]byte(password), salt, uint32(hp.Time), uint32(hp.Memory), uint8(hp.Parallelism), argon2KeyLen)
+    return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, hp.Memory, hp.Time, hp.Parallelism,
+        base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// parseArgon2id splits an argon2id PHC string into its parameters, salt
+// and key.
+func parseArgon2id(hash string) (HashParams, []byte, []byte, error) {
+    hp := HashParams{Algorithm: HashArgon2id}
+    parts := strings.Split(hash, "$")
+    if len(parts) != 6 || parts[1] != HashArgon2id {
+        return hp, nil, nil, errors.New("malformed argon2id hash")
+    }
+    var version int
+    if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+        return hp, nil, nil, errors.New("unsupported argon2 version")
+    }
+    if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hp.Memory, &hp.Time, &hp.Parallelism); err != nil {
+        return hp, nil, nil, errors.New("malformed argon2id parameters")
+    }
+    if hp.Time < 1 || hp.Parallelism < 1 || hp.Parallelism > 255 || hp.Memory < 1 {
+        return hp, nil, nil, errors.New("invalid argon2id parameters")
+    }
+    salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+    if err != nil {
+        return hp, nil, nil, errors.New("malformed argon2id salt")
+    }
+    key, err := base64.RawStdEncoding.DecodeString(parts[5])
+    if err != nil || len(key) == 0 {
+        return hp, nil, nil, errors.New("malformed argon2id key")
+    }
+    return hp, salt, key, nil
+}
+
+// checkArgon2id verifies password against an argon2id PHC string.
+func checkArgon2id(password, hash string) error {
+    hp, salt, key, err := parseArgon2id(hash)
+    if err != nil {
+        return err
+    }
+    got := argon2.IDKey([]byte(password), salt, uint32(hp.Time), uint32(hp.Memory), uint8(hp.Parallelism), uint32(len(key)))
+    if subtle.ConstantTimeCompare(got, key) != 1 {
+        return errors.New("password does not match")
+    }
+    return nil
+}
+
+// needsRehash reports whether hash was made with other than the current
+// algorithm and parameters.
+func needsRehash(hash string) bool {
+    hp := currentHashParams()
+    if strings.HasPrefix(hash, "$argon2id$") {
+        if hp.Algorithm != HashArgon2id {
+            return true
+        }
+        old, _, _, err := parseArgon2id(hash)
+        return err != nil || old.Memory != hp.Memory || old.Time != hp.Time || old.Parallelism != hp.Parallelism
+    }
+    if hp.Algorithm != HashBcrypt {
+        return true
+    }
+    cost, err := bcrypt.Cost([]byte(hash))
+    return err != nil || cost != hp.Cost
+}
@@ -0,0 +1,66 @@
+package main
+
+// This file implements per-user disarm PINs for a keypad.  PINs are hashed
+// like passwords.  A keypad may send the username with the PIN, in which
+// case only that user's PIN is checked; otherwise the PIN is resolved to
+// its owner by checking every user, so that the time taken does not
+// reveal whose PIN was entered.  Either way wrong PINs count towards the
+// PIN lockout of lockout.go, which bounds how many guesses, and bcrypt
+// comparisons, a client can make.  PINs need not be unique, as refusing
+// one already in use would tell the user someone else's PIN.
+
+import (
+    "errors"
+    "net/http"
+    "time"
+)
+
+var (
+    errInvalidPIN = errors.New("invalid PIN")
+    errPINLocked  = errors.New("too many wrong PINs, try again later")
+)
+
+// pinOwners returns the enabled operators and admins whose PIN is pin,
+// only username if given.  Every candidate's PIN is checked, whether or
+// not an earlier one matched.
+func (cm *ConfigManager) pinOwners(username, pin string) []User {
+    var found []User
+    for _, u := range cm.Get().Users {
+        if u.PINHash == "" || (username != "" && u.Username != username) {
+            continue
+        }
+        if checkPasswordHash(pin, u.PINHash) == nil && !u.Disabled && u.hasRole(RoleOperator) {
+            found = append(found, u)
+        }
+    }
+    return found
+}
+
+// checkNewPIN validates a PIN about to be set against the policy.
+func (s *Server) checkNewPIN(pin string) error {
+    return validatePIN(pin, s.cfgMgr.Get().PasswordPolicy)
+}
+
+// pinDisarm disarms on behalf of the keypad user with a PIN entered on it,
+// and the username entered with it if any.  The disarm is attributed to
+// the PIN's owner, or to the keypad if the PIN is shared by several users,
+// without naming them.  A wrong PIN is recorded as a failed login.
+func (s *Server) pinDisarm(r *http.Request, keypad User, username, pin string) error {
+    if s.lockouts.remaining(s.lockoutKey(lockoutPIN, r), time.Now()) > 0 {
+        return errPINLocked
+    }
+    owners := s.cfgMgr.pinOwners(username, pin)
+    switch len(owners) {
+    case 0:
+        s.recordFailedPIN(r)
+        return errInvalidPIN
+    case 1:
+        s.lockouts.clear(s.lockoutKey(lockoutPIN, r))
+        s.recordLogin(r, owners[0].Username, LoginViaPIN)
+        s.disarm(owners[0].Username + " (PIN)")
+    default:
+        s.lockouts.clear(s.lockoutKey(lockoutPIN, r))
+        s.disarm(keypad.Username + " (shared PIN)")
+    }
+    return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+// This file holds per-user preferences: which events a user is notified
+// of, quiet hours during which only critical events reach them, the page
+// the web UI opens on and the units temperatures are shown in.  Users
+// created before preferences existed have none stored and get the
+// defaults.
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// Temperature units.
+const (
+    UnitCelsius    = "celsius"
+    UnitFahrenheit = "fahrenheit"
+)
+
+// landingPages lists the pages of the web UI a user may open on.
+var landingPages = []string{"status", "zones", "armModes", "users", "logs", "test", "help"}
+
+// defaultPreferences are the preferences of a user who has set none:
+// notified of every event their alerts send, at any hour, opening on the
+// status page with temperatures in Celsius.
+var defaultPreferences = Preferences{LandingPage: "status", TemperatureUnit: UnitCelsius}
+
+// preferences returns u's preferences with defaults filled in.
+func (u User) preferences() Preferences {
+    p := defaultPreferences
+    if u.Preferences == nil {
+        return p
+    }
+    p.NotifyEvents = u.Preferences.NotifyEvents
+    p.QuietHours = u.Preferences.QuietHours
+    if u.Preferences.LandingPage != "" {
+        p.LandingPage = u.Preferences.LandingPage
+    }
+    if u.Preferences.TemperatureUnit != "" {
+        p.TemperatureUnit = u.Preferences.TemperatureUnit
+    }
+    return p
+}
+
+// parseClock parses a time of day such as "22:30" into minutes after
+// midnight.
+func parseClock(v string) (int, error) {
+    t, err := time.Parse("15:04", v)
+    if err != nil {
+        return 0, fmt.Errorf("invalid time of day %q (expected HH:MM)", v)
+    }
+    return t.Hour()*60 + t.Minute(), nil
+}
+
+// validatePreferences checks preferences sent by a user.
+func validatePreferences(p Preferences) error {
+    if err := validateAlertEvents(p.NotifyEvents); err != nil {
+        return fmt.Errorf("notify_events: %w", err)
+    }
+    if q := p.QuietHours; q != nil {
+        start, err := parseClock(q.Start)
+        if err != nil {
+            return fmt.Errorf("quiet_hours start: %w", err)
+        }
+        end, err := parseClock(q.End)
+        if err != nil {
+            return fmt.Errorf("quiet_hours end: %w", err)
+        }
+        if start == end {
+            return errors.New("quiet_hours start and end must differ")
+        }
+    }
+    if p.LandingPage != "" {
+        known := false
+        for _, l := range landingPages {
+            if p.LandingPage == l {
+                known = true
+            }
+        }
+        if !known {
+            return fmt.Errorf("unknown landing_page %q", p.LandingPage)
+        }
+    }
+    if p.TemperatureUnit != "" && p.TemperatureUnit != UnitCelsius && p.TemperatureUnit != UnitFahrenheit {
+        return errors.New("temperature_unit must be celsius or fahrenheit")
+    }
+    return nil
+}
+
+// quiet reports whether t falls within the quiet hours, which may span
+// midnight.
+func (q QuietHours) quiet(t time.Time) bool {
+    start, err1 := parseClock(q.Start)
+    end, err2 := parseClock(q.End)
+    if err1 != nil || err2 != nil {
+        return false
+    }
+    now := t.Hour()*60 + t.Minute()
+    if start < end {
+        return now >= start && now < end
+    }
+    return now >= start || now < end
+}
+
+// wants reports whether a user with preferences p should be notified of
+// event.  Outside quiet hours that is any event in NotifyEvents, or any
+// event at all if it is empty; during them only critical events get
+// through.
+func (p Preferences) wants(event AlertEvent) bool {
+    if len(p.NotifyEvents) > 0 {
+        match := false
+        for _, e := range alertEvents(AlertConfig{Events: p.NotifyEvents}) {
+            if e == event.Type {
+                match = true
+            }
+        }
+        if !match {
+            return false
+        }
+    }
+    if p.QuietHours != nil && p.QuietHours.quiet(event.Time) {
+        return eventSeverity(event.Type) == SeverityCritical
+    }
+    return true
+}
+
+// handleProfile returns the logged in user's preferences with GET
+// /api/profile and replaces them with PUT, e.g.
+// {"notify_events":["alarm"],"quiet_hours":{"start":"22:00","end":"07:00"},
+// "landing_page":"status","temperature_unit":"celsius"}.
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request, user User) {
+    switch r.Method {
+    case http.MethodGet:
+    case http.MethodPut:
+        if s.configReadOnly(w) {
+            return
+        }
+        var prefs Preferences
+        if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+            http.Error(w, "invalid JSON", http.StatusBadRequest)
+            return
+        }
+        if err := validatePreferences(prefs); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        err := s.cfgMgr.Update(func(c *Config) error {
+            for i := range c.Users {
+                if c.Users[i].Username == user.Username {
+                    c.Users[i].Preferences = &prefs
+                    return nil
+                }
+            }
+            return errors.New("not found")
+        })
+        if err != nil {
+            // API keys act as synthetic users without a record.
+            if err.Error() == "not found" {
+                http.Error(w, "no profile for this user", http.StatusNotFound)
+            } else {
+                http.Error(w, "internal error", http.StatusInternalServerError)
+            }
+            return
+        }
+        user.Preferences = &prefs
+    default:
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(user.preferences())
+}
@@ -0,0 +1,94 @@
+package main
+
+// This file authenticates requests by a header set by an authenticating
+// reverse proxy such as Authelia.  The header is believed only on requests
+// whose peer is a listed proxy; from anywhere else it is ignored, since any
+// client could send it.
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+)
+
+// defaultProxyAuthHeader is the header carrying the user name by default.
+const defaultProxyAuthHeader = "Remote-User"
+
+// validateProxyAuth checks the proxy_auth section of config.json.
+func validateProxyAuth(pa *ProxyAuthConfig) error {
+    if pa == nil {
+        return nil
+    }
+    if err := validateTrustedProxies(pa.Proxies); err != nil {
+        return fmt.Errorf("proxy_auth: %w", err)
+    }
+    if _, ok := roleRanks[pa.DefaultRole]; pa.DefaultRole != "" && !ok {
+        return fmt.Errorf("proxy_auth default_role: unknown role %q", pa.DefaultRole)
+    }
+    if pa.AutoProvision && pa.DefaultRole == "" {
+        return errors.New("proxy_auth auto_provision needs a default_role")
+    }
+    return nil
+}
+
+// warnProxyAuth warns, in the event log and on standard error, when header
+// authentication is enabled without any proxy to accept it from.
+func warnProxyAuth(pa *ProxyAuthConfig, logger *EventLogger) {
+    if pa == nil || !pa.Enabled || len(pa.Proxies) > 0 {
+        return
+    }
+    msg := "WARNING: proxy_auth is enabled but lists no proxies; the header will be ignored on every request"
+    logger.Warning("%s", msg)
+}
+
+// proxyUsername returns the user name the reverse proxy sent with r.  It
+// reports false unless proxy authentication is enabled, r comes directly
+// from a listed proxy and the header is set.
+func (s *Server) proxyUsername(r *http.Request) (string, bool) {
+    pa := s.cfgMgr.Get().ProxyAuth
+    if pa == nil || !pa.Enabled || len(pa.Proxies) == 0 || !trustedProxy(remoteHost(r), pa.Proxies) {
+        return "", false
+    }
+    header := pa.Header
+    if header == "" {
+        header = defaultProxyAuthHeader
+    }
+    name := strings.TrimSpace(r.Header.Get(header))
+    return name, name != ""
+}
+
+// proxyUser returns the user a reverse proxy named, creating them with the
+// default role if auto_provision is set.  Users created are audited as the
+// request r.
+func (s *Server) proxyUser(r *http.Request, username string) (User, error) {
+    if user, _ := s.cfgMgr.FindUser(username); user.Username != "" {
+        if user.Disabled {
+            return User{}, errors.New("account disabled")
+        }
+        return user, nil
+    }
+    var user User
+    created := false
+    err := s.cfgMgr.Update(func(c *Config) error {
+        pa := c.ProxyAuth
+        if pa == nil || !pa.AutoProvision {
+            return errors.New("no such user and auto_provision is off")
+        }
+        for _, u := range c.Users {
+            if u.Username == username {
+                user = u
+                return nil
+            }
+        }
+        user = User{Username: username, Role: pa.DefaultRole, Admin: pa.DefaultRole == RoleAdmin, Proxy: true}
+        c.Users = append(c.Users, user)
+        created = true
+        s.logger.Log("create user %s with role %s by proxy authentication", username, pa.DefaultRole)
+        return nil
+    })
+    if created && err == nil {
+        s.auditProvisioned(r, "proxy_auth", User{}, user)
+    }
+    return user, err
+}
@@ -0,0 +1,29 @@
+package main
+
+// This file implements read_only_config, for systems whose configuration
+// is managed by a tool such as Ansible, so that the API is not a second
+// writer to config.json.  While it is set the endpoints that change zones,
+// users, arm modes, alerts, API keys and settings, or import a
+// configuration, answer 403, and nothing is saved.  Runtime operations
+// still work: arming, disarming, acknowledging and bypassing zones, as do
+// the details kept by logins such as last_login, but these changes are
+// held in memory only and the file wins at the next reload or restart.
+// So does a forced password change, without which the user could do
+// nothing else.
+// The flag can only be set in the file or with MINDER_READ_ONLY_CONFIG.
+
+import "net/http"
+
+// errReadOnlyConfig is the message of a change refused by
+// read_only_config.
+const errReadOnlyConfig = "the configuration is read only (read_only_config): change it in the configuration file instead"
+
+// configReadOnly refuses a change to the configuration with 403 and
+// returns true if read_only_config is set.
+func (s *Server) configReadOnly(w http.ResponseWriter) bool {
+    if !s.cfgMgr.Get().ReadOnlyConfig {
+        return false
+    }
+    http.Error(w, errReadOnlyConfig, http.StatusForbidden)
+    return true
+}
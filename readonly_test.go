@@ -0,0 +1,37 @@
+package main
+
+// Tests of read_only_config: the password change it still allows.
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestForcedPasswordChangeReadOnly(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.ReadOnlyConfig = true
+    cfg.Users = []User{
+        {Username: "admin", PasswordHash: testHash("Initial-pass-1"), Role: RoleAdmin, MustChangePassword: true},
+        {Username: "alice", PasswordHash: testHash("Initial-pass-1"), Role: RoleOperator},
+    }
+    s, _ := newTestServer(t, cfg)
+    change := func(username string) int {
+        user, _ := s.cfgMgr.FindUser(username)
+        w := httptest.NewRecorder()
+        body := `{"current_password": "Initial-pass-1", "new_password": "Replaced-pass-2"}`
+        s.handlePassword(w, httptest.NewRequest("POST", "/api/password", strings.NewReader(body)), user)
+        return w.Code
+    }
+    if code := change("alice"); code != http.StatusForbidden {
+        t.Errorf("voluntary change: status %d, want 403", code)
+    }
+    if code := change("admin"); code != http.StatusNoContent {
+        t.Fatalf("forced change: status %d, want 204", code)
+    }
+    admin, _ := s.cfgMgr.FindUser("admin")
+    if admin.MustChangePassword || checkPasswordHash("Replaced-pass-2", admin.PasswordHash) != nil {
+        t.Error("forced change not made in memory")
+    }
+}
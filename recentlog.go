@@ -0,0 +1,109 @@
+package main
+
+// This file keeps the most recent event log entries in memory, so that the
+// dashboard can show recent activity without reading the log from disk.
+
+import (
+    "encoding/json"
+    "io"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// recentLogSize is how many entries recentLog keeps.
+const recentLogSize = 200
+
+// recentLog is a ring buffer of the latest log lines.  It is safe for
+// concurrent use.
+type recentLog struct {
+    mu    sync.RWMutex
+    lines [recentLogSize]string
+    next  int // where the next line goes
+    count int
+}
+
+// add appends a line, overwriting the oldest once the buffer is full.
+func (rl *recentLog) add(line string) {
+    rl.mu.Lock()
+    defer rl.mu.Unlock()
+    rl.lines[rl.next] = line
+    rl.next = (rl.next + 1) % recentLogSize
+    if rl.count < recentLogSize {
+        rl.count++
+    }
+}
+
+// latest returns up to limit lines for which keep is true, newest first.
+func (rl *recentLog) latest(limit int, keep func(string) bool) []string {
+    rl.mu.RLock()
+    defer rl.mu.RUnlock()
+    out := []string{}
+    for i := 1; i <= rl.count && len(out) < limit; i++ {
+        line := rl.lines[(rl.next-i+recentLogSize)%recentLogSize]
+        if keep(line) {
+            out = append(out, line)
+        }
+    }
+    return out
+}
+
+// fill loads the last lines of the log at path, so that a restart does not
+// empty the buffer.
+func (rl *recentLog) fill(path string) {
+    f, err := os.Open(path)
+    if err != nil {
+        return
+    }
+    defer f.Close()
+    info, err := f.Stat()
+    if err != nil {
+        return
+    }
+    var lines []string
+    rr := newReverseLineReader(f, info.Size())
+    for len(lines) < recentLogSize {
+        b, _, err := rr.next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return
+        }
+        if len(b) > 0 {
+            lines = append(lines, string(b))
+        }
+    }
+    for i := len(lines) - 1; i >= 0; i-- {
+        rl.add(lines[i])
+    }
+}
+
+// handleLogsRecent returns the latest log entries with GET
+// /api/logs/recent, newest first, from memory.  type selects entries of
+// one type and lines limits how many are returned (at most 200).  As with
+// /api/logs, users other than admins only see their own actions.
+func (s *Server) handleLogsRecent(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    limit := recentLogSize
+    if n, err := strconv.Atoi(r.URL.Query().Get("lines")); err == nil && n > 0 && n < limit {
+        limit = n
+    }
+    eventType := strings.ToLower(r.URL.Query().Get("type"))
+    lines := s.logger.Recent(limit, func(line string) bool {
+        if eventType != "" {
+            e, ok := parseLogLine(line)
+            if !ok || logEventType(e.Message) != eventType {
+                return false
+            }
+        }
+        return user.hasRole(RoleAdmin) || mentionsUser(line, user.Username)
+    })
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(localLogLines(lines))
+}
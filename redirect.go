@@ -0,0 +1,94 @@
+package main
+
+// This file listens for plain HTTP on redirect_port, usually 80, so that
+// typing the Pi's bare address into a browser reaches the web UI instead
+// of nothing.  The listener is off unless redirect_port is set.  It
+// listens on each host of listen, or every interface.  Every request is
+// redirected with 308 to the same path over HTTPS; the only other thing
+// served is the ACME http-01 challenge when acme is set.  No API handler
+// is ever reachable over plain HTTP.
+
+import (
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+)
+
+// redirectPort returns the port of cfg's redirect listener, or 0 if it is
+// disabled.  -1, which disabled it when it was on by default, still does.
+func redirectPort(cfg Config) int {
+    if cfg.RedirectPort < 0 {
+        return 0
+    }
+    return cfg.RedirectPort
+}
+
+// startRedirect starts the redirect listeners of cfg, if enabled, one on
+// each host the server listens on.  Failing to listen is logged and the
+// server carries on, unless the listener is needed for the ACME http-01
+// challenge.
+func (s *Server) startRedirect(cfg Config) error {
+    port := redirectPort(cfg)
+    if port == 0 {
+        return nil
+    }
+    var handler http.Handler = httpsRedirect(cfg)
+    if s.acme != nil {
+        handler = s.acme.manager.HTTPHandler(handler)
+    }
+    for _, host := range listenHosts(cfg) {
+        srv := newHTTPServer(net.JoinHostPort(host, strconv.Itoa(port)), handler, nil, cfg.HTTPLimits)
+        if !s.addHTTPServer(srv) {
+            return nil
+        }
+        ln, err := listenLimited(srv.Addr, cfg.HTTPLimits)
+        if err != nil {
+            if cfg.ACME != nil && acmeChallenge(*cfg.ACME) == ACMEChallengeHTTP {
+                return fmt.Errorf("unable to listen on %s for ACME http-01 challenges: %w", srv.Addr, err)
+            }
+            s.logger.Warning("unable to redirect HTTP to HTTPS on %s: %v", srv.Addr, err)
+            continue
+        }
+        go func() {
+            if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+                s.logger.Warning("HTTP redirect listener on %s stopped: %v", srv.Addr, err)
+            }
+        }()
+        s.logger.Print(LogInfo, "Redirecting http://%s to HTTPS", ln.Addr())
+    }
+    return nil
+}
+
+// httpsRedirect returns the handler redirecting every request to the same
+// path and query on the HTTPS server: at base_url if it names the host
+// asked for, so that a forwarded port is kept, otherwise on http_port of
+// that host, or the port of the first listen entry.
+func httpsRedirect(cfg Config) http.HandlerFunc {
+    base, _ := url.Parse(cfg.BaseURL)
+    port := serverPort(cfg)
+    return func(w http.ResponseWriter, r *http.Request) {
+        host, _, err := net.SplitHostPort(r.Host)
+        if err != nil {
+            host = r.Host
+        }
+        host = strings.Trim(host, "[]")
+        if host == "" {
+            http.Error(w, "missing Host header", http.StatusBadRequest)
+            return
+        }
+        hostport := net.JoinHostPort(host, strconv.Itoa(port))
+        switch {
+        case base != nil && base.Scheme == "https" && strings.EqualFold(base.Hostname(), host):
+            hostport = base.Host
+        case port == 443 && strings.Contains(host, ":"):
+            hostport = "[" + host + "]"
+        case port == 443:
+            hostport = host
+        }
+        http.Redirect(w, r, "https://"+hostport+r.URL.RequestURI(), http.StatusPermanentRedirect)
+    }
+}
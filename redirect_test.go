@@ -0,0 +1,55 @@
+package main
+
+// Tests of the HTTP to HTTPS redirect listener: that it is opt-in, and
+// where it redirects to.
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestRedirectOptIn(t *testing.T) {
+    tests := []struct {
+        port, want int
+    }{
+        {0, 0},
+        {-1, 0},
+        {80, 80},
+        {8080, 8080},
+    }
+    for _, tt := range tests {
+        if got := redirectPort(Config{RedirectPort: tt.port}); got != tt.want {
+            t.Errorf("redirect_port %d: listener on %d, want %d", tt.port, got, tt.want)
+        }
+    }
+    cfg := validTestConfig()
+    cfg.ACME = &ACMEConfig{Domains: []string{"alarm.example.org"}, Challenge: ACMEChallengeHTTP}
+    if validateACMEConfig(cfg.ACME, redirectPort(cfg)) == nil {
+        t.Error("http-01 challenge accepted without redirect_port")
+    }
+    cfg.RedirectPort = 80
+    if err := validateACMEConfig(cfg.ACME, redirectPort(cfg)); err != nil {
+        t.Errorf("http-01 challenge with redirect_port 80: %v", err)
+    }
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+    tests := []struct {
+        cfg  Config
+        url  string
+        want string
+    }{
+        {Config{HTTPPort: 8443}, "http://192.168.1.10/zones?id=3", "https://192.168.1.10:8443/zones?id=3"},
+        {Config{HTTPPort: 443}, "http://alarm.lan/", "https://alarm.lan/"},
+        {Config{HTTPPort: 8443, BaseURL: "https://alarm.example.org:4443"}, "http://alarm.example.org/", "https://alarm.example.org:4443/"},
+        {Config{Listen: []string{"192.168.1.10:9443"}}, "http://192.168.1.10/", "https://192.168.1.10:9443/"},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        httpsRedirect(tt.cfg)(w, httptest.NewRequest("GET", tt.url, nil))
+        if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != tt.want {
+            t.Errorf("%s: %d to %q, want 308 to %q", tt.url, w.Code, w.Header().Get("Location"), tt.want)
+        }
+    }
+}
@@ -0,0 +1,143 @@
+package main
+
+// This file reloads config.json on SIGHUP, so that hand edits take effect
+// without restarting, and so disarming, the system.  A file with errors is
+// rejected as a whole and the running configuration kept.  Otherwise the
+// sections that changed are applied at once: zones, arm modes, delays and
+// most other settings are read afresh whenever they are used, and the
+// alert handlers, logging, time zone and password hashing are rebuilt.
+// The few settings only read at startup are reported as pending restart.
+
+import (
+    "encoding/json"
+    "sort"
+    "strings"
+)
+
+// restartSections are the sections of config.json that take effect only
+// after a restart.
+var restartSections = map[string]bool{
+    "http_port":        true,
+    "listen":           true,
+    "unix_socket":      true,
+    "cert_file":        true,
+    "key_file":         true,
+    "client_certs":     true,
+    "http_limits":      true,
+    "acme":             true,
+    "redirect_port":    true,
+    "listen_mode":      true,
+    "insecure_bind":    true,
+    "log_file":         true,
+    "mqtt":             true,
+    "persist_sessions": true,
+}
+
+// configSections returns the names of the top level sections of
+// config.json that differ between a and b, sorted.
+func configSections(a, b Config) []string {
+    var am, bm map[string]json.RawMessage
+    for _, c := range []struct {
+        cfg Config
+        m   *map[string]json.RawMessage
+    }{{a, &am}, {b, &bm}} {
+        data, err := json.Marshal(c.cfg)
+        if err == nil {
+            err = json.Unmarshal(data, c.m)
+        }
+        if err != nil {
+            return nil
+        }
+    }
+    var changed []string
+    for key, av := range am {
+        if bv, ok := bm[key]; !ok || string(av) != string(bv) {
+            changed = append(changed, key)
+        }
+    }
+    for key := range bm {
+        if _, ok := am[key]; !ok {
+            changed = append(changed, key)
+        }
+    }
+    sort.Strings(changed)
+    return changed
+}
+
+// Reload reads config.json again and applies the changes, logging which
+// sections changed.  An invalid file is rejected and logged.
+func (s *Server) Reload() {
+    old, err := s.cfgMgr.Reload()
+    if err != nil {
+        s.logger.Warning("config reload rejected, running configuration kept: %v", err)
+        return
+    }
+    s.applyConfig(old, "reloaded")
+}
+
+// applyConfig puts into effect the changes from old to the current
+// configuration, which was how ("reloaded" or "imported"), logging which
+// sections changed and which wait for a restart.
+func (s *Server) applyConfig(old Config, how string) {
+    cfg := s.cfgMgr.Get()
+    changed := configSections(old, cfg)
+    if len(changed) == 0 {
+        s.logger.Log("config %s: no changes", how)
+        return
+    }
+    var applied, pending []string
+    sections := make(map[string]bool)
+    for _, key := range changed {
+        sections[key] = true
+        if restartSections[key] {
+            pending = append(pending, key)
+        } else {
+            applied = append(applied, key)
+        }
+    }
+    if sections["log_rotation"] {
+        s.logger.SetRotation(cfg.LogRotation)
+    }
+    if sections["log_level"] {
+        s.logger.SetLevel(cfg.LogLevel)
+    }
+    if sections["log_rotation"] || sections["log_sinks"] {
+        sinks := append([]LogSinkConfig(nil), cfg.LogSinks...)
+        for i := range sinks {
+            sinks[i].Path = s.cfgMgr.resolve(sinks[i].Path)
+        }
+        s.logger.SetSinks(sinks)
+    }
+    if sections["timezone"] {
+        setTimezone(cfg.Timezone)
+    }
+    if sections["hash_params"] {
+        setHashParams(cfg.HashParams)
+    }
+    if sections["proxy_auth"] {
+        warnProxyAuth(cfg.ProxyAuth, s.logger)
+    }
+    if sections["alert_rate_limit"] {
+        s.alertQueue.setRateLimit(cfg.AlertRateLimit)
+    }
+    if sections["users"] {
+        // Users removed or disabled in the file are logged out, as they
+        // are when deleted or disabled through the API.
+        for _, u := range old.Users {
+            if cur, ok := findUser(cfg.Users, u.Username); !u.Disabled && (!ok || cur.Disabled) {
+                n, d := s.endUserSessions(u.Username)
+                s.logger.Log("config %s: user %s removed or disabled, %d sessions ended and %d devices forgotten", how, u.Username, n, d)
+            }
+        }
+    }
+    if len(applied) > 0 {
+        s.logger.Log("config %s: %s changed", how, strings.Join(applied, ", "))
+    }
+    if len(pending) > 0 {
+        s.logger.Warning("config %s: %s changed, pending restart", how, strings.Join(pending, ", "))
+    }
+    for _, p := range cfg.Validate() {
+        s.logger.Warning("config warning: %s", p)
+    }
+    s.publishLive("config", Zone{}, "")
+}
@@ -0,0 +1,114 @@
+package main
+
+// This file enforces the retention period of the event log.  Once a day,
+// and on demand through the API, rotated logs whose entries are all older
+// than retention_days are deleted.  The active log is never touched;
+// log_rotation keeps it to a day or a few megabytes.
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// retentionInterval is how often the retention period is enforced.
+const retentionInterval = 24 * time.Hour
+
+// Prune deletes the rotated logs holding only entries from before the day
+// of cutoff and returns their names.  It waits for any compression of
+// rotated logs to finish, and does not block logging.
+func (el *EventLogger) Prune(cutoff time.Time) ([]string, error) {
+    el.tidying.Lock()
+    defer el.tidying.Unlock()
+    day := cutoff.Format("20060102")
+    removed := []string{}
+    for _, name := range rotatedLogs(el.filePath) {
+        // A log rotated on a day holds no entries after that day.
+        if logDay(name, el.filePath) >= day {
+            continue
+        }
+        if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+            return removed, err
+        }
+        removed = append(removed, filepath.Base(name))
+    }
+    return removed, nil
+}
+
+// validateLogRetention checks the log_rotation and retention_days
+// settings.
+func validateLogRetention(cfg Config) error {
+    if lr := cfg.LogRotation; lr != nil && (lr.MaxSizeMB < 0 || lr.Keep < 0) {
+        return errors.New("log_rotation max_size_mb and keep must not be negative")
+    }
+    return nil
+}
+
+// retentionCutoff returns the time before which log entries are deleted,
+// or the zero time if there is no retention period.
+func retentionCutoff(cfg Config) time.Time {
+    if cfg.RetentionDays <= 0 {
+        return time.Time{}
+    }
+    return time.Now().AddDate(0, 0, -cfg.RetentionDays)
+}
+
+// pruneLogs enforces the retention period and logs what it deleted.
+func (s *Server) pruneLogs(cutoff time.Time) ([]string, error) {
+    removed, err := s.logger.Prune(cutoff)
+    if len(removed) > 0 {
+        s.logger.Log("retention: pruned %d rotated logs from before %s: %s", len(removed), cutoff.Format("2006-01-02"), strings.Join(removed, ", "))
+    }
+    if err != nil {
+        s.logger.Log("retention: unable to prune logs: %v", err)
+    }
+    return removed, err
+}
+
+// retentionLoop enforces the retention period at startup and then daily
+// until the server stops.
+func (s *Server) retentionLoop() {
+    ticker := time.NewTicker(retentionInterval)
+    defer ticker.Stop()
+    for {
+        if cutoff := retentionCutoff(s.cfgMgr.Get()); !cutoff.IsZero() {
+            _, _ = s.pruneLogs(cutoff)
+        }
+        select {
+        case <-ticker.C:
+        case <-s.stop:
+            return
+        }
+    }
+}
+
+// handleLogsPrune enforces the retention period now with POST
+// /api/logs/prune and returns the cutoff and the logs deleted.  Admins
+// only.
+func (s *Server) handleLogsPrune(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    cutoff := retentionCutoff(s.cfgMgr.Get())
+    if cutoff.IsZero() {
+        http.Error(w, "retention_days is not set", http.StatusBadRequest)
+        return
+    }
+    removed, err := s.pruneLogs(cutoff)
+    if err != nil {
+        http.Error(w, "internal error", http.StatusInternalServerError)
+        return
+    }
+    s.audit(r, user.Username, "logs.prune", "", nil, map[string]any{"cutoff": cutoff.Format("2006-01-02"), "removed": removed})
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(map[string]any{"cutoff": cutoff.Format("2006-01-02"), "removed": removed})
+}
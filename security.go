@@ -0,0 +1,215 @@
+package main
+
+// This file raises security events: failed logins and administrative
+// changes, delivered to alerts subscribed to the "security" class.
+
+import (
+    "encoding/json"
+    "fmt"
+    "net"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// loginFailureWindow is the period over which failed logins from one
+// address are coalesced into a single notification.
+const loginFailureWindow = time.Minute
+
+// maxRecentLoginFailures is the number of failed logins kept in memory for
+// /api/security/failures.
+const maxRecentLoginFailures = 200
+
+// LoginFailure is a failed login as returned by /api/security/failures.
+type LoginFailure struct {
+    Time     time.Time `json:"time"`
+    Username string    `json:"username"`
+    IP       string    `json:"ip"`
+}
+
+// loginFailureBurst counts the failed logins from one address within the
+// current window.
+type loginFailureBurst struct {
+    count     int
+    usernames map[string]bool
+}
+
+// failedLogins holds the open bursts, keyed by client address, and the
+// most recent failures, oldest first.
+type failedLogins struct {
+    mu     sync.Mutex
+    bursts map[string]*loginFailureBurst
+    recent []LoginFailure
+}
+
+// remoteHost returns the address of the peer that sent r, without the
+// port, or "unix" for a Unix domain socket.
+func remoteHost(r *http.Request) string {
+    if viaUnixSocket(r) {
+        return "unix"
+    }
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
+
+// fromTrustedProxy reports whether r was sent by one of proxies, or over a
+// Unix domain socket, whose peer is a local proxy allowed in by the
+// socket's permissions.
+func fromTrustedProxy(r *http.Request, proxies []string) bool {
+    return viaUnixSocket(r) || trustedProxy(remoteHost(r), proxies)
+}
+
+// trustedProxy reports whether ip matches an entry of proxies.
+func trustedProxy(ip string, proxies []string) bool {
+    addr := net.ParseIP(ip)
+    if addr == nil {
+        return false
+    }
+    for _, p := range proxies {
+        if _, network, err := net.ParseCIDR(p); err == nil {
+            if network.Contains(addr) {
+                return true
+            }
+        } else if other := net.ParseIP(p); other != nil && other.Equal(addr) {
+            return true
+        }
+    }
+    return false
+}
+
+// clientIP returns the address of the client that sent r.  When the
+// request comes from a trusted proxy the X-Forwarded-For header is followed
+// back to the first address that is not itself a trusted proxy.
+func (s *Server) clientIP(r *http.Request) string {
+    ip := remoteHost(r)
+    proxies := s.cfgMgr.Get().TrustedProxies
+    if !fromTrustedProxy(r, proxies) {
+        return ip
+    }
+    hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+    for i := len(hops) - 1; i >= 0; i-- {
+        hop := strings.TrimSpace(hops[i])
+        if net.ParseIP(hop) == nil {
+            break
+        }
+        ip = hop
+        if !trustedProxy(hop, proxies) {
+            break
+        }
+    }
+    return ip
+}
+
+// validateTrustedProxies checks that every trusted proxy is an IP address
+// or CIDR range.
+func validateTrustedProxies(proxies []string) error {
+    for _, p := range proxies {
+        if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+            return fmt.Errorf("trusted proxy %q is not an IP address or CIDR range", p)
+        }
+    }
+    return nil
+}
+
+// recordFailedLogin logs a failed login and counts it towards the burst of
+// its address.  The first failure opens a window; when it closes a single
+// event reports every failure seen within it.  It also counts towards the
+// address's lockout, see lockout.go.
+func (s *Server) recordFailedLogin(r *http.Request, username string) {
+    s.recordFailure(r, username, lockoutLogin)
+}
+
+// recordFailedPIN is recordFailedLogin for a wrong keypad PIN, which counts
+// towards the PIN lockout instead.
+func (s *Server) recordFailedPIN(r *http.Request) {
+    s.recordFailure(r, "(PIN)", lockoutPIN)
+}
+
+// recordFailure records a failure of kind under username.
+func (s *Server) recordFailure(r *http.Request, username, kind string) {
+    s.countFailure(r, kind)
+    ip := s.clientIP(r)
+    s.logger.Log("failed login as %q from %s", username, ip)
+    s.failedLogins.mu.Lock()
+    defer s.failedLogins.mu.Unlock()
+    s.failedLogins.recent = append(s.failedLogins.recent, LoginFailure{Time: time.Now(), Username: username, IP: ip})
+    if n := len(s.failedLogins.recent); n > maxRecentLoginFailures {
+        s.failedLogins.recent = append([]LoginFailure(nil), s.failedLogins.recent[n-maxRecentLoginFailures:]...)
+    }
+    b := s.failedLogins.bursts[ip]
+    if b == nil {
+        b = &loginFailureBurst{usernames: make(map[string]bool)}
+        s.failedLogins.bursts[ip] = b
+        time.AfterFunc(loginFailureWindow, func() {
+            s.reportFailedLogins(ip)
+        })
+    }
+    b.count++
+    b.usernames[username] = true
+}
+
+// reportFailedLogins closes the window of ip and sends its failures as one
+// login_failed event.
+func (s *Server) reportFailedLogins(ip string) {
+    s.failedLogins.mu.Lock()
+    b := s.failedLogins.bursts[ip]
+    delete(s.failedLogins.bursts, ip)
+    s.failedLogins.mu.Unlock()
+    if b == nil {
+        return
+    }
+    var names, quoted []string
+    for name := range b.usernames {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        quoted = append(quoted, fmt.Sprintf("%q", name))
+    }
+    desc := fmt.Sprintf("failed login as %s from %s", quoted[0], ip)
+    if b.count > 1 {
+        desc = fmt.Sprintf("%d failed logins as %s from %s within a minute", b.count, strings.Join(quoted, ", "), ip)
+    }
+    event := s.newAlertEvent(EventLoginFailed, Zone{Name: desc}, strings.Join(names, ", "))
+    s.sendAlerts(event)
+}
+
+// handleSecurityFailures returns the most recent failed logins, newest
+// first, with GET /api/security/failures?limit=N (default 50).  Only
+// admins may see them.  The list is held in memory and starts empty when
+// Minder restarts; the event log keeps the full history.
+func (s *Server) handleSecurityFailures(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    limit := 50
+    if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+        limit = n
+    }
+    s.failedLogins.mu.Lock()
+    failures := []LoginFailure{}
+    for i := len(s.failedLogins.recent) - 1; i >= 0 && len(failures) < limit; i-- {
+        failures = append(failures, s.failedLogins.recent[i])
+    }
+    s.failedLogins.mu.Unlock()
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(failures)
+}
+
+// adminChange reports a change to users, zones or arm modes made by actor.
+// The change should already have been written to the event log.
+func (s *Server) adminChange(actor, format string, args ...any) {
+    event := s.newAlertEvent(EventAdminChange, Zone{Name: fmt.Sprintf(format, args...)}, actor)
+    s.sendAlerts(event)
+}
@@ -0,0 +1,150 @@
+package main
+
+// This file creates a self-signed TLS certificate on first run, so that a
+// fresh install serves HTTPS without the user having to learn openssl.
+// When neither cert_file nor key_file exists an ECDSA certificate valid for
+// ten years is written to them, for cert_hostname (default the machine's
+// host name) and the machine's addresses.  Browsers warn about it; the
+// SHA-256 fingerprint logged lets the user check that the certificate they
+// are shown is this one.  Run with -regenerate-cert to replace it, e.g.
+// after the host name changes.
+
+import (
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/pem"
+    "fmt"
+    "math/big"
+    "net"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// certValidity is the lifetime of a generated certificate.
+const certValidity = 10 * 365 * 24 * time.Hour
+
+// certHostname returns the host name of the certificate generated for
+// cfg.
+func certHostname(cfg Config) string {
+    if cfg.CertHostname != "" {
+        return cfg.CertHostname
+    }
+    if name, err := os.Hostname(); err == nil && name != "" {
+        return name
+    }
+    return "localhost"
+}
+
+// certificateMissing reports whether cfg's certificate is to be generated:
+// neither cert_file nor key_file exists.  If only one of them does, the
+// other has been lost and an error is returned rather than replace it.
+func (cm *ConfigManager) certificateMissing(cfg Config) (bool, error) {
+    certPath, keyPath := cm.resolve(cfg.CertFile), cm.resolve(cfg.KeyFile)
+    _, certErr := os.Stat(certPath)
+    _, keyErr := os.Stat(keyPath)
+    switch {
+    case os.IsNotExist(certErr) && os.IsNotExist(keyErr):
+        return true, nil
+    case os.IsNotExist(certErr):
+        return false, fmt.Errorf("%s is missing but %s exists; restore it or remove both to generate a new certificate", certPath, keyPath)
+    case os.IsNotExist(keyErr):
+        return false, fmt.Errorf("%s is missing but %s exists; restore it or remove both to generate a new certificate", keyPath, certPath)
+    }
+    return false, nil
+}
+
+// generateCertificate writes a new self-signed certificate and key to
+// cfg's cert_file and key_file, replacing any there, and returns the
+// certificate's SHA-256 fingerprint.
+func (cm *ConfigManager) generateCertificate(cfg Config) (string, error) {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        return "", err
+    }
+    serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+    if err != nil {
+        return "", err
+    }
+    hostname := certHostname(cfg)
+    now := time.Now()
+    tmpl := x509.Certificate{
+        SerialNumber:          serial,
+        Subject:               pkix.Name{CommonName: hostname, Organization: []string{"Minder"}},
+        NotBefore:             now.Add(-time.Hour),
+        NotAfter:              now.Add(certValidity),
+        KeyUsage:              x509.KeyUsageDigitalSignature,
+        ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+        BasicConstraintsValid: true,
+    }
+    tmpl.DNSNames, tmpl.IPAddresses = certNames(hostname)
+    der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+    if err != nil {
+        return "", err
+    }
+    keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+    if err != nil {
+        return "", err
+    }
+    certPath, keyPath := cm.resolve(cfg.CertFile), cm.resolve(cfg.KeyFile)
+    for _, f := range []struct {
+        path  string
+        block pem.Block
+    }{
+        {keyPath, pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}},
+        {certPath, pem.Block{Type: "CERTIFICATE", Bytes: der}},
+    } {
+        if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+            return "", err
+        }
+        if err := writeFileSync(f.path, pem.EncodeToMemory(&f.block)); err != nil {
+            return "", fmt.Errorf("unable to write %s: %w", f.path, err)
+        }
+    }
+    return certFingerprint(der), nil
+}
+
+// certNames returns the names and addresses a certificate for hostname
+// is valid for: hostname, with hostname.local for mDNS if it is a bare
+// name, localhost and the addresses of the machine's interfaces.
+func certNames(hostname string) ([]string, []net.IP) {
+    var names []string
+    var ips []net.IP
+    if ip := net.ParseIP(hostname); ip != nil {
+        ips = append(ips, ip)
+    } else {
+        names = append(names, hostname)
+        if !strings.Contains(hostname, ".") && hostname != "localhost" {
+            names = append(names, hostname+".local")
+        }
+    }
+    if hostname != "localhost" {
+        names = append(names, "localhost")
+    }
+    ips = append(ips, net.IPv4(127, 0, 0, 1), net.IPv6loopback)
+    addrs, _ := net.InterfaceAddrs()
+    for _, a := range addrs {
+        ipnet, ok := a.(*net.IPNet)
+        if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+            continue
+        }
+        ips = append(ips, ipnet.IP)
+    }
+    return names, ips
+}
+
+// certFingerprint returns the SHA-256 fingerprint of a DER certificate as
+// browsers show it, e.g. "3A:F1:...".
+func certFingerprint(der []byte) string {
+    sum := sha256.Sum256(der)
+    parts := make([]string, len(sum))
+    for i, b := range sum {
+        parts[i] = fmt.Sprintf("%02X", b)
+    }
+    return strings.Join(parts, ":")
+}
@@ -9,7 +9,12 @@ import "strings"
 // typically use resistive dividers to detect tamper; our stub treats them
 // like normally open sensors.  Any unrecognised mode defaults to NO semantics.
 func zoneTriggered(z Zone) bool {
-    state := readPin(z.Pin)
+    return pinTriggered(z, readPin(z.Pin))
+}
+
+// pinTriggered interprets state, a raw read of the pin of z, as
+// zoneTriggered does.
+func pinTriggered(z Zone, state bool) bool {
     switch strings.ToUpper(z.Mode) {
     case "NC":
         // Normally closed: low means triggered
@@ -23,4 +28,13 @@ func zoneTriggered(z Zone) bool {
     default:
         return state
     }
+}
+
+// sense reads whether z is triggered while polling, printing the raw pin
+// read at the debug level.
+func (s *Server) sense(z Zone) bool {
+    state := readPin(z.Pin)
+    triggered := pinTriggered(z, state)
+    s.logger.Debug("poll zone id=%d pin=%d mode=%s raw=%t triggered=%t", z.ID, z.Pin, z.Mode, state, triggered)
+    return triggered
 }
\ No newline at end of file
@@ -2,35 +2,42 @@ package main
 
 import (
     "crypto/tls"
+    "crypto/x509"
     "embed"
     "encoding/json"
     "errors"
     "fmt"
-    "log"
+    "io"
     "net/http"
     "strconv"
     "strings"
     "sync"
+    "sync/atomic"
     "time"
     "os"
     "io/fs"
-    "mime"
     "path/filepath"
 )
 
-//go:embed web/dist/* web/dist/assets/**
+// embeddedFiles holds the web UI.  The all: prefix includes files whose
+// names begin with _ or ., which Vite may give to chunks.
+//
+//go:embed all:web/dist
 var embeddedFiles embed.FS
 
 // Server holds global state for the HTTP server and the alarm logic.
 type Server struct {
     cfgMgr    *ConfigManager
     sessions  *SessionManager
+    devices   *DeviceManager
     currentMode string        // name of currently active arm mode ("Disarmed" if none)
     triggered map[int]bool    // zones that have been triggered since last arm
     logger    *EventLogger    // event logger
-    testMode  int             // 0 = normal, 1 = TestSoft, 2 = TestWiring
+    testMode  int             // 0 = normal, 1 = TestSoft, 2 = TestWiring, 3 = WalkTest
     alerts    []AlertHandler  // configured alert handlers
+    alertsMu  sync.Mutex      // guards alerts, which are rebuilt when they change
     triggerMu sync.Mutex      // guards concurrent access to triggered map
+    zones     atomic.Pointer[zoneIndex] // zones polled in each arm mode
 
     // pendingMode holds the arm mode that will become active once the exit
     // delay completes.  When non-empty, currentMode is set to "ExitDelay"
@@ -43,10 +50,72 @@ type Server struct {
     // alarm if not disarmed before entryDelayEnd.
     entryTimer   *time.Timer
     entryDelayEnd time.Time
+    entryZone    Zone // the entry/exit zone that started the entry delay
     // alarm indicates that the system has entered alarm state due to a
     // triggered sensor or expired entry delay.  When true, the status
     // endpoint should report an alarm condition to the UI.
     alarm     bool
+    // alarmMode is the arm mode the alarm was raised in, whose zones are
+    // still polled during the alarm, and alarmed the zones that have raised
+    // an alarm event since, guarded by triggerMu.
+    alarmMode string
+    alarmed   map[int]bool
+    // acked holds the zones acknowledged during the alarm that have not
+    // yet restored, see awaitingRestore; guarded by triggerMu.
+    acked     map[int]bool
+    // walkTest is non-nil while a walk test is in progress (testMode 3).
+    walkTest  *walkTest
+    // mqtt is the shared broker connection, or nil if MQTT is not configured.
+    mqtt      *MQTTClient
+    // alertQueue delivers events to the alert handlers and retries failed
+    // deliveries.
+    alertQueue *alertQueue
+    // escalations holds the active alarm escalation of each zone, guarded
+    // by escMu.
+    escalations map[int]*escalation
+    escMu       sync.Mutex
+    // alarms records alarm events until they are acknowledged.
+    alarms      *AlarmLog
+    // digests records the daily digests sent.
+    digests     digests
+    // heartbeats records the scheduled heartbeat results of each alert.
+    heartbeats  heartbeats
+    // failedLogins coalesces failed logins by client address.
+    failedLogins failedLogins
+    // lockouts counts failed logins and PINs towards lockouts.
+    lockouts     lockouts
+    // totp holds two-factor setups in progress and the codes used.
+    totp         totpState
+    // webauthn holds outstanding passkey ceremonies.
+    webauthn    webauthnChallenges
+    // lastLogins batches last-login updates to config.json.
+    lastLogins  lastLogins
+    // oidc holds single sign-on logins in progress and provider metadata.
+    oidc        oidcProvider
+    // acme serves certificates obtained by ACME, if configured.
+    acme        *acmeCertificates
+    // crl caches the revocation list for client certificates.
+    crl         crlCache
+    // clientCertMode is the client_certs mode the TLS listener was started
+    // with, and clientCAs the certificates of its CA file, which must also
+    // sign the CRL.  Changing client_certs needs a restart.
+    clientCertMode string
+    clientCAs      []*x509.Certificate
+    // live fans updates out to clients of /api/events.
+    live        eventHub
+    // auditTrail serialises writes to the audit trail.
+    auditTrail  auditTrail
+    // stop is closed by Shutdown to end background loops owned by the
+    // server, which are counted in loops (see shutdown.go).
+    stop        chan struct{}
+    stopOnce    sync.Once
+    loops       sync.WaitGroup
+    // httpServers are the HTTPS servers and the HTTP redirect listeners
+    // once Start has created them, and listening the addresses the former
+    // are bound to, guarded by httpMu.
+    httpServers []*http.Server
+    listening   []string
+    httpMu      sync.Mutex
 }
 
 // startExitDelay begins an exit delay when arming the system.  It sets
@@ -83,16 +152,18 @@ func (s *Server) completeExitDelay() {
     s.exitTimer = nil
     s.exitDelayEnd = time.Time{}
     s.logger.Log("exit delay complete, system armed")
+    s.publishLive("armed", Zone{}, "")
 }
 
-// startEntryDelay begins an entry delay when an entry/exit sensor is
-// triggered while armed.  If an entry delay is already active, this
-// function does nothing.  The timer will call triggerAlarm when it
-// expires.
-func (s *Server) startEntryDelay() {
+// startEntryDelay begins an entry delay when the entry/exit sensor of zone
+// is triggered while armed.  If an entry delay is already active, this
+// function does nothing.  The timer will mark zone triggered and call
+// triggerAlarm when it expires.
+func (s *Server) startEntryDelay(zone Zone) {
     if s.entryTimer != nil {
         return
     }
+    s.entryZone = zone
     cfg := s.cfgMgr.Get()
     delay := cfg.EntryDelay
     if delay <= 0 {
@@ -100,9 +171,13 @@ func (s *Server) startEntryDelay() {
     }
     s.entryDelayEnd = time.Now().Add(time.Duration(delay) * time.Second)
     s.entryTimer = time.AfterFunc(time.Duration(delay)*time.Second, func() {
+        s.triggerMu.Lock()
+        s.triggered[zone.ID] = true
+        s.triggerMu.Unlock()
         s.triggerAlarm("entry delay expired")
     })
     s.logger.Log("entry delay started (%d seconds)", delay)
+    s.publishLive("entry_delay", Zone{}, "")
 }
 
 // cancelEntryDelay stops any running entry delay timer.
@@ -115,34 +190,68 @@ func (s *Server) cancelEntryDelay() {
 }
 
 // triggerAlarm transitions the system into alarm state.  It logs the
-// reason and invokes alert handlers for all triggered zones.  When in
-// alarm state, status responses will include Alarm=true.  Triggering the
-// alarm also stops any running entry or exit delays.
+// reason, and raises an alarm event and invokes the alert handlers for
+// every triggered zone that has not already done so, so that zones
+// triggered while the alarm is sounding are reported too.  When in alarm
+// state, status responses will include Alarm=true.  Triggering the alarm
+// also stops any running entry or exit delays.
 func (s *Server) triggerAlarm(reason string) {
-    if s.alarm {
-        return
-    }
-    s.alarm = true
-    s.cancelEntryDelay()
-    if s.exitTimer != nil {
-        s.exitTimer.Stop()
-        s.exitTimer = nil
-        s.exitDelayEnd = time.Time{}
-        s.pendingMode = ""
+    if !s.alarm {
+        s.alarm = true
+        s.alarmMode = s.currentMode
+        s.cancelEntryDelay()
+        if s.exitTimer != nil {
+            s.exitTimer.Stop()
+            s.exitTimer = nil
+            s.exitDelayEnd = time.Time{}
+            s.pendingMode = ""
+        }
+        s.currentMode = "Alarm"
+        s.triggerMu.Lock()
+        s.alarmed = make(map[int]bool)
+        s.triggerMu.Unlock()
+        s.logger.Alarm("alarm triggered: %s", reason)
+    } else {
+        s.logger.Alarm("alarm extended: %s", reason)
     }
-    s.currentMode = "Alarm"
-    s.logger.Log("alarm triggered: %s", reason)
-    // Invoke alert handlers for each currently triggered zone
     cfg := s.cfgMgr.Get()
+    var zones []Zone
+    s.triggerMu.Lock()
     for _, z := range cfg.Zones {
-        if s.triggered[z.ID] {
-            for _, h := range s.alerts {
-                if err := h.Send(z, s.logger); err != nil {
-                    s.logger.Log("alert handler %s error: %v", h.Name(), err)
-                }
-            }
+        if s.triggered[z.ID] && !s.alarmed[z.ID] {
+            s.alarmed[z.ID] = true
+            zones = append(zones, z)
         }
     }
+    s.triggerMu.Unlock()
+    for _, z := range zones {
+        s.raiseAlarm(z, s.alarmMode, reason)
+        event := s.newAlertEvent(EventAlarm, z, "")
+        event.Mode = s.alarmMode
+        s.sendAlerts(event)
+        s.startEscalation(event)
+    }
+}
+
+// newAlertEvent builds an AlertEvent of the given type for zone, stamped
+// with the current arm mode and time.
+func (s *Server) newAlertEvent(eventType string, zone Zone, username string) AlertEvent {
+    return AlertEvent{
+        Type: eventType,
+        Zone: zone,
+        Mode: s.currentMode,
+        User: username,
+        Time: time.Now(),
+    }
+}
+
+// sendAlerts queues an event for delivery to every configured alert
+// handler and returns immediately; delivery happens on the dispatcher's
+// worker pool so callers such as pollSensors are never blocked by a slow
+// handler.  Errors are logged and the failed deliveries queued for retry.
+func (s *Server) sendAlerts(event AlertEvent) {
+    s.alertQueue.enqueue(event)
+    s.live.publish(liveEvent{Type: event.Type, ZoneID: event.Zone.ID, Zone: event.Zone.Name, Mode: event.Mode, User: event.User, Time: event.Time})
 }
 
 // NewServer constructs a new Server and initialises GPIO.
@@ -151,164 +260,433 @@ func NewServer(cfgMgr *ConfigManager) (*Server, error) {
         return nil, err
     }
     cfg := cfgMgr.Get()
-    logger := NewEventLogger(cfg.LogFile)
+    logger := NewEventLogger(cfgMgr.resolve(cfg.LogFile))
+    logger.SetRotation(cfg.LogRotation)
+    logger.SetLevel(cfg.LogLevel)
+    sinks := append([]LogSinkConfig(nil), cfg.LogSinks...)
+    for i := range sinks {
+        sinks[i].Path = cfgMgr.resolve(sinks[i].Path)
+    }
+    logger.SetSinks(sinks)
+    if path, err := filepath.Abs(cfgMgr.Path()); err == nil {
+        logger.Print(LogInfo, "Loaded configuration from %s", path)
+    }
+    for _, p := range cfg.Validate() {
+        logger.Warning("config warning: %s", p)
+    }
+    setHashParams(cfg.HashParams)
+    setTimezone(cfg.Timezone)
+    if hp, took := benchmarkHash(); hp.Algorithm == HashArgon2id {
+        logger.Log("password hashing: argon2id m=%d t=%d p=%d takes %s", hp.Memory, hp.Time, hp.Parallelism, took.Round(time.Millisecond))
+    } else {
+        logger.Log("password hashing: bcrypt cost %d takes %s", hp.Cost, took.Round(time.Millisecond))
+    }
     s := &Server{
         cfgMgr:     cfgMgr,
-        sessions:   NewSessionManager(),
+        sessions:   NewSessionManager(sessionStorePath(cfg, sessionsPath), logger),
+        devices:    NewDeviceManager(sessionStorePath(cfg, devicesPath), logger),
         currentMode: "Disarmed",
         triggered:  make(map[int]bool),
+        escalations: make(map[int]*escalation),
+        alarms:      NewAlarmLog(alarmsPath, logger),
+        live:        eventHub{ids: logger.NextID},
+        heartbeats:  heartbeats{state: make(map[int]*heartbeatStatus)},
+        failedLogins: failedLogins{bursts: make(map[string]*loginFailureBurst)},
+        webauthn:    webauthnChallenges{pending: make(map[string]webauthnChallenge)},
         logger:     logger,
         testMode:   0,
+        stop:       make(chan struct{}),
+    }
+    cfgMgr.directory.report = s.reportDirectory
+    warnProxyAuth(cfg.ProxyAuth, logger)
+    if cfg.MQTT != nil && cfg.MQTT.Broker != "" {
+        s.mqtt = NewMQTTClient(*cfg.MQTT, logger)
+        if ha := cfg.MQTT.HomeAssistant; ha != nil && ha.Enabled {
+            s.startHomeAssistant(*ha)
+        }
     }
     // Initialise alert handlers based on configuration.  If no alerts are
     // configured, a default LogAlert is used.
-    s.alerts = initAlertHandlers(cfg, logger)
+    handlers, ids := initAlertHandlers(cfg, logger, s.mqtt, s.currentUsers)
+    s.alerts = handlers
+    s.alertQueue = newAlertQueue(handlers, ids, cfg, logger)
+    s.zones.Store(newZoneIndex(cfg))
+    s.watchConfig()
     // Start polling sensors in the background.  The goroutine will idle
     // while the system is disarmed or in TestSoft mode.
-    go s.pollSensors()
+    s.background(s.pollSensors)
+    s.background(s.heartbeatLoop)
+    s.background(s.sessionPurgeLoop)
+    s.background(s.retentionLoop)
+    s.background(s.digestLoop)
+    if reason := cfgMgr.Fallback(); reason != "" {
+        logger.Alarm("fault: %s", reason)
+        s.sendAlerts(s.newAlertEvent(EventFault, Zone{Name: "configuration"}, ""))
+    }
     return s, nil
 }
 
-// Start launches the HTTPS server.  It blocks until the server shuts down.
+// Start launches the HTTPS server.  It blocks until the server shuts down,
+// returning nil if it was stopped by Shutdown.
 func (s *Server) Start() error {
     cfg := s.cfgMgr.Get()
-    addr := fmt.Sprintf(":%d", cfg.HTTPPort)
 
     mux := http.NewServeMux()
     
     // API routes
     mux.HandleFunc("/api/login", s.handleLogin)
     mux.HandleFunc("/api/logout", s.handleLogout)
+    mux.HandleFunc("/api/oidc/login", s.handleOIDCLogin)
+    mux.HandleFunc("/api/oidc/callback", s.handleOIDCCallback)
+    mux.HandleFunc("/api/password", s.withAuth(s.handlePassword))
+    mux.HandleFunc("/api/csrf", s.withAuth(s.handleCSRF))
+    mux.HandleFunc("/api/profile", s.withAuth(s.handleProfile))
+    mux.HandleFunc("/api/session/refresh", s.handleSessionRefresh)
+    mux.HandleFunc("/api/devices", s.withAuth(s.handleDevices))
+    mux.HandleFunc("/api/devices/", s.withAuth(s.handleDeviceByID))
+    mux.HandleFunc("/api/sessions", s.withAuth(s.handleSessions))
+    mux.HandleFunc("/api/sessions/", s.withAuth(s.handleSessionByID))
+    mux.HandleFunc("/api/api_keys", s.withAuth(s.handleAPIKeys))
+    mux.HandleFunc("/api/api_keys/", s.withAuth(s.handleAPIKeyByID))
+    mux.HandleFunc("/api/tokens", s.withAuth(s.handleTokens))
+    mux.HandleFunc("/api/tokens/", s.withAuth(s.handleTokenByID))
+    mux.HandleFunc("/api/2fa", s.withAuth(s.handleTwoFactor))
+    mux.HandleFunc("/api/2fa/", s.withAuth(s.handleTwoFactor))
+    mux.HandleFunc("/api/webauthn/register/begin", s.withAuth(s.handleWebAuthnRegisterBegin))
+    mux.HandleFunc("/api/webauthn/register/finish", s.withAuth(s.handleWebAuthnRegisterFinish))
+    mux.HandleFunc("/api/webauthn/login/begin", s.handleWebAuthnLoginBegin)
+    mux.HandleFunc("/api/webauthn/login/finish", s.handleWebAuthnLoginFinish)
+    mux.HandleFunc("/api/webauthn/credentials", s.withAuth(s.handleWebAuthnCredentials))
+    mux.HandleFunc("/api/webauthn/credentials/", s.withAuth(s.handleWebAuthnCredentials))
     mux.HandleFunc("/api/status", s.withAuth(s.handleStatus))
+    mux.HandleFunc("/api/health/config", s.withAuth(s.handleConfigHealth))
+    mux.HandleFunc("/api/config/export", s.withAuth(s.handleConfigExport))
+    mux.HandleFunc("/api/config/import", s.withAuth(s.handleConfigImport))
+    mux.HandleFunc("/api/events", s.withAuth(s.handleEvents))
+    mux.HandleFunc("/api/events/", s.withAuth(s.handleEventByID))
+    mux.HandleFunc("/api/ws", s.withAuth(s.handleWS))
     mux.HandleFunc("/api/arm", s.withAuth(s.handleArm))
     mux.HandleFunc("/api/disarm", s.withAuth(s.handleDisarm))
+    mux.HandleFunc("/api/acknowledge", s.withAuth(s.handleAcknowledge))
     mux.HandleFunc("/api/zones", s.withAuth(s.handleZones))
     mux.HandleFunc("/api/zones/", s.withAuth(s.handleZoneByID))
     mux.HandleFunc("/api/users", s.withAuth(s.handleUsers))
     mux.HandleFunc("/api/users/", s.withAuth(s.handleUserByID))
     mux.HandleFunc("/api/arm_modes", s.withAuth(s.handleArmModes))
     mux.HandleFunc("/api/logs", s.withAuth(s.handleLogs))
+    mux.HandleFunc("/api/logs/prune", s.withAuth(s.handleLogsPrune))
+    mux.HandleFunc("/api/logs/export", s.withAuth(s.handleLogsExport))
+    mux.HandleFunc("/api/logs/recent", s.withAuth(s.handleLogsRecent))
+    mux.HandleFunc("/api/settings", s.withAuth(s.handleSettings))
+    mux.HandleFunc("/api/settings/log_level", s.withAuth(s.handleLogLevel))
+    mux.HandleFunc("/api/security/failures", s.withAuth(s.handleSecurityFailures))
+    mux.HandleFunc("/api/audit", s.withAuth(s.handleAudit))
     mux.HandleFunc("/api/test_trigger", s.withAuth(s.handleTestTrigger))
+    mux.HandleFunc("/api/walk_test/start", s.withAuth(s.handleWalkTestStart))
+    mux.HandleFunc("/api/walk_test/stop", s.withAuth(s.handleWalkTestStop))
+    mux.HandleFunc("/api/walk_test/status", s.withAuth(s.handleWalkTestStatus))
+    mux.HandleFunc("/api/alerts", s.withAuth(s.handleAlerts))
+    mux.HandleFunc("/api/alerts/status", s.withAuth(s.handleAlertStatus))
+    mux.HandleFunc("/api/alerts/test", s.withAuth(s.handleAlertTestAll))
+    mux.HandleFunc("/api/alerts/", s.withAuth(s.handleAlertByID))
     
-    // Static file handling.  The front‑end is built into web/dist by Vite.
-    // We embed `web/dist` under the embedded filesystem (see //go:embed
-    // directive).  Create a sub-filesystem rooted at web/dist so that
-    // requests to `/index.html` map to the file `web/dist/index.html`.
-    // Without using fs.Sub, http.FileServer will try to serve files from the
-    // root of the embedded filesystem and generate 301 redirects when it
-    // cannot find the requested path.  Serving via a sub-FS prevents
-    // redirect loops like "Too many redirects" when requesting "/".
+    // Static file handling.  The front‑end is built into web/dist by Vite
+    // and embedded; see static.go.
     distFS, err := fs.Sub(embeddedFiles, "web/dist")
     if err != nil {
         return fmt.Errorf("failed to init embedded filesystem: %w", err)
     }
-    // Serve the embedded SPA manually rather than relying on http.FileServer to
-    // avoid automatic 301 redirects when a directory is requested.  We
-    // attempt to serve the requested file from distFS.  If it does not
-    // exist or is a directory, we fall back to index.html.  The content
-    // type is inferred from the file extension using the mime package.
-    mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-        // derive relative path within the dist folder
-        name := strings.TrimPrefix(r.URL.Path, "/")
-        if name == "" {
-            name = "index.html"
-        }
-        // Attempt to read the requested file
-        data, err := fs.ReadFile(distFS, name)
-        if err != nil {
-            // fallback to index.html for unknown files or directories
-            data, err = fs.ReadFile(distFS, "index.html")
-            if err != nil {
-                http.NotFound(w, r)
-                return
-            }
-            name = "index.html"
-        }
-        // Set content type based on file extension
-        ext := filepath.Ext(name)
-        if ctype := mime.TypeByExtension(ext); ctype != "" {
-            w.Header().Set("Content-Type", ctype)
-        }
-        _, _ = w.Write(data)
-    })
+    mux.HandleFunc("/", spaHandler(distFS))
+
+    if plainHTTP(cfg) {
+        return s.serveHTTP(cfg, s.withCORS(withCompression(mux)))
+    }
 
     // TLS configuration: use modern defaults
     tlsConfig := &tls.Config{
         MinVersion: tls.VersionTLS12,
     }
+    if err := s.clientCertTLS(tlsConfig, cfg.ClientCerts); err != nil {
+        return err
+    }
     
-    srv := &http.Server{
-        Addr:      addr,
-        Handler:   mux,
-        TLSConfig: tlsConfig,
+    certFile, keyFile := s.cfgMgr.resolve(cfg.CertFile), s.cfgMgr.resolve(cfg.KeyFile)
+    if cfg.ACME != nil {
+        s.startACME(*cfg.ACME, tlsConfig)
+        certFile, keyFile = "", ""
+    } else if missing, err := s.cfgMgr.certificateMissing(cfg); err != nil {
+        return err
+    } else if missing {
+        fingerprint, err := s.cfgMgr.generateCertificate(cfg)
+        if err != nil {
+            return fmt.Errorf("unable to generate a certificate: %w", err)
+        }
+        s.logger.Print(LogInfo, "Generated a self-signed certificate for %s in %s, SHA-256 fingerprint %s", certHostname(cfg), s.cfgMgr.resolve(cfg.CertFile), fingerprint)
     }
-
-    log.Printf("Listening on https://0.0.0.0%s\n", addr)
-    return srv.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+    if err := s.startRedirect(cfg); err != nil {
+        return err
+    }
+    return s.serve(cfg, s.withCORS(withCompression(mux)), tlsConfig, certFile, keyFile)
 }
 
 // withAuth wraps handlers that require a valid session.  If the request
-// contains a valid "session" cookie, it calls the underlying handler with
-// the username; otherwise it responds with 401.
+// contains a valid "session" cookie, or an API token in an
+// "Authorization: Bearer" header, it calls the underlying handler with the
+// user; otherwise it responds with 401.  A user who must change their
+// password is refused everything but /api/password with a 403 whose JSON
+// body is {"error":"password_change_required"}, so the UI can tell it apart.
 func (s *Server) withAuth(handler func(http.ResponseWriter, *http.Request, User)) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
-        cookie, err := r.Cookie("session")
-        if err != nil {
-            http.Error(w, "unauthenticated", http.StatusUnauthorized)
-            return
-        }
-        sess, ok := s.sessions.Get(cookie.Value)
+        user, ok := s.authenticate(w, r)
         if !ok {
-            http.Error(w, "session expired", http.StatusUnauthorized)
             return
         }
-        user, _ := s.cfgMgr.FindUser(sess.Username)
-        if user.Username == "" {
-            http.Error(w, "unknown user", http.StatusUnauthorized)
+        if user.MustChangePassword && r.URL.Path != "/api/password" {
+            w.Header().Set("Content-Type", "application/json")
+            w.WriteHeader(http.StatusForbidden)
+            _ = json.NewEncoder(w).Encode(map[string]string{"error": "password_change_required"})
             return
         }
         handler(w, r, user)
     }
 }
 
+// authenticate resolves the user making r from its API key, client
+// certificate, reverse proxy header, API token or session cookie.  If there is none, or it is invalid, an error response is
+// written and false returned.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (User, bool) {
+    if key := r.Header.Get("X-API-Key"); key != "" {
+        user, err := s.authenticateAPIKey(key, r)
+        if err != nil {
+            status := http.StatusUnauthorized
+            if err.Error() != "invalid API key" {
+                status = http.StatusForbidden
+            }
+            http.Error(w, err.Error(), status)
+            return User{}, false
+        }
+        return user, true
+    }
+    if user, cert, err := s.certUser(r); cert != nil {
+        if err == nil {
+            s.logger.Log("client certificate %s authenticated %s for %s %s", cert.Subject, user.Username, r.Method, r.URL.Path)
+            if !s.sameOrigin(r) {
+                s.logger.Log("security: refused cross-origin %s %s authenticated by client certificate %s", r.Method, r.URL.Path, cert.Subject)
+                http.Error(w, "cross-origin request refused", http.StatusForbidden)
+                return User{}, false
+            }
+            s.recordLogin(r, user.Username, LoginViaCert)
+            return user, true
+        }
+        // In "request" mode an unmapped certificate falls back to the
+        // other credentials.
+        if s.clientCertMode == ClientCertRequire {
+            s.logger.Log("client certificate %s refused: %v", cert.Subject, err)
+            http.Error(w, "client certificate not accepted", http.StatusUnauthorized)
+            return User{}, false
+        }
+    }
+    if name, ok := s.proxyUsername(r); ok {
+        user, err := s.proxyUser(r, name)
+        if err != nil {
+            s.logger.Log("proxy authentication as %q from %s refused: %v", name, s.clientIP(r), err)
+            http.Error(w, "unknown user", http.StatusUnauthorized)
+            return User{}, false
+        }
+        if !s.sameOrigin(r) {
+            s.logger.Log("security: refused cross-origin %s %s authenticated by proxy as %s", r.Method, r.URL.Path, user.Username)
+            http.Error(w, "cross-origin request refused", http.StatusForbidden)
+            return User{}, false
+        }
+        s.recordLogin(r, user.Username, LoginViaProxy)
+        return user, true
+    }
+    if token := bearerToken(r); token != "" {
+        user, err := s.authenticateToken(token, r)
+        if err != nil {
+            status := http.StatusUnauthorized
+            if err.Error() != "invalid token" {
+                status = http.StatusForbidden
+            }
+            http.Error(w, err.Error(), status)
+            return User{}, false
+        }
+        s.recordLogin(r, user.Username, LoginViaToken)
+        return user, true
+    }
+    cookie, err := r.Cookie("session")
+    if err != nil {
+        http.Error(w, "unauthenticated", http.StatusUnauthorized)
+        return User{}, false
+    }
+    cfg := s.cfgMgr.Get()
+    ttl, idle := sessionLimits(cfg)
+    old, _ := s.sessions.Get(cookie.Value)
+    if reason := s.sessionMismatch(cfg.SessionBinding, old, r); old.ID != "" && reason != "" {
+        s.sessionMismatched(cookie.Value, old, reason)
+        http.Error(w, "session expired", http.StatusUnauthorized)
+        return User{}, false
+    }
+    sess, ok := s.sessions.Touch(cookie.Value, ttl, idle)
+    if !ok {
+        http.Error(w, "session expired", http.StatusUnauthorized)
+        return User{}, false
+    }
+    user, _ := s.cfgMgr.FindUser(sess.Username)
+    if user.Username == "" || user.Disabled {
+        http.Error(w, "unknown user", http.StatusUnauthorized)
+        return User{}, false
+    }
+    if !validCSRF(r, sess) {
+        http.Error(w, "invalid CSRF token", http.StatusForbidden)
+        return User{}, false
+    }
+    w.Header().Set(csrfHeader, sess.CSRF)
+    if !sess.Expires.Equal(old.Expires) {
+        setSessionCookie(w, cookie.Value, sess.Expires, s.secureRequest(r))
+    }
+    return user, true
+}
+
 // handleLogin authenticates a user and sets a session cookie.  Expected JSON:
-// {"username":"...","password":"..."}
+// {"username":"...","password":"..."}, plus "otp" or "recovery_code" for a
+// user with two-factor login, who is otherwise refused with a 401 whose
+// JSON body is {"error":"otp_required"}.
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
     if r.Method != http.MethodPost {
         http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
         return
     }
+    if s.lockedOut(w, r, lockoutLogin) {
+        return
+    }
     var creds struct {
-        Username string `json:"username"`
-        Password string `json:"password"`
+        Username     string `json:"username"`
+        Password     string `json:"password"`
+        // Remember also registers the device for refresh tokens.
+        Remember     bool   `json:"remember"`
+        // OTP or RecoveryCode is the second factor, see totp.go.
+        OTP          string `json:"otp"`
+        RecoveryCode string `json:"recovery_code"`
     }
     if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
         http.Error(w, "invalid JSON", http.StatusBadRequest)
         return
     }
+    before, _ := s.cfgMgr.FindUser(creds.Username)
     user, err := s.cfgMgr.Authenticate(creds.Username, creds.Password)
     if err != nil {
+        s.recordFailedLogin(r, creds.Username)
         http.Error(w, "invalid credentials", http.StatusUnauthorized)
         return
     }
-    // Create session valid for 24h
-    sessID, _, err := s.sessions.Create(user.Username, 24*time.Hour)
+    if user.Directory {
+        s.auditProvisioned(r, "ldap", before, user)
+    }
+    if !passwordLoginAllowed(s.cfgMgr.Get().OIDC, user) {
+        s.logger.Log("password login by %s refused: single sign-on required", user.Username)
+        http.Error(w, "password login is disabled, use single sign-on", http.StatusForbidden)
+        return
+    }
+    if user.TOTPSecret != "" {
+        err := s.checkSecondFactor(r, user, creds.OTP, creds.RecoveryCode)
+        if err == errOTPRequired {
+            w.Header().Set("Content-Type", "application/json")
+            w.WriteHeader(http.StatusUnauthorized)
+            _ = json.NewEncoder(w).Encode(map[string]string{"error": "otp_required"})
+            return
+        }
+        if err != nil {
+            s.recordFailedLogin(r, creds.Username)
+            http.Error(w, "invalid credentials", http.StatusUnauthorized)
+            return
+        }
+    }
+    if user.PasswordHash != "" && needsRehash(user.PasswordHash) {
+        s.rehashPassword(user.Username, creds.Password)
+    }
+    start := s.startSession
+    if creds.Remember {
+        start = s.startRememberedSession
+    }
+    if err := start(w, r, user.Username); err != nil {
+        sessionError(w, err)
+        return
+    }
+    s.lockouts.clear(s.lockoutKey(lockoutLogin, r))
+    s.recordLogin(r, user.Username, LoginViaPassword)
+    s.logger.Log("login %s", user.Username)
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(map[string]any{"status": "ok", "must_change_password": user.MustChangePassword})
+}
+
+// rehashPassword replaces the stored hash of username, which has just been
+// verified against password, with one made with the current parameters.
+func (s *Server) rehashPassword(username, password string) {
+    hash, err := hashPassword(password)
     if err != nil {
-        http.Error(w, "failed to create session", http.StatusInternalServerError)
+        s.logger.Log("unable to upgrade password hash of %s: %v", username, err)
         return
     }
+    err = s.cfgMgr.Update(func(c *Config) error {
+        for i, u := range c.Users {
+            if u.Username == username {
+                c.Users[i].PasswordHash = hash
+                return nil
+            }
+        }
+        return errors.New("not found")
+    })
+    if err != nil {
+        s.logger.Log("unable to upgrade password hash of %s: %v", username, err)
+        return
+    }
+    s.logger.Log("password hash of %s upgraded to current parameters", username)
+}
+
+// startSession creates a session for username, with the configured
+// lifetime, idle timeout and session limit, and sets its cookie on the
+// response.  The client address and user agent of r are recorded for GET
+// /api/sessions.  Sessions evicted to make room, and logins refused with
+// errTooManySessions, are reported as security events.
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, username string) error {
+    return s.startDeviceSession(w, r, username, "")
+}
+
+// startDeviceSession is startSession for a session started from the
+// remembered device with ID device ("" for none).
+func (s *Server) startDeviceSession(w http.ResponseWriter, r *http.Request, username, device string) error {
+    cfg := s.cfgMgr.Get()
+    ttl, idle := sessionLimits(cfg)
+    user, _ := s.cfgMgr.FindUser(username)
+    tmpl := Session{Username: username, IP: s.clientIP(r), UserAgent: r.UserAgent(), Device: device}
+    sessID, sess, evicted, err := s.sessions.Create(tmpl, ttl, idle, userSessionLimit(cfg, user))
+    if err == errTooManySessions {
+        s.sessionLimitReached(username, fmt.Sprintf("login by %s from %s refused: already at the limit of %d sessions", username, s.clientIP(r), cfg.MaxSessionsPerUser))
+    }
+    if err != nil {
+        return err
+    }
+    for _, old := range evicted {
+        s.sessionLimitReached(username, fmt.Sprintf("session %s of %s from %s ended by a new login from %s: limit of %d sessions", old.ID, username, old.IP, sess.IP, cfg.MaxSessionsPerUser))
+    }
+    setSessionCookie(w, sessID, sess.Expires, s.secureRequest(r))
+    w.Header().Set(csrfHeader, sess.CSRF)
+    return nil
+}
+
+// setSessionCookie sets the session cookie, expiring with the session.
+// secure marks it for HTTPS only; see secureRequest.
+func setSessionCookie(w http.ResponseWriter, id string, expires time.Time, secure bool) {
     http.SetCookie(w, &http.Cookie{
         Name:     "session",
-        Value:    sessID,
+        Value:    id,
         Path:     "/",
         HttpOnly: true,
-        Secure:   true,
+        Secure:   secure,
         SameSite: http.SameSiteStrictMode,
-        Expires:  time.Now().Add(24 * time.Hour),
+        Expires:  expires,
     })
-    s.logger.Log("login %s", user.Username)
-    w.Header().Set("Content-Type", "application/json")
-    _ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
 // handleLogout deletes the session cookie.
@@ -317,34 +695,149 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
         return
     }
-    cookie, err := r.Cookie("session")
-    if err == nil {
-        s.sessions.Delete(cookie.Value)
+    if sess, cookie, ok := s.requestSession(r); ok {
+        s.sessions.Delete(cookie)
+        // Logging out also forgets the device the session came from.
+        if sess.Device != "" {
+            s.devices.Revoke(sess.Device, "")
+            s.sessions.DeleteDevice(sess.Device)
+            clearRefreshCookie(w, s.secureRequest(r))
+        }
     }
     http.SetCookie(w, &http.Cookie{
         Name:     "session",
         Value:    "",
         Path:     "/",
         HttpOnly: true,
-        Secure:   true,
+        Secure:   s.secureRequest(r),
         Expires:  time.Unix(0, 0),
     })
     s.logger.Log("logout")
     w.WriteHeader(http.StatusNoContent)
 }
 
+// handlePassword lets any user change their own password.  Expected JSON:
+// {"current_password":"...","new_password":"..."}.  On success every other
+// session of the user is ended.  With read_only_config only a user who
+// must change their password may, and only in memory.
+func (s *Server) handlePassword(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    // A user made to change their password could do nothing else, so with
+    // read_only_config the change is still made, in memory only.
+    readOnly := s.cfgMgr.Get().ReadOnlyConfig
+    if readOnly && !user.MustChangePassword {
+        s.configReadOnly(w)
+        return
+    }
+    var req struct {
+        CurrentPassword string `json:"current_password"`
+        NewPassword     string `json:"new_password"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "invalid JSON", http.StatusBadRequest)
+        return
+    }
+    if user.Directory {
+        http.Error(w, "your password is managed by the LDAP directory", http.StatusBadRequest)
+        return
+    }
+    if user.SSO {
+        http.Error(w, "you log in with single sign-on and have no password here", http.StatusBadRequest)
+        return
+    }
+    if user.Proxy {
+        http.Error(w, "you log in through the reverse proxy and have no password here", http.StatusBadRequest)
+        return
+    }
+    if err := checkPasswordHash(req.CurrentPassword, user.PasswordHash); err != nil {
+        s.logger.Log("password change by %s rejected: wrong current password", user.Username)
+        http.Error(w, "current password is incorrect", http.StatusForbidden)
+        return
+    }
+    if err := validatePassword(req.NewPassword, user.Username, s.cfgMgr.Get().PasswordPolicy); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    hash, err := hashPassword(req.NewPassword)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    err = s.cfgMgr.Update(func(c *Config) error {
+        for i, u := range c.Users {
+            if u.Username == user.Username {
+                c.Users[i].PasswordHash = hash
+                c.Users[i].MustChangePassword = false
+                return nil
+            }
+        }
+        return errors.New("not found")
+    })
+    if err != nil {
+        http.Error(w, "internal error", http.StatusInternalServerError)
+        return
+    }
+    // The first run admin has now replaced the generated password, unless
+    // the file still holds it.
+    if user.MustChangePassword && user.hasRole(RoleAdmin) && !readOnly {
+        if err := s.cfgMgr.removeInitialPassword(); err != nil {
+            s.logger.Log("unable to remove the initial password file: %v", err)
+        }
+    }
+    sess, current, _ := s.requestSession(r)
+    ended := s.sessions.DeleteUser(user.Username, current)
+    forgotten := s.devices.DeleteUser(user.Username, sess.Device)
+    s.logger.Log("password changed by %s, %d other sessions ended and %d devices forgotten", user.Username, ended, forgotten)
+    if readOnly {
+        s.logger.Warning("password of %s changed in memory only, as the configuration is read only: change it in the configuration file too", user.Username)
+    }
+    s.audit(r, user.Username, "password.change", user.Username, nil, nil)
+    w.WriteHeader(http.StatusNoContent)
+}
+
 // handleStatus returns the current arm mode and triggered zones.
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request, user User) {
-    type status struct {
-        Mode      string     `json:"mode"`
-        Triggered []int      `json:"triggered"`
-        Zones     []ZoneInfo `json:"zones"`
-        // Remaining seconds for exit and entry delays; zero if no delay active
-        ExitDelay int `json:"exit_delay"`
-        EntryDelay int `json:"entry_delay"`
-        // Alarm indicates that the system is in alarm state
-        Alarm     bool `json:"alarm"`
-    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(s.statusSnapshot())
+}
+
+// systemStatus is the state of the system as returned by /api/status.
+type systemStatus struct {
+    Mode      string     `json:"mode"`
+    Triggered []int      `json:"triggered"`
+    Zones     []ZoneInfo `json:"zones"`
+    // Remaining seconds for exit and entry delays; zero if no delay active
+    ExitDelay int `json:"exit_delay"`
+    EntryDelay int `json:"entry_delay"`
+    // Alarm indicates that the system is in alarm state
+    Alarm     bool `json:"alarm"`
+    // Escalations lists alarms that are escalating through the
+    // configured tiers.
+    Escalations []escalationStatus `json:"escalations"`
+    // Faults describes conditions that need attention, such as an
+    // alert whose heartbeats keep failing.
+    Faults []string `json:"faults"`
+    // Degraded is true when the most recent delivery attempt of any
+    // alert handler failed.
+    Degraded bool `json:"degraded"`
+    // Sessions is the number of live login sessions.
+    Sessions int `json:"sessions"`
+    // Unacked is the number of alarm events not yet acknowledged.
+    Unacked int `json:"unacked"`
+    // Timezone names the time zone the times of the response are in.
+    Timezone string `json:"timezone"`
+    // ReadOnlyConfig is true when read_only_config stops the API from
+    // changing the configuration.
+    ReadOnlyConfig bool `json:"read_only_config"`
+    // Listening lists the addresses the server is bound to.
+    Listening []string `json:"listening"`
+}
+
+// statusSnapshot returns the current state of the system.
+func (s *Server) statusSnapshot() systemStatus {
     cfg := s.cfgMgr.Get()
     triggered := []int{}
     for id, active := range s.triggered {
@@ -379,9 +872,16 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request, user User)
             entryRem = d
         }
     }
-    resp := status{Mode: s.currentMode, Triggered: triggered, Zones: zones, ExitDelay: exitRem, EntryDelay: entryRem, Alarm: s.alarm}
-    w.Header().Set("Content-Type", "application/json")
-    _ = json.NewEncoder(w).Encode(resp)
+    return systemStatus{Mode: s.currentMode, Triggered: triggered, Zones: zones, ExitDelay: exitRem, EntryDelay: entryRem, Alarm: s.alarm, Escalations: s.escalationSnapshot(), Faults: s.faults(), Degraded: s.alertQueue.degraded(), Sessions: s.sessions.Count(), Unacked: len(s.alarms.Unacked()), Timezone: localTime(now).Location().String(), ReadOnlyConfig: cfg.ReadOnlyConfig, Listening: s.listeningAddrs()}
+}
+
+// faults returns the conditions that need attention, for /api/status.
+func (s *Server) faults() []string {
+    faults := append(s.heartbeatFaults(), s.cfgMgr.directory.faults()...)
+    if reason := s.cfgMgr.Fallback(); reason != "" {
+        faults = append(faults, reason)
+    }
+    return append(faults, s.acme.faults()...)
 }
 
 // ZoneInfo extends Zone with an Active flag used in status responses.
@@ -400,6 +900,10 @@ func (s *Server) handleArm(w http.ResponseWriter, r *http.Request, user User) {
         http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
         return
     }
+    if !user.hasRole(RoleOperator) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
     var req struct {
         Mode string `json:"mode"`
     }
@@ -407,29 +911,49 @@ func (s *Server) handleArm(w http.ResponseWriter, r *http.Request, user User) {
         http.Error(w, "invalid JSON", http.StatusBadRequest)
         return
     }
-    mode := strings.TrimSpace(req.Mode)
+    switch err := s.armAs(user, req.Mode); err {
+    case nil:
+        w.WriteHeader(http.StatusNoContent)
+    case errForbidden:
+        http.Error(w, err.Error(), http.StatusForbidden)
+    default:
+        http.Error(w, err.Error(), http.StatusBadRequest)
+    }
+}
+
+// errUnknownArmMode is returned by arm when the requested mode is neither a
+// test mode nor a configured arm mode.
+var errUnknownArmMode = errors.New("unknown arm mode")
+
+// arm switches the system into mode on behalf of actor, which is recorded in
+// the event log.  The test modes are recognised by name; any other mode must
+// be a configured arm mode.  It is shared by the HTTP API and other control
+// paths such as MQTT.
+func (s *Server) arm(mode, actor string) error {
     cfg := s.cfgMgr.Get()
     // Handle special test modes
     lower := strings.ToLower(mode)
     if lower == "testsoft" || lower == "test soft" {
+        s.endWalkTest(actor)
         s.currentMode = "TestSoft"
         s.testMode = 1
         s.triggerMu.Lock()
         s.triggered = make(map[int]bool)
         s.triggerMu.Unlock()
-        s.logger.Log("arm TestSoft by %s", user.Username)
-        w.WriteHeader(http.StatusNoContent)
-        return
+        s.logger.Log("arm TestSoft by %s", actor)
+        s.sendAlerts(s.newAlertEvent(EventArm, Zone{}, actor))
+        return nil
     }
     if lower == "testwiring" || lower == "test wiring" {
+        s.endWalkTest(actor)
         s.currentMode = "TestWiring"
         s.testMode = 2
         s.triggerMu.Lock()
         s.triggered = make(map[int]bool)
         s.triggerMu.Unlock()
-        s.logger.Log("arm TestWiring by %s", user.Username)
-        w.WriteHeader(http.StatusNoContent)
-        return
+        s.logger.Log("arm TestWiring by %s", actor)
+        s.sendAlerts(s.newAlertEvent(EventArm, Zone{}, actor))
+        return nil
     }
     // Validate normal arm mode exists
     var activeZones []int
@@ -440,9 +964,10 @@ func (s *Server) handleArm(w http.ResponseWriter, r *http.Request, user User) {
         }
     }
     if activeZones == nil {
-        http.Error(w, "unknown arm mode", http.StatusBadRequest)
-        return
+        return errUnknownArmMode
     }
+    // Arming ends any walk test in progress; record what was verified.
+    s.endWalkTest(actor)
     // Determine if any of the active zones are entry/exit sensors.  If so,
     // start an exit delay before fully arming.  During the delay the
     // system remains in "ExitDelay" state, and closing the entry/exit
@@ -470,11 +995,13 @@ func (s *Server) handleArm(w http.ResponseWriter, r *http.Request, user User) {
     s.testMode = 0
     if hasEntryExit {
         s.startExitDelay(mode)
+        s.logger.Log("arm %s by %s", mode, actor)
     } else {
         s.currentMode = mode
-        s.logger.Log("arm %s by %s", s.currentMode, user.Username)
+        s.logger.Log("arm %s by %s", s.currentMode, actor)
     }
-    w.WriteHeader(http.StatusNoContent)
+    s.sendAlerts(s.newAlertEvent(EventArm, Zone{}, actor))
+    return nil
 }
 
 // handleDisarm disarms the system and resets triggered flags.
@@ -483,6 +1010,35 @@ func (s *Server) handleDisarm(w http.ResponseWriter, r *http.Request, user User)
         http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
         return
     }
+    if !user.hasRole(RoleOperator) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    // A keypad sends the PIN that was entered, and optionally the
+    // username; the disarm is then attributed to the PIN's owner rather
+    // than the keypad's account.
+    var req struct {
+        Username string `json:"username"`
+        PIN      string `json:"pin"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+        http.Error(w, "invalid JSON", http.StatusBadRequest)
+        return
+    }
+    switch err := s.disarmAs(r, user, req.Username, req.PIN); err {
+    case nil:
+        w.WriteHeader(http.StatusNoContent)
+    case errPINLocked:
+        http.Error(w, err.Error(), http.StatusTooManyRequests)
+    default:
+        http.Error(w, err.Error(), http.StatusForbidden)
+    }
+}
+
+// disarm returns the system to Disarmed on behalf of actor, cancelling any
+// delays, clearing the alarm and resetting triggered flags.
+func (s *Server) disarm(actor string) {
+    s.endWalkTest(actor)
     s.currentMode = "Disarmed"
     s.testMode = 0
     // Cancel any running entry or exit delay and clear alarm
@@ -493,13 +1049,17 @@ func (s *Server) handleDisarm(w http.ResponseWriter, r *http.Request, user User)
         s.pendingMode = ""
     }
     s.cancelEntryDelay()
+    s.cancelEscalations(actor, "disarmed")
     s.alarm = false
+    s.alarmMode = ""
     s.triggerMu.Lock()
     s.triggered = make(map[int]bool)
+    s.alarmed = nil
+    s.acked = nil
     s.triggerMu.Unlock()
-    log.Println("System disarmed")
-    s.logger.Log("disarm by %s", user.Username)
-    w.WriteHeader(http.StatusNoContent)
+    s.logger.Print(LogInfo, "System disarmed")
+    s.logger.Log("disarm by %s", actor)
+    s.sendAlerts(s.newAlertEvent(EventDisarm, Zone{}, actor))
 }
 
 // handleZones handles GET and POST on /api/zones.  GET returns all zones.  POST
@@ -508,10 +1068,17 @@ func (s *Server) handleZones(w http.ResponseWriter, r *http.Request, user User)
     switch r.Method {
     case http.MethodGet:
         cfg := s.cfgMgr.Get()
+        zones := make([]Zone, len(cfg.Zones))
+        for i, z := range cfg.Zones {
+            zones[i] = redactZone(z)
+        }
         w.Header().Set("Content-Type", "application/json")
-        _ = json.NewEncoder(w).Encode(cfg.Zones)
+        _ = json.NewEncoder(w).Encode(zones)
     case http.MethodPost:
-        if !user.Admin {
+        if s.configReadOnly(w) {
+            return
+        }
+        if !user.hasRole(RoleAdmin) {
             http.Error(w, "forbidden", http.StatusForbidden)
             return
         }
@@ -525,7 +1092,7 @@ func (s *Server) handleZones(w http.ResponseWriter, r *http.Request, user User)
             return
         }
         // Assign ID: one greater than max existing ID
-        s.cfgMgr.Update(func(c *Config) error {
+        err := s.cfgMgr.Update(func(c *Config) error {
             maxID := 0
             for _, existing := range c.Zones {
                 if existing.ID > maxID {
@@ -536,17 +1103,34 @@ func (s *Server) handleZones(w http.ResponseWriter, r *http.Request, user User)
             c.Zones = append(c.Zones, z)
             return nil
         })
+        // A refused change is not audited as made.
+        var invalid configErrors
+        switch {
+        case err == errLastAdmin:
+            http.Error(w, err.Error(), http.StatusConflict)
+            return
+        case errors.As(err, &invalid):
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        case err != nil:
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
         s.logger.Log("create zone %s (id=%d) by %s", z.Name, z.ID, user.Username)
+        s.audit(r, user.Username, "zone.create", fmt.Sprintf("zone %d", z.ID), nil, redactZone(z))
+        s.adminChange(user.Username, "zone %s (id=%d) created", z.Name, z.ID)
         w.WriteHeader(http.StatusCreated)
-        _ = json.NewEncoder(w).Encode(z)
+        _ = json.NewEncoder(w).Encode(redactZone(z))
     default:
         http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
     }
 }
 
-// handleZoneByID handles PUT and DELETE on /api/zones/{id}.
+// handleZoneByID handles PUT, PATCH and DELETE on /api/zones/{id}.
+// Operators may only bypass a zone, with a PATCH of enabled alone; every
+// other change is for admins.
 func (s *Server) handleZoneByID(w http.ResponseWriter, r *http.Request, user User) {
-    if !user.Admin {
+    if !user.hasRole(RoleOperator) {
         http.Error(w, "forbidden", http.StatusForbidden)
         return
     }
@@ -561,9 +1145,16 @@ func (s *Server) handleZoneByID(w http.ResponseWriter, r *http.Request, user Use
         http.Error(w, "invalid id", http.StatusBadRequest)
         return
     }
+    if r.Method != http.MethodPatch && !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
     switch r.Method {
     case http.MethodPut:
-        var z Zone
+        if s.configReadOnly(w) {
+            return
+        }
+        var z, before Zone
         if err := json.NewDecoder(r.Body).Decode(&z); err != nil {
             http.Error(w, "invalid JSON", http.StatusBadRequest)
             return
@@ -571,7 +1162,13 @@ func (s *Server) handleZoneByID(w http.ResponseWriter, r *http.Request, user Use
         err = s.cfgMgr.Update(func(c *Config) error {
             for i, existing := range c.Zones {
                 if existing.ID == id {
+                    before = existing
                     z.ID = id
+                    // Walk test history is server-maintained
+                    z.LastWalkTest = existing.LastWalkTest
+                    if z.SnapshotPassword == redactedSecret {
+                        z.SnapshotPassword = existing.SnapshotPassword
+                    }
                     c.Zones[i] = z
                     return nil
                 }
@@ -587,11 +1184,18 @@ func (s *Server) handleZoneByID(w http.ResponseWriter, r *http.Request, user Use
             return
         }
         s.logger.Log("update zone id=%d by %s", id, user.Username)
+        s.audit(r, user.Username, "zone.update", fmt.Sprintf("zone %d", id), redactZone(before), redactZone(z))
+        s.adminChange(user.Username, "zone id=%d updated", id)
         w.WriteHeader(http.StatusNoContent)
     case http.MethodDelete:
+        if s.configReadOnly(w) {
+            return
+        }
+        var removed Zone
         err = s.cfgMgr.Update(func(c *Config) error {
             for i, existing := range c.Zones {
                 if existing.ID == id {
+                    removed = existing
                     c.Zones = append(c.Zones[:i], c.Zones[i+1:]...)
                     return nil
                 }
@@ -607,7 +1211,143 @@ func (s *Server) handleZoneByID(w http.ResponseWriter, r *http.Request, user Use
             return
         }
         s.logger.Log("delete zone id=%d by %s", id, user.Username)
+        s.audit(r, user.Username, "zone.delete", fmt.Sprintf("zone %d", id), redactZone(removed), nil)
+        s.adminChange(user.Username, "zone id=%d deleted", id)
         w.WriteHeader(http.StatusNoContent)
+    case http.MethodPatch:
+        // Partial update: only the supplied fields are merged into the
+        // existing zone so that clients cannot accidentally wipe fields
+        // they did not send.
+        var req struct {
+            Name             *string   `json:"name,omitempty"`
+            Type             *ZoneType `json:"type,omitempty"`
+            Pin              *int      `json:"pin,omitempty"`
+            Enabled          *bool     `json:"enabled,omitempty"`
+            Mode             *string   `json:"mode,omitempty"`
+            EntryExit        *bool     `json:"entry_exit,omitempty"`
+            Silent           *bool     `json:"silent,omitempty"`
+            SnapshotURL      *string   `json:"snapshot_url,omitempty"`
+            SnapshotUsername *string   `json:"snapshot_username,omitempty"`
+            SnapshotPassword *string   `json:"snapshot_password,omitempty"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "invalid JSON", http.StatusBadRequest)
+            return
+        }
+        // Bypassing, by changing enabled alone, is a runtime operation
+        // allowed with read_only_config.
+        if req.Enabled != nil && req.Name == nil && req.Type == nil && req.Pin == nil && req.Mode == nil && req.EntryExit == nil && req.Silent == nil && req.SnapshotURL == nil && req.SnapshotUsername == nil && req.SnapshotPassword == nil {
+            z, err := s.bypassZone(r, user, id, !*req.Enabled)
+            switch {
+            case err == errForbidden:
+                http.Error(w, "forbidden", http.StatusForbidden)
+            case errors.Is(err, errZoneNotFound):
+                http.Error(w, "not found", http.StatusNotFound)
+            case err != nil:
+                http.Error(w, "internal error", http.StatusInternalServerError)
+            default:
+                w.Header().Set("Content-Type", "application/json")
+                _ = json.NewEncoder(w).Encode(redactZone(z))
+            }
+            return
+        }
+        if !user.hasRole(RoleAdmin) {
+            http.Error(w, "forbidden", http.StatusForbidden)
+            return
+        }
+        if req.Name != nil || req.Type != nil || req.Pin != nil || req.Mode != nil || req.EntryExit != nil || req.Silent != nil || req.SnapshotURL != nil || req.SnapshotUsername != nil || req.SnapshotPassword != nil {
+            if s.configReadOnly(w) {
+                return
+            }
+        }
+        if req.Name != nil && *req.Name == "" {
+            http.Error(w, "missing name", http.StatusBadRequest)
+            return
+        }
+        if req.Pin != nil && *req.Pin == 0 {
+            http.Error(w, "missing pin", http.StatusBadRequest)
+            return
+        }
+        var changes []string
+        var before, updated Zone
+        err = s.cfgMgr.Update(func(c *Config) error {
+            for i, existing := range c.Zones {
+                if existing.ID != id {
+                    continue
+                }
+                z := existing
+                before = existing
+                if req.Name != nil && *req.Name != z.Name {
+                    changes = append(changes, fmt.Sprintf("name %q -> %q", z.Name, *req.Name))
+                    z.Name = *req.Name
+                }
+                if req.Type != nil && *req.Type != z.Type {
+                    changes = append(changes, fmt.Sprintf("type %s -> %s", z.Type, *req.Type))
+                    z.Type = *req.Type
+                }
+                if req.Pin != nil && *req.Pin != z.Pin {
+                    changes = append(changes, fmt.Sprintf("pin %d -> %d", z.Pin, *req.Pin))
+                    z.Pin = *req.Pin
+                }
+                if req.Enabled != nil && *req.Enabled != z.Enabled {
+                    changes = append(changes, fmt.Sprintf("enabled %t -> %t", z.Enabled, *req.Enabled))
+                    z.Enabled = *req.Enabled
+                }
+                if req.Mode != nil && *req.Mode != z.Mode {
+                    changes = append(changes, fmt.Sprintf("mode %q -> %q", z.Mode, *req.Mode))
+                    z.Mode = *req.Mode
+                }
+                if req.EntryExit != nil && *req.EntryExit != z.EntryExit {
+                    changes = append(changes, fmt.Sprintf("entry_exit %t -> %t", z.EntryExit, *req.EntryExit))
+                    z.EntryExit = *req.EntryExit
+                }
+                if req.Silent != nil && *req.Silent != z.Silent {
+                    changes = append(changes, fmt.Sprintf("silent %t -> %t", z.Silent, *req.Silent))
+                    z.Silent = *req.Silent
+                }
+                if req.SnapshotURL != nil && *req.SnapshotURL != z.SnapshotURL {
+                    changes = append(changes, fmt.Sprintf("snapshot_url %q -> %q", z.SnapshotURL, *req.SnapshotURL))
+                    z.SnapshotURL = *req.SnapshotURL
+                }
+                if req.SnapshotUsername != nil && *req.SnapshotUsername != z.SnapshotUsername {
+                    changes = append(changes, fmt.Sprintf("snapshot_username %q -> %q", z.SnapshotUsername, *req.SnapshotUsername))
+                    z.SnapshotUsername = *req.SnapshotUsername
+                }
+                if req.SnapshotPassword != nil && *req.SnapshotPassword != redactedSecret && *req.SnapshotPassword != z.SnapshotPassword {
+                    changes = append(changes, "snapshot_password changed")
+                    z.SnapshotPassword = *req.SnapshotPassword
+                }
+                c.Zones[i] = z
+                updated = z
+                return nil
+            }
+            return errors.New("not found")
+        })
+        if err != nil {
+            if err.Error() == "not found" {
+                http.Error(w, "not found", http.StatusNotFound)
+            } else {
+                http.Error(w, "internal error", http.StatusInternalServerError)
+            }
+            return
+        }
+        // A disabled zone is no longer monitored, so drop any stale trigger.
+        if !updated.Enabled {
+            s.triggerMu.Lock()
+            delete(s.triggered, id)
+            s.triggerMu.Unlock()
+        }
+        changed := len(changes) > 0
+        if !changed {
+            changes = append(changes, "no changes")
+        }
+        s.logger.Log("patch zone id=%d by %s: %s", id, user.Username, strings.Join(changes, ", "))
+        if changed {
+            s.audit(r, user.Username, "zone.update", fmt.Sprintf("zone %d", id), redactZone(before), redactZone(updated))
+            s.adminChange(user.Username, "zone id=%d changed: %s", id, strings.Join(changes, ", "))
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(redactZone(updated))
     default:
         http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
     }
@@ -615,7 +1355,7 @@ func (s *Server) handleZoneByID(w http.ResponseWriter, r *http.Request, user Use
 
 // handleUsers handles GET and POST on /api/users.  Only admins may manage users.
 func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request, user User) {
-    if !user.Admin {
+    if !user.hasRole(RoleAdmin) {
         http.Error(w, "forbidden", http.StatusForbidden)
         return
     }
@@ -624,20 +1364,62 @@ func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request, user User)
         cfg := s.cfgMgr.Get()
         // Do not expose password hashes to clients
         type userView struct {
-            Username string `json:"username"`
-            Admin    bool   `json:"admin"`
+            Username         string     `json:"username"`
+            Role             string     `json:"role"`
+            Admin            bool       `json:"admin"`
+            Disabled         bool       `json:"disabled"`
+            SessionLimitExempt bool     `json:"session_limit_exempt,omitempty"`
+            HasPIN           bool       `json:"has_pin"`
+            Directory        bool       `json:"ldap,omitempty"`
+            SSO              bool       `json:"oidc,omitempty"`
+            Proxy            bool       `json:"proxy_auth,omitempty"`
+            Email            string     `json:"email,omitempty"`
+            Phone            string     `json:"phone,omitempty"`
+            NotificationsOff bool       `json:"notifications_off"`
+            AllowedModes     []string   `json:"allowed_modes,omitempty"`
+            LastLogin        *time.Time `json:"last_login,omitempty"`
+            LastLoginIP      string     `json:"last_login_ip,omitempty"`
+            LastLoginVia     string     `json:"last_login_via,omitempty"`
         }
         users := make([]userView, len(cfg.Users))
         for i, u := range cfg.Users {
-            users[i] = userView{Username: u.Username, Admin: u.Admin}
+            u = s.withPendingLogin(u)
+            users[i] = userView{
+                Username:         u.Username,
+                Role:             u.Role,
+                Admin:            u.Admin,
+                Disabled:         u.Disabled,
+                SessionLimitExempt: u.SessionLimitExempt,
+                HasPIN:           u.PINHash != "",
+                Directory:        u.Directory,
+                SSO:              u.SSO,
+                Proxy:            u.Proxy,
+                Email:            u.Email,
+                Phone:            u.Phone,
+                NotificationsOff: u.NotificationsOff,
+                AllowedModes:     u.AllowedModes,
+                LastLogin:        u.LastLogin,
+                LastLoginIP:      u.LastLoginIP,
+                LastLoginVia:     u.LastLoginVia,
+            }
         }
         w.Header().Set("Content-Type", "application/json")
         _ = json.NewEncoder(w).Encode(users)
     case http.MethodPost:
+        if s.configReadOnly(w) {
+            return
+        }
         var req struct {
-            Username string `json:"username"`
-            Password string `json:"password"`
-            Admin    bool   `json:"admin"`
+            Username         string   `json:"username"`
+            Password         string   `json:"password"`
+            Role             string   `json:"role"`
+            Admin            bool     `json:"admin"`
+            AllowedModes     []string `json:"allowed_modes"`
+            PIN              string   `json:"pin"`
+            Email            string   `json:"email"`
+            Phone            string   `json:"phone"`
+            NotificationsOff bool     `json:"notifications_off"`
+            SessionLimitExempt bool   `json:"session_limit_exempt"`
         }
         if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
             http.Error(w, "invalid JSON", http.StatusBadRequest)
@@ -647,14 +1429,68 @@ func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request, user User)
             http.Error(w, "missing username or password", http.StatusBadRequest)
             return
         }
-        err := s.cfgMgr.Update(func(c *Config) error {
+        if err := validatePassword(req.Password, req.Username, s.cfgMgr.Get().PasswordPolicy); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        // Clients that predate roles send only the admin flag.
+        if req.Role == "" {
+            req.Role = RoleOperator
+            if req.Admin {
+                req.Role = RoleAdmin
+            }
+        }
+        if _, ok := roleRanks[req.Role]; !ok {
+            http.Error(w, "role must be viewer, operator or admin", http.StatusBadRequest)
+            return
+        }
+        req.Admin = req.Role == RoleAdmin
+        if err := validateAllowedModes(req.AllowedModes, s.cfgMgr.Get().ArmModes); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if err := validateContact(req.Email, req.Phone); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        var pinHash string
+        var err error
+        if req.PIN != "" {
+            if err := s.checkNewPIN(req.PIN); err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+            if pinHash, err = hashPassword(req.PIN); err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+        }
+        passwordHash, err := hashPassword(req.Password)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        var created User
+        err = s.cfgMgr.Update(func(c *Config) error {
             // Check for duplicate username
             for _, u := range c.Users {
                 if u.Username == req.Username {
                     return errors.New("exists")
                 }
             }
-            c.Users = append(c.Users, User{Username: req.Username, PasswordHash: hashPassword(req.Password), Admin: req.Admin})
+            created = User{
+                Username:     req.Username,
+                PasswordHash: passwordHash,
+                Role:         req.Role,
+                Admin:        req.Admin,
+                AllowedModes: req.AllowedModes,
+                PINHash:      pinHash,
+                Email:        req.Email,
+                Phone:        req.Phone,
+                NotificationsOff: req.NotificationsOff,
+                SessionLimitExempt: req.SessionLimitExempt,
+            }
+            c.Users = append(c.Users, created)
             return nil
         })
         if err != nil {
@@ -666,6 +1502,8 @@ func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request, user User)
             return
         }
         s.logger.Log("create user %s by %s", req.Username, user.Username)
+        s.audit(r, user.Username, "user.create", req.Username, nil, auditUser(created))
+        s.adminChange(user.Username, "user %s created", req.Username)
         // Return the created user (without password) as JSON.  A status of
         // 201 indicates successful creation and prevents the front‑end from
         // attempting to parse an empty response body.
@@ -673,82 +1511,236 @@ func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request, user User)
         w.WriteHeader(http.StatusCreated)
         _ = json.NewEncoder(w).Encode(struct {
             Username string `json:"username"`
+            Role     string `json:"role"`
             Admin    bool   `json:"admin"`
-        }{Username: req.Username, Admin: req.Admin})
+        }{Username: req.Username, Role: req.Role, Admin: req.Admin})
     default:
         http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
     }
 }
 
-// handleUserByID handles PUT/DELETE on /api/users/{username}.
+// handleUserByID handles PUT/DELETE on /api/users/{username}.  Users other
+// than admins may only PUT their own record to change their PIN, contact
+// details and notification preference.
 func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request, user User) {
-    if !user.Admin {
-        http.Error(w, "forbidden", http.StatusForbidden)
-        return
-    }
     parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
     if len(parts) < 3 {
         http.NotFound(w, r)
         return
     }
     username := parts[2]
+    if !user.hasRole(RoleAdmin) && !(r.Method == http.MethodPut && username == user.Username) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
     switch r.Method {
     case http.MethodPut:
+        if s.configReadOnly(w) {
+            return
+        }
         var req struct {
-            Password *string `json:"password,omitempty"`
-            Admin    *bool   `json:"admin,omitempty"`
+            Password         *string   `json:"password,omitempty"`
+            Role             *string   `json:"role,omitempty"`
+            Admin            *bool     `json:"admin,omitempty"`
+            Disabled         *bool     `json:"disabled,omitempty"`
+            // AllowedModes replaces the user's list when present; an empty
+            // list allows every mode.
+            AllowedModes     *[]string `json:"allowed_modes,omitempty"`
+            // PIN sets the keypad PIN; an empty string removes it.
+            PIN              *string   `json:"pin,omitempty"`
+            // CurrentPassword confirms a user changing their own PIN.
+            CurrentPassword  string    `json:"current_password,omitempty"`
+            Email            *string   `json:"email,omitempty"`
+            Phone            *string   `json:"phone,omitempty"`
+            NotificationsOff *bool     `json:"notifications_off,omitempty"`
+            SessionLimitExempt *bool   `json:"session_limit_exempt,omitempty"`
         }
         if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
             http.Error(w, "invalid JSON", http.StatusBadRequest)
             return
         }
+        if !user.hasRole(RoleAdmin) {
+            if req.Password != nil || req.Role != nil || req.Admin != nil || req.Disabled != nil || req.AllowedModes != nil || req.SessionLimitExempt != nil {
+                http.Error(w, "forbidden", http.StatusForbidden)
+                return
+            }
+            if req.PIN != nil {
+                if _, err := s.cfgMgr.Authenticate(user.Username, req.CurrentPassword); err != nil {
+                    http.Error(w, "current password is incorrect", http.StatusForbidden)
+                    return
+                }
+            }
+        }
+        target, _ := s.cfgMgr.FindUser(username)
+        email, phone := target.Email, target.Phone
+        if req.Email != nil {
+            email = *req.Email
+        }
+        if req.Phone != nil {
+            phone = *req.Phone
+        }
+        if err := validateContact(email, phone); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        var pinHash string
+        if req.PIN != nil && *req.PIN != "" {
+            if err := s.checkNewPIN(*req.PIN); err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+            var err error
+            if pinHash, err = hashPassword(*req.PIN); err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+        }
+        if req.Role != nil {
+            if _, ok := roleRanks[*req.Role]; !ok {
+                http.Error(w, "role must be viewer, operator or admin", http.StatusBadRequest)
+                return
+            }
+        }
+        var passwordHash string
+        if req.Password != nil {
+            if err := validatePassword(*req.Password, username, s.cfgMgr.Get().PasswordPolicy); err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+            var err error
+            if passwordHash, err = hashPassword(*req.Password); err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+        }
+        if req.AllowedModes != nil {
+            if err := validateAllowedModes(*req.AllowedModes, s.cfgMgr.Get().ArmModes); err != nil {
+                http.Error(w, err.Error(), http.StatusBadRequest)
+                return
+            }
+        }
+        var before, after User
         err := s.cfgMgr.Update(func(c *Config) error {
             for i, u := range c.Users {
                 if u.Username == username {
+                    before = u
                     if req.Password != nil {
-                        c.Users[i].PasswordHash = hashPassword(*req.Password)
+                        c.Users[i].PasswordHash = passwordHash
+                        // A password set by an admin is only a temporary
+                        // one for its owner.
+                        c.Users[i].MustChangePassword = username != user.Username
+                    }
+                    // Clients that predate roles send only the admin
+                    // flag, which promotes to admin or demotes an admin
+                    // to operator, leaving other roles as they are.
+                    role := req.Role
+                    if role == nil && req.Admin != nil && *req.Admin != (u.Role == RoleAdmin) {
+                        legacy := RoleOperator
+                        if *req.Admin {
+                            legacy = RoleAdmin
+                        }
+                        role = &legacy
+                    }
+                    if role != nil {
+                        c.Users[i].Role = *role
+                        c.Users[i].Admin = *role == RoleAdmin
+                    }
+                    if req.Disabled != nil {
+                        if *req.Disabled && !u.Disabled {
+                            c.Users[i].Tokens = nil
+                        }
+                        c.Users[i].Disabled = *req.Disabled
+                    }
+                    if req.AllowedModes != nil {
+                        c.Users[i].AllowedModes = *req.AllowedModes
+                    }
+                    if req.PIN != nil {
+                        c.Users[i].PINHash = pinHash
+                    }
+                    if req.Email != nil {
+                        c.Users[i].Email = *req.Email
+                    }
+                    if req.Phone != nil {
+                        c.Users[i].Phone = *req.Phone
                     }
-                    if req.Admin != nil {
-                        c.Users[i].Admin = *req.Admin
+                    if req.NotificationsOff != nil {
+                        c.Users[i].NotificationsOff = *req.NotificationsOff
                     }
+                    if req.SessionLimitExempt != nil {
+                        c.Users[i].SessionLimitExempt = *req.SessionLimitExempt
+                    }
+                    after = c.Users[i]
                     return nil
                 }
             }
             return errors.New("not found")
         })
         if err != nil {
-            if err.Error() == "not found" {
+            switch {
+            case err == errLastAdmin:
+                http.Error(w, err.Error(), http.StatusConflict)
+            case err.Error() == "not found":
                 http.Error(w, "not found", http.StatusNotFound)
-            } else {
+            default:
                 http.Error(w, "internal error", http.StatusInternalServerError)
             }
             return
         }
         s.logger.Log("update user %s by %s", username, user.Username)
+        action := "user.update"
+        if req.Password != nil {
+            action = "user.password_reset"
+        }
+        s.audit(r, user.Username, action, username, auditUser(before), auditUser(after))
+        if before.Role != after.Role {
+            s.adminChange(user.Username, "user %s role changed from %s to %s", username, before.Role, after.Role)
+        }
+        if req.PIN != nil {
+            s.logger.Log("PIN of %s changed by %s", username, user.Username)
+        }
+        if req.Disabled != nil {
+            if *req.Disabled {
+                n, _ := s.endUserSessions(username)
+                s.logger.Log("disable user %s by %s (%d sessions ended)", username, user.Username, n)
+                s.adminChange(user.Username, "user %s disabled", username)
+            } else {
+                s.logger.Log("enable user %s by %s", username, user.Username)
+                s.adminChange(user.Username, "user %s enabled", username)
+            }
+        }
         w.WriteHeader(http.StatusNoContent)
     case http.MethodDelete:
-        if username == "admin" {
-            http.Error(w, "cannot delete default admin", http.StatusBadRequest)
+        if s.configReadOnly(w) {
             return
         }
+        var removed User
         err := s.cfgMgr.Update(func(c *Config) error {
             for i, u := range c.Users {
                 if u.Username == username {
-                    c.Users = append(c.Users[:i], c.Users[i+1:]...)
+                    removed = u
+                    c.Users = append(c.Users[:i:i], c.Users[i+1:]...)
                     return nil
                 }
             }
             return errors.New("not found")
         })
         if err != nil {
-            if err.Error() == "not found" {
+            switch {
+            case err == errLastAdmin:
+                http.Error(w, err.Error(), http.StatusConflict)
+            case err.Error() == "not found":
                 http.Error(w, "not found", http.StatusNotFound)
-            } else {
+            default:
                 http.Error(w, "internal error", http.StatusInternalServerError)
             }
             return
         }
-        s.logger.Log("delete user %s by %s", username, user.Username)
+        // Sessions and devices are ended, or a user later created with the
+        // same name would inherit them.
+        n, _ := s.endUserSessions(username)
+        s.logger.Log("delete user %s by %s (%d sessions ended)", username, user.Username, n)
+        s.audit(r, user.Username, "user.delete", username, auditUser(removed), nil)
+        s.adminChange(user.Username, "user %s deleted", username)
         w.WriteHeader(http.StatusNoContent)
     default:
         http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -760,10 +1752,23 @@ func (s *Server) handleArmModes(w http.ResponseWriter, r *http.Request, user Use
     switch r.Method {
     case http.MethodGet:
         cfg := s.cfgMgr.Get()
+        // Each mode is flagged with whether the requesting user may select
+        // it, so the UI only offers buttons that will work.
+        type armModeView struct {
+            ArmMode
+            Allowed bool `json:"allowed"`
+        }
+        modes := make([]armModeView, len(cfg.ArmModes))
+        for i, am := range cfg.ArmModes {
+            modes[i] = armModeView{ArmMode: am, Allowed: user.mayArm(am.Name)}
+        }
         w.Header().Set("Content-Type", "application/json")
-        _ = json.NewEncoder(w).Encode(cfg.ArmModes)
+        _ = json.NewEncoder(w).Encode(modes)
     case http.MethodPost:
-        if !user.Admin {
+        if s.configReadOnly(w) {
+            return
+        }
+        if !user.hasRole(RoleAdmin) {
             http.Error(w, "forbidden", http.StatusForbidden)
             return
         }
@@ -776,10 +1781,12 @@ func (s *Server) handleArmModes(w http.ResponseWriter, r *http.Request, user Use
             http.Error(w, "missing name", http.StatusBadRequest)
             return
         }
+        var before *ArmMode
         err := s.cfgMgr.Update(func(c *Config) error {
             // Replace existing with same name or append new
             for i, am := range c.ArmModes {
                 if strings.EqualFold(am.Name, req.Name) {
+                    before = &am
                     c.ArmModes[i] = req
                     return nil
                 }
@@ -792,6 +1799,12 @@ func (s *Server) handleArmModes(w http.ResponseWriter, r *http.Request, user Use
             return
         }
         s.logger.Log("update arm mode %s by %s", req.Name, user.Username)
+        if before != nil {
+            s.audit(r, user.Username, "arm_mode.update", req.Name, before, req)
+        } else {
+            s.audit(r, user.Username, "arm_mode.create", req.Name, nil, req)
+        }
+        s.adminChange(user.Username, "arm mode %s updated", req.Name)
         // Return the created or updated arm mode as JSON with status 201.  This
         // avoids sending an empty body, which would cause the front‑end to
         // attempt to parse an empty response and yield a JSON error.
@@ -803,12 +1816,17 @@ func (s *Server) handleArmModes(w http.ResponseWriter, r *http.Request, user Use
     }
 }
 
-// handleLogs returns the event log.  Admins only.  Accepts optional query parameter `lines=n` to limit number of lines returned.
+// handleLogs returns the most recent entries of the event log, newest
+// first.  Admins see every entry; other users see only the entries naming
+// them.  Accepts optional query parameter `lines=n` to limit number of lines
+// returned, `before` to page back from X-Next-Before of an earlier response,
+// and the filters type, zone, user, from, to and q (see logFilter).
+// Rotated logs are read on from the current one as needed.  With a filter
+// the number of matching entries is sent as X-Total-Count, which needs
+// every log to be read; without one only the lines returned are.
+// since_id instead returns the entries with a higher event ID, oldest
+// first, with X-Next-Since set if there are more than lines.
 func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request, user User) {
-    if !user.Admin {
-        http.Error(w, "forbidden", http.StatusForbidden)
-        return
-    }
     linesParam := r.URL.Query().Get("lines")
     limit := 200
     if linesParam != "" {
@@ -816,24 +1834,73 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request, user User) {
             limit = n
         }
     }
-    cfg := s.cfgMgr.Get()
-    data, err := os.ReadFile(cfg.LogFile)
+    filter, err := parseLogFilter(r.URL.Query())
     if err != nil {
-        http.Error(w, "log not found", http.StatusNotFound)
+        http.Error(w, err.Error(), http.StatusBadRequest)
         return
     }
-    allLines := strings.Split(string(data), "\n")
-    // Drop empty trailing line
-    if len(allLines) > 0 && allLines[len(allLines)-1] == "" {
-        allLines = allLines[:len(allLines)-1]
+    // Users other than admins only see their own actions.
+    keep := func(line string) bool {
+        return user.hasRole(RoleAdmin) || mentionsUser(line, user.Username)
+    }
+    s.logger.Flush()
+    if v := r.URL.Query().Get("since_id"); v != "" {
+        since, err := strconv.ParseUint(v, 10, 64)
+        if err != nil {
+            http.Error(w, "invalid since_id", http.StatusBadRequest)
+            return
+        }
+        lines, more, err := readLogsSince(s.cfgMgr.resolve(s.cfgMgr.Get().LogFile), filter, keep, limit, since)
+        if err != nil {
+            if os.IsNotExist(err) {
+                http.Error(w, "log not found", http.StatusNotFound)
+            } else {
+                http.Error(w, "internal error", http.StatusInternalServerError)
+            }
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        if more {
+            e, _ := parseLogLine(lines[len(lines)-1])
+            w.Header().Set("X-Next-Since", strconv.FormatUint(e.ID, 10))
+        }
+        _ = json.NewEncoder(w).Encode(localLogLines(lines))
+        return
     }
-    start := 0
-    if len(allLines) > limit {
-        start = len(allLines) - limit
+    page, err := readLogs(s.cfgMgr.resolve(s.cfgMgr.Get().LogFile), filter, keep, limit, r.URL.Query().Get("before"))
+    if err != nil {
+        switch {
+        case os.IsNotExist(err):
+            http.Error(w, "log not found", http.StatusNotFound)
+        case errors.Is(err, errInvalidCursor):
+            http.Error(w, err.Error(), http.StatusBadRequest)
+        default:
+            http.Error(w, "internal error", http.StatusInternalServerError)
+        }
+        return
     }
-    lines := allLines[start:]
     w.Header().Set("Content-Type", "application/json")
-    _ = json.NewEncoder(w).Encode(lines)
+    if page.Counted {
+        w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+    }
+    if page.Next != "" {
+        w.Header().Set("X-Next-Before", page.Next)
+    }
+    _ = json.NewEncoder(w).Encode(localLogLines(page.Lines))
+}
+
+// mentionsUser reports whether a log line names username as a whole word,
+// as in "arm Away by alice" or "login alice".
+func mentionsUser(line, username string) bool {
+    words := strings.FieldsFunc(line, func(r rune) bool {
+        return r == ' ' || r == ',' || r == ':' || r == '(' || r == ')' || r == '"'
+    })
+    for _, w := range words {
+        if w == username {
+            return true
+        }
+    }
+    return false
 }
 
 // handleTestTrigger allows an admin to simulate a zone trigger while in
@@ -847,6 +1914,10 @@ func (s *Server) handleTestTrigger(w http.ResponseWriter, r *http.Request, user
         http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
         return
     }
+    if !user.hasRole(RoleOperator) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
     if s.testMode != 1 {
         http.Error(w, "not in TestSoft mode", http.StatusBadRequest)
         return
@@ -878,11 +1949,7 @@ func (s *Server) handleTestTrigger(w http.ResponseWriter, r *http.Request, user
         s.logger.Log("test trigger zone id=%d (%s) by %s", zone.ID, zone.Name, user.Username)
         // Invoke all alert handlers even in TestSoft mode to allow testing the
         // configured notifications.  Errors are logged but do not propagate.
-        for _, h := range s.alerts {
-            if err := h.Send(*zone, s.logger); err != nil {
-                s.logger.Log("alert handler %s error: %v", h.Name(), err)
-            }
-        }
+        s.sendAlerts(s.newAlertEvent(EventTest, *zone, user.Username))
     } else {
         s.triggerMu.Unlock()
     }
@@ -893,44 +1960,51 @@ func (s *Server) handleTestTrigger(w http.ResponseWriter, r *http.Request, user
 // in the current arm mode.  When a new trigger is detected, it logs the event
 // and notifies configured alert handlers.  In TestWiring mode the alert
 // handlers are suppressed, but triggers are still logged.  The loop sleeps
-// briefly between iterations to reduce CPU usage.  It relies on readPin and
-// zoneTriggered defined in hal.go and sensor.go.
+// briefly between iterations to reduce CPU usage, and returns when the
+// server stops.  It relies on readPin and sense defined in hal.go and
+// sensor.go.
 func (s *Server) pollSensors() {
+    ticker := time.NewTicker(200 * time.Millisecond)
+    defer ticker.Stop()
     for {
-        time.Sleep(200 * time.Millisecond)
+        select {
+        case <-ticker.C:
+        case <-s.stop:
+            return
+        }
         // Skip polling when disarmed or in TestSoft mode
         if s.currentMode == "Disarmed" || s.testMode == 1 {
             continue
         }
-        cfg := s.cfgMgr.Get()
-        var activeIDs []int
-        if s.testMode == 2 {
-            // In wiring test, monitor all zones
-            for _, z := range cfg.Zones {
-                activeIDs = append(activeIDs, z.ID)
-            }
+        // The enabled zones to poll are kept up to date by watchConfig.
+        idx := s.zones.Load()
+        var active []Zone
+        if s.testMode == 2 || s.testMode == 3 {
+            // In wiring and walk tests, monitor all zones
+            active = idx.all
         } else {
             // Find active zones for the current mode (pendingMode acts as normal until exit delay completes)
+            // During an alarm the zones of the armed mode stay watched.
             modeName := s.currentMode
-            if s.currentMode == "ExitDelay" {
+            switch s.currentMode {
+            case "ExitDelay":
                 modeName = s.pendingMode
+            case "Alarm":
+                modeName = s.alarmMode
             }
-            for _, am := range cfg.ArmModes {
-                if strings.EqualFold(am.Name, modeName) {
-                    activeIDs = am.ActiveZones
-                    break
-                }
-            }
+            active = idx.modes[strings.ToLower(modeName)]
         }
-        for _, id := range activeIDs {
-            var zone *Zone
-            for i := range cfg.Zones {
-                if cfg.Zones[i].ID == id {
-                    zone = &cfg.Zones[i]
-                    break
+        for i := range active {
+            zone := &active[i]
+            // During a walk test only record the first activation of each
+            // zone; no alerts, delays or alarms are raised.
+            if s.testMode == 3 {
+                if s.sense(*zone) {
+                    s.recordWalkTest(*zone)
                 }
+                continue
             }
-            if zone == nil || !zone.Enabled {
+            if s.awaitingRestore(*zone) {
                 continue
             }
             // When an exit delay is active, check for early completion: if
@@ -939,11 +2013,12 @@ func (s *Server) pollSensors() {
             if s.exitTimer != nil {
                 if zone.EntryExit {
                     // If the entry/exit sensor reads closed (not triggered), finish the exit delay
-                    if !zoneTriggered(*zone) {
+                    if !s.sense(*zone) {
                         s.completeExitDelay()
                     }
                 }
                 // Skip processing triggers during exit delay
+                s.logger.Debug("zone id=%d not monitored during exit delay", zone.ID)
                 continue
             }
             // If an entry delay is active: any trigger on a non-entry/exit zone
@@ -952,9 +2027,10 @@ func (s *Server) pollSensors() {
             if s.entryTimer != nil {
                 if zone.EntryExit {
                     // ignore triggers during entry delay on entry/exit zone
+                    s.logger.Debug("zone id=%d not monitored during entry delay", zone.ID)
                     continue
                 }
-                if zoneTriggered(*zone) {
+                if s.sense(*zone) {
                     // immediate alarm
                     s.triggerMu.Lock()
                     s.triggered[zone.ID] = true
@@ -965,13 +2041,14 @@ func (s *Server) pollSensors() {
             }
             // Normal armed operation (no delays): if entry/exit sensor triggers,
             // start entry delay.  Otherwise handle trigger normally.
-            if zone.EntryExit {
-                if zoneTriggered(*zone) {
-                    s.startEntryDelay()
+            // During an alarm there is no entry delay to give.
+            if zone.EntryExit && !s.alarm {
+                if s.sense(*zone) {
+                    s.startEntryDelay(*zone)
                 }
                 continue
             }
-            if zoneTriggered(*zone) {
+            if s.sense(*zone) {
                 s.triggerMu.Lock()
                 already := s.triggered[zone.ID]
                 if !already {
@@ -980,17 +2057,17 @@ func (s *Server) pollSensors() {
                     s.logger.Log("trigger zone id=%d (%s)", zone.ID, zone.Name)
                     // Only send alerts if not in wiring test mode
                     if s.testMode == 0 {
-                        for _, h := range s.alerts {
-                            if err := h.Send(*zone, s.logger); err != nil {
-                                s.logger.Log("alert handler %s error: %v", h.Name(), err)
-                            }
-                        }
+                        s.sendAlerts(s.newAlertEvent(EventTrigger, *zone, ""))
                     }
                     // Triggering a non entry/exit zone immediately causes alarm
-                    // Trigger an immediate alarm; include zone name in reason
-                    s.triggerAlarm(fmt.Sprintf("zone %s triggered", zone.Name))
+                    // Trigger an immediate alarm; include zone name in reason.
+                    // Silent zones notify but never raise the local alarm.
+                    if !zone.Silent {
+                        s.triggerAlarm(fmt.Sprintf("zone %s triggered", zone.Name))
+                    }
                 } else {
                     s.triggerMu.Unlock()
+                    s.logger.Debug("zone id=%d still triggered, already reported", zone.ID)
                 }
             }
         }
@@ -998,32 +2075,122 @@ func (s *Server) pollSensors() {
 }
 
 // initAlertHandlers constructs a slice of AlertHandler instances from the
-// provided configuration.  If cfg.Alerts is empty, a single LogAlert is
-// returned to ensure that triggered events are always recorded.  The logger
-// parameter is passed to handlers that need to log internal diagnostics.
-func initAlertHandlers(cfg Config, logger *EventLogger) []AlertHandler {
+// provided configuration, together with the ID of the alert configuration
+// each handler was built from.  If cfg.Alerts is empty, a single LogAlert
+// (ID 0) is returned to ensure that triggered events are always recorded.
+// Entries with an unknown type are skipped.  The logger parameter is passed
+// to handlers that need to log internal diagnostics.  MQTT handlers publish
+// through the shared mqtt connection when it is non-nil.  Email, SMS and
+// voice handlers with NotifyUsers also contact the users returned by users.
+func initAlertHandlers(cfg Config, logger *EventLogger, mqtt *MQTTClient, users userDirectory) ([]AlertHandler, []int) {
     if len(cfg.Alerts) == 0 {
-        return []AlertHandler{LogAlert{}}
+        return []AlertHandler{LogAlert{}}, []int{0}
     }
     var handlers []AlertHandler
+    var ids []int
     for _, ac := range cfg.Alerts {
+        if ac.Timeout == 0 {
+            ac.Timeout = cfg.AlertTimeout
+        }
+        // Templates are validated when the configuration is loaded, so an
+        // error here only occurs if config.json was edited underneath us.
+        tmpl, err := newAlertTemplates(ac)
+        if err != nil {
+            logger.Log("alert %s template error, using default wording: %v", ac.Type, err)
+        }
+        var h AlertHandler
         switch strings.ToLower(ac.Type) {
         case "log":
-            handlers = append(handlers, LogAlert{})
+            h = LogAlert{templates: tmpl}
         case "email":
-            handlers = append(handlers, EmailAlert{
+            email := EmailAlert{
                 SMTPServer: ac.SMTPServer,
                 SMTPPort:   ac.SMTPPort,
                 Username:   ac.Username,
                 Password:   ac.Password,
                 From:       ac.From,
                 To:         ac.To,
+                Cc:         ac.Cc,
+                Bcc:        ac.Bcc,
                 Subject:    ac.Subject,
-            })
+                TLSMode:    ac.TLSMode,
+                CAFile:     ac.CAFile,
+                Timeout:    alertTimeout(ac),
+                templates:  tmpl,
+            }
+            if ac.NotifyUsers {
+                email.Users = users
+            }
+            h = email
+        case "webhook":
+            h = NewWebhookAlert(ac, tmpl)
+        case "sms":
+            sms := NewSMSAlert(ac, tmpl)
+            if ac.NotifyUsers {
+                sms.Users = users
+            }
+            h = sms
+        case "voice":
+            voice := NewVoiceAlert(ac, tmpl)
+            if ac.NotifyUsers {
+                voice.Users = users
+            }
+            h = voice
+        case "ntfy":
+            h = NewNtfyAlert(ac, tmpl)
+        case "gotify":
+            h = NewGotifyAlert(ac, tmpl)
+        case "slack":
+            h = NewSlackAlert(ac, tmpl)
+        case "discord":
+            h = NewDiscordAlert(ac, tmpl)
+        case "signal":
+            h = NewSignalAlert(ac, tmpl)
+        case "matrix":
+            h = NewMatrixAlert(ac, tmpl)
+        case "pushbullet":
+            h = NewPushbulletAlert(ac, tmpl, cfg.BaseURL)
+        case "mqtt":
+            h = NewMQTTAlert(ac, tmpl, mqtt, logger)
+        default:
+            continue
         }
+        handlers = append(handlers, h)
+        ids = append(ids, ac.ID)
     }
     if len(handlers) == 0 {
         handlers = append(handlers, LogAlert{})
+        ids = append(ids, 0)
+    }
+    return handlers, ids
+}
+
+// reloadAlerts rebuilds the alert handlers from cfg so that changes to
+// the alerts take effect without a restart.  Retries still queued for the
+// old handlers pass to their successors, and connections of their own are
+// closed.
+func (s *Server) reloadAlerts(cfg Config) {
+    handlers, ids := initAlertHandlers(cfg, s.logger, s.mqtt, s.currentUsers)
+    s.alertsMu.Lock()
+    old := s.alerts
+    s.alerts = handlers
+    s.alertsMu.Unlock()
+    s.alertQueue.setHandlers(handlers, ids, cfg)
+    closeAlertHandlers(old)
+}
+
+// alertCloser is implemented by handlers holding a connection of their own,
+// such as MQTT handlers without the shared broker connection.
+type alertCloser interface {
+    Close()
+}
+
+// closeAlertHandlers releases the connections held by handlers that have
+// been replaced or are no longer needed.
+func closeAlertHandlers(handlers []AlertHandler) {
+    for _, h := range handlers {
+        if c, ok := h.(alertCloser); ok {
+            c.Close()
+        }
     }
-    return handlers
 }
\ No newline at end of file
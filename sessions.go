@@ -0,0 +1,332 @@
+package main
+
+// This file persists sessions so that wall tablets and phones stay logged
+// in across restarts, expires and purges them, and lets users list and
+// revoke them.  Only the SHA-256 hash of each session ID is written, with
+// its username and expiry; a copy of the file cannot be replayed as a
+// cookie.
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "net"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+)
+
+// sessionsPath is the file sessions are persisted to.
+const sessionsPath = "sessions.json"
+
+// defaultSessionTTL is the lifetime of a session if Config.SessionTTL is
+// not set.
+const defaultSessionTTL = 24 * time.Hour
+
+// sessionPurgeInterval is how often expired sessions are removed.  It is
+// a variable so that tests can shorten it.
+var sessionPurgeInterval = 5 * time.Minute
+
+// sessionLimits returns the absolute session lifetime and the idle timeout
+// (zero for none) configured in cfg.
+func sessionLimits(cfg Config) (time.Duration, time.Duration) {
+    ttl := defaultSessionTTL
+    if cfg.SessionTTL > 0 {
+        ttl = time.Duration(cfg.SessionTTL) * time.Second
+    }
+    return ttl, time.Duration(cfg.IdleTimeout) * time.Second
+}
+
+// Values of Config.SessionLimitPolicy.
+const (
+    SessionLimitEvict  = "evict"
+    SessionLimitReject = "reject"
+)
+
+// validateSessionLimits checks session_ttl, idle_timeout and the
+// per-user session limit.
+func validateSessionLimits(cfg Config) error {
+    if cfg.SessionTTL < 0 || cfg.IdleTimeout < 0 || cfg.MaxSessionsPerUser < 0 || cfg.RememberTTL < 0 {
+        return errors.New("session_ttl, idle_timeout, max_sessions_per_user and remember_ttl must not be negative")
+    }
+    switch cfg.SessionLimitPolicy {
+    case "", SessionLimitEvict, SessionLimitReject:
+    default:
+        return fmt.Errorf("unknown session_limit_policy %q (expected evict or reject)", cfg.SessionLimitPolicy)
+    }
+    return nil
+}
+
+// validateSessionBinding checks the session_binding section.
+func validateSessionBinding(sb *SessionBinding) error {
+    if sb == nil {
+        return nil
+    }
+    if sb.IPv4Prefix < 0 || sb.IPv4Prefix > 32 || sb.IPv6Prefix < 0 || sb.IPv6Prefix > 128 {
+        return errors.New("session_binding ipv4_prefix must be 0 to 32 and ipv6_prefix 0 to 128")
+    }
+    return nil
+}
+
+// sameNetwork reports whether the addresses a and b share their first v4
+// (for IPv4) or v6 (for IPv6) bits.  Zero prefixes mean the whole address.
+func sameNetwork(a, b string, v4, v6 int) bool {
+    ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+    if ipA == nil || ipB == nil {
+        return a == b
+    }
+    if ipA.To4() != nil && ipB.To4() != nil {
+        if v4 == 0 {
+            v4 = 32
+        }
+        mask := net.CIDRMask(v4, 32)
+        return ipA.To4().Mask(mask).Equal(ipB.To4().Mask(mask))
+    }
+    if v6 == 0 {
+        v6 = 128
+    }
+    mask := net.CIDRMask(v6, 128)
+    return ipA.To16().Mask(mask).Equal(ipB.To16().Mask(mask))
+}
+
+// sessionMismatch compares the client of r with the one that started sess
+// under the binding sb, and describes the difference, or returns "" if
+// the request may use the session.
+func (s *Server) sessionMismatch(sb *SessionBinding, sess Session, r *http.Request) string {
+    if sb == nil {
+        return ""
+    }
+    if sb.IP {
+        if ip := s.clientIP(r); !sameNetwork(ip, sess.IP, sb.IPv4Prefix, sb.IPv6Prefix) {
+            return fmt.Sprintf("client address %s, logged in from %s", ip, sess.IP)
+        }
+    }
+    if sb.UserAgent && r.UserAgent() != sess.UserAgent {
+        return fmt.Sprintf("user agent %q, logged in with %q", r.UserAgent(), sess.UserAgent)
+    }
+    return ""
+}
+
+// userSessionLimit returns the session limit that applies to user under
+// cfg.
+func userSessionLimit(cfg Config, user User) sessionLimit {
+    if user.SessionLimitExempt {
+        return sessionLimit{}
+    }
+    return sessionLimit{Max: cfg.MaxSessionsPerUser, Evict: cfg.SessionLimitPolicy != SessionLimitReject}
+}
+
+// hashSessionID returns the key a session is stored under.
+func hashSessionID(id string) string {
+    sum := sha256.Sum256([]byte(id))
+    return hex.EncodeToString(sum[:])
+}
+
+// load restores the sessions saved by a previous run, dropping expired
+// ones.  A missing or unreadable file starts with no sessions.
+func (sm *SessionManager) load() {
+    data, err := ioutil.ReadFile(sm.path)
+    if err != nil {
+        if !os.IsNotExist(err) {
+            sm.logger.Log("unable to read %s: %v", sm.path, err)
+        }
+        return
+    }
+    var saved map[string]Session
+    if err := json.Unmarshal(data, &saved); err != nil {
+        sm.logger.Log("ignoring invalid %s: %v", sm.path, err)
+        return
+    }
+    now := time.Now()
+    for key, s := range saved {
+        if now.Before(s.Expires) && s.Username != "" && s.ID != "" && s.CSRF != "" && !s.Created.IsZero() {
+            sm.addLocked(key, s)
+        }
+    }
+    sm.logger.Log("restored %d sessions from %s", len(sm.sessions), sm.path)
+}
+
+// saveLocked writes the sessions to disk if persistence is enabled.  The
+// caller must hold sm.mu.  Failures are logged; the sessions remain valid
+// in memory.
+func (sm *SessionManager) saveLocked() {
+    if sm.path == "" {
+        return
+    }
+    sm.dirty = false
+    data, err := json.MarshalIndent(sm.sessions, "", "  ")
+    if err == nil {
+        tmpPath := sm.path + ".tmp"
+        if err = ioutil.WriteFile(tmpPath, data, 0600); err == nil {
+            err = os.Rename(tmpPath, sm.path)
+        }
+    }
+    if err != nil {
+        sm.logger.Log("unable to save %s: %v", sm.path, err)
+    }
+}
+
+// sessionStorePath returns path, the file sessions or remembered devices
+// are persisted to, or "" if cfg disables persistence.  Disabling it
+// removes any file left by an earlier run.
+func sessionStorePath(cfg Config, path string) string {
+    if cfg.PersistSessions != nil && !*cfg.PersistSessions {
+        os.Remove(path)
+        return ""
+    }
+    return path
+}
+
+// sessionPurgeLoop removes expired sessions and remembered devices every
+// sessionPurgeInterval until
+// the server is stopped, so that the store does not grow without bound.
+// Sessions extended since the last save are written at the same time, and
+// once more when the loop stops.
+func (s *Server) sessionPurgeLoop() {
+    ticker := time.NewTicker(sessionPurgeInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ticker.C:
+            s.sessions.Purge()
+            s.devices.Purge()
+        case <-s.stop:
+            s.sessions.Purge()
+            return
+        }
+    }
+}
+
+// sessionView is a session as listed by the API.  Current marks the
+// session the request was made with.
+type sessionView struct {
+    ID        string    `json:"id"`
+    Username  string    `json:"username"`
+    Created   time.Time `json:"created"`
+    LastSeen  time.Time `json:"last_seen"`
+    Expires   time.Time `json:"expires"`
+    IP        string    `json:"ip,omitempty"`
+    UserAgent string    `json:"user_agent,omitempty"`
+    Current   bool      `json:"current,omitempty"`
+}
+
+// requestSession returns the session r was made with, if it carries a
+// valid session cookie.
+func (s *Server) requestSession(r *http.Request) (Session, string, bool) {
+    cookie, err := r.Cookie("session")
+    if err != nil {
+        return Session{}, "", false
+    }
+    sess, ok := s.sessions.Get(cookie.Value)
+    return sess, cookie.Value, ok
+}
+
+// handleSessions lists live sessions with GET /api/sessions: the user's
+// own, or every user's for an admin.
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    owner := user.Username
+    if user.hasRole(RoleAdmin) {
+        owner = ""
+    }
+    current, _, _ := s.requestSession(r)
+    views := []sessionView{}
+    for _, sess := range s.sessions.List(owner) {
+        views = append(views, sessionView{
+            ID:        sess.ID,
+            Username:  sess.Username,
+            Created:   sess.Created,
+            LastSeen:  sess.LastSeen,
+            Expires:   sess.Expires,
+            IP:        sess.IP,
+            UserAgent: sess.UserAgent,
+            Current:   current.ID != "" && sess.ID == current.ID,
+        })
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(views)
+}
+
+// handleSessionByID revokes a session with DELETE /api/sessions/{id}, and
+// every session and remembered device of the user but the current ones
+// with POST /api/sessions/revoke_others.  Users may revoke only their own sessions;
+// admins may revoke anyone's.  A revoked session is refused by withAuth
+// from its next request.
+func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request, user User) {
+    id := strings.TrimPrefix(r.URL.Path, "/api/sessions/")
+    if id == "revoke_others" {
+        if r.Method != http.MethodPost {
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        // The user's other remembered devices are forgotten too, or they
+        // could start new sessions at once.
+        sess, cookie, _ := s.requestSession(r)
+        n := s.sessions.DeleteUser(user.Username, cookie)
+        forgotten := s.devices.DeleteUser(user.Username, sess.Device)
+        s.logger.Log("revoke %d other sessions and %d devices of %s", n, forgotten, user.Username)
+        s.audit(r, user.Username, "session.revoke_others", user.Username, nil, map[string]int{"sessions": n, "devices": forgotten})
+        s.adminChange(user.Username, "%d other sessions and %d devices of %s revoked", n, forgotten, user.Username)
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(map[string]int{"revoked": n, "devices": forgotten})
+        return
+    }
+    if r.Method != http.MethodDelete {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    owner := user.Username
+    if user.hasRole(RoleAdmin) {
+        owner = ""
+    }
+    sess, ok := s.sessions.Revoke(id, owner)
+    if !ok {
+        http.Error(w, "not found", http.StatusNotFound)
+        return
+    }
+    s.logger.Log("revoke session %s of %s by %s", sess.ID, sess.Username, user.Username)
+    s.audit(r, user.Username, "session.revoke", sess.Username, map[string]string{"id": sess.ID, "ip": sess.IP}, nil)
+    s.adminChange(user.Username, "session %s of %s revoked", sess.ID, sess.Username)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// endUserSessions ends every session of username and forgets their
+// remembered devices, for a user deleted or disabled, returning the
+// numbers of each.
+func (s *Server) endUserSessions(username string) (int, int) {
+    return s.sessions.DeleteUser(username, ""), s.devices.DeleteUser(username, "")
+}
+
+// sessionLimitReached logs desc, a login beyond the session limit of
+// username, and reports it as a session_limit event.
+func (s *Server) sessionLimitReached(username, desc string) {
+    s.logger.Log("security: %s", desc)
+    s.sendAlerts(s.newAlertEvent(EventSessionLimit, Zone{Name: desc}, username))
+}
+
+// sessionMismatched ends the session sess, whose cookie was presented by a
+// different client as described by reason, and reports it as a security
+// event.
+func (s *Server) sessionMismatched(cookie string, sess Session, reason string) {
+    s.sessions.Delete(cookie)
+    desc := fmt.Sprintf("session %s of %s ended: %s", sess.ID, sess.Username, reason)
+    s.logger.Log("security: %s", desc)
+    s.sendAlerts(s.newAlertEvent(EventSessionMismatch, Zone{Name: desc}, sess.Username))
+}
+
+// sessionError answers a login whose session could not be started: 409 if
+// the user is at their session limit, otherwise 500.
+func sessionError(w http.ResponseWriter, err error) {
+    if err == errTooManySessions {
+        http.Error(w, "too many sessions: log out elsewhere or revoke a session first", http.StatusConflict)
+        return
+    }
+    http.Error(w, "failed to create session", http.StatusInternalServerError)
+}
@@ -0,0 +1,235 @@
+package main
+
+// Tests of sessions: their idle timeout, sliding renewal and absolute
+// lifetime, the refreshed cookie, purging and revocation.
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// newTestSession starts a session of alice and moves its start and last
+// use back by age and idle.
+func newTestSession(t *testing.T, sm *SessionManager, ttl, idleTimeout, age, idle time.Duration) string {
+    id, _, _, err := sm.Create(Session{Username: "alice"}, ttl, idleTimeout, sessionLimit{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+    key := hashSessionID(id)
+    s := sm.sessions[key]
+    s.Created = s.Created.Add(-age)
+    s.LastSeen = s.LastSeen.Add(-idle)
+    s.Expires = s.expiry(s.LastSeen, ttl, idleTimeout)
+    sm.sessions[key] = s
+    return id
+}
+
+func TestSessionExpiry(t *testing.T) {
+    const ttl, idle = time.Hour, 10 * time.Minute
+    tests := []struct {
+        name      string
+        age, idle time.Duration // since the session started and was last used
+        valid     bool
+        expires   time.Duration // after now, once touched
+    }{
+        {"fresh", 0, 0, true, idle},
+        {"idle within the timeout slides", 30 * time.Minute, 9 * time.Minute, true, idle},
+        {"idle beyond the timeout", 30 * time.Minute, 11 * time.Minute, false, 0},
+        {"renewal capped by the lifetime", 55 * time.Minute, time.Minute, true, 5 * time.Minute},
+        {"beyond the lifetime though in use", 61 * time.Minute, 0, false, 0},
+    }
+    dir := inTempDir(t)
+    sm := NewSessionManager("", NewEventLogger(filepath.Join(dir, "events.log")))
+    for _, tt := range tests {
+        id := newTestSession(t, sm, ttl, idle, tt.age, tt.idle)
+        before := time.Now()
+        s, ok := sm.Touch(id, ttl, idle)
+        if ok != tt.valid {
+            t.Errorf("%s: valid %v, want %v", tt.name, ok, tt.valid)
+            continue
+        }
+        if !ok {
+            if _, ok := sm.Get(id); ok {
+                t.Errorf("%s: expired session still returned by Get", tt.name)
+            }
+            continue
+        }
+        if got := s.Expires.Sub(before); got < tt.expires-time.Second || got > tt.expires+time.Second {
+            t.Errorf("%s: expires in %s, want %s", tt.name, got, tt.expires)
+        }
+    }
+}
+
+func TestSessionSlidesWithUse(t *testing.T) {
+    const ttl, idle = time.Hour, 10 * time.Minute
+    dir := inTempDir(t)
+    sm := NewSessionManager("", NewEventLogger(filepath.Join(dir, "events.log")))
+    id := newTestSession(t, sm, ttl, idle, 0, 0)
+    // Used every eight minutes, the session outlives several idle
+    // timeouts, but not its lifetime.
+    for elapsed := 8 * time.Minute; elapsed < 2*ttl; elapsed += 8 * time.Minute {
+        sm.mu.Lock()
+        key := hashSessionID(id)
+        s := sm.sessions[key]
+        s.Created = s.Created.Add(-8 * time.Minute)
+        s.LastSeen = s.LastSeen.Add(-8 * time.Minute)
+        s.Expires = s.Expires.Add(-8 * time.Minute)
+        sm.sessions[key] = s
+        sm.mu.Unlock()
+        _, ok := sm.Touch(id, ttl, idle)
+        if want := elapsed < ttl; ok != want {
+            t.Fatalf("after %s: valid %v, want %v", elapsed, ok, want)
+        }
+        if !ok {
+            return
+        }
+    }
+    t.Fatal("session never expired")
+}
+
+func TestSessionCookieRefreshed(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.SessionTTL, cfg.IdleTimeout = 3600, 600
+    cfg.Users = []User{{Username: "alice", Role: RoleViewer}}
+    s, _ := newTestServer(t, cfg)
+    id := newTestSession(t, s.sessions, time.Hour, 10*time.Minute, 5*time.Minute, 5*time.Minute)
+    r := httptest.NewRequest("GET", "/api/status", nil)
+    r.AddCookie(&http.Cookie{Name: "session", Value: id})
+    w := httptest.NewRecorder()
+    s.withAuth(s.handleStatus)(w, r)
+    if w.Code != http.StatusOK {
+        t.Fatalf("status %d", w.Code)
+    }
+    var expires time.Time
+    for _, c := range w.Result().Cookies() {
+        if c.Name == "session" && c.Value == id {
+            expires = c.Expires
+        }
+    }
+    if d := time.Until(expires); d < 9*time.Minute || d > 10*time.Minute+time.Second {
+        t.Errorf("cookie expires in %s, want the idle timeout of 10m", d)
+    }
+}
+
+func TestSessionsPurgedInBackground(t *testing.T) {
+    s, _ := newTestServer(t, validTestConfig())
+    interval := sessionPurgeInterval
+    sessionPurgeInterval = 10 * time.Millisecond
+    defer func() { sessionPurgeInterval = interval }()
+    for i := 0; i < 5; i++ {
+        if _, _, _, err := s.sessions.Create(Session{Username: "alice"}, 50*time.Millisecond, 0, sessionLimit{}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if _, _, _, err := s.sessions.Create(Session{Username: "bob"}, time.Hour, 0, sessionLimit{}); err != nil {
+        t.Fatal(err)
+    }
+    done := make(chan struct{})
+    go func() {
+        s.sessionPurgeLoop()
+        close(done)
+    }()
+    defer func() {
+        close(s.stop)
+        <-done
+    }()
+    stored := func() int {
+        s.sessions.mu.RLock()
+        defer s.sessions.mu.RUnlock()
+        return len(s.sessions.sessions)
+    }
+    waitFor(t, "expired sessions to be purged", func() bool { return stored() == 1 })
+    if n := s.sessions.Count(); n != 1 {
+        t.Errorf("%d live sessions reported, want 1", n)
+    }
+    s.sessions.mu.RLock()
+    defer s.sessions.mu.RUnlock()
+    if len(s.sessions.byUser["alice"]) != 0 {
+        t.Errorf("index still holds %d sessions of alice", len(s.sessions.byUser["alice"]))
+    }
+}
+
+func TestRevokeOthersForgetsDevices(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{{Username: "alice", Role: RoleViewer}, {Username: "bob", Role: RoleViewer}}
+    s, _ := newTestServer(t, cfg)
+    var current string
+    for i := 0; i < 3; i++ {
+        d, _, err := s.devices.Register("alice", "192.0.2.1", "phone", time.Hour)
+        if err != nil {
+            t.Fatal(err)
+        }
+        id, _, _, err := s.sessions.Create(Session{Username: "alice", Device: d.ID}, time.Hour, 0, sessionLimit{})
+        if err != nil {
+            t.Fatal(err)
+        }
+        if i == 0 {
+            current = id
+        }
+    }
+    if _, _, err := s.devices.Register("bob", "192.0.2.2", "tablet", time.Hour); err != nil {
+        t.Fatal(err)
+    }
+    r := httptest.NewRequest("POST", "/api/sessions/revoke_others", nil)
+    r.AddCookie(&http.Cookie{Name: "session", Value: current})
+    w := httptest.NewRecorder()
+    s.handleSessionByID(w, r, User{Username: "alice", Role: RoleViewer})
+    if w.Code != http.StatusOK {
+        t.Fatalf("status %d", w.Code)
+    }
+    sess, _ := s.sessions.Get(current)
+    if got := s.sessions.List("alice"); len(got) != 1 || got[0].ID != sess.ID {
+        t.Errorf("%d sessions of alice left, want only the current one", len(got))
+    }
+    if got := s.devices.List("alice"); len(got) != 1 || got[0].ID != sess.Device {
+        t.Errorf("%d devices of alice left, want only the current one", len(got))
+    }
+    if got := s.devices.List("bob"); len(got) != 1 {
+        t.Errorf("%d devices of bob left, want 1", len(got))
+    }
+}
+
+func TestDeletedUserLoggedOut(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{
+        {Username: "admin", Role: RoleAdmin},
+        {Username: "alice", Role: RoleViewer},
+        {Username: "bob", Role: RoleViewer},
+    }
+    s, _ := newTestServer(t, cfg)
+    for _, name := range []string{"alice", "bob"} {
+        if _, _, err := s.devices.Register(name, "192.0.2.1", "phone", time.Hour); err != nil {
+            t.Fatal(err)
+        }
+        if _, _, _, err := s.sessions.Create(Session{Username: name}, time.Hour, 0, sessionLimit{}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    w := httptest.NewRecorder()
+    s.handleUserByID(w, httptest.NewRequest("DELETE", "/api/users/alice", nil), User{Username: "admin", Role: RoleAdmin, Admin: true})
+    if w.Code != http.StatusNoContent {
+        t.Fatalf("delete: status %d", w.Code)
+    }
+    // bob is removed from the file and the configuration reloaded.
+    old := s.cfgMgr.Get()
+    if err := s.cfgMgr.Update(func(c *Config) error {
+        c.Users = c.Users[:1]
+        return nil
+    }); err != nil {
+        t.Fatal(err)
+    }
+    s.applyConfig(old, "reloaded")
+    for _, name := range []string{"alice", "bob"} {
+        if n := len(s.sessions.List(name)); n != 0 {
+            t.Errorf("%d sessions of %s left", n, name)
+        }
+        if n := len(s.devices.List(name)); n != 0 {
+            t.Errorf("%d devices of %s left", n, name)
+        }
+    }
+}
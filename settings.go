@@ -0,0 +1,176 @@
+package main
+
+// This file serves the system settings of config.json, such as the port,
+// delays and session lifetimes, through GET and PUT /api/settings, so that
+// they can be changed without editing the file like zones and users.
+// Settings are checked like the rest of the configuration and applied as a
+// reload would apply them: most at once, and the few read only at startup
+// after a restart.  Settings overridden by environment variables are read
+// only.
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "reflect"
+    "sort"
+)
+
+// apiSettings are the settings served by /api/settings, by their names in
+// config.json.
+var apiSettings = []string{
+    "http_port", "base_url", "log_file", "log_level", "timezone",
+    "retention_days", "exit_delay", "entry_delay", "alert_timeout",
+    "session_ttl", "idle_timeout", "remember_ttl", "max_sessions_per_user",
+}
+
+// settingsResponse is returned by /api/settings.  ReadOnly lists the
+// settings overridden by environment variables and Restart those that take
+// effect only after a restart.  RestartRequired lists the settings changed
+// by a PUT that wait for one, and is empty for a GET.
+type settingsResponse struct {
+    Settings        map[string]any `json:"settings"`
+    ReadOnly        []string       `json:"read_only"`
+    Restart         []string       `json:"restart"`
+    RestartRequired []string       `json:"restart_required"`
+}
+
+// settingValues returns the values of apiSettings in cfg.
+func settingValues(cfg Config) map[string]any {
+    values := make(map[string]any)
+    for _, key := range apiSettings {
+        values[key] = configField(&cfg, key).Interface()
+    }
+    return values
+}
+
+// validateSetting checks the value of the setting key given through the
+// API, so that a bad value is refused naming that setting alone.  The
+// configuration as a whole is then checked by Update.
+func validateSetting(key string, field reflect.Value) error {
+    switch {
+    case key == "http_port":
+        if port := field.Int(); port < 1 || port > 65535 {
+            return fmt.Errorf("must be between 1 and 65535, not %d", port)
+        }
+    case field.Kind() == reflect.Int:
+        if field.Int() < 0 {
+            return errors.New("must not be negative")
+        }
+    case key == "base_url":
+        return validateBaseURL(field.String())
+    }
+    return nil
+}
+
+// validateBaseURL checks that base is empty or an absolute http or https
+// URL, such as "https://minder.example.org".
+func validateBaseURL(base string) error {
+    if base == "" {
+        return nil
+    }
+    u, err := url.Parse(base)
+    if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+        return fmt.Errorf("invalid URL %q (expected e.g. \"https://minder.example.org\")", base)
+    }
+    return nil
+}
+
+// handleSettings serves the system settings.  GET returns them; PUT takes
+// an object of the settings to change, e.g. {"exit_delay": 45}, saves and
+// applies them, and returns the settings with the changed ones waiting for
+// a restart in restart_required.  Unknown and read only settings and
+// invalid values are refused.  Each change is audited as settings.update
+// with its old and new value.  Admins only.
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request, user User) {
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    restartRequired := []string{}
+    switch r.Method {
+    case http.MethodGet:
+    case http.MethodPut:
+        if s.configReadOnly(w) {
+            return
+        }
+        var req map[string]json.RawMessage
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "invalid JSON", http.StatusBadRequest)
+            return
+        }
+        known := make(map[string]bool)
+        for _, key := range apiSettings {
+            known[key] = true
+        }
+        keys := make([]string, 0, len(req))
+        for key := range req {
+            if !known[key] {
+                http.Error(w, fmt.Sprintf("unknown setting %q", key), http.StatusBadRequest)
+                return
+            }
+            if env, ok := s.cfgMgr.Overridden(key); ok {
+                http.Error(w, key+" is set by "+env, http.StatusConflict)
+                return
+            }
+            keys = append(keys, key)
+        }
+        sort.Strings(keys)
+        old := s.cfgMgr.Get()
+        err := s.cfgMgr.Update(func(c *Config) error {
+            for _, key := range keys {
+                field := configField(c, key)
+                value := reflect.New(field.Type())
+                if err := json.Unmarshal(req[key], value.Interface()); err != nil {
+                    kind := "string"
+                    if field.Kind() == reflect.Int {
+                        kind = "whole number"
+                    }
+                    return configErrors{{Path: key, Message: "must be a " + kind, Severity: ProblemError}}
+                }
+                field.Set(value.Elem())
+                if err := validateSetting(key, field); err != nil {
+                    return configErrors{{Path: key, Message: err.Error(), Severity: ProblemError}}
+                }
+            }
+            return nil
+        })
+        var problems configErrors
+        if errors.As(err, &problems) {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if err != nil {
+            s.logger.Log("settings change by %s failed: %v", user.Username, err)
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        before, after := settingValues(old), settingValues(s.cfgMgr.Get())
+        for _, key := range keys {
+            if reflect.DeepEqual(before[key], after[key]) {
+                continue
+            }
+            s.audit(r, user.Username, "settings.update", key, before[key], after[key])
+            if restartSections[key] {
+                restartRequired = append(restartRequired, key)
+            }
+        }
+        s.applyConfig(old, "changed by "+user.Username)
+    default:
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    resp := settingsResponse{Settings: settingValues(s.cfgMgr.Get()), ReadOnly: []string{}, Restart: []string{}, RestartRequired: restartRequired}
+    for _, key := range apiSettings {
+        if _, ok := s.cfgMgr.Overridden(key); ok {
+            resp.ReadOnly = append(resp.ReadOnly, key)
+        }
+        if restartSections[key] {
+            resp.Restart = append(resp.Restart, key)
+        }
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(resp)
+}
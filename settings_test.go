@@ -0,0 +1,54 @@
+package main
+
+// Tests of the settings API: the range checks of the values it takes.
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestSettingsRanges(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{{Username: "admin", Role: RoleAdmin}}
+    s, _ := newTestServer(t, cfg)
+    admin := User{Username: "admin", Role: RoleAdmin, Admin: true}
+    tests := []struct {
+        body string
+        code int
+    }{
+        {`{"retention_days": 30, "alert_timeout": 20, "base_url": "https://minder.example.org"}`, http.StatusOK},
+        {`{"retention_days": -1}`, http.StatusBadRequest},
+        {`{"alert_timeout": -5}`, http.StatusBadRequest},
+        {`{"exit_delay": -1}`, http.StatusBadRequest},
+        {`{"http_port": 70000}`, http.StatusBadRequest},
+        {`{"base_url": "minder.example.org"}`, http.StatusBadRequest},
+        {`{"base_url": "ftp://minder.example.org"}`, http.StatusBadRequest},
+        {`{"base_url": ""}`, http.StatusOK},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        s.handleSettings(w, httptest.NewRequest("PUT", "/api/settings", strings.NewReader(tt.body)), admin)
+        if w.Code != tt.code {
+            t.Errorf("%s: status %d, want %d: %s", tt.body, w.Code, tt.code, w.Body)
+        }
+    }
+    if got := s.cfgMgr.Get(); got.RetentionDays != 30 || got.AlertTimeout != 20 {
+        t.Errorf("retention_days %d, alert_timeout %d after refused changes", got.RetentionDays, got.AlertTimeout)
+    }
+}
+
+func TestValidateSettingRanges(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.RetentionDays, cfg.AlertTimeout, cfg.BaseURL = -1, -1, "/minder"
+    want := map[string]bool{"retention_days": true, "alert_timeout": true, "base_url": true}
+    for _, p := range cfg.Validate() {
+        if p.Severity == ProblemError {
+            delete(want, p.Path)
+        }
+    }
+    for path := range want {
+        t.Errorf("%s not reported", path)
+    }
+}
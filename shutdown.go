@@ -0,0 +1,98 @@
+package main
+
+// This file stops the server in order on SIGTERM or SIGINT, so that
+// stopping the service does not cut off requests, interrupt a write of
+// config.json or lose buffered log events.  The background loops select on
+// the server's stop channel, and Shutdown waits for them, the HTTP servers
+// and the alert dispatcher in turn, all within one timeout.
+
+import (
+    "context"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// shutdownTimeout bounds how long Shutdown waits for requests, loops and
+// alert deliveries to finish.
+const shutdownTimeout = 10 * time.Second
+
+// background runs fn in a goroutine that Shutdown waits for.  fn must
+// return once s.stop is closed.
+func (s *Server) background(fn func()) {
+    s.loops.Add(1)
+    go func() {
+        defer s.loops.Done()
+        fn()
+    }()
+}
+
+// Shutdown stops the server.  It ends the background loops and live
+// streams, lets requests in progress finish, delivers the alerts already
+// queued, writes out pending logins and the alert delivery status, waits
+// for any save of the configuration and flushes the event log.  Whatever
+// has not finished when timeout runs out is abandoned.
+func (s *Server) Shutdown(timeout time.Duration) {
+    deadline := time.Now().Add(timeout)
+    s.logger.Log("shutting down")
+    s.stopOnce.Do(func() { close(s.stop) })
+    s.httpMu.Lock()
+    servers := s.httpServers
+    s.httpMu.Unlock()
+    for _, srv := range servers {
+        ctx, cancel := context.WithDeadline(context.Background(), deadline)
+        if err := srv.Shutdown(ctx); err != nil {
+            s.logger.Log("shutdown: requests still in progress: %v", err)
+        }
+        cancel()
+    }
+    if !waitUntil(&s.loops, deadline) {
+        s.logger.Log("shutdown: background tasks still running")
+    }
+    s.flushLastLogins()
+    s.alertQueue.Stop()
+    if !waitUntil(&s.alertQueue.loops, deadline) {
+        s.logger.Log("shutdown: alert deliveries still in progress")
+    }
+    s.alertsMu.Lock()
+    closeAlertHandlers(s.alerts)
+    s.alertsMu.Unlock()
+    if s.mqtt != nil {
+        s.mqtt.Close()
+    }
+    s.cfgMgr.Close()
+    s.logger.Log("shutdown complete")
+    s.logger.Flush()
+    s.logger.CloseSinks(deadline)
+}
+
+// addHTTPServer records srv for Shutdown to stop.  It returns false if the
+// server is already stopping, in which case srv must not be started.
+func (s *Server) addHTTPServer(srv *http.Server) bool {
+    s.httpMu.Lock()
+    s.httpServers = append(s.httpServers, srv)
+    s.httpMu.Unlock()
+    select {
+    case <-s.stop:
+        return false
+    default:
+        return true
+    }
+}
+
+// waitUntil waits for wg until deadline and reports whether it finished.
+func waitUntil(wg *sync.WaitGroup, deadline time.Time) bool {
+    done := make(chan struct{})
+    go func() {
+        wg.Wait()
+        close(done)
+    }()
+    timer := time.NewTimer(time.Until(deadline))
+    defer timer.Stop()
+    select {
+    case <-done:
+        return true
+    case <-timer.C:
+        return false
+    }
+}
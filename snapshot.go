@@ -0,0 +1,109 @@
+package main
+
+// This file fetches camera snapshots that are attached to alarm
+// notifications.
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// snapshotTimeout bounds how long fetching a snapshot may delay an alert.
+const snapshotTimeout = 5 * time.Second
+
+// snapshotMaxSize is the largest snapshot that will be attached.
+const snapshotMaxSize = 5 << 20
+
+// Snapshot is an image captured from a zone's camera when the zone
+// triggered.  Handlers that can carry attachments include it; others ignore
+// it.
+type Snapshot struct {
+    ContentType string
+    Data        []byte
+    Time        time.Time
+}
+
+// Filename returns a file name for the snapshot based on its capture time
+// and content type.
+func (sn *Snapshot) Filename() string {
+    ext := ".jpg"
+    switch sn.ContentType {
+    case "image/png":
+        ext = ".png"
+    case "image/gif":
+        ext = ".gif"
+    }
+    return "snapshot-" + sn.Time.Format("20060102-150405") + ext
+}
+
+var snapshotClient = &http.Client{Timeout: snapshotTimeout}
+
+// fetchSnapshot downloads the image at zone.SnapshotURL, using basic
+// authentication if a username is configured.
+func fetchSnapshot(zone Zone) (*Snapshot, error) {
+    req, err := http.NewRequest(http.MethodGet, zone.SnapshotURL, nil)
+    if err != nil {
+        return nil, err
+    }
+    if zone.SnapshotUsername != "" {
+        req.SetBasicAuth(zone.SnapshotUsername, zone.SnapshotPassword)
+    }
+    resp, err := snapshotClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("camera returned %s", resp.Status)
+    }
+    ctype := resp.Header.Get("Content-Type")
+    if i := strings.Index(ctype, ";"); i >= 0 {
+        ctype = ctype[:i]
+    }
+    ctype = strings.TrimSpace(strings.ToLower(ctype))
+    if ctype == "" {
+        ctype = "image/jpeg"
+    }
+    if !strings.HasPrefix(ctype, "image/") {
+        return nil, fmt.Errorf("camera returned %s, not an image", ctype)
+    }
+    data, err := io.ReadAll(io.LimitReader(resp.Body, snapshotMaxSize+1))
+    if err != nil {
+        return nil, err
+    }
+    if len(data) > snapshotMaxSize {
+        return nil, errors.New("snapshot too large")
+    }
+    return &Snapshot{ContentType: ctype, Data: data, Time: time.Now()}, nil
+}
+
+// redactZone hides the camera password of z in API responses.
+func redactZone(z Zone) Zone {
+    if z.SnapshotPassword != "" {
+        z.SnapshotPassword = redactedSecret
+    }
+    return z
+}
+
+// attachSnapshot fetches a snapshot for trigger and alarm events of zones
+// with a camera.  A failed fetch is logged and the event is sent without
+// an image.
+func attachSnapshot(event AlertEvent, logger *EventLogger) AlertEvent {
+    if event.Snapshot != nil || event.Zone.SnapshotURL == "" {
+        return event
+    }
+    if event.Type != EventTrigger && event.Type != EventAlarm {
+        return event
+    }
+    sn, err := fetchSnapshot(event.Zone)
+    if err != nil {
+        logger.Log("snapshot for zone %s failed: %v", event.Zone.Name, err)
+        return event
+    }
+    event.Snapshot = sn
+    return event
+}
@@ -0,0 +1,238 @@
+package main
+
+// This file pushes live updates to clients with Server-Sent Events, so the
+// web UI sees a zone trigger at once instead of at its next poll.  The
+// alarm logic publishes to a hub, which hands each event to every
+// subscriber without waiting: a subscriber whose buffer is full is
+// dropped, so one stuck tablet cannot hold up the alarm.
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+const (
+    // liveBuffer is how many events a subscriber may fall behind by before
+    // it is dropped.
+    liveBuffer = 64
+    // liveHistory is how many recent events are kept for clients resuming
+    // with Last-Event-ID.
+    liveHistory = 256
+)
+
+// liveKeepAlive is how often an idle stream sends a comment, so that
+// proxies and dead connections are noticed, and checks that its
+// credentials are still valid.  It is a variable so that tests can
+// shorten it.
+var liveKeepAlive = 30 * time.Second
+
+// liveEvent is an update pushed to clients.  Zone is omitted for events
+// that do not concern one.
+type liveEvent struct {
+    ID     uint64    `json:"id"`
+    Type   string    `json:"type"`
+    ZoneID int       `json:"zone_id,omitempty"`
+    Zone   string    `json:"zone,omitempty"`
+    Mode   string    `json:"mode,omitempty"`
+    User   string    `json:"user,omitempty"`
+    Time   time.Time `json:"time"`
+}
+
+// eventHub fans live events out to subscribers and remembers the most
+// recent for resuming clients.  IDs are taken from ids, the event log's
+// sequence, so that they never repeat and can be passed to /api/logs as
+// since_id; without it they count from one.
+type eventHub struct {
+    mu     sync.Mutex
+    ids    func() uint64
+    nextID uint64 // the ID of the latest event
+    recent []liveEvent
+    subs   map[chan liveEvent]struct{}
+}
+
+// publish assigns the next ID to e and hands it to every subscriber.
+// Subscribers that cannot take it are dropped by closing their channel.
+func (h *eventHub) publish(e liveEvent) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if h.ids != nil {
+        h.nextID = h.ids()
+    } else {
+        h.nextID++
+    }
+    e.ID = h.nextID
+    e.Time = localTime(e.Time)
+    h.recent = append(h.recent, e)
+    if len(h.recent) > liveHistory {
+        h.recent = h.recent[len(h.recent)-liveHistory:]
+    }
+    for ch := range h.subs {
+        select {
+        case ch <- e:
+        default:
+            delete(h.subs, ch)
+            close(ch)
+        }
+    }
+}
+
+// subscribe registers a subscriber.  If lastID is still covered by the
+// history, the events after it are returned as backlog and resumed is
+// true; otherwise the client needs a fresh snapshot.  latest is the ID of
+// the most recent event.
+func (h *eventHub) subscribe(lastID uint64, resume bool) (ch chan liveEvent, backlog []liveEvent, resumed bool, latest uint64) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if h.subs == nil {
+        h.subs = make(map[chan liveEvent]struct{})
+    }
+    ch = make(chan liveEvent, liveBuffer)
+    h.subs[ch] = struct{}{}
+    oldest := h.nextID + 1
+    if len(h.recent) > 0 {
+        oldest = h.recent[0].ID
+    }
+    if resume && lastID <= h.nextID && lastID+1 >= oldest {
+        for _, e := range h.recent {
+            if e.ID > lastID {
+                backlog = append(backlog, e)
+            }
+        }
+        resumed = true
+    }
+    return ch, backlog, resumed, h.nextID
+}
+
+// unsubscribe removes a subscriber, unless it has already been dropped.
+func (h *eventHub) unsubscribe(ch chan liveEvent) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if _, ok := h.subs[ch]; ok {
+        delete(h.subs, ch)
+        close(ch)
+    }
+}
+
+// publishLive publishes an event of eventType about zone, with the current
+// mode, to clients of /api/events.
+func (s *Server) publishLive(eventType string, zone Zone, username string) {
+    s.live.publish(liveEvent{Type: eventType, ZoneID: zone.ID, Zone: zone.Name, Mode: s.currentMode, User: username, Time: time.Now()})
+}
+
+// liveAllowed reports whether user may see e.  Security events name
+// other users and addresses, so they go to admins only.
+func liveAllowed(user User, e liveEvent) bool {
+    for _, t := range alertEventClasses["security"] {
+        if e.Type == t {
+            return user.hasRole(RoleAdmin)
+        }
+    }
+    return true
+}
+
+// writeSSE writes one event to an event stream.
+func writeSSE(w http.ResponseWriter, id uint64, event string, data any) error {
+    b, err := json.Marshal(data)
+    if err != nil {
+        return err
+    }
+    _, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, b)
+    return err
+}
+
+// liveCredentialsValid reports whether the credentials r opened an event
+// stream or WebSocket with are still good, so that neither outlives a
+// logout, an expired session, a revoked key or a disabled account.  Both
+// check at each keep-alive or ping.
+func (s *Server) liveCredentialsValid(r *http.Request, user User) bool {
+    cfg := s.cfgMgr.Get()
+    if user.APIKey != "" {
+        for _, k := range cfg.APIKeys {
+            if k.Name == user.APIKey {
+                return true
+            }
+        }
+        return false
+    }
+    current, _ := s.cfgMgr.FindUser(user.Username)
+    if current.Username == "" || current.Disabled {
+        return false
+    }
+    if bearerToken(r) != "" {
+        _, ok := wsToken(cfg, r)
+        return ok
+    }
+    if cookie, err := r.Cookie("session"); err == nil {
+        _, ok := s.sessions.Get(cookie.Value)
+        return ok
+    }
+    return true
+}
+
+// handleEvents streams live updates with GET /api/events as Server-Sent
+// Events.  It first sends a "status" event holding what /api/status
+// returns, then an event named after the type of each update (trigger,
+// alarm, arm, disarm, fault...).  A client reconnecting with Last-Event-ID
+// is sent the updates it missed instead, if they are still remembered.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+        return
+    }
+    clearDeadlines(w)
+    lastID, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+    ch, backlog, resumed, latest := s.live.subscribe(lastID, err == nil)
+    defer s.live.unsubscribe(ch)
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("X-Accel-Buffering", "no")
+    if !resumed {
+        if writeSSE(w, latest, "status", s.statusSnapshot()) != nil {
+            return
+        }
+    }
+    for _, e := range backlog {
+        if liveAllowed(user, e) && writeSSE(w, e.ID, e.Type, e) != nil {
+            return
+        }
+    }
+    flusher.Flush()
+    keepAlive := time.NewTicker(liveKeepAlive)
+    defer keepAlive.Stop()
+    for {
+        select {
+        case e, ok := <-ch:
+            if !ok {
+                // Dropped for falling behind; the client reconnects and
+                // resumes.
+                return
+            }
+            if !liveAllowed(user, e) {
+                continue
+            }
+            if writeSSE(w, e.ID, e.Type, e) != nil {
+                return
+            }
+        case <-keepAlive.C:
+            if !s.liveCredentialsValid(r, user) {
+                return
+            }
+            if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+                return
+            }
+        case <-r.Context().Done():
+            return
+        case <-s.stop:
+            return
+        }
+        flusher.Flush()
+    }
+}
@@ -0,0 +1,41 @@
+package main
+
+// Tests of the live event stream.
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+func TestEventStreamEndsWithSession(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{{Username: "alice", Role: RoleViewer}}
+    s, _ := newTestServer(t, cfg)
+    keepAlive := liveKeepAlive
+    liveKeepAlive = 10 * time.Millisecond
+    defer func() { liveKeepAlive = keepAlive }()
+    id, _, _, err := s.sessions.Create(Session{Username: "alice"}, time.Hour, 0, sessionLimit{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    r := httptest.NewRequest("GET", "/api/events", nil)
+    r.AddCookie(&http.Cookie{Name: "session", Value: id})
+    done := make(chan struct{})
+    go func() {
+        s.handleEvents(httptest.NewRecorder(), r, User{Username: "alice", Role: RoleViewer})
+        close(done)
+    }()
+    select {
+    case <-done:
+        t.Fatal("stream ended with a valid session")
+    case <-time.After(50 * time.Millisecond):
+    }
+    s.sessions.Delete(id)
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("stream still open after the session was revoked")
+    }
+}
@@ -0,0 +1,72 @@
+package main
+
+// This file serves the web UI, built into web/dist by Vite and embedded in
+// the binary.  Files are served from web/dist, so that /assets/index-abc.js
+// is web/dist/assets/index-abc.js.  Any other path without an extension,
+// such as /zones/3, is a route of the single page app and gets index.html;
+// a missing file, such as a stale asset, and unknown /api paths get 404.
+// Vite names the files in assets/ after a hash of their content, so they
+// are cached for a year; index.html, which names the current ones, is
+// never stored.  A file compressed at build time as name.gz is sent to
+// clients that accept gzip in place of name.  web/dist is built from
+// web/src by go generate and must not be edited by hand.
+
+//go:generate npm --prefix web run build
+
+import (
+    "io/fs"
+    "mime"
+    "net/http"
+    "path"
+    "strings"
+)
+
+// spaHandler serves the web UI from dist, the content of web/dist.
+func spaHandler(dist fs.FS) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet && r.Method != http.MethodHead {
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/") {
+            http.NotFound(w, r)
+            return
+        }
+        name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+        if name == "" {
+            name = "index.html"
+        }
+        data, err := fs.ReadFile(dist, name)
+        if err != nil {
+            if path.Ext(name) != "" {
+                http.NotFound(w, r)
+                return
+            }
+            name = "index.html"
+            if data, err = fs.ReadFile(dist, name); err != nil {
+                http.NotFound(w, r)
+                return
+            }
+        }
+        ctype := mime.TypeByExtension(path.Ext(name))
+        if ctype == "" {
+            ctype = http.DetectContentType(data)
+        }
+        w.Header().Set("Content-Type", ctype)
+        switch {
+        case strings.HasPrefix(name, "assets/"):
+            w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+        case name == "index.html":
+            w.Header().Set("Cache-Control", "no-store")
+        default:
+            w.Header().Set("Cache-Control", "no-cache")
+        }
+        if acceptsGzip(r) {
+            if gz, err := fs.ReadFile(dist, name+".gz"); err == nil {
+                w.Header().Set("Content-Encoding", "gzip")
+                data = gz
+            }
+        }
+        _, _ = w.Write(data)
+    }
+}
@@ -0,0 +1,86 @@
+package main
+
+// Tests of the web UI handler: assets fetched from the embedded web/dist,
+// routes of the single page app and missing files.
+
+import (
+    "bytes"
+    "io/fs"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "testing/fstest"
+)
+
+// embeddedDist returns web/dist as embedded in the binary.
+func embeddedDist(t *testing.T) fs.FS {
+    dist, err := fs.Sub(embeddedFiles, "web/dist")
+    if err != nil {
+        t.Fatal(err)
+    }
+    return dist
+}
+
+func TestEmbeddedAsset(t *testing.T) {
+    dist := embeddedDist(t)
+    scripts, _ := fs.Glob(dist, "assets/*.js")
+    if len(scripts) == 0 {
+        t.Fatal("no script in the embedded web/dist/assets")
+    }
+    want, err := fs.ReadFile(dist, scripts[0])
+    if err != nil {
+        t.Fatal(err)
+    }
+    w := httptest.NewRecorder()
+    spaHandler(dist)(w, httptest.NewRequest("GET", "/"+scripts[0], nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("/%s: status %d", scripts[0], w.Code)
+    }
+    if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, "javascript") {
+        t.Errorf("/%s: Content-Type %q", scripts[0], ct)
+    }
+    if !bytes.Equal(w.Body.Bytes(), want) {
+        t.Errorf("/%s: body is not the embedded file", scripts[0])
+    }
+}
+
+func TestStaticPaths(t *testing.T) {
+    dist := fstest.MapFS{
+        "index.html":                      {Data: []byte("<!doctype html><title>Minder</title>")},
+        "assets/index-abc.js":             {Data: []byte("console.log(1)")},
+        "assets/fonts/inter/inter-1.woff2": {Data: []byte("wOF2")},
+    }
+    tests := []struct {
+        path  string
+        code  int
+        ctype string
+        body  string
+    }{
+        {"/", http.StatusOK, "text/html", "<!doctype html>"},
+        {"/assets/index-abc.js", http.StatusOK, "javascript", "console.log"},
+        {"/assets/fonts/inter/inter-1.woff2", http.StatusOK, "font/woff2", "wOF2"},
+        {"/zones/3", http.StatusOK, "text/html", "<!doctype html>"},
+        {"/assets/index-old.js", http.StatusNotFound, "", ""},
+        {"/assets/fonts/missing.woff2", http.StatusNotFound, "", ""},
+        {"/api/unknown", http.StatusNotFound, "", ""},
+        {"/../../server.go", http.StatusNotFound, "", ""},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        spaHandler(dist)(w, httptest.NewRequest("GET", tt.path, nil))
+        if w.Code != tt.code {
+            t.Errorf("%s: status %d, want %d", tt.path, w.Code, tt.code)
+            continue
+        }
+        if tt.code != http.StatusOK {
+            continue
+        }
+        if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, tt.ctype) {
+            t.Errorf("%s: Content-Type %q, want %s", tt.path, ct, tt.ctype)
+        }
+        if !strings.HasPrefix(w.Body.String(), tt.body) {
+            t.Errorf("%s: body %q", tt.path, w.Body)
+        }
+    }
+}
@@ -0,0 +1,72 @@
+package main
+
+// This file renders times in the household's time zone.  The event log
+// keeps its timestamps in UTC; the API, alert messages and the UI show
+// them in Config.Timezone, which may differ from the zone of the machine
+// Minder runs on.
+
+import (
+    "fmt"
+    "strings"
+    "sync/atomic"
+    "time"
+)
+
+// displayLocation is the time zone times are shown in.  It is set from the
+// configuration by setTimezone; until then the machine's zone is used.
+var displayLocation atomic.Pointer[time.Location]
+
+// validateTimezone checks that name is an IANA time zone, such as
+// "Europe/London".  "" means the machine's zone.
+func validateTimezone(name string) error {
+    if name == "" {
+        return nil
+    }
+    if _, err := time.LoadLocation(name); err != nil {
+        return fmt.Errorf("unknown timezone %q (expected an IANA name such as \"Europe/London\")", name)
+    }
+    return nil
+}
+
+// setTimezone selects the time zone times are shown in.  name must be
+// valid.
+func setTimezone(name string) {
+    loc := time.Local
+    if name != "" {
+        if l, err := time.LoadLocation(name); err == nil {
+            loc = l
+        }
+    }
+    displayLocation.Store(loc)
+}
+
+// localTime returns t in the configured time zone.
+func localTime(t time.Time) time.Time {
+    if loc := displayLocation.Load(); loc != nil {
+        return t.In(loc)
+    }
+    return t.Local()
+}
+
+// localLogLine rewrites the timestamp of a log line in the configured
+// time zone.  Lines without a timestamp are returned unchanged.
+func localLogLine(line string) string {
+    ts, rest, ok := strings.Cut(line, " ")
+    if !ok {
+        return line
+    }
+    t, err := time.Parse(time.RFC3339, ts)
+    if err != nil {
+        return line
+    }
+    return localTime(t).Format(time.RFC3339) + " " + rest
+}
+
+// localLogLines rewrites the timestamps of lines in place, see
+// localLogLine, and returns them.
+func localLogLines(lines []string) []string {
+    for i, line := range lines {
+        lines[i] = localLogLine(line)
+    }
+    return lines
+}
@@ -0,0 +1,65 @@
+package main
+
+// Tests of showing times in the configured time zone, around the start of
+// summer time.
+
+import (
+    "testing"
+    "time"
+)
+
+// withTimezone shows times in name for the rest of the test.
+func withTimezone(t *testing.T, name string) {
+    old := displayLocation.Load()
+    t.Cleanup(func() { displayLocation.Store(old) })
+    if err := validateTimezone(name); err != nil {
+        t.Fatal(err)
+    }
+    setTimezone(name)
+}
+
+func TestLocalTimeSpringForward(t *testing.T) {
+    withTimezone(t, "Europe/London")
+    // Clocks in London went forward from 01:00 GMT to 02:00 BST at 01:00
+    // UTC on 29 March 2026.
+    tests := []struct {
+        utc, local string
+    }{
+        {"2026-03-29T00:59:59Z", "2026-03-29T00:59:59Z"},
+        {"2026-03-29T01:00:00Z", "2026-03-29T02:00:00+01:00"},
+        {"2026-03-29T01:30:00Z", "2026-03-29T02:30:00+01:00"},
+        {"2026-03-28T23:30:00Z", "2026-03-28T23:30:00Z"},
+        // And back at 01:00 UTC on 25 October, 01:00 BST happening twice.
+        {"2026-10-25T00:30:00Z", "2026-10-25T01:30:00+01:00"},
+        {"2026-10-25T01:30:00Z", "2026-10-25T01:30:00Z"},
+    }
+    for _, tt := range tests {
+        line := tt.utc + " #7 - arm Away by alice"
+        if got, want := localLogLine(line), tt.local+" #7 - arm Away by alice"; got != want {
+            t.Errorf("%s: %q, want %q", tt.utc, got, want)
+        }
+    }
+    // An hour of wall clock time across the change is no time at all.
+    before := localTime(time.Date(2026, 3, 29, 0, 59, 0, 0, time.UTC))
+    after := localTime(time.Date(2026, 3, 29, 1, 1, 0, 0, time.UTC))
+    if before.Hour() != 0 || after.Hour() != 2 || after.Sub(before) != 2*time.Minute {
+        t.Errorf("%s then %s", before, after)
+    }
+    // Lines without a timestamp are left alone.
+    if got := localLogLine("old style line"); got != "old style line" {
+        t.Errorf("untimed line rewritten to %q", got)
+    }
+}
+
+func TestValidateTimezone(t *testing.T) {
+    for _, name := range []string{"", "UTC", "Europe/London", "America/New_York"} {
+        if err := validateTimezone(name); err != nil {
+            t.Errorf("%q: %v", name, err)
+        }
+    }
+    for _, name := range []string{"Europe/Londn", "BST+1", "../etc/passwd"} {
+        if err := validateTimezone(name); err == nil {
+            t.Errorf("%q accepted", name)
+        }
+    }
+}
@@ -0,0 +1,391 @@
+package main
+
+// This file implements two-factor login with an authenticator app (TOTP,
+// RFC 6238) and the one-time recovery codes that let a user in when the
+// phone with the app is lost.  A logged in user turns two-factor on with
+// POST /api/2fa/setup, which needs their password and returns a new
+// secret, then POST /api/2fa/enable with a code from the app, which
+// returns ten recovery codes.  The codes are shown only then, or when
+// regenerated, and stored as password hashes on the user.  From then on a
+// password login must also send an "otp" from the app or a
+// "recovery_code"; each recovery code works once.
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha1"
+    "encoding/base32"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+// TOTP parameters, those every authenticator app supports.
+const (
+    totpDigits = 6
+    totpPeriod = 30 // seconds
+    // totpSkew is the number of periods either side of the current one
+    // whose codes are accepted, allowing for clock drift.
+    totpSkew = 1
+    // totpSetupTimeout bounds the time between setup and enable.
+    totpSetupTimeout = 10 * time.Minute
+)
+
+// Recovery code parameters.  Codes are written as two groups of five
+// characters, e.g. "k7m2q-x9d4r", and read without regard to case,
+// spaces or dashes.
+const (
+    recoveryCodeCount    = 10
+    recoveryCodeLength   = 10
+    recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// errOTPRequired is returned by checkSecondFactor when the login carries
+// neither a code nor a recovery code.
+var errOTPRequired = errors.New("otp_required")
+
+// totpState holds the secrets of setups not yet enabled, by username, and
+// the last period a code was accepted for each user, so that a code cannot
+// be used twice.
+type totpState struct {
+    mu      sync.Mutex
+    pending map[string]totpSetup
+    used    map[string]int64
+}
+
+// totpSetup is a secret offered by POST /api/2fa/setup.
+type totpSetup struct {
+    secret  string
+    expires time.Time
+}
+
+// newTOTPSecret returns a random secret, base32 encoded without padding as
+// authenticator apps expect.
+func newTOTPSecret() (string, error) {
+    b := make([]byte, 20)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// decodeTOTPSecret returns the key of a secret from newTOTPSecret.
+func decodeTOTPSecret(secret string) ([]byte, error) {
+    return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+}
+
+// totpCode returns the code of key for the given period.
+func totpCode(key []byte, period int64) string {
+    mac := hmac.New(sha1.New, key)
+    var msg [8]byte
+    binary.BigEndian.PutUint64(msg[:], uint64(period))
+    mac.Write(msg[:])
+    sum := mac.Sum(nil)
+    offset := sum[len(sum)-1] & 0x0f
+    value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+    mod := uint32(1)
+    for i := 0; i < totpDigits; i++ {
+        mod *= 10
+    }
+    return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// totpPeriodOf returns the period a code given at now should be for, or
+// -1 if code is not valid for secret within the allowed skew.
+func totpPeriodOf(secret, code string, now time.Time) int64 {
+    key, err := decodeTOTPSecret(secret)
+    code = strings.TrimSpace(code)
+    if err != nil || len(code) != totpDigits {
+        return -1
+    }
+    current := now.Unix() / totpPeriod
+    for p := current - totpSkew; p <= current+totpSkew; p++ {
+        if hmac.Equal([]byte(totpCode(key, p)), []byte(code)) {
+            return p
+        }
+    }
+    return -1
+}
+
+// verify reports whether code is valid for username's secret and not one
+// already used, recording its period if so.
+func (ts *totpState) verify(username, secret, code string) bool {
+    p := totpPeriodOf(secret, code, time.Now())
+    if p < 0 {
+        return false
+    }
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    if p <= ts.used[username] {
+        return false
+    }
+    if ts.used == nil {
+        ts.used = make(map[string]int64)
+    }
+    ts.used[username] = p
+    return true
+}
+
+// offer records secret as the pending setup of username.
+func (ts *totpState) offer(username, secret string) {
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    now := time.Now()
+    for name, p := range ts.pending {
+        if now.After(p.expires) {
+            delete(ts.pending, name)
+        }
+    }
+    if ts.pending == nil {
+        ts.pending = make(map[string]totpSetup)
+    }
+    ts.pending[username] = totpSetup{secret: secret, expires: now.Add(totpSetupTimeout)}
+}
+
+// pendingSecret returns the unexpired pending setup of username.
+func (ts *totpState) pendingSecret(username string) (string, bool) {
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    p, ok := ts.pending[username]
+    if !ok || time.Now().After(p.expires) {
+        return "", false
+    }
+    return p.secret, true
+}
+
+// drop forgets the pending setup of username.
+func (ts *totpState) drop(username string) {
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    delete(ts.pending, username)
+}
+
+// newRecoveryCodes returns a fresh set of recovery codes and their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+    codes := make([]string, recoveryCodeCount)
+    hashes := make([]string, recoveryCodeCount)
+    b := make([]byte, recoveryCodeLength)
+    for i := range codes {
+        if _, err := rand.Read(b); err != nil {
+            return nil, nil, err
+        }
+        code := make([]byte, recoveryCodeLength)
+        for j, c := range b {
+            code[j] = recoveryCodeAlphabet[int(c)%len(recoveryCodeAlphabet)]
+        }
+        codes[i] = string(code[:recoveryCodeLength/2]) + "-" + string(code[recoveryCodeLength/2:])
+        hash, err := hashPassword(string(code))
+        if err != nil {
+            return nil, nil, err
+        }
+        hashes[i] = hash
+    }
+    return codes, hashes, nil
+}
+
+// normaliseRecoveryCode strips the spaces and dashes of code and folds it
+// to lower case.
+func normaliseRecoveryCode(code string) string {
+    return strings.Map(func(r rune) rune {
+        if r == '-' || r == ' ' {
+            return -1
+        }
+        return r
+    }, strings.ToLower(code))
+}
+
+// useRecoveryCode consumes the recovery code of username matching code,
+// returning the number left.  The hashes are checked without holding the
+// configuration lock, as that is slow, but the matching one is removed
+// under it only if still there, so two logins cannot both use it.
+func (s *Server) useRecoveryCode(username, code string) (int, error) {
+    code = normaliseRecoveryCode(code)
+    user, _ := s.cfgMgr.FindUser(username)
+    match := ""
+    for _, hash := range user.RecoveryCodes {
+        if checkPasswordHash(code, hash) == nil {
+            match = hash
+            break
+        }
+    }
+    if match == "" {
+        return 0, errors.New("invalid recovery code")
+    }
+    left := 0
+    err := s.cfgMgr.Update(func(c *Config) error {
+        for i, u := range c.Users {
+            if u.Username != username {
+                continue
+            }
+            for j, hash := range u.RecoveryCodes {
+                if hash == match {
+                    c.Users[i].RecoveryCodes = append(u.RecoveryCodes[:j:j], u.RecoveryCodes[j+1:]...)
+                    left = len(c.Users[i].RecoveryCodes)
+                    return nil
+                }
+            }
+        }
+        return errors.New("recovery code already used")
+    })
+    return left, err
+}
+
+// checkSecondFactor checks the code or recovery code sent with a password
+// login by user, who has two-factor on.  Use of a recovery code is logged
+// as a security event.
+func (s *Server) checkSecondFactor(r *http.Request, user User, otp, recovery string) error {
+    switch {
+    case otp != "":
+        if !s.totp.verify(user.Username, user.TOTPSecret, otp) {
+            return errors.New("invalid code")
+        }
+        return nil
+    case recovery != "":
+        left, err := s.useRecoveryCode(user.Username, recovery)
+        if err != nil {
+            return err
+        }
+        s.logger.Log("security: recovery code used by %s from %s, %d left", user.Username, s.clientIP(r), left)
+        s.audit(r, user.Username, "user.recovery_code", user.Username, nil, map[string]int{"left": left})
+        return nil
+    }
+    return errOTPRequired
+}
+
+// handleTwoFactor manages the two-factor login of the logged in user:
+//
+//   GET  /api/2fa                 {"enabled": true, "recovery_codes_left": 9}
+//   POST /api/2fa/setup           {"password": "..."} returns the new secret
+//   POST /api/2fa/enable          {"code": "123456"} returns recovery codes
+//   POST /api/2fa/recovery_codes  {"password": "..."} returns new ones
+//   POST /api/2fa/disable         {"password": "..."}
+//
+// Regenerating the recovery codes invalidates those unused.
+func (s *Server) handleTwoFactor(w http.ResponseWriter, r *http.Request, user User) {
+    action := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/2fa"), "/")
+    if action == "" {
+        if r.Method != http.MethodGet {
+            http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(map[string]any{"enabled": user.TOTPSecret != "", "recovery_codes_left": len(user.RecoveryCodes)})
+        return
+    }
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if s.configReadOnly(w) {
+        return
+    }
+    var req struct {
+        Password string `json:"password"`
+        Code     string `json:"code"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "invalid JSON", http.StatusBadRequest)
+        return
+    }
+    if action != "enable" {
+        if _, err := s.cfgMgr.Authenticate(user.Username, req.Password); err != nil {
+            s.recordFailedLogin(r, user.Username)
+            http.Error(w, "wrong password", http.StatusForbidden)
+            return
+        }
+    }
+    switch action {
+    case "setup":
+        if user.TOTPSecret != "" {
+            http.Error(w, "two-factor login is already on", http.StatusConflict)
+            return
+        }
+        secret, err := newTOTPSecret()
+        if err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        s.totp.offer(user.Username, secret)
+        u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/Minder:" + user.Username}
+        u.RawQuery = url.Values{"secret": {secret}, "issuer": {"Minder"}}.Encode()
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(map[string]string{"secret": secret, "otpauth_url": u.String()})
+    case "enable":
+        secret, ok := s.totp.pendingSecret(user.Username)
+        if !ok {
+            http.Error(w, "no setup in progress, start again", http.StatusConflict)
+            return
+        }
+        if !s.totp.verify(user.Username, secret, req.Code) {
+            http.Error(w, "invalid code", http.StatusForbidden)
+            return
+        }
+        codes, err := s.setTwoFactor(user.Username, secret, true)
+        if err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        s.totp.drop(user.Username)
+        s.logger.Log("two-factor login enabled by %s", user.Username)
+        s.audit(r, user.Username, "user.2fa_enable", user.Username, nil, nil)
+        s.adminChange(user.Username, "two-factor login of %s enabled", user.Username)
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+    case "recovery_codes":
+        if user.TOTPSecret == "" {
+            http.Error(w, "two-factor login is off", http.StatusConflict)
+            return
+        }
+        codes, err := s.setTwoFactor(user.Username, user.TOTPSecret, true)
+        if err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        s.logger.Log("recovery codes of %s regenerated", user.Username)
+        s.audit(r, user.Username, "user.recovery_codes", user.Username, nil, nil)
+        s.adminChange(user.Username, "recovery codes of %s regenerated", user.Username)
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+    case "disable":
+        if _, err := s.setTwoFactor(user.Username, "", false); err != nil {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+            return
+        }
+        s.logger.Log("two-factor login disabled by %s", user.Username)
+        s.audit(r, user.Username, "user.2fa_disable", user.Username, nil, nil)
+        s.adminChange(user.Username, "two-factor login of %s disabled", user.Username)
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.NotFound(w, r)
+    }
+}
+
+// setTwoFactor stores secret as the TOTP secret of username, "" to turn
+// two-factor off, with a new set of recovery codes if codes is set, which
+// it returns.
+func (s *Server) setTwoFactor(username, secret string, codes bool) ([]string, error) {
+    var plain, hashes []string
+    if codes {
+        var err error
+        if plain, hashes, err = newRecoveryCodes(); err != nil {
+            return nil, err
+        }
+    }
+    err := s.cfgMgr.Update(func(c *Config) error {
+        for i, u := range c.Users {
+            if u.Username == username {
+                c.Users[i].TOTPSecret = secret
+                c.Users[i].RecoveryCodes = hashes
+                return nil
+            }
+        }
+        return errors.New("not found")
+    })
+    return plain, err
+}
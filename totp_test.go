@@ -0,0 +1,102 @@
+package main
+
+// Tests of two-factor login: codes from an authenticator app and the
+// one-time recovery codes.
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890", base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+    tests := []struct {
+        unix int64
+        code string
+    }{
+        {59, "287082"},
+        {1111111109, "081804"},
+        {1234567890, "005924"},
+        {2000000000, "279037"},
+    }
+    for _, tt := range tests {
+        now := time.Unix(tt.unix, 0)
+        if p := totpPeriodOf(rfc6238Secret, tt.code, now); p != tt.unix/totpPeriod {
+            t.Errorf("code %s at %d: period %d, want %d", tt.code, tt.unix, p, tt.unix/totpPeriod)
+        }
+    }
+}
+
+func TestTOTPSkew(t *testing.T) {
+    at := time.Unix(1111111109, 0)
+    tests := []struct {
+        offset time.Duration
+        valid  bool
+    }{
+        {0, true},
+        {-totpPeriod * time.Second, true},
+        {totpPeriod * time.Second, true},
+        {-2 * totpPeriod * time.Second, false},
+        {2 * totpPeriod * time.Second, false},
+    }
+    for _, tt := range tests {
+        if got := totpPeriodOf(rfc6238Secret, "081804", at.Add(tt.offset)) >= 0; got != tt.valid {
+            t.Errorf("offset %s: valid %v, want %v", tt.offset, got, tt.valid)
+        }
+    }
+    if totpPeriodOf(rfc6238Secret, "81804", at) >= 0 {
+        t.Error("a short code was accepted")
+    }
+}
+
+func TestTOTPReplay(t *testing.T) {
+    var ts totpState
+    secret, err := newTOTPSecret()
+    if err != nil {
+        t.Fatal(err)
+    }
+    key, _ := decodeTOTPSecret(secret)
+    code := totpCode(key, time.Now().Unix()/totpPeriod)
+    if !ts.verify("alice", secret, code) {
+        t.Fatal("current code rejected")
+    }
+    if ts.verify("alice", secret, code) {
+        t.Error("code accepted twice")
+    }
+    // An earlier code still within the skew is refused too.
+    if ts.verify("alice", secret, totpCode(key, time.Now().Unix()/totpPeriod-1)) {
+        t.Error("earlier code accepted after a later one")
+    }
+}
+
+func TestRecoveryCodeSingleUse(t *testing.T) {
+    codes, hashes, err := newRecoveryCodes()
+    if err != nil {
+        t.Fatal(err)
+    }
+    cfg := validTestConfig()
+    cfg.Users = []User{{Username: "alice", Role: RoleAdmin, TOTPSecret: rfc6238Secret, RecoveryCodes: hashes}}
+    s, _ := newTestServer(t, cfg)
+    // Codes are read without regard to case or dashes.
+    plain := strings.ToUpper(normaliseRecoveryCode(codes[3]))
+    left, err := s.useRecoveryCode("alice", plain[:5]+" "+plain[5:])
+    if err != nil {
+        t.Fatalf("first use: %v", err)
+    }
+    if left != recoveryCodeCount-1 {
+        t.Errorf("%d codes left, want %d", left, recoveryCodeCount-1)
+    }
+    if _, err := s.useRecoveryCode("alice", codes[3]); err == nil {
+        t.Error("recovery code accepted twice")
+    }
+    if _, err := s.useRecoveryCode("alice", "aaaaa-aaaaa"); err == nil {
+        t.Error("unknown recovery code accepted")
+    }
+    if _, err := s.useRecoveryCode("alice", codes[4]); err != nil {
+        t.Errorf("another code: %v", err)
+    }
+}
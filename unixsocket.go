@@ -0,0 +1,136 @@
+package main
+
+// This file lets the server listen on a Unix domain socket, for a reverse
+// proxy such as nginx on the same machine, so that access is governed by
+// file permissions rather than a TCP port.  A listen entry of the form
+// unix:/run/minder/minder.sock creates the socket with the mode and group
+// of unix_socket (default 0660 and the server's group) and serves plain
+// HTTP on it, whatever listen_mode says.  A socket file left by a server
+// that did not stop cleanly is removed at startup; one still answering
+// means another server is running, and startup fails.  The socket is
+// removed when its listener is closed at shutdown.  A socket peer has no
+// address, so the proxy is trusted as if listed in trusted_proxies and
+// the client address is taken from X-Forwarded-For.
+
+import (
+    "fmt"
+    "net"
+    "net/http"
+    "os"
+    "os/user"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "golang.org/x/net/netutil"
+)
+
+// unixPrefix marks a listen entry naming a Unix domain socket.
+const unixPrefix = "unix:"
+
+// defaultSocketMode is the permission of the socket when unix_socket.mode
+// is empty: the server's user and group may connect.
+const defaultSocketMode os.FileMode = 0660
+
+// unixSocketPath returns the path of the socket named by the listen entry
+// addr, and whether it names one.
+func unixSocketPath(addr string) (string, bool) {
+    if !strings.HasPrefix(addr, unixPrefix) {
+        return "", false
+    }
+    return strings.TrimPrefix(addr, unixPrefix), true
+}
+
+// socketMode returns the permission of sockets for uc.
+func socketMode(uc *UnixSocketConfig) (os.FileMode, error) {
+    if uc == nil || uc.Mode == "" {
+        return defaultSocketMode, nil
+    }
+    mode, err := strconv.ParseUint(uc.Mode, 8, 32)
+    if err != nil || mode > 0777 {
+        return 0, fmt.Errorf("unix_socket mode %q must be octal permissions, e.g. 0660", uc.Mode)
+    }
+    return os.FileMode(mode), nil
+}
+
+// validateUnixSocket checks the unix_socket section of config.json.
+func validateUnixSocket(uc *UnixSocketConfig) error {
+    _, err := socketMode(uc)
+    return err
+}
+
+// listenUnix creates the socket at path with the mode and group of uc,
+// first removing a stale socket left there.
+func listenUnix(path string, uc *UnixSocketConfig, limits *HTTPLimitsConfig) (net.Listener, error) {
+    mode, err := socketMode(uc)
+    if err != nil {
+        return nil, err
+    }
+    gid := -1
+    if uc != nil && uc.Group != "" {
+        g, err := user.LookupGroup(uc.Group)
+        if err != nil {
+            return nil, err
+        }
+        if gid, err = strconv.Atoi(g.Gid); err != nil {
+            return nil, fmt.Errorf("group %s has no numeric id", uc.Group)
+        }
+    }
+    if err := removeStaleSocket(path); err != nil {
+        return nil, err
+    }
+    ln, err := net.Listen("unix", path)
+    if err != nil {
+        return nil, err
+    }
+    if gid >= 0 {
+        if err := os.Chown(path, -1, gid); err != nil {
+            ln.Close()
+            return nil, err
+        }
+    }
+    if err := os.Chmod(path, mode); err != nil {
+        ln.Close()
+        return nil, err
+    }
+    max := defaultMaxConnections
+    if limits != nil && limits.MaxConnections > 0 {
+        max = limits.MaxConnections
+    }
+    return netutil.LimitListener(ln, max), nil
+}
+
+// removeStaleSocket removes the socket at path if no server answers on
+// it.  Anything else at path is left alone and reported.
+func removeStaleSocket(path string) error {
+    info, err := os.Lstat(path)
+    if os.IsNotExist(err) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    if info.Mode()&os.ModeSocket == 0 {
+        return fmt.Errorf("%s exists and is not a socket", path)
+    }
+    if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+        conn.Close()
+        return fmt.Errorf("%s is in use by another server", path)
+    }
+    return os.Remove(path)
+}
+
+// viaUnixSocket reports whether r arrived over a Unix domain socket.
+func viaUnixSocket(r *http.Request) bool {
+    addr, _ := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+    return addr != nil && addr.Network() == "unix"
+}
+
+// validateUnixListen checks the socket path of a listen entry.
+func validateUnixListen(addr, path string) error {
+    if path == "" || !filepath.IsAbs(path) {
+        return fmt.Errorf("listen address %q must name an absolute socket path, e.g. unix:/run/minder/minder.sock", addr)
+    }
+    return nil
+}
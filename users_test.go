@@ -0,0 +1,108 @@
+package main
+
+// Tests of user management through /api/users.
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestOverlongPassword(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{{Username: "admin", Role: RoleAdmin, Admin: true}, {Username: "alice", Role: RoleOperator}}
+    s, _ := newTestServer(t, cfg)
+    admin := User{Username: "admin", Role: RoleAdmin, Admin: true}
+    long := strings.Repeat("Aa1!", 20)
+    w := httptest.NewRecorder()
+    s.handleUsers(w, httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"username": "bob", "password": "`+long+`"}`)), admin)
+    if w.Code != http.StatusBadRequest {
+        t.Errorf("create: status %d, want 400", w.Code)
+    }
+    w = httptest.NewRecorder()
+    s.handleUserByID(w, httptest.NewRequest("PUT", "/api/users/alice", strings.NewReader(`{"password": "`+long+`"}`)), admin)
+    if w.Code != http.StatusBadRequest {
+        t.Errorf("reset: status %d, want 400", w.Code)
+    }
+    // The configuration is still usable, and unchanged.
+    if got := s.cfgMgr.Get().Users; len(got) != 2 || got[1].PasswordHash != "" {
+        t.Errorf("users changed: %+v", got)
+    }
+}
+
+func TestOverlongPasswordChange(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{{Username: "admin", Role: RoleAdmin, Admin: true}, {Username: "vera", PasswordHash: testHash("Current-pass-1"), Role: RoleViewer}}
+    s, _ := newTestServer(t, cfg)
+    user, _ := s.cfgMgr.FindUser("vera")
+    body := `{"current_password": "Current-pass-1", "new_password": "` + strings.Repeat("Aa1!", 20) + `"}`
+    w := httptest.NewRecorder()
+    s.handlePassword(w, httptest.NewRequest("POST", "/api/password", strings.NewReader(body)), user)
+    if w.Code != http.StatusBadRequest {
+        t.Errorf("status %d, want 400", w.Code)
+    }
+    if u, _ := s.cfgMgr.FindUser("vera"); u.PasswordHash != user.PasswordHash {
+        t.Error("password changed")
+    }
+}
+
+func TestLegacyAdminFlag(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{
+        {Username: "admin", Role: RoleAdmin, Admin: true},
+        {Username: "root", Role: RoleAdmin, Admin: true},
+        {Username: "vera", Role: RoleViewer},
+        {Username: "alice", Role: RoleOperator},
+    }
+    s, _ := newTestServer(t, cfg)
+    admin := User{Username: "admin", Role: RoleAdmin, Admin: true}
+    tests := []struct {
+        username, body, want string
+    }{
+        {"vera", `{"admin": false}`, RoleViewer},
+        {"alice", `{"admin": false}`, RoleOperator},
+        {"root", `{"admin": false}`, RoleOperator},
+        {"vera", `{"admin": true}`, RoleAdmin},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        s.handleUserByID(w, httptest.NewRequest("PUT", "/api/users/"+tt.username, strings.NewReader(tt.body)), admin)
+        if w.Code != http.StatusNoContent {
+            t.Fatalf("%s %s: status %d", tt.username, tt.body, w.Code)
+        }
+        if u, _ := s.cfgMgr.FindUser(tt.username); u.Role != tt.want || u.Admin != (tt.want == RoleAdmin) {
+            t.Errorf("%s %s: role %q admin %v, want %q", tt.username, tt.body, u.Role, u.Admin, tt.want)
+        }
+    }
+}
+
+func TestLastAdminKept(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Users = []User{
+        {Username: "boss", Role: RoleAdmin, Admin: true},
+        {Username: "former", Role: RoleAdmin, Admin: true, Disabled: true},
+        {Username: "alice", Role: RoleOperator},
+    }
+    s, _ := newTestServer(t, cfg)
+    boss := User{Username: "boss", Role: RoleAdmin, Admin: true}
+    before := s.cfgMgr.Get().Users
+    tests := []struct {
+        method, body string
+    }{
+        {"DELETE", ""},
+        {"PUT", `{"admin": false}`},
+        {"PUT", `{"disabled": true}`},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        s.handleUserByID(w, httptest.NewRequest(tt.method, "/api/users/boss", strings.NewReader(tt.body)), boss)
+        if w.Code != http.StatusConflict {
+            t.Errorf("%s %s: status %d, want 409", tt.method, tt.body, w.Code)
+        }
+        if got := s.cfgMgr.Get().Users; !reflect.DeepEqual(got, before) {
+            t.Errorf("%s %s: users changed to %+v", tt.method, tt.body, got)
+        }
+    }
+}
@@ -0,0 +1,91 @@
+package main
+
+// This file keeps the user accounts in a file of their own when
+// users_file is set, so that config.json, free of password hashes, can be
+// kept in version control.  The users file holds {"users": [...]}, in
+// YAML if its extension says so, and is written with mode 0600.
+// Everything else stays in config.json.  Save writes the users file first
+// and config.json second, one save at a time, and keeps a backup of each.  When users_file is first set, the users
+// still in config.json are moved to the new file.
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "os"
+)
+
+// usersDocument is the content of the users file.
+type usersDocument struct {
+    Users []User `json:"users"`
+}
+
+// usersPath returns the users file of cfg, or "" if it has none.
+func (cm *ConfigManager) usersPath(cfg Config) string {
+    return cm.resolve(cfg.UsersFile)
+}
+
+// readUsers sets the users of cfg from its users file, if it has one.  If
+// the file is invalid, or missing after a move, its backup is used and the
+// reason returned.  If the file does not exist yet the users in config.json
+// are kept, to be moved by migrateUsers; users left in config.json by an
+// interrupted move are ignored once the file exists.
+func (cm *ConfigManager) readUsers(cfg *Config) (string, error) {
+    path := cm.usersPath(*cfg)
+    if path == "" {
+        return "", nil
+    }
+    users, err := readUsersFile(path, path)
+    if os.IsNotExist(err) && len(cfg.Users) > 0 {
+        return "", nil
+    }
+    if err == nil {
+        cfg.Users = users
+        return "", nil
+    }
+    backup := path + ".bak"
+    users, berr := readUsersFile(backup, path)
+    if berr != nil {
+        return "", fmt.Errorf("unable to load users_file %s: %w", path, err)
+    }
+    cfg.Users = users
+    return fmt.Sprintf("%s could not be loaded (%v), running on the backup %s", path, err, backup), nil
+}
+
+// readUsersFile reads the users from a users file, or its backup, in the
+// format of the users file at format.
+func readUsersFile(path, format string) ([]User, error) {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    data, err = decodeFile(format, data)
+    if err != nil {
+        return nil, fmt.Errorf("invalid %s: %w", path, err)
+    }
+    var doc usersDocument
+    if err := json.Unmarshal(data, &doc); err != nil {
+        return nil, fmt.Errorf("invalid %s: %w", path, err)
+    }
+    return doc.Users, nil
+}
+
+// migrateUsers moves the users out of config.json when users_file is set
+// and the users file does not exist yet.  While running on a backup
+// nothing is moved, so the fault stays visible, nor with read_only_config.
+func (cm *ConfigManager) migrateUsers() error {
+    cfg := cm.Get()
+    path := cm.usersPath(cfg)
+    if path == "" || cm.Fallback() != "" || cfg.ReadOnlyConfig {
+        return nil
+    }
+    if _, err := os.Stat(path); !os.IsNotExist(err) {
+        return nil
+    }
+    if err := cm.Save(); err != nil {
+        return fmt.Errorf("unable to move users to %s: %w", path, err)
+    }
+    log.Printf("Moved %d users from %s to %s", len(cfg.Users), cm.Path(), path)
+    return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+// This file implements walk tests: a technician triggers each sensor in
+// turn while the system records which zones responded, so that every zone
+// can be shown to have been physically verified recently.
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// walkTestMaxAge is the age after which a zone's last completed walk test
+// is reported as overdue.  The UI uses this to warn that a sensor has not
+// been physically verified recently.
+const walkTestMaxAge = 180 * 24 * time.Hour
+
+// walkTest records the progress of an in‑progress walk test.  While a walk
+// test is active the system behaves like TestWiring: every enabled zone is
+// polled and activations are logged, but no alerts are sent and no alarm is
+// raised.  The first activation of each zone is recorded in activations.
+type walkTest struct {
+    mu          sync.Mutex
+    started     time.Time
+    startedBy   string
+    activations map[int]time.Time
+}
+
+// walkTestZone describes the walk test state of a single zone in the
+// /api/walk_test/status response.
+type walkTestZone struct {
+    ID           int        `json:"id"`
+    Name         string     `json:"name"`
+    Verified     bool       `json:"verified"`
+    ActivatedAt  *time.Time `json:"activated_at,omitempty"`
+    LastWalkTest *time.Time `json:"last_walk_test,omitempty"`
+    // Overdue is true when the zone has never been walk tested or the last
+    // completed walk test is older than walkTestMaxAge.
+    Overdue bool `json:"overdue"`
+}
+
+// recordWalkTest notes the first activation of a zone during a walk test.
+// Subsequent activations of the same zone are ignored.
+func (s *Server) recordWalkTest(zone Zone) {
+    wt := s.walkTest
+    if wt == nil {
+        return
+    }
+    wt.mu.Lock()
+    if _, seen := wt.activations[zone.ID]; seen {
+        wt.mu.Unlock()
+        return
+    }
+    wt.activations[zone.ID] = time.Now()
+    wt.mu.Unlock()
+    s.triggerMu.Lock()
+    s.triggered[zone.ID] = true
+    s.triggerMu.Unlock()
+    s.logger.Log("walk test zone id=%d (%s) verified", zone.ID, zone.Name)
+}
+
+// endWalkTest stops the active walk test, persists the activation time of
+// every verified zone as its last walk test date and writes a summary event.
+// It returns false if no walk test was active.
+func (s *Server) endWalkTest(username string) bool {
+    wt := s.walkTest
+    if wt == nil {
+        return false
+    }
+    s.walkTest = nil
+    wt.mu.Lock()
+    activations := make(map[int]time.Time, len(wt.activations))
+    for id, t := range wt.activations {
+        activations[id] = t
+    }
+    wt.mu.Unlock()
+    var outstanding []string
+    verified, total := 0, 0
+    err := s.cfgMgr.Update(func(c *Config) error {
+        for i, z := range c.Zones {
+            if !z.Enabled {
+                continue
+            }
+            total++
+            if t, ok := activations[z.ID]; ok {
+                t := t
+                c.Zones[i].LastWalkTest = &t
+                verified++
+            } else {
+                outstanding = append(outstanding, z.Name)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        s.logger.Log("walk test results could not be saved: %v", err)
+    }
+    summary := fmt.Sprintf("walk test stopped by %s: %d/%d zones verified", username, verified, total)
+    if len(outstanding) > 0 {
+        summary += "; outstanding: " + strings.Join(outstanding, ", ")
+    }
+    s.logger.Log("%s", summary)
+    return true
+}
+
+// handleWalkTestStart begins a walk test.  Admins only.  The system must be
+// disarmed so that starting a test never silently disarms an armed system.
+func (s *Server) handleWalkTestStart(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    if s.walkTest != nil {
+        http.Error(w, "walk test already active", http.StatusConflict)
+        return
+    }
+    if s.currentMode != "Disarmed" {
+        http.Error(w, "system must be disarmed to start a walk test", http.StatusConflict)
+        return
+    }
+    s.triggerMu.Lock()
+    s.triggered = make(map[int]bool)
+    s.triggerMu.Unlock()
+    s.walkTest = &walkTest{
+        started:     time.Now(),
+        startedBy:   user.Username,
+        activations: make(map[int]time.Time),
+    }
+    s.testMode = 3
+    s.currentMode = "WalkTest"
+    s.logger.Log("walk test started by %s", user.Username)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleWalkTestStop ends the walk test, records the results and returns the
+// system to Disarmed.  Admins only.
+func (s *Server) handleWalkTestStop(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if !user.hasRole(RoleAdmin) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+        return
+    }
+    if !s.endWalkTest(user.Username) {
+        http.Error(w, "no walk test active", http.StatusConflict)
+        return
+    }
+    s.testMode = 0
+    s.currentMode = "Disarmed"
+    s.triggerMu.Lock()
+    s.triggered = make(map[int]bool)
+    s.triggerMu.Unlock()
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleWalkTestStatus reports which enabled zones have been verified in the
+// current walk test and which are outstanding, together with each zone's
+// last completed walk test date.  When no walk test is active the response
+// still lists the zones so the UI can show overdue warnings.
+func (s *Server) handleWalkTestStatus(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodGet {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    type status struct {
+        Active      bool           `json:"active"`
+        Started     *time.Time     `json:"started,omitempty"`
+        StartedBy   string         `json:"started_by,omitempty"`
+        Verified    []walkTestZone `json:"verified"`
+        Outstanding []walkTestZone `json:"outstanding"`
+    }
+    resp := status{Verified: []walkTestZone{}, Outstanding: []walkTestZone{}}
+    activations := map[int]time.Time{}
+    if wt := s.walkTest; wt != nil {
+        wt.mu.Lock()
+        for id, t := range wt.activations {
+            activations[id] = t
+        }
+        started := wt.started
+        resp.Active = true
+        resp.Started = &started
+        resp.StartedBy = wt.startedBy
+        wt.mu.Unlock()
+    }
+    cfg := s.cfgMgr.Get()
+    now := time.Now()
+    for _, z := range cfg.Zones {
+        if !z.Enabled {
+            continue
+        }
+        info := walkTestZone{
+            ID:           z.ID,
+            Name:         z.Name,
+            LastWalkTest: z.LastWalkTest,
+            Overdue:      z.LastWalkTest == nil || now.Sub(*z.LastWalkTest) > walkTestMaxAge,
+        }
+        if t, ok := activations[z.ID]; ok {
+            t := t
+            info.Verified = true
+            info.ActivatedAt = &t
+            resp.Verified = append(resp.Verified, info)
+        } else {
+            resp.Outstanding = append(resp.Outstanding, info)
+        }
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(resp)
+}
@@ -1,40 +0,0 @@
-(function(){const t=document.createElement("link").relList;if(t&&t.supports&&t.supports("modulepreload"))return;for(const l of document.querySelectorAll('link[rel="modulepreload"]'))r(l);new MutationObserver(l=>{for(const i of l)if(i.type==="childList")for(const o of i.addedNodes)o.tagName==="LINK"&&o.rel==="modulepreload"&&r(o)}).observe(document,{childList:!0,subtree:!0});function n(l){const i={};return l.integrity&&(i.integrity=l.integrity),l.referrerPolicy&&(i.referrerPolicy=l.referrerPolicy),l.crossOrigin==="use-credentials"?i.credentials="include":l.crossOrigin==="anonymous"?i.credentials="omit":i.credentials="same-origin",i}function r(l){if(l.ep)return;l.ep=!0;const i=n(l);fetch(l.href,i)}})();function wc(e){return e&&e.__esModule&&Object.prototype.hasOwnProperty.call(e,"default")?e.default:e}var Gu={exports:{}},cl={},Ju={exports:{}},M={};/**
- * @license React
- * react.production.min.js
- *
- * Copyright (c) Facebook, Inc. and its affiliates.
- *
- * This source code is licensed under the MIT license found in the
- * LICENSE file in the root directory of this source tree.
- */var or=Symbol.for("react.element"),xc=Symbol.for("react.portal"),Sc=Symbol.for("react.fragment"),kc=Symbol.for("react.strict_mode"),Ec=Symbol.for("react.profiler"),jc=Symbol.for("react.provider"),Cc=Symbol.for("react.context"),_c=Symbol.for("react.forward_ref"),Nc=Symbol.for("react.suspense"),Pc=Symbol.for("react.memo"),Tc=Symbol.for("react.lazy"),Vo=Symbol.iterator;function zc(e){return e===null||typeof e!="object"?null:(e=Vo&&e[Vo]||e["@@iterator"],typeof e=="function"?e:null)}var qu={isMounted:function(){return!1},enqueueForceUpdate:function(){},enqueueReplaceState:function(){},enqueueSetState:function(){}},bu=Object.assign,es={};function gn(e,t,n){this.props=e,this.context=t,this.refs=es,this.updater=n||qu}gn.prototype.isReactComponent={};gn.prototype.setState=function(e,t){if(typeof e!="object"&&typeof e!="function"&&e!=null)throw Error("setState(...): takes an object of state variables to update or a function which returns an object of state variables.");this.updater.enqueueSetState(this,e,t,"setState")};gn.prototype.forceUpdate=function(e){this.updater.enqueueForceUpdate(this,e,"forceUpdate")};function ts(){}ts.prototype=gn.prototype;function Qi(e,t,n){this.props=e,this.context=t,this.refs=es,this.updater=n||qu}var Ki=Qi.prototype=new ts;Ki.constructor=Qi;bu(Ki,gn.prototype);Ki.isPureReactComponent=!0;var Wo=Array.isArray,ns=Object.prototype.hasOwnProperty,Yi={current:null},rs={key:!0,ref:!0,__self:!0,__source:!0};function ls(e,t,n){var r,l={},i=null,o=null;if(t!=null)for(r in t.ref!==void 0&&(o=t.ref),t.key!==void 0&&(i=""+t.key),t)ns.call(t,r)&&!rs.hasOwnProperty(r)&&(l[r]=t[r]);var u=arguments.length-2;if(u===1)l.children=n;else if(1<u){for(var s=Array(u),d=0;d<u;d++)s[d]=arguments[d+2];l.children=s}if(e&&e.defaultProps)for(r in u=e.defaultProps,u)l[r]===void 0&&(l[r]=u[r]);return{$$typeof:or,type:e,key:i,ref:o,props:l,_owner:Yi.current}}function Lc(e,t){return{$$typeof:or,type:e.type,key:t,ref:e.ref,props:e.props,_owner:e._owner}}function Zi(e){return typeof e=="object"&&e!==null&&e.$$typeof===or}function Mc(e){var t={"=":"=0",":":"=2"};return"$"+e.replace(/[=:]/g,function(n){return t[n]})}var Bo=/\/+/g;function Nl(e,t){return typeof e=="object"&&e!==null&&e.key!=null?Mc(""+e.key):t.toString(36)}function Pr(e,t,n,r,l){var i=typeof e;(i==="undefined"||i==="boolean")&&(e=null);var o=!1;if(e===null)o=!0;else switch(i){case"string":case"number":o=!0;break;case"object":switch(e.$$typeof){case or:case xc:o=!0}}if(o)return o=e,l=l(o),e=r===""?"."+Nl(o,0):r,Wo(l)?(n="",e!=null&&(n=e.replace(Bo,"$&/")+"/"),Pr(l,t,n,"",function(d){return d})):l!=null&&(Zi(l)&&(l=Lc(l,n+(!l.key||o&&o.key===l.key?"":(""+l.key).replace(Bo,"$&/")+"/")+e)),t.push(l)),1;if(o=0,r=r===""?".":r+":",Wo(e))for(var u=0;u<e.length;u++){i=e[u];var s=r+Nl(i,u);o+=Pr(i,t,n,s,l)}else if(s=zc(e),typeof s=="function")for(e=s.call(e),u=0;!(i=e.next()).done;)i=i.value,s=r+Nl(i,u++),o+=Pr(i,t,n,s,l);else if(i==="object")throw t=String(e),Error("Objects are not valid as a React child (found: "+(t==="[object Object]"?"object with keys {"+Object.keys(e).join(", ")+"}":t)+"). If you meant to render a collection of children, use an array instead.");return o}function fr(e,t,n){if(e==null)return e;var r=[],l=0;return Pr(e,r,"","",function(i){return t.call(n,i,l++)}),r}function Oc(e){if(e._status===-1){var t=e._result;t=t(),t.then(function(n){(e._status===0||e._status===-1)&&(e._status=1,e._result=n)},function(n){(e._status===0||e._status===-1)&&(e._status=2,e._result=n)}),e._status===-1&&(e._status=0,e._result=t)}if(e._status===1)return e._result.default;throw e._result}var pe={current:null},Tr={transition:null},Dc={ReactCurrentDispatcher:pe,ReactCurrentBatchConfig:Tr,ReactCurrentOwner:Yi};function is(){throw Error("act(...) is not supported in production builds of React.")}M.Children={map:fr,forEach:function(e,t,n){fr(e,function(){t.apply(this,arguments)},n)},count:function(e){var t=0;return fr(e,function(){t++}),t},toArray:function(e){return fr(e,function(t){return t})||[]},only:function(e){if(!Zi(e))throw Error("React.Children.only expected to receive a single React element child.");return e}};M.Component=gn;M.Fragment=Sc;M.Profiler=Ec;M.PureComponent=Qi;M.StrictMode=kc;M.Suspense=Nc;M.__SECRET_INTERNALS_DO_NOT_USE_OR_YOU_WILL_BE_FIRED=Dc;M.act=is;M.cloneElement=function(e,t,n){if(e==null)throw Error("React.cloneElement(...): The argument must be a React element, but you passed "+e+".");var r=bu({},e.props),l=e.key,i=e.ref,o=e._owner;if(t!=null){if(t.ref!==void 0&&(i=t.ref,o=Yi.current),t.key!==void 0&&(l=""+t.key),e.type&&e.type.defaultProps)var u=e.type.defaultProps;for(s in t)ns.call(t,s)&&!rs.hasOwnProperty(s)&&(r[s]=t[s]===void 0&&u!==void 0?u[s]:t[s])}var s=arguments.length-2;if(s===1)r.children=n;else if(1<s){u=Array(s);for(var d=0;d<s;d++)u[d]=arguments[d+2];r.children=u}return{$$typeof:or,type:e.type,key:l,ref:i,props:r,_owner:o}};M.createContext=function(e){return e={$$typeof:Cc,_currentValue:e,_currentValue2:e,_threadCount:0,Provider:null,Consumer:null,_defaultValue:null,_globalName:null},e.Provider={$$typeof:jc,_context:e},e.Consumer=e};M.createElement=ls;M.createFactory=function(e){var t=ls.bind(null,e);return t.type=e,t};M.createRef=function(){return{current:null}};M.forwardRef=function(e){return{$$typeof:_c,render:e}};M.isValidElement=Zi;M.lazy=function(e){return{$$typeof:Tc,_payload:{_status:-1,_result:e},_init:Oc}};M.memo=function(e,t){return{$$typeof:Pc,type:e,compare:t===void 0?null:t}};M.startTransition=function(e){var t=Tr.transition;Tr.transition={};try{e()}finally{Tr.transition=t}};M.unstable_act=is;M.useCallback=function(e,t){return pe.current.useCallback(e,t)};M.useContext=function(e){return pe.current.useContext(e)};M.useDebugValue=function(){};M.useDeferredValue=function(e){return pe.current.useDeferredValue(e)};M.useEffect=function(e,t){return pe.current.useEffect(e,t)};M.useId=function(){return pe.current.useId()};M.useImperativeHandle=function(e,t,n){return pe.current.useImperativeHandle(e,t,n)};M.useInsertionEffect=function(e,t){return pe.current.useInsertionEffect(e,t)};M.useLayoutEffect=function(e,t){return pe.current.useLayoutEffect(e,t)};M.useMemo=function(e,t){return pe.current.useMemo(e,t)};M.useReducer=function(e,t,n){return pe.current.useReducer(e,t,n)};M.useRef=function(e){return pe.current.useRef(e)};M.useState=function(e){return pe.current.useState(e)};M.useSyncExternalStore=function(e,t,n){return pe.current.useSyncExternalStore(e,t,n)};M.useTransition=function(){return pe.current.useTransition()};M.version="18.3.1";Ju.exports=M;var R=Ju.exports;const Rc=wc(R);/**
- * @license React
- * react-jsx-runtime.production.min.js
- *
- * Copyright (c) Facebook, Inc. and its affiliates.
- *
- * This source code is licensed under the MIT license found in the
- * LICENSE file in the root directory of this source tree.
- */var Ic=R,Fc=Symbol.for("react.element"),Uc=Symbol.for("react.fragment"),Ac=Object.prototype.hasOwnProperty,$c=Ic.__SECRET_INTERNALS_DO_NOT_USE_OR_YOU_WILL_BE_FIRED.ReactCurrentOwner,Vc={key:!0,ref:!0,__self:!0,__source:!0};function os(e,t,n){var r,l={},i=null,o=null;n!==void 0&&(i=""+n),t.key!==void 0&&(i=""+t.key),t.ref!==void 0&&(o=t.ref);for(r in t)Ac.call(t,r)&&!Vc.hasOwnProperty(r)&&(l[r]=t[r]);if(e&&e.defaultProps)for(r in t=e.defaultProps,t)l[r]===void 0&&(l[r]=t[r]);return{$$typeof:Fc,type:e,key:i,ref:o,props:l,_owner:$c.current}}cl.Fragment=Uc;cl.jsx=os;cl.jsxs=os;Gu.exports=cl;var a=Gu.exports,us={exports:{}},je={},ss={exports:{}},as={};/**
- * @license React
- * scheduler.production.min.js
- *
- * Copyright (c) Facebook, Inc. and its affiliates.
- *
- * This source code is licensed under the MIT license found in the
- * LICENSE file in the root directory of this source tree.
- */(function(e){function t(C,T){var z=C.length;C.push(T);e:for(;0<z;){var W=z-1>>>1,Y=C[W];if(0<l(Y,T))C[W]=T,C[z]=Y,z=W;else break e}}function n(C){return C.length===0?null:C[0]}function r(C){if(C.length===0)return null;var T=C[0],z=C.pop();if(z!==T){C[0]=z;e:for(var W=0,Y=C.length,Bt=Y>>>1;W<Bt;){var $e=2*(W+1)-1,Sn=C[$e],Ve=$e+1,Ht=C[Ve];if(0>l(Sn,z))Ve<Y&&0>l(Ht,Sn)?(C[W]=Ht,C[Ve]=z,W=Ve):(C[W]=Sn,C[$e]=z,W=$e);else if(Ve<Y&&0>l(Ht,z))C[W]=Ht,C[Ve]=z,W=Ve;else break e}}return T}function l(C,T){var z=C.sortIndex-T.sortIndex;return z!==0?z:C.id-T.id}if(typeof performance=="object"&&typeof performance.now=="function"){var i=performance;e.unstable_now=function(){return i.now()}}else{var o=Date,u=o.now();e.unstable_now=function(){return o.now()-u}}var s=[],d=[],y=1,m=null,h=3,x=!1,S=!1,j=!1,O=typeof setTimeout=="function"?setTimeout:null,f=typeof clearTimeout=="function"?clearTimeout:null,c=typeof setImmediate<"u"?setImmediate:null;typeof navigator<"u"&&navigator.scheduling!==void 0&&navigator.scheduling.isInputPending!==void 0&&navigator.scheduling.isInputPending.bind(navigator.scheduling);function p(C){for(var T=n(d);T!==null;){if(T.callback===null)r(d);else if(T.startTime<=C)r(d),T.sortIndex=T.expirationTime,t(s,T);else break;T=n(d)}}function v(C){if(j=!1,p(C),!S)if(n(s)!==null)S=!0,Vt(k);else{var T=n(d);T!==null&&Wt(v,T.startTime-C)}}function k(C,T){S=!1,j&&(j=!1,f(P),P=-1),x=!0;var z=h;try{for(p(T),m=n(s);m!==null&&(!(m.expirationTime>T)||C&&!ce());){var W=m.callback;if(typeof W=="function"){m.callback=null,h=m.priorityLevel;var Y=W(m.expirationTime<=T);T=e.unstable_now(),typeof Y=="function"?m.callback=Y:m===n(s)&&r(s),p(T)}else r(s);m=n(s)}if(m!==null)var Bt=!0;else{var $e=n(d);$e!==null&&Wt(v,$e.startTime-T),Bt=!1}return Bt}finally{m=null,h=z,x=!1}}var E=!1,N=null,P=-1,$=5,L=-1;function ce(){return!(e.unstable_now()-L<$)}function lt(){if(N!==null){var C=e.unstable_now();L=C;var T=!0;try{T=N(!0,C)}finally{T?Ye():(E=!1,N=null)}}else E=!1}var Ye;if(typeof c=="function")Ye=function(){c(lt)};else if(typeof MessageChannel<"u"){var Ae=new MessageChannel,$t=Ae.port2;Ae.port1.onmessage=lt,Ye=function(){$t.postMessage(null)}}else Ye=function(){O(lt,0)};function Vt(C){N=C,E||(E=!0,Ye())}function Wt(C,T){P=O(function(){C(e.unstable_now())},T)}e.unstable_IdlePriority=5,e.unstable_ImmediatePriority=1,e.unstable_LowPriority=4,e.unstable_NormalPriority=3,e.unstable_Profiling=null,e.unstable_UserBlockingPriority=2,e.unstable_cancelCallback=function(C){C.callback=null},e.unstable_continueExecution=function(){S||x||(S=!0,Vt(k))},e.unstable_forceFrameRate=function(C){0>C||125<C?console.error("forceFrameRate takes a positive int between 0 and 125, forcing frame rates higher than 125 fps is not supported"):$=0<C?Math.floor(1e3/C):5},e.unstable_getCurrentPriorityLevel=function(){return h},e.unstable_getFirstCallbackNode=function(){return n(s)},e.unstable_next=function(C){switch(h){case 1:case 2:case 3:var T=3;break;default:T=h}var z=h;h=T;try{return C()}finally{h=z}},e.unstable_pauseExecution=function(){},e.unstable_requestPaint=function(){},e.unstable_runWithPriority=function(C,T){switch(C){case 1:case 2:case 3:case 4:case 5:break;default:C=3}var z=h;h=C;try{return T()}finally{h=z}},e.unstable_scheduleCallback=function(C,T,z){var W=e.unstable_now();switch(typeof z=="object"&&z!==null?(z=z.delay,z=typeof z=="number"&&0<z?W+z:W):z=W,C){case 1:var Y=-1;break;case 2:Y=250;break;case 5:Y=1073741823;break;case 4:Y=1e4;break;default:Y=5e3}return Y=z+Y,C={id:y++,callback:T,priorityLevel:C,startTime:z,expirationTime:Y,sortIndex:-1},z>W?(C.sortIndex=z,t(d,C),n(s)===null&&C===n(d)&&(j?(f(P),P=-1):j=!0,Wt(v,z-W))):(C.sortIndex=Y,t(s,C),S||x||(S=!0,Vt(k))),C},e.unstable_shouldYield=ce,e.unstable_wrapCallback=function(C){var T=h;return function(){var z=h;h=T;try{return C.apply(this,arguments)}finally{h=z}}}})(as);ss.exports=as;var Wc=ss.exports;/**
- * @license React
- * react-dom.production.min.js
- *
- * Copyright (c) Facebook, Inc. and its affiliates.
- *
- * This source code is licensed under the MIT license found in the
- * LICENSE file in the root directory of this source tree.
- */var Bc=R,Ee=Wc;function w(e){for(var t="https://reactjs.org/docs/error-decoder.html?invariant="+e,n=1;n<arguments.length;n++)t+="&args[]="+encodeURIComponent(arguments[n]);return"Minified React error #"+e+"; visit "+t+" for the full message or use the non-minified dev environment for full errors and additional helpful warnings."}var cs=new Set,Bn={};function Ut(e,t){fn(e,t),fn(e+"Capture",t)}function fn(e,t){for(Bn[e]=t,e=0;e<t.length;e++)cs.add(t[e])}var be=!(typeof window>"u"||typeof window.document>"u"||typeof window.document.createElement>"u"),bl=Object.prototype.hasOwnProperty,Hc=/^[:A-Z_a-z\u00C0-\u00D6\u00D8-\u00F6\u00F8-\u02FF\u0370-\u037D\u037F-\u1FFF\u200C-\u200D\u2070-\u218F\u2C00-\u2FEF\u3001-\uD7FF\uF900-\uFDCF\uFDF0-\uFFFD][:A-Z_a-z\u00C0-\u00D6\u00D8-\u00F6\u00F8-\u02FF\u0370-\u037D\u037F-\u1FFF\u200C-\u200D\u2070-\u218F\u2C00-\u2FEF\u3001-\uD7FF\uF900-\uFDCF\uFDF0-\uFFFD\-.0-9\u00B7\u0300-\u036F\u203F-\u2040]*$/,Ho={},Qo={};function Qc(e){return bl.call(Qo,e)?!0:bl.call(Ho,e)?!1:Hc.test(e)?Qo[e]=!0:(Ho[e]=!0,!1)}function Kc(e,t,n,r){if(n!==null&&n.type===0)return!1;switch(typeof t){case"function":case"symbol":return!0;case"boolean":return r?!1:n!==null?!n.acceptsBooleans:(e=e.toLowerCase().slice(0,5),e!=="data-"&&e!=="aria-");default:return!1}}function Yc(e,t,n,r){if(t===null||typeof t>"u"||Kc(e,t,n,r))return!0;if(r)return!1;if(n!==null)switch(n.type){case 3:return!t;case 4:return t===!1;case 5:return isNaN(t);case 6:return isNaN(t)||1>t}return!1}function he(e,t,n,r,l,i,o){this.acceptsBooleans=t===2||t===3||t===4,this.attributeName=r,this.attributeNamespace=l,this.mustUseProperty=n,this.propertyName=e,this.type=t,this.sanitizeURL=i,this.removeEmptyString=o}var ie={};"children dangerouslySetInnerHTML defaultValue defaultChecked innerHTML suppressContentEditableWarning suppressHydrationWarning style".split(" ").forEach(function(e){ie[e]=new he(e,0,!1,e,null,!1,!1)});[["acceptCharset","accept-charset"],["className","class"],["htmlFor","for"],["httpEquiv","http-equiv"]].forEach(function(e){var t=e[0];ie[t]=new he(t,1,!1,e[1],null,!1,!1)});["contentEditable","draggable","spellCheck","value"].forEach(function(e){ie[e]=new he(e,2,!1,e.toLowerCase(),null,!1,!1)});["autoReverse","externalResourcesRequired","focusable","preserveAlpha"].forEach(function(e){ie[e]=new he(e,2,!1,e,null,!1,!1)});"allowFullScreen async autoFocus autoPlay controls default defer disabled disablePictureInPicture disableRemotePlayback formNoValidate hidden loop noModule noValidate open playsInline readOnly required reversed scoped seamless itemScope".split(" ").forEach(function(e){ie[e]=new he(e,3,!1,e.toLowerCase(),null,!1,!1)});["checked","multiple","muted","selected"].forEach(function(e){ie[e]=new he(e,3,!0,e,null,!1,!1)});["capture","download"].forEach(function(e){ie[e]=new he(e,4,!1,e,null,!1,!1)});["cols","rows","size","span"].forEach(function(e){ie[e]=new he(e,6,!1,e,null,!1,!1)});["rowSpan","start"].forEach(function(e){ie[e]=new he(e,5,!1,e.toLowerCase(),null,!1,!1)});var Xi=/[\-:]([a-z])/g;function Gi(e){return e[1].toUpperCase()}"accent-height alignment-baseline arabic-form baseline-shift cap-height clip-path clip-rule color-interpolation color-interpolation-filters color-profile color-rendering dominant-baseline enable-background fill-opacity fill-rule flood-color flood-opacity font-family font-size font-size-adjust font-stretch font-style font-variant font-weight glyph-name glyph-orientation-horizontal glyph-orientation-vertical horiz-adv-x horiz-origin-x image-rendering letter-spacing lighting-color marker-end marker-mid marker-start overline-position overline-thickness paint-order panose-1 pointer-events rendering-intent shape-rendering stop-color stop-opacity strikethrough-position strikethrough-thickness stroke-dasharray stroke-dashoffset stroke-linecap stroke-linejoin stroke-miterlimit stroke-opacity stroke-width text-anchor text-decoration text-rendering underline-position underline-thickness unicode-bidi unicode-range units-per-em v-alphabetic v-hanging v-ideographic v-mathematical vector-effect vert-adv-y vert-origin-x vert-origin-y word-spacing writing-mode xmlns:xlink x-height".split(" ").forEach(function(e){var t=e.replace(Xi,Gi);ie[t]=new he(t,1,!1,e,null,!1,!1)});"xlink:actuate xlink:arcrole xlink:role xlink:show xlink:title xlink:type".split(" ").forEach(function(e){var t=e.replace(Xi,Gi);ie[t]=new he(t,1,!1,e,"http://www.w3.org/1999/xlink",!1,!1)});["xml:base","xml:lang","xml:space"].forEach(function(e){var t=e.replace(Xi,Gi);ie[t]=new he(t,1,!1,e,"http://www.w3.org/XML/1998/namespace",!1,!1)});["tabIndex","crossOrigin"].forEach(function(e){ie[e]=new he(e,1,!1,e.toLowerCase(),null,!1,!1)});ie.xlinkHref=new he("xlinkHref",1,!1,"xlink:href","http://www.w3.org/1999/xlink",!0,!1);["src","href","action","formAction"].forEach(function(e){ie[e]=new he(e,1,!1,e.toLowerCase(),null,!0,!0)});function Ji(e,t,n,r){var l=ie.hasOwnProperty(t)?ie[t]:null;(l!==null?l.type!==0:r||!(2<t.length)||t[0]!=="o"&&t[0]!=="O"||t[1]!=="n"&&t[1]!=="N")&&(Yc(t,n,l,r)&&(n=null),r||l===null?Qc(t)&&(n===null?e.removeAttribute(t):e.setAttribute(t,""+n)):l.mustUseProperty?e[l.propertyName]=n===null?l.type===3?!1:"":n:(t=l.attributeName,r=l.attributeNamespace,n===null?e.removeAttribute(t):(l=l.type,n=l===3||l===4&&n===!0?"":""+n,r?e.setAttributeNS(r,t,n):e.setAttribute(t,n))))}var rt=Bc.__SECRET_INTERNALS_DO_NOT_USE_OR_YOU_WILL_BE_FIRED,dr=Symbol.for("react.element"),Kt=Symbol.for("react.portal"),Yt=Symbol.for("react.fragment"),qi=Symbol.for("react.strict_mode"),ei=Symbol.for("react.profiler"),fs=Symbol.for("react.provider"),ds=Symbol.for("react.context"),bi=Symbol.for("react.forward_ref"),ti=Symbol.for("react.suspense"),ni=Symbol.for("react.suspense_list"),eo=Symbol.for("react.memo"),ot=Symbol.for("react.lazy"),ps=Symbol.for("react.offscreen"),Ko=Symbol.iterator;function kn(e){return e===null||typeof e!="object"?null:(e=Ko&&e[Ko]||e["@@iterator"],typeof e=="function"?e:null)}var K=Object.assign,Pl;function zn(e){if(Pl===void 0)try{throw Error()}catch(n){var t=n.stack.trim().match(/\n( *(at )?)/);Pl=t&&t[1]||""}return`
-`+Pl+e}var Tl=!1;function zl(e,t){if(!e||Tl)return"";Tl=!0;var n=Error.prepareStackTrace;Error.prepareStackTrace=void 0;try{if(t)if(t=function(){throw Error()},Object.defineProperty(t.prototype,"props",{set:function(){throw Error()}}),typeof Reflect=="object"&&Reflect.construct){try{Reflect.construct(t,[])}catch(d){var r=d}Reflect.construct(e,[],t)}else{try{t.call()}catch(d){r=d}e.call(t.prototype)}else{try{throw Error()}catch(d){r=d}e()}}catch(d){if(d&&r&&typeof d.stack=="string"){for(var l=d.stack.split(`
-`),i=r.stack.split(`
-`),o=l.length-1,u=i.length-1;1<=o&&0<=u&&l[o]!==i[u];)u--;for(;1<=o&&0<=u;o--,u--)if(l[o]!==i[u]){if(o!==1||u!==1)do if(o--,u--,0>u||l[o]!==i[u]){var s=`
-`+l[o].replace(" at new "," at ");return e.displayName&&s.includes("<anonymous>")&&(s=s.replace("<anonymous>",e.displayName)),s}while(1<=o&&0<=u);break}}}finally{Tl=!1,Error.prepareStackTrace=n}return(e=e?e.displayName||e.name:"")?zn(e):""}function Zc(e){switch(e.tag){case 5:return zn(e.type);case 16:return zn("Lazy");case 13:return zn("Suspense");case 19:return zn("SuspenseList");case 0:case 2:case 15:return e=zl(e.type,!1),e;case 11:return e=zl(e.type.render,!1),e;case 1:return e=zl(e.type,!0),e;default:return""}}function ri(e){if(e==null)return null;if(typeof e=="function")return e.displayName||e.name||null;if(typeof e=="string")return e;switch(e){case Yt:return"Fragment";case Kt:return"Portal";case ei:return"Profiler";case qi:return"StrictMode";case ti:return"Suspense";case ni:return"SuspenseList"}if(typeof e=="object")switch(e.$$typeof){case ds:return(e.displayName||"Context")+".Consumer";case fs:return(e._context.displayName||"Context")+".Provider";case bi:var t=e.render;return e=e.displayName,e||(e=t.displayName||t.name||"",e=e!==""?"ForwardRef("+e+")":"ForwardRef"),e;case eo:return t=e.displayName||null,t!==null?t:ri(e.type)||"Memo";case ot:t=e._payload,e=e._init;try{return ri(e(t))}catch{}}return null}function Xc(e){var t=e.type;switch(e.tag){case 24:return"Cache";case 9:return(t.displayName||"Context")+".Consumer";case 10:return(t._context.displayName||"Context")+".Provider";case 18:return"DehydratedFragment";case 11:return e=t.render,e=e.displayName||e.name||"",t.displayName||(e!==""?"ForwardRef("+e+")":"ForwardRef");case 7:return"Fragment";case 5:return t;case 4:return"Portal";case 3:return"Root";case 6:return"Text";case 16:return ri(t);case 8:return t===qi?"StrictMode":"Mode";case 22:return"Offscreen";case 12:return"Profiler";case 21:return"Scope";case 13:return"Suspense";case 19:return"SuspenseList";case 25:return"TracingMarker";case 1:case 0:case 17:case 2:case 14:case 15:if(typeof t=="function")return t.displayName||t.name||null;if(typeof t=="string")return t}return null}function xt(e){switch(typeof e){case"boolean":case"number":case"string":case"undefined":return e;case"object":return e;default:return""}}function hs(e){var t=e.type;return(e=e.nodeName)&&e.toLowerCase()==="input"&&(t==="checkbox"||t==="radio")}function Gc(e){var t=hs(e)?"checked":"value",n=Object.getOwnPropertyDescriptor(e.constructor.prototype,t),r=""+e[t];if(!e.hasOwnProperty(t)&&typeof n<"u"&&typeof n.get=="function"&&typeof n.set=="function"){var l=n.get,i=n.set;return Object.defineProperty(e,t,{configurable:!0,get:function(){return l.call(this)},set:function(o){r=""+o,i.call(this,o)}}),Object.defineProperty(e,t,{enumerable:n.enumerable}),{getValue:function(){return r},setValue:function(o){r=""+o},stopTracking:function(){e._valueTracker=null,delete e[t]}}}}function pr(e){e._valueTracker||(e._valueTracker=Gc(e))}function ms(e){if(!e)return!1;var t=e._valueTracker;if(!t)return!0;var n=t.getValue(),r="";return e&&(r=hs(e)?e.checked?"true":"false":e.value),e=r,e!==n?(t.setValue(e),!0):!1}function $r(e){if(e=e||(typeof document<"u"?document:void 0),typeof e>"u")return null;try{return e.activeElement||e.body}catch{return e.body}}function li(e,t){var n=t.checked;return K({},t,{defaultChecked:void 0,defaultValue:void 0,value:void 0,checked:n??e._wrapperState.initialChecked})}function Yo(e,t){var n=t.defaultValue==null?"":t.defaultValue,r=t.checked!=null?t.checked:t.defaultChecked;n=xt(t.value!=null?t.value:n),e._wrapperState={initialChecked:r,initialValue:n,controlled:t.type==="checkbox"||t.type==="radio"?t.checked!=null:t.value!=null}}function vs(e,t){t=t.checked,t!=null&&Ji(e,"checked",t,!1)}function ii(e,t){vs(e,t);var n=xt(t.value),r=t.type;if(n!=null)r==="number"?(n===0&&e.value===""||e.value!=n)&&(e.value=""+n):e.value!==""+n&&(e.value=""+n);else if(r==="submit"||r==="reset"){e.removeAttribute("value");return}t.hasOwnProperty("value")?oi(e,t.type,n):t.hasOwnProperty("defaultValue")&&oi(e,t.type,xt(t.defaultValue)),t.checked==null&&t.defaultChecked!=null&&(e.defaultChecked=!!t.defaultChecked)}function Zo(e,t,n){if(t.hasOwnProperty("value")||t.hasOwnProperty("defaultValue")){var r=t.type;if(!(r!=="submit"&&r!=="reset"||t.value!==void 0&&t.value!==null))return;t=""+e._wrapperState.initialValue,n||t===e.value||(e.value=t),e.defaultValue=t}n=e.name,n!==""&&(e.name=""),e.defaultChecked=!!e._wrapperState.initialChecked,n!==""&&(e.name=n)}function oi(e,t,n){(t!=="number"||$r(e.ownerDocument)!==e)&&(n==null?e.defaultValue=""+e._wrapperState.initialValue:e.defaultValue!==""+n&&(e.defaultValue=""+n))}var Ln=Array.isArray;function ln(e,t,n,r){if(e=e.options,t){t={};for(var l=0;l<n.length;l++)t["$"+n[l]]=!0;for(n=0;n<e.length;n++)l=t.hasOwnProperty("$"+e[n].value),e[n].selected!==l&&(e[n].selected=l),l&&r&&(e[n].defaultSelected=!0)}else{for(n=""+xt(n),t=null,l=0;l<e.length;l++){if(e[l].value===n){e[l].selected=!0,r&&(e[l].defaultSelected=!0);return}t!==null||e[l].disabled||(t=e[l])}t!==null&&(t.selected=!0)}}function ui(e,t){if(t.dangerouslySetInnerHTML!=null)throw Error(w(91));return K({},t,{value:void 0,defaultValue:void 0,children:""+e._wrapperState.initialValue})}function Xo(e,t){var n=t.value;if(n==null){if(n=t.children,t=t.defaultValue,n!=null){if(t!=null)throw Error(w(92));if(Ln(n)){if(1<n.length)throw Error(w(93));n=n[0]}t=n}t==null&&(t=""),n=t}e._wrapperState={initialValue:xt(n)}}function ys(e,t){var n=xt(t.value),r=xt(t.defaultValue);n!=null&&(n=""+n,n!==e.value&&(e.value=n),t.defaultValue==null&&e.defaultValue!==n&&(e.defaultValue=n)),r!=null&&(e.defaultValue=""+r)}function Go(e){var t=e.textContent;t===e._wrapperState.initialValue&&t!==""&&t!==null&&(e.value=t)}function gs(e){switch(e){case"svg":return"http://www.w3.org/2000/svg";case"math":return"http://www.w3.org/1998/Math/MathML";default:return"http://www.w3.org/1999/xhtml"}}function si(e,t){return e==null||e==="http://www.w3.org/1999/xhtml"?gs(t):e==="http://www.w3.org/2000/svg"&&t==="foreignObject"?"http://www.w3.org/1999/xhtml":e}var hr,ws=function(e){return typeof MSApp<"u"&&MSApp.execUnsafeLocalFunction?function(t,n,r,l){MSApp.execUnsafeLocalFunction(function(){return e(t,n,r,l)})}:e}(function(e,t){if(e.namespaceURI!=="http://www.w3.org/2000/svg"||"innerHTML"in e)e.innerHTML=t;else{for(hr=hr||document.createElement("div"),hr.innerHTML="<svg>"+t.valueOf().toString()+"</svg>",t=hr.firstChild;e.firstChild;)e.removeChild(e.firstChild);for(;t.firstChild;)e.appendChild(t.firstChild)}});function Hn(e,t){if(t){var n=e.firstChild;if(n&&n===e.lastChild&&n.nodeType===3){n.nodeValue=t;return}}e.textContent=t}var Dn={animationIterationCount:!0,aspectRatio:!0,borderImageOutset:!0,borderImageSlice:!0,borderImageWidth:!0,boxFlex:!0,boxFlexGroup:!0,boxOrdinalGroup:!0,columnCount:!0,columns:!0,flex:!0,flexGrow:!0,flexPositive:!0,flexShrink:!0,flexNegative:!0,flexOrder:!0,gridArea:!0,gridRow:!0,gridRowEnd:!0,gridRowSpan:!0,gridRowStart:!0,gridColumn:!0,gridColumnEnd:!0,gridColumnSpan:!0,gridColumnStart:!0,fontWeight:!0,lineClamp:!0,lineHeight:!0,opacity:!0,order:!0,orphans:!0,tabSize:!0,widows:!0,zIndex:!0,zoom:!0,fillOpacity:!0,floodOpacity:!0,stopOpacity:!0,strokeDasharray:!0,strokeDashoffset:!0,strokeMiterlimit:!0,strokeOpacity:!0,strokeWidth:!0},Jc=["Webkit","ms","Moz","O"];Object.keys(Dn).forEach(function(e){Jc.forEach(function(t){t=t+e.charAt(0).toUpperCase()+e.substring(1),Dn[t]=Dn[e]})});function xs(e,t,n){return t==null||typeof t=="boolean"||t===""?"":n||typeof t!="number"||t===0||Dn.hasOwnProperty(e)&&Dn[e]?(""+t).trim():t+"px"}function Ss(e,t){e=e.style;for(var n in t)if(t.hasOwnProperty(n)){var r=n.indexOf("--")===0,l=xs(n,t[n],r);n==="float"&&(n="cssFloat"),r?e.setProperty(n,l):e[n]=l}}var qc=K({menuitem:!0},{area:!0,base:!0,br:!0,col:!0,embed:!0,hr:!0,img:!0,input:!0,keygen:!0,link:!0,meta:!0,param:!0,source:!0,track:!0,wbr:!0});function ai(e,t){if(t){if(qc[e]&&(t.children!=null||t.dangerouslySetInnerHTML!=null))throw Error(w(137,e));if(t.dangerouslySetInnerHTML!=null){if(t.children!=null)throw Error(w(60));if(typeof t.dangerouslySetInnerHTML!="object"||!("__html"in t.dangerouslySetInnerHTML))throw Error(w(61))}if(t.style!=null&&typeof t.style!="object")throw Error(w(62))}}function ci(e,t){if(e.indexOf("-")===-1)return typeof t.is=="string";switch(e){case"annotation-xml":case"color-profile":case"font-face":case"font-face-src":case"font-face-uri":case"font-face-format":case"font-face-name":case"missing-glyph":return!1;default:return!0}}var fi=null;function to(e){return e=e.target||e.srcElement||window,e.correspondingUseElement&&(e=e.correspondingUseElement),e.nodeType===3?e.parentNode:e}var di=null,on=null,un=null;function Jo(e){if(e=ar(e)){if(typeof di!="function")throw Error(w(280));var t=e.stateNode;t&&(t=ml(t),di(e.stateNode,e.type,t))}}function ks(e){on?un?un.push(e):un=[e]:on=e}function Es(){if(on){var e=on,t=un;if(un=on=null,Jo(e),t)for(e=0;e<t.length;e++)Jo(t[e])}}function js(e,t){return e(t)}function Cs(){}var Ll=!1;function _s(e,t,n){if(Ll)return e(t,n);Ll=!0;try{return js(e,t,n)}finally{Ll=!1,(on!==null||un!==null)&&(Cs(),Es())}}function Qn(e,t){var n=e.stateNode;if(n===null)return null;var r=ml(n);if(r===null)return null;n=r[t];e:switch(t){case"onClick":case"onClickCapture":case"onDoubleClick":case"onDoubleClickCapture":case"onMouseDown":case"onMouseDownCapture":case"onMouseMove":case"onMouseMoveCapture":case"onMouseUp":case"onMouseUpCapture":case"onMouseEnter":(r=!r.disabled)||(e=e.type,r=!(e==="button"||e==="input"||e==="select"||e==="textarea")),e=!r;break e;default:e=!1}if(e)return null;if(n&&typeof n!="function")throw Error(w(231,t,typeof n));return n}var pi=!1;if(be)try{var En={};Object.defineProperty(En,"passive",{get:function(){pi=!0}}),window.addEventListener("test",En,En),window.removeEventListener("test",En,En)}catch{pi=!1}function bc(e,t,n,r,l,i,o,u,s){var d=Array.prototype.slice.call(arguments,3);try{t.apply(n,d)}catch(y){this.onError(y)}}var Rn=!1,Vr=null,Wr=!1,hi=null,ef={onError:function(e){Rn=!0,Vr=e}};function tf(e,t,n,r,l,i,o,u,s){Rn=!1,Vr=null,bc.apply(ef,arguments)}function nf(e,t,n,r,l,i,o,u,s){if(tf.apply(this,arguments),Rn){if(Rn){var d=Vr;Rn=!1,Vr=null}else throw Error(w(198));Wr||(Wr=!0,hi=d)}}function At(e){var t=e,n=e;if(e.alternate)for(;t.return;)t=t.return;else{e=t;do t=e,t.flags&4098&&(n=t.return),e=t.return;while(e)}return t.tag===3?n:null}function Ns(e){if(e.tag===13){var t=e.memoizedState;if(t===null&&(e=e.alternate,e!==null&&(t=e.memoizedState)),t!==null)return t.dehydrated}return null}function qo(e){if(At(e)!==e)throw Error(w(188))}function rf(e){var t=e.alternate;if(!t){if(t=At(e),t===null)throw Error(w(188));return t!==e?null:e}for(var n=e,r=t;;){var l=n.return;if(l===null)break;var i=l.alternate;if(i===null){if(r=l.return,r!==null){n=r;continue}break}if(l.child===i.child){for(i=l.child;i;){if(i===n)return qo(l),e;if(i===r)return qo(l),t;i=i.sibling}throw Error(w(188))}if(n.return!==r.return)n=l,r=i;else{for(var o=!1,u=l.child;u;){if(u===n){o=!0,n=l,r=i;break}if(u===r){o=!0,r=l,n=i;break}u=u.sibling}if(!o){for(u=i.child;u;){if(u===n){o=!0,n=i,r=l;break}if(u===r){o=!0,r=i,n=l;break}u=u.sibling}if(!o)throw Error(w(189))}}if(n.alternate!==r)throw Error(w(190))}if(n.tag!==3)throw Error(w(188));return n.stateNode.current===n?e:t}function Ps(e){return e=rf(e),e!==null?Ts(e):null}function Ts(e){if(e.tag===5||e.tag===6)return e;for(e=e.child;e!==null;){var t=Ts(e);if(t!==null)return t;e=e.sibling}return null}var zs=Ee.unstable_scheduleCallback,bo=Ee.unstable_cancelCallback,lf=Ee.unstable_shouldYield,of=Ee.unstable_requestPaint,G=Ee.unstable_now,uf=Ee.unstable_getCurrentPriorityLevel,no=Ee.unstable_ImmediatePriority,Ls=Ee.unstable_UserBlockingPriority,Br=Ee.unstable_NormalPriority,sf=Ee.unstable_LowPriority,Ms=Ee.unstable_IdlePriority,fl=null,Qe=null;function af(e){if(Qe&&typeof Qe.onCommitFiberRoot=="function")try{Qe.onCommitFiberRoot(fl,e,void 0,(e.current.flags&128)===128)}catch{}}var Ie=Math.clz32?Math.clz32:df,cf=Math.log,ff=Math.LN2;function df(e){return e>>>=0,e===0?32:31-(cf(e)/ff|0)|0}var mr=64,vr=4194304;function Mn(e){switch(e&-e){case 1:return 1;case 2:return 2;case 4:return 4;case 8:return 8;case 16:return 16;case 32:return 32;case 64:case 128:case 256:case 512:case 1024:case 2048:case 4096:case 8192:case 16384:case 32768:case 65536:case 131072:case 262144:case 524288:case 1048576:case 2097152:return e&4194240;case 4194304:case 8388608:case 16777216:case 33554432:case 67108864:return e&130023424;case 134217728:return 134217728;case 268435456:return 268435456;case 536870912:return 536870912;case 1073741824:return 1073741824;default:return e}}function Hr(e,t){var n=e.pendingLanes;if(n===0)return 0;var r=0,l=e.suspendedLanes,i=e.pingedLanes,o=n&268435455;if(o!==0){var u=o&~l;u!==0?r=Mn(u):(i&=o,i!==0&&(r=Mn(i)))}else o=n&~l,o!==0?r=Mn(o):i!==0&&(r=Mn(i));if(r===0)return 0;if(t!==0&&t!==r&&!(t&l)&&(l=r&-r,i=t&-t,l>=i||l===16&&(i&4194240)!==0))return t;if(r&4&&(r|=n&16),t=e.entangledLanes,t!==0)for(e=e.entanglements,t&=r;0<t;)n=31-Ie(t),l=1<<n,r|=e[n],t&=~l;return r}function pf(e,t){switch(e){case 1:case 2:case 4:return t+250;case 8:case 16:case 32:case 64:case 128:case 256:case 512:case 1024:case 2048:case 4096:case 8192:case 16384:case 32768:case 65536:case 131072:case 262144:case 524288:case 1048576:case 2097152:return t+5e3;case 4194304:case 8388608:case 16777216:case 33554432:case 67108864:return-1;case 134217728:case 268435456:case 536870912:case 1073741824:return-1;default:return-1}}function hf(e,t){for(var n=e.suspendedLanes,r=e.pingedLanes,l=e.expirationTimes,i=e.pendingLanes;0<i;){var o=31-Ie(i),u=1<<o,s=l[o];s===-1?(!(u&n)||u&r)&&(l[o]=pf(u,t)):s<=t&&(e.expiredLanes|=u),i&=~u}}function mi(e){return e=e.pendingLanes&-1073741825,e!==0?e:e&1073741824?1073741824:0}function Os(){var e=mr;return mr<<=1,!(mr&4194240)&&(mr=64),e}function Ml(e){for(var t=[],n=0;31>n;n++)t.push(e);return t}function ur(e,t,n){e.pendingLanes|=t,t!==536870912&&(e.suspendedLanes=0,e.pingedLanes=0),e=e.eventTimes,t=31-Ie(t),e[t]=n}function mf(e,t){var n=e.pendingLanes&~t;e.pendingLanes=t,e.suspendedLanes=0,e.pingedLanes=0,e.expiredLanes&=t,e.mutableReadLanes&=t,e.entangledLanes&=t,t=e.entanglements;var r=e.eventTimes;for(e=e.expirationTimes;0<n;){var l=31-Ie(n),i=1<<l;t[l]=0,r[l]=-1,e[l]=-1,n&=~i}}function ro(e,t){var n=e.entangledLanes|=t;for(e=e.entanglements;n;){var r=31-Ie(n),l=1<<r;l&t|e[r]&t&&(e[r]|=t),n&=~l}}var I=0;function Ds(e){return e&=-e,1<e?4<e?e&268435455?16:536870912:4:1}var Rs,lo,Is,Fs,Us,vi=!1,yr=[],dt=null,pt=null,ht=null,Kn=new Map,Yn=new Map,st=[],vf="mousedown mouseup touchcancel touchend touchstart auxclick dblclick pointercancel pointerdown pointerup dragend dragstart drop compositionend compositionstart keydown keypress keyup input textInput copy cut paste click change contextmenu reset submit".split(" ");function eu(e,t){switch(e){case"focusin":case"focusout":dt=null;break;case"dragenter":case"dragleave":pt=null;break;case"mouseover":case"mouseout":ht=null;break;case"pointerover":case"pointerout":Kn.delete(t.pointerId);break;case"gotpointercapture":case"lostpointercapture":Yn.delete(t.pointerId)}}function jn(e,t,n,r,l,i){return e===null||e.nativeEvent!==i?(e={blockedOn:t,domEventName:n,eventSystemFlags:r,nativeEvent:i,targetContainers:[l]},t!==null&&(t=ar(t),t!==null&&lo(t)),e):(e.eventSystemFlags|=r,t=e.targetContainers,l!==null&&t.indexOf(l)===-1&&t.push(l),e)}function yf(e,t,n,r,l){switch(t){case"focusin":return dt=jn(dt,e,t,n,r,l),!0;case"dragenter":return pt=jn(pt,e,t,n,r,l),!0;case"mouseover":return ht=jn(ht,e,t,n,r,l),!0;case"pointerover":var i=l.pointerId;return Kn.set(i,jn(Kn.get(i)||null,e,t,n,r,l)),!0;case"gotpointercapture":return i=l.pointerId,Yn.set(i,jn(Yn.get(i)||null,e,t,n,r,l)),!0}return!1}function As(e){var t=Pt(e.target);if(t!==null){var n=At(t);if(n!==null){if(t=n.tag,t===13){if(t=Ns(n),t!==null){e.blockedOn=t,Us(e.priority,function(){Is(n)});return}}else if(t===3&&n.stateNode.current.memoizedState.isDehydrated){e.blockedOn=n.tag===3?n.stateNode.containerInfo:null;return}}}e.blockedOn=null}function zr(e){if(e.blockedOn!==null)return!1;for(var t=e.targetContainers;0<t.length;){var n=yi(e.domEventName,e.eventSystemFlags,t[0],e.nativeEvent);if(n===null){n=e.nativeEvent;var r=new n.constructor(n.type,n);fi=r,n.target.dispatchEvent(r),fi=null}else return t=ar(n),t!==null&&lo(t),e.blockedOn=n,!1;t.shift()}return!0}function tu(e,t,n){zr(e)&&n.delete(t)}function gf(){vi=!1,dt!==null&&zr(dt)&&(dt=null),pt!==null&&zr(pt)&&(pt=null),ht!==null&&zr(ht)&&(ht=null),Kn.forEach(tu),Yn.forEach(tu)}function Cn(e,t){e.blockedOn===t&&(e.blockedOn=null,vi||(vi=!0,Ee.unstable_scheduleCallback(Ee.unstable_NormalPriority,gf)))}function Zn(e){function t(l){return Cn(l,e)}if(0<yr.length){Cn(yr[0],e);for(var n=1;n<yr.length;n++){var r=yr[n];r.blockedOn===e&&(r.blockedOn=null)}}for(dt!==null&&Cn(dt,e),pt!==null&&Cn(pt,e),ht!==null&&Cn(ht,e),Kn.forEach(t),Yn.forEach(t),n=0;n<st.length;n++)r=st[n],r.blockedOn===e&&(r.blockedOn=null);for(;0<st.length&&(n=st[0],n.blockedOn===null);)As(n),n.blockedOn===null&&st.shift()}var sn=rt.ReactCurrentBatchConfig,Qr=!0;function wf(e,t,n,r){var l=I,i=sn.transition;sn.transition=null;try{I=1,io(e,t,n,r)}finally{I=l,sn.transition=i}}function xf(e,t,n,r){var l=I,i=sn.transition;sn.transition=null;try{I=4,io(e,t,n,r)}finally{I=l,sn.transition=i}}function io(e,t,n,r){if(Qr){var l=yi(e,t,n,r);if(l===null)Wl(e,t,r,Kr,n),eu(e,r);else if(yf(l,e,t,n,r))r.stopPropagation();else if(eu(e,r),t&4&&-1<vf.indexOf(e)){for(;l!==null;){var i=ar(l);if(i!==null&&Rs(i),i=yi(e,t,n,r),i===null&&Wl(e,t,r,Kr,n),i===l)break;l=i}l!==null&&r.stopPropagation()}else Wl(e,t,r,null,n)}}var Kr=null;function yi(e,t,n,r){if(Kr=null,e=to(r),e=Pt(e),e!==null)if(t=At(e),t===null)e=null;else if(n=t.tag,n===13){if(e=Ns(t),e!==null)return e;e=null}else if(n===3){if(t.stateNode.current.memoizedState.isDehydrated)return t.tag===3?t.stateNode.containerInfo:null;e=null}else t!==e&&(e=null);return Kr=e,null}function $s(e){switch(e){case"cancel":case"click":case"close":case"contextmenu":case"copy":case"cut":case"auxclick":case"dblclick":case"dragend":case"dragstart":case"drop":case"focusin":case"focusout":case"input":case"invalid":case"keydown":case"keypress":case"keyup":case"mousedown":case"mouseup":case"paste":case"pause":case"play":case"pointercancel":case"pointerdown":case"pointerup":case"ratechange":case"reset":case"resize":case"seeked":case"submit":case"touchcancel":case"touchend":case"touchstart":case"volumechange":case"change":case"selectionchange":case"textInput":case"compositionstart":case"compositionend":case"compositionupdate":case"beforeblur":case"afterblur":case"beforeinput":case"blur":case"fullscreenchange":case"focus":case"hashchange":case"popstate":case"select":case"selectstart":return 1;case"drag":case"dragenter":case"dragexit":case"dragleave":case"dragover":case"mousemove":case"mouseout":case"mouseover":case"pointermove":case"pointerout":case"pointerover":case"scroll":case"toggle":case"touchmove":case"wheel":case"mouseenter":case"mouseleave":case"pointerenter":case"pointerleave":return 4;case"message":switch(uf()){case no:return 1;case Ls:return 4;case Br:case sf:return 16;case Ms:return 536870912;default:return 16}default:return 16}}var ct=null,oo=null,Lr=null;function Vs(){if(Lr)return Lr;var e,t=oo,n=t.length,r,l="value"in ct?ct.value:ct.textContent,i=l.length;for(e=0;e<n&&t[e]===l[e];e++);var o=n-e;for(r=1;r<=o&&t[n-r]===l[i-r];r++);return Lr=l.slice(e,1<r?1-r:void 0)}function Mr(e){var t=e.keyCode;return"charCode"in e?(e=e.charCode,e===0&&t===13&&(e=13)):e=t,e===10&&(e=13),32<=e||e===13?e:0}function gr(){return!0}function nu(){return!1}function Ce(e){function t(n,r,l,i,o){this._reactName=n,this._targetInst=l,this.type=r,this.nativeEvent=i,this.target=o,this.currentTarget=null;for(var u in e)e.hasOwnProperty(u)&&(n=e[u],this[u]=n?n(i):i[u]);return this.isDefaultPrevented=(i.defaultPrevented!=null?i.defaultPrevented:i.returnValue===!1)?gr:nu,this.isPropagationStopped=nu,this}return K(t.prototype,{preventDefault:function(){this.defaultPrevented=!0;var n=this.nativeEvent;n&&(n.preventDefault?n.preventDefault():typeof n.returnValue!="unknown"&&(n.returnValue=!1),this.isDefaultPrevented=gr)},stopPropagation:function(){var n=this.nativeEvent;n&&(n.stopPropagation?n.stopPropagation():typeof n.cancelBubble!="unknown"&&(n.cancelBubble=!0),this.isPropagationStopped=gr)},persist:function(){},isPersistent:gr}),t}var wn={eventPhase:0,bubbles:0,cancelable:0,timeStamp:function(e){return e.timeStamp||Date.now()},defaultPrevented:0,isTrusted:0},uo=Ce(wn),sr=K({},wn,{view:0,detail:0}),Sf=Ce(sr),Ol,Dl,_n,dl=K({},sr,{screenX:0,screenY:0,clientX:0,clientY:0,pageX:0,pageY:0,ctrlKey:0,shiftKey:0,altKey:0,metaKey:0,getModifierState:so,button:0,buttons:0,relatedTarget:function(e){return e.relatedTarget===void 0?e.fromElement===e.srcElement?e.toElement:e.fromElement:e.relatedTarget},movementX:function(e){return"movementX"in e?e.movementX:(e!==_n&&(_n&&e.type==="mousemove"?(Ol=e.screenX-_n.screenX,Dl=e.screenY-_n.screenY):Dl=Ol=0,_n=e),Ol)},movementY:function(e){return"movementY"in e?e.movementY:Dl}}),ru=Ce(dl),kf=K({},dl,{dataTransfer:0}),Ef=Ce(kf),jf=K({},sr,{relatedTarget:0}),Rl=Ce(jf),Cf=K({},wn,{animationName:0,elapsedTime:0,pseudoElement:0}),_f=Ce(Cf),Nf=K({},wn,{clipboardData:function(e){return"clipboardData"in e?e.clipboardData:window.clipboardData}}),Pf=Ce(Nf),Tf=K({},wn,{data:0}),lu=Ce(Tf),zf={Esc:"Escape",Spacebar:" ",Left:"ArrowLeft",Up:"ArrowUp",Right:"ArrowRight",Down:"ArrowDown",Del:"Delete",Win:"OS",Menu:"ContextMenu",Apps:"ContextMenu",Scroll:"ScrollLock",MozPrintableKey:"Unidentified"},Lf={8:"Backspace",9:"Tab",12:"Clear",13:"Enter",16:"Shift",17:"Control",18:"Alt",19:"Pause",20:"CapsLock",27:"Escape",32:" ",33:"PageUp",34:"PageDown",35:"End",36:"Home",37:"ArrowLeft",38:"ArrowUp",39:"ArrowRight",40:"ArrowDown",45:"Insert",46:"Delete",112:"F1",113:"F2",114:"F3",115:"F4",116:"F5",117:"F6",118:"F7",119:"F8",120:"F9",121:"F10",122:"F11",123:"F12",144:"NumLock",145:"ScrollLock",224:"Meta"},Mf={Alt:"altKey",Control:"ctrlKey",Meta:"metaKey",Shift:"shiftKey"};function Of(e){var t=this.nativeEvent;return t.getModifierState?t.getModifierState(e):(e=Mf[e])?!!t[e]:!1}function so(){return Of}var Df=K({},sr,{key:function(e){if(e.key){var t=zf[e.key]||e.key;if(t!=="Unidentified")return t}return e.type==="keypress"?(e=Mr(e),e===13?"Enter":String.fromCharCode(e)):e.type==="keydown"||e.type==="keyup"?Lf[e.keyCode]||"Unidentified":""},code:0,location:0,ctrlKey:0,shiftKey:0,altKey:0,metaKey:0,repeat:0,locale:0,getModifierState:so,charCode:function(e){return e.type==="keypress"?Mr(e):0},keyCode:function(e){return e.type==="keydown"||e.type==="keyup"?e.keyCode:0},which:function(e){return e.type==="keypress"?Mr(e):e.type==="keydown"||e.type==="keyup"?e.keyCode:0}}),Rf=Ce(Df),If=K({},dl,{pointerId:0,width:0,height:0,pressure:0,tangentialPressure:0,tiltX:0,tiltY:0,twist:0,pointerType:0,isPrimary:0}),iu=Ce(If),Ff=K({},sr,{touches:0,targetTouches:0,changedTouches:0,altKey:0,metaKey:0,ctrlKey:0,shiftKey:0,getModifierState:so}),Uf=Ce(Ff),Af=K({},wn,{propertyName:0,elapsedTime:0,pseudoElement:0}),$f=Ce(Af),Vf=K({},dl,{deltaX:function(e){return"deltaX"in e?e.deltaX:"wheelDeltaX"in e?-e.wheelDeltaX:0},deltaY:function(e){return"deltaY"in e?e.deltaY:"wheelDeltaY"in e?-e.wheelDeltaY:"wheelDelta"in e?-e.wheelDelta:0},deltaZ:0,deltaMode:0}),Wf=Ce(Vf),Bf=[9,13,27,32],ao=be&&"CompositionEvent"in window,In=null;be&&"documentMode"in document&&(In=document.documentMode);var Hf=be&&"TextEvent"in window&&!In,Ws=be&&(!ao||In&&8<In&&11>=In),ou=" ",uu=!1;function Bs(e,t){switch(e){case"keyup":return Bf.indexOf(t.keyCode)!==-1;case"keydown":return t.keyCode!==229;case"keypress":case"mousedown":case"focusout":return!0;default:return!1}}function Hs(e){return e=e.detail,typeof e=="object"&&"data"in e?e.data:null}var Zt=!1;function Qf(e,t){switch(e){case"compositionend":return Hs(t);case"keypress":return t.which!==32?null:(uu=!0,ou);case"textInput":return e=t.data,e===ou&&uu?null:e;default:return null}}function Kf(e,t){if(Zt)return e==="compositionend"||!ao&&Bs(e,t)?(e=Vs(),Lr=oo=ct=null,Zt=!1,e):null;switch(e){case"paste":return null;case"keypress":if(!(t.ctrlKey||t.altKey||t.metaKey)||t.ctrlKey&&t.altKey){if(t.char&&1<t.char.length)return t.char;if(t.which)return String.fromCharCode(t.which)}return null;case"compositionend":return Ws&&t.locale!=="ko"?null:t.data;default:return null}}var Yf={color:!0,date:!0,datetime:!0,"datetime-local":!0,email:!0,month:!0,number:!0,password:!0,range:!0,search:!0,tel:!0,text:!0,time:!0,url:!0,week:!0};function su(e){var t=e&&e.nodeName&&e.nodeName.toLowerCase();return t==="input"?!!Yf[e.type]:t==="textarea"}function Qs(e,t,n,r){ks(r),t=Yr(t,"onChange"),0<t.length&&(n=new uo("onChange","change",null,n,r),e.push({event:n,listeners:t}))}var Fn=null,Xn=null;function Zf(e){na(e,0)}function pl(e){var t=Jt(e);if(ms(t))return e}function Xf(e,t){if(e==="change")return t}var Ks=!1;if(be){var Il;if(be){var Fl="oninput"in document;if(!Fl){var au=document.createElement("div");au.setAttribute("oninput","return;"),Fl=typeof au.oninput=="function"}Il=Fl}else Il=!1;Ks=Il&&(!document.documentMode||9<document.documentMode)}function cu(){Fn&&(Fn.detachEvent("onpropertychange",Ys),Xn=Fn=null)}function Ys(e){if(e.propertyName==="value"&&pl(Xn)){var t=[];Qs(t,Xn,e,to(e)),_s(Zf,t)}}function Gf(e,t,n){e==="focusin"?(cu(),Fn=t,Xn=n,Fn.attachEvent("onpropertychange",Ys)):e==="focusout"&&cu()}function Jf(e){if(e==="selectionchange"||e==="keyup"||e==="keydown")return pl(Xn)}function qf(e,t){if(e==="click")return pl(t)}function bf(e,t){if(e==="input"||e==="change")return pl(t)}function ed(e,t){return e===t&&(e!==0||1/e===1/t)||e!==e&&t!==t}var Ue=typeof Object.is=="function"?Object.is:ed;function Gn(e,t){if(Ue(e,t))return!0;if(typeof e!="object"||e===null||typeof t!="object"||t===null)return!1;var n=Object.keys(e),r=Object.keys(t);if(n.length!==r.length)return!1;for(r=0;r<n.length;r++){var l=n[r];if(!bl.call(t,l)||!Ue(e[l],t[l]))return!1}return!0}function fu(e){for(;e&&e.firstChild;)e=e.firstChild;return e}function du(e,t){var n=fu(e);e=0;for(var r;n;){if(n.nodeType===3){if(r=e+n.textContent.length,e<=t&&r>=t)return{node:n,offset:t-e};e=r}e:{for(;n;){if(n.nextSibling){n=n.nextSibling;break e}n=n.parentNode}n=void 0}n=fu(n)}}function Zs(e,t){return e&&t?e===t?!0:e&&e.nodeType===3?!1:t&&t.nodeType===3?Zs(e,t.parentNode):"contains"in e?e.contains(t):e.compareDocumentPosition?!!(e.compareDocumentPosition(t)&16):!1:!1}function Xs(){for(var e=window,t=$r();t instanceof e.HTMLIFrameElement;){try{var n=typeof t.contentWindow.location.href=="string"}catch{n=!1}if(n)e=t.contentWindow;else break;t=$r(e.document)}return t}function co(e){var t=e&&e.nodeName&&e.nodeName.toLowerCase();return t&&(t==="input"&&(e.type==="text"||e.type==="search"||e.type==="tel"||e.type==="url"||e.type==="password")||t==="textarea"||e.contentEditable==="true")}function td(e){var t=Xs(),n=e.focusedElem,r=e.selectionRange;if(t!==n&&n&&n.ownerDocument&&Zs(n.ownerDocument.documentElement,n)){if(r!==null&&co(n)){if(t=r.start,e=r.end,e===void 0&&(e=t),"selectionStart"in n)n.selectionStart=t,n.selectionEnd=Math.min(e,n.value.length);else if(e=(t=n.ownerDocument||document)&&t.defaultView||window,e.getSelection){e=e.getSelection();var l=n.textContent.length,i=Math.min(r.start,l);r=r.end===void 0?i:Math.min(r.end,l),!e.extend&&i>r&&(l=r,r=i,i=l),l=du(n,i);var o=du(n,r);l&&o&&(e.rangeCount!==1||e.anchorNode!==l.node||e.anchorOffset!==l.offset||e.focusNode!==o.node||e.focusOffset!==o.offset)&&(t=t.createRange(),t.setStart(l.node,l.offset),e.removeAllRanges(),i>r?(e.addRange(t),e.extend(o.node,o.offset)):(t.setEnd(o.node,o.offset),e.addRange(t)))}}for(t=[],e=n;e=e.parentNode;)e.nodeType===1&&t.push({element:e,left:e.scrollLeft,top:e.scrollTop});for(typeof n.focus=="function"&&n.focus(),n=0;n<t.length;n++)e=t[n],e.element.scrollLeft=e.left,e.element.scrollTop=e.top}}var nd=be&&"documentMode"in document&&11>=document.documentMode,Xt=null,gi=null,Un=null,wi=!1;function pu(e,t,n){var r=n.window===n?n.document:n.nodeType===9?n:n.ownerDocument;wi||Xt==null||Xt!==$r(r)||(r=Xt,"selectionStart"in r&&co(r)?r={start:r.selectionStart,end:r.selectionEnd}:(r=(r.ownerDocument&&r.ownerDocument.defaultView||window).getSelection(),r={anchorNode:r.anchorNode,anchorOffset:r.anchorOffset,focusNode:r.focusNode,focusOffset:r.focusOffset}),Un&&Gn(Un,r)||(Un=r,r=Yr(gi,"onSelect"),0<r.length&&(t=new uo("onSelect","select",null,t,n),e.push({event:t,listeners:r}),t.target=Xt)))}function wr(e,t){var n={};return n[e.toLowerCase()]=t.toLowerCase(),n["Webkit"+e]="webkit"+t,n["Moz"+e]="moz"+t,n}var Gt={animationend:wr("Animation","AnimationEnd"),animationiteration:wr("Animation","AnimationIteration"),animationstart:wr("Animation","AnimationStart"),transitionend:wr("Transition","TransitionEnd")},Ul={},Gs={};be&&(Gs=document.createElement("div").style,"AnimationEvent"in window||(delete Gt.animationend.animation,delete Gt.animationiteration.animation,delete Gt.animationstart.animation),"TransitionEvent"in window||delete Gt.transitionend.transition);function hl(e){if(Ul[e])return Ul[e];if(!Gt[e])return e;var t=Gt[e],n;for(n in t)if(t.hasOwnProperty(n)&&n in Gs)return Ul[e]=t[n];return e}var Js=hl("animationend"),qs=hl("animationiteration"),bs=hl("animationstart"),ea=hl("transitionend"),ta=new Map,hu="abort auxClick cancel canPlay canPlayThrough click close contextMenu copy cut drag dragEnd dragEnter dragExit dragLeave dragOver dragStart drop durationChange emptied encrypted ended error gotPointerCapture input invalid keyDown keyPress keyUp load loadedData loadedMetadata loadStart lostPointerCapture mouseDown mouseMove mouseOut mouseOver mouseUp paste pause play playing pointerCancel pointerDown pointerMove pointerOut pointerOver pointerUp progress rateChange reset resize seeked seeking stalled submit suspend timeUpdate touchCancel touchEnd touchStart volumeChange scroll toggle touchMove waiting wheel".split(" ");function kt(e,t){ta.set(e,t),Ut(t,[e])}for(var Al=0;Al<hu.length;Al++){var $l=hu[Al],rd=$l.toLowerCase(),ld=$l[0].toUpperCase()+$l.slice(1);kt(rd,"on"+ld)}kt(Js,"onAnimationEnd");kt(qs,"onAnimationIteration");kt(bs,"onAnimationStart");kt("dblclick","onDoubleClick");kt("focusin","onFocus");kt("focusout","onBlur");kt(ea,"onTransitionEnd");fn("onMouseEnter",["mouseout","mouseover"]);fn("onMouseLeave",["mouseout","mouseover"]);fn("onPointerEnter",["pointerout","pointerover"]);fn("onPointerLeave",["pointerout","pointerover"]);Ut("onChange","change click focusin focusout input keydown keyup selectionchange".split(" "));Ut("onSelect","focusout contextmenu dragend focusin keydown keyup mousedown mouseup selectionchange".split(" "));Ut("onBeforeInput",["compositionend","keypress","textInput","paste"]);Ut("onCompositionEnd","compositionend focusout keydown keypress keyup mousedown".split(" "));Ut("onCompositionStart","compositionstart focusout keydown keypress keyup mousedown".split(" "));Ut("onCompositionUpdate","compositionupdate focusout keydown keypress keyup mousedown".split(" "));var On="abort canplay canplaythrough durationchange emptied encrypted ended error loadeddata loadedmetadata loadstart pause play playing progress ratechange resize seeked seeking stalled suspend timeupdate volumechange waiting".split(" "),id=new Set("cancel close invalid load scroll toggle".split(" ").concat(On));function mu(e,t,n){var r=e.type||"unknown-event";e.currentTarget=n,nf(r,t,void 0,e),e.currentTarget=null}function na(e,t){t=(t&4)!==0;for(var n=0;n<e.length;n++){var r=e[n],l=r.event;r=r.listeners;e:{var i=void 0;if(t)for(var o=r.length-1;0<=o;o--){var u=r[o],s=u.instance,d=u.currentTarget;if(u=u.listener,s!==i&&l.isPropagationStopped())break e;mu(l,u,d),i=s}else for(o=0;o<r.length;o++){if(u=r[o],s=u.instance,d=u.currentTarget,u=u.listener,s!==i&&l.isPropagationStopped())break e;mu(l,u,d),i=s}}}if(Wr)throw e=hi,Wr=!1,hi=null,e}function U(e,t){var n=t[ji];n===void 0&&(n=t[ji]=new Set);var r=e+"__bubble";n.has(r)||(ra(t,e,2,!1),n.add(r))}function Vl(e,t,n){var r=0;t&&(r|=4),ra(n,e,r,t)}var xr="_reactListening"+Math.random().toString(36).slice(2);function Jn(e){if(!e[xr]){e[xr]=!0,cs.forEach(function(n){n!=="selectionchange"&&(id.has(n)||Vl(n,!1,e),Vl(n,!0,e))});var t=e.nodeType===9?e:e.ownerDocument;t===null||t[xr]||(t[xr]=!0,Vl("selectionchange",!1,t))}}function ra(e,t,n,r){switch($s(t)){case 1:var l=wf;break;case 4:l=xf;break;default:l=io}n=l.bind(null,t,n,e),l=void 0,!pi||t!=="touchstart"&&t!=="touchmove"&&t!=="wheel"||(l=!0),r?l!==void 0?e.addEventListener(t,n,{capture:!0,passive:l}):e.addEventListener(t,n,!0):l!==void 0?e.addEventListener(t,n,{passive:l}):e.addEventListener(t,n,!1)}function Wl(e,t,n,r,l){var i=r;if(!(t&1)&&!(t&2)&&r!==null)e:for(;;){if(r===null)return;var o=r.tag;if(o===3||o===4){var u=r.stateNode.containerInfo;if(u===l||u.nodeType===8&&u.parentNode===l)break;if(o===4)for(o=r.return;o!==null;){var s=o.tag;if((s===3||s===4)&&(s=o.stateNode.containerInfo,s===l||s.nodeType===8&&s.parentNode===l))return;o=o.return}for(;u!==null;){if(o=Pt(u),o===null)return;if(s=o.tag,s===5||s===6){r=i=o;continue e}u=u.parentNode}}r=r.return}_s(function(){var d=i,y=to(n),m=[];e:{var h=ta.get(e);if(h!==void 0){var x=uo,S=e;switch(e){case"keypress":if(Mr(n)===0)break e;case"keydown":case"keyup":x=Rf;break;case"focusin":S="focus",x=Rl;break;case"focusout":S="blur",x=Rl;break;case"beforeblur":case"afterblur":x=Rl;break;case"click":if(n.button===2)break e;case"auxclick":case"dblclick":case"mousedown":case"mousemove":case"mouseup":case"mouseout":case"mouseover":case"contextmenu":x=ru;break;case"drag":case"dragend":case"dragenter":case"dragexit":case"dragleave":case"dragover":case"dragstart":case"drop":x=Ef;break;case"touchcancel":case"touchend":case"touchmove":case"touchstart":x=Uf;break;case Js:case qs:case bs:x=_f;break;case ea:x=$f;break;case"scroll":x=Sf;break;case"wheel":x=Wf;break;case"copy":case"cut":case"paste":x=Pf;break;case"gotpointercapture":case"lostpointercapture":case"pointercancel":case"pointerdown":case"pointermove":case"pointerout":case"pointerover":case"pointerup":x=iu}var j=(t&4)!==0,O=!j&&e==="scroll",f=j?h!==null?h+"Capture":null:h;j=[];for(var c=d,p;c!==null;){p=c;var v=p.stateNode;if(p.tag===5&&v!==null&&(p=v,f!==null&&(v=Qn(c,f),v!=null&&j.push(qn(c,v,p)))),O)break;c=c.return}0<j.length&&(h=new x(h,S,null,n,y),m.push({event:h,listeners:j}))}}if(!(t&7)){e:{if(h=e==="mouseover"||e==="pointerover",x=e==="mouseout"||e==="pointerout",h&&n!==fi&&(S=n.relatedTarget||n.fromElement)&&(Pt(S)||S[et]))break e;if((x||h)&&(h=y.window===y?y:(h=y.ownerDocument)?h.defaultView||h.parentWindow:window,x?(S=n.relatedTarget||n.toElement,x=d,S=S?Pt(S):null,S!==null&&(O=At(S),S!==O||S.tag!==5&&S.tag!==6)&&(S=null)):(x=null,S=d),x!==S)){if(j=ru,v="onMouseLeave",f="onMouseEnter",c="mouse",(e==="pointerout"||e==="pointerover")&&(j=iu,v="onPointerLeave",f="onPointerEnter",c="pointer"),O=x==null?h:Jt(x),p=S==null?h:Jt(S),h=new j(v,c+"leave",x,n,y),h.target=O,h.relatedTarget=p,v=null,Pt(y)===d&&(j=new j(f,c+"enter",S,n,y),j.target=p,j.relatedTarget=O,v=j),O=v,x&&S)t:{for(j=x,f=S,c=0,p=j;p;p=Qt(p))c++;for(p=0,v=f;v;v=Qt(v))p++;for(;0<c-p;)j=Qt(j),c--;for(;0<p-c;)f=Qt(f),p--;for(;c--;){if(j===f||f!==null&&j===f.alternate)break t;j=Qt(j),f=Qt(f)}j=null}else j=null;x!==null&&vu(m,h,x,j,!1),S!==null&&O!==null&&vu(m,O,S,j,!0)}}e:{if(h=d?Jt(d):window,x=h.nodeName&&h.nodeName.toLowerCase(),x==="select"||x==="input"&&h.type==="file")var k=Xf;else if(su(h))if(Ks)k=bf;else{k=Jf;var E=Gf}else(x=h.nodeName)&&x.toLowerCase()==="input"&&(h.type==="checkbox"||h.type==="radio")&&(k=qf);if(k&&(k=k(e,d))){Qs(m,k,n,y);break e}E&&E(e,h,d),e==="focusout"&&(E=h._wrapperState)&&E.controlled&&h.type==="number"&&oi(h,"number",h.value)}switch(E=d?Jt(d):window,e){case"focusin":(su(E)||E.contentEditable==="true")&&(Xt=E,gi=d,Un=null);break;case"focusout":Un=gi=Xt=null;break;case"mousedown":wi=!0;break;case"contextmenu":case"mouseup":case"dragend":wi=!1,pu(m,n,y);break;case"selectionchange":if(nd)break;case"keydown":case"keyup":pu(m,n,y)}var N;if(ao)e:{switch(e){case"compositionstart":var P="onCompositionStart";break e;case"compositionend":P="onCompositionEnd";break e;case"compositionupdate":P="onCompositionUpdate";break e}P=void 0}else Zt?Bs(e,n)&&(P="onCompositionEnd"):e==="keydown"&&n.keyCode===229&&(P="onCompositionStart");P&&(Ws&&n.locale!=="ko"&&(Zt||P!=="onCompositionStart"?P==="onCompositionEnd"&&Zt&&(N=Vs()):(ct=y,oo="value"in ct?ct.value:ct.textContent,Zt=!0)),E=Yr(d,P),0<E.length&&(P=new lu(P,e,null,n,y),m.push({event:P,listeners:E}),N?P.data=N:(N=Hs(n),N!==null&&(P.data=N)))),(N=Hf?Qf(e,n):Kf(e,n))&&(d=Yr(d,"onBeforeInput"),0<d.length&&(y=new lu("onBeforeInput","beforeinput",null,n,y),m.push({event:y,listeners:d}),y.data=N))}na(m,t)})}function qn(e,t,n){return{instance:e,listener:t,currentTarget:n}}function Yr(e,t){for(var n=t+"Capture",r=[];e!==null;){var l=e,i=l.stateNode;l.tag===5&&i!==null&&(l=i,i=Qn(e,n),i!=null&&r.unshift(qn(e,i,l)),i=Qn(e,t),i!=null&&r.push(qn(e,i,l))),e=e.return}return r}function Qt(e){if(e===null)return null;do e=e.return;while(e&&e.tag!==5);return e||null}function vu(e,t,n,r,l){for(var i=t._reactName,o=[];n!==null&&n!==r;){var u=n,s=u.alternate,d=u.stateNode;if(s!==null&&s===r)break;u.tag===5&&d!==null&&(u=d,l?(s=Qn(n,i),s!=null&&o.unshift(qn(n,s,u))):l||(s=Qn(n,i),s!=null&&o.push(qn(n,s,u)))),n=n.return}o.length!==0&&e.push({event:t,listeners:o})}var od=/\r\n?/g,ud=/\u0000|\uFFFD/g;function yu(e){return(typeof e=="string"?e:""+e).replace(od,`
-`).replace(ud,"")}function Sr(e,t,n){if(t=yu(t),yu(e)!==t&&n)throw Error(w(425))}function Zr(){}var xi=null,Si=null;function ki(e,t){return e==="textarea"||e==="noscript"||typeof t.children=="string"||typeof t.children=="number"||typeof t.dangerouslySetInnerHTML=="object"&&t.dangerouslySetInnerHTML!==null&&t.dangerouslySetInnerHTML.__html!=null}var Ei=typeof setTimeout=="function"?setTimeout:void 0,sd=typeof clearTimeout=="function"?clearTimeout:void 0,gu=typeof Promise=="function"?Promise:void 0,ad=typeof queueMicrotask=="function"?queueMicrotask:typeof gu<"u"?function(e){return gu.resolve(null).then(e).catch(cd)}:Ei;function cd(e){setTimeout(function(){throw e})}function Bl(e,t){var n=t,r=0;do{var l=n.nextSibling;if(e.removeChild(n),l&&l.nodeType===8)if(n=l.data,n==="/$"){if(r===0){e.removeChild(l),Zn(t);return}r--}else n!=="$"&&n!=="$?"&&n!=="$!"||r++;n=l}while(n);Zn(t)}function mt(e){for(;e!=null;e=e.nextSibling){var t=e.nodeType;if(t===1||t===3)break;if(t===8){if(t=e.data,t==="$"||t==="$!"||t==="$?")break;if(t==="/$")return null}}return e}function wu(e){e=e.previousSibling;for(var t=0;e;){if(e.nodeType===8){var n=e.data;if(n==="$"||n==="$!"||n==="$?"){if(t===0)return e;t--}else n==="/$"&&t++}e=e.previousSibling}return null}var xn=Math.random().toString(36).slice(2),He="__reactFiber$"+xn,bn="__reactProps$"+xn,et="__reactContainer$"+xn,ji="__reactEvents$"+xn,fd="__reactListeners$"+xn,dd="__reactHandles$"+xn;function Pt(e){var t=e[He];if(t)return t;for(var n=e.parentNode;n;){if(t=n[et]||n[He]){if(n=t.alternate,t.child!==null||n!==null&&n.child!==null)for(e=wu(e);e!==null;){if(n=e[He])return n;e=wu(e)}return t}e=n,n=e.parentNode}return null}function ar(e){return e=e[He]||e[et],!e||e.tag!==5&&e.tag!==6&&e.tag!==13&&e.tag!==3?null:e}function Jt(e){if(e.tag===5||e.tag===6)return e.stateNode;throw Error(w(33))}function ml(e){return e[bn]||null}var Ci=[],qt=-1;function Et(e){return{current:e}}function A(e){0>qt||(e.current=Ci[qt],Ci[qt]=null,qt--)}function F(e,t){qt++,Ci[qt]=e.current,e.current=t}var St={},ae=Et(St),ye=Et(!1),Ot=St;function dn(e,t){var n=e.type.contextTypes;if(!n)return St;var r=e.stateNode;if(r&&r.__reactInternalMemoizedUnmaskedChildContext===t)return r.__reactInternalMemoizedMaskedChildContext;var l={},i;for(i in n)l[i]=t[i];return r&&(e=e.stateNode,e.__reactInternalMemoizedUnmaskedChildContext=t,e.__reactInternalMemoizedMaskedChildContext=l),l}function ge(e){return e=e.childContextTypes,e!=null}function Xr(){A(ye),A(ae)}function xu(e,t,n){if(ae.current!==St)throw Error(w(168));F(ae,t),F(ye,n)}function la(e,t,n){var r=e.stateNode;if(t=t.childContextTypes,typeof r.getChildContext!="function")return n;r=r.getChildContext();for(var l in r)if(!(l in t))throw Error(w(108,Xc(e)||"Unknown",l));return K({},n,r)}function Gr(e){return e=(e=e.stateNode)&&e.__reactInternalMemoizedMergedChildContext||St,Ot=ae.current,F(ae,e),F(ye,ye.current),!0}function Su(e,t,n){var r=e.stateNode;if(!r)throw Error(w(169));n?(e=la(e,t,Ot),r.__reactInternalMemoizedMergedChildContext=e,A(ye),A(ae),F(ae,e)):A(ye),F(ye,n)}var Xe=null,vl=!1,Hl=!1;function ia(e){Xe===null?Xe=[e]:Xe.push(e)}function pd(e){vl=!0,ia(e)}function jt(){if(!Hl&&Xe!==null){Hl=!0;var e=0,t=I;try{var n=Xe;for(I=1;e<n.length;e++){var r=n[e];do r=r(!0);while(r!==null)}Xe=null,vl=!1}catch(l){throw Xe!==null&&(Xe=Xe.slice(e+1)),zs(no,jt),l}finally{I=t,Hl=!1}}return null}var bt=[],en=0,Jr=null,qr=0,_e=[],Ne=0,Dt=null,Ge=1,Je="";function _t(e,t){bt[en++]=qr,bt[en++]=Jr,Jr=e,qr=t}function oa(e,t,n){_e[Ne++]=Ge,_e[Ne++]=Je,_e[Ne++]=Dt,Dt=e;var r=Ge;e=Je;var l=32-Ie(r)-1;r&=~(1<<l),n+=1;var i=32-Ie(t)+l;if(30<i){var o=l-l%5;i=(r&(1<<o)-1).toString(32),r>>=o,l-=o,Ge=1<<32-Ie(t)+l|n<<l|r,Je=i+e}else Ge=1<<i|n<<l|r,Je=e}function fo(e){e.return!==null&&(_t(e,1),oa(e,1,0))}function po(e){for(;e===Jr;)Jr=bt[--en],bt[en]=null,qr=bt[--en],bt[en]=null;for(;e===Dt;)Dt=_e[--Ne],_e[Ne]=null,Je=_e[--Ne],_e[Ne]=null,Ge=_e[--Ne],_e[Ne]=null}var ke=null,Se=null,V=!1,Re=null;function ua(e,t){var n=Pe(5,null,null,0);n.elementType="DELETED",n.stateNode=t,n.return=e,t=e.deletions,t===null?(e.deletions=[n],e.flags|=16):t.push(n)}function ku(e,t){switch(e.tag){case 5:var n=e.type;return t=t.nodeType!==1||n.toLowerCase()!==t.nodeName.toLowerCase()?null:t,t!==null?(e.stateNode=t,ke=e,Se=mt(t.firstChild),!0):!1;case 6:return t=e.pendingProps===""||t.nodeType!==3?null:t,t!==null?(e.stateNode=t,ke=e,Se=null,!0):!1;case 13:return t=t.nodeType!==8?null:t,t!==null?(n=Dt!==null?{id:Ge,overflow:Je}:null,e.memoizedState={dehydrated:t,treeContext:n,retryLane:1073741824},n=Pe(18,null,null,0),n.stateNode=t,n.return=e,e.child=n,ke=e,Se=null,!0):!1;default:return!1}}function _i(e){return(e.mode&1)!==0&&(e.flags&128)===0}function Ni(e){if(V){var t=Se;if(t){var n=t;if(!ku(e,t)){if(_i(e))throw Error(w(418));t=mt(n.nextSibling);var r=ke;t&&ku(e,t)?ua(r,n):(e.flags=e.flags&-4097|2,V=!1,ke=e)}}else{if(_i(e))throw Error(w(418));e.flags=e.flags&-4097|2,V=!1,ke=e}}}function Eu(e){for(e=e.return;e!==null&&e.tag!==5&&e.tag!==3&&e.tag!==13;)e=e.return;ke=e}function kr(e){if(e!==ke)return!1;if(!V)return Eu(e),V=!0,!1;var t;if((t=e.tag!==3)&&!(t=e.tag!==5)&&(t=e.type,t=t!=="head"&&t!=="body"&&!ki(e.type,e.memoizedProps)),t&&(t=Se)){if(_i(e))throw sa(),Error(w(418));for(;t;)ua(e,t),t=mt(t.nextSibling)}if(Eu(e),e.tag===13){if(e=e.memoizedState,e=e!==null?e.dehydrated:null,!e)throw Error(w(317));e:{for(e=e.nextSibling,t=0;e;){if(e.nodeType===8){var n=e.data;if(n==="/$"){if(t===0){Se=mt(e.nextSibling);break e}t--}else n!=="$"&&n!=="$!"&&n!=="$?"||t++}e=e.nextSibling}Se=null}}else Se=ke?mt(e.stateNode.nextSibling):null;return!0}function sa(){for(var e=Se;e;)e=mt(e.nextSibling)}function pn(){Se=ke=null,V=!1}function ho(e){Re===null?Re=[e]:Re.push(e)}var hd=rt.ReactCurrentBatchConfig;function Nn(e,t,n){if(e=n.ref,e!==null&&typeof e!="function"&&typeof e!="object"){if(n._owner){if(n=n._owner,n){if(n.tag!==1)throw Error(w(309));var r=n.stateNode}if(!r)throw Error(w(147,e));var l=r,i=""+e;return t!==null&&t.ref!==null&&typeof t.ref=="function"&&t.ref._stringRef===i?t.ref:(t=function(o){var u=l.refs;o===null?delete u[i]:u[i]=o},t._stringRef=i,t)}if(typeof e!="string")throw Error(w(284));if(!n._owner)throw Error(w(290,e))}return e}function Er(e,t){throw e=Object.prototype.toString.call(t),Error(w(31,e==="[object Object]"?"object with keys {"+Object.keys(t).join(", ")+"}":e))}function ju(e){var t=e._init;return t(e._payload)}function aa(e){function t(f,c){if(e){var p=f.deletions;p===null?(f.deletions=[c],f.flags|=16):p.push(c)}}function n(f,c){if(!e)return null;for(;c!==null;)t(f,c),c=c.sibling;return null}function r(f,c){for(f=new Map;c!==null;)c.key!==null?f.set(c.key,c):f.set(c.index,c),c=c.sibling;return f}function l(f,c){return f=wt(f,c),f.index=0,f.sibling=null,f}function i(f,c,p){return f.index=p,e?(p=f.alternate,p!==null?(p=p.index,p<c?(f.flags|=2,c):p):(f.flags|=2,c)):(f.flags|=1048576,c)}function o(f){return e&&f.alternate===null&&(f.flags|=2),f}function u(f,c,p,v){return c===null||c.tag!==6?(c=Jl(p,f.mode,v),c.return=f,c):(c=l(c,p),c.return=f,c)}function s(f,c,p,v){var k=p.type;return k===Yt?y(f,c,p.props.children,v,p.key):c!==null&&(c.elementType===k||typeof k=="object"&&k!==null&&k.$$typeof===ot&&ju(k)===c.type)?(v=l(c,p.props),v.ref=Nn(f,c,p),v.return=f,v):(v=Ar(p.type,p.key,p.props,null,f.mode,v),v.ref=Nn(f,c,p),v.return=f,v)}function d(f,c,p,v){return c===null||c.tag!==4||c.stateNode.containerInfo!==p.containerInfo||c.stateNode.implementation!==p.implementation?(c=ql(p,f.mode,v),c.return=f,c):(c=l(c,p.children||[]),c.return=f,c)}function y(f,c,p,v,k){return c===null||c.tag!==7?(c=Mt(p,f.mode,v,k),c.return=f,c):(c=l(c,p),c.return=f,c)}function m(f,c,p){if(typeof c=="string"&&c!==""||typeof c=="number")return c=Jl(""+c,f.mode,p),c.return=f,c;if(typeof c=="object"&&c!==null){switch(c.$$typeof){case dr:return p=Ar(c.type,c.key,c.props,null,f.mode,p),p.ref=Nn(f,null,c),p.return=f,p;case Kt:return c=ql(c,f.mode,p),c.return=f,c;case ot:var v=c._init;return m(f,v(c._payload),p)}if(Ln(c)||kn(c))return c=Mt(c,f.mode,p,null),c.return=f,c;Er(f,c)}return null}function h(f,c,p,v){var k=c!==null?c.key:null;if(typeof p=="string"&&p!==""||typeof p=="number")return k!==null?null:u(f,c,""+p,v);if(typeof p=="object"&&p!==null){switch(p.$$typeof){case dr:return p.key===k?s(f,c,p,v):null;case Kt:return p.key===k?d(f,c,p,v):null;case ot:return k=p._init,h(f,c,k(p._payload),v)}if(Ln(p)||kn(p))return k!==null?null:y(f,c,p,v,null);Er(f,p)}return null}function x(f,c,p,v,k){if(typeof v=="string"&&v!==""||typeof v=="number")return f=f.get(p)||null,u(c,f,""+v,k);if(typeof v=="object"&&v!==null){switch(v.$$typeof){case dr:return f=f.get(v.key===null?p:v.key)||null,s(c,f,v,k);case Kt:return f=f.get(v.key===null?p:v.key)||null,d(c,f,v,k);case ot:var E=v._init;return x(f,c,p,E(v._payload),k)}if(Ln(v)||kn(v))return f=f.get(p)||null,y(c,f,v,k,null);Er(c,v)}return null}function S(f,c,p,v){for(var k=null,E=null,N=c,P=c=0,$=null;N!==null&&P<p.length;P++){N.index>P?($=N,N=null):$=N.sibling;var L=h(f,N,p[P],v);if(L===null){N===null&&(N=$);break}e&&N&&L.alternate===null&&t(f,N),c=i(L,c,P),E===null?k=L:E.sibling=L,E=L,N=$}if(P===p.length)return n(f,N),V&&_t(f,P),k;if(N===null){for(;P<p.length;P++)N=m(f,p[P],v),N!==null&&(c=i(N,c,P),E===null?k=N:E.sibling=N,E=N);return V&&_t(f,P),k}for(N=r(f,N);P<p.length;P++)$=x(N,f,P,p[P],v),$!==null&&(e&&$.alternate!==null&&N.delete($.key===null?P:$.key),c=i($,c,P),E===null?k=$:E.sibling=$,E=$);return e&&N.forEach(function(ce){return t(f,ce)}),V&&_t(f,P),k}function j(f,c,p,v){var k=kn(p);if(typeof k!="function")throw Error(w(150));if(p=k.call(p),p==null)throw Error(w(151));for(var E=k=null,N=c,P=c=0,$=null,L=p.next();N!==null&&!L.done;P++,L=p.next()){N.index>P?($=N,N=null):$=N.sibling;var ce=h(f,N,L.value,v);if(ce===null){N===null&&(N=$);break}e&&N&&ce.alternate===null&&t(f,N),c=i(ce,c,P),E===null?k=ce:E.sibling=ce,E=ce,N=$}if(L.done)return n(f,N),V&&_t(f,P),k;if(N===null){for(;!L.done;P++,L=p.next())L=m(f,L.value,v),L!==null&&(c=i(L,c,P),E===null?k=L:E.sibling=L,E=L);return V&&_t(f,P),k}for(N=r(f,N);!L.done;P++,L=p.next())L=x(N,f,P,L.value,v),L!==null&&(e&&L.alternate!==null&&N.delete(L.key===null?P:L.key),c=i(L,c,P),E===null?k=L:E.sibling=L,E=L);return e&&N.forEach(function(lt){return t(f,lt)}),V&&_t(f,P),k}function O(f,c,p,v){if(typeof p=="object"&&p!==null&&p.type===Yt&&p.key===null&&(p=p.props.children),typeof p=="object"&&p!==null){switch(p.$$typeof){case dr:e:{for(var k=p.key,E=c;E!==null;){if(E.key===k){if(k=p.type,k===Yt){if(E.tag===7){n(f,E.sibling),c=l(E,p.props.children),c.return=f,f=c;break e}}else if(E.elementType===k||typeof k=="object"&&k!==null&&k.$$typeof===ot&&ju(k)===E.type){n(f,E.sibling),c=l(E,p.props),c.ref=Nn(f,E,p),c.return=f,f=c;break e}n(f,E);break}else t(f,E);E=E.sibling}p.type===Yt?(c=Mt(p.props.children,f.mode,v,p.key),c.return=f,f=c):(v=Ar(p.type,p.key,p.props,null,f.mode,v),v.ref=Nn(f,c,p),v.return=f,f=v)}return o(f);case Kt:e:{for(E=p.key;c!==null;){if(c.key===E)if(c.tag===4&&c.stateNode.containerInfo===p.containerInfo&&c.stateNode.implementation===p.implementation){n(f,c.sibling),c=l(c,p.children||[]),c.return=f,f=c;break e}else{n(f,c);break}else t(f,c);c=c.sibling}c=ql(p,f.mode,v),c.return=f,f=c}return o(f);case ot:return E=p._init,O(f,c,E(p._payload),v)}if(Ln(p))return S(f,c,p,v);if(kn(p))return j(f,c,p,v);Er(f,p)}return typeof p=="string"&&p!==""||typeof p=="number"?(p=""+p,c!==null&&c.tag===6?(n(f,c.sibling),c=l(c,p),c.return=f,f=c):(n(f,c),c=Jl(p,f.mode,v),c.return=f,f=c),o(f)):n(f,c)}return O}var hn=aa(!0),ca=aa(!1),br=Et(null),el=null,tn=null,mo=null;function vo(){mo=tn=el=null}function yo(e){var t=br.current;A(br),e._currentValue=t}function Pi(e,t,n){for(;e!==null;){var r=e.alternate;if((e.childLanes&t)!==t?(e.childLanes|=t,r!==null&&(r.childLanes|=t)):r!==null&&(r.childLanes&t)!==t&&(r.childLanes|=t),e===n)break;e=e.return}}function an(e,t){el=e,mo=tn=null,e=e.dependencies,e!==null&&e.firstContext!==null&&(e.lanes&t&&(ve=!0),e.firstContext=null)}function ze(e){var t=e._currentValue;if(mo!==e)if(e={context:e,memoizedValue:t,next:null},tn===null){if(el===null)throw Error(w(308));tn=e,el.dependencies={lanes:0,firstContext:e}}else tn=tn.next=e;return t}var Tt=null;function go(e){Tt===null?Tt=[e]:Tt.push(e)}function fa(e,t,n,r){var l=t.interleaved;return l===null?(n.next=n,go(t)):(n.next=l.next,l.next=n),t.interleaved=n,tt(e,r)}function tt(e,t){e.lanes|=t;var n=e.alternate;for(n!==null&&(n.lanes|=t),n=e,e=e.return;e!==null;)e.childLanes|=t,n=e.alternate,n!==null&&(n.childLanes|=t),n=e,e=e.return;return n.tag===3?n.stateNode:null}var ut=!1;function wo(e){e.updateQueue={baseState:e.memoizedState,firstBaseUpdate:null,lastBaseUpdate:null,shared:{pending:null,interleaved:null,lanes:0},effects:null}}function da(e,t){e=e.updateQueue,t.updateQueue===e&&(t.updateQueue={baseState:e.baseState,firstBaseUpdate:e.firstBaseUpdate,lastBaseUpdate:e.lastBaseUpdate,shared:e.shared,effects:e.effects})}function qe(e,t){return{eventTime:e,lane:t,tag:0,payload:null,callback:null,next:null}}function vt(e,t,n){var r=e.updateQueue;if(r===null)return null;if(r=r.shared,D&2){var l=r.pending;return l===null?t.next=t:(t.next=l.next,l.next=t),r.pending=t,tt(e,n)}return l=r.interleaved,l===null?(t.next=t,go(r)):(t.next=l.next,l.next=t),r.interleaved=t,tt(e,n)}function Or(e,t,n){if(t=t.updateQueue,t!==null&&(t=t.shared,(n&4194240)!==0)){var r=t.lanes;r&=e.pendingLanes,n|=r,t.lanes=n,ro(e,n)}}function Cu(e,t){var n=e.updateQueue,r=e.alternate;if(r!==null&&(r=r.updateQueue,n===r)){var l=null,i=null;if(n=n.firstBaseUpdate,n!==null){do{var o={eventTime:n.eventTime,lane:n.lane,tag:n.tag,payload:n.payload,callback:n.callback,next:null};i===null?l=i=o:i=i.next=o,n=n.next}while(n!==null);i===null?l=i=t:i=i.next=t}else l=i=t;n={baseState:r.baseState,firstBaseUpdate:l,lastBaseUpdate:i,shared:r.shared,effects:r.effects},e.updateQueue=n;return}e=n.lastBaseUpdate,e===null?n.firstBaseUpdate=t:e.next=t,n.lastBaseUpdate=t}function tl(e,t,n,r){var l=e.updateQueue;ut=!1;var i=l.firstBaseUpdate,o=l.lastBaseUpdate,u=l.shared.pending;if(u!==null){l.shared.pending=null;var s=u,d=s.next;s.next=null,o===null?i=d:o.next=d,o=s;var y=e.alternate;y!==null&&(y=y.updateQueue,u=y.lastBaseUpdate,u!==o&&(u===null?y.firstBaseUpdate=d:u.next=d,y.lastBaseUpdate=s))}if(i!==null){var m=l.baseState;o=0,y=d=s=null,u=i;do{var h=u.lane,x=u.eventTime;if((r&h)===h){y!==null&&(y=y.next={eventTime:x,lane:0,tag:u.tag,payload:u.payload,callback:u.callback,next:null});e:{var S=e,j=u;switch(h=t,x=n,j.tag){case 1:if(S=j.payload,typeof S=="function"){m=S.call(x,m,h);break e}m=S;break e;case 3:S.flags=S.flags&-65537|128;case 0:if(S=j.payload,h=typeof S=="function"?S.call(x,m,h):S,h==null)break e;m=K({},m,h);break e;case 2:ut=!0}}u.callback!==null&&u.lane!==0&&(e.flags|=64,h=l.effects,h===null?l.effects=[u]:h.push(u))}else x={eventTime:x,lane:h,tag:u.tag,payload:u.payload,callback:u.callback,next:null},y===null?(d=y=x,s=m):y=y.next=x,o|=h;if(u=u.next,u===null){if(u=l.shared.pending,u===null)break;h=u,u=h.next,h.next=null,l.lastBaseUpdate=h,l.shared.pending=null}}while(!0);if(y===null&&(s=m),l.baseState=s,l.firstBaseUpdate=d,l.lastBaseUpdate=y,t=l.shared.interleaved,t!==null){l=t;do o|=l.lane,l=l.next;while(l!==t)}else i===null&&(l.shared.lanes=0);It|=o,e.lanes=o,e.memoizedState=m}}function _u(e,t,n){if(e=t.effects,t.effects=null,e!==null)for(t=0;t<e.length;t++){var r=e[t],l=r.callback;if(l!==null){if(r.callback=null,r=n,typeof l!="function")throw Error(w(191,l));l.call(r)}}}var cr={},Ke=Et(cr),er=Et(cr),tr=Et(cr);function zt(e){if(e===cr)throw Error(w(174));return e}function xo(e,t){switch(F(tr,t),F(er,e),F(Ke,cr),e=t.nodeType,e){case 9:case 11:t=(t=t.documentElement)?t.namespaceURI:si(null,"");break;default:e=e===8?t.parentNode:t,t=e.namespaceURI||null,e=e.tagName,t=si(t,e)}A(Ke),F(Ke,t)}function mn(){A(Ke),A(er),A(tr)}function pa(e){zt(tr.current);var t=zt(Ke.current),n=si(t,e.type);t!==n&&(F(er,e),F(Ke,n))}function So(e){er.current===e&&(A(Ke),A(er))}var H=Et(0);function nl(e){for(var t=e;t!==null;){if(t.tag===13){var n=t.memoizedState;if(n!==null&&(n=n.dehydrated,n===null||n.data==="$?"||n.data==="$!"))return t}else if(t.tag===19&&t.memoizedProps.revealOrder!==void 0){if(t.flags&128)return t}else if(t.child!==null){t.child.return=t,t=t.child;continue}if(t===e)break;for(;t.sibling===null;){if(t.return===null||t.return===e)return null;t=t.return}t.sibling.return=t.return,t=t.sibling}return null}var Ql=[];function ko(){for(var e=0;e<Ql.length;e++)Ql[e]._workInProgressVersionPrimary=null;Ql.length=0}var Dr=rt.ReactCurrentDispatcher,Kl=rt.ReactCurrentBatchConfig,Rt=0,Q=null,b=null,te=null,rl=!1,An=!1,nr=0,md=0;function oe(){throw Error(w(321))}function Eo(e,t){if(t===null)return!1;for(var n=0;n<t.length&&n<e.length;n++)if(!Ue(e[n],t[n]))return!1;return!0}function jo(e,t,n,r,l,i){if(Rt=i,Q=t,t.memoizedState=null,t.updateQueue=null,t.lanes=0,Dr.current=e===null||e.memoizedState===null?wd:xd,e=n(r,l),An){i=0;do{if(An=!1,nr=0,25<=i)throw Error(w(301));i+=1,te=b=null,t.updateQueue=null,Dr.current=Sd,e=n(r,l)}while(An)}if(Dr.current=ll,t=b!==null&&b.next!==null,Rt=0,te=b=Q=null,rl=!1,t)throw Error(w(300));return e}function Co(){var e=nr!==0;return nr=0,e}function Be(){var e={memoizedState:null,baseState:null,baseQueue:null,queue:null,next:null};return te===null?Q.memoizedState=te=e:te=te.next=e,te}function Le(){if(b===null){var e=Q.alternate;e=e!==null?e.memoizedState:null}else e=b.next;var t=te===null?Q.memoizedState:te.next;if(t!==null)te=t,b=e;else{if(e===null)throw Error(w(310));b=e,e={memoizedState:b.memoizedState,baseState:b.baseState,baseQueue:b.baseQueue,queue:b.queue,next:null},te===null?Q.memoizedState=te=e:te=te.next=e}return te}function rr(e,t){return typeof t=="function"?t(e):t}function Yl(e){var t=Le(),n=t.queue;if(n===null)throw Error(w(311));n.lastRenderedReducer=e;var r=b,l=r.baseQueue,i=n.pending;if(i!==null){if(l!==null){var o=l.next;l.next=i.next,i.next=o}r.baseQueue=l=i,n.pending=null}if(l!==null){i=l.next,r=r.baseState;var u=o=null,s=null,d=i;do{var y=d.lane;if((Rt&y)===y)s!==null&&(s=s.next={lane:0,action:d.action,hasEagerState:d.hasEagerState,eagerState:d.eagerState,next:null}),r=d.hasEagerState?d.eagerState:e(r,d.action);else{var m={lane:y,action:d.action,hasEagerState:d.hasEagerState,eagerState:d.eagerState,next:null};s===null?(u=s=m,o=r):s=s.next=m,Q.lanes|=y,It|=y}d=d.next}while(d!==null&&d!==i);s===null?o=r:s.next=u,Ue(r,t.memoizedState)||(ve=!0),t.memoizedState=r,t.baseState=o,t.baseQueue=s,n.lastRenderedState=r}if(e=n.interleaved,e!==null){l=e;do i=l.lane,Q.lanes|=i,It|=i,l=l.next;while(l!==e)}else l===null&&(n.lanes=0);return[t.memoizedState,n.dispatch]}function Zl(e){var t=Le(),n=t.queue;if(n===null)throw Error(w(311));n.lastRenderedReducer=e;var r=n.dispatch,l=n.pending,i=t.memoizedState;if(l!==null){n.pending=null;var o=l=l.next;do i=e(i,o.action),o=o.next;while(o!==l);Ue(i,t.memoizedState)||(ve=!0),t.memoizedState=i,t.baseQueue===null&&(t.baseState=i),n.lastRenderedState=i}return[i,r]}function ha(){}function ma(e,t){var n=Q,r=Le(),l=t(),i=!Ue(r.memoizedState,l);if(i&&(r.memoizedState=l,ve=!0),r=r.queue,_o(ga.bind(null,n,r,e),[e]),r.getSnapshot!==t||i||te!==null&&te.memoizedState.tag&1){if(n.flags|=2048,lr(9,ya.bind(null,n,r,l,t),void 0,null),ne===null)throw Error(w(349));Rt&30||va(n,t,l)}return l}function va(e,t,n){e.flags|=16384,e={getSnapshot:t,value:n},t=Q.updateQueue,t===null?(t={lastEffect:null,stores:null},Q.updateQueue=t,t.stores=[e]):(n=t.stores,n===null?t.stores=[e]:n.push(e))}function ya(e,t,n,r){t.value=n,t.getSnapshot=r,wa(t)&&xa(e)}function ga(e,t,n){return n(function(){wa(t)&&xa(e)})}function wa(e){var t=e.getSnapshot;e=e.value;try{var n=t();return!Ue(e,n)}catch{return!0}}function xa(e){var t=tt(e,1);t!==null&&Fe(t,e,1,-1)}function Nu(e){var t=Be();return typeof e=="function"&&(e=e()),t.memoizedState=t.baseState=e,e={pending:null,interleaved:null,lanes:0,dispatch:null,lastRenderedReducer:rr,lastRenderedState:e},t.queue=e,e=e.dispatch=gd.bind(null,Q,e),[t.memoizedState,e]}function lr(e,t,n,r){return e={tag:e,create:t,destroy:n,deps:r,next:null},t=Q.updateQueue,t===null?(t={lastEffect:null,stores:null},Q.updateQueue=t,t.lastEffect=e.next=e):(n=t.lastEffect,n===null?t.lastEffect=e.next=e:(r=n.next,n.next=e,e.next=r,t.lastEffect=e)),e}function Sa(){return Le().memoizedState}function Rr(e,t,n,r){var l=Be();Q.flags|=e,l.memoizedState=lr(1|t,n,void 0,r===void 0?null:r)}function yl(e,t,n,r){var l=Le();r=r===void 0?null:r;var i=void 0;if(b!==null){var o=b.memoizedState;if(i=o.destroy,r!==null&&Eo(r,o.deps)){l.memoizedState=lr(t,n,i,r);return}}Q.flags|=e,l.memoizedState=lr(1|t,n,i,r)}function Pu(e,t){return Rr(8390656,8,e,t)}function _o(e,t){return yl(2048,8,e,t)}function ka(e,t){return yl(4,2,e,t)}function Ea(e,t){return yl(4,4,e,t)}function ja(e,t){if(typeof t=="function")return e=e(),t(e),function(){t(null)};if(t!=null)return e=e(),t.current=e,function(){t.current=null}}function Ca(e,t,n){return n=n!=null?n.concat([e]):null,yl(4,4,ja.bind(null,t,e),n)}function No(){}function _a(e,t){var n=Le();t=t===void 0?null:t;var r=n.memoizedState;return r!==null&&t!==null&&Eo(t,r[1])?r[0]:(n.memoizedState=[e,t],e)}function Na(e,t){var n=Le();t=t===void 0?null:t;var r=n.memoizedState;return r!==null&&t!==null&&Eo(t,r[1])?r[0]:(e=e(),n.memoizedState=[e,t],e)}function Pa(e,t,n){return Rt&21?(Ue(n,t)||(n=Os(),Q.lanes|=n,It|=n,e.baseState=!0),t):(e.baseState&&(e.baseState=!1,ve=!0),e.memoizedState=n)}function vd(e,t){var n=I;I=n!==0&&4>n?n:4,e(!0);var r=Kl.transition;Kl.transition={};try{e(!1),t()}finally{I=n,Kl.transition=r}}function Ta(){return Le().memoizedState}function yd(e,t,n){var r=gt(e);if(n={lane:r,action:n,hasEagerState:!1,eagerState:null,next:null},za(e))La(t,n);else if(n=fa(e,t,n,r),n!==null){var l=de();Fe(n,e,r,l),Ma(n,t,r)}}function gd(e,t,n){var r=gt(e),l={lane:r,action:n,hasEagerState:!1,eagerState:null,next:null};if(za(e))La(t,l);else{var i=e.alternate;if(e.lanes===0&&(i===null||i.lanes===0)&&(i=t.lastRenderedReducer,i!==null))try{var o=t.lastRenderedState,u=i(o,n);if(l.hasEagerState=!0,l.eagerState=u,Ue(u,o)){var s=t.interleaved;s===null?(l.next=l,go(t)):(l.next=s.next,s.next=l),t.interleaved=l;return}}catch{}finally{}n=fa(e,t,l,r),n!==null&&(l=de(),Fe(n,e,r,l),Ma(n,t,r))}}function za(e){var t=e.alternate;return e===Q||t!==null&&t===Q}function La(e,t){An=rl=!0;var n=e.pending;n===null?t.next=t:(t.next=n.next,n.next=t),e.pending=t}function Ma(e,t,n){if(n&4194240){var r=t.lanes;r&=e.pendingLanes,n|=r,t.lanes=n,ro(e,n)}}var ll={readContext:ze,useCallback:oe,useContext:oe,useEffect:oe,useImperativeHandle:oe,useInsertionEffect:oe,useLayoutEffect:oe,useMemo:oe,useReducer:oe,useRef:oe,useState:oe,useDebugValue:oe,useDeferredValue:oe,useTransition:oe,useMutableSource:oe,useSyncExternalStore:oe,useId:oe,unstable_isNewReconciler:!1},wd={readContext:ze,useCallback:function(e,t){return Be().memoizedState=[e,t===void 0?null:t],e},useContext:ze,useEffect:Pu,useImperativeHandle:function(e,t,n){return n=n!=null?n.concat([e]):null,Rr(4194308,4,ja.bind(null,t,e),n)},useLayoutEffect:function(e,t){return Rr(4194308,4,e,t)},useInsertionEffect:function(e,t){return Rr(4,2,e,t)},useMemo:function(e,t){var n=Be();return t=t===void 0?null:t,e=e(),n.memoizedState=[e,t],e},useReducer:function(e,t,n){var r=Be();return t=n!==void 0?n(t):t,r.memoizedState=r.baseState=t,e={pending:null,interleaved:null,lanes:0,dispatch:null,lastRenderedReducer:e,lastRenderedState:t},r.queue=e,e=e.dispatch=yd.bind(null,Q,e),[r.memoizedState,e]},useRef:function(e){var t=Be();return e={current:e},t.memoizedState=e},useState:Nu,useDebugValue:No,useDeferredValue:function(e){return Be().memoizedState=e},useTransition:function(){var e=Nu(!1),t=e[0];return e=vd.bind(null,e[1]),Be().memoizedState=e,[t,e]},useMutableSource:function(){},useSyncExternalStore:function(e,t,n){var r=Q,l=Be();if(V){if(n===void 0)throw Error(w(407));n=n()}else{if(n=t(),ne===null)throw Error(w(349));Rt&30||va(r,t,n)}l.memoizedState=n;var i={value:n,getSnapshot:t};return l.queue=i,Pu(ga.bind(null,r,i,e),[e]),r.flags|=2048,lr(9,ya.bind(null,r,i,n,t),void 0,null),n},useId:function(){var e=Be(),t=ne.identifierPrefix;if(V){var n=Je,r=Ge;n=(r&~(1<<32-Ie(r)-1)).toString(32)+n,t=":"+t+"R"+n,n=nr++,0<n&&(t+="H"+n.toString(32)),t+=":"}else n=md++,t=":"+t+"r"+n.toString(32)+":";return e.memoizedState=t},unstable_isNewReconciler:!1},xd={readContext:ze,useCallback:_a,useContext:ze,useEffect:_o,useImperativeHandle:Ca,useInsertionEffect:ka,useLayoutEffect:Ea,useMemo:Na,useReducer:Yl,useRef:Sa,useState:function(){return Yl(rr)},useDebugValue:No,useDeferredValue:function(e){var t=Le();return Pa(t,b.memoizedState,e)},useTransition:function(){var e=Yl(rr)[0],t=Le().memoizedState;return[e,t]},useMutableSource:ha,useSyncExternalStore:ma,useId:Ta,unstable_isNewReconciler:!1},Sd={readContext:ze,useCallback:_a,useContext:ze,useEffect:_o,useImperativeHandle:Ca,useInsertionEffect:ka,useLayoutEffect:Ea,useMemo:Na,useReducer:Zl,useRef:Sa,useState:function(){return Zl(rr)},useDebugValue:No,useDeferredValue:function(e){var t=Le();return b===null?t.memoizedState=e:Pa(t,b.memoizedState,e)},useTransition:function(){var e=Zl(rr)[0],t=Le().memoizedState;return[e,t]},useMutableSource:ha,useSyncExternalStore:ma,useId:Ta,unstable_isNewReconciler:!1};function Oe(e,t){if(e&&e.defaultProps){t=K({},t),e=e.defaultProps;for(var n in e)t[n]===void 0&&(t[n]=e[n]);return t}return t}function Ti(e,t,n,r){t=e.memoizedState,n=n(r,t),n=n==null?t:K({},t,n),e.memoizedState=n,e.lanes===0&&(e.updateQueue.baseState=n)}var gl={isMounted:function(e){return(e=e._reactInternals)?At(e)===e:!1},enqueueSetState:function(e,t,n){e=e._reactInternals;var r=de(),l=gt(e),i=qe(r,l);i.payload=t,n!=null&&(i.callback=n),t=vt(e,i,l),t!==null&&(Fe(t,e,l,r),Or(t,e,l))},enqueueReplaceState:function(e,t,n){e=e._reactInternals;var r=de(),l=gt(e),i=qe(r,l);i.tag=1,i.payload=t,n!=null&&(i.callback=n),t=vt(e,i,l),t!==null&&(Fe(t,e,l,r),Or(t,e,l))},enqueueForceUpdate:function(e,t){e=e._reactInternals;var n=de(),r=gt(e),l=qe(n,r);l.tag=2,t!=null&&(l.callback=t),t=vt(e,l,r),t!==null&&(Fe(t,e,r,n),Or(t,e,r))}};function Tu(e,t,n,r,l,i,o){return e=e.stateNode,typeof e.shouldComponentUpdate=="function"?e.shouldComponentUpdate(r,i,o):t.prototype&&t.prototype.isPureReactComponent?!Gn(n,r)||!Gn(l,i):!0}function Oa(e,t,n){var r=!1,l=St,i=t.contextType;return typeof i=="object"&&i!==null?i=ze(i):(l=ge(t)?Ot:ae.current,r=t.contextTypes,i=(r=r!=null)?dn(e,l):St),t=new t(n,i),e.memoizedState=t.state!==null&&t.state!==void 0?t.state:null,t.updater=gl,e.stateNode=t,t._reactInternals=e,r&&(e=e.stateNode,e.__reactInternalMemoizedUnmaskedChildContext=l,e.__reactInternalMemoizedMaskedChildContext=i),t}function zu(e,t,n,r){e=t.state,typeof t.componentWillReceiveProps=="function"&&t.componentWillReceiveProps(n,r),typeof t.UNSAFE_componentWillReceiveProps=="function"&&t.UNSAFE_componentWillReceiveProps(n,r),t.state!==e&&gl.enqueueReplaceState(t,t.state,null)}function zi(e,t,n,r){var l=e.stateNode;l.props=n,l.state=e.memoizedState,l.refs={},wo(e);var i=t.contextType;typeof i=="object"&&i!==null?l.context=ze(i):(i=ge(t)?Ot:ae.current,l.context=dn(e,i)),l.state=e.memoizedState,i=t.getDerivedStateFromProps,typeof i=="function"&&(Ti(e,t,i,n),l.state=e.memoizedState),typeof t.getDerivedStateFromProps=="function"||typeof l.getSnapshotBeforeUpdate=="function"||typeof l.UNSAFE_componentWillMount!="function"&&typeof l.componentWillMount!="function"||(t=l.state,typeof l.componentWillMount=="function"&&l.componentWillMount(),typeof l.UNSAFE_componentWillMount=="function"&&l.UNSAFE_componentWillMount(),t!==l.state&&gl.enqueueReplaceState(l,l.state,null),tl(e,n,l,r),l.state=e.memoizedState),typeof l.componentDidMount=="function"&&(e.flags|=4194308)}function vn(e,t){try{var n="",r=t;do n+=Zc(r),r=r.return;while(r);var l=n}catch(i){l=`
-Error generating stack: `+i.message+`
-`+i.stack}return{value:e,source:t,stack:l,digest:null}}function Xl(e,t,n){return{value:e,source:null,stack:n??null,digest:t??null}}function Li(e,t){try{console.error(t.value)}catch(n){setTimeout(function(){throw n})}}var kd=typeof WeakMap=="function"?WeakMap:Map;function Da(e,t,n){n=qe(-1,n),n.tag=3,n.payload={element:null};var r=t.value;return n.callback=function(){ol||(ol=!0,Vi=r),Li(e,t)},n}function Ra(e,t,n){n=qe(-1,n),n.tag=3;var r=e.type.getDerivedStateFromError;if(typeof r=="function"){var l=t.value;n.payload=function(){return r(l)},n.callback=function(){Li(e,t)}}var i=e.stateNode;return i!==null&&typeof i.componentDidCatch=="function"&&(n.callback=function(){Li(e,t),typeof r!="function"&&(yt===null?yt=new Set([this]):yt.add(this));var o=t.stack;this.componentDidCatch(t.value,{componentStack:o!==null?o:""})}),n}function Lu(e,t,n){var r=e.pingCache;if(r===null){r=e.pingCache=new kd;var l=new Set;r.set(t,l)}else l=r.get(t),l===void 0&&(l=new Set,r.set(t,l));l.has(n)||(l.add(n),e=Id.bind(null,e,t,n),t.then(e,e))}function Mu(e){do{var t;if((t=e.tag===13)&&(t=e.memoizedState,t=t!==null?t.dehydrated!==null:!0),t)return e;e=e.return}while(e!==null);return null}function Ou(e,t,n,r,l){return e.mode&1?(e.flags|=65536,e.lanes=l,e):(e===t?e.flags|=65536:(e.flags|=128,n.flags|=131072,n.flags&=-52805,n.tag===1&&(n.alternate===null?n.tag=17:(t=qe(-1,1),t.tag=2,vt(n,t,1))),n.lanes|=1),e)}var Ed=rt.ReactCurrentOwner,ve=!1;function fe(e,t,n,r){t.child=e===null?ca(t,null,n,r):hn(t,e.child,n,r)}function Du(e,t,n,r,l){n=n.render;var i=t.ref;return an(t,l),r=jo(e,t,n,r,i,l),n=Co(),e!==null&&!ve?(t.updateQueue=e.updateQueue,t.flags&=-2053,e.lanes&=~l,nt(e,t,l)):(V&&n&&fo(t),t.flags|=1,fe(e,t,r,l),t.child)}function Ru(e,t,n,r,l){if(e===null){var i=n.type;return typeof i=="function"&&!Ro(i)&&i.defaultProps===void 0&&n.compare===null&&n.defaultProps===void 0?(t.tag=15,t.type=i,Ia(e,t,i,r,l)):(e=Ar(n.type,null,r,t,t.mode,l),e.ref=t.ref,e.return=t,t.child=e)}if(i=e.child,!(e.lanes&l)){var o=i.memoizedProps;if(n=n.compare,n=n!==null?n:Gn,n(o,r)&&e.ref===t.ref)return nt(e,t,l)}return t.flags|=1,e=wt(i,r),e.ref=t.ref,e.return=t,t.child=e}function Ia(e,t,n,r,l){if(e!==null){var i=e.memoizedProps;if(Gn(i,r)&&e.ref===t.ref)if(ve=!1,t.pendingProps=r=i,(e.lanes&l)!==0)e.flags&131072&&(ve=!0);else return t.lanes=e.lanes,nt(e,t,l)}return Mi(e,t,n,r,l)}function Fa(e,t,n){var r=t.pendingProps,l=r.children,i=e!==null?e.memoizedState:null;if(r.mode==="hidden")if(!(t.mode&1))t.memoizedState={baseLanes:0,cachePool:null,transitions:null},F(rn,xe),xe|=n;else{if(!(n&1073741824))return e=i!==null?i.baseLanes|n:n,t.lanes=t.childLanes=1073741824,t.memoizedState={baseLanes:e,cachePool:null,transitions:null},t.updateQueue=null,F(rn,xe),xe|=e,null;t.memoizedState={baseLanes:0,cachePool:null,transitions:null},r=i!==null?i.baseLanes:n,F(rn,xe),xe|=r}else i!==null?(r=i.baseLanes|n,t.memoizedState=null):r=n,F(rn,xe),xe|=r;return fe(e,t,l,n),t.child}function Ua(e,t){var n=t.ref;(e===null&&n!==null||e!==null&&e.ref!==n)&&(t.flags|=512,t.flags|=2097152)}function Mi(e,t,n,r,l){var i=ge(n)?Ot:ae.current;return i=dn(t,i),an(t,l),n=jo(e,t,n,r,i,l),r=Co(),e!==null&&!ve?(t.updateQueue=e.updateQueue,t.flags&=-2053,e.lanes&=~l,nt(e,t,l)):(V&&r&&fo(t),t.flags|=1,fe(e,t,n,l),t.child)}function Iu(e,t,n,r,l){if(ge(n)){var i=!0;Gr(t)}else i=!1;if(an(t,l),t.stateNode===null)Ir(e,t),Oa(t,n,r),zi(t,n,r,l),r=!0;else if(e===null){var o=t.stateNode,u=t.memoizedProps;o.props=u;var s=o.context,d=n.contextType;typeof d=="object"&&d!==null?d=ze(d):(d=ge(n)?Ot:ae.current,d=dn(t,d));var y=n.getDerivedStateFromProps,m=typeof y=="function"||typeof o.getSnapshotBeforeUpdate=="function";m||typeof o.UNSAFE_componentWillReceiveProps!="function"&&typeof o.componentWillReceiveProps!="function"||(u!==r||s!==d)&&zu(t,o,r,d),ut=!1;var h=t.memoizedState;o.state=h,tl(t,r,o,l),s=t.memoizedState,u!==r||h!==s||ye.current||ut?(typeof y=="function"&&(Ti(t,n,y,r),s=t.memoizedState),(u=ut||Tu(t,n,u,r,h,s,d))?(m||typeof o.UNSAFE_componentWillMount!="function"&&typeof o.componentWillMount!="function"||(typeof o.componentWillMount=="function"&&o.componentWillMount(),typeof o.UNSAFE_componentWillMount=="function"&&o.UNSAFE_componentWillMount()),typeof o.componentDidMount=="function"&&(t.flags|=4194308)):(typeof o.componentDidMount=="function"&&(t.flags|=4194308),t.memoizedProps=r,t.memoizedState=s),o.props=r,o.state=s,o.context=d,r=u):(typeof o.componentDidMount=="function"&&(t.flags|=4194308),r=!1)}else{o=t.stateNode,da(e,t),u=t.memoizedProps,d=t.type===t.elementType?u:Oe(t.type,u),o.props=d,m=t.pendingProps,h=o.context,s=n.contextType,typeof s=="object"&&s!==null?s=ze(s):(s=ge(n)?Ot:ae.current,s=dn(t,s));var x=n.getDerivedStateFromProps;(y=typeof x=="function"||typeof o.getSnapshotBeforeUpdate=="function")||typeof o.UNSAFE_componentWillReceiveProps!="function"&&typeof o.componentWillReceiveProps!="function"||(u!==m||h!==s)&&zu(t,o,r,s),ut=!1,h=t.memoizedState,o.state=h,tl(t,r,o,l);var S=t.memoizedState;u!==m||h!==S||ye.current||ut?(typeof x=="function"&&(Ti(t,n,x,r),S=t.memoizedState),(d=ut||Tu(t,n,d,r,h,S,s)||!1)?(y||typeof o.UNSAFE_componentWillUpdate!="function"&&typeof o.componentWillUpdate!="function"||(typeof o.componentWillUpdate=="function"&&o.componentWillUpdate(r,S,s),typeof o.UNSAFE_componentWillUpdate=="function"&&o.UNSAFE_componentWillUpdate(r,S,s)),typeof o.componentDidUpdate=="function"&&(t.flags|=4),typeof o.getSnapshotBeforeUpdate=="function"&&(t.flags|=1024)):(typeof o.componentDidUpdate!="function"||u===e.memoizedProps&&h===e.memoizedState||(t.flags|=4),typeof o.getSnapshotBeforeUpdate!="function"||u===e.memoizedProps&&h===e.memoizedState||(t.flags|=1024),t.memoizedProps=r,t.memoizedState=S),o.props=r,o.state=S,o.context=s,r=d):(typeof o.componentDidUpdate!="function"||u===e.memoizedProps&&h===e.memoizedState||(t.flags|=4),typeof o.getSnapshotBeforeUpdate!="function"||u===e.memoizedProps&&h===e.memoizedState||(t.flags|=1024),r=!1)}return Oi(e,t,n,r,i,l)}function Oi(e,t,n,r,l,i){Ua(e,t);var o=(t.flags&128)!==0;if(!r&&!o)return l&&Su(t,n,!1),nt(e,t,i);r=t.stateNode,Ed.current=t;var u=o&&typeof n.getDerivedStateFromError!="function"?null:r.render();return t.flags|=1,e!==null&&o?(t.child=hn(t,e.child,null,i),t.child=hn(t,null,u,i)):fe(e,t,u,i),t.memoizedState=r.state,l&&Su(t,n,!0),t.child}function Aa(e){var t=e.stateNode;t.pendingContext?xu(e,t.pendingContext,t.pendingContext!==t.context):t.context&&xu(e,t.context,!1),xo(e,t.containerInfo)}function Fu(e,t,n,r,l){return pn(),ho(l),t.flags|=256,fe(e,t,n,r),t.child}var Di={dehydrated:null,treeContext:null,retryLane:0};function Ri(e){return{baseLanes:e,cachePool:null,transitions:null}}function $a(e,t,n){var r=t.pendingProps,l=H.current,i=!1,o=(t.flags&128)!==0,u;if((u=o)||(u=e!==null&&e.memoizedState===null?!1:(l&2)!==0),u?(i=!0,t.flags&=-129):(e===null||e.memoizedState!==null)&&(l|=1),F(H,l&1),e===null)return Ni(t),e=t.memoizedState,e!==null&&(e=e.dehydrated,e!==null)?(t.mode&1?e.data==="$!"?t.lanes=8:t.lanes=1073741824:t.lanes=1,null):(o=r.children,e=r.fallback,i?(r=t.mode,i=t.child,o={mode:"hidden",children:o},!(r&1)&&i!==null?(i.childLanes=0,i.pendingProps=o):i=Sl(o,r,0,null),e=Mt(e,r,n,null),i.return=t,e.return=t,i.sibling=e,t.child=i,t.child.memoizedState=Ri(n),t.memoizedState=Di,e):Po(t,o));if(l=e.memoizedState,l!==null&&(u=l.dehydrated,u!==null))return jd(e,t,o,r,u,l,n);if(i){i=r.fallback,o=t.mode,l=e.child,u=l.sibling;var s={mode:"hidden",children:r.children};return!(o&1)&&t.child!==l?(r=t.child,r.childLanes=0,r.pendingProps=s,t.deletions=null):(r=wt(l,s),r.subtreeFlags=l.subtreeFlags&14680064),u!==null?i=wt(u,i):(i=Mt(i,o,n,null),i.flags|=2),i.return=t,r.return=t,r.sibling=i,t.child=r,r=i,i=t.child,o=e.child.memoizedState,o=o===null?Ri(n):{baseLanes:o.baseLanes|n,cachePool:null,transitions:o.transitions},i.memoizedState=o,i.childLanes=e.childLanes&~n,t.memoizedState=Di,r}return i=e.child,e=i.sibling,r=wt(i,{mode:"visible",children:r.children}),!(t.mode&1)&&(r.lanes=n),r.return=t,r.sibling=null,e!==null&&(n=t.deletions,n===null?(t.deletions=[e],t.flags|=16):n.push(e)),t.child=r,t.memoizedState=null,r}function Po(e,t){return t=Sl({mode:"visible",children:t},e.mode,0,null),t.return=e,e.child=t}function jr(e,t,n,r){return r!==null&&ho(r),hn(t,e.child,null,n),e=Po(t,t.pendingProps.children),e.flags|=2,t.memoizedState=null,e}function jd(e,t,n,r,l,i,o){if(n)return t.flags&256?(t.flags&=-257,r=Xl(Error(w(422))),jr(e,t,o,r)):t.memoizedState!==null?(t.child=e.child,t.flags|=128,null):(i=r.fallback,l=t.mode,r=Sl({mode:"visible",children:r.children},l,0,null),i=Mt(i,l,o,null),i.flags|=2,r.return=t,i.return=t,r.sibling=i,t.child=r,t.mode&1&&hn(t,e.child,null,o),t.child.memoizedState=Ri(o),t.memoizedState=Di,i);if(!(t.mode&1))return jr(e,t,o,null);if(l.data==="$!"){if(r=l.nextSibling&&l.nextSibling.dataset,r)var u=r.dgst;return r=u,i=Error(w(419)),r=Xl(i,r,void 0),jr(e,t,o,r)}if(u=(o&e.childLanes)!==0,ve||u){if(r=ne,r!==null){switch(o&-o){case 4:l=2;break;case 16:l=8;break;case 64:case 128:case 256:case 512:case 1024:case 2048:case 4096:case 8192:case 16384:case 32768:case 65536:case 131072:case 262144:case 524288:case 1048576:case 2097152:case 4194304:case 8388608:case 16777216:case 33554432:case 67108864:l=32;break;case 536870912:l=268435456;break;default:l=0}l=l&(r.suspendedLanes|o)?0:l,l!==0&&l!==i.retryLane&&(i.retryLane=l,tt(e,l),Fe(r,e,l,-1))}return Do(),r=Xl(Error(w(421))),jr(e,t,o,r)}return l.data==="$?"?(t.flags|=128,t.child=e.child,t=Fd.bind(null,e),l._reactRetry=t,null):(e=i.treeContext,Se=mt(l.nextSibling),ke=t,V=!0,Re=null,e!==null&&(_e[Ne++]=Ge,_e[Ne++]=Je,_e[Ne++]=Dt,Ge=e.id,Je=e.overflow,Dt=t),t=Po(t,r.children),t.flags|=4096,t)}function Uu(e,t,n){e.lanes|=t;var r=e.alternate;r!==null&&(r.lanes|=t),Pi(e.return,t,n)}function Gl(e,t,n,r,l){var i=e.memoizedState;i===null?e.memoizedState={isBackwards:t,rendering:null,renderingStartTime:0,last:r,tail:n,tailMode:l}:(i.isBackwards=t,i.rendering=null,i.renderingStartTime=0,i.last=r,i.tail=n,i.tailMode=l)}function Va(e,t,n){var r=t.pendingProps,l=r.revealOrder,i=r.tail;if(fe(e,t,r.children,n),r=H.current,r&2)r=r&1|2,t.flags|=128;else{if(e!==null&&e.flags&128)e:for(e=t.child;e!==null;){if(e.tag===13)e.memoizedState!==null&&Uu(e,n,t);else if(e.tag===19)Uu(e,n,t);else if(e.child!==null){e.child.return=e,e=e.child;continue}if(e===t)break e;for(;e.sibling===null;){if(e.return===null||e.return===t)break e;e=e.return}e.sibling.return=e.return,e=e.sibling}r&=1}if(F(H,r),!(t.mode&1))t.memoizedState=null;else switch(l){case"forwards":for(n=t.child,l=null;n!==null;)e=n.alternate,e!==null&&nl(e)===null&&(l=n),n=n.sibling;n=l,n===null?(l=t.child,t.child=null):(l=n.sibling,n.sibling=null),Gl(t,!1,l,n,i);break;case"backwards":for(n=null,l=t.child,t.child=null;l!==null;){if(e=l.alternate,e!==null&&nl(e)===null){t.child=l;break}e=l.sibling,l.sibling=n,n=l,l=e}Gl(t,!0,n,null,i);break;case"together":Gl(t,!1,null,null,void 0);break;default:t.memoizedState=null}return t.child}function Ir(e,t){!(t.mode&1)&&e!==null&&(e.alternate=null,t.alternate=null,t.flags|=2)}function nt(e,t,n){if(e!==null&&(t.dependencies=e.dependencies),It|=t.lanes,!(n&t.childLanes))return null;if(e!==null&&t.child!==e.child)throw Error(w(153));if(t.child!==null){for(e=t.child,n=wt(e,e.pendingProps),t.child=n,n.return=t;e.sibling!==null;)e=e.sibling,n=n.sibling=wt(e,e.pendingProps),n.return=t;n.sibling=null}return t.child}function Cd(e,t,n){switch(t.tag){case 3:Aa(t),pn();break;case 5:pa(t);break;case 1:ge(t.type)&&Gr(t);break;case 4:xo(t,t.stateNode.containerInfo);break;case 10:var r=t.type._context,l=t.memoizedProps.value;F(br,r._currentValue),r._currentValue=l;break;case 13:if(r=t.memoizedState,r!==null)return r.dehydrated!==null?(F(H,H.current&1),t.flags|=128,null):n&t.child.childLanes?$a(e,t,n):(F(H,H.current&1),e=nt(e,t,n),e!==null?e.sibling:null);F(H,H.current&1);break;case 19:if(r=(n&t.childLanes)!==0,e.flags&128){if(r)return Va(e,t,n);t.flags|=128}if(l=t.memoizedState,l!==null&&(l.rendering=null,l.tail=null,l.lastEffect=null),F(H,H.current),r)break;return null;case 22:case 23:return t.lanes=0,Fa(e,t,n)}return nt(e,t,n)}var Wa,Ii,Ba,Ha;Wa=function(e,t){for(var n=t.child;n!==null;){if(n.tag===5||n.tag===6)e.appendChild(n.stateNode);else if(n.tag!==4&&n.child!==null){n.child.return=n,n=n.child;continue}if(n===t)break;for(;n.sibling===null;){if(n.return===null||n.return===t)return;n=n.return}n.sibling.return=n.return,n=n.sibling}};Ii=function(){};Ba=function(e,t,n,r){var l=e.memoizedProps;if(l!==r){e=t.stateNode,zt(Ke.current);var i=null;switch(n){case"input":l=li(e,l),r=li(e,r),i=[];break;case"select":l=K({},l,{value:void 0}),r=K({},r,{value:void 0}),i=[];break;case"textarea":l=ui(e,l),r=ui(e,r),i=[];break;default:typeof l.onClick!="function"&&typeof r.onClick=="function"&&(e.onclick=Zr)}ai(n,r);var o;n=null;for(d in l)if(!r.hasOwnProperty(d)&&l.hasOwnProperty(d)&&l[d]!=null)if(d==="style"){var u=l[d];for(o in u)u.hasOwnProperty(o)&&(n||(n={}),n[o]="")}else d!=="dangerouslySetInnerHTML"&&d!=="children"&&d!=="suppressContentEditableWarning"&&d!=="suppressHydrationWarning"&&d!=="autoFocus"&&(Bn.hasOwnProperty(d)?i||(i=[]):(i=i||[]).push(d,null));for(d in r){var s=r[d];if(u=l!=null?l[d]:void 0,r.hasOwnProperty(d)&&s!==u&&(s!=null||u!=null))if(d==="style")if(u){for(o in u)!u.hasOwnProperty(o)||s&&s.hasOwnProperty(o)||(n||(n={}),n[o]="");for(o in s)s.hasOwnProperty(o)&&u[o]!==s[o]&&(n||(n={}),n[o]=s[o])}else n||(i||(i=[]),i.push(d,n)),n=s;else d==="dangerouslySetInnerHTML"?(s=s?s.__html:void 0,u=u?u.__html:void 0,s!=null&&u!==s&&(i=i||[]).push(d,s)):d==="children"?typeof s!="string"&&typeof s!="number"||(i=i||[]).push(d,""+s):d!=="suppressContentEditableWarning"&&d!=="suppressHydrationWarning"&&(Bn.hasOwnProperty(d)?(s!=null&&d==="onScroll"&&U("scroll",e),i||u===s||(i=[])):(i=i||[]).push(d,s))}n&&(i=i||[]).push("style",n);var d=i;(t.updateQueue=d)&&(t.flags|=4)}};Ha=function(e,t,n,r){n!==r&&(t.flags|=4)};function Pn(e,t){if(!V)switch(e.tailMode){case"hidden":t=e.tail;for(var n=null;t!==null;)t.alternate!==null&&(n=t),t=t.sibling;n===null?e.tail=null:n.sibling=null;break;case"collapsed":n=e.tail;for(var r=null;n!==null;)n.alternate!==null&&(r=n),n=n.sibling;r===null?t||e.tail===null?e.tail=null:e.tail.sibling=null:r.sibling=null}}function ue(e){var t=e.alternate!==null&&e.alternate.child===e.child,n=0,r=0;if(t)for(var l=e.child;l!==null;)n|=l.lanes|l.childLanes,r|=l.subtreeFlags&14680064,r|=l.flags&14680064,l.return=e,l=l.sibling;else for(l=e.child;l!==null;)n|=l.lanes|l.childLanes,r|=l.subtreeFlags,r|=l.flags,l.return=e,l=l.sibling;return e.subtreeFlags|=r,e.childLanes=n,t}function _d(e,t,n){var r=t.pendingProps;switch(po(t),t.tag){case 2:case 16:case 15:case 0:case 11:case 7:case 8:case 12:case 9:case 14:return ue(t),null;case 1:return ge(t.type)&&Xr(),ue(t),null;case 3:return r=t.stateNode,mn(),A(ye),A(ae),ko(),r.pendingContext&&(r.context=r.pendingContext,r.pendingContext=null),(e===null||e.child===null)&&(kr(t)?t.flags|=4:e===null||e.memoizedState.isDehydrated&&!(t.flags&256)||(t.flags|=1024,Re!==null&&(Hi(Re),Re=null))),Ii(e,t),ue(t),null;case 5:So(t);var l=zt(tr.current);if(n=t.type,e!==null&&t.stateNode!=null)Ba(e,t,n,r,l),e.ref!==t.ref&&(t.flags|=512,t.flags|=2097152);else{if(!r){if(t.stateNode===null)throw Error(w(166));return ue(t),null}if(e=zt(Ke.current),kr(t)){r=t.stateNode,n=t.type;var i=t.memoizedProps;switch(r[He]=t,r[bn]=i,e=(t.mode&1)!==0,n){case"dialog":U("cancel",r),U("close",r);break;case"iframe":case"object":case"embed":U("load",r);break;case"video":case"audio":for(l=0;l<On.length;l++)U(On[l],r);break;case"source":U("error",r);break;case"img":case"image":case"link":U("error",r),U("load",r);break;case"details":U("toggle",r);break;case"input":Yo(r,i),U("invalid",r);break;case"select":r._wrapperState={wasMultiple:!!i.multiple},U("invalid",r);break;case"textarea":Xo(r,i),U("invalid",r)}ai(n,i),l=null;for(var o in i)if(i.hasOwnProperty(o)){var u=i[o];o==="children"?typeof u=="string"?r.textContent!==u&&(i.suppressHydrationWarning!==!0&&Sr(r.textContent,u,e),l=["children",u]):typeof u=="number"&&r.textContent!==""+u&&(i.suppressHydrationWarning!==!0&&Sr(r.textContent,u,e),l=["children",""+u]):Bn.hasOwnProperty(o)&&u!=null&&o==="onScroll"&&U("scroll",r)}switch(n){case"input":pr(r),Zo(r,i,!0);break;case"textarea":pr(r),Go(r);break;case"select":case"option":break;default:typeof i.onClick=="function"&&(r.onclick=Zr)}r=l,t.updateQueue=r,r!==null&&(t.flags|=4)}else{o=l.nodeType===9?l:l.ownerDocument,e==="http://www.w3.org/1999/xhtml"&&(e=gs(n)),e==="http://www.w3.org/1999/xhtml"?n==="script"?(e=o.createElement("div"),e.innerHTML="<script><\/script>",e=e.removeChild(e.firstChild)):typeof r.is=="string"?e=o.createElement(n,{is:r.is}):(e=o.createElement(n),n==="select"&&(o=e,r.multiple?o.multiple=!0:r.size&&(o.size=r.size))):e=o.createElementNS(e,n),e[He]=t,e[bn]=r,Wa(e,t,!1,!1),t.stateNode=e;e:{switch(o=ci(n,r),n){case"dialog":U("cancel",e),U("close",e),l=r;break;case"iframe":case"object":case"embed":U("load",e),l=r;break;case"video":case"audio":for(l=0;l<On.length;l++)U(On[l],e);l=r;break;case"source":U("error",e),l=r;break;case"img":case"image":case"link":U("error",e),U("load",e),l=r;break;case"details":U("toggle",e),l=r;break;case"input":Yo(e,r),l=li(e,r),U("invalid",e);break;case"option":l=r;break;case"select":e._wrapperState={wasMultiple:!!r.multiple},l=K({},r,{value:void 0}),U("invalid",e);break;case"textarea":Xo(e,r),l=ui(e,r),U("invalid",e);break;default:l=r}ai(n,l),u=l;for(i in u)if(u.hasOwnProperty(i)){var s=u[i];i==="style"?Ss(e,s):i==="dangerouslySetInnerHTML"?(s=s?s.__html:void 0,s!=null&&ws(e,s)):i==="children"?typeof s=="string"?(n!=="textarea"||s!=="")&&Hn(e,s):typeof s=="number"&&Hn(e,""+s):i!=="suppressContentEditableWarning"&&i!=="suppressHydrationWarning"&&i!=="autoFocus"&&(Bn.hasOwnProperty(i)?s!=null&&i==="onScroll"&&U("scroll",e):s!=null&&Ji(e,i,s,o))}switch(n){case"input":pr(e),Zo(e,r,!1);break;case"textarea":pr(e),Go(e);break;case"option":r.value!=null&&e.setAttribute("value",""+xt(r.value));break;case"select":e.multiple=!!r.multiple,i=r.value,i!=null?ln(e,!!r.multiple,i,!1):r.defaultValue!=null&&ln(e,!!r.multiple,r.defaultValue,!0);break;default:typeof l.onClick=="function"&&(e.onclick=Zr)}switch(n){case"button":case"input":case"select":case"textarea":r=!!r.autoFocus;break e;case"img":r=!0;break e;default:r=!1}}r&&(t.flags|=4)}t.ref!==null&&(t.flags|=512,t.flags|=2097152)}return ue(t),null;case 6:if(e&&t.stateNode!=null)Ha(e,t,e.memoizedProps,r);else{if(typeof r!="string"&&t.stateNode===null)throw Error(w(166));if(n=zt(tr.current),zt(Ke.current),kr(t)){if(r=t.stateNode,n=t.memoizedProps,r[He]=t,(i=r.nodeValue!==n)&&(e=ke,e!==null))switch(e.tag){case 3:Sr(r.nodeValue,n,(e.mode&1)!==0);break;case 5:e.memoizedProps.suppressHydrationWarning!==!0&&Sr(r.nodeValue,n,(e.mode&1)!==0)}i&&(t.flags|=4)}else r=(n.nodeType===9?n:n.ownerDocument).createTextNode(r),r[He]=t,t.stateNode=r}return ue(t),null;case 13:if(A(H),r=t.memoizedState,e===null||e.memoizedState!==null&&e.memoizedState.dehydrated!==null){if(V&&Se!==null&&t.mode&1&&!(t.flags&128))sa(),pn(),t.flags|=98560,i=!1;else if(i=kr(t),r!==null&&r.dehydrated!==null){if(e===null){if(!i)throw Error(w(318));if(i=t.memoizedState,i=i!==null?i.dehydrated:null,!i)throw Error(w(317));i[He]=t}else pn(),!(t.flags&128)&&(t.memoizedState=null),t.flags|=4;ue(t),i=!1}else Re!==null&&(Hi(Re),Re=null),i=!0;if(!i)return t.flags&65536?t:null}return t.flags&128?(t.lanes=n,t):(r=r!==null,r!==(e!==null&&e.memoizedState!==null)&&r&&(t.child.flags|=8192,t.mode&1&&(e===null||H.current&1?ee===0&&(ee=3):Do())),t.updateQueue!==null&&(t.flags|=4),ue(t),null);case 4:return mn(),Ii(e,t),e===null&&Jn(t.stateNode.containerInfo),ue(t),null;case 10:return yo(t.type._context),ue(t),null;case 17:return ge(t.type)&&Xr(),ue(t),null;case 19:if(A(H),i=t.memoizedState,i===null)return ue(t),null;if(r=(t.flags&128)!==0,o=i.rendering,o===null)if(r)Pn(i,!1);else{if(ee!==0||e!==null&&e.flags&128)for(e=t.child;e!==null;){if(o=nl(e),o!==null){for(t.flags|=128,Pn(i,!1),r=o.updateQueue,r!==null&&(t.updateQueue=r,t.flags|=4),t.subtreeFlags=0,r=n,n=t.child;n!==null;)i=n,e=r,i.flags&=14680066,o=i.alternate,o===null?(i.childLanes=0,i.lanes=e,i.child=null,i.subtreeFlags=0,i.memoizedProps=null,i.memoizedState=null,i.updateQueue=null,i.dependencies=null,i.stateNode=null):(i.childLanes=o.childLanes,i.lanes=o.lanes,i.child=o.child,i.subtreeFlags=0,i.deletions=null,i.memoizedProps=o.memoizedProps,i.memoizedState=o.memoizedState,i.updateQueue=o.updateQueue,i.type=o.type,e=o.dependencies,i.dependencies=e===null?null:{lanes:e.lanes,firstContext:e.firstContext}),n=n.sibling;return F(H,H.current&1|2),t.child}e=e.sibling}i.tail!==null&&G()>yn&&(t.flags|=128,r=!0,Pn(i,!1),t.lanes=4194304)}else{if(!r)if(e=nl(o),e!==null){if(t.flags|=128,r=!0,n=e.updateQueue,n!==null&&(t.updateQueue=n,t.flags|=4),Pn(i,!0),i.tail===null&&i.tailMode==="hidden"&&!o.alternate&&!V)return ue(t),null}else 2*G()-i.renderingStartTime>yn&&n!==1073741824&&(t.flags|=128,r=!0,Pn(i,!1),t.lanes=4194304);i.isBackwards?(o.sibling=t.child,t.child=o):(n=i.last,n!==null?n.sibling=o:t.child=o,i.last=o)}return i.tail!==null?(t=i.tail,i.rendering=t,i.tail=t.sibling,i.renderingStartTime=G(),t.sibling=null,n=H.current,F(H,r?n&1|2:n&1),t):(ue(t),null);case 22:case 23:return Oo(),r=t.memoizedState!==null,e!==null&&e.memoizedState!==null!==r&&(t.flags|=8192),r&&t.mode&1?xe&1073741824&&(ue(t),t.subtreeFlags&6&&(t.flags|=8192)):ue(t),null;case 24:return null;case 25:return null}throw Error(w(156,t.tag))}function Nd(e,t){switch(po(t),t.tag){case 1:return ge(t.type)&&Xr(),e=t.flags,e&65536?(t.flags=e&-65537|128,t):null;case 3:return mn(),A(ye),A(ae),ko(),e=t.flags,e&65536&&!(e&128)?(t.flags=e&-65537|128,t):null;case 5:return So(t),null;case 13:if(A(H),e=t.memoizedState,e!==null&&e.dehydrated!==null){if(t.alternate===null)throw Error(w(340));pn()}return e=t.flags,e&65536?(t.flags=e&-65537|128,t):null;case 19:return A(H),null;case 4:return mn(),null;case 10:return yo(t.type._context),null;case 22:case 23:return Oo(),null;case 24:return null;default:return null}}var Cr=!1,se=!1,Pd=typeof WeakSet=="function"?WeakSet:Set,_=null;function nn(e,t){var n=e.ref;if(n!==null)if(typeof n=="function")try{n(null)}catch(r){X(e,t,r)}else n.current=null}function Fi(e,t,n){try{n()}catch(r){X(e,t,r)}}var Au=!1;function Td(e,t){if(xi=Qr,e=Xs(),co(e)){if("selectionStart"in e)var n={start:e.selectionStart,end:e.selectionEnd};else e:{n=(n=e.ownerDocument)&&n.defaultView||window;var r=n.getSelection&&n.getSelection();if(r&&r.rangeCount!==0){n=r.anchorNode;var l=r.anchorOffset,i=r.focusNode;r=r.focusOffset;try{n.nodeType,i.nodeType}catch{n=null;break e}var o=0,u=-1,s=-1,d=0,y=0,m=e,h=null;t:for(;;){for(var x;m!==n||l!==0&&m.nodeType!==3||(u=o+l),m!==i||r!==0&&m.nodeType!==3||(s=o+r),m.nodeType===3&&(o+=m.nodeValue.length),(x=m.firstChild)!==null;)h=m,m=x;for(;;){if(m===e)break t;if(h===n&&++d===l&&(u=o),h===i&&++y===r&&(s=o),(x=m.nextSibling)!==null)break;m=h,h=m.parentNode}m=x}n=u===-1||s===-1?null:{start:u,end:s}}else n=null}n=n||{start:0,end:0}}else n=null;for(Si={focusedElem:e,selectionRange:n},Qr=!1,_=t;_!==null;)if(t=_,e=t.child,(t.subtreeFlags&1028)!==0&&e!==null)e.return=t,_=e;else for(;_!==null;){t=_;try{var S=t.alternate;if(t.flags&1024)switch(t.tag){case 0:case 11:case 15:break;case 1:if(S!==null){var j=S.memoizedProps,O=S.memoizedState,f=t.stateNode,c=f.getSnapshotBeforeUpdate(t.elementType===t.type?j:Oe(t.type,j),O);f.__reactInternalSnapshotBeforeUpdate=c}break;case 3:var p=t.stateNode.containerInfo;p.nodeType===1?p.textContent="":p.nodeType===9&&p.documentElement&&p.removeChild(p.documentElement);break;case 5:case 6:case 4:case 17:break;default:throw Error(w(163))}}catch(v){X(t,t.return,v)}if(e=t.sibling,e!==null){e.return=t.return,_=e;break}_=t.return}return S=Au,Au=!1,S}function $n(e,t,n){var r=t.updateQueue;if(r=r!==null?r.lastEffect:null,r!==null){var l=r=r.next;do{if((l.tag&e)===e){var i=l.destroy;l.destroy=void 0,i!==void 0&&Fi(t,n,i)}l=l.next}while(l!==r)}}function wl(e,t){if(t=t.updateQueue,t=t!==null?t.lastEffect:null,t!==null){var n=t=t.next;do{if((n.tag&e)===e){var r=n.create;n.destroy=r()}n=n.next}while(n!==t)}}function Ui(e){var t=e.ref;if(t!==null){var n=e.stateNode;switch(e.tag){case 5:e=n;break;default:e=n}typeof t=="function"?t(e):t.current=e}}function Qa(e){var t=e.alternate;t!==null&&(e.alternate=null,Qa(t)),e.child=null,e.deletions=null,e.sibling=null,e.tag===5&&(t=e.stateNode,t!==null&&(delete t[He],delete t[bn],delete t[ji],delete t[fd],delete t[dd])),e.stateNode=null,e.return=null,e.dependencies=null,e.memoizedProps=null,e.memoizedState=null,e.pendingProps=null,e.stateNode=null,e.updateQueue=null}function Ka(e){return e.tag===5||e.tag===3||e.tag===4}function $u(e){e:for(;;){for(;e.sibling===null;){if(e.return===null||Ka(e.return))return null;e=e.return}for(e.sibling.return=e.return,e=e.sibling;e.tag!==5&&e.tag!==6&&e.tag!==18;){if(e.flags&2||e.child===null||e.tag===4)continue e;e.child.return=e,e=e.child}if(!(e.flags&2))return e.stateNode}}function Ai(e,t,n){var r=e.tag;if(r===5||r===6)e=e.stateNode,t?n.nodeType===8?n.parentNode.insertBefore(e,t):n.insertBefore(e,t):(n.nodeType===8?(t=n.parentNode,t.insertBefore(e,n)):(t=n,t.appendChild(e)),n=n._reactRootContainer,n!=null||t.onclick!==null||(t.onclick=Zr));else if(r!==4&&(e=e.child,e!==null))for(Ai(e,t,n),e=e.sibling;e!==null;)Ai(e,t,n),e=e.sibling}function $i(e,t,n){var r=e.tag;if(r===5||r===6)e=e.stateNode,t?n.insertBefore(e,t):n.appendChild(e);else if(r!==4&&(e=e.child,e!==null))for($i(e,t,n),e=e.sibling;e!==null;)$i(e,t,n),e=e.sibling}var re=null,De=!1;function it(e,t,n){for(n=n.child;n!==null;)Ya(e,t,n),n=n.sibling}function Ya(e,t,n){if(Qe&&typeof Qe.onCommitFiberUnmount=="function")try{Qe.onCommitFiberUnmount(fl,n)}catch{}switch(n.tag){case 5:se||nn(n,t);case 6:var r=re,l=De;re=null,it(e,t,n),re=r,De=l,re!==null&&(De?(e=re,n=n.stateNode,e.nodeType===8?e.parentNode.removeChild(n):e.removeChild(n)):re.removeChild(n.stateNode));break;case 18:re!==null&&(De?(e=re,n=n.stateNode,e.nodeType===8?Bl(e.parentNode,n):e.nodeType===1&&Bl(e,n),Zn(e)):Bl(re,n.stateNode));break;case 4:r=re,l=De,re=n.stateNode.containerInfo,De=!0,it(e,t,n),re=r,De=l;break;case 0:case 11:case 14:case 15:if(!se&&(r=n.updateQueue,r!==null&&(r=r.lastEffect,r!==null))){l=r=r.next;do{var i=l,o=i.destroy;i=i.tag,o!==void 0&&(i&2||i&4)&&Fi(n,t,o),l=l.next}while(l!==r)}it(e,t,n);break;case 1:if(!se&&(nn(n,t),r=n.stateNode,typeof r.componentWillUnmount=="function"))try{r.props=n.memoizedProps,r.state=n.memoizedState,r.componentWillUnmount()}catch(u){X(n,t,u)}it(e,t,n);break;case 21:it(e,t,n);break;case 22:n.mode&1?(se=(r=se)||n.memoizedState!==null,it(e,t,n),se=r):it(e,t,n);break;default:it(e,t,n)}}function Vu(e){var t=e.updateQueue;if(t!==null){e.updateQueue=null;var n=e.stateNode;n===null&&(n=e.stateNode=new Pd),t.forEach(function(r){var l=Ud.bind(null,e,r);n.has(r)||(n.add(r),r.then(l,l))})}}function Me(e,t){var n=t.deletions;if(n!==null)for(var r=0;r<n.length;r++){var l=n[r];try{var i=e,o=t,u=o;e:for(;u!==null;){switch(u.tag){case 5:re=u.stateNode,De=!1;break e;case 3:re=u.stateNode.containerInfo,De=!0;break e;case 4:re=u.stateNode.containerInfo,De=!0;break e}u=u.return}if(re===null)throw Error(w(160));Ya(i,o,l),re=null,De=!1;var s=l.alternate;s!==null&&(s.return=null),l.return=null}catch(d){X(l,t,d)}}if(t.subtreeFlags&12854)for(t=t.child;t!==null;)Za(t,e),t=t.sibling}function Za(e,t){var n=e.alternate,r=e.flags;switch(e.tag){case 0:case 11:case 14:case 15:if(Me(t,e),We(e),r&4){try{$n(3,e,e.return),wl(3,e)}catch(j){X(e,e.return,j)}try{$n(5,e,e.return)}catch(j){X(e,e.return,j)}}break;case 1:Me(t,e),We(e),r&512&&n!==null&&nn(n,n.return);break;case 5:if(Me(t,e),We(e),r&512&&n!==null&&nn(n,n.return),e.flags&32){var l=e.stateNode;try{Hn(l,"")}catch(j){X(e,e.return,j)}}if(r&4&&(l=e.stateNode,l!=null)){var i=e.memoizedProps,o=n!==null?n.memoizedProps:i,u=e.type,s=e.updateQueue;if(e.updateQueue=null,s!==null)try{u==="input"&&i.type==="radio"&&i.name!=null&&vs(l,i),ci(u,o);var d=ci(u,i);for(o=0;o<s.length;o+=2){var y=s[o],m=s[o+1];y==="style"?Ss(l,m):y==="dangerouslySetInnerHTML"?ws(l,m):y==="children"?Hn(l,m):Ji(l,y,m,d)}switch(u){case"input":ii(l,i);break;case"textarea":ys(l,i);break;case"select":var h=l._wrapperState.wasMultiple;l._wrapperState.wasMultiple=!!i.multiple;var x=i.value;x!=null?ln(l,!!i.multiple,x,!1):h!==!!i.multiple&&(i.defaultValue!=null?ln(l,!!i.multiple,i.defaultValue,!0):ln(l,!!i.multiple,i.multiple?[]:"",!1))}l[bn]=i}catch(j){X(e,e.return,j)}}break;case 6:if(Me(t,e),We(e),r&4){if(e.stateNode===null)throw Error(w(162));l=e.stateNode,i=e.memoizedProps;try{l.nodeValue=i}catch(j){X(e,e.return,j)}}break;case 3:if(Me(t,e),We(e),r&4&&n!==null&&n.memoizedState.isDehydrated)try{Zn(t.containerInfo)}catch(j){X(e,e.return,j)}break;case 4:Me(t,e),We(e);break;case 13:Me(t,e),We(e),l=e.child,l.flags&8192&&(i=l.memoizedState!==null,l.stateNode.isHidden=i,!i||l.alternate!==null&&l.alternate.memoizedState!==null||(Lo=G())),r&4&&Vu(e);break;case 22:if(y=n!==null&&n.memoizedState!==null,e.mode&1?(se=(d=se)||y,Me(t,e),se=d):Me(t,e),We(e),r&8192){if(d=e.memoizedState!==null,(e.stateNode.isHidden=d)&&!y&&e.mode&1)for(_=e,y=e.child;y!==null;){for(m=_=y;_!==null;){switch(h=_,x=h.child,h.tag){case 0:case 11:case 14:case 15:$n(4,h,h.return);break;case 1:nn(h,h.return);var S=h.stateNode;if(typeof S.componentWillUnmount=="function"){r=h,n=h.return;try{t=r,S.props=t.memoizedProps,S.state=t.memoizedState,S.componentWillUnmount()}catch(j){X(r,n,j)}}break;case 5:nn(h,h.return);break;case 22:if(h.memoizedState!==null){Bu(m);continue}}x!==null?(x.return=h,_=x):Bu(m)}y=y.sibling}e:for(y=null,m=e;;){if(m.tag===5){if(y===null){y=m;try{l=m.stateNode,d?(i=l.style,typeof i.setProperty=="function"?i.setProperty("display","none","important"):i.display="none"):(u=m.stateNode,s=m.memoizedProps.style,o=s!=null&&s.hasOwnProperty("display")?s.display:null,u.style.display=xs("display",o))}catch(j){X(e,e.return,j)}}}else if(m.tag===6){if(y===null)try{m.stateNode.nodeValue=d?"":m.memoizedProps}catch(j){X(e,e.return,j)}}else if((m.tag!==22&&m.tag!==23||m.memoizedState===null||m===e)&&m.child!==null){m.child.return=m,m=m.child;continue}if(m===e)break e;for(;m.sibling===null;){if(m.return===null||m.return===e)break e;y===m&&(y=null),m=m.return}y===m&&(y=null),m.sibling.return=m.return,m=m.sibling}}break;case 19:Me(t,e),We(e),r&4&&Vu(e);break;case 21:break;default:Me(t,e),We(e)}}function We(e){var t=e.flags;if(t&2){try{e:{for(var n=e.return;n!==null;){if(Ka(n)){var r=n;break e}n=n.return}throw Error(w(160))}switch(r.tag){case 5:var l=r.stateNode;r.flags&32&&(Hn(l,""),r.flags&=-33);var i=$u(e);$i(e,i,l);break;case 3:case 4:var o=r.stateNode.containerInfo,u=$u(e);Ai(e,u,o);break;default:throw Error(w(161))}}catch(s){X(e,e.return,s)}e.flags&=-3}t&4096&&(e.flags&=-4097)}function zd(e,t,n){_=e,Xa(e)}function Xa(e,t,n){for(var r=(e.mode&1)!==0;_!==null;){var l=_,i=l.child;if(l.tag===22&&r){var o=l.memoizedState!==null||Cr;if(!o){var u=l.alternate,s=u!==null&&u.memoizedState!==null||se;u=Cr;var d=se;if(Cr=o,(se=s)&&!d)for(_=l;_!==null;)o=_,s=o.child,o.tag===22&&o.memoizedState!==null?Hu(l):s!==null?(s.return=o,_=s):Hu(l);for(;i!==null;)_=i,Xa(i),i=i.sibling;_=l,Cr=u,se=d}Wu(e)}else l.subtreeFlags&8772&&i!==null?(i.return=l,_=i):Wu(e)}}function Wu(e){for(;_!==null;){var t=_;if(t.flags&8772){var n=t.alternate;try{if(t.flags&8772)switch(t.tag){case 0:case 11:case 15:se||wl(5,t);break;case 1:var r=t.stateNode;if(t.flags&4&&!se)if(n===null)r.componentDidMount();else{var l=t.elementType===t.type?n.memoizedProps:Oe(t.type,n.memoizedProps);r.componentDidUpdate(l,n.memoizedState,r.__reactInternalSnapshotBeforeUpdate)}var i=t.updateQueue;i!==null&&_u(t,i,r);break;case 3:var o=t.updateQueue;if(o!==null){if(n=null,t.child!==null)switch(t.child.tag){case 5:n=t.child.stateNode;break;case 1:n=t.child.stateNode}_u(t,o,n)}break;case 5:var u=t.stateNode;if(n===null&&t.flags&4){n=u;var s=t.memoizedProps;switch(t.type){case"button":case"input":case"select":case"textarea":s.autoFocus&&n.focus();break;case"img":s.src&&(n.src=s.src)}}break;case 6:break;case 4:break;case 12:break;case 13:if(t.memoizedState===null){var d=t.alternate;if(d!==null){var y=d.memoizedState;if(y!==null){var m=y.dehydrated;m!==null&&Zn(m)}}}break;case 19:case 17:case 21:case 22:case 23:case 25:break;default:throw Error(w(163))}se||t.flags&512&&Ui(t)}catch(h){X(t,t.return,h)}}if(t===e){_=null;break}if(n=t.sibling,n!==null){n.return=t.return,_=n;break}_=t.return}}function Bu(e){for(;_!==null;){var t=_;if(t===e){_=null;break}var n=t.sibling;if(n!==null){n.return=t.return,_=n;break}_=t.return}}function Hu(e){for(;_!==null;){var t=_;try{switch(t.tag){case 0:case 11:case 15:var n=t.return;try{wl(4,t)}catch(s){X(t,n,s)}break;case 1:var r=t.stateNode;if(typeof r.componentDidMount=="function"){var l=t.return;try{r.componentDidMount()}catch(s){X(t,l,s)}}var i=t.return;try{Ui(t)}catch(s){X(t,i,s)}break;case 5:var o=t.return;try{Ui(t)}catch(s){X(t,o,s)}}}catch(s){X(t,t.return,s)}if(t===e){_=null;break}var u=t.sibling;if(u!==null){u.return=t.return,_=u;break}_=t.return}}var Ld=Math.ceil,il=rt.ReactCurrentDispatcher,To=rt.ReactCurrentOwner,Te=rt.ReactCurrentBatchConfig,D=0,ne=null,q=null,le=0,xe=0,rn=Et(0),ee=0,ir=null,It=0,xl=0,zo=0,Vn=null,me=null,Lo=0,yn=1/0,Ze=null,ol=!1,Vi=null,yt=null,_r=!1,ft=null,ul=0,Wn=0,Wi=null,Fr=-1,Ur=0;function de(){return D&6?G():Fr!==-1?Fr:Fr=G()}function gt(e){return e.mode&1?D&2&&le!==0?le&-le:hd.transition!==null?(Ur===0&&(Ur=Os()),Ur):(e=I,e!==0||(e=window.event,e=e===void 0?16:$s(e.type)),e):1}function Fe(e,t,n,r){if(50<Wn)throw Wn=0,Wi=null,Error(w(185));ur(e,n,r),(!(D&2)||e!==ne)&&(e===ne&&(!(D&2)&&(xl|=n),ee===4&&at(e,le)),we(e,r),n===1&&D===0&&!(t.mode&1)&&(yn=G()+500,vl&&jt()))}function we(e,t){var n=e.callbackNode;hf(e,t);var r=Hr(e,e===ne?le:0);if(r===0)n!==null&&bo(n),e.callbackNode=null,e.callbackPriority=0;else if(t=r&-r,e.callbackPriority!==t){if(n!=null&&bo(n),t===1)e.tag===0?pd(Qu.bind(null,e)):ia(Qu.bind(null,e)),ad(function(){!(D&6)&&jt()}),n=null;else{switch(Ds(r)){case 1:n=no;break;case 4:n=Ls;break;case 16:n=Br;break;case 536870912:n=Ms;break;default:n=Br}n=rc(n,Ga.bind(null,e))}e.callbackPriority=t,e.callbackNode=n}}function Ga(e,t){if(Fr=-1,Ur=0,D&6)throw Error(w(327));var n=e.callbackNode;if(cn()&&e.callbackNode!==n)return null;var r=Hr(e,e===ne?le:0);if(r===0)return null;if(r&30||r&e.expiredLanes||t)t=sl(e,r);else{t=r;var l=D;D|=2;var i=qa();(ne!==e||le!==t)&&(Ze=null,yn=G()+500,Lt(e,t));do try{Dd();break}catch(u){Ja(e,u)}while(!0);vo(),il.current=i,D=l,q!==null?t=0:(ne=null,le=0,t=ee)}if(t!==0){if(t===2&&(l=mi(e),l!==0&&(r=l,t=Bi(e,l))),t===1)throw n=ir,Lt(e,0),at(e,r),we(e,G()),n;if(t===6)at(e,r);else{if(l=e.current.alternate,!(r&30)&&!Md(l)&&(t=sl(e,r),t===2&&(i=mi(e),i!==0&&(r=i,t=Bi(e,i))),t===1))throw n=ir,Lt(e,0),at(e,r),we(e,G()),n;switch(e.finishedWork=l,e.finishedLanes=r,t){case 0:case 1:throw Error(w(345));case 2:Nt(e,me,Ze);break;case 3:if(at(e,r),(r&130023424)===r&&(t=Lo+500-G(),10<t)){if(Hr(e,0)!==0)break;if(l=e.suspendedLanes,(l&r)!==r){de(),e.pingedLanes|=e.suspendedLanes&l;break}e.timeoutHandle=Ei(Nt.bind(null,e,me,Ze),t);break}Nt(e,me,Ze);break;case 4:if(at(e,r),(r&4194240)===r)break;for(t=e.eventTimes,l=-1;0<r;){var o=31-Ie(r);i=1<<o,o=t[o],o>l&&(l=o),r&=~i}if(r=l,r=G()-r,r=(120>r?120:480>r?480:1080>r?1080:1920>r?1920:3e3>r?3e3:4320>r?4320:1960*Ld(r/1960))-r,10<r){e.timeoutHandle=Ei(Nt.bind(null,e,me,Ze),r);break}Nt(e,me,Ze);break;case 5:Nt(e,me,Ze);break;default:throw Error(w(329))}}}return we(e,G()),e.callbackNode===n?Ga.bind(null,e):null}function Bi(e,t){var n=Vn;return e.current.memoizedState.isDehydrated&&(Lt(e,t).flags|=256),e=sl(e,t),e!==2&&(t=me,me=n,t!==null&&Hi(t)),e}function Hi(e){me===null?me=e:me.push.apply(me,e)}function Md(e){for(var t=e;;){if(t.flags&16384){var n=t.updateQueue;if(n!==null&&(n=n.stores,n!==null))for(var r=0;r<n.length;r++){var l=n[r],i=l.getSnapshot;l=l.value;try{if(!Ue(i(),l))return!1}catch{return!1}}}if(n=t.child,t.subtreeFlags&16384&&n!==null)n.return=t,t=n;else{if(t===e)break;for(;t.sibling===null;){if(t.return===null||t.return===e)return!0;t=t.return}t.sibling.return=t.return,t=t.sibling}}return!0}function at(e,t){for(t&=~zo,t&=~xl,e.suspendedLanes|=t,e.pingedLanes&=~t,e=e.expirationTimes;0<t;){var n=31-Ie(t),r=1<<n;e[n]=-1,t&=~r}}function Qu(e){if(D&6)throw Error(w(327));cn();var t=Hr(e,0);if(!(t&1))return we(e,G()),null;var n=sl(e,t);if(e.tag!==0&&n===2){var r=mi(e);r!==0&&(t=r,n=Bi(e,r))}if(n===1)throw n=ir,Lt(e,0),at(e,t),we(e,G()),n;if(n===6)throw Error(w(345));return e.finishedWork=e.current.alternate,e.finishedLanes=t,Nt(e,me,Ze),we(e,G()),null}function Mo(e,t){var n=D;D|=1;try{return e(t)}finally{D=n,D===0&&(yn=G()+500,vl&&jt())}}function Ft(e){ft!==null&&ft.tag===0&&!(D&6)&&cn();var t=D;D|=1;var n=Te.transition,r=I;try{if(Te.transition=null,I=1,e)return e()}finally{I=r,Te.transition=n,D=t,!(D&6)&&jt()}}function Oo(){xe=rn.current,A(rn)}function Lt(e,t){e.finishedWork=null,e.finishedLanes=0;var n=e.timeoutHandle;if(n!==-1&&(e.timeoutHandle=-1,sd(n)),q!==null)for(n=q.return;n!==null;){var r=n;switch(po(r),r.tag){case 1:r=r.type.childContextTypes,r!=null&&Xr();break;case 3:mn(),A(ye),A(ae),ko();break;case 5:So(r);break;case 4:mn();break;case 13:A(H);break;case 19:A(H);break;case 10:yo(r.type._context);break;case 22:case 23:Oo()}n=n.return}if(ne=e,q=e=wt(e.current,null),le=xe=t,ee=0,ir=null,zo=xl=It=0,me=Vn=null,Tt!==null){for(t=0;t<Tt.length;t++)if(n=Tt[t],r=n.interleaved,r!==null){n.interleaved=null;var l=r.next,i=n.pending;if(i!==null){var o=i.next;i.next=l,r.next=o}n.pending=r}Tt=null}return e}function Ja(e,t){do{var n=q;try{if(vo(),Dr.current=ll,rl){for(var r=Q.memoizedState;r!==null;){var l=r.queue;l!==null&&(l.pending=null),r=r.next}rl=!1}if(Rt=0,te=b=Q=null,An=!1,nr=0,To.current=null,n===null||n.return===null){ee=1,ir=t,q=null;break}e:{var i=e,o=n.return,u=n,s=t;if(t=le,u.flags|=32768,s!==null&&typeof s=="object"&&typeof s.then=="function"){var d=s,y=u,m=y.tag;if(!(y.mode&1)&&(m===0||m===11||m===15)){var h=y.alternate;h?(y.updateQueue=h.updateQueue,y.memoizedState=h.memoizedState,y.lanes=h.lanes):(y.updateQueue=null,y.memoizedState=null)}var x=Mu(o);if(x!==null){x.flags&=-257,Ou(x,o,u,i,t),x.mode&1&&Lu(i,d,t),t=x,s=d;var S=t.updateQueue;if(S===null){var j=new Set;j.add(s),t.updateQueue=j}else S.add(s);break e}else{if(!(t&1)){Lu(i,d,t),Do();break e}s=Error(w(426))}}else if(V&&u.mode&1){var O=Mu(o);if(O!==null){!(O.flags&65536)&&(O.flags|=256),Ou(O,o,u,i,t),ho(vn(s,u));break e}}i=s=vn(s,u),ee!==4&&(ee=2),Vn===null?Vn=[i]:Vn.push(i),i=o;do{switch(i.tag){case 3:i.flags|=65536,t&=-t,i.lanes|=t;var f=Da(i,s,t);Cu(i,f);break e;case 1:u=s;var c=i.type,p=i.stateNode;if(!(i.flags&128)&&(typeof c.getDerivedStateFromError=="function"||p!==null&&typeof p.componentDidCatch=="function"&&(yt===null||!yt.has(p)))){i.flags|=65536,t&=-t,i.lanes|=t;var v=Ra(i,u,t);Cu(i,v);break e}}i=i.return}while(i!==null)}ec(n)}catch(k){t=k,q===n&&n!==null&&(q=n=n.return);continue}break}while(!0)}function qa(){var e=il.current;return il.current=ll,e===null?ll:e}function Do(){(ee===0||ee===3||ee===2)&&(ee=4),ne===null||!(It&268435455)&&!(xl&268435455)||at(ne,le)}function sl(e,t){var n=D;D|=2;var r=qa();(ne!==e||le!==t)&&(Ze=null,Lt(e,t));do try{Od();break}catch(l){Ja(e,l)}while(!0);if(vo(),D=n,il.current=r,q!==null)throw Error(w(261));return ne=null,le=0,ee}function Od(){for(;q!==null;)ba(q)}function Dd(){for(;q!==null&&!lf();)ba(q)}function ba(e){var t=nc(e.alternate,e,xe);e.memoizedProps=e.pendingProps,t===null?ec(e):q=t,To.current=null}function ec(e){var t=e;do{var n=t.alternate;if(e=t.return,t.flags&32768){if(n=Nd(n,t),n!==null){n.flags&=32767,q=n;return}if(e!==null)e.flags|=32768,e.subtreeFlags=0,e.deletions=null;else{ee=6,q=null;return}}else if(n=_d(n,t,xe),n!==null){q=n;return}if(t=t.sibling,t!==null){q=t;return}q=t=e}while(t!==null);ee===0&&(ee=5)}function Nt(e,t,n){var r=I,l=Te.transition;try{Te.transition=null,I=1,Rd(e,t,n,r)}finally{Te.transition=l,I=r}return null}function Rd(e,t,n,r){do cn();while(ft!==null);if(D&6)throw Error(w(327));n=e.finishedWork;var l=e.finishedLanes;if(n===null)return null;if(e.finishedWork=null,e.finishedLanes=0,n===e.current)throw Error(w(177));e.callbackNode=null,e.callbackPriority=0;var i=n.lanes|n.childLanes;if(mf(e,i),e===ne&&(q=ne=null,le=0),!(n.subtreeFlags&2064)&&!(n.flags&2064)||_r||(_r=!0,rc(Br,function(){return cn(),null})),i=(n.flags&15990)!==0,n.subtreeFlags&15990||i){i=Te.transition,Te.transition=null;var o=I;I=1;var u=D;D|=4,To.current=null,Td(e,n),Za(n,e),td(Si),Qr=!!xi,Si=xi=null,e.current=n,zd(n),of(),D=u,I=o,Te.transition=i}else e.current=n;if(_r&&(_r=!1,ft=e,ul=l),i=e.pendingLanes,i===0&&(yt=null),af(n.stateNode),we(e,G()),t!==null)for(r=e.onRecoverableError,n=0;n<t.length;n++)l=t[n],r(l.value,{componentStack:l.stack,digest:l.digest});if(ol)throw ol=!1,e=Vi,Vi=null,e;return ul&1&&e.tag!==0&&cn(),i=e.pendingLanes,i&1?e===Wi?Wn++:(Wn=0,Wi=e):Wn=0,jt(),null}function cn(){if(ft!==null){var e=Ds(ul),t=Te.transition,n=I;try{if(Te.transition=null,I=16>e?16:e,ft===null)var r=!1;else{if(e=ft,ft=null,ul=0,D&6)throw Error(w(331));var l=D;for(D|=4,_=e.current;_!==null;){var i=_,o=i.child;if(_.flags&16){var u=i.deletions;if(u!==null){for(var s=0;s<u.length;s++){var d=u[s];for(_=d;_!==null;){var y=_;switch(y.tag){case 0:case 11:case 15:$n(8,y,i)}var m=y.child;if(m!==null)m.return=y,_=m;else for(;_!==null;){y=_;var h=y.sibling,x=y.return;if(Qa(y),y===d){_=null;break}if(h!==null){h.return=x,_=h;break}_=x}}}var S=i.alternate;if(S!==null){var j=S.child;if(j!==null){S.child=null;do{var O=j.sibling;j.sibling=null,j=O}while(j!==null)}}_=i}}if(i.subtreeFlags&2064&&o!==null)o.return=i,_=o;else e:for(;_!==null;){if(i=_,i.flags&2048)switch(i.tag){case 0:case 11:case 15:$n(9,i,i.return)}var f=i.sibling;if(f!==null){f.return=i.return,_=f;break e}_=i.return}}var c=e.current;for(_=c;_!==null;){o=_;var p=o.child;if(o.subtreeFlags&2064&&p!==null)p.return=o,_=p;else e:for(o=c;_!==null;){if(u=_,u.flags&2048)try{switch(u.tag){case 0:case 11:case 15:wl(9,u)}}catch(k){X(u,u.return,k)}if(u===o){_=null;break e}var v=u.sibling;if(v!==null){v.return=u.return,_=v;break e}_=u.return}}if(D=l,jt(),Qe&&typeof Qe.onPostCommitFiberRoot=="function")try{Qe.onPostCommitFiberRoot(fl,e)}catch{}r=!0}return r}finally{I=n,Te.transition=t}}return!1}function Ku(e,t,n){t=vn(n,t),t=Da(e,t,1),e=vt(e,t,1),t=de(),e!==null&&(ur(e,1,t),we(e,t))}function X(e,t,n){if(e.tag===3)Ku(e,e,n);else for(;t!==null;){if(t.tag===3){Ku(t,e,n);break}else if(t.tag===1){var r=t.stateNode;if(typeof t.type.getDerivedStateFromError=="function"||typeof r.componentDidCatch=="function"&&(yt===null||!yt.has(r))){e=vn(n,e),e=Ra(t,e,1),t=vt(t,e,1),e=de(),t!==null&&(ur(t,1,e),we(t,e));break}}t=t.return}}function Id(e,t,n){var r=e.pingCache;r!==null&&r.delete(t),t=de(),e.pingedLanes|=e.suspendedLanes&n,ne===e&&(le&n)===n&&(ee===4||ee===3&&(le&130023424)===le&&500>G()-Lo?Lt(e,0):zo|=n),we(e,t)}function tc(e,t){t===0&&(e.mode&1?(t=vr,vr<<=1,!(vr&130023424)&&(vr=4194304)):t=1);var n=de();e=tt(e,t),e!==null&&(ur(e,t,n),we(e,n))}function Fd(e){var t=e.memoizedState,n=0;t!==null&&(n=t.retryLane),tc(e,n)}function Ud(e,t){var n=0;switch(e.tag){case 13:var r=e.stateNode,l=e.memoizedState;l!==null&&(n=l.retryLane);break;case 19:r=e.stateNode;break;default:throw Error(w(314))}r!==null&&r.delete(t),tc(e,n)}var nc;nc=function(e,t,n){if(e!==null)if(e.memoizedProps!==t.pendingProps||ye.current)ve=!0;else{if(!(e.lanes&n)&&!(t.flags&128))return ve=!1,Cd(e,t,n);ve=!!(e.flags&131072)}else ve=!1,V&&t.flags&1048576&&oa(t,qr,t.index);switch(t.lanes=0,t.tag){case 2:var r=t.type;Ir(e,t),e=t.pendingProps;var l=dn(t,ae.current);an(t,n),l=jo(null,t,r,e,l,n);var i=Co();return t.flags|=1,typeof l=="object"&&l!==null&&typeof l.render=="function"&&l.$$typeof===void 0?(t.tag=1,t.memoizedState=null,t.updateQueue=null,ge(r)?(i=!0,Gr(t)):i=!1,t.memoizedState=l.state!==null&&l.state!==void 0?l.state:null,wo(t),l.updater=gl,t.stateNode=l,l._reactInternals=t,zi(t,r,e,n),t=Oi(null,t,r,!0,i,n)):(t.tag=0,V&&i&&fo(t),fe(null,t,l,n),t=t.child),t;case 16:r=t.elementType;e:{switch(Ir(e,t),e=t.pendingProps,l=r._init,r=l(r._payload),t.type=r,l=t.tag=$d(r),e=Oe(r,e),l){case 0:t=Mi(null,t,r,e,n);break e;case 1:t=Iu(null,t,r,e,n);break e;case 11:t=Du(null,t,r,e,n);break e;case 14:t=Ru(null,t,r,Oe(r.type,e),n);break e}throw Error(w(306,r,""))}return t;case 0:return r=t.type,l=t.pendingProps,l=t.elementType===r?l:Oe(r,l),Mi(e,t,r,l,n);case 1:return r=t.type,l=t.pendingProps,l=t.elementType===r?l:Oe(r,l),Iu(e,t,r,l,n);case 3:e:{if(Aa(t),e===null)throw Error(w(387));r=t.pendingProps,i=t.memoizedState,l=i.element,da(e,t),tl(t,r,null,n);var o=t.memoizedState;if(r=o.element,i.isDehydrated)if(i={element:r,isDehydrated:!1,cache:o.cache,pendingSuspenseBoundaries:o.pendingSuspenseBoundaries,transitions:o.transitions},t.updateQueue.baseState=i,t.memoizedState=i,t.flags&256){l=vn(Error(w(423)),t),t=Fu(e,t,r,n,l);break e}else if(r!==l){l=vn(Error(w(424)),t),t=Fu(e,t,r,n,l);break e}else for(Se=mt(t.stateNode.containerInfo.firstChild),ke=t,V=!0,Re=null,n=ca(t,null,r,n),t.child=n;n;)n.flags=n.flags&-3|4096,n=n.sibling;else{if(pn(),r===l){t=nt(e,t,n);break e}fe(e,t,r,n)}t=t.child}return t;case 5:return pa(t),e===null&&Ni(t),r=t.type,l=t.pendingProps,i=e!==null?e.memoizedProps:null,o=l.children,ki(r,l)?o=null:i!==null&&ki(r,i)&&(t.flags|=32),Ua(e,t),fe(e,t,o,n),t.child;case 6:return e===null&&Ni(t),null;case 13:return $a(e,t,n);case 4:return xo(t,t.stateNode.containerInfo),r=t.pendingProps,e===null?t.child=hn(t,null,r,n):fe(e,t,r,n),t.child;case 11:return r=t.type,l=t.pendingProps,l=t.elementType===r?l:Oe(r,l),Du(e,t,r,l,n);case 7:return fe(e,t,t.pendingProps,n),t.child;case 8:return fe(e,t,t.pendingProps.children,n),t.child;case 12:return fe(e,t,t.pendingProps.children,n),t.child;case 10:e:{if(r=t.type._context,l=t.pendingProps,i=t.memoizedProps,o=l.value,F(br,r._currentValue),r._currentValue=o,i!==null)if(Ue(i.value,o)){if(i.children===l.children&&!ye.current){t=nt(e,t,n);break e}}else for(i=t.child,i!==null&&(i.return=t);i!==null;){var u=i.dependencies;if(u!==null){o=i.child;for(var s=u.firstContext;s!==null;){if(s.context===r){if(i.tag===1){s=qe(-1,n&-n),s.tag=2;var d=i.updateQueue;if(d!==null){d=d.shared;var y=d.pending;y===null?s.next=s:(s.next=y.next,y.next=s),d.pending=s}}i.lanes|=n,s=i.alternate,s!==null&&(s.lanes|=n),Pi(i.return,n,t),u.lanes|=n;break}s=s.next}}else if(i.tag===10)o=i.type===t.type?null:i.child;else if(i.tag===18){if(o=i.return,o===null)throw Error(w(341));o.lanes|=n,u=o.alternate,u!==null&&(u.lanes|=n),Pi(o,n,t),o=i.sibling}else o=i.child;if(o!==null)o.return=i;else for(o=i;o!==null;){if(o===t){o=null;break}if(i=o.sibling,i!==null){i.return=o.return,o=i;break}o=o.return}i=o}fe(e,t,l.children,n),t=t.child}return t;case 9:return l=t.type,r=t.pendingProps.children,an(t,n),l=ze(l),r=r(l),t.flags|=1,fe(e,t,r,n),t.child;case 14:return r=t.type,l=Oe(r,t.pendingProps),l=Oe(r.type,l),Ru(e,t,r,l,n);case 15:return Ia(e,t,t.type,t.pendingProps,n);case 17:return r=t.type,l=t.pendingProps,l=t.elementType===r?l:Oe(r,l),Ir(e,t),t.tag=1,ge(r)?(e=!0,Gr(t)):e=!1,an(t,n),Oa(t,r,l),zi(t,r,l,n),Oi(null,t,r,!0,e,n);case 19:return Va(e,t,n);case 22:return Fa(e,t,n)}throw Error(w(156,t.tag))};function rc(e,t){return zs(e,t)}function Ad(e,t,n,r){this.tag=e,this.key=n,this.sibling=this.child=this.return=this.stateNode=this.type=this.elementType=null,this.index=0,this.ref=null,this.pendingProps=t,this.dependencies=this.memoizedState=this.updateQueue=this.memoizedProps=null,this.mode=r,this.subtreeFlags=this.flags=0,this.deletions=null,this.childLanes=this.lanes=0,this.alternate=null}function Pe(e,t,n,r){return new Ad(e,t,n,r)}function Ro(e){return e=e.prototype,!(!e||!e.isReactComponent)}function $d(e){if(typeof e=="function")return Ro(e)?1:0;if(e!=null){if(e=e.$$typeof,e===bi)return 11;if(e===eo)return 14}return 2}function wt(e,t){var n=e.alternate;return n===null?(n=Pe(e.tag,t,e.key,e.mode),n.elementType=e.elementType,n.type=e.type,n.stateNode=e.stateNode,n.alternate=e,e.alternate=n):(n.pendingProps=t,n.type=e.type,n.flags=0,n.subtreeFlags=0,n.deletions=null),n.flags=e.flags&14680064,n.childLanes=e.childLanes,n.lanes=e.lanes,n.child=e.child,n.memoizedProps=e.memoizedProps,n.memoizedState=e.memoizedState,n.updateQueue=e.updateQueue,t=e.dependencies,n.dependencies=t===null?null:{lanes:t.lanes,firstContext:t.firstContext},n.sibling=e.sibling,n.index=e.index,n.ref=e.ref,n}function Ar(e,t,n,r,l,i){var o=2;if(r=e,typeof e=="function")Ro(e)&&(o=1);else if(typeof e=="string")o=5;else e:switch(e){case Yt:return Mt(n.children,l,i,t);case qi:o=8,l|=8;break;case ei:return e=Pe(12,n,t,l|2),e.elementType=ei,e.lanes=i,e;case ti:return e=Pe(13,n,t,l),e.elementType=ti,e.lanes=i,e;case ni:return e=Pe(19,n,t,l),e.elementType=ni,e.lanes=i,e;case ps:return Sl(n,l,i,t);default:if(typeof e=="object"&&e!==null)switch(e.$$typeof){case fs:o=10;break e;case ds:o=9;break e;case bi:o=11;break e;case eo:o=14;break e;case ot:o=16,r=null;break e}throw Error(w(130,e==null?e:typeof e,""))}return t=Pe(o,n,t,l),t.elementType=e,t.type=r,t.lanes=i,t}function Mt(e,t,n,r){return e=Pe(7,e,r,t),e.lanes=n,e}function Sl(e,t,n,r){return e=Pe(22,e,r,t),e.elementType=ps,e.lanes=n,e.stateNode={isHidden:!1},e}function Jl(e,t,n){return e=Pe(6,e,null,t),e.lanes=n,e}function ql(e,t,n){return t=Pe(4,e.children!==null?e.children:[],e.key,t),t.lanes=n,t.stateNode={containerInfo:e.containerInfo,pendingChildren:null,implementation:e.implementation},t}function Vd(e,t,n,r,l){this.tag=t,this.containerInfo=e,this.finishedWork=this.pingCache=this.current=this.pendingChildren=null,this.timeoutHandle=-1,this.callbackNode=this.pendingContext=this.context=null,this.callbackPriority=0,this.eventTimes=Ml(0),this.expirationTimes=Ml(-1),this.entangledLanes=this.finishedLanes=this.mutableReadLanes=this.expiredLanes=this.pingedLanes=this.suspendedLanes=this.pendingLanes=0,this.entanglements=Ml(0),this.identifierPrefix=r,this.onRecoverableError=l,this.mutableSourceEagerHydrationData=null}function Io(e,t,n,r,l,i,o,u,s){return e=new Vd(e,t,n,u,s),t===1?(t=1,i===!0&&(t|=8)):t=0,i=Pe(3,null,null,t),e.current=i,i.stateNode=e,i.memoizedState={element:r,isDehydrated:n,cache:null,transitions:null,pendingSuspenseBoundaries:null},wo(i),e}function Wd(e,t,n){var r=3<arguments.length&&arguments[3]!==void 0?arguments[3]:null;return{$$typeof:Kt,key:r==null?null:""+r,children:e,containerInfo:t,implementation:n}}function lc(e){if(!e)return St;e=e._reactInternals;e:{if(At(e)!==e||e.tag!==1)throw Error(w(170));var t=e;do{switch(t.tag){case 3:t=t.stateNode.context;break e;case 1:if(ge(t.type)){t=t.stateNode.__reactInternalMemoizedMergedChildContext;break e}}t=t.return}while(t!==null);throw Error(w(171))}if(e.tag===1){var n=e.type;if(ge(n))return la(e,n,t)}return t}function ic(e,t,n,r,l,i,o,u,s){return e=Io(n,r,!0,e,l,i,o,u,s),e.context=lc(null),n=e.current,r=de(),l=gt(n),i=qe(r,l),i.callback=t??null,vt(n,i,l),e.current.lanes=l,ur(e,l,r),we(e,r),e}function kl(e,t,n,r){var l=t.current,i=de(),o=gt(l);return n=lc(n),t.context===null?t.context=n:t.pendingContext=n,t=qe(i,o),t.payload={element:e},r=r===void 0?null:r,r!==null&&(t.callback=r),e=vt(l,t,o),e!==null&&(Fe(e,l,o,i),Or(e,l,o)),o}function al(e){if(e=e.current,!e.child)return null;switch(e.child.tag){case 5:return e.child.stateNode;default:return e.child.stateNode}}function Yu(e,t){if(e=e.memoizedState,e!==null&&e.dehydrated!==null){var n=e.retryLane;e.retryLane=n!==0&&n<t?n:t}}function Fo(e,t){Yu(e,t),(e=e.alternate)&&Yu(e,t)}function Bd(){return null}var oc=typeof reportError=="function"?reportError:function(e){console.error(e)};function Uo(e){this._internalRoot=e}El.prototype.render=Uo.prototype.render=function(e){var t=this._internalRoot;if(t===null)throw Error(w(409));kl(e,t,null,null)};El.prototype.unmount=Uo.prototype.unmount=function(){var e=this._internalRoot;if(e!==null){this._internalRoot=null;var t=e.containerInfo;Ft(function(){kl(null,e,null,null)}),t[et]=null}};function El(e){this._internalRoot=e}El.prototype.unstable_scheduleHydration=function(e){if(e){var t=Fs();e={blockedOn:null,target:e,priority:t};for(var n=0;n<st.length&&t!==0&&t<st[n].priority;n++);st.splice(n,0,e),n===0&&As(e)}};function Ao(e){return!(!e||e.nodeType!==1&&e.nodeType!==9&&e.nodeType!==11)}function jl(e){return!(!e||e.nodeType!==1&&e.nodeType!==9&&e.nodeType!==11&&(e.nodeType!==8||e.nodeValue!==" react-mount-point-unstable "))}function Zu(){}function Hd(e,t,n,r,l){if(l){if(typeof r=="function"){var i=r;r=function(){var d=al(o);i.call(d)}}var o=ic(t,r,e,0,null,!1,!1,"",Zu);return e._reactRootContainer=o,e[et]=o.current,Jn(e.nodeType===8?e.parentNode:e),Ft(),o}for(;l=e.lastChild;)e.removeChild(l);if(typeof r=="function"){var u=r;r=function(){var d=al(s);u.call(d)}}var s=Io(e,0,!1,null,null,!1,!1,"",Zu);return e._reactRootContainer=s,e[et]=s.current,Jn(e.nodeType===8?e.parentNode:e),Ft(function(){kl(t,s,n,r)}),s}function Cl(e,t,n,r,l){var i=n._reactRootContainer;if(i){var o=i;if(typeof l=="function"){var u=l;l=function(){var s=al(o);u.call(s)}}kl(t,o,e,l)}else o=Hd(n,t,e,l,r);return al(o)}Rs=function(e){switch(e.tag){case 3:var t=e.stateNode;if(t.current.memoizedState.isDehydrated){var n=Mn(t.pendingLanes);n!==0&&(ro(t,n|1),we(t,G()),!(D&6)&&(yn=G()+500,jt()))}break;case 13:Ft(function(){var r=tt(e,1);if(r!==null){var l=de();Fe(r,e,1,l)}}),Fo(e,1)}};lo=function(e){if(e.tag===13){var t=tt(e,134217728);if(t!==null){var n=de();Fe(t,e,134217728,n)}Fo(e,134217728)}};Is=function(e){if(e.tag===13){var t=gt(e),n=tt(e,t);if(n!==null){var r=de();Fe(n,e,t,r)}Fo(e,t)}};Fs=function(){return I};Us=function(e,t){var n=I;try{return I=e,t()}finally{I=n}};di=function(e,t,n){switch(t){case"input":if(ii(e,n),t=n.name,n.type==="radio"&&t!=null){for(n=e;n.parentNode;)n=n.parentNode;for(n=n.querySelectorAll("input[name="+JSON.stringify(""+t)+'][type="radio"]'),t=0;t<n.length;t++){var r=n[t];if(r!==e&&r.form===e.form){var l=ml(r);if(!l)throw Error(w(90));ms(r),ii(r,l)}}}break;case"textarea":ys(e,n);break;case"select":t=n.value,t!=null&&ln(e,!!n.multiple,t,!1)}};js=Mo;Cs=Ft;var Qd={usingClientEntryPoint:!1,Events:[ar,Jt,ml,ks,Es,Mo]},Tn={findFiberByHostInstance:Pt,bundleType:0,version:"18.3.1",rendererPackageName:"react-dom"},Kd={bundleType:Tn.bundleType,version:Tn.version,rendererPackageName:Tn.rendererPackageName,rendererConfig:Tn.rendererConfig,overrideHookState:null,overrideHookStateDeletePath:null,overrideHookStateRenamePath:null,overrideProps:null,overridePropsDeletePath:null,overridePropsRenamePath:null,setErrorHandler:null,setSuspenseHandler:null,scheduleUpdate:null,currentDispatcherRef:rt.ReactCurrentDispatcher,findHostInstanceByFiber:function(e){return e=Ps(e),e===null?null:e.stateNode},findFiberByHostInstance:Tn.findFiberByHostInstance||Bd,findHostInstancesForRefresh:null,scheduleRefresh:null,scheduleRoot:null,setRefreshHandler:null,getCurrentFiber:null,reconcilerVersion:"18.3.1-next-f1338f8080-20240426"};if(typeof __REACT_DEVTOOLS_GLOBAL_HOOK__<"u"){var Nr=__REACT_DEVTOOLS_GLOBAL_HOOK__;if(!Nr.isDisabled&&Nr.supportsFiber)try{fl=Nr.inject(Kd),Qe=Nr}catch{}}je.__SECRET_INTERNALS_DO_NOT_USE_OR_YOU_WILL_BE_FIRED=Qd;je.createPortal=function(e,t){var n=2<arguments.length&&arguments[2]!==void 0?arguments[2]:null;if(!Ao(t))throw Error(w(200));return Wd(e,t,null,n)};je.createRoot=function(e,t){if(!Ao(e))throw Error(w(299));var n=!1,r="",l=oc;return t!=null&&(t.unstable_strictMode===!0&&(n=!0),t.identifierPrefix!==void 0&&(r=t.identifierPrefix),t.onRecoverableError!==void 0&&(l=t.onRecoverableError)),t=Io(e,1,!1,null,null,n,!1,r,l),e[et]=t.current,Jn(e.nodeType===8?e.parentNode:e),new Uo(t)};je.findDOMNode=function(e){if(e==null)return null;if(e.nodeType===1)return e;var t=e._reactInternals;if(t===void 0)throw typeof e.render=="function"?Error(w(188)):(e=Object.keys(e).join(","),Error(w(268,e)));return e=Ps(t),e=e===null?null:e.stateNode,e};je.flushSync=function(e){return Ft(e)};je.hydrate=function(e,t,n){if(!jl(t))throw Error(w(200));return Cl(null,e,t,!0,n)};je.hydrateRoot=function(e,t,n){if(!Ao(e))throw Error(w(405));var r=n!=null&&n.hydratedSources||null,l=!1,i="",o=oc;if(n!=null&&(n.unstable_strictMode===!0&&(l=!0),n.identifierPrefix!==void 0&&(i=n.identifierPrefix),n.onRecoverableError!==void 0&&(o=n.onRecoverableError)),t=ic(t,null,e,1,n??null,l,!1,i,o),e[et]=t.current,Jn(e),r)for(e=0;e<r.length;e++)n=r[e],l=n._getVersion,l=l(n._source),t.mutableSourceEagerHydrationData==null?t.mutableSourceEagerHydrationData=[n,l]:t.mutableSourceEagerHydrationData.push(n,l);return new El(t)};je.render=function(e,t,n){if(!jl(t))throw Error(w(200));return Cl(null,e,t,!1,n)};je.unmountComponentAtNode=function(e){if(!jl(e))throw Error(w(40));return e._reactRootContainer?(Ft(function(){Cl(null,null,e,!1,function(){e._reactRootContainer=null,e[et]=null})}),!0):!1};je.unstable_batchedUpdates=Mo;je.unstable_renderSubtreeIntoContainer=function(e,t,n,r){if(!jl(n))throw Error(w(200));if(e==null||e._reactInternals===void 0)throw Error(w(38));return Cl(e,t,n,!1,r)};je.version="18.3.1-next-f1338f8080-20240426";function uc(){if(!(typeof __REACT_DEVTOOLS_GLOBAL_HOOK__>"u"||typeof __REACT_DEVTOOLS_GLOBAL_HOOK__.checkDCE!="function"))try{__REACT_DEVTOOLS_GLOBAL_HOOK__.checkDCE(uc)}catch(e){console.error(e)}}uc(),us.exports=je;var Yd=us.exports,sc,Xu=Yd;sc=Xu.createRoot,Xu.hydrateRoot;async function J(e,t={}){const n=await fetch(e,{credentials:"include",headers:{"Content-Type":"application/json",...t.headers||{}},...t});if(!n.ok){const r=await n.text();throw new Error(r||n.statusText)}return n.status===204?null:n.json()}function Zd(){const[e,t]=R.useState(!1),[n,r]=R.useState(""),[l,i]=R.useState(""),[o,u]=R.useState(""),[s,d]=R.useState(null),[y,m]=R.useState([]),[h,x]=R.useState([]),[S,j]=R.useState([]),[O,f]=R.useState(""),[c,p]=R.useState(""),[v,k]=R.useState("status"),[E,N]=R.useState({name:"",type:"contact",pin:"",enabled:!0,entry_exit:!1}),[P,$]=R.useState(""),[L,ce]=R.useState(""),[lt,Ye]=R.useState(""),[Ae,$t]=R.useState({username:"",password:"",admin:!1}),[Vt,Wt]=R.useState(""),[C,T]=R.useState([]),[z,W]=R.useState(0),[Y,Bt]=R.useState(0),[$e,Sn]=R.useState(30),[Ve,Ht]=R.useState(30),[ac,cc]=R.useState(!1);R.useEffect(()=>{if(!e)return;async function g(){try{const B=await J("/api/status");d(B),f(B.mode),m(B.zones),W(B.exit_delay||0),Bt(B.entry_delay||0),cc(!!B.alarm),Sn(B.exit_total||30),Ht(B.entry_total||30)}catch(B){console.error(B)}}g();const Z=setInterval(g,5e3);return()=>clearInterval(Z)},[e]),R.useEffect(()=>{if(!e)return;async function g(){try{const Z=await J("/api/zones");m(Z);try{const B=await J("/api/users");x(B)}catch{}try{const B=await J("/api/arm_modes");j(B),B&&B.length>0&&!c&&p(B[0].name)}catch{}}catch(Z){console.error(Z)}}g()},[e]);async function fc(g){g.preventDefault();try{await J("/api/login",{method:"POST",body:JSON.stringify({username:l,password:o})}),t(!0),r("")}catch{r("Login failed")}}async function dc(){await J("/api/logout",{method:"POST"}),t(!1),i(""),u("")}async function _l(g){await J("/api/arm",{method:"POST",body:JSON.stringify({mode:g})}),f(g)}async function $o(){await J("/api/disarm",{method:"POST"}),f("Disarmed")}R.useEffect(()=>{if(!e||v!=="logs")return;async function g(){try{const Z=await J("/api/logs?lines=200");T(Z)}catch(Z){console.error(Z),T([])}}g()},[e,v]);async function pc(g){try{await J("/api/test_trigger",{method:"POST",body:JSON.stringify({zone_id:g})})}catch(Z){alert(Z.message)}}async function hc(){try{const g=parseInt(E.pin,10);if(isNaN(g))throw new Error("Pin must be a number");const Z={...E,pin:g,enabled:!!E.enabled,entry_exit:!!E.entry_exit};await J("/api/zones",{method:"POST",body:JSON.stringify(Z)}),N({name:"",type:"contact",pin:"",enabled:!0}),$("");const B=await J("/api/zones");m(B)}catch(g){$(g.message)}}async function mc(g){await J(`/api/zones/${g}`,{method:"DELETE"});const Z=await J("/api/zones");m(Z)}async function vc(){try{const g=lt.split(",").map(Ct=>Ct.trim()).filter(Ct=>Ct.length>0).map(Ct=>parseInt(Ct,10)).filter(Ct=>!isNaN(Ct));await J("/api/arm_modes",{method:"POST",body:JSON.stringify({name:L,active_zones:g})}),ce(""),Ye("");const B=await J("/api/arm_modes");j(B)}catch(g){console.error(g)}}async function yc(){try{await J("/api/users",{method:"POST",body:JSON.stringify(Ae)}),$t({username:"",password:"",admin:!1});const g=await J("/api/users");x(g),Wt("")}catch(g){Wt(g.message)}}async function gc(g){await J(`/api/users/${g}`,{method:"DELETE"});const Z=await J("/api/users");x(Z)}return e?a.jsxs("div",{className:"app-container",children:[a.jsxs("header",{children:[a.jsx("h1",{children:"Minder Alarm"}),a.jsxs("nav",{children:[a.jsx("button",{onClick:()=>k("status"),className:v==="status"?"active":"",children:"Status"}),a.jsx("button",{onClick:()=>k("zones"),className:v==="zones"?"active":"",children:"Zones"}),a.jsx("button",{onClick:()=>k("armModes"),className:v==="armModes"?"active":"",children:"Arm Modes"}),a.jsx("button",{onClick:()=>k("users"),className:v==="users"?"active":"",children:"Users"}),a.jsx("button",{onClick:()=>k("logs"),className:v==="logs"?"active":"",children:"Logs"}),a.jsx("button",{onClick:()=>k("test"),className:v==="test"?"active":"",children:"Test"}),a.jsx("button",{onClick:()=>k("help"),className:v==="help"?"active":"",children:"Help"}),a.jsx("button",{onClick:dc,children:"Logout"})]})]}),a.jsxs("main",{children:[v==="status"&&s&&a.jsx("div",{className:"status",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"System Status"}),a.jsxs("p",{children:["Mode: ",a.jsx("strong",{children:O})]}),ac&&a.jsx("div",{className:"alarm-alert",children:"🔴 Alarm Triggered!"}),z>0&&a.jsxs("div",{className:"delay-container exit-delay",children:[a.jsxs("div",{className:"delay-label",children:[a.jsx("span",{children:"Exit Delay"}),a.jsxs("span",{children:[z,"s"]})]}),a.jsx("div",{className:"delay-bar",children:a.jsx("div",{className:"delay-bar-fill",style:{width:`${($e-z)/$e*100}%`}})})]}),Y>0&&a.jsxs("div",{className:"delay-container entry-delay",children:[a.jsxs("div",{className:"delay-label",children:[a.jsx("span",{children:"Entry Delay"}),a.jsxs("span",{children:[Y,"s"]})]}),a.jsx("div",{className:"delay-bar",children:a.jsx("div",{className:"delay-bar-fill",style:{width:`${(Ve-Y)/Ve*100}%`}})})]}),a.jsxs("div",{className:"buttons",children:[a.jsxs("select",{value:c,onChange:g=>p(g.target.value),children:[S.map(g=>a.jsx("option",{value:g.name,children:g.name},g.name)),a.jsx("option",{value:"TestSoft",children:"Test Soft"}),a.jsx("option",{value:"TestWiring",children:"Test Wiring"})]}),a.jsx("button",{onClick:()=>_l(c),disabled:O===c,children:"Arm"}),a.jsx("button",{onClick:$o,disabled:O==="Disarmed",children:"Disarm"})]}),a.jsx("h3",{children:"Zones"}),a.jsxs("table",{children:[a.jsx("thead",{children:a.jsxs("tr",{children:[a.jsx("th",{children:"ID"}),a.jsx("th",{children:"Name"}),a.jsx("th",{children:"Type"}),a.jsx("th",{children:"Pin"}),a.jsx("th",{children:"Enabled"}),a.jsx("th",{children:"Triggered"})]})}),a.jsx("tbody",{children:y.map(g=>a.jsxs("tr",{className:g.active?"triggered":"",children:[a.jsx("td",{children:g.id}),a.jsx("td",{children:g.name}),a.jsx("td",{children:g.type}),a.jsx("td",{children:g.pin}),a.jsx("td",{children:g.enabled?"Yes":"No"}),a.jsx("td",{children:g.active?"Yes":"No"})]},g.id))})]})]})}),v==="zones"&&a.jsx("div",{className:"zones",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"Zones"}),a.jsxs("table",{children:[a.jsx("thead",{children:a.jsxs("tr",{children:[a.jsx("th",{children:"ID"}),a.jsx("th",{children:"Name"}),a.jsx("th",{children:"Type"}),a.jsx("th",{children:"Pin"}),a.jsx("th",{children:"Enabled"}),a.jsx("th",{children:"Entry/Exit"}),a.jsx("th",{children:"Actions"})]})}),a.jsx("tbody",{children:y.map(g=>a.jsxs("tr",{children:[a.jsx("td",{children:g.id}),a.jsx("td",{children:g.name}),a.jsx("td",{children:g.type}),a.jsx("td",{children:g.pin}),a.jsx("td",{children:g.enabled?"Yes":"No"}),a.jsx("td",{children:g.entry_exit?"Yes":"No"}),a.jsx("td",{children:a.jsx("button",{onClick:()=>mc(g.id),children:"Delete"})})]},g.id))})]}),a.jsx("h3",{children:"Add Zone"}),a.jsxs("div",{className:"form-row",children:[a.jsx("input",{placeholder:"Name",value:E.name,onChange:g=>N({...E,name:g.target.value})}),a.jsxs("select",{value:E.type,onChange:g=>N({...E,type:g.target.value}),children:[a.jsx("option",{value:"contact",children:"Contact"}),a.jsx("option",{value:"pir",children:"PIR"})]}),a.jsx("input",{placeholder:"Pin",value:E.pin,onChange:g=>N({...E,pin:g.target.value})}),a.jsxs("label",{children:[a.jsx("input",{type:"checkbox",checked:E.enabled,onChange:g=>N({...E,enabled:g.target.checked})})," Enabled"]}),a.jsxs("label",{children:[a.jsx("input",{type:"checkbox",checked:E.entry_exit,onChange:g=>N({...E,entry_exit:g.target.checked})})," Entry/Exit"]}),a.jsx("button",{onClick:hc,children:"Create"})]}),P&&a.jsx("p",{className:"error",children:P})]})}),v==="armModes"&&a.jsx("div",{className:"armmodes",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"Arm Modes"}),a.jsxs("table",{children:[a.jsx("thead",{children:a.jsxs("tr",{children:[a.jsx("th",{children:"Name"}),a.jsx("th",{children:"Active Zones"}),a.jsx("th",{children:"Actions"})]})}),a.jsx("tbody",{children:S.map(g=>a.jsxs("tr",{children:[a.jsx("td",{children:g.name}),a.jsx("td",{children:g.active_zones.join(", ")}),a.jsx("td",{children:a.jsx("button",{onClick:()=>{ce(g.name),Ye(g.active_zones.join(", "))},children:"Edit"})})]},g.name))})]}),a.jsx("h3",{children:"Add/Update Arm Mode"}),a.jsxs("div",{className:"form-row",children:[a.jsx("input",{placeholder:"Mode Name",value:L,onChange:g=>ce(g.target.value)}),a.jsx("input",{placeholder:"Zone IDs (comma separated)",value:lt,onChange:g=>Ye(g.target.value)}),a.jsx("button",{onClick:vc,children:"Save"})]})]})}),v==="users"&&a.jsx("div",{className:"users",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"Users"}),a.jsxs("table",{children:[a.jsx("thead",{children:a.jsxs("tr",{children:[a.jsx("th",{children:"Username"}),a.jsx("th",{children:"Admin"}),a.jsx("th",{children:"Actions"})]})}),a.jsx("tbody",{children:h.map(g=>a.jsxs("tr",{children:[a.jsx("td",{children:g.username}),a.jsx("td",{children:g.admin?"Yes":"No"}),a.jsx("td",{children:g.username!=="admin"&&a.jsx("button",{onClick:()=>gc(g.username),children:"Delete"})})]},g.username))})]}),a.jsx("h3",{children:"Add User"}),a.jsxs("div",{className:"form-row",children:[a.jsx("input",{placeholder:"Username",value:Ae.username,onChange:g=>$t({...Ae,username:g.target.value})}),a.jsx("input",{type:"password",placeholder:"Password",value:Ae.password,onChange:g=>$t({...Ae,password:g.target.value})}),a.jsxs("label",{children:[a.jsx("input",{type:"checkbox",checked:Ae.admin,onChange:g=>$t({...Ae,admin:g.target.checked})})," Admin"]}),a.jsx("button",{onClick:yc,children:"Create"})]}),Vt&&a.jsx("p",{className:"error",children:Vt})]})}),v==="logs"&&a.jsx("div",{className:"logs",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"Event Log"}),C.length===0&&a.jsx("p",{children:"No log entries found."}),C.length>0&&a.jsxs("table",{children:[a.jsx("thead",{children:a.jsxs("tr",{children:[a.jsx("th",{children:"#"}),a.jsx("th",{children:"Entry"})]})}),a.jsx("tbody",{children:C.map((g,Z)=>a.jsxs("tr",{children:[a.jsx("td",{children:Z+1}),a.jsx("td",{children:a.jsx("pre",{style:{margin:0},children:g})})]},Z))})]})]})}),v==="test"&&a.jsx("div",{className:"test",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"Test Mode"}),a.jsxs("p",{children:["Current Mode: ",a.jsx("strong",{children:O})]}),a.jsxs("div",{className:"buttons",children:[a.jsx("button",{onClick:()=>_l("TestSoft"),disabled:O==="TestSoft",children:"Start Test Soft"}),a.jsx("button",{onClick:()=>_l("TestWiring"),disabled:O==="TestWiring",children:"Start Test Wiring"}),a.jsx("button",{onClick:$o,disabled:O==="Disarmed",children:"Disarm"})]}),O==="TestSoft"&&a.jsxs("div",{children:[a.jsx("h3",{children:"Trigger Zones"}),a.jsxs("table",{children:[a.jsx("thead",{children:a.jsxs("tr",{children:[a.jsx("th",{children:"ID"}),a.jsx("th",{children:"Name"}),a.jsx("th",{children:"Type"}),a.jsx("th",{children:"Pin"}),a.jsx("th",{children:"Enabled"}),a.jsx("th",{children:"Triggered"}),a.jsx("th",{children:"Actions"})]})}),a.jsx("tbody",{children:y.map(g=>a.jsxs("tr",{className:g.active?"triggered":"",children:[a.jsx("td",{children:g.id}),a.jsx("td",{children:g.name}),a.jsx("td",{children:g.type}),a.jsx("td",{children:g.pin}),a.jsx("td",{children:g.enabled?"Yes":"No"}),a.jsx("td",{children:g.active?"Yes":"No"}),a.jsx("td",{children:a.jsx("button",{onClick:()=>pc(g.id),children:"Trigger"})})]},g.id))})]})]}),O==="TestWiring"&&a.jsx("p",{children:"Trigger sensors physically to verify wiring.  Alerts will be suppressed but events will be logged."}),O!=="TestSoft"&&O!=="TestWiring"&&a.jsx("p",{children:"Select a test mode above to begin."})]})}),v==="help"&&a.jsx("div",{className:"help",children:a.jsxs("div",{className:"card",children:[a.jsx("h2",{children:"Help & User Guide"}),a.jsxs("p",{children:[a.jsx("strong",{children:"Welcome to Minder!"}),"  This system monitors sensors connected to a Raspberry Pi and lets you control them from your browser.  All communication is encrypted over HTTPS."]}),a.jsx("h3",{children:"Logging In"}),a.jsxs("p",{children:["When the server starts for the first time it creates a default administrator account called ",a.jsx("code",{children:"admin"})," with password ",a.jsx("code",{children:"admin"}),".  Log in with these credentials and immediately create a new user and change the admin password."]}),a.jsx("h3",{children:"Zones"}),a.jsxs("p",{children:["Zones represent physical sensors.  Use the ",a.jsx("em",{children:"Zones"})," page to add a zone by specifying a name, type (contact or PIR), GPIO pin and whether it is enabled.  Delete zones when they are no longer used."]}),a.jsx("h3",{children:"Arm Modes"}),a.jsxs("p",{children:["An arm mode defines which zones should be active when the system is armed.  For example, ",a.jsx("em",{children:"Away"})," might include all zones, while ",a.jsx("em",{children:"Home"})," might exclude interior motion sensors.  Use the ",a.jsx("em",{children:"Arm Modes"})," page to create or update modes by listing zone IDs."]}),a.jsx("h3",{children:"Arming and Disarming"}),a.jsxs("p",{children:["The ",a.jsx("em",{children:"Status"})," page shows the current mode and a list of zones with their triggered state.  Use the buttons to arm in a chosen mode or to disarm.  When armed, the system continuously monitors the active zones and records any triggers in the log."]}),a.jsx("h3",{children:"Test Modes"}),a.jsxs("p",{children:["Two special modes make it easy to test without causing a disturbance.  ",a.jsx("strong",{children:"Test Soft"})," ignores real sensors and lets you trigger zones manually from the ",a.jsx("em",{children:"Test"})," page.  ",a.jsx("strong",{children:"Test Wiring"})," monitors sensors but suppresses alerts, logging triggers only.  Use these modes to check your configuration and wiring."]}),a.jsx("h3",{children:"Logs"}),a.jsxs("p",{children:["Every significant event (login, arm/disarm, zone trigger, configuration change, alert delivery) is recorded to a rolling log file.  View recent entries on the ",a.jsx("em",{children:"Logs"})," page."]}),a.jsx("h3",{children:"User Management"}),a.jsxs("p",{children:["Administrators can add or remove user accounts and assign administrator privileges on the ",a.jsx("em",{children:"Users"})," page.  Never delete the built‑in ",a.jsx("code",{children:"admin"})," user; instead, change its password for security."]}),a.jsx("h3",{children:"Alerts"}),a.jsxs("p",{children:["When a zone triggers in a normal arm mode the system can send notifications.  By default it logs an alert entry.  To enable email alerts, edit the ",a.jsx("code",{children:"alerts"})," section of ",a.jsx("code",{children:"config.json"})," with your SMTP server details (see the development guide)."]}),a.jsx("h3",{children:"TLS Certificate"}),a.jsxs("p",{children:["The server requires a certificate and private key to operate.  Use the provided ",a.jsx("code",{children:"generate_cert.sh"})," script in ",a.jsx("code",{children:"scripts/"})," to generate a self‑signed certificate for testing or obtain a Let’s Encrypt certificate for production.  Update ",a.jsx("code",{children:"config.json"})," to point to your cert and key files."]}),a.jsxs("p",{children:["If you need more technical information (build instructions, extending the system, etc.), see the ",a.jsx("em",{children:"Development Guide"})," (",a.jsx("code",{children:"DEVELOPMENT.md"}),") in the project repository."]})]})})]}),a.jsx("footer",{children:a.jsxs("small",{children:["© ",new Date().getFullYear()," Minder Alarm System"]})})]}):a.jsxs("div",{className:"login-container",children:[a.jsx("h2",{children:"Login to Minder"}),a.jsxs("form",{onSubmit:fc,className:"card",children:[a.jsxs("label",{children:["Username",a.jsx("input",{value:l,onChange:g=>i(g.target.value),required:!0})]}),a.jsxs("label",{children:["Password",a.jsx("input",{type:"password",value:o,onChange:g=>u(g.target.value),required:!0})]}),n&&a.jsx("p",{className:"error",children:n}),a.jsx("button",{type:"submit",children:"Login"})]})]})}const Xd=document.getElementById("root"),Gd=sc(Xd);Gd.render(a.jsx(Rc.StrictMode,{children:a.jsx(Zd,{})}));
@@ -1,15 +1,23 @@
 import React, { useEffect, useState } from 'react';
 
+// CSRF token of the current session.  The server sends it in the
+// X-CSRF-Token header of the login response and of every authenticated
+// response, and requires it on every request that changes something.
+let csrfToken = '';
+
 // Utility to call the backend API with credentials.  Returns JSON or throws.
 async function api(path, opts = {}) {
   const res = await fetch(path, {
     credentials: 'include',
     headers: {
       'Content-Type': 'application/json',
+      ...(csrfToken ? { 'X-CSRF-Token': csrfToken } : {}),
       ...(opts.headers || {})
     },
     ...opts
   });
+  const token = res.headers.get('X-CSRF-Token');
+  if (token) csrfToken = token;
   if (!res.ok) {
     const msg = await res.text();
     throw new Error(msg || res.statusText);
@@ -22,6 +30,10 @@ export default function App() {
   const [loginError, setLoginError] = useState('');
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
+  // Second factor, asked for once the server answers otp_required: a code
+  // from the authenticator app or a recovery code.
+  const [otpNeeded, setOtpNeeded] = useState(false);
+  const [otp, setOtp] = useState('');
   const [status, setStatus] = useState(null);
   const [zones, setZones] = useState([]);
   const [users, setUsers] = useState([]);
@@ -68,8 +80,22 @@ export default function App() {
       }
     }
     load();
-    const id = setInterval(load, 5000);
-    return () => clearInterval(id);
+    let id = setInterval(load, 5000);
+    // Reload as soon as the server reports a change; while the stream is
+    // open, polling is only a fallback.
+    let events;
+    if (window.EventSource) {
+      events = new EventSource('/api/events');
+      events.onopen = () => {
+        clearInterval(id);
+        id = setInterval(load, 30000);
+      };
+      ['trigger', 'alarm', 'test', 'arm', 'disarm', 'fault', 'armed', 'entry_delay', 'acknowledge'].forEach((type) => events.addEventListener(type, load));
+    }
+    return () => {
+      clearInterval(id);
+      if (events) events.close();
+    };
   }, [loggedIn]);
 
   // Fetch initial data for zones, users, arm modes when logged in
@@ -79,6 +105,13 @@ export default function App() {
       try {
         const zs = await api('/api/zones');
         setZones(zs);
+        // Open on the user's preferred page
+        try {
+          const profile = await api('/api/profile');
+          if (profile.landing_page) setPage(profile.landing_page);
+        } catch (err) {
+          // ignore
+        }
         // Try to fetch users and arm modes; non‑admins will receive 403
         try {
           const us = await api('/api/users');
@@ -106,14 +139,27 @@ export default function App() {
   async function handleLogin(e) {
     e.preventDefault();
     try {
+      const creds = { username, password };
+      if (otpNeeded) {
+        const code = otp.trim();
+        if (/^\d{6}$/.test(code)) creds.otp = code;
+        else creds.recovery_code = code;
+      }
       await api('/api/login', {
         method: 'POST',
-        body: JSON.stringify({ username, password })
+        body: JSON.stringify(creds)
       });
       setLoggedIn(true);
       setLoginError('');
+      setOtpNeeded(false);
+      setOtp('');
     } catch (err) {
-      setLoginError('Login failed');
+      if (err.message.includes('otp_required')) {
+        setOtpNeeded(true);
+        setLoginError('Enter the code from your authenticator app, or a recovery code');
+      } else {
+        setLoginError('Login failed');
+      }
     }
   }
 
@@ -211,9 +257,14 @@ export default function App() {
   }
 
   async function deleteUser(name) {
-    await api(`/api/users/${name}`, { method: 'DELETE' });
-    const us = await api('/api/users');
-    setUsers(us);
+    try {
+      await api(`/api/users/${name}`, { method: 'DELETE' });
+      const us = await api('/api/users');
+      setUsers(us);
+      setUserError('');
+    } catch (err) {
+      setUserError(err.message);
+    }
   }
 
   if (!loggedIn) {
@@ -229,6 +280,12 @@ export default function App() {
             Password
             <input type="password" value={password} onChange={(e) => setPassword(e.target.value)} required />
           </label>
+          {otpNeeded && (
+            <label>
+              Code
+              <input value={otp} onChange={(e) => setOtp(e.target.value)} autoComplete="one-time-code" required autoFocus />
+            </label>
+          )}
           {loginError && <p className="error">{loginError}</p>}
           <button type="submit">Login</button>
         </form>
@@ -236,6 +293,14 @@ export default function App() {
     );
   }
 
+  // With read_only_config the configuration is managed elsewhere, so
+  // the forms that would change it are hidden.
+  const readOnly = status && status.read_only_config;
+  // The server refuses to delete the last enabled admin, whatever it is
+  // called, so its Delete button is not offered.
+  const enabledAdmins = users.filter((u) => u.role === 'admin' && !u.disabled).length;
+  const lastAdmin = (u) => u.role === 'admin' && !u.disabled && enabledAdmins <= 1;
+
   return (
     <div className="app-container">
       <header>
@@ -339,13 +404,14 @@ export default function App() {
                       <td>{z.pin}</td>
                       <td>{z.enabled ? 'Yes' : 'No'}</td>
                       <td>{z.entry_exit ? 'Yes' : 'No'}</td>
-                      <td><button onClick={() => deleteZone(z.id)}>Delete</button></td>
+                      <td>{!readOnly && <button onClick={() => deleteZone(z.id)}>Delete</button>}</td>
                     </tr>
                   ))}
                 </tbody>
               </table>
-              <h3>Add Zone</h3>
-              <div className="form-row">
+              {readOnly && <p>The configuration is read only; change zones in the configuration file.</p>}
+              {!readOnly && <h3>Add Zone</h3>}
+              {!readOnly && <div className="form-row">
                 <input placeholder="Name" value={newZone.name} onChange={(e) => setNewZone({ ...newZone, name: e.target.value })} />
                 <select value={newZone.type} onChange={(e) => setNewZone({ ...newZone, type: e.target.value })}>
                   <option value="contact">Contact</option>
@@ -355,7 +421,7 @@ export default function App() {
                 <label><input type="checkbox" checked={newZone.enabled} onChange={(e) => setNewZone({ ...newZone, enabled: e.target.checked })} /> Enabled</label>
                 <label><input type="checkbox" checked={newZone.entry_exit} onChange={(e) => setNewZone({ ...newZone, entry_exit: e.target.checked })} /> Entry/Exit</label>
                 <button onClick={createZone}>Create</button>
-              </div>
+              </div>}
               {zoneError && <p className="error">{zoneError}</p>}
             </div>
           </div>
@@ -375,23 +441,23 @@ export default function App() {
                       <td>{am.name}</td>
                       <td>{am.active_zones.join(', ')}</td>
                       <td>
-                        <button
+                        {!readOnly && <button
                           onClick={() => {
                             setArmModeName(am.name);
                             setNewArmModeZones(am.active_zones.join(', '));
                           }}
-                        >Edit</button>
+                        >Edit</button>}
                       </td>
                     </tr>
                   ))}
                 </tbody>
               </table>
-              <h3>Add/Update Arm Mode</h3>
-              <div className="form-row">
+              {!readOnly && <h3>Add/Update Arm Mode</h3>}
+              {!readOnly && <div className="form-row">
                 <input placeholder="Mode Name" value={armModeName} onChange={(e) => setArmModeName(e.target.value)} />
                 <input placeholder="Zone IDs (comma separated)" value={newArmModeZones} onChange={(e) => setNewArmModeZones(e.target.value)} />
                 <button onClick={createArmMode}>Save</button>
-              </div>
+              </div>}
             </div>
           </div>
         )}
@@ -409,18 +475,18 @@ export default function App() {
                     <tr key={u.username}>
                       <td>{u.username}</td>
                       <td>{u.admin ? 'Yes' : 'No'}</td>
-                      <td>{u.username !== 'admin' && <button onClick={() => deleteUser(u.username)}>Delete</button>}</td>
+                      <td>{!readOnly && !lastAdmin(u) && <button onClick={() => deleteUser(u.username)}>Delete</button>}</td>
                     </tr>
                   ))}
                 </tbody>
               </table>
-              <h3>Add User</h3>
-              <div className="form-row">
+              {!readOnly && <h3>Add User</h3>}
+              {!readOnly && <div className="form-row">
                 <input placeholder="Username" value={newUser.username} onChange={(e) => setNewUser({ ...newUser, username: e.target.value })} />
                 <input type="password" placeholder="Password" value={newUser.password} onChange={(e) => setNewUser({ ...newUser, password: e.target.value })} />
                 <label><input type="checkbox" checked={newUser.admin} onChange={(e) => setNewUser({ ...newUser, admin: e.target.checked })} /> Admin</label>
                 <button onClick={createUser}>Create</button>
-              </div>
+              </div>}
               {userError && <p className="error">{userError}</p>}
             </div>
           </div>
@@ -497,7 +563,7 @@ export default function App() {
               <h2>Help &amp; User Guide</h2>
               <p><strong>Welcome to Minder!</strong>  This system monitors sensors connected to a Raspberry&nbsp;Pi and lets you control them from your browser.  All communication is encrypted over HTTPS.</p>
               <h3>Logging In</h3>
-              <p>When the server starts for the first time it creates a default administrator account called <code>admin</code> with password <code>admin</code>.  Log in with these credentials and immediately create a new user and change the admin password.</p>
+              <p>When the server starts for the first time it creates an administrator account called <code>admin</code> with a random password, which is printed when the server starts and saved in the file <code>minder-initial-password</code> next to the configuration.  Log in with these credentials; you will be asked to choose a new password straight away.</p>
               <h3>Zones</h3>
               <p>Zones represent physical sensors.  Use the <em>Zones</em> page to add a zone by specifying a name, type (contact or PIR), GPIO pin and whether it is enabled.  Delete zones when they are no longer used.</p>
               <h3>Arm Modes</h3>
@@ -509,7 +575,7 @@ export default function App() {
               <h3>Logs</h3>
               <p>Every significant event (login, arm/disarm, zone trigger, configuration change, alert delivery) is recorded to a rolling log file.  View recent entries on the <em>Logs</em> page.</p>
               <h3>User Management</h3>
-              <p>Administrators can add or remove user accounts and assign administrator privileges on the <em>Users</em> page.  Never delete the built‑in <code>admin</code> user; instead, change its password for security.</p>
+              <p>Administrators can add or remove user accounts and assign administrator privileges on the <em>Users</em> page.  At least one enabled administrator must remain: deleting, disabling or demoting the last one is refused, whatever the account is called.</p>
               <h3>Alerts</h3>
               <p>When a zone triggers in a normal arm mode the system can send notifications.  By default it logs an alert entry.  To enable email alerts, edit the <code>alerts</code> section of <code>config.json</code> with your SMTP server details (see the development guide).</p>
               <h3>TLS Certificate</h3>
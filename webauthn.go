@@ -0,0 +1,649 @@
+package main
+
+// This file implements passkey (WebAuthn) registration and login.  Only
+// "none" attestation is requested, so the authenticator's make and model
+// are not verified; the credential is trusted because it was registered
+// from a logged in session.
+
+import (
+    "bytes"
+    "crypto"
+    "crypto/ecdh"
+    "crypto/ecdsa"
+    "crypto/ed25519"
+    "crypto/elliptic"
+    "crypto/rsa"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math/big"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+// webauthnTimeout is how long a ceremony may take between begin and
+// finish.
+const webauthnTimeout = 5 * time.Minute
+
+// COSE algorithm identifiers of the supported credential keys.
+const (
+    coseES256 = -7
+    coseRS256 = -257
+    coseEdDSA = -8
+)
+
+// Authenticator data flags.
+const (
+    authDataUserPresent  = 0x01
+    authDataUserVerified = 0x04
+    authDataAttested     = 0x40
+)
+
+// webauthnChallenge is an outstanding registration or login ceremony.
+// Username is empty for a login that lets the authenticator choose the
+// account.
+type webauthnChallenge struct {
+    username string
+    login    bool
+    expires  time.Time
+}
+
+// webauthnChallenges holds the outstanding ceremonies keyed by challenge.
+type webauthnChallenges struct {
+    mu      sync.Mutex
+    pending map[string]webauthnChallenge
+}
+
+// add records a new challenge and discards expired ones.
+func (wc *webauthnChallenges) add(username string, login bool) (string, error) {
+    challenge, err := randomString(32)
+    if err != nil {
+        return "", err
+    }
+    wc.mu.Lock()
+    defer wc.mu.Unlock()
+    now := time.Now()
+    for c, p := range wc.pending {
+        if now.After(p.expires) {
+            delete(wc.pending, c)
+        }
+    }
+    wc.pending[challenge] = webauthnChallenge{username: username, login: login, expires: now.Add(webauthnTimeout)}
+    return challenge, nil
+}
+
+// take removes and returns the challenge, which must be unexpired and of
+// the expected kind.  Each challenge can therefore be answered only once.
+func (wc *webauthnChallenges) take(challenge string, login bool) (webauthnChallenge, bool) {
+    wc.mu.Lock()
+    defer wc.mu.Unlock()
+    p, ok := wc.pending[challenge]
+    delete(wc.pending, challenge)
+    if !ok || p.login != login || time.Now().After(p.expires) {
+        return webauthnChallenge{}, false
+    }
+    return p, true
+}
+
+// relyingParty returns the relying party ID and the expected origin, both
+// derived from Config.BaseURL.
+func (s *Server) relyingParty() (string, string, error) {
+    base := s.cfgMgr.Get().BaseURL
+    if base == "" {
+        return "", "", errors.New("passkeys require base_url to be configured")
+    }
+    u, err := url.Parse(base)
+    if err != nil || u.Host == "" {
+        return "", "", fmt.Errorf("invalid base_url %q", base)
+    }
+    return u.Hostname(), u.Scheme + "://" + u.Host, nil
+}
+
+// decodeB64URL decodes the unpadded base64url encoding used by WebAuthn
+// clients, tolerating padding.
+func decodeB64URL(s string) ([]byte, error) {
+    return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// webauthnCredentialJSON is a PublicKeyCredential as serialised by the
+// front-end, with binary fields base64url encoded.
+type webauthnCredentialJSON struct {
+    RawID    string `json:"rawId"`
+    Type     string `json:"type"`
+    Response struct {
+        ClientDataJSON    string `json:"clientDataJSON"`
+        AttestationObject string `json:"attestationObject"`
+        AuthenticatorData string `json:"authenticatorData"`
+        Signature         string `json:"signature"`
+        UserHandle        string `json:"userHandle"`
+    } `json:"response"`
+}
+
+// verifyClientData checks the type and origin of the client data and
+// returns the challenge it answers.
+func verifyClientData(raw []byte, ceremony, origin string) (string, error) {
+    var cd struct {
+        Type      string `json:"type"`
+        Challenge string `json:"challenge"`
+        Origin    string `json:"origin"`
+    }
+    if err := json.Unmarshal(raw, &cd); err != nil {
+        return "", fmt.Errorf("invalid client data: %w", err)
+    }
+    if cd.Type != ceremony {
+        return "", fmt.Errorf("unexpected client data type %q", cd.Type)
+    }
+    if cd.Origin != origin {
+        return "", fmt.Errorf("origin %q does not match %q", cd.Origin, origin)
+    }
+    return cd.Challenge, nil
+}
+
+// authData is the parsed authenticator data.  CredID and CredKey are only
+// present when a credential is being registered.
+type authData struct {
+    RPIDHash  []byte
+    Flags     byte
+    SignCount uint32
+    CredID    []byte
+    CredKey   []byte // COSE_Key
+}
+
+// parseAuthData parses authenticator data and checks that it was produced
+// for rpID with the user present and verified.
+func parseAuthData(b []byte, rpID string) (authData, error) {
+    var ad authData
+    if len(b) < 37 {
+        return ad, errors.New("authenticator data too short")
+    }
+    ad.RPIDHash, ad.Flags, ad.SignCount = b[:32], b[32], binary.BigEndian.Uint32(b[33:37])
+    hash := sha256.Sum256([]byte(rpID))
+    if !bytes.Equal(ad.RPIDHash, hash[:]) {
+        return ad, errors.New("credential belongs to a different relying party")
+    }
+    if ad.Flags&authDataUserPresent == 0 || ad.Flags&authDataUserVerified == 0 {
+        return ad, errors.New("user was not verified by the authenticator")
+    }
+    if ad.Flags&authDataAttested == 0 {
+        return ad, nil
+    }
+    rest := b[37:]
+    if len(rest) < 18 {
+        return ad, errors.New("attested credential data too short")
+    }
+    n := int(binary.BigEndian.Uint16(rest[16:18]))
+    rest = rest[18:]
+    if n == 0 || len(rest) < n {
+        return ad, errors.New("invalid credential ID")
+    }
+    ad.CredID, rest = rest[:n], rest[n:]
+    _, after, err := decodeCBOR(rest)
+    if err != nil {
+        return ad, fmt.Errorf("invalid credential public key: %w", err)
+    }
+    ad.CredKey = rest[:len(rest)-len(after)]
+    return ad, nil
+}
+
+// parseCOSEKey converts a COSE_Key to a public key and its algorithm.
+func parseCOSEKey(b []byte) (crypto.PublicKey, int64, error) {
+    v, _, err := decodeCBOR(b)
+    if err != nil {
+        return nil, 0, err
+    }
+    m, ok := v.(map[any]any)
+    if !ok {
+        return nil, 0, errors.New("COSE key is not a map")
+    }
+    kty, _ := m[int64(1)].(int64)
+    alg, _ := m[int64(3)].(int64)
+    crv, _ := m[int64(-1)].(int64)
+    x, _ := m[int64(-2)].([]byte)
+    switch {
+    case kty == 2 && alg == coseES256 && crv == 1:
+        y, _ := m[int64(-3)].([]byte)
+        if len(x) != 32 || len(y) != 32 {
+            return nil, 0, errors.New("invalid P-256 key")
+        }
+        // ecdh validates that the point lies on the curve.
+        if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+            return nil, 0, err
+        }
+        return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, alg, nil
+    case kty == 3 && alg == coseRS256:
+        n, _ := m[int64(-1)].([]byte)
+        e, _ := m[int64(-2)].([]byte)
+        if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+            return nil, 0, errors.New("invalid RSA key")
+        }
+        return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, alg, nil
+    case kty == 1 && alg == coseEdDSA && crv == 6:
+        if len(x) != ed25519.PublicKeySize {
+            return nil, 0, errors.New("invalid Ed25519 key")
+        }
+        return ed25519.PublicKey(x), alg, nil
+    }
+    return nil, 0, fmt.Errorf("unsupported key type %d with algorithm %d", kty, alg)
+}
+
+// verifyAssertion checks sig over the authenticator data and client data
+// hash with the credential's COSE key.
+func verifyAssertion(coseKey, authenticatorData, clientData, sig []byte) error {
+    key, alg, err := parseCOSEKey(coseKey)
+    if err != nil {
+        return err
+    }
+    cdHash := sha256.Sum256(clientData)
+    signed := append(append([]byte{}, authenticatorData...), cdHash[:]...)
+    digest := sha256.Sum256(signed)
+    switch alg {
+    case coseES256:
+        if ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest[:], sig) {
+            return nil
+        }
+    case coseRS256:
+        if rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], sig) == nil {
+            return nil
+        }
+    case coseEdDSA:
+        if ed25519.Verify(key.(ed25519.PublicKey), signed, sig) {
+            return nil
+        }
+    }
+    return errors.New("invalid signature")
+}
+
+// handleWebAuthnRegisterBegin returns the options for navigator.credentials.create
+// so that the logged in user can register a passkey.
+func (s *Server) handleWebAuthnRegisterBegin(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    if s.configReadOnly(w) {
+        return
+    }
+    rpID, _, err := s.relyingParty()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusServiceUnavailable)
+        return
+    }
+    challenge, err := s.webauthn.add(user.Username, false)
+    if err != nil {
+        http.Error(w, "internal error", http.StatusInternalServerError)
+        return
+    }
+    exclude := []map[string]string{}
+    for _, c := range user.WebAuthn {
+        exclude = append(exclude, map[string]string{"type": "public-key", "id": base64.RawURLEncoding.EncodeToString(c.ID)})
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(map[string]any{
+        "publicKey": map[string]any{
+            "challenge": challenge,
+            "rp":        map[string]string{"id": rpID, "name": "Minder"},
+            "user": map[string]string{
+                "id":          base64.RawURLEncoding.EncodeToString([]byte(user.Username)),
+                "name":        user.Username,
+                "displayName": user.Username,
+            },
+            "pubKeyCredParams": []map[string]any{
+                {"type": "public-key", "alg": coseES256},
+                {"type": "public-key", "alg": coseEdDSA},
+                {"type": "public-key", "alg": coseRS256},
+            },
+            "timeout":            webauthnTimeout.Milliseconds(),
+            "attestation":        "none",
+            "excludeCredentials": exclude,
+            "authenticatorSelection": map[string]string{
+                "residentKey":      "preferred",
+                "userVerification": "required",
+            },
+        },
+    })
+}
+
+// handleWebAuthnRegisterFinish verifies the new credential and stores it on
+// the user.  Expected JSON: the PublicKeyCredential plus an optional
+// "name" labelling the passkey.
+func (s *Server) handleWebAuthnRegisterFinish(w http.ResponseWriter, r *http.Request, user User) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    var req struct {
+        webauthnCredentialJSON
+        Name string `json:"name"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "invalid JSON", http.StatusBadRequest)
+        return
+    }
+    rpID, origin, err := s.relyingParty()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusServiceUnavailable)
+        return
+    }
+    cred, err := s.verifyRegistration(req.webauthnCredentialJSON, user.Username, rpID, origin)
+    if err != nil {
+        http.Error(w, "passkey registration failed: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    cred.Name = req.Name
+    err = s.cfgMgr.Update(func(c *Config) error {
+        idx := -1
+        for i, u := range c.Users {
+            for _, existing := range u.WebAuthn {
+                if bytes.Equal(existing.ID, cred.ID) {
+                    return errors.New("exists")
+                }
+            }
+            if u.Username == user.Username {
+                idx = i
+            }
+        }
+        if idx < 0 {
+            return errors.New("not found")
+        }
+        c.Users[idx].WebAuthn = append(c.Users[idx].WebAuthn, cred)
+        return nil
+    })
+    if err != nil {
+        if err.Error() == "exists" {
+            http.Error(w, "passkey already registered", http.StatusConflict)
+        } else {
+            http.Error(w, "internal error", http.StatusInternalServerError)
+        }
+        return
+    }
+    s.logger.Log("passkey %q registered by %s", cred.Name, user.Username)
+    s.audit(r, user.Username, "passkey.register", user.Username, nil, credentialView(cred))
+    s.adminChange(user.Username, "passkey %q registered for %s", cred.Name, user.Username)
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    _ = json.NewEncoder(w).Encode(credentialView(cred))
+}
+
+// verifyRegistration checks a registration response against the pending
+// challenge of username and returns the credential to store.
+func (s *Server) verifyRegistration(cj webauthnCredentialJSON, username, rpID, origin string) (WebAuthnCredential, error) {
+    var cred WebAuthnCredential
+    clientData, err := decodeB64URL(cj.Response.ClientDataJSON)
+    if err != nil {
+        return cred, errors.New("invalid client data encoding")
+    }
+    challenge, err := verifyClientData(clientData, "webauthn.create", origin)
+    if err != nil {
+        return cred, err
+    }
+    if p, ok := s.webauthn.take(challenge, false); !ok || p.username != username {
+        return cred, errors.New("unknown or expired challenge")
+    }
+    attObj, err := decodeB64URL(cj.Response.AttestationObject)
+    if err != nil {
+        return cred, errors.New("invalid attestation object encoding")
+    }
+    v, _, err := decodeCBOR(attObj)
+    if err != nil {
+        return cred, err
+    }
+    att, _ := v.(map[any]any)
+    raw, _ := att["authData"].([]byte)
+    ad, err := parseAuthData(raw, rpID)
+    if err != nil {
+        return cred, err
+    }
+    if ad.CredID == nil {
+        return cred, errors.New("no credential in attestation")
+    }
+    if _, _, err := parseCOSEKey(ad.CredKey); err != nil {
+        return cred, err
+    }
+    return WebAuthnCredential{
+        ID:        ad.CredID,
+        PublicKey: ad.CredKey,
+        SignCount: ad.SignCount,
+        CreatedAt: time.Now(),
+    }, nil
+}
+
+// handleWebAuthnLoginBegin returns the options for navigator.credentials.get.
+// Expected JSON: {"username":"..."}, or an empty username to let the
+// authenticator offer its passkeys for this site.
+func (s *Server) handleWebAuthnLoginBegin(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    var req struct {
+        Username string `json:"username"`
+    }
+    if r.ContentLength != 0 {
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "invalid JSON", http.StatusBadRequest)
+            return
+        }
+    }
+    rpID, _, err := s.relyingParty()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusServiceUnavailable)
+        return
+    }
+    challenge, err := s.webauthn.add(req.Username, true)
+    if err != nil {
+        http.Error(w, "internal error", http.StatusInternalServerError)
+        return
+    }
+    allow := []map[string]string{}
+    if req.Username != "" {
+        user, _ := s.cfgMgr.FindUser(req.Username)
+        for _, c := range user.WebAuthn {
+            allow = append(allow, map[string]string{"type": "public-key", "id": base64.RawURLEncoding.EncodeToString(c.ID)})
+        }
+    }
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(map[string]any{
+        "publicKey": map[string]any{
+            "challenge":        challenge,
+            "rpId":             rpID,
+            "timeout":          webauthnTimeout.Milliseconds(),
+            "allowCredentials": allow,
+            "userVerification": "required",
+        },
+    })
+}
+
+// handleWebAuthnLoginFinish verifies an assertion and, if it is valid,
+// starts a session exactly as a password login does.  A signature counter
+// that has not advanced suggests a cloned authenticator and is rejected.
+func (s *Server) handleWebAuthnLoginFinish(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    var req webauthnCredentialJSON
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "invalid JSON", http.StatusBadRequest)
+        return
+    }
+    rpID, origin, err := s.relyingParty()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusServiceUnavailable)
+        return
+    }
+    username, err := s.verifyLogin(req, rpID, origin)
+    if err != nil {
+        s.logger.Log("passkey login failed: %v", err)
+        s.recordFailedLogin(r, username)
+        http.Error(w, "invalid credentials", http.StatusUnauthorized)
+        return
+    }
+    if err := s.startSession(w, r, username); err != nil {
+        sessionError(w, err)
+        return
+    }
+    s.recordLogin(r, username, LoginViaPasskey)
+    s.logger.Log("login %s (passkey)", username)
+    w.Header().Set("Content-Type", "application/json")
+    _ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// verifyLogin checks an assertion and advances the stored signature
+// counter.  It returns the user the credential belongs to, which is also
+// returned with the error once the credential has been identified.
+func (s *Server) verifyLogin(cj webauthnCredentialJSON, rpID, origin string) (string, error) {
+    clientData, err := decodeB64URL(cj.Response.ClientDataJSON)
+    if err != nil {
+        return "", errors.New("invalid client data encoding")
+    }
+    challenge, err := verifyClientData(clientData, "webauthn.get", origin)
+    if err != nil {
+        return "", err
+    }
+    p, ok := s.webauthn.take(challenge, true)
+    if !ok {
+        return "", errors.New("unknown or expired challenge")
+    }
+    credID, err := decodeB64URL(cj.RawID)
+    if err != nil {
+        return "", errors.New("invalid credential ID encoding")
+    }
+    var owner string
+    var cred WebAuthnCredential
+    for _, u := range s.cfgMgr.Get().Users {
+        for _, c := range u.WebAuthn {
+            if bytes.Equal(c.ID, credID) {
+                owner, cred = u.Username, c
+            }
+        }
+    }
+    if owner == "" {
+        return "", errors.New("unknown credential")
+    }
+    if u, _ := s.cfgMgr.FindUser(owner); u.Disabled {
+        return owner, errors.New("account disabled")
+    }
+    if p.username != "" && p.username != owner {
+        return owner, fmt.Errorf("credential does not belong to %s", p.username)
+    }
+    if cj.Response.UserHandle != "" {
+        if handle, err := decodeB64URL(cj.Response.UserHandle); err != nil || string(handle) != owner {
+            return owner, errors.New("user handle does not match credential")
+        }
+    }
+    rawAuthData, err := decodeB64URL(cj.Response.AuthenticatorData)
+    if err != nil {
+        return owner, errors.New("invalid authenticator data encoding")
+    }
+    ad, err := parseAuthData(rawAuthData, rpID)
+    if err != nil {
+        return owner, err
+    }
+    sig, err := decodeB64URL(cj.Response.Signature)
+    if err != nil {
+        return owner, errors.New("invalid signature encoding")
+    }
+    if err := verifyAssertion(cred.PublicKey, rawAuthData, clientData, sig); err != nil {
+        return owner, err
+    }
+    // The counter is checked and advanced under the config lock so that two
+    // concurrent logins cannot both present the same count.
+    err = s.cfgMgr.Update(func(c *Config) error {
+        for i := range c.Users {
+            for j := range c.Users[i].WebAuthn {
+                stored := &c.Users[i].WebAuthn[j]
+                if !bytes.Equal(stored.ID, credID) {
+                    continue
+                }
+                if (ad.SignCount != 0 || stored.SignCount != 0) && ad.SignCount <= stored.SignCount {
+                    return fmt.Errorf("signature counter went from %d to %d, authenticator may be cloned", stored.SignCount, ad.SignCount)
+                }
+                now := time.Now()
+                stored.SignCount = ad.SignCount
+                stored.LastUsedAt = &now
+                return nil
+            }
+        }
+        return errors.New("credential was removed")
+    })
+    return owner, err
+}
+
+// credentialViewJSON is a registered passkey as listed by the API.
+type credentialViewJSON struct {
+    ID         string     `json:"id"`
+    Name       string     `json:"name,omitempty"`
+    CreatedAt  time.Time  `json:"created_at"`
+    LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// credentialView returns the API representation of c.
+func credentialView(c WebAuthnCredential) credentialViewJSON {
+    return credentialViewJSON{
+        ID:         base64.RawURLEncoding.EncodeToString(c.ID),
+        Name:       c.Name,
+        CreatedAt:  c.CreatedAt,
+        LastUsedAt: c.LastUsedAt,
+    }
+}
+
+// handleWebAuthnCredentials lists the passkeys of the logged in user
+// (GET /api/webauthn/credentials) or deletes one of them
+// (DELETE /api/webauthn/credentials/{id}).
+func (s *Server) handleWebAuthnCredentials(w http.ResponseWriter, r *http.Request, user User) {
+    id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/webauthn/credentials"), "/")
+    switch {
+    case r.Method == http.MethodGet && id == "":
+        creds := []credentialViewJSON{}
+        for _, c := range user.WebAuthn {
+            creds = append(creds, credentialView(c))
+        }
+        w.Header().Set("Content-Type", "application/json")
+        _ = json.NewEncoder(w).Encode(creds)
+    case r.Method == http.MethodDelete && id != "":
+        if s.configReadOnly(w) {
+            return
+        }
+        credID, err := decodeB64URL(id)
+        if err != nil {
+            http.NotFound(w, r)
+            return
+        }
+        var removed WebAuthnCredential
+        err = s.cfgMgr.Update(func(c *Config) error {
+            for i, u := range c.Users {
+                if u.Username != user.Username {
+                    continue
+                }
+                for j, cred := range u.WebAuthn {
+                    if bytes.Equal(cred.ID, credID) {
+                        removed = cred
+                        c.Users[i].WebAuthn = append(u.WebAuthn[:j:j], u.WebAuthn[j+1:]...)
+                        return nil
+                    }
+                }
+            }
+            return errors.New("not found")
+        })
+        if err != nil {
+            if err.Error() == "not found" {
+                http.Error(w, "not found", http.StatusNotFound)
+            } else {
+                http.Error(w, "internal error", http.StatusInternalServerError)
+            }
+            return
+        }
+        s.logger.Log("passkey %s deleted by %s", id, user.Username)
+        s.audit(r, user.Username, "passkey.delete", user.Username, credentialView(removed), nil)
+        s.adminChange(user.Username, "passkey %q of %s deleted", removed.Name, user.Username)
+        w.WriteHeader(http.StatusNoContent)
+    default:
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+    }
+}
@@ -0,0 +1,243 @@
+package main
+
+// This file provides a WebSocket for wall panels, carrying live updates
+// down and commands up over one connection.  Messages are JSON objects
+// with a "type":
+//
+//   hello    server -> client  on connecting: the user and their role
+//   state    server -> client  what /api/status returns, on connecting, on
+//                              request and after every event
+//   event    server -> client  an update, as sent by /api/events
+//   command  client -> server  "arm" (mode), "disarm" (optional pin and
+//                              username), "bypass" (zone_id, bypass),
+//                              "acknowledge" or "status", with an id to
+//                              match the ack
+//   ack      server -> client  the outcome of a command: ok or error
+//
+// Commands need the same roles as the REST endpoints they mirror, and a
+// socket opened with an API key or a scoped token may only send the
+// commands whose endpoints the key or token permits.
+
+import (
+    "crypto/subtle"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/gorilla/websocket"
+)
+
+const (
+    // wsPongWait is how long a socket may stay silent, not even answering
+    // a ping, before it is closed.
+    wsPongWait = 60 * time.Second
+    // wsPingInterval is how often the server pings; it must be shorter
+    // than wsPongWait.
+    wsPingInterval = 25 * time.Second
+    // wsWriteWait bounds each write to the socket.
+    wsWriteWait = 10 * time.Second
+    // wsMaxMessage bounds the size of a client message.
+    wsMaxMessage = 4096
+)
+
+// wsUpgrader upgrades /api/ws.  Its default origin check refuses pages of
+// other sites, which would otherwise ride on the session cookie.
+var wsUpgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// wsCommand is a message from the client.
+type wsCommand struct {
+    Type     string `json:"type"`
+    ID       string `json:"id,omitempty"`
+    Command  string `json:"command"`
+    Mode     string `json:"mode,omitempty"`
+    Username string `json:"username,omitempty"` // with pin
+    PIN      string `json:"pin,omitempty"`
+    ZoneID   int    `json:"zone_id,omitempty"`
+    Bypass   bool   `json:"bypass,omitempty"`
+}
+
+// wsMessage is a message to the client.
+type wsMessage struct {
+    Type  string        `json:"type"`
+    ID    string        `json:"id,omitempty"`
+    OK    bool          `json:"ok,omitempty"`
+    Error string        `json:"error,omitempty"`
+    User  string        `json:"user,omitempty"`
+    Role  string        `json:"role,omitempty"`
+    State *systemStatus `json:"state,omitempty"`
+    Event *liveEvent    `json:"event,omitempty"`
+}
+
+// wsEndpoints are the REST endpoints that the commands mirror.
+var wsEndpoints = map[string]string{
+    "status":      "GET /api/status",
+    "arm":         "POST /api/arm",
+    "disarm":      "POST /api/disarm",
+    "acknowledge": "POST /api/acknowledge",
+    "bypass":      "PATCH /api/zones/",
+}
+
+// wsToken returns the bearer token r was made with.
+func wsToken(cfg Config, r *http.Request) (APIToken, bool) {
+    hash := hashAPIToken(bearerToken(r))
+    for _, u := range cfg.Users {
+        for _, t := range u.Tokens {
+            if !u.Disabled && subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) == 1 {
+                return t, true
+            }
+        }
+    }
+    return APIToken{}, false
+}
+
+// wsPermits reports whether the API key or token r opened the socket with
+// permits cmd, as if it were a request to the endpoint cmd mirrors.
+func (s *Server) wsPermits(r *http.Request, user User, cmd wsCommand) bool {
+    method, path, _ := strings.Cut(wsEndpoints[cmd.Command], " ")
+    if cmd.Command == "bypass" {
+        path += fmt.Sprint(cmd.ZoneID)
+    }
+    req := &http.Request{Method: method, URL: &url.URL{Path: path}}
+    cfg := s.cfgMgr.Get()
+    if user.APIKey != "" {
+        for _, k := range cfg.APIKeys {
+            if k.Name == user.APIKey {
+                return apiKeyAllows(k.Endpoints, req)
+            }
+        }
+        return false
+    }
+    if bearerToken(r) != "" {
+        t, ok := wsToken(cfg, r)
+        return ok && tokenAllows(t.Scope, req)
+    }
+    return true
+}
+
+// wsRun carries out a command for user, with r the request that opened the
+// socket.
+func (s *Server) wsRun(r *http.Request, user User, cmd wsCommand) error {
+    if _, ok := wsEndpoints[cmd.Command]; !ok {
+        return fmt.Errorf("unknown command %q", cmd.Command)
+    }
+    if !s.wsPermits(r, user, cmd) {
+        return errForbidden
+    }
+    switch cmd.Command {
+    case "arm":
+        return s.armAs(user, cmd.Mode)
+    case "disarm":
+        return s.disarmAs(r, user, cmd.Username, cmd.PIN)
+    case "acknowledge":
+        return s.acknowledgeAs(user)
+    case "bypass":
+        _, err := s.bypassZone(r, user, cmd.ZoneID, cmd.Bypass)
+        return err
+    }
+    return nil
+}
+
+// handleWS upgrades GET /api/ws to a WebSocket speaking the protocol
+// described at the top of this file.
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request, user User) {
+    conn, err := wsUpgrader.Upgrade(w, r, nil)
+    if err != nil {
+        // The upgrader has already replied.
+        return
+    }
+    defer conn.Close()
+    events, _, _, _ := s.live.subscribe(0, false)
+    defer s.live.unsubscribe(events)
+
+    // The reader runs commands and queues their acks; all writes happen
+    // below.  done is closed when the reader stops, and quit when the
+    // writer does, so that neither waits for the other once it is gone.
+    acks := make(chan wsMessage, 16)
+    done := make(chan struct{})
+    quit := make(chan struct{})
+    defer close(quit)
+    conn.SetReadLimit(wsMaxMessage)
+    _ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+    conn.SetPongHandler(func(string) error {
+        return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+    })
+    go func() {
+        defer close(done)
+        for {
+            var cmd wsCommand
+            if err := conn.ReadJSON(&cmd); err != nil {
+                return
+            }
+            _ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+            if cmd.Type != "command" {
+                continue
+            }
+            if !s.liveCredentialsValid(r, user) {
+                return
+            }
+            ack := wsMessage{Type: "ack", ID: cmd.ID, OK: true}
+            if err := s.wsRun(r, user, cmd); err != nil {
+                ack = wsMessage{Type: "ack", ID: cmd.ID, Error: err.Error()}
+            }
+            select {
+            case acks <- ack:
+            case <-quit:
+                return
+            case <-s.stop:
+                return
+            }
+        }
+    }()
+
+    send := func(m wsMessage) bool {
+        _ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+        return conn.WriteJSON(m) == nil
+    }
+    state := func() wsMessage {
+        st := s.statusSnapshot()
+        return wsMessage{Type: "state", State: &st}
+    }
+    if !send(wsMessage{Type: "hello", User: user.Username, Role: user.Role}) || !send(state()) {
+        return
+    }
+    ping := time.NewTicker(wsPingInterval)
+    defer ping.Stop()
+    for {
+        select {
+        case e, ok := <-events:
+            if !ok {
+                // Dropped for falling behind.
+                _ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(wsWriteWait))
+                return
+            }
+            if !liveAllowed(user, e) {
+                continue
+            }
+            if !send(wsMessage{Type: "event", Event: &e}) || !send(state()) {
+                return
+            }
+        case ack := <-acks:
+            if !send(ack) {
+                return
+            }
+            if ack.OK && !send(state()) {
+                return
+            }
+        case <-ping.C:
+            if !s.liveCredentialsValid(r, user) {
+                _ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "logged out"), time.Now().Add(wsWriteWait))
+                return
+            }
+            if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)) != nil {
+                return
+            }
+        case <-done:
+            return
+        case <-s.stop:
+            _ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server stopping"), time.Now().Add(wsWriteWait))
+            return
+        }
+    }
+}
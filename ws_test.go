@@ -0,0 +1,111 @@
+package main
+
+// Tests of the WebSocket: commands sent over it need the same roles as the
+// REST endpoints they mirror.
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+)
+
+// wsDial opens /api/ws of s as username, reading past the greeting.
+func wsDial(t *testing.T, s *Server, username string) *websocket.Conn {
+    srv := httptest.NewServer(s.withAuth(s.handleWS))
+    t.Cleanup(srv.Close)
+    id, _, _, err := s.sessions.Create(Session{Username: username}, time.Hour, 0, sessionLimit{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    header := http.Header{"Cookie": {"session=" + id}}
+    conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/ws", header)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    for _, want := range []string{"hello", "state"} {
+        if m := wsRead(t, conn, ""); m.Type != want {
+            t.Fatalf("got %q, want %q", m.Type, want)
+        }
+    }
+    return conn
+}
+
+// wsRead returns the next message of type typ, or of any type if typ is "".
+func wsRead(t *testing.T, conn *websocket.Conn, typ string) wsMessage {
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    for {
+        var m wsMessage
+        if err := conn.ReadJSON(&m); err != nil {
+            t.Fatal(err)
+        }
+        if typ == "" || m.Type == typ {
+            return m
+        }
+    }
+}
+
+// wsSend sends cmd and returns its ack.
+func wsSend(t *testing.T, conn *websocket.Conn, cmd wsCommand) wsMessage {
+    cmd.Type, cmd.ID = "command", "1"
+    if err := conn.WriteJSON(cmd); err != nil {
+        t.Fatal(err)
+    }
+    return wsRead(t, conn, "ack")
+}
+
+func TestWSArming(t *testing.T) {
+    cfg := alarmTestConfig()
+    cfg.HTTPPort, cfg.CertFile, cfg.KeyFile = 8443, "cert.pem", "key.pem"
+    cfg.ArmModes = append(cfg.ArmModes, ArmMode{Name: "Night", ActiveZones: []int{1}})
+    cfg.Users = []User{
+        {Username: "admin", Role: RoleAdmin, Admin: true},
+        {Username: "alice", Role: RoleOperator, PINHash: testHash("4826")},
+        {Username: "viewer", Role: RoleViewer},
+        {Username: "limited", Role: RoleOperator, AllowedModes: []string{"Night"}},
+    }
+    s, _ := newTestServer(t, cfg)
+    s.currentMode = "Disarmed"
+
+    viewer := wsDial(t, s, "viewer")
+    for _, cmd := range []wsCommand{{Command: "arm", Mode: "Away"}, {Command: "disarm"}, {Command: "acknowledge"}, {Command: "bypass", ZoneID: 2, Bypass: true}} {
+        if ack := wsSend(t, viewer, cmd); ack.OK || ack.Error != "forbidden" {
+            t.Errorf("%s as viewer: ack %+v", cmd.Command, ack)
+        }
+    }
+    if ack := wsSend(t, wsDial(t, s, "limited"), wsCommand{Command: "arm", Mode: "Away"}); ack.OK {
+        t.Error("operator armed a mode they may not")
+    }
+
+    alice := wsDial(t, s, "alice")
+    if ack := wsSend(t, alice, wsCommand{Command: "arm", Mode: "Away"}); !ack.OK {
+        t.Fatalf("arm: %s", ack.Error)
+    }
+    if s.currentMode != "ExitDelay" || s.pendingMode != "Away" {
+        t.Errorf("mode %q, pending %q after arm, want the exit delay to Away", s.currentMode, s.pendingMode)
+    }
+    if ack := wsSend(t, alice, wsCommand{Command: "disarm", PIN: "0000"}); ack.OK || ack.Error != errInvalidPIN.Error() {
+        t.Errorf("wrong PIN: ack %+v", ack)
+    }
+    if ack := wsSend(t, alice, wsCommand{Command: "disarm", PIN: "4826"}); !ack.OK {
+        t.Fatalf("disarm: %s", ack.Error)
+    }
+    if s.currentMode != "Disarmed" {
+        t.Errorf("mode %q after disarm", s.currentMode)
+    }
+    if ack := wsSend(t, alice, wsCommand{Command: "bypass", ZoneID: 2, Bypass: true}); !ack.OK {
+        t.Fatalf("bypass: %s", ack.Error)
+    }
+    for _, z := range s.cfgMgr.Get().Zones {
+        if z.ID == 2 && z.Enabled {
+            t.Error("bypassed zone still enabled")
+        }
+    }
+    if ack := wsSend(t, alice, wsCommand{Command: "bypass", ZoneID: 9, Bypass: true}); ack.OK {
+        t.Error("unknown zone bypassed")
+    }
+}